@@ -12,10 +12,16 @@ import (
 	"github.com/aihub/backend-go/internal/dashscope"
 	"github.com/aihub/backend-go/internal/di"
 	"github.com/aihub/backend-go/internal/kafka"
+	"github.com/aihub/backend-go/internal/knowledge"
 	"github.com/aihub/backend-go/internal/logger"
 	"github.com/aihub/backend-go/internal/middleware"
+	"github.com/aihub/backend-go/internal/outbox"
+	"github.com/aihub/backend-go/internal/plugins"
 	"github.com/aihub/backend-go/internal/services"
 	"github.com/aihub/backend-go/internal/storage"
+	"github.com/aihub/backend-go/internal/transfer"
+	"github.com/aihub/backend-go/internal/trash"
+	"github.com/aihub/backend-go/internal/webhook"
 	"github.com/joho/godotenv"
 	"go.uber.org/dig"
 	"go.uber.org/zap"
@@ -155,6 +161,13 @@ func Init() (*App, error) {
 		}
 	}
 
+	// Plugin calls carry the caller as a JWT signed with the shared secret;
+	// the plugin service verifies it instead of trusting identity headers.
+	plugins.SetPrincipalAuth(plugins.NewPrincipalAuth(config.GetAppConfig().JWT.Secret))
+	if config.GetAppConfig().JWT.Secret == "" {
+		logger.Warn("JWT secret is not configured, plugin calls are treated as anonymous")
+	}
+
 	// Initialize database.
 	if _, err := database.InitDB(); err != nil {
 		return nil, err
@@ -164,7 +177,11 @@ func Init() (*App, error) {
 	})
 
 	// Initialize Redis (optional). Failure shouldn't block the app.
-	if _, err := database.InitRedis(); err != nil {
+	// Standalone mode uses the in-process progress broker and deduplicator instead.
+	standalone := config.GetAppConfig().Standalone.Enabled
+	if standalone {
+		logger.Info("Standalone mode enabled", zap.String("data_dir", config.GetAppConfig().Standalone.DataDir))
+	} else if _, err := database.InitRedis(); err != nil {
 		logger.Warn("Failed to initialize Redis", zap.Error(err))
 	} else {
 		app.cleanupTasks = append(app.cleanupTasks, func() error {
@@ -185,6 +202,16 @@ func Init() (*App, error) {
 		app.elasticsearchService = esService
 	}
 
+	// Initialize fulltext indexer selected by knowledge.search.provider (optional).
+	if _, err := middleware.NewFulltextIndexer(); err != nil {
+		logger.Warn("Failed to initialize fulltext indexer", zap.Error(err))
+	} else {
+		logger.Info("Fulltext indexer initialized", zap.String("provider", config.GetAppConfig().Knowledge.Search.Provider))
+		app.cleanupTasks = append(app.cleanupTasks, func() error {
+			return middleware.CloseFulltextIndexer()
+		})
+	}
+
 	// Initialize Milvus (optional). Failure shouldn't block the app.
 	if milvusService, err := middleware.NewMilvusService(); err != nil {
 		logger.Warn("Failed to initialize Milvus", zap.Error(err))
@@ -193,9 +220,272 @@ func Init() (*App, error) {
 		app.milvusService = milvusService
 	}
 
+	// Resolve the configured vector store. The DI provider runs on first use,
+	// after Milvus and the fulltext indexer above have been initialized, and
+	// the search engine shares the same instance.
+	var vectorStore knowledge.VectorStore
+	if err := container.Invoke(func(store knowledge.VectorStore) {
+		vectorStore = store
+	}); err != nil {
+		logger.Warn("Failed to initialize vector store", zap.Error(err))
+	}
+
+	// Resolve the shared embedding router. Search, ingestion, reindex,
+	// reconcile and import all embed with the model recorded per knowledge
+	// base, share one embedder and rate limiter per model, and see the same
+	// in-progress embedding migrations.
+	var embeddingRouter *knowledge.EmbeddingRouter
+	if err := container.Invoke(func(router *knowledge.EmbeddingRouter) {
+		embeddingRouter = router
+	}); err != nil {
+		logger.Warn("Failed to initialize embedding router", zap.Error(err))
+	}
+
+	// Wire document ingestion. New documents are chunked, written to the
+	// fulltext index, embedded in batches and written to the vector store; the scheduler falls back to the search
+	// engine's embedder for knowledge bases without a recorded model.
+	var embedScheduler *knowledge.EmbedScheduler
+	if err := container.Invoke(func(engine *knowledge.HybridSearchEngine, ds interfaces.DocumentServiceInterface) {
+		if engine != nil && engine.GetEmbedder() != nil {
+			embedScheduler = knowledge.NewEmbedScheduler(engine.GetEmbedder(), knowledge.EmbedSchedulerOptions{
+				Concurrency: config.GetAppConfig().Knowledge.MaxParallel,
+			})
+		}
+		documentService, ok := ds.(*services.DocumentService)
+		if !ok {
+			return
+		}
+		chunker := knowledge.NewDynamicChunker()
+		if size := config.GetAppConfig().Knowledge.ChunkSize; size > 0 {
+			chunker = knowledge.NewChunker(size, config.GetAppConfig().Knowledge.ChunkOverlap)
+		}
+		documentService.SetIngestionPipeline(chunker, embedScheduler, vectorStore)
+		documentService.SetFulltextIndexer(middleware.GetFulltextIndexer())
+		documentService.SetEmbeddingRouter(embeddingRouter)
+		documentService.SetKafkaService(middleware.NewKafkaService())
+	}); err != nil {
+		logger.Warn("Failed to configure document ingestion", zap.Error(err))
+	}
+
+	// Wire reindex backends. Vector reindex and embedding migration use the
+	// per-knowledge-base embedding router; embedders are created lazily and
+	// the ingestion scheduler is the fallback when no router is available.
+	if err := container.Invoke(func(rs *services.ReindexService) {
+		rs.SetBackends(middleware.GetFulltextIndexer(), vectorStore, embedScheduler)
+		rs.SetEmbeddingRouter(embeddingRouter)
+	}); err != nil {
+		logger.Warn("Failed to configure reindex service", zap.Error(err))
+	}
+
+	// Wire per-knowledge-base synonyms, stop words and dictionaries into the
+	// fulltext indexer, the vector query expansion and the lexicon management
+	// service. The IK remote dictionary is cluster-wide, so it is only served
+	// to Elasticsearch nodes holding the shared token.
+	if err := container.Invoke(func(ls *services.LexiconService, engine *knowledge.HybridSearchEngine, db interfaces.DatabaseInterface) {
+		store := knowledge.NewLexiconStore(db.GetDB(), 0)
+		indexer := middleware.GetFulltextIndexer()
+		if aware, ok := indexer.(knowledge.LexiconAware); ok {
+			aware.SetLexiconProvider(store)
+		}
+		engine.SetLexiconProvider(store)
+		ls.SetBackends(store, indexer)
+		ls.SetIKDictionaryToken(config.GetAppConfig().Knowledge.Search.Elasticsearch.IKDictionaryToken)
+	}); err != nil {
+		logger.Warn("Failed to configure lexicon service", zap.Error(err))
+	}
+
+	// Wire document processing progress. Events go to Redis streams when Redis
+	// is available so they survive restarts and are shared across replicas.
+	if err := container.Invoke(func(ps *services.ProgressService, ds interfaces.DocumentServiceInterface) {
+		if database.RedisClient != nil {
+			ps.SetBroker(knowledge.NewRedisProgressBroker(database.RedisClient, 0))
+		}
+		if documentService, ok := ds.(*services.DocumentService); ok {
+			documentService.SetProgressBroker(ps.Broker())
+		}
+	}); err != nil {
+		logger.Warn("Failed to configure document progress", zap.Error(err))
+	}
+
+	// Wire outbound webhooks. Deliveries are queued in the database and sent by
+	// a background dispatcher, so publishing never blocks document processing.
+	if err := container.Invoke(func(ws *services.WebhookService, kbs *services.KnowledgeBaseService, ds interfaces.DocumentServiceInterface, db interfaces.DatabaseInterface) {
+		dispatcher := webhook.NewDispatcher(webhook.NewGormStore(db.GetDB()), nil, webhook.Options{})
+		ws.SetDispatcher(dispatcher)
+		kbs.SetWebhookPublisher(dispatcher)
+		if documentService, ok := ds.(*services.DocumentService); ok {
+			documentService.SetWebhookPublisher(dispatcher)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go dispatcher.Run(ctx)
+		app.cleanupTasks = append(app.cleanupTasks, func() error {
+			cancel()
+			return nil
+		})
+	}); err != nil {
+		logger.Warn("Failed to configure webhooks", zap.Error(err))
+	}
+
+	// Wire document version history. Document updates go through the
+	// incremental updater, which snapshots each version before and after.
+	if err := container.Invoke(func(vs *services.DocumentVersionService, ss *services.SearchService, ds interfaces.DocumentServiceInterface, db interfaces.DatabaseInterface) {
+		chunker := knowledge.NewDynamicChunker()
+		updater := services.NewIncrementalUpdater(chunker, nil)
+		updater.SetVectorIndexing(embedScheduler, vectorStore)
+		updater.SetFulltextIndexer(middleware.GetFulltextIndexer())
+		updater.SetEmbeddingRouter(embeddingRouter)
+		updater.SetVersionRecorder(vs)
+		vs.SetUpdater(updater)
+		vs.SetChunker(chunker)
+		ss.SetVersionService(vs)
+		if documentService, ok := ds.(*services.DocumentService); ok {
+			documentService.SetVersionRecorder(vs)
+		}
+	}); err != nil {
+		logger.Warn("Failed to configure document versions", zap.Error(err))
+	}
+
+	// Wire the knowledge trash. Deleted documents and knowledge bases stay
+	// searchable-but-hidden until the retention window ends, then a background
+	// purger removes them from every store and retries stores that failed.
+	if err := container.Invoke(func(engine *knowledge.HybridSearchEngine, db interfaces.DatabaseInterface) {
+		trashCfg := config.GetAppConfig().Knowledge.Trash
+		if trashCfg.RetentionDays > 0 {
+			trash.SetRetention(time.Duration(trashCfg.RetentionDays) * 24 * time.Hour)
+		}
+		if engine != nil {
+			engine.SetDocumentFilter(trash.NewSearchFilter(db.GetDB()))
+		}
+
+		var backends []trash.Backend
+		if vectorStore != nil {
+			backends = append(backends, trash.NewVectorBackend(vectorStore))
+		}
+		if indexer := middleware.GetFulltextIndexer(); indexer != nil {
+			backends = append(backends, trash.NewFulltextBackend(indexer))
+		}
+		if database.RedisClient != nil {
+			if chunkStore, err := services.NewRedisChunkStore(); err != nil {
+				logger.Warn("Redis chunk store unavailable, chunk cache will not be purged", zap.Error(err))
+			} else {
+				backends = append(backends, trash.NewChunkCacheBackend(chunkStore))
+			}
+		}
+		if minioService := middleware.GetMinIOService(); minioService != nil && minioService.GetClient() != nil {
+			backends = append(backends, trash.NewObjectBackend(minioService.GetClient(), "aihub", config.GetAppConfig().Knowledge.Storage.Bucket))
+		} else if storageCfg := config.GetAppConfig().Knowledge.Storage; storageCfg.Provider == "local" && storageCfg.BasePath != "" {
+			backends = append(backends, trash.NewDirObjectBackend(storageCfg.BasePath))
+		}
+
+		purger := trash.NewPurger(trash.NewGormStore(db.GetDB()), backends, trash.Options{
+			PollInterval: time.Duration(trashCfg.PurgeIntervalSecond) * time.Second,
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		go purger.Run(ctx)
+		app.cleanupTasks = append(app.cleanupTasks, func() error {
+			cancel()
+			return nil
+		})
+	}); err != nil {
+		logger.Warn("Failed to configure knowledge trash", zap.Error(err))
+	}
+
+	// Wire the cross-store consistency check. Every knowledge base is compared
+	// against the fulltext index, vector store, chunk cache and object storage
+	// once per interval; cmd/reconcile runs the same check on demand.
+	if err := container.Invoke(func(rs *services.ReconcileService, db interfaces.DatabaseInterface) {
+		reconciler := knowledge.NewReconciler(db.GetDB(), middleware.GetFulltextIndexer(), vectorStore)
+		reconciler.SetEmbeddingRouter(embeddingRouter)
+		if database.RedisClient != nil {
+			if chunkStore, err := services.NewRedisChunkStore(); err != nil {
+				logger.Warn("Redis chunk store unavailable, chunk cache will not be reconciled", zap.Error(err))
+			} else {
+				reconciler.AddChecker(services.NewChunkCacheChecker(chunkStore))
+			}
+		}
+		if minioService := middleware.GetMinIOService(); minioService != nil && minioService.GetClient() != nil {
+			reconciler.AddChecker(services.NewObjectChecker(minioService.GetClient(), "aihub", config.GetAppConfig().Knowledge.Storage.Bucket))
+		}
+		rs.SetReconciler(reconciler)
+
+		reconcileCfg := config.GetAppConfig().Knowledge.Reconcile
+		if reconcileCfg.IntervalHour < 0 {
+			return
+		}
+		interval := 24 * time.Hour
+		if reconcileCfg.IntervalHour > 0 {
+			interval = time.Duration(reconcileCfg.IntervalHour) * time.Hour
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		go rs.RunSchedule(ctx, interval, reconcileCfg.Repair)
+		app.cleanupTasks = append(app.cleanupTasks, func() error {
+			cancel()
+			return nil
+		})
+	}); err != nil {
+		logger.Warn("Failed to configure reconcile service", zap.Error(err))
+	}
+
+	// Wire knowledge base export and import. Uploaded archives are kept in
+	// MinIO (or a local directory without it) and imported by a background
+	// runner that resumes from the last committed document.
+	if err := container.Invoke(func(ts *services.TransferService, db interfaces.DatabaseInterface) {
+		transferCfg := config.GetAppConfig().Knowledge.Transfer
+		var objects transfer.Objects
+		var archives transfer.ArchiveStore = transfer.NewDirArchiveStore(transferCfg.ArchiveDir)
+		if minioService := middleware.GetMinIOService(); minioService != nil && minioService.GetClient() != nil {
+			objects = transfer.NewMinIOObjects(minioService.GetClient(), "aihub")
+			archives = transfer.NewMinIOArchiveStore(minioService.GetClient(), "aihub")
+		} else if storageCfg := config.GetAppConfig().Knowledge.Storage; storageCfg.Provider == "local" && storageCfg.BasePath != "" {
+			objects = transfer.NewDirObjects(storageCfg.BasePath)
+		}
+
+		exporter := transfer.NewExporter(db.GetDB(), objects, vectorStore)
+		importer := transfer.NewImporter(db.GetDB(), archives, objects, middleware.GetFulltextIndexer(), vectorStore)
+		importer.SetEmbeddingRouter(embeddingRouter)
+		runner := transfer.NewRunner(transfer.NewGormStore(db.GetDB()), importer, transfer.Options{
+			PollInterval: time.Duration(transferCfg.PollIntervalSecond) * time.Second,
+		})
+		ts.SetTransfer(exporter, importer, archives, runner)
+		ts.SetMaxArchiveSize(int64(transferCfg.MaxArchiveSizeMB) << 20)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go runner.Run(ctx)
+		app.cleanupTasks = append(app.cleanupTasks, func() error {
+			cancel()
+			return nil
+		})
+	}); err != nil {
+		logger.Warn("Failed to configure knowledge transfer", zap.Error(err))
+	}
+
 	// Note: Component health checks are managed by Consul, not MiddlewareManager
 	// Consul provides service discovery and health monitoring for all registered services
 
+	// Standalone mode replaces Kafka with an in-process queue. Events still go
+	// through the outbox, so undelivered ones are relayed again after a restart.
+	if standalone {
+		kafkaCfg := config.GetAppConfig().Kafka
+		if err := kafka.InitMemoryQueue(kafkaCfg.Topic, kafkaCfg.GroupID, []string{kafkaCfg.Topic}); err != nil {
+			logger.Warn("Failed to initialize in-memory queue", zap.Error(err))
+		} else {
+			outbox.SetEnabled(true)
+			relay := outbox.NewRelay(outbox.NewGormStore(database.DB), kafka.GetProducer(), outbox.Options{})
+			consumer := kafka.GetConsumer()
+			consumer.SetDeduplicator(kafka.NewMemoryDeduplicator(0))
+			ctx, cancel := context.WithCancel(context.Background())
+			go relay.Run(ctx)
+			app.cleanupTasks = append(app.cleanupTasks, func() error {
+				cancel()
+				if err := consumer.Close(); err != nil {
+					return err
+				}
+				return kafka.GetProducer().Close()
+			})
+		}
+	}
+
 	// Initialize Kafka (optional). Failure shouldn't block the app.
 	if config.GetAppConfig().Kafka.Enabled {
 		if err := kafka.InitProducer(config.GetAppConfig().Kafka.Brokers, config.GetAppConfig().Kafka.Topic); err != nil {
@@ -210,6 +500,20 @@ func Init() (*App, error) {
 			})
 		}
 
+		// 事件先写入发件箱，由中继按聚合键顺序发布；生产者不可用时事件保留在发件箱，恢复后发布
+		outbox.SetEnabled(true)
+		if producer := kafka.GetProducer(); producer != nil && database.DB != nil {
+			relay := outbox.NewRelay(outbox.NewGormStore(database.DB), producer, outbox.Options{})
+			ctx, cancel := context.WithCancel(context.Background())
+			go relay.Run(ctx)
+			app.cleanupTasks = append(app.cleanupTasks, func() error {
+				cancel()
+				return nil
+			})
+		} else {
+			logger.Warn("Kafka producer unavailable, outbox events will be relayed after restart")
+		}
+
 		// 启动Kafka消费者
 		topics := []string{config.GetAppConfig().Kafka.Topic}
 		if err := kafka.InitConsumer(config.GetAppConfig().Kafka.Brokers, config.GetAppConfig().Kafka.GroupID, topics); err != nil {
@@ -217,6 +521,12 @@ func Init() (*App, error) {
 		} else {
 			consumer := kafka.GetConsumer()
 			if consumer != nil {
+				// 发件箱至少一次发布，按事件ID跳过重复消息
+				if database.RedisClient != nil {
+					consumer.SetDeduplicator(kafka.NewRedisDeduplicator(database.RedisClient, config.GetAppConfig().Kafka.GroupID, 0))
+				} else {
+					consumer.SetDeduplicator(kafka.NewMemoryDeduplicator(0))
+				}
 				// Consumer已经在InitConsumer中自动启动
 				app.cleanupTasks = append(app.cleanupTasks, func() error {
 					return consumer.Close()
@@ -258,6 +568,17 @@ func Init() (*App, error) {
 		logger.Warn("DashScope API key not configured, AI services will not be available")
 	}
 
+	// Query rewriting / multi-query / HyDE before retrieval. Stages are enabled
+	// per request or via the knowledge base config (query_transform).
+	if err := app.container.Invoke(func(engine *knowledge.HybridSearchEngine, db interfaces.DatabaseInterface) {
+		if engine != nil {
+			engine.SetQueryTransformer(knowledge.NewQueryTransformer(
+				dashscope.GetGlobalService(), config.GetAppConfig().AI.DefaultModel, db.GetDB()))
+		}
+	}); err != nil {
+		logger.Warn("Failed to configure query transformer", zap.Error(err))
+	}
+
 	// 启动数据库监控
 	err := app.container.Invoke(func(db interfaces.DatabaseInterface) {
 		if dbWrapper, ok := db.(*database.DatabaseWrapper); ok {
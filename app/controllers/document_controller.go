@@ -5,19 +5,20 @@ import (
 	"net/http"
 	"strconv"
 
+	apperrors "github.com/aihub/backend-go/internal/errors"
 	"github.com/aihub/backend-go/internal/services"
 )
 
 // DocumentController 文档控制器
 type DocumentController struct {
 	BaseController
-	docService *services.DocumentService
+	DocService *services.DocumentService
 }
 
 // NewDocumentController 创建文档控制器
 func NewDocumentController(docService *services.DocumentService) *DocumentController {
 	return &DocumentController{
-		docService: docService,
+		DocService: docService,
 	}
 }
 
@@ -33,7 +34,7 @@ func (c *DocumentController) GetDocuments() {
 		return
 	}
 
-	documents, err := c.docService.GetDocuments(uint(kbID), userID)
+	documents, err := c.DocService.GetDocuments(uint(kbID), userID)
 	if err != nil {
 		c.JSONError(http.StatusInternalServerError, "获取文档列表失败")
 		return
@@ -61,7 +62,7 @@ func (c *DocumentController) GetDocument() {
 		return
 	}
 
-	document, err := c.docService.GetDocumentDetail(uint(kbID), uint(docID), userID)
+	document, err := c.DocService.GetDocumentDetail(uint(kbID), uint(docID), userID)
 	if err != nil {
 		c.JSONError(http.StatusNotFound, "文档不存在")
 		return
@@ -70,6 +71,37 @@ func (c *DocumentController) GetDocument() {
 	c.JSONSuccess(document)
 }
 
+// DeleteDocument 将文档移入回收站
+func (c *DocumentController) DeleteDocument() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+
+	docID, ok := c.mustParseUintParam(":doc_id")
+	if !ok {
+		return
+	}
+
+	if err := c.DocService.TrashDocument(c.Ctx.Request.Context(), uint(kbID), uint(docID), userID); err != nil {
+		if appErr, ok := err.(*apperrors.AppError); ok {
+			c.JSONError(appErr.HTTPCode, appErr.Message)
+			return
+		}
+		c.JSONError(http.StatusInternalServerError, "删除文档失败")
+		return
+	}
+
+	c.JSONSuccess(map[string]interface{}{
+		"message": "文档已移入回收站",
+	})
+}
+
 // UploadDocuments 上传文档
 func (c *DocumentController) UploadDocuments() {
 	userID, ok := c.getAuthenticatedUserID()
@@ -89,7 +121,7 @@ func (c *DocumentController) UploadDocuments() {
 		return
 	}
 
-	documents, err := c.docService.UploadDocuments(uint(kbID), userID, req)
+	documents, err := c.DocService.UploadDocuments(uint(kbID), userID, req)
 	if err != nil {
 		c.JSONError(http.StatusInternalServerError, "上传文档失败")
 		return
@@ -112,7 +144,7 @@ func (c *DocumentController) ProcessDocuments() {
 		return
 	}
 
-	if err := c.docService.ProcessDocuments(uint(kbID), userID); err != nil {
+	if err := c.DocService.ProcessDocuments(uint(kbID), userID); err != nil {
 		c.JSONError(http.StatusInternalServerError, "处理文档失败")
 		return
 	}
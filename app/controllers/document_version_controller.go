@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	apperrors "github.com/aihub/backend-go/internal/errors"
+	"github.com/aihub/backend-go/internal/services"
+)
+
+// DocumentVersionController 文档版本历史控制器
+type DocumentVersionController struct {
+	BaseController
+	VersionService *services.DocumentVersionService
+}
+
+// NewDocumentVersionController 创建文档版本历史控制器
+func NewDocumentVersionController(versionService *services.DocumentVersionService) *DocumentVersionController {
+	return &DocumentVersionController{
+		VersionService: versionService,
+	}
+}
+
+// List 文档的版本列表
+func (c *DocumentVersionController) List() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+	docID, ok := c.mustParseUintParam(":doc_id")
+	if !ok {
+		return
+	}
+
+	versions, err := c.VersionService.ListVersions(c.Ctx.Request.Context(), uint(kbID), uint(docID), userID)
+	if err != nil {
+		c.versionError(err)
+		return
+	}
+	c.JSONSuccess(versions)
+}
+
+// Diff 两个版本之间的文本差异：from=1&to=3&context=3
+func (c *DocumentVersionController) Diff() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+	docID, ok := c.mustParseUintParam(":doc_id")
+	if !ok {
+		return
+	}
+	from, err := c.GetInt("from")
+	if err != nil || from <= 0 {
+		c.JSONError(http.StatusBadRequest, "from参数格式错误")
+		return
+	}
+	to, err := c.GetInt("to")
+	if err != nil || to <= 0 {
+		c.JSONError(http.StatusBadRequest, "to参数格式错误")
+		return
+	}
+	contextLines, _ := c.GetInt("context", -1)
+
+	diff, err := c.VersionService.Diff(c.Ctx.Request.Context(), uint(kbID), uint(docID), userID, from, to, contextLines)
+	if err != nil {
+		c.versionError(err)
+		return
+	}
+	c.JSONSuccess(diff)
+}
+
+// Restore 恢复到指定版本，生成一个内容与该版本相同的新版本
+func (c *DocumentVersionController) Restore() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+	docID, ok := c.mustParseUintParam(":doc_id")
+	if !ok {
+		return
+	}
+	version, ok := c.mustParseUintParam(":version")
+	if !ok {
+		return
+	}
+
+	restored, err := c.VersionService.Restore(c.Ctx.Request.Context(), uint(kbID), uint(docID), userID, int(version))
+	if err != nil {
+		c.versionError(err)
+		return
+	}
+	c.JSONSuccess(restored)
+}
+
+func (c *DocumentVersionController) versionError(err error) {
+	if appErr, ok := err.(*apperrors.AppError); ok {
+		c.JSONError(appErr.HTTPCode, appErr.Message)
+		return
+	}
+	c.JSONError(http.StatusInternalServerError, "文档版本操作失败")
+}
+
+// getAuthenticatedUserID 获取认证用户ID
+func (c *DocumentVersionController) getAuthenticatedUserID() (uint, bool) {
+	if userID, ok := c.Ctx.Input.GetData("user_id").(uint); ok && userID > 0 {
+		return userID, true
+	}
+	c.JSONError(http.StatusUnauthorized, "未授权访问")
+	return 0, false
+}
+
+// mustParseUintParam 解析URL参数为uint
+func (c *DocumentVersionController) mustParseUintParam(key string) (uint64, bool) {
+	value := c.GetString(key)
+	if value == "" {
+		c.JSONError(http.StatusBadRequest, "缺少必要参数")
+		return 0, false
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		c.JSONError(http.StatusBadRequest, "参数格式错误")
+		return 0, false
+	}
+
+	return id, true
+}
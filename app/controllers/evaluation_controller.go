@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	apperrors "github.com/aihub/backend-go/internal/errors"
+	"github.com/aihub/backend-go/internal/services"
+)
+
+// EvaluationController 检索效果评测控制器
+type EvaluationController struct {
+	BaseController
+	EvaluationService *services.EvaluationService
+}
+
+// NewEvaluationController 创建检索效果评测控制器
+func NewEvaluationController(evaluationService *services.EvaluationService) *EvaluationController {
+	return &EvaluationController{
+		EvaluationService: evaluationService,
+	}
+}
+
+// Run 运行评测并保存结果
+// 请求体：{"name":"baseline","queries_jsonl":"{\"query\":\"年假怎么申请\",\"relevant_document_ids\":[12]}\n...",
+// "configs":[{"name":"hybrid","mode":"hybrid","rerank":true}],"ks":[1,5,10]}
+func (c *EvaluationController) Run() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+
+	var req services.EvaluationRequest
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+		c.JSONError(http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	run, err := c.EvaluationService.RunEvaluation(c.Ctx.Request.Context(), uint(kbID), userID, req)
+	if err != nil {
+		c.evaluationError(err)
+		return
+	}
+	c.JSON(http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    run,
+	})
+}
+
+// List 评测记录列表，query_set_hash用于筛选同一查询集的记录
+func (c *EvaluationController) List() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+	limit, _ := c.GetInt("limit", 20)
+
+	runs, err := c.EvaluationService.ListRuns(c.Ctx.Request.Context(), uint(kbID), userID, c.GetString("query_set_hash"), limit)
+	if err != nil {
+		c.evaluationError(err)
+		return
+	}
+	c.JSONSuccess(runs)
+}
+
+// Get 获取单次评测记录
+func (c *EvaluationController) Get() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+	runID, ok := c.mustParseUintParam(":runId")
+	if !ok {
+		return
+	}
+
+	run, err := c.EvaluationService.GetRun(c.Ctx.Request.Context(), uint(kbID), uint(runID), userID)
+	if err != nil {
+		c.evaluationError(err)
+		return
+	}
+	c.JSONSuccess(run)
+}
+
+func (c *EvaluationController) evaluationError(err error) {
+	if appErr, ok := err.(*apperrors.AppError); ok {
+		c.JSONError(appErr.HTTPCode, appErr.Message)
+		return
+	}
+	c.JSONError(http.StatusInternalServerError, "评测失败")
+}
+
+// getAuthenticatedUserID 获取认证用户ID
+func (c *EvaluationController) getAuthenticatedUserID() (uint, bool) {
+	if userID, ok := c.Ctx.Input.GetData("user_id").(uint); ok && userID > 0 {
+		return userID, true
+	}
+	c.JSONError(http.StatusUnauthorized, "未授权访问")
+	return 0, false
+}
+
+// mustParseUintParam 解析URL参数为uint
+func (c *EvaluationController) mustParseUintParam(key string) (uint64, bool) {
+	value := c.GetString(key)
+	if value == "" {
+		c.JSONError(http.StatusBadRequest, "缺少必要参数")
+		return 0, false
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		c.JSONError(http.StatusBadRequest, "参数格式错误")
+		return 0, false
+	}
+
+	return id, true
+}
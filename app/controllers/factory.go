@@ -7,6 +7,7 @@ import (
 )
 
 // ControllerFactory 控制器工厂
+// Beego为每个请求新建控制器并只复制导出字段，注入的服务字段因此必须导出
 type ControllerFactory struct {
 	container *dig.Container
 }
@@ -94,3 +95,123 @@ func (f *ControllerFactory) CreateIntegrationController() (*IntegrationControlle
 }
 
 // 注意：旧的控制器工厂方法已被移除，使用新的专用控制器工厂方法
+
+// CreateReindexController 创建索引重建控制器
+func (f *ControllerFactory) CreateReindexController() (*ReindexController, error) {
+	var reindexService *services.ReindexService
+
+	err := f.container.Invoke(func(rs *services.ReindexService) {
+		reindexService = rs
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewReindexController(reindexService), nil
+}
+
+// CreateLexiconController 创建词库控制器
+func (f *ControllerFactory) CreateLexiconController() (*LexiconController, error) {
+	var lexiconService *services.LexiconService
+
+	err := f.container.Invoke(func(ls *services.LexiconService) {
+		lexiconService = ls
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewLexiconController(lexiconService), nil
+}
+
+// CreateEvaluationController 创建检索效果评测控制器
+func (f *ControllerFactory) CreateEvaluationController() (*EvaluationController, error) {
+	var evaluationService *services.EvaluationService
+
+	err := f.container.Invoke(func(es *services.EvaluationService) {
+		evaluationService = es
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEvaluationController(evaluationService), nil
+}
+
+// CreateProgressController 创建文档处理进度控制器
+func (f *ControllerFactory) CreateProgressController() (*ProgressController, error) {
+	var progressService *services.ProgressService
+
+	err := f.container.Invoke(func(ps *services.ProgressService) {
+		progressService = ps
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewProgressController(progressService), nil
+}
+
+// CreateWebhookController 创建知识库Webhook控制器
+func (f *ControllerFactory) CreateWebhookController() (*WebhookController, error) {
+	var webhookService *services.WebhookService
+
+	err := f.container.Invoke(func(ws *services.WebhookService) {
+		webhookService = ws
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWebhookController(webhookService), nil
+}
+
+// CreateDocumentVersionController 创建文档版本控制器
+func (f *ControllerFactory) CreateDocumentVersionController() (*DocumentVersionController, error) {
+	var versionService *services.DocumentVersionService
+
+	err := f.container.Invoke(func(vs *services.DocumentVersionService) {
+		versionService = vs
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDocumentVersionController(versionService), nil
+}
+
+// CreateTrashController 创建回收站控制器
+func (f *ControllerFactory) CreateTrashController() (*TrashController, error) {
+	var trashService *services.TrashService
+
+	err := f.container.Invoke(func(ts *services.TrashService) {
+		trashService = ts
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTrashController(trashService), nil
+}
+
+// CreateTransferController 创建知识库导出导入控制器
+func (f *ControllerFactory) CreateTransferController() (*TransferController, error) {
+	var transferService *services.TransferService
+
+	err := f.container.Invoke(func(ts *services.TransferService) {
+		transferService = ts
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTransferController(transferService), nil
+}
@@ -11,13 +11,13 @@ import (
 // IntegrationController 集成控制器
 type IntegrationController struct {
 	BaseController
-	integrationService *services.IntegrationService
+	IntegrationService *services.IntegrationService
 }
 
 // NewIntegrationController 创建集成控制器
 func NewIntegrationController(integrationService *services.IntegrationService) *IntegrationController {
 	return &IntegrationController{
-		integrationService: integrationService,
+		IntegrationService: integrationService,
 	}
 }
 
@@ -40,7 +40,7 @@ func (c *IntegrationController) SyncNotion() {
 		return
 	}
 
-	documents, err := c.integrationService.SyncNotionContent(uint(kbID), userID, req)
+	documents, err := c.IntegrationService.SyncNotionContent(uint(kbID), userID, req)
 	if err != nil {
 		c.JSONError(http.StatusInternalServerError, "同步Notion内容失败")
 		return
@@ -70,7 +70,7 @@ func (c *IntegrationController) SyncWeb() {
 		return
 	}
 
-	documents, err := c.integrationService.SyncWebContent(uint(kbID), userID, req)
+	documents, err := c.IntegrationService.SyncWebContent(uint(kbID), userID, req)
 	if err != nil {
 		c.JSONError(http.StatusInternalServerError, "同步Web内容失败")
 		return
@@ -83,7 +83,7 @@ func (c *IntegrationController) SyncWeb() {
 
 // CheckQwenHealth 检查Qwen服务健康状态
 func (c *IntegrationController) CheckQwenHealth() {
-	health := c.integrationService.CheckQwenHealth()
+	health := c.IntegrationService.CheckQwenHealth()
 	c.JSONSuccess(health)
 }
 
@@ -5,19 +5,20 @@ import (
 	"net/http"
 	"strconv"
 
+	apperrors "github.com/aihub/backend-go/internal/errors"
 	"github.com/aihub/backend-go/internal/services"
 )
 
 // KnowledgeBaseController 知识库控制器
 type KnowledgeBaseController struct {
 	BaseController
-	kbService *services.KnowledgeBaseService
+	KBService *services.KnowledgeBaseService
 }
 
 // NewKnowledgeBaseController 创建知识库控制器
 func NewKnowledgeBaseController(kbService *services.KnowledgeBaseService) *KnowledgeBaseController {
 	return &KnowledgeBaseController{
-		kbService: kbService,
+		KBService: kbService,
 	}
 }
 
@@ -32,7 +33,7 @@ func (c *KnowledgeBaseController) List() {
 	limit, _ := strconv.Atoi(c.GetString("limit", "20"))
 	search := c.GetString("search")
 
-	bases, total, err := c.kbService.GetKnowledgeBases(userID, page, limit, search)
+	bases, total, err := c.KBService.GetKnowledgeBases(userID, page, limit, search)
 	if err != nil {
 		c.JSONError(http.StatusInternalServerError, "获取知识库列表失败")
 		return
@@ -58,7 +59,7 @@ func (c *KnowledgeBaseController) Get() {
 		return
 	}
 
-	kb, err := c.kbService.GetKnowledgeBase(uint(kbID), userID)
+	kb, err := c.KBService.GetKnowledgeBase(uint(kbID), userID)
 	if err != nil {
 		c.JSONError(http.StatusNotFound, "知识库不存在")
 		return
@@ -81,7 +82,7 @@ func (c *KnowledgeBaseController) Create() {
 		return
 	}
 
-	kb, err := c.kbService.CreateKnowledgeBase(userID, req)
+	kb, err := c.KBService.CreateKnowledgeBase(userID, req)
 	if err != nil {
 		c.JSONError(http.StatusInternalServerError, "创建知识库失败")
 		return
@@ -109,7 +110,7 @@ func (c *KnowledgeBaseController) Update() {
 		return
 	}
 
-	kb, err := c.kbService.UpdateKnowledgeBase(uint(kbID), userID, req)
+	kb, err := c.KBService.UpdateKnowledgeBase(uint(kbID), userID, req)
 	if err != nil {
 		c.JSONError(http.StatusInternalServerError, "更新知识库失败")
 		return
@@ -118,7 +119,7 @@ func (c *KnowledgeBaseController) Update() {
 	c.JSONSuccess(kb)
 }
 
-// Delete 删除知识库
+// Delete 将知识库移入回收站
 func (c *KnowledgeBaseController) Delete() {
 	userID, ok := c.getAuthenticatedUserID()
 	if !ok {
@@ -130,13 +131,17 @@ func (c *KnowledgeBaseController) Delete() {
 		return
 	}
 
-	if err := c.kbService.DeleteKnowledgeBase(uint(kbID), userID); err != nil {
+	if err := c.KBService.DeleteKnowledgeBase(uint(kbID), userID); err != nil {
+		if appErr, ok := err.(*apperrors.AppError); ok {
+			c.JSONError(appErr.HTTPCode, appErr.Message)
+			return
+		}
 		c.JSONError(http.StatusInternalServerError, "删除知识库失败")
 		return
 	}
 
 	c.JSONSuccess(map[string]interface{}{
-		"message": "知识库已删除",
+		"message": "知识库已移入回收站",
 	})
 }
 
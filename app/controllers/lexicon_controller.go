@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	apperrors "github.com/aihub/backend-go/internal/errors"
+	"github.com/aihub/backend-go/internal/models"
+	"github.com/aihub/backend-go/internal/services"
+)
+
+// LexiconController 知识库同义词、停用词与自定义词典控制器
+type LexiconController struct {
+	BaseController
+	LexiconService *services.LexiconService
+}
+
+// NewLexiconController 创建词库控制器
+func NewLexiconController(lexiconService *services.LexiconService) *LexiconController {
+	return &LexiconController{
+		LexiconService: lexiconService,
+	}
+}
+
+// Get 获取知识库词库
+func (c *LexiconController) Get() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+
+	lexicon, err := c.LexiconService.GetLexicon(c.Ctx.Request.Context(), uint(kbID), userID)
+	if err != nil {
+		c.lexiconError(err)
+		return
+	}
+	c.JSONSuccess(lexicon)
+}
+
+// CreateSynonym 新增同义词组，请求体：{"terms":["报销","报账"]}
+func (c *LexiconController) CreateSynonym() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+	terms, ok := c.parseTerms()
+	if !ok {
+		return
+	}
+
+	synonym, err := c.LexiconService.CreateSynonym(c.Ctx.Request.Context(), uint(kbID), userID, terms)
+	if err != nil {
+		c.lexiconError(err)
+		return
+	}
+	c.JSON(http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    synonym,
+	})
+}
+
+// UpdateSynonym 修改同义词组
+func (c *LexiconController) UpdateSynonym() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+	synonymID, ok := c.mustParseUintParam(":synonymId")
+	if !ok {
+		return
+	}
+	terms, ok := c.parseTerms()
+	if !ok {
+		return
+	}
+
+	synonym, err := c.LexiconService.UpdateSynonym(c.Ctx.Request.Context(), uint(kbID), userID, uint(synonymID), terms)
+	if err != nil {
+		c.lexiconError(err)
+		return
+	}
+	c.JSONSuccess(synonym)
+}
+
+// DeleteSynonym 删除同义词组
+func (c *LexiconController) DeleteSynonym() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+	synonymID, ok := c.mustParseUintParam(":synonymId")
+	if !ok {
+		return
+	}
+
+	if err := c.LexiconService.DeleteSynonym(c.Ctx.Request.Context(), uint(kbID), userID, uint(synonymID)); err != nil {
+		c.lexiconError(err)
+		return
+	}
+	c.JSONSuccess(map[string]interface{}{"deleted": true})
+}
+
+// ReplaceStopWords 整体替换停用词，请求体：{"words":["的","了"]}
+func (c *LexiconController) ReplaceStopWords() {
+	c.replaceWords(models.LexiconKindStopWord)
+}
+
+// ReplaceDictionary 整体替换自定义词典，请求体：{"words":["数据中台"]}
+// 词典变更约一分钟后自动重建全文索引
+func (c *LexiconController) ReplaceDictionary() {
+	c.replaceWords(models.LexiconKindDictionary)
+}
+
+// IKDictionary IK分词插件的远程扩展词典（ext_dict远程地址），插件通过ETag判断是否需要重新加载
+// IK插件只能配置URL，密钥通过token查询参数传递，如 /lexicon/ik/ext.dic?token=xxx
+func (c *LexiconController) IKDictionary() {
+	token := c.GetString("token")
+	if token == "" {
+		token = strings.TrimPrefix(c.Ctx.Input.Header("Authorization"), "Bearer ")
+	}
+	text, err := c.LexiconService.DictionaryText(c.Ctx.Request.Context(), token)
+	if err != nil {
+		c.lexiconError(err)
+		return
+	}
+
+	sum := sha1.Sum([]byte(text))
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	c.Ctx.Output.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Ctx.Output.Header("ETag", etag)
+	if c.Ctx.Input.Header("If-None-Match") == etag {
+		c.Ctx.Output.SetStatus(http.StatusNotModified)
+		return
+	}
+	if c.Ctx.Input.Method() == http.MethodHead {
+		c.Ctx.Output.SetStatus(http.StatusOK)
+		return
+	}
+	c.Ctx.Output.Body([]byte(text))
+}
+
+func (c *LexiconController) replaceWords(kind string) {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Words []string `json:"words"`
+	}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+		c.JSONError(http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	words, err := c.LexiconService.ReplaceWords(c.Ctx.Request.Context(), uint(kbID), userID, kind, req.Words)
+	if err != nil {
+		c.lexiconError(err)
+		return
+	}
+	c.JSONSuccess(map[string]interface{}{"words": words})
+}
+
+func (c *LexiconController) parseTerms() ([]string, bool) {
+	var req struct {
+		Terms []string `json:"terms"`
+	}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+		c.JSONError(http.StatusBadRequest, "请求参数错误")
+		return nil, false
+	}
+	return req.Terms, true
+}
+
+func (c *LexiconController) lexiconError(err error) {
+	if appErr, ok := err.(*apperrors.AppError); ok {
+		c.JSONError(appErr.HTTPCode, appErr.Message)
+		return
+	}
+	c.JSONError(http.StatusInternalServerError, "词库操作失败")
+}
+
+// getAuthenticatedUserID 获取认证用户ID
+func (c *LexiconController) getAuthenticatedUserID() (uint, bool) {
+	if userID, ok := c.Ctx.Input.GetData("user_id").(uint); ok && userID > 0 {
+		return userID, true
+	}
+	c.JSONError(http.StatusUnauthorized, "未授权访问")
+	return 0, false
+}
+
+// mustParseUintParam 解析URL参数为uint
+func (c *LexiconController) mustParseUintParam(key string) (uint64, bool) {
+	value := c.GetString(key)
+	if value == "" {
+		c.JSONError(http.StatusBadRequest, "缺少必要参数")
+		return 0, false
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		c.JSONError(http.StatusBadRequest, "参数格式错误")
+		return 0, false
+	}
+
+	return id, true
+}
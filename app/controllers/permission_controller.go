@@ -11,13 +11,13 @@ import (
 // PermissionController 权限控制器
 type PermissionController struct {
 	BaseController
-	permService *services.PermissionService
+	PermService *services.PermissionService
 }
 
 // NewPermissionController 创建权限控制器
 func NewPermissionController(permService *services.PermissionService) *PermissionController {
 	return &PermissionController{
-		permService: permService,
+		PermService: permService,
 	}
 }
 
@@ -33,7 +33,7 @@ func (c *PermissionController) GetPermissions() {
 		return
 	}
 
-	permissions, err := c.permService.GetPermissions(uint(kbID), userID)
+	permissions, err := c.PermService.GetPermissions(uint(kbID), userID)
 	if err != nil {
 		c.JSONError(http.StatusInternalServerError, "获取权限失败")
 		return
@@ -61,7 +61,7 @@ func (c *PermissionController) UpdatePermissions() {
 		return
 	}
 
-	if err := c.permService.UpdatePermissions(uint(kbID), userID, req); err != nil {
+	if err := c.PermService.UpdatePermissions(uint(kbID), userID, req); err != nil {
 		c.JSONError(http.StatusInternalServerError, "更新权限失败")
 		return
 	}
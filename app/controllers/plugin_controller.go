@@ -7,7 +7,6 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"strconv"
 
 	"github.com/aihub/backend-go/internal/plugins"
 )
@@ -24,23 +23,31 @@ func (c *PluginController) Prepare() {
 		pluginServiceURL = "http://plugin-service:8002"
 	}
 
-	userID, _ := c.getAuthenticatedUserID()
-	c.pluginClient = plugins.NewPluginServiceClient(pluginServiceURL, userID)
+	c.pluginClient = plugins.NewPluginServiceClient(pluginServiceURL, c.getPrincipal())
 }
 
-// getAuthenticatedUserID 获取认证用户ID（简化实现，从header获取）
-func (c *PluginController) getAuthenticatedUserID() (uint, bool) {
-	userIDStr := c.Ctx.Input.Header("X-User-Id")
-	if userIDStr == "" {
-		userIDStr = c.GetString("user_id", "1") // 默认用户ID，用于测试
+// getPrincipal 从认证中间件校验过的JWT声明构造调用方身份，转发给插件服务
+func (c *PluginController) getPrincipal() plugins.Principal {
+	principal := plugins.Principal{}
+	if userID, ok := c.Ctx.Input.GetData("user_id").(uint); ok {
+		principal.UserID = userID
 	}
-	
-	userID, err := strconv.ParseUint(userIDStr, 10, 32)
-	if err != nil {
-		// 如果解析失败，返回默认值1（用于测试）
-		return 1, true
+	if orgID, ok := c.Ctx.Input.GetData("org_id").(uint); ok {
+		principal.OrgID = orgID
+	}
+	if roles, ok := c.Ctx.Input.GetData("roles").([]string); ok {
+		principal.Roles = roles
+	}
+	return principal
+}
+
+// getAuthenticatedUserID 获取认证用户ID
+func (c *PluginController) getAuthenticatedUserID() (uint, bool) {
+	if userID, ok := c.Ctx.Input.GetData("user_id").(uint); ok && userID > 0 {
+		return userID, true
 	}
-	return uint(userID), true
+	c.JSONError(http.StatusUnauthorized, "未授权访问")
+	return 0, false
 }
 
 // POST /api/plugins/upload - 上传插件（转发到插件服务）
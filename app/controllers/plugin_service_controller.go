@@ -4,14 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/aihub/backend-go/internal/middleware"
@@ -19,14 +18,19 @@ import (
 )
 
 // PluginServiceController 插件服务控制器（独立微服务）
+// PluginMgr与MinioSvc由路由注入进程内共享的实例，使HTTP与gRPC接口共用插件状态和调用限制
 type PluginServiceController struct {
 	BaseController
-	pluginMgr *plugins.PluginManager
-	minioSvc  *middleware.MinIOService
+	PluginMgr *plugins.PluginManager
+	MinioSvc  *middleware.MinIOService
 }
 
 func (c *PluginServiceController) Prepare() {
-	// 创建PluginManager
+	if c.PluginMgr != nil {
+		return
+	}
+
+	// 未注入时创建PluginManager
 	cfg := plugins.ManagerConfig{
 		PluginDir:    "./tmp/plugins", // 临时目录，实际存储到MinIO
 		TempDir:      "./tmp/plugins/extract",
@@ -34,38 +38,69 @@ func (c *PluginServiceController) Prepare() {
 		AutoLoad:     false,
 	}
 	var err error
-	c.pluginMgr, err = plugins.NewPluginManager(cfg)
+	c.PluginMgr, err = plugins.NewPluginManager(cfg)
 	if err != nil {
 		log.Printf("[plugin-service] Failed to create plugin manager: %v", err)
 	}
 
 	// 初始化MinIO服务
-	c.minioSvc, err = middleware.NewMinIOService()
+	c.MinioSvc, err = middleware.NewMinIOService()
 	if err != nil {
 		log.Printf("[plugin-service] Failed to initialize MinIO: %v", err)
 	}
 }
 
-// getAuthenticatedUserID 获取认证用户ID
-func (c *PluginServiceController) getAuthenticatedUserID() (uint, bool) {
-	userIDStr := c.Ctx.Input.Header("X-User-Id")
-	if userIDStr == "" {
-		userIDStr = c.GetString("user_id", "1")
+// getPrincipal 从签名令牌获取调用方身份（用户、组织与角色）
+// 请求头中的用户、组织与角色不可信，只以Authorization中的令牌为准；未携带令牌时视为匿名的内部调用
+func (c *PluginServiceController) getPrincipal() (plugins.Principal, bool) {
+	principal, err := plugins.VerifyAuthorization(c.Ctx.Input.Header("Authorization"))
+	if err != nil {
+		c.JSONError(http.StatusUnauthorized, fmt.Sprintf("身份验证失败: %v", err))
+		return plugins.Principal{}, false
+	}
+	return principal, true
+}
+
+// authzError 输出授权失败的响应
+func (c *PluginServiceController) authzError(err error) {
+	switch {
+	case errors.Is(err, plugins.ErrPluginAccessDenied):
+		c.JSONError(http.StatusForbidden, fmt.Sprintf("无权访问插件: %v", err))
+	case errors.Is(err, plugins.ErrPluginDisabled):
+		c.JSONError(http.StatusForbidden, fmt.Sprintf("插件已禁用: %v", err))
+	default:
+		c.JSONError(http.StatusNotFound, fmt.Sprintf("插件不存在: %v", err))
 	}
+}
 
-	userID, err := strconv.ParseUint(userIDStr, 10, 32)
-	if err != nil {
-		return 1, true
+// pluginCallError 输出插件调用失败的响应，并发受限与插件超时使用对应的状态码
+func (c *PluginServiceController) pluginCallError(action string, err error) {
+	switch {
+	case errors.Is(err, plugins.ErrPluginBusy):
+		c.JSONError(http.StatusTooManyRequests, fmt.Sprintf("%s: %v", action, err))
+	case errors.Is(err, plugins.ErrPluginCallTimeout):
+		c.JSONError(http.StatusGatewayTimeout, fmt.Sprintf("%s: %v", action, err))
+	default:
+		c.JSONError(http.StatusInternalServerError, fmt.Sprintf("%s: %v", action, err))
 	}
-	return uint(userID), true
 }
 
 // POST /api/plugins/upload - 上传插件到MinIO并加载
 func (c *PluginServiceController) Upload() {
-	userID, ok := c.getAuthenticatedUserID()
+	principal, ok := c.getPrincipal()
 	if !ok {
 		return
 	}
+	userID := principal.UserID
+
+	visibility := plugins.DefaultVisibility(principal)
+	if value := c.GetString("visibility"); value != "" {
+		var err error
+		if visibility, err = plugins.ParseVisibility(value); err != nil {
+			c.JSONError(http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 
 	// 获取上传的文件
 	file, header, err := c.GetFile("file")
@@ -75,6 +110,9 @@ func (c *PluginServiceController) Upload() {
 	}
 	defer file.Close()
 
+	// 文件名由客户端提供，只保留最后一级，避免写出临时目录
+	header.Filename = filepath.Base(header.Filename)
+
 	// 检查文件扩展名
 	if filepath.Ext(header.Filename) != ".xpkg" {
 		c.JSONError(http.StatusBadRequest, "只支持.xpkg格式的插件文件")
@@ -129,24 +167,43 @@ func (c *PluginServiceController) Upload() {
 
 	pluginID := metadata.ID
 
-	// 上传到MinIO
-	if c.minioSvc != nil {
-		objectKey := fmt.Sprintf("plugins/%s/%s", pluginID, header.Filename)
+	// 先检查权限，避免覆盖他人插件的存储文件
+	if c.PluginMgr != nil {
+		if err := c.PluginMgr.AuthorizeInstall(principal, pluginID, visibility); err != nil {
+			if errors.Is(err, plugins.ErrPluginAccessDenied) {
+				c.JSONError(http.StatusForbidden, err.Error())
+				return
+			}
+			c.JSONError(http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	// 上传到MinIO（按版本存储，保留历史版本用于回滚）
+	if c.MinioSvc != nil {
+		objectKey := fmt.Sprintf("plugins/%s/%s/%s", pluginID, metadata.Version, header.Filename)
 		reader := bytes.NewReader(fileBytes)
-		if err := c.minioSvc.UploadFile("plugins", objectKey, reader, int64(len(fileBytes)), "application/zip"); err != nil {
+		if err := c.MinioSvc.UploadFile("plugins", objectKey, reader, int64(len(fileBytes)), "application/zip"); err != nil {
 			c.JSONError(http.StatusInternalServerError, fmt.Sprintf("上传到MinIO失败: %v", err))
 			return
 		}
 		log.Printf("[plugin-service] Plugin uploaded to MinIO: %s", objectKey)
 	}
 
-	// 加载插件
-	if c.pluginMgr != nil {
-		if err := c.pluginMgr.LoadPlugin(tempPath); err != nil {
-			errMsg := err.Error()
-			if strings.Contains(errMsg, "plugin: not implemented") ||
-				strings.Contains(errMsg, "cannot load") ||
-				strings.Contains(errMsg, "incompatible") {
+	// 安装新版本并热切换（已有版本时不中断服务）
+	var previousVersion string
+	if c.PluginMgr != nil {
+		previousVersion = c.PluginMgr.ActiveVersion(pluginID)
+		if _, err := c.PluginMgr.InstallPluginAs(principal, tempPath, header.Filename, visibility); err != nil {
+			if errors.Is(err, plugins.ErrPluginAccessDenied) {
+				c.JSONError(http.StatusForbidden, err.Error())
+				return
+			}
+			if errors.Is(err, plugins.ErrPluginVersionExists) {
+				c.JSONError(http.StatusConflict, fmt.Sprintf("插件版本已存在: %v", err))
+				return
+			}
+			if errors.Is(err, plugins.ErrPluginIncompatible) {
 				c.JSONError(http.StatusBadRequest, fmt.Sprintf(
 					"插件平台不兼容: %v\n\n"+
 						"原因: 插件是在不同的操作系统/架构上编译的\n"+
@@ -157,31 +214,35 @@ func (c *PluginServiceController) Upload() {
 			c.JSONError(http.StatusBadRequest, fmt.Sprintf("加载插件失败: %v", err))
 			return
 		}
-		log.Printf("[plugin-service] Plugin loaded by user %d: %s", userID, header.Filename)
+		log.Printf("[plugin-service] Plugin loaded by user %d: %s (version %s)", userID, header.Filename, metadata.Version)
 	}
 
 	c.JSONSuccess(map[string]interface{}{
-		"plugin_id": pluginID,
-		"filename":  header.Filename,
-		"message":   "插件上传并加载成功",
+		"plugin_id":        pluginID,
+		"filename":         header.Filename,
+		"version":          metadata.Version,
+		"previous_version": previousVersion,
+		"visibility":       visibility,
+		"message":          "插件上传并加载成功",
 	})
 }
 
 // GET /api/plugins - 列出所有插件
 func (c *PluginServiceController) List() {
-	userID, ok := c.getAuthenticatedUserID()
+	principal, ok := c.getPrincipal()
 	if !ok {
 		return
 	}
+	userID := principal.UserID
 
-	if c.pluginMgr == nil {
+	if c.PluginMgr == nil {
 		c.JSONSuccess(map[string]interface{}{
 			"plugins": []interface{}{},
 		})
 		return
 	}
 
-	entries := c.pluginMgr.ListPlugins()
+	entries := c.PluginMgr.ListPluginsFor(principal)
 	pluginList := make([]map[string]interface{}, 0, len(entries))
 
 	for _, entry := range entries {
@@ -198,6 +259,12 @@ func (c *PluginServiceController) List() {
 			"capabilities": make([]map[string]interface{}, 0),
 		}
 
+		tenancy := c.PluginMgr.Tenancy(meta.ID)
+		pluginInfo["owner_id"] = tenancy.OwnerID
+		pluginInfo["org_id"] = tenancy.OrgID
+		pluginInfo["visibility"] = tenancy.Visibility
+		pluginInfo["enabled"] = c.PluginMgr.IsEnabledFor(principal, meta.ID)
+
 		// 添加能力信息
 		for _, cap := range meta.Capabilities {
 			pluginInfo["capabilities"] = append(pluginInfo["capabilities"].([]map[string]interface{}), map[string]interface{}{
@@ -217,10 +284,11 @@ func (c *PluginServiceController) List() {
 
 // POST /api/plugins/:id/models - 获取插件支持的模型
 func (c *PluginServiceController) GetModels() {
-	userID, ok := c.getAuthenticatedUserID()
+	principal, ok := c.getPrincipal()
 	if !ok {
 		return
 	}
+	userID := principal.UserID
 
 	pluginID := c.Ctx.Input.Param(":id")
 	apiKey := c.GetString("api_key")
@@ -230,12 +298,17 @@ func (c *PluginServiceController) GetModels() {
 		return
 	}
 
-	if c.pluginMgr == nil {
+	if c.PluginMgr == nil {
 		c.JSONError(http.StatusInternalServerError, "插件管理器未初始化")
 		return
 	}
 
-	plugin, err := c.pluginMgr.GetPlugin(pluginID)
+	if err := c.PluginMgr.AuthorizeView(principal, pluginID); err != nil {
+		c.authzError(err)
+		return
+	}
+
+	plugin, err := c.PluginMgr.GetPlugin(pluginID)
 	if err != nil {
 		c.JSONError(http.StatusNotFound, fmt.Sprintf("插件不存在: %v", err))
 		return
@@ -244,15 +317,11 @@ func (c *PluginServiceController) GetModels() {
 	// 获取模型列表
 	models := make(map[string][]string)
 
-	// 如果没有提供apiKey，尝试从插件配置中获取
+	// 如果没有提供apiKey，尝试从插件配置（含用户/组织覆盖）中获取
 	if apiKey == "" {
-		entries := c.pluginMgr.ListPlugins()
-		for _, e := range entries {
-			if e.Plugin.Metadata().ID == pluginID {
-				if apiKeyVal, ok := e.Config.Settings["api_key"].(string); ok && apiKeyVal != "" {
-					apiKey = apiKeyVal
-					break
-				}
+		if config, err := c.PluginMgr.EffectiveConfig(principal, pluginID); err == nil {
+			if apiKeyVal, ok := config.Settings["api_key"].(string); ok && apiKeyVal != "" {
+				apiKey = apiKeyVal
 			}
 		}
 	}
@@ -325,10 +394,11 @@ func (c *PluginServiceController) GetModels() {
 
 // POST /api/plugins/:id/enable - 启用插件
 func (c *PluginServiceController) Enable() {
-	userID, ok := c.getAuthenticatedUserID()
+	principal, ok := c.getPrincipal()
 	if !ok {
 		return
 	}
+	userID := principal.UserID
 
 	pluginID := c.Ctx.Input.Param(":id")
 	if pluginID == "" {
@@ -336,28 +406,41 @@ func (c *PluginServiceController) Enable() {
 		return
 	}
 
-	if c.pluginMgr == nil {
+	if c.PluginMgr == nil {
 		c.JSONError(http.StatusInternalServerError, "插件管理器未初始化")
 		return
 	}
 
-	if err := c.pluginMgr.EnablePlugin(pluginID); err != nil {
+	scope, err := plugins.ParseOverlayScope(c.GetString("scope"))
+	if err != nil {
+		c.JSONError(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	scope, err = c.PluginMgr.SetEnabledFor(principal, pluginID, scope, true)
+	if err != nil {
+		if errors.Is(err, plugins.ErrPluginAccessDenied) {
+			c.JSONError(http.StatusForbidden, err.Error())
+			return
+		}
 		c.JSONError(http.StatusBadRequest, fmt.Sprintf("启用插件失败: %v", err))
 		return
 	}
 
-	log.Printf("[plugin-service] User %d enabled plugin %s", userID, pluginID)
+	log.Printf("[plugin-service] User %d enabled plugin %s (scope %s)", userID, pluginID, scope)
 	c.JSONSuccess(map[string]interface{}{
+		"scope":   scope,
 		"message": "插件已启用",
 	})
 }
 
 // POST /api/plugins/:id/disable - 禁用插件
 func (c *PluginServiceController) Disable() {
-	userID, ok := c.getAuthenticatedUserID()
+	principal, ok := c.getPrincipal()
 	if !ok {
 		return
 	}
+	userID := principal.UserID
 
 	pluginID := c.Ctx.Input.Param(":id")
 	if pluginID == "" {
@@ -365,28 +448,41 @@ func (c *PluginServiceController) Disable() {
 		return
 	}
 
-	if c.pluginMgr == nil {
+	if c.PluginMgr == nil {
 		c.JSONError(http.StatusInternalServerError, "插件管理器未初始化")
 		return
 	}
 
-	if err := c.pluginMgr.DisablePlugin(pluginID); err != nil {
+	scope, err := plugins.ParseOverlayScope(c.GetString("scope"))
+	if err != nil {
+		c.JSONError(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	scope, err = c.PluginMgr.SetEnabledFor(principal, pluginID, scope, false)
+	if err != nil {
+		if errors.Is(err, plugins.ErrPluginAccessDenied) {
+			c.JSONError(http.StatusForbidden, err.Error())
+			return
+		}
 		c.JSONError(http.StatusBadRequest, fmt.Sprintf("禁用插件失败: %v", err))
 		return
 	}
 
-	log.Printf("[plugin-service] User %d disabled plugin %s", userID, pluginID)
+	log.Printf("[plugin-service] User %d disabled plugin %s (scope %s)", userID, pluginID, scope)
 	c.JSONSuccess(map[string]interface{}{
+		"scope":   scope,
 		"message": "插件已禁用",
 	})
 }
 
 // DELETE /api/plugins/:id - 删除插件
 func (c *PluginServiceController) Delete() {
-	userID, ok := c.getAuthenticatedUserID()
+	principal, ok := c.getPrincipal()
 	if !ok {
 		return
 	}
+	userID := principal.UserID
 
 	pluginID := c.Ctx.Input.Param(":id")
 	if pluginID == "" {
@@ -394,24 +490,29 @@ func (c *PluginServiceController) Delete() {
 		return
 	}
 
-	if c.pluginMgr == nil {
+	if c.PluginMgr == nil {
 		c.JSONError(http.StatusInternalServerError, "插件管理器未初始化")
 		return
 	}
 
+	if err := c.PluginMgr.AuthorizeManage(principal, pluginID); err != nil {
+		c.authzError(err)
+		return
+	}
+
 	// 卸载插件
-	if err := c.pluginMgr.UnloadPlugin(pluginID); err != nil {
+	if err := c.PluginMgr.UnloadPlugin(pluginID); err != nil {
 		c.JSONError(http.StatusBadRequest, fmt.Sprintf("卸载插件失败: %v", err))
 		return
 	}
 
 	// 从MinIO删除
-	if c.minioSvc != nil {
+	if c.MinioSvc != nil {
 		// 列出所有该插件的文件
-		files, err := c.minioSvc.ListFiles("plugins", fmt.Sprintf("plugins/%s/", pluginID))
+		files, err := c.MinioSvc.ListFiles("plugins", fmt.Sprintf("plugins/%s/", pluginID))
 		if err == nil {
 			for _, file := range files {
-				if err := c.minioSvc.DeleteFile("plugins", file); err != nil {
+				if err := c.MinioSvc.DeleteFile("plugins", file); err != nil {
 					log.Printf("[plugin-service] Failed to delete file from MinIO: %s, error: %v", file, err)
 				}
 			}
@@ -424,12 +525,133 @@ func (c *PluginServiceController) Delete() {
 	})
 }
 
+// GET /api/plugins/:id/versions - 列出插件的已安装版本
+func (c *PluginServiceController) ListVersions() {
+	principal, ok := c.getPrincipal()
+	if !ok {
+		return
+	}
+	userID := principal.UserID
+
+	pluginID := c.Ctx.Input.Param(":id")
+	if pluginID == "" {
+		c.JSONError(http.StatusBadRequest, "插件ID不能为空")
+		return
+	}
+
+	if c.PluginMgr == nil {
+		c.JSONError(http.StatusInternalServerError, "插件管理器未初始化")
+		return
+	}
+
+	if err := c.PluginMgr.AuthorizeView(principal, pluginID); err != nil {
+		c.authzError(err)
+		return
+	}
+
+	versions, err := c.PluginMgr.ListPluginVersions(pluginID)
+	if err != nil {
+		c.JSONError(http.StatusNotFound, fmt.Sprintf("插件不存在: %v", err))
+		return
+	}
+
+	log.Printf("[plugin-service] User %d listed versions of plugin %s", userID, pluginID)
+	c.JSONSuccess(map[string]interface{}{
+		"plugin_id":      pluginID,
+		"active_version": c.PluginMgr.ActiveVersion(pluginID),
+		"versions":       versions,
+	})
+}
+
+// POST /api/plugins/:id/versions/:version/activate - 切换插件的当前版本
+func (c *PluginServiceController) ActivateVersion() {
+	principal, ok := c.getPrincipal()
+	if !ok {
+		return
+	}
+	userID := principal.UserID
+
+	pluginID := c.Ctx.Input.Param(":id")
+	version := c.Ctx.Input.Param(":version")
+	if pluginID == "" || version == "" {
+		c.JSONError(http.StatusBadRequest, "插件ID和版本号不能为空")
+		return
+	}
+
+	if c.PluginMgr == nil {
+		c.JSONError(http.StatusInternalServerError, "插件管理器未初始化")
+		return
+	}
+
+	if err := c.PluginMgr.AuthorizeManage(principal, pluginID); err != nil {
+		c.authzError(err)
+		return
+	}
+
+	previousVersion := c.PluginMgr.ActiveVersion(pluginID)
+	if err := c.PluginMgr.ActivateVersion(pluginID, version); err != nil {
+		if errors.Is(err, plugins.ErrPluginVersionNotFound) {
+			c.JSONError(http.StatusNotFound, fmt.Sprintf("插件版本不存在: %v", err))
+			return
+		}
+		c.JSONError(http.StatusBadRequest, fmt.Sprintf("切换版本失败: %v", err))
+		return
+	}
+
+	log.Printf("[plugin-service] User %d activated plugin %s version %s", userID, pluginID, version)
+	c.JSONSuccess(map[string]interface{}{
+		"version":          version,
+		"previous_version": previousVersion,
+		"message":          "插件版本已切换",
+	})
+}
+
+// POST /api/plugins/:id/rollback - 回滚到上一个版本
+func (c *PluginServiceController) Rollback() {
+	principal, ok := c.getPrincipal()
+	if !ok {
+		return
+	}
+	userID := principal.UserID
+
+	pluginID := c.Ctx.Input.Param(":id")
+	if pluginID == "" {
+		c.JSONError(http.StatusBadRequest, "插件ID不能为空")
+		return
+	}
+
+	if c.PluginMgr == nil {
+		c.JSONError(http.StatusInternalServerError, "插件管理器未初始化")
+		return
+	}
+
+	if err := c.PluginMgr.AuthorizeManage(principal, pluginID); err != nil {
+		c.authzError(err)
+		return
+	}
+
+	previousVersion := c.PluginMgr.ActiveVersion(pluginID)
+	version, err := c.PluginMgr.Rollback(pluginID)
+	if err != nil {
+		c.JSONError(http.StatusBadRequest, fmt.Sprintf("回滚失败: %v", err))
+		return
+	}
+
+	log.Printf("[plugin-service] User %d rolled back plugin %s to version %s", userID, pluginID, version.Version)
+	c.JSONSuccess(map[string]interface{}{
+		"version":          version.Version,
+		"previous_version": previousVersion,
+		"message":          "插件已回滚",
+	})
+}
+
 // PUT /api/plugins/:id/config - 更新插件配置（保存API Key等）
 func (c *PluginServiceController) UpdateConfig() {
-	userID, ok := c.getAuthenticatedUserID()
+	principal, ok := c.getPrincipal()
 	if !ok {
 		return
 	}
+	userID := principal.UserID
 
 	pluginID := c.Ctx.Input.Param(":id")
 	if pluginID == "" {
@@ -444,54 +666,47 @@ func (c *PluginServiceController) UpdateConfig() {
 		return
 	}
 
-	if c.pluginMgr == nil {
+	if c.PluginMgr == nil {
 		c.JSONError(http.StatusInternalServerError, "插件管理器未初始化")
 		return
 	}
 
-	// 获取插件entry（通过List找到）
-	entries := c.pluginMgr.ListPlugins()
-	var entry *plugins.PluginEntry
-	for _, e := range entries {
-		if e.Plugin.Metadata().ID == pluginID {
-			entry = e
-			break
-		}
-	}
-	if entry == nil {
-		c.JSONError(http.StatusNotFound, "插件不存在")
+	scope, err := plugins.ParseOverlayScope(c.GetString("scope"))
+	if err != nil {
+		c.JSONError(http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// 更新配置
-	currentConfig := entry.Config
-	if currentConfig.Settings == nil {
-		currentConfig.Settings = make(map[string]interface{})
-	}
-
-	// 合并新配置
-	for k, v := range configData {
-		currentConfig.Settings[k] = v
+	if err := c.PluginMgr.AuthorizeView(principal, pluginID); err != nil {
+		c.authzError(err)
+		return
 	}
 
-	// 重新加载配置
-	if err := c.pluginMgr.ReloadPluginConfig(pluginID, currentConfig); err != nil {
+	// 全局范围合并到插件配置并重新加载，用户/组织范围写入覆盖设置
+	scope, err = c.PluginMgr.UpdateConfigFor(principal, pluginID, scope, configData)
+	if err != nil {
+		if errors.Is(err, plugins.ErrPluginAccessDenied) {
+			c.JSONError(http.StatusForbidden, err.Error())
+			return
+		}
 		c.JSONError(http.StatusBadRequest, fmt.Sprintf("更新配置失败: %v", err))
 		return
 	}
 
-	log.Printf("[plugin-service] User %d updated config for plugin %s", userID, pluginID)
+	log.Printf("[plugin-service] User %d updated config for plugin %s (scope %s)", userID, pluginID, scope)
 	c.JSONSuccess(map[string]interface{}{
+		"scope":   scope,
 		"message": "插件配置已更新",
 	})
 }
 
 // GET /api/plugins/:id/config - 获取插件配置
 func (c *PluginServiceController) GetConfig() {
-	userID, ok := c.getAuthenticatedUserID()
+	principal, ok := c.getPrincipal()
 	if !ok {
 		return
 	}
+	userID := principal.UserID
 
 	pluginID := c.Ctx.Input.Param(":id")
 	if pluginID == "" {
@@ -499,30 +714,25 @@ func (c *PluginServiceController) GetConfig() {
 		return
 	}
 
-	if c.pluginMgr == nil {
+	if c.PluginMgr == nil {
 		c.JSONError(http.StatusInternalServerError, "插件管理器未初始化")
 		return
 	}
 
-	// 获取插件entry（通过List找到）
-	entries := c.pluginMgr.ListPlugins()
-	var entry *plugins.PluginEntry
-	for _, e := range entries {
-		if e.Plugin.Metadata().ID == pluginID {
-			entry = e
-			break
-		}
-	}
-	if entry == nil {
-		c.JSONError(http.StatusNotFound, "插件不存在")
+	if err := c.PluginMgr.AuthorizeView(principal, pluginID); err != nil {
+		c.authzError(err)
 		return
 	}
 
-	// 返回配置（隐藏敏感信息）
-	config := entry.Config
+	// 返回当前用户生效的配置（隐藏敏感信息）
+	config, err := c.PluginMgr.EffectiveConfig(principal, pluginID)
+	if err != nil {
+		c.JSONError(http.StatusNotFound, fmt.Sprintf("插件不存在: %v", err))
+		return
+	}
 	safeConfig := map[string]interface{}{
-		"plugin_id": config.PluginID,
-		"enabled":   config.Enabled,
+		"plugin_id": pluginID,
+		"enabled":   c.PluginMgr.IsEnabledFor(principal, pluginID),
 		"settings":  make(map[string]interface{}),
 	}
 
@@ -559,23 +769,39 @@ func (c *PluginServiceController) Embed() {
 		return
 	}
 
-	if c.pluginMgr == nil {
+	if c.PluginMgr == nil {
 		c.JSONError(http.StatusInternalServerError, "插件管理器未初始化")
 		return
 	}
 
-	embedder, err := c.pluginMgr.GetEmbedderPlugin(pluginID)
+	principal, ok := c.getPrincipal()
+	if !ok {
+		return
+	}
+	callConfig, err := c.PluginMgr.AuthorizeCall(principal, pluginID)
+	if err != nil {
+		c.authzError(err)
+		return
+	}
+
+	embedder, release, err := c.PluginMgr.AcquireEmbedderPlugin(pluginID)
 	if err != nil {
 		c.JSONError(http.StatusNotFound, fmt.Sprintf("插件不存在或不支持向量化: %v", err))
 		return
 	}
+	defer release()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(plugins.WithCallConfig(context.Background(), callConfig), 30*time.Second)
 	defer cancel()
 
-	embedding, err := embedder.Embed(ctx, text)
+	var embedding []float32
+	err = c.PluginMgr.LimitCall(ctx, pluginID, func(ctx context.Context) error {
+		var err error
+		embedding, err = embedder.Embed(ctx, text)
+		return err
+	})
 	if err != nil {
-		c.JSONError(http.StatusInternalServerError, fmt.Sprintf("向量化失败: %v", err))
+		c.pluginCallError("向量化失败", err)
 		return
 	}
 
@@ -613,23 +839,39 @@ func (c *PluginServiceController) Rerank() {
 	}
 	documents := requestBody.Documents
 
-	if c.pluginMgr == nil {
+	if c.PluginMgr == nil {
 		c.JSONError(http.StatusInternalServerError, "插件管理器未初始化")
 		return
 	}
 
-	reranker, err := c.pluginMgr.GetRerankerPlugin(pluginID)
+	principal, ok := c.getPrincipal()
+	if !ok {
+		return
+	}
+	callConfig, err := c.PluginMgr.AuthorizeCall(principal, pluginID)
+	if err != nil {
+		c.authzError(err)
+		return
+	}
+
+	reranker, release, err := c.PluginMgr.AcquireRerankerPlugin(pluginID)
 	if err != nil {
 		c.JSONError(http.StatusNotFound, fmt.Sprintf("插件不存在或不支持重排序: %v", err))
 		return
 	}
+	defer release()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(plugins.WithCallConfig(context.Background(), callConfig), 30*time.Second)
 	defer cancel()
 
-	results, err := reranker.Rerank(ctx, query, documents)
+	var results []plugins.RerankResult
+	err = c.PluginMgr.LimitCall(ctx, pluginID, func(ctx context.Context) error {
+		var err error
+		results, err = reranker.Rerank(ctx, query, documents)
+		return err
+	})
 	if err != nil {
-		c.JSONError(http.StatusInternalServerError, fmt.Sprintf("重排序失败: %v", err))
+		c.pluginCallError("重排序失败", err)
 		return
 	}
 
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	apperrors "github.com/aihub/backend-go/internal/errors"
+	"github.com/aihub/backend-go/internal/services"
+)
+
+// progressKeepAlive SSE心跳间隔，避免代理断开空闲连接
+const progressKeepAlive = 15 * time.Second
+
+// ProgressController 文档处理进度控制器
+type ProgressController struct {
+	BaseController
+	ProgressService *services.ProgressService
+}
+
+// NewProgressController 创建文档处理进度控制器
+func NewProgressController(progressService *services.ProgressService) *ProgressController {
+	return &ProgressController{
+		ProgressService: progressService,
+	}
+}
+
+// Latest 文档处理的最新状态（不支持SSE的客户端轮询使用）
+// 带:doc_id时返回单个文档，否则返回知识库下全部文档
+func (c *ProgressController) Latest() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+	docID, ok := c.optionalUintParam(":doc_id")
+	if !ok {
+		return
+	}
+
+	events, err := c.ProgressService.Latest(c.Ctx.Request.Context(), uint(kbID), uint(docID), userID)
+	if err != nil {
+		c.progressError(err)
+		return
+	}
+	c.JSONSuccess(events)
+}
+
+// Stream 通过SSE推送文档处理进度，事件类型为progress，id可作为Last-Event-ID断线续传
+func (c *ProgressController) Stream() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+	docID, ok := c.optionalUintParam(":doc_id")
+	if !ok {
+		return
+	}
+	lastEventID := c.Ctx.Input.Header("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.GetString("last_event_id")
+	}
+
+	ctx := c.Ctx.Request.Context()
+	sub, err := c.ProgressService.Subscribe(ctx, uint(kbID), uint(docID), userID, lastEventID)
+	if err != nil {
+		c.progressError(err)
+		return
+	}
+
+	c.EnableRender = false
+	w := c.Ctx.ResponseWriter
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	for ctx.Err() == nil {
+		events, err := sub.Next(ctx, progressKeepAlive)
+		if err != nil {
+			if ctx.Err() == nil {
+				fmt.Fprintf(w, "event: error\ndata: %s\n\n", "进度读取失败")
+				w.Flush()
+			}
+			return
+		}
+		if len(events) == 0 {
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		for _, event := range events {
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(w, "id: %s\nevent: progress\ndata: %s\n\n", event.ID, data)
+		}
+		w.Flush()
+	}
+}
+
+func (c *ProgressController) progressError(err error) {
+	if appErr, ok := err.(*apperrors.AppError); ok {
+		c.JSONError(appErr.HTTPCode, appErr.Message)
+		return
+	}
+	c.JSONError(http.StatusInternalServerError, "获取进度失败")
+}
+
+// getAuthenticatedUserID 获取认证用户ID
+func (c *ProgressController) getAuthenticatedUserID() (uint, bool) {
+	if userID, ok := c.Ctx.Input.GetData("user_id").(uint); ok && userID > 0 {
+		return userID, true
+	}
+	c.JSONError(http.StatusUnauthorized, "未授权访问")
+	return 0, false
+}
+
+// mustParseUintParam 解析URL参数为uint
+func (c *ProgressController) mustParseUintParam(key string) (uint64, bool) {
+	value := c.GetString(key)
+	if value == "" {
+		c.JSONError(http.StatusBadRequest, "缺少必要参数")
+		return 0, false
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		c.JSONError(http.StatusBadRequest, "参数格式错误")
+		return 0, false
+	}
+
+	return id, true
+}
+
+// optionalUintParam 解析可选的URL参数，缺省时为0
+func (c *ProgressController) optionalUintParam(key string) (uint64, bool) {
+	if c.GetString(key) == "" {
+		return 0, true
+	}
+	return c.mustParseUintParam(key)
+}
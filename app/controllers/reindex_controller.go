@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	apperrors "github.com/aihub/backend-go/internal/errors"
+	"github.com/aihub/backend-go/internal/knowledge"
+	"github.com/aihub/backend-go/internal/services"
+)
+
+// ReindexController 知识库索引重建控制器（管理员接口）
+type ReindexController struct {
+	BaseController
+	ReindexService *services.ReindexService
+}
+
+// NewReindexController 创建索引重建控制器
+func NewReindexController(reindexService *services.ReindexService) *ReindexController {
+	return &ReindexController{
+		ReindexService: reindexService,
+	}
+}
+
+// Start 启动重建，请求体：{"fulltext":true,"vector":true,"reembed":false}，均为空时重建全部索引
+func (c *ReindexController) Start() {
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+
+	var req knowledge.ReindexRequest
+	if body := c.Ctx.Input.RequestBody; len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			c.JSONError(http.StatusBadRequest, "请求参数错误")
+			return
+		}
+	}
+	req.KnowledgeBaseID = uint(kbID)
+
+	job, err := c.ReindexService.StartReindex(c.Ctx.Request.Context(), req)
+	if err != nil {
+		c.reindexError(err)
+		return
+	}
+	c.JSON(http.StatusAccepted, map[string]interface{}{
+		"success": true,
+		"data":    job,
+	})
+}
+
+// Status 查询重建进度
+func (c *ReindexController) Status() {
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+
+	job, err := c.ReindexService.GetReindexStatus(uint(kbID))
+	if err != nil {
+		c.reindexError(err)
+		return
+	}
+	c.JSONSuccess(job)
+}
+
+// Cancel 取消重建
+func (c *ReindexController) Cancel() {
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+
+	if err := c.ReindexService.CancelReindex(uint(kbID)); err != nil {
+		c.reindexError(err)
+		return
+	}
+	c.JSONSuccess(map[string]interface{}{"cancelled": true})
+}
+
+// EmbeddingProfile 查询知识库的嵌入模型及迁移状态
+func (c *ReindexController) EmbeddingProfile() {
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+
+	info, err := c.ReindexService.GetEmbeddingProfile(c.Ctx.Request.Context(), uint(kbID))
+	if err != nil {
+		c.reindexError(err)
+		return
+	}
+	c.JSONSuccess(info)
+}
+
+// MigrateEmbedding 迁移到新的嵌入模型，请求体：{"provider":"dashscope","model":"text-embedding-v3"}
+// 进度通过重建状态接口查询
+func (c *ReindexController) MigrateEmbedding() {
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+
+	var target knowledge.EmbeddingProfile
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &target); err != nil {
+		c.JSONError(http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	job, err := c.ReindexService.MigrateEmbedding(c.Ctx.Request.Context(), uint(kbID), target)
+	if err != nil {
+		c.reindexError(err)
+		return
+	}
+	c.JSON(http.StatusAccepted, map[string]interface{}{
+		"success": true,
+		"data":    job,
+	})
+}
+
+func (c *ReindexController) reindexError(err error) {
+	if appErr, ok := err.(*apperrors.AppError); ok {
+		c.JSONError(appErr.HTTPCode, appErr.Message)
+		return
+	}
+	c.JSONError(http.StatusInternalServerError, "索引重建失败")
+}
+
+// mustParseUintParam 解析URL参数为uint
+func (c *ReindexController) mustParseUintParam(key string) (uint64, bool) {
+	value := c.GetString(key)
+	if value == "" {
+		c.JSONError(http.StatusBadRequest, "缺少必要参数")
+		return 0, false
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		c.JSONError(http.StatusBadRequest, "参数格式错误")
+		return 0, false
+	}
+
+	return id, true
+}
@@ -1,22 +1,26 @@
 package controllers
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
+	apperrors "github.com/aihub/backend-go/internal/errors"
+	"github.com/aihub/backend-go/internal/knowledge"
 	"github.com/aihub/backend-go/internal/services"
 )
 
 // SearchController 搜索控制器
 type SearchController struct {
 	BaseController
-	searchService *services.SearchService
+	SearchService *services.SearchService
 }
 
 // NewSearchController 创建搜索控制器
 func NewSearchController(searchService *services.SearchService) *SearchController {
 	return &SearchController{
-		searchService: searchService,
+		SearchService: searchService,
 	}
 }
 
@@ -42,56 +46,199 @@ func (c *SearchController) Search() {
 	mode := c.GetString("mode", "hybrid")
 	vectorThreshold, _ := strconv.ParseFloat(c.GetString("vector_threshold", "0.5"), 64)
 
-	results, err := c.searchService.SearchKnowledgeBase(c.Ctx.Request.Context(), uint(kbID), userID, query, topK, mode, vectorThreshold)
+	// 审计检索：document_id=12&as_of_version=3，在文档的历史版本上检索
+	if c.GetString("as_of_version") != "" {
+		version, err := c.GetInt("as_of_version")
+		if err != nil || version <= 0 {
+			c.JSONError(http.StatusBadRequest, "as_of_version参数格式错误")
+			return
+		}
+		docID, err := strconv.ParseUint(c.GetString("document_id"), 10, 64)
+		if err != nil || docID == 0 {
+			c.JSONError(http.StatusBadRequest, "as_of_version需要指定document_id")
+			return
+		}
+		result, err := c.SearchService.SearchDocumentAsOfVersion(c.Ctx.Request.Context(), uint(kbID), userID, uint(docID), version, query, topK)
+		if err != nil {
+			if appErr, ok := err.(*apperrors.AppError); ok {
+				c.JSONError(appErr.HTTPCode, appErr.Message)
+				return
+			}
+			c.JSONError(http.StatusInternalServerError, "搜索失败")
+			return
+		}
+		c.JSONSuccess(result)
+		return
+	}
+
+	// 分页检索：page_size=20&cursor=<上一页的next_cursor>&facets=document,file_type,source,tag
+	if c.GetString("page_size") != "" || c.GetString("cursor") != "" {
+		pageSize, _ := c.GetInt("page_size", 0)
+		var facets []string
+		for _, field := range strings.Split(c.GetString("facets"), ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				facets = append(facets, field)
+			}
+		}
+		result, err := c.SearchService.SearchKnowledgeBasePage(c.Ctx.Request.Context(), uint(kbID), userID, services.KnowledgeSearchPageRequest{
+			Query:           query,
+			Mode:            mode,
+			VectorThreshold: vectorThreshold,
+			PageSize:        pageSize,
+			Cursor:          c.GetString("cursor"),
+			Facets:          facets,
+		})
+		if err != nil {
+			if appErr, ok := err.(*apperrors.AppError); ok {
+				c.JSONError(appErr.HTTPCode, appErr.Message)
+				return
+			}
+			c.JSONError(http.StatusInternalServerError, "搜索失败")
+			return
+		}
+		c.JSONSuccess(result)
+		return
+	}
+
+	if diversify, _ := c.GetBool("diversity", false); !diversify {
+		results, err := c.SearchService.SearchKnowledgeBase(c.Ctx.Request.Context(), uint(kbID), userID, query, topK, mode, vectorThreshold)
+		if err != nil {
+			c.JSONError(http.StatusInternalServerError, "搜索失败")
+			return
+		}
+
+		c.JSONSuccess(map[string]interface{}{
+			"results": results,
+			"query":   query,
+		})
+		return
+	}
+
+	// 结果多样化：diversity=true&diversity_lambda=0.5&max_per_document=2&diversity_before_rerank=false
+	lambda, _ := c.GetFloat("diversity_lambda", 0)
+	maxPerDocument, _ := c.GetInt("max_per_document", 0)
+	beforeRerank, _ := c.GetBool("diversity_before_rerank", false)
+	result, err := c.SearchService.SearchKnowledgeBaseWithContext(c.Ctx.Request.Context(), uint(kbID), userID, services.KnowledgeSearchRequest{
+		Query:           query,
+		TopK:            topK,
+		Mode:            mode,
+		VectorThreshold: vectorThreshold,
+		Diversity: &knowledge.DiversityOptions{
+			Lambda:         lambda,
+			MaxPerDocument: maxPerDocument,
+			BeforeRerank:   beforeRerank,
+		},
+	})
 	if err != nil {
 		c.JSONError(http.StatusInternalServerError, "搜索失败")
 		return
 	}
 
-	c.JSONSuccess(map[string]interface{}{
-		"results": results,
-		"query":   query,
-	})
+	c.JSONSuccess(result)
 }
 
-// SearchAll 搜索所有知识库
-func (c *SearchController) SearchAll() {
+// SearchWithContext 结合对话历史检索知识库
+// 请求体：{"query":"那年假呢","history":[{"role":"user","content":"病假怎么请"}],"transform":{"rewrite":true,"multi_query":3,"hyde":false},
+// "diversity":{"lambda":0.7,"max_per_document":2}}
+func (c *SearchController) SearchWithContext() {
 	userID, ok := c.getAuthenticatedUserID()
 	if !ok {
 		return
 	}
 
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+
+	var req services.KnowledgeSearchRequest
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+		c.JSONError(http.StatusBadRequest, "请求参数错误")
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		c.JSONError(http.StatusBadRequest, "查询参数不能为空")
+		return
+	}
+
+	result, err := c.SearchService.SearchKnowledgeBaseWithContext(c.Ctx.Request.Context(), uint(kbID), userID, req)
+	if err != nil {
+		c.JSONError(http.StatusInternalServerError, "搜索失败")
+		return
+	}
+
+	c.JSONSuccess(result)
+}
+
+// SearchAll 跨知识库检索，kb_ids为逗号分隔的知识库ID，为空时检索全部知识库
+func (c *SearchController) SearchAll() {
 	query := c.GetString("query")
 	if query == "" {
 		c.JSONError(http.StatusBadRequest, "查询参数不能为空")
 		return
 	}
 
-	topK, _ := strconv.Atoi(c.GetString("top_k", "10"))
-	mode := c.GetString("mode", "hybrid")
-	vectorThreshold, _ := strconv.ParseFloat(c.GetString("vector_threshold", "0.5"), 64)
+	req := services.FederatedSearchRequest{Query: query, Mode: c.GetString("mode", "hybrid"), Normalization: c.GetString("normalization")}
+	req.TopK, _ = strconv.Atoi(c.GetString("top_k", "10"))
+	req.VectorThreshold, _ = strconv.ParseFloat(c.GetString("vector_threshold", "0.5"), 64)
+	req.TimeoutMs, _ = strconv.Atoi(c.GetString("timeout_ms", "0"))
+	for _, value := range strings.Split(c.GetString("kb_ids"), ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSONError(http.StatusBadRequest, "参数格式错误")
+			return
+		}
+		req.KnowledgeBaseIDs = append(req.KnowledgeBaseIDs, uint(id))
+	}
+	c.federatedSearch(req)
+}
 
-	results, err := c.searchService.SearchAllKnowledgeBases(c.Ctx.Request.Context(), userID, query, topK, mode, vectorThreshold)
+// FederatedSearch 跨知识库检索
+// 请求体：{"query":"报销流程","knowledge_base_ids":[1,2],"top_k":10,"timeout_ms":3000,"normalization":"minmax"}
+func (c *SearchController) FederatedSearch() {
+	var req services.FederatedSearchRequest
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+		c.JSONError(http.StatusBadRequest, "请求参数错误")
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		c.JSONError(http.StatusBadRequest, "查询参数不能为空")
+		return
+	}
+	c.federatedSearch(req)
+}
+
+func (c *SearchController) federatedSearch(req services.FederatedSearchRequest) {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+
+	result, err := c.SearchService.FederatedSearch(c.Ctx.Request.Context(), userID, req)
 	if err != nil {
+		if appErr, ok := err.(*apperrors.AppError); ok {
+			c.JSONError(appErr.HTTPCode, appErr.Message)
+			return
+		}
 		c.JSONError(http.StatusInternalServerError, "搜索失败")
 		return
 	}
 
-	c.JSONSuccess(map[string]interface{}{
-		"results": results,
-		"query":   query,
-	})
+	c.JSONSuccess(result)
 }
 
 // GetCacheStats 获取缓存统计
 func (c *SearchController) GetCacheStats() {
-	stats := c.searchService.GetCacheStats()
+	stats := c.SearchService.GetCacheStats()
 	c.JSONSuccess(stats)
 }
 
 // GetPerformanceStats 获取性能统计
 func (c *SearchController) GetPerformanceStats() {
-	stats := c.searchService.GetPerformanceStats()
+	stats := c.SearchService.GetPerformanceStats()
 	c.JSONSuccess(stats)
 }
 
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	apperrors "github.com/aihub/backend-go/internal/errors"
+	"github.com/aihub/backend-go/internal/logger"
+	"github.com/aihub/backend-go/internal/models"
+	"github.com/aihub/backend-go/internal/services"
+	"github.com/aihub/backend-go/internal/transfer"
+	"github.com/beego/beego/v2/server/web/context"
+	"go.uber.org/zap"
+)
+
+// TransferController 知识库导出与导入控制器
+type TransferController struct {
+	BaseController
+	TransferService *services.TransferService
+}
+
+// NewTransferController 创建导出导入控制器
+func NewTransferController(transferService *services.TransferService) *TransferController {
+	return &TransferController{
+		TransferService: transferService,
+	}
+}
+
+// Export 下载知识库归档，embeddings=true时包含向量
+func (c *TransferController) Export() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+	includeEmbeddings, _ := c.GetBool("embeddings", false)
+
+	// 响应头在写入第一个字节时才发送，写入前出错仍可返回JSON错误
+	w := &archiveResponseWriter{
+		response: c.Ctx.ResponseWriter,
+		filename: fmt.Sprintf("knowledge-base-%d.zip", kbID),
+	}
+	_, err := c.TransferService.Export(c.Ctx.Request.Context(), uint(kbID), userID, includeEmbeddings, w)
+	if err != nil && !w.started {
+		c.transferError(err)
+		return
+	}
+	c.EnableRender = false
+	if err != nil {
+		// 归档已部分发送，只能中断响应
+		logger.Warn("Knowledge base export aborted", zap.Uint64("kb_id", kbID), zap.Error(err))
+	}
+}
+
+// archiveResponseWriter 首次写入时发送下载响应头
+type archiveResponseWriter struct {
+	response *context.Response
+	filename string
+	started  bool
+}
+
+func (w *archiveResponseWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.response.Header().Set("Content-Type", "application/zip")
+		w.response.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", w.filename))
+		w.response.WriteHeader(http.StatusOK)
+	}
+	return w.response.Write(p)
+}
+
+// Import 上传归档并创建导入任务，可用name指定新知识库名称
+func (c *TransferController) Import() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+
+	file, header, err := c.GetFile("file")
+	if err != nil {
+		c.JSONError(http.StatusBadRequest, "请选择要导入的归档文件")
+		return
+	}
+	defer file.Close()
+	if !strings.EqualFold(filepath.Ext(header.Filename), ".zip") {
+		c.JSONError(http.StatusBadRequest, "只支持.zip格式的知识库归档")
+		return
+	}
+
+	job, err := c.TransferService.CreateImport(c.Ctx.Request.Context(), userID, strings.TrimSpace(c.GetString("name")), header.Filename, file, header.Size)
+	if err != nil {
+		c.transferError(err)
+		return
+	}
+	c.JSON(http.StatusAccepted, map[string]interface{}{
+		"success": true,
+		"data":    importView(job),
+	})
+}
+
+// GetImport 导入任务状态与进度
+func (c *TransferController) GetImport() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	jobID, ok := c.mustParseUintParam(":jobId")
+	if !ok {
+		return
+	}
+
+	job, err := c.TransferService.GetImport(c.Ctx.Request.Context(), uint(jobID), userID)
+	if err != nil {
+		c.transferError(err)
+		return
+	}
+	c.JSONSuccess(importView(job))
+}
+
+// ResumeImport 从断点重新执行失败的导入任务
+func (c *TransferController) ResumeImport() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	jobID, ok := c.mustParseUintParam(":jobId")
+	if !ok {
+		return
+	}
+
+	job, err := c.TransferService.ResumeImport(c.Ctx.Request.Context(), uint(jobID), userID)
+	if err != nil {
+		c.transferError(err)
+		return
+	}
+	c.JSONSuccess(importView(job))
+}
+
+func importView(job *models.KnowledgeImportJob) map[string]interface{} {
+	return map[string]interface{}{
+		"job":      job,
+		"progress": transfer.Progress(job),
+	}
+}
+
+func (c *TransferController) transferError(err error) {
+	if appErr, ok := err.(*apperrors.AppError); ok {
+		c.JSONError(appErr.HTTPCode, appErr.Message)
+		return
+	}
+	c.JSONError(http.StatusInternalServerError, "知识库导出导入失败")
+}
+
+// getAuthenticatedUserID 获取认证用户ID
+func (c *TransferController) getAuthenticatedUserID() (uint, bool) {
+	if userID, ok := c.Ctx.Input.GetData("user_id").(uint); ok && userID > 0 {
+		return userID, true
+	}
+	c.JSONError(http.StatusUnauthorized, "未授权访问")
+	return 0, false
+}
+
+// mustParseUintParam 解析URL参数为uint
+func (c *TransferController) mustParseUintParam(key string) (uint64, bool) {
+	value := c.GetString(key)
+	if value == "" {
+		c.JSONError(http.StatusBadRequest, "缺少必要参数")
+		return 0, false
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		c.JSONError(http.StatusBadRequest, "参数格式错误")
+		return 0, false
+	}
+
+	return id, true
+}
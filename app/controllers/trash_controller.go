@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	apperrors "github.com/aihub/backend-go/internal/errors"
+	"github.com/aihub/backend-go/internal/services"
+)
+
+// TrashController 知识库回收站控制器
+type TrashController struct {
+	BaseController
+	TrashService *services.TrashService
+}
+
+// NewTrashController 创建回收站控制器
+func NewTrashController(trashService *services.TrashService) *TrashController {
+	return &TrashController{
+		TrashService: trashService,
+	}
+}
+
+// List 回收站条目列表，可用knowledge_base_id过滤
+func (c *TrashController) List() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+
+	var kbID uint64
+	if value := c.GetString("knowledge_base_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSONError(http.StatusBadRequest, "knowledge_base_id参数格式错误")
+			return
+		}
+		kbID = id
+	}
+
+	items, err := c.TrashService.ListTrash(c.Ctx.Request.Context(), userID, uint(kbID))
+	if err != nil {
+		c.trashError(err)
+		return
+	}
+	c.JSONSuccess(items)
+}
+
+// Restore 从回收站恢复
+func (c *TrashController) Restore() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	taskID, ok := c.mustParseUintParam(":taskId")
+	if !ok {
+		return
+	}
+
+	restored, err := c.TrashService.Restore(c.Ctx.Request.Context(), uint(taskID), userID)
+	if err != nil {
+		c.trashError(err)
+		return
+	}
+	c.JSONSuccess(restored)
+}
+
+// Purge 立即清理，不再等待保留期
+func (c *TrashController) Purge() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	taskID, ok := c.mustParseUintParam(":taskId")
+	if !ok {
+		return
+	}
+
+	if err := c.TrashService.PurgeNow(c.Ctx.Request.Context(), uint(taskID), userID); err != nil {
+		c.trashError(err)
+		return
+	}
+	c.JSONSuccess(map[string]interface{}{
+		"message": "已安排立即清理",
+	})
+}
+
+func (c *TrashController) trashError(err error) {
+	if appErr, ok := err.(*apperrors.AppError); ok {
+		c.JSONError(appErr.HTTPCode, appErr.Message)
+		return
+	}
+	c.JSONError(http.StatusInternalServerError, "回收站操作失败")
+}
+
+// getAuthenticatedUserID 获取认证用户ID
+func (c *TrashController) getAuthenticatedUserID() (uint, bool) {
+	if userID, ok := c.Ctx.Input.GetData("user_id").(uint); ok && userID > 0 {
+		return userID, true
+	}
+	c.JSONError(http.StatusUnauthorized, "未授权访问")
+	return 0, false
+}
+
+// mustParseUintParam 解析URL参数为uint
+func (c *TrashController) mustParseUintParam(key string) (uint64, bool) {
+	value := c.GetString(key)
+	if value == "" {
+		c.JSONError(http.StatusBadRequest, "缺少必要参数")
+		return 0, false
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		c.JSONError(http.StatusBadRequest, "参数格式错误")
+		return 0, false
+	}
+
+	return id, true
+}
@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	apperrors "github.com/aihub/backend-go/internal/errors"
+	"github.com/aihub/backend-go/internal/services"
+)
+
+// WebhookController 知识库Webhook控制器
+type WebhookController struct {
+	BaseController
+	WebhookService *services.WebhookService
+}
+
+// NewWebhookController 创建知识库Webhook控制器
+func NewWebhookController(webhookService *services.WebhookService) *WebhookController {
+	return &WebhookController{
+		WebhookService: webhookService,
+	}
+}
+
+// List Webhook订阅列表
+func (c *WebhookController) List() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+
+	webhooks, err := c.WebhookService.ListWebhooks(c.Ctx.Request.Context(), uint(kbID), userID)
+	if err != nil {
+		c.webhookError(err)
+		return
+	}
+	c.JSONSuccess(webhooks)
+}
+
+// Create 创建Webhook订阅，响应中的secret只返回这一次
+// 请求体：{"url":"https://example.com/hooks","events":["document.completed","document.failed"],"description":"同步到工单系统"}
+func (c *WebhookController) Create() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+
+	var req services.WebhookRequest
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+		c.JSONError(http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	webhook, err := c.WebhookService.CreateWebhook(c.Ctx.Request.Context(), uint(kbID), userID, req)
+	if err != nil {
+		c.webhookError(err)
+		return
+	}
+	c.JSON(http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    webhook,
+	})
+}
+
+// Get 获取单个Webhook订阅
+func (c *WebhookController) Get() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+	webhookID, ok := c.mustParseUintParam(":webhookId")
+	if !ok {
+		return
+	}
+
+	webhook, err := c.WebhookService.GetWebhook(c.Ctx.Request.Context(), uint(kbID), uint(webhookID), userID)
+	if err != nil {
+		c.webhookError(err)
+		return
+	}
+	c.JSONSuccess(webhook)
+}
+
+// Update 修改Webhook订阅，{"enabled":true}可重新启用被自动停用的订阅
+func (c *WebhookController) Update() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+	webhookID, ok := c.mustParseUintParam(":webhookId")
+	if !ok {
+		return
+	}
+
+	var req services.WebhookRequest
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+		c.JSONError(http.StatusBadRequest, "请求参数错误")
+		return
+	}
+
+	webhook, err := c.WebhookService.UpdateWebhook(c.Ctx.Request.Context(), uint(kbID), uint(webhookID), userID, req)
+	if err != nil {
+		c.webhookError(err)
+		return
+	}
+	c.JSONSuccess(webhook)
+}
+
+// Delete 删除Webhook订阅
+func (c *WebhookController) Delete() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+	webhookID, ok := c.mustParseUintParam(":webhookId")
+	if !ok {
+		return
+	}
+
+	if err := c.WebhookService.DeleteWebhook(c.Ctx.Request.Context(), uint(kbID), uint(webhookID), userID); err != nil {
+		c.webhookError(err)
+		return
+	}
+	c.JSONSuccess(nil)
+}
+
+// Deliveries 投递记录，status可按pending/succeeded/failed/skipped筛选
+func (c *WebhookController) Deliveries() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+	webhookID, ok := c.mustParseUintParam(":webhookId")
+	if !ok {
+		return
+	}
+	limit, _ := c.GetInt("limit", 20)
+
+	deliveries, err := c.WebhookService.ListDeliveries(c.Ctx.Request.Context(), uint(kbID), uint(webhookID), userID, c.GetString("status"), limit)
+	if err != nil {
+		c.webhookError(err)
+		return
+	}
+	c.JSONSuccess(deliveries)
+}
+
+// Replay 重新投递一条记录
+func (c *WebhookController) Replay() {
+	userID, ok := c.getAuthenticatedUserID()
+	if !ok {
+		return
+	}
+	kbID, ok := c.mustParseUintParam(":id")
+	if !ok {
+		return
+	}
+	webhookID, ok := c.mustParseUintParam(":webhookId")
+	if !ok {
+		return
+	}
+	deliveryID, ok := c.mustParseUintParam(":deliveryId")
+	if !ok {
+		return
+	}
+
+	delivery, err := c.WebhookService.ReplayDelivery(c.Ctx.Request.Context(), uint(kbID), uint(webhookID), uint(deliveryID), userID)
+	if err != nil {
+		c.webhookError(err)
+		return
+	}
+	c.JSON(http.StatusAccepted, map[string]interface{}{
+		"success": true,
+		"data":    delivery,
+	})
+}
+
+func (c *WebhookController) webhookError(err error) {
+	if appErr, ok := err.(*apperrors.AppError); ok {
+		c.JSONError(appErr.HTTPCode, appErr.Message)
+		return
+	}
+	c.JSONError(http.StatusInternalServerError, "Webhook操作失败")
+}
+
+// getAuthenticatedUserID 获取认证用户ID
+func (c *WebhookController) getAuthenticatedUserID() (uint, bool) {
+	if userID, ok := c.Ctx.Input.GetData("user_id").(uint); ok && userID > 0 {
+		return userID, true
+	}
+	c.JSONError(http.StatusUnauthorized, "未授权访问")
+	return 0, false
+}
+
+// mustParseUintParam 解析URL参数为uint
+func (c *WebhookController) mustParseUintParam(key string) (uint64, bool) {
+	value := c.GetString(key)
+	if value == "" {
+		c.JSONError(http.StatusBadRequest, "缺少必要参数")
+		return 0, false
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		c.JSONError(http.StatusBadRequest, "参数格式错误")
+		return 0, false
+	}
+
+	return id, true
+}
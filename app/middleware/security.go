@@ -206,6 +206,7 @@ func (sm *SecurityMiddleware) authenticateJWT(ctx *beecontext.Context) (uint, er
 	ctx.Input.SetData("username", claims.Username)
 	ctx.Input.SetData("email", claims.Email)
 	ctx.Input.SetData("roles", claims.Roles)
+	ctx.Input.SetData("org_id", claims.OrgID)
 
 	return claims.UserID, nil
 }
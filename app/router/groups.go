@@ -186,6 +186,46 @@ func buildKnowledgeRoutes(api *RouteGroup, factory *controllers.ControllerFactor
 		return nil, err
 	}
 
+	reindexController, err := factory.CreateReindexController()
+	if err != nil {
+		return nil, err
+	}
+
+	lexiconController, err := factory.CreateLexiconController()
+	if err != nil {
+		return nil, err
+	}
+
+	evaluationController, err := factory.CreateEvaluationController()
+	if err != nil {
+		return nil, err
+	}
+
+	progressController, err := factory.CreateProgressController()
+	if err != nil {
+		return nil, err
+	}
+
+	webhookController, err := factory.CreateWebhookController()
+	if err != nil {
+		return nil, err
+	}
+
+	versionController, err := factory.CreateDocumentVersionController()
+	if err != nil {
+		return nil, err
+	}
+
+	trashController, err := factory.CreateTrashController()
+	if err != nil {
+		return nil, err
+	}
+
+	transferController, err := factory.CreateTransferController()
+	if err != nil {
+		return nil, err
+	}
+
 	// 直接注册路由到beego，避免类型转换问题
 	// 知识库CRUD路由
 	web.Router("/api/knowledge", kbController, "get:List;post:Create")
@@ -195,10 +235,33 @@ func buildKnowledgeRoutes(api *RouteGroup, factory *controllers.ControllerFactor
 	web.Router("/api/knowledge/:id/upload", docController, "post:UploadDocuments")
 	web.Router("/api/knowledge/:id/process", docController, "post:ProcessDocuments")
 	web.Router("/api/knowledge/:id/documents", docController, "get:GetDocuments")
-	web.Router("/api/knowledge/:id/documents/:doc_id", docController, "get:GetDocument")
+	web.Router("/api/knowledge/:id/documents/:doc_id", docController, "get:GetDocument;delete:DeleteDocument")
+
+	// 文档处理进度路由：SSE推送与最新状态
+	web.Router("/api/knowledge/:id/progress", progressController, "get:Latest")
+	web.Router("/api/knowledge/:id/progress/stream", progressController, "get:Stream")
+	web.Router("/api/knowledge/:id/documents/:doc_id/progress", progressController, "get:Latest")
+	web.Router("/api/knowledge/:id/documents/:doc_id/progress/stream", progressController, "get:Stream")
+
+	// 文档版本历史路由：版本列表、差异对比与恢复
+	web.Router("/api/knowledge/:id/documents/:doc_id/versions", versionController, "get:List")
+	web.Router("/api/knowledge/:id/documents/:doc_id/versions/diff", versionController, "get:Diff")
+	web.Router("/api/knowledge/:id/documents/:doc_id/versions/:version/restore", versionController, "post:Restore")
+
+	// 回收站路由：列表、恢复与立即清理
+	web.Router("/api/knowledge/trash", trashController, "get:List")
+	web.Router("/api/knowledge/trash/:taskId", trashController, "delete:Purge")
+	web.Router("/api/knowledge/trash/:taskId/restore", trashController, "post:Restore")
+
+	// 导出导入路由：下载归档、上传归档创建导入任务、查询与恢复导入任务
+	web.Router("/api/knowledge/:id/export", transferController, "get:Export")
+	web.Router("/api/knowledge/imports", transferController, "post:Import")
+	web.Router("/api/knowledge/imports/:jobId", transferController, "get:GetImport")
+	web.Router("/api/knowledge/imports/:jobId/resume", transferController, "post:ResumeImport")
 
 	// 搜索路由
-	web.Router("/api/knowledge/:id/search", searchController, "get:Search")
+	web.Router("/api/knowledge/search", searchController, "get:SearchAll;post:FederatedSearch")
+	web.Router("/api/knowledge/:id/search", searchController, "get:Search;post:SearchWithContext")
 	web.Router("/api/knowledge/:id/cache/stats", searchController, "get:GetCacheStats")
 	web.Router("/api/knowledge/:id/performance/stats", searchController, "get:GetPerformanceStats")
 
@@ -210,6 +273,30 @@ func buildKnowledgeRoutes(api *RouteGroup, factory *controllers.ControllerFactor
 	web.Router("/api/knowledge/:id/sync/web", integrationController, "post:SyncWeb")
 	web.Router("/api/knowledge/:id/sync/qwen/health", integrationController, "get:CheckQwenHealth")
 
+	// 词库路由
+	web.Router("/api/knowledge/:id/lexicon", lexiconController, "get:Get")
+	web.Router("/api/knowledge/:id/lexicon/synonyms", lexiconController, "post:CreateSynonym")
+	web.Router("/api/knowledge/:id/lexicon/synonyms/:synonymId", lexiconController, "put:UpdateSynonym;delete:DeleteSynonym")
+	web.Router("/api/knowledge/:id/lexicon/stopwords", lexiconController, "put:ReplaceStopWords")
+	web.Router("/api/knowledge/:id/lexicon/dictionary", lexiconController, "put:ReplaceDictionary")
+	// IK远程扩展词典，不在/api下以便ES节点无需JWT拉取；词典在ES集群内全局生效（所有知识库词的并集），
+	// 通过knowledge.search.elasticsearch.ik_dictionary_token共享密钥访问
+	web.Router("/lexicon/ik/ext.dic", lexiconController, "get,head:IKDictionary")
+
+	// 检索效果评测路由
+	web.Router("/api/knowledge/:id/evaluations", evaluationController, "get:List;post:Run")
+	web.Router("/api/knowledge/:id/evaluations/:runId", evaluationController, "get:Get")
+
+	// Webhook订阅与投递记录路由
+	web.Router("/api/knowledge/:id/webhooks", webhookController, "get:List;post:Create")
+	web.Router("/api/knowledge/:id/webhooks/:webhookId", webhookController, "get:Get;put:Update;delete:Delete")
+	web.Router("/api/knowledge/:id/webhooks/:webhookId/deliveries", webhookController, "get:Deliveries")
+	web.Router("/api/knowledge/:id/webhooks/:webhookId/deliveries/:deliveryId/replay", webhookController, "post:Replay")
+
+	// 索引重建与嵌入模型迁移路由（管理员）
+	web.Router("/api/admin/knowledge/:id/reindex", reindexController, "post:Start;get:Status;delete:Cancel")
+	web.Router("/api/admin/knowledge/:id/embedding", reindexController, "get:EmbeddingProfile;post:MigrateEmbedding")
+
 	return knowledge, nil
 }
 
@@ -5,16 +5,19 @@ package router
 
 import (
 	"github.com/aihub/backend-go/app/controllers"
+	"github.com/aihub/backend-go/internal/middleware"
+	"github.com/aihub/backend-go/internal/plugins"
 	"github.com/beego/beego/v2/server/web"
 )
 
-// InitPluginRoutes 初始化插件服务路由（独立微服务）
-func InitPluginRoutes() {
+// InitPluginRoutes 初始化插件服务路由（独立微服务），与gRPC服务共用插件管理器
+func InitPluginRoutes(pluginMgr *plugins.PluginManager, minioSvc *middleware.MinIOService) {
 	web.Router("/", &controllers.RootController{}, "get:Index")
 	web.Router("/health", &controllers.HealthController{}, "get:Health")
+	web.Router("/metrics", &controllers.MetricsController{}, "get:Metrics")
 
 	// 插件服务路由
-	pluginServiceController := &controllers.PluginServiceController{}
+	pluginServiceController := &controllers.PluginServiceController{PluginMgr: pluginMgr, MinioSvc: minioSvc}
 	web.Router("/api/plugins/upload", pluginServiceController, "post:Upload")
 	web.Router("/api/plugins", pluginServiceController, "get:List")
 	web.Router("/api/plugins/:id/models", pluginServiceController, "post:GetModels")
@@ -22,6 +25,10 @@ func InitPluginRoutes() {
 	web.Router("/api/plugins/:id/enable", pluginServiceController, "post:Enable")
 	web.Router("/api/plugins/:id/disable", pluginServiceController, "post:Disable")
 	web.Router("/api/plugins/:id", pluginServiceController, "delete:Delete")
+	// 插件版本管理
+	web.Router("/api/plugins/:id/versions", pluginServiceController, "get:ListVersions")
+	web.Router("/api/plugins/:id/versions/:version/activate", pluginServiceController, "post:ActivateVersion")
+	web.Router("/api/plugins/:id/rollback", pluginServiceController, "post:Rollback")
 	// 插件功能接口（供其他服务调用）
 	web.Router("/api/plugins/:id/embed", pluginServiceController, "post:Embed")
 	web.Router("/api/plugins/:id/rerank", pluginServiceController, "post:Rerank")
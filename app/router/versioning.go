@@ -9,6 +9,7 @@ import (
 
 	"github.com/aihub/backend-go/app/controllers"
 	"github.com/aihub/backend-go/internal/auth"
+	"github.com/aihub/backend-go/internal/config"
 	"github.com/aihub/backend-go/internal/errors"
 	"github.com/aihub/backend-go/internal/interfaces"
 	"github.com/beego/beego/v2/server/web"
@@ -41,6 +42,9 @@ type VersionManager struct {
 
 // NewVersionManager 创建版本管理器
 func NewVersionManager(logger interfaces.LoggerInterface, errorHandler *errors.ErrorHandler) *VersionManager {
+	// 初始化JWT服务，密钥取自auth.secret
+	jwtService := auth.NewJWTService(config.GetAppConfig().JWT.Secret, "aihub-backend", 24*time.Hour)
+
 	config := &VersionConfig{
 		DefaultVersion: APIVersionV1,
 		SupportedVersions: []APIVersion{
@@ -50,9 +54,6 @@ func NewVersionManager(logger interfaces.LoggerInterface, errorHandler *errors.E
 		DeprecatedVersions: []APIVersion{}, // 目前没有废弃版本
 	}
 
-	// 初始化JWT服务
-	jwtService := auth.NewJWTService("your-secret-key", "aihub-backend", 24*time.Hour)
-
 	return &VersionManager{
 		config:        config,
 		logger:        logger,
@@ -325,6 +326,7 @@ func (vm *VersionManager) authenticateRequest(ctx *beecontext.Context) error {
 	ctx.Input.SetData("username", claims.Username)
 	ctx.Input.SetData("email", claims.Email)
 	ctx.Input.SetData("roles", claims.Roles)
+	ctx.Input.SetData("org_id", claims.OrgID)
 
 	return nil
 }
@@ -0,0 +1,253 @@
+// 检索效果离线评测工具
+//
+// 在知识库上运行标注的查询集（JSONL，每行{"query":...,"relevant_document_ids":[...]}或relevant_chunk_ids），
+// 按多组检索配置输出recall@k、MRR、nDCG与延迟百分位，并保存到search_evaluation_runs以便跨时间对比。
+// 只使用数据库全文索引、数据库向量存储与确定性的哈希向量化，不依赖任何外部服务，也不做查询转换。
+// -reindex会覆盖已保存的向量，只能在评测专用的数据库副本上使用，必须通过-dsn显式指定。
+//
+//	go run ./cmd/evaluate -kb 12 -queries testdata/hr.jsonl -reindex -dsn postgres://localhost/aihub_eval
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aihub/backend-go/internal/config/v2"
+	"github.com/aihub/backend-go/internal/knowledge"
+	"github.com/aihub/backend-go/internal/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func main() {
+	var (
+		kbID        = flag.Uint("kb", 0, "Knowledge base ID")
+		queriesPath = flag.String("queries", "", "Labeled query set (JSONL)")
+		configsPath = flag.String("configs", "", "Search configurations (JSON array), defaults to mode/fusion/rerank grid")
+		ksFlag      = flag.String("k", "1,5,10", "Cutoffs for recall@k and nDCG@k")
+		dims        = flag.Int("dims", 256, "Dimensions of the deterministic hash embedder")
+		reindex     = flag.Bool("reindex", false, "Rewrite hash embeddings and full-text vectors for every chunk first (overwrites stored embeddings, requires -dsn)")
+		dsn         = flag.String("dsn", "", "Database URL of a scratch copy dedicated to evaluation, defaults to the configured database")
+		name        = flag.String("name", "", "Run name stored with the results")
+		save        = flag.Bool("save", true, "Store the results in search_evaluation_runs")
+		output      = flag.String("output", "table", "Output format: table, json")
+	)
+	flag.Parse()
+
+	if *kbID == 0 || *queriesPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	ks, err := parseKs(*ksFlag)
+	if err != nil {
+		log.Fatalf("Invalid -k: %v", err)
+	}
+
+	file, err := os.Open(*queriesPath)
+	if err != nil {
+		log.Fatalf("Failed to open query set: %v", err)
+	}
+	queries, err := knowledge.ParseEvalQueries(file)
+	file.Close()
+	if err != nil {
+		log.Fatalf("Failed to parse query set: %v", err)
+	}
+
+	var configs []knowledge.EvalConfig
+	if *configsPath != "" {
+		data, err := os.ReadFile(*configsPath)
+		if err != nil {
+			log.Fatalf("Failed to read configs: %v", err)
+		}
+		if err := json.Unmarshal(data, &configs); err != nil {
+			log.Fatalf("Failed to parse configs: %v", err)
+		}
+	}
+
+	cfg, err := v2.NewConfigLoader().Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	databaseURL := cfg.Database.URL
+	if *reindex {
+		if *dsn == "" {
+			log.Fatalf("-reindex overwrites stored embeddings, pass -dsn with a scratch database dedicated to evaluation")
+		}
+		if *dsn == cfg.Database.URL {
+			log.Fatalf("-dsn points to the configured application database, refusing to reindex it")
+		}
+	}
+	if *dsn != "" {
+		databaseURL = *dsn
+	}
+	db, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{Logger: logger.Default.LogMode(logger.Warn)})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	ctx := context.Background()
+	embedder := knowledge.NewHashEmbedder(*dims)
+	indexer := knowledge.NewDatabaseIndexer(db)
+	store := knowledge.NewDatabaseVectorStore(db)
+	if *reindex {
+		n, err := prepare(ctx, db, uint(*kbID), embedder, indexer, store)
+		if err != nil {
+			log.Fatalf("Failed to prepare knowledge base: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "Prepared %d chunks\n", n)
+	}
+
+	// 离线评测不配置重排序与查询转换，rerank开关不影响结果
+	engine := knowledge.NewHybridSearchEngine(indexer, store, embedder, nil)
+	results, err := engine.Evaluate(ctx, uint(*kbID), queries, configs, ks)
+	if err != nil {
+		log.Fatalf("Evaluation failed: %v", err)
+	}
+
+	if *save {
+		encoded, _ := json.Marshal(results)
+		run := models.SearchEvaluationRun{
+			KnowledgeBaseID: uint(*kbID),
+			Name:            *name,
+			QuerySetHash:    knowledge.QuerySetHash(queries),
+			QueryCount:      len(queries),
+			Embedder:        knowledge.DescribeEmbedder(embedder),
+			Results:         string(encoded),
+			CreateTime:      time.Now(),
+		}
+		if err := db.WithContext(ctx).Create(&run).Error; err != nil {
+			log.Fatalf("Failed to save evaluation run: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "Saved run %d (query set %s)\n", run.RunID, run.QuerySetHash[:12])
+	}
+
+	switch *output {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			log.Fatalf("Failed to write results: %v", err)
+		}
+	default:
+		printTable(results, ks)
+	}
+}
+
+func parseKs(value string) ([]int, error) {
+	var ks []int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		k, err := strconv.Atoi(part)
+		if err != nil || k <= 0 {
+			return nil, fmt.Errorf("invalid cutoff %q", part)
+		}
+		ks = append(ks, k)
+	}
+	if len(ks) == 0 {
+		return nil, fmt.Errorf("no cutoffs")
+	}
+	return ks, nil
+}
+
+// prepare 用哈希向量化重写知识库全部分块的向量与全文索引，使检索结果可复现
+func prepare(ctx context.Context, db *gorm.DB, kbID uint, embedder knowledge.Embedder, indexer knowledge.FulltextIndexer, store knowledge.VectorStore) (int, error) {
+	type chunkRow struct {
+		ChunkID    uint
+		DocumentID uint
+		Content    string
+		ChunkIndex int
+		FileName   string
+	}
+
+	var lastID uint
+	total := 0
+	for {
+		var rows []chunkRow
+		err := db.WithContext(ctx).
+			Table("knowledge_chunks AS c").
+			Select("c.chunk_id, c.document_id, c.content, c.chunk_index, d.title AS file_name").
+			Joins("JOIN knowledge_documents d ON c.document_id = d.document_id").
+			Where("d.knowledge_base_id = ? AND c.is_active IS NOT FALSE AND c.chunk_id > ?", kbID, lastID).
+			Order("c.chunk_id").
+			Limit(500).
+			Scan(&rows).Error
+		if err != nil {
+			return total, err
+		}
+		if len(rows) == 0 {
+			return total, nil
+		}
+
+		for _, row := range rows {
+			if err := indexer.IndexChunk(ctx, knowledge.FulltextChunk{
+				ChunkID:         row.ChunkID,
+				DocumentID:      row.DocumentID,
+				KnowledgeBaseID: kbID,
+				Content:         row.Content,
+				ChunkIndex:      row.ChunkIndex,
+				FileName:        row.FileName,
+			}); err != nil {
+				return total, err
+			}
+			embedding, err := embedder.Embed(ctx, row.Content)
+			if err != nil {
+				continue
+			}
+			if _, err := store.UpsertChunk(ctx, knowledge.VectorChunk{
+				ChunkID:         row.ChunkID,
+				DocumentID:      row.DocumentID,
+				KnowledgeBaseID: kbID,
+				Text:            row.Content,
+				Embedding:       embedding,
+			}); err != nil {
+				return total, err
+			}
+		}
+		total += len(rows)
+		lastID = rows[len(rows)-1].ChunkID
+	}
+}
+
+func printTable(results []knowledge.EvalResult, ks []int) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := []string{"config"}
+	for _, k := range ks {
+		header = append(header, fmt.Sprintf("recall@%d", k))
+	}
+	for _, k := range ks {
+		header = append(header, fmt.Sprintf("ndcg@%d", k))
+	}
+	header = append(header, "mrr", "p50(ms)", "p90(ms)", "p99(ms)", "errors")
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, result := range results {
+		m := result.Metrics
+		row := []string{result.Config.Name}
+		for _, k := range ks {
+			row = append(row, fmt.Sprintf("%.3f", m.Recall[k]))
+		}
+		for _, k := range ks {
+			row = append(row, fmt.Sprintf("%.3f", m.NDCG[k]))
+		}
+		row = append(row,
+			fmt.Sprintf("%.3f", m.MRR),
+			fmt.Sprintf("%.1f", m.LatencyP50Ms),
+			fmt.Sprintf("%.1f", m.LatencyP90Ms),
+			fmt.Sprintf("%.1f", m.LatencyP99Ms),
+			strconv.Itoa(m.Errors))
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+}
@@ -8,7 +8,9 @@ import (
 	"net"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/aihub/backend-go/app/bootstrap"
 	"github.com/aihub/backend-go/app/router"
@@ -56,6 +58,7 @@ func main() {
 		AutoDiscover: false,
 		AutoLoad:     false,
 	}
+	loadCallLimits(&cfg)
 	pluginMgr, err := plugins.NewPluginManager(cfg)
 	if err != nil {
 		log.Printf("[plugin] Failed to create plugin manager: %v", err)
@@ -76,7 +79,7 @@ func main() {
 	}()
 
 	// 初始化插件服务路由
-	router.InitPluginRoutes()
+	router.InitPluginRoutes(pluginMgr, minioSvc)
 
 	// 配置Beego全局设置
 	web.BConfig.AppName = "Plugin Service"
@@ -97,7 +100,11 @@ func startGRPCServer(port string, pluginMgr *plugins.PluginManager, minioSvc *mi
 		log.Fatalf("[plugin-grpc] Failed to listen: %v", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	interceptor := plugins.NewPluginCallInterceptor(pluginMgr)
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(interceptor.Unary()),
+		grpc.ChainStreamInterceptor(interceptor.Stream()),
+	)
 	pluginService := plugins.NewPluginGRPCServer(pluginMgr, minioSvc)
 	plugin_service.RegisterPluginServiceServer(grpcServer, pluginService)
 
@@ -107,3 +114,55 @@ func startGRPCServer(port string, pluginMgr *plugins.PluginManager, minioSvc *mi
 	}
 }
 
+// loadCallLimits 从环境变量加载插件调用限制
+//
+//	PLUGIN_CALL_TIMEOUT=30s
+//	PLUGIN_MAX_CONCURRENT_CALLS=16
+//	PLUGIN_CALL_LIMITS=dashscope:60s:8,openai:20s:4
+func loadCallLimits(cfg *plugins.ManagerConfig) {
+	if v := os.Getenv("PLUGIN_CALL_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.CallTimeout = d
+		} else {
+			log.Printf("[plugin] Invalid PLUGIN_CALL_TIMEOUT %q: %v", v, err)
+		}
+	}
+	if v := os.Getenv("PLUGIN_MAX_CONCURRENT_CALLS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.MaxConcurrentCalls = n
+		} else {
+			log.Printf("[plugin] Invalid PLUGIN_MAX_CONCURRENT_CALLS %q: %v", v, err)
+		}
+	}
+
+	v := os.Getenv("PLUGIN_CALL_LIMITS")
+	if v == "" {
+		return
+	}
+	cfg.CallLimits = make(map[string]plugins.CallLimit)
+	for _, item := range strings.Split(v, ",") {
+		parts := strings.Split(strings.TrimSpace(item), ":")
+		if len(parts) != 3 {
+			log.Printf("[plugin] Invalid PLUGIN_CALL_LIMITS entry %q, expected plugin_id:timeout:max_concurrent", item)
+			continue
+		}
+		var limit plugins.CallLimit
+		if parts[1] != "" {
+			d, err := time.ParseDuration(parts[1])
+			if err != nil {
+				log.Printf("[plugin] Invalid timeout in PLUGIN_CALL_LIMITS entry %q: %v", item, err)
+				continue
+			}
+			limit.Timeout = d
+		}
+		if parts[2] != "" {
+			n, err := strconv.Atoi(parts[2])
+			if err != nil {
+				log.Printf("[plugin] Invalid max_concurrent in PLUGIN_CALL_LIMITS entry %q: %v", item, err)
+				continue
+			}
+			limit.MaxConcurrent = n
+		}
+		cfg.CallLimits[parts[0]] = limit
+	}
+}
@@ -0,0 +1,115 @@
+// 知识库跨存储一致性检查工具
+//
+// 以knowledge_chunks中的有效分块为准，对比全文索引、向量存储、Redis分块缓存与MinIO对象，
+// 输出缺失、孤立与内容过期的数据；-repair时重新写入缺失与过期的分块并删除孤立数据。
+// 检查结果保存在knowledge_reconcile_runs，与服务中的定时检查共用记录，同一知识库不会同时检查。
+//
+//	go run ./cmd/reconcile -kb 12
+//	go run ./cmd/reconcile -repair -output json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/aihub/backend-go/app/bootstrap"
+	"github.com/aihub/backend-go/internal/knowledge"
+	"github.com/aihub/backend-go/internal/services"
+)
+
+func main() {
+	var (
+		kbID   = flag.Uint("kb", 0, "Knowledge base ID, 0 checks every knowledge base")
+		repair = flag.Bool("repair", false, "Re-index missing and stale chunks and delete orphaned data")
+		output = flag.String("output", "table", "Output format: table, json")
+	)
+	flag.Parse()
+
+	app, err := bootstrap.Init()
+	if err != nil {
+		log.Fatalf("Failed to bootstrap application: %v", err)
+	}
+	defer app.Shutdown()
+
+	var rs *services.ReconcileService
+	if err := app.GetContainer().Invoke(func(s *services.ReconcileService) { rs = s }); err != nil {
+		log.Fatalf("Failed to resolve reconcile service: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ids := []uint{uint(*kbID)}
+	if *kbID == 0 {
+		if ids, err = rs.KnowledgeBaseIDs(ctx); err != nil {
+			log.Fatalf("Failed to list knowledge bases: %v", err)
+		}
+	}
+
+	var reports []*knowledge.ReconcileReport
+	failed := false
+	for _, id := range ids {
+		run, report, err := rs.Reconcile(ctx, id, *repair)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Knowledge base %d: %v\n", id, err)
+			failed = true
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		if report != nil {
+			reports = append(reports, report)
+		}
+		if run != nil && run.Error != "" {
+			failed = true
+		}
+	}
+
+	switch *output {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			log.Fatalf("Failed to write reports: %v", err)
+		}
+	default:
+		printTable(reports)
+	}
+
+	if failed {
+		app.Shutdown()
+		os.Exit(1)
+	}
+}
+
+func printTable(reports []*knowledge.ReconcileReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KB\tSTORE\tCHECKED\tMISSING\tORPHANED\tSTALE\tREPAIRED\tFAILED\tERROR")
+	for _, report := range reports {
+		for _, store := range report.Stores {
+			fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%s\n", report.KnowledgeBaseID, store.Store,
+				store.Checked, store.Missing, store.Orphaned, store.Stale, store.Repaired, store.RepairFailed, store.Error)
+		}
+	}
+	w.Flush()
+
+	for _, report := range reports {
+		for _, d := range report.Discrepancies {
+			target := d.Object
+			if target == "" {
+				target = fmt.Sprintf("document %d chunk %d", d.DocumentID, d.ChunkID)
+			}
+			fmt.Fprintf(os.Stderr, "kb %d %s %s: %s\n", report.KnowledgeBaseID, d.Store, d.Kind, target)
+		}
+		if report.Truncated {
+			fmt.Fprintf(os.Stderr, "kb %d: discrepancy list truncated\n", report.KnowledgeBaseID)
+		}
+	}
+}
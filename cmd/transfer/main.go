@@ -0,0 +1,176 @@
+// 知识库导出导入工具
+//
+// export把知识库写成单个zip归档（清单、知识库配置、文档源文件、提取的文本、分块，可选向量）；
+// import上传归档并创建导入任务，在新的所有者下重建知识库，等待任务结束并输出进度。
+// 导入任务与服务中的后台导入共用knowledge_import_jobs，中断后用-resume从断点继续。
+//
+//	go run ./cmd/transfer export -kb 12 -owner 1 -embeddings -o kb-12.zip
+//	go run ./cmd/transfer import -owner 7 -name "产品手册" kb-12.zip
+//	go run ./cmd/transfer import -owner 7 -resume 3
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/aihub/backend-go/app/bootstrap"
+	"github.com/aihub/backend-go/internal/models"
+	"github.com/aihub/backend-go/internal/services"
+	"github.com/aihub/backend-go/internal/transfer"
+)
+
+// importPollInterval 任务由其他进程执行时查询进度的间隔
+const importPollInterval = 2 * time.Second
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "export":
+		runExport(os.Args[2:])
+	case "import":
+		runImport(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: transfer export -kb ID -owner ID [-embeddings] [-o FILE]")
+	fmt.Fprintln(os.Stderr, "       transfer import -owner ID [-name NAME] ARCHIVE")
+	fmt.Fprintln(os.Stderr, "       transfer import -owner ID -resume JOB_ID")
+	os.Exit(2)
+}
+
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	var (
+		kbID       = fs.Uint("kb", 0, "Knowledge base ID")
+		ownerID    = fs.Uint("owner", 0, "Owner user ID of the knowledge base")
+		embeddings = fs.Bool("embeddings", false, "Include chunk embeddings and their model")
+		output     = fs.String("o", "", "Archive file, defaults to knowledge-base-<kb>.zip")
+	)
+	fs.Parse(args)
+	if *kbID == 0 || *ownerID == 0 {
+		usage()
+	}
+	if *output == "" {
+		*output = fmt.Sprintf("knowledge-base-%d.zip", *kbID)
+	}
+
+	app, ts := initTransfer()
+	defer app.Shutdown()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	f, err := os.Create(*output)
+	if err != nil {
+		log.Fatalf("Failed to create archive: %v", err)
+	}
+	manifest, err := ts.Export(ctx, uint(*kbID), uint(*ownerID), *embeddings, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(*output)
+		fail(app, "Failed to export knowledge base: %v", err)
+	}
+	fmt.Printf("Exported knowledge base %d to %s: %d documents, %d chunks, embeddings %t\n",
+		*kbID, *output, manifest.Documents, manifest.Chunks, manifest.IncludesEmbeddings)
+}
+
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	var (
+		ownerID = fs.Uint("owner", 0, "Owner user ID of the new knowledge base")
+		name    = fs.String("name", "", "Name of the new knowledge base, defaults to the archived name")
+		resume  = fs.Uint("resume", 0, "Resume a failed import job instead of uploading an archive")
+	)
+	fs.Parse(args)
+	if *ownerID == 0 || (*resume == 0 && fs.NArg() != 1) {
+		usage()
+	}
+
+	app, ts := initTransfer()
+	defer app.Shutdown()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var job *models.KnowledgeImportJob
+	var err error
+	if *resume > 0 {
+		job, err = ts.ResumeImport(ctx, uint(*resume), uint(*ownerID))
+	} else {
+		job, err = createImport(ctx, ts, uint(*ownerID), *name, fs.Arg(0))
+	}
+	if err != nil {
+		fail(app, "Failed to start import: %v", err)
+	}
+	fmt.Printf("Import job %d: %d documents, %d chunks\n", job.JobID, job.TotalDocuments, job.TotalChunks)
+
+	// 后台导入可能已经领取了任务，RunImport返回冲突时等待其结束
+	if running, rerr := ts.RunImport(ctx, job.JobID); running != nil {
+		job = running
+	} else if rerr != nil {
+		fail(app, "Failed to run import: %v", rerr)
+	}
+	for err == nil && (job.Status == models.ImportPending || job.Status == models.ImportRunning) {
+		fmt.Printf("Import job %d: %s %.0f%%\n", job.JobID, job.Stage, transfer.Progress(job)*100)
+		select {
+		case <-ctx.Done():
+			fail(app, "Interrupted, resume with -resume %d once the job fails or its lease expires", job.JobID)
+		case <-time.After(importPollInterval):
+		}
+		job, err = ts.GetImport(ctx, job.JobID, uint(*ownerID))
+	}
+	if err != nil {
+		fail(app, "Failed to read import job: %v", err)
+	}
+
+	if job.Status != models.ImportSucceeded {
+		fail(app, "Import job %d failed at stage %s: %s (resume with -resume %d)", job.JobID, job.Stage, job.Error, job.JobID)
+	}
+	fmt.Printf("Imported knowledge base %d: %d documents, %d chunks, embeddings reused %t\n",
+		job.KnowledgeBaseID, job.ImportedDocuments, job.ImportedChunks, job.ReuseEmbeddings)
+}
+
+func createImport(ctx context.Context, ts *services.TransferService, ownerID uint, name, path string) (*models.KnowledgeImportJob, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return ts.CreateImport(ctx, ownerID, name, filepath.Base(path), f, info.Size())
+}
+
+func initTransfer() (*bootstrap.App, *services.TransferService) {
+	app, err := bootstrap.Init()
+	if err != nil {
+		log.Fatalf("Failed to bootstrap application: %v", err)
+	}
+	var ts *services.TransferService
+	if err := app.GetContainer().Invoke(func(s *services.TransferService) { ts = s }); err != nil {
+		log.Fatalf("Failed to resolve transfer service: %v", err)
+	}
+	return app, ts
+}
+
+func fail(app *bootstrap.App, format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	app.Shutdown()
+	os.Exit(1)
+}
@@ -215,10 +215,42 @@ func (p *DashScopePlugin) Embed(ctx context.Context, text string) ([]float32, er
 		return nil, fmt.Errorf("text is empty")
 	}
 
+	results, err := p.createEmbeddings(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// EmbedBatch 批量向量化，按模型的单次上限拆分请求
+func (p *DashScopePlugin) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	// v3/v4模型单次最多10条，其余模型最多25条
+	batchSize := 25
+	if p.embeddingModel == "text-embedding-v3" || p.embeddingModel == "text-embedding-v4" {
+		batchSize = 10
+	}
+
+	results := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		end := start + batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := p.createEmbeddings(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, batch...)
+	}
+	return results, nil
+}
+
+// createEmbeddings 调用DashScope Embedding API，结果按输入顺序返回
+func (p *DashScopePlugin) createEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
 	// 构建请求
 	reqBody := map[string]interface{}{
 		"model":           p.embeddingModel,
-		"input":           []string{text},
+		"input":           texts,
 		"encoding_format": "float",
 	}
 
@@ -257,6 +289,7 @@ func (p *DashScopePlugin) Embed(ctx context.Context, text string) ([]float32, er
 	var embeddingResp struct {
 		Data []struct {
 			Embedding []float64 `json:"embedding"`
+			Index     int       `json:"index"`
 		} `json:"data"`
 	}
 
@@ -264,18 +297,25 @@ func (p *DashScopePlugin) Embed(ctx context.Context, text string) ([]float32, er
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	if len(embeddingResp.Data) == 0 {
-		return nil, fmt.Errorf("empty embedding response")
+	if len(embeddingResp.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding response size mismatch: got %d, want %d", len(embeddingResp.Data), len(texts))
 	}
 
-	// 转换float64到float32
-	embedding := embeddingResp.Data[0].Embedding
-	result := make([]float32, len(embedding))
-	for i, v := range embedding {
-		result[i] = float32(v)
+	// 转换float64到float32，按index归位
+	results := make([][]float32, len(texts))
+	for i, data := range embeddingResp.Data {
+		pos := data.Index
+		if pos < 0 || pos >= len(texts) || results[pos] != nil {
+			pos = i
+		}
+		embedding := make([]float32, len(data.Embedding))
+		for j, v := range data.Embedding {
+			embedding[j] = float32(v)
+		}
+		results[pos] = embedding
 	}
 
-	return result, nil
+	return results, nil
 }
 
 // Rerank 重排序文档
@@ -401,6 +441,7 @@ func (p *DashScopePlugin) Chat(ctx context.Context, req plugins.ChatRequest) (*p
 	if req.TopK > 0 {
 		chatReq["top_k"] = req.TopK
 	}
+	applyChatOptions(chatReq, req)
 
 	jsonData, err := json.Marshal(chatReq)
 	if err != nil {
@@ -437,6 +478,31 @@ func (p *DashScopePlugin) Chat(ctx context.Context, req plugins.ChatRequest) (*p
 	return &chatResp, nil
 }
 
+// applyChatOptions 设置停止词、工具等可选参数
+func applyChatOptions(chatReq map[string]interface{}, req plugins.ChatRequest) {
+	if len(req.Stop) > 0 {
+		chatReq["stop"] = req.Stop
+	}
+	if len(req.Tools) > 0 {
+		chatReq["tools"] = req.Tools
+		switch req.ToolChoice {
+		case "":
+		case "auto", "none", "required":
+			chatReq["tool_choice"] = req.ToolChoice
+		default:
+			chatReq["tool_choice"] = map[string]interface{}{
+				"type":     "function",
+				"function": map[string]string{"name": req.ToolChoice},
+			}
+		}
+	}
+	for key, value := range req.ExtraParams {
+		if _, exists := chatReq[key]; !exists {
+			chatReq[key] = value
+		}
+	}
+}
+
 // ChatStream 流式聊天
 func (p *DashScopePlugin) ChatStream(ctx context.Context, req plugins.ChatRequest, onChunk func([]byte) error) error {
 	// 构建请求
@@ -455,6 +521,15 @@ func (p *DashScopePlugin) ChatStream(ctx context.Context, req plugins.ChatReques
 	if req.MaxTokens > 0 {
 		chatReq["max_tokens"] = req.MaxTokens
 	}
+	if req.TopP > 0 {
+		chatReq["top_p"] = req.TopP
+	}
+	if req.TopK > 0 {
+		chatReq["top_k"] = req.TopK
+	}
+	applyChatOptions(chatReq, req)
+	// 在最后一个分片中返回用量
+	chatReq["stream_options"] = map[string]interface{}{"include_usage": true}
 
 	jsonData, err := json.Marshal(chatReq)
 	if err != nil {
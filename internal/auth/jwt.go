@@ -14,6 +14,7 @@ type JWTClaims struct {
 	Username string   `json:"username"`
 	Email    string   `json:"email"`
 	Roles    []string `json:"roles"`
+	OrgID    uint     `json:"org_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -38,12 +39,18 @@ func NewJWTService(secretKey string, issuer string, expiresIn time.Duration) *JW
 
 // GenerateToken 生成JWT token
 func (j *JWTService) GenerateToken(userID uint, username, email string, roles []string) (string, error) {
+	return j.GenerateOrgToken(userID, 0, username, email, roles)
+}
+
+// GenerateOrgToken 生成携带所属组织的JWT token
+func (j *JWTService) GenerateOrgToken(userID, orgID uint, username, email string, roles []string) (string, error) {
 	now := time.Now()
 	claims := &JWTClaims{
 		UserID:   userID,
 		Username: username,
 		Email:    email,
 		Roles:    roles,
+		OrgID:    orgID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    j.issuer,
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -95,7 +102,7 @@ func (j *JWTService) RefreshToken(tokenString string) (string, error) {
 	}
 
 	// 生成新token
-	return j.GenerateToken(claims.UserID, claims.Username, claims.Email, claims.Roles)
+	return j.GenerateOrgToken(claims.UserID, claims.OrgID, claims.Username, claims.Email, claims.Roles)
 }
 
 // ExtractTokenFromHeader 从请求头提取token
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -78,6 +79,10 @@ func GetAppConfig() *Config {
 				ChunkSize:    1000,
 				ChunkOverlap: 200,
 				MaxParallel:  5,
+				Transfer: TransferConfig{
+					ArchiveDir:       "./data/imports",
+					MaxArchiveSizeMB: 1024,
+				},
 			},
 			Payment: PaymentConfig{
 				WeChatPay: WeChatPayConfig{
@@ -90,6 +95,13 @@ func GetAppConfig() *Config {
 			Provider: ProviderConfig{
 				CatalogCacheTTLSeconds: 300,
 			},
+			Standalone: StandaloneConfig{
+				Enabled: globalConfig.Standalone.Enabled,
+				DataDir: globalConfig.Standalone.DataDir,
+			},
+		}
+		if globalOldConfig.Standalone.Enabled {
+			applyStandalone(globalOldConfig)
 		}
 	})
 
@@ -110,6 +122,34 @@ type Config struct {
 	Payment    PaymentConfig
 	Knowledge  KnowledgeConfig
 	Provider   ProviderConfig
+	Standalone StandaloneConfig
+}
+
+// StandaloneConfig 单机模式配置，由standalone.enabled开启
+type StandaloneConfig struct {
+	Enabled bool
+	DataDir string // 数据库文件、对象、索引与归档的根目录
+}
+
+// applyStandalone 单机模式下把各存储切换为内嵌实现，数据统一放在DataDir下
+func applyStandalone(cfg *Config) {
+	dir := cfg.Standalone.DataDir
+	if dir == "" {
+		dir = "./data"
+		cfg.Standalone.DataDir = dir
+	}
+	cfg.Database.URL = "sqlite://" + filepath.Join(dir, "aihub.db")
+	cfg.Kafka.Enabled = false
+	cfg.Consul.Enabled = false
+	cfg.Etcd.Enabled = false
+	cfg.Knowledge.Storage = ObjectStorageConfig{
+		Provider: "local",
+		BasePath: filepath.Join(dir, "objects"),
+	}
+	cfg.Knowledge.Search.Provider = "bm25"
+	cfg.Knowledge.Search.BM25.Dir = filepath.Join(dir, "bm25")
+	cfg.Knowledge.VectorStore.Provider = "database"
+	cfg.Knowledge.Transfer.ArchiveDir = filepath.Join(dir, "imports")
 }
 
 type ConsulConfig struct {
@@ -203,7 +243,10 @@ type KnowledgeConfig struct {
 	VectorStore  VectorStoreConfig
 	Embedding    EmbeddingConfig
 	Rerank       RerankConfig
-	LongText     LongTextConfig // 超长文本RAG配置
+	LongText     LongTextConfig  // 超长文本RAG配置
+	Trash        TrashConfig     // 回收站配置
+	Reconcile    ReconcileConfig // 跨存储一致性检查配置
+	Transfer     TransferConfig  // 知识库导出导入配置
 }
 
 type ProviderConfig struct {
@@ -221,8 +264,17 @@ type ObjectStorageConfig struct {
 }
 
 type SearchConfig struct {
-	Provider      string
+	Provider      string // elasticsearch / postgres / bm25
 	Elasticsearch ElasticsearchConfig
+	BM25          BM25Config
+}
+
+// BM25Config 内嵌BM25全文索引配置
+type BM25Config struct {
+	Dir            string
+	DictionaryPath string
+	FlushThreshold int
+	MergeThreshold int
 }
 
 type ElasticsearchConfig struct {
@@ -231,11 +283,15 @@ type ElasticsearchConfig struct {
 	Password    string
 	APIKey      string
 	IndexPrefix string
+	// IK远程扩展词典的共享密钥，为空时不开放词典接口
+	IKDictionaryToken string
 }
 
 type VectorStoreConfig struct {
-	Provider string
+	Provider string // milvus、pgvector或database
 	Milvus   MilvusConfig
+	Pgvector PgvectorConfig
+	Database DatabaseVectorConfig
 }
 
 type MilvusConfig struct {
@@ -249,12 +305,50 @@ type MilvusConfig struct {
 	Distance   string
 }
 
+// PgvectorConfig pgvector向量存储配置，向量写入主数据库
+type PgvectorConfig struct {
+	TablePrefix    string // 维度表名前缀，默认knowledge_vectors
+	Index          string // hnsw或ivfflat
+	Distance       string
+	M              int
+	EfConstruction int
+	Lists          int
+	Probes         int
+}
+
+// DatabaseVectorConfig 数据库向量存储的量化与缓存配置
+type DatabaseVectorConfig struct {
+	Quantization        string // none、int8或binary
+	RescoreLimit        int    // 量化候选中精确重新打分的数量，0按量化方案取默认倍数
+	CacheKnowledgeBases int    // 进程内缓存向量的知识库数，0不缓存
+	CacheTTLSeconds     int
+}
+
 type EmbeddingConfig struct {
 	ProviderCode string
 	ModelCode    string
 	CredentialID uint
 }
 
+// TrashConfig 回收站配置
+type TrashConfig struct {
+	RetentionDays       int // 删除后保留天数，期满后清理各存储中的数据
+	PurgeIntervalSecond int // 清理任务的轮询间隔
+}
+
+// ReconcileConfig 跨存储一致性检查配置
+type ReconcileConfig struct {
+	IntervalHour int  // 每个知识库的检查间隔，0使用默认值，负数关闭定时检查
+	Repair       bool // 定时检查时是否自动修复
+}
+
+// TransferConfig 知识库导出导入配置
+type TransferConfig struct {
+	ArchiveDir         string // 没有MinIO时保存待导入归档的本地目录
+	PollIntervalSecond int    // 导入任务的轮询间隔
+	MaxArchiveSizeMB   int    // 上传归档的大小上限
+}
+
 type RerankConfig struct {
 	Enabled      bool
 	ProviderCode string
@@ -368,6 +462,9 @@ func LoadConfig() error {
 	viper.SetDefault("knowledge.search.provider", "elasticsearch")
 	viper.SetDefault("knowledge.search.elasticsearch.addresses", []string{"http://localhost:9200"})
 	viper.SetDefault("knowledge.search.elasticsearch.index_prefix", "knowledge_chunks")
+	viper.SetDefault("knowledge.search.bm25.dir", "./data/bm25")
+	viper.SetDefault("knowledge.search.bm25.flush_threshold", 1000)
+	viper.SetDefault("knowledge.search.bm25.merge_threshold", 8)
 	viper.SetDefault("knowledge.vector_store.provider", "memory")
 	viper.SetDefault("knowledge.vector_store.milvus.address", "localhost:19530")
 	viper.SetDefault("knowledge.vector_store.milvus.collection", "kb_vectors")
@@ -398,6 +495,11 @@ func LoadConfig() error {
 	viper.SetDefault("knowledge.long_text.max_tokens", 1000000) // 100万token阈值
 	viper.SetDefault("knowledge.long_text.fallback_mode", true)
 	viper.SetDefault("knowledge.long_text.related_chunk_size", 1) // 前后各1块
+	viper.SetDefault("knowledge.trash.retention_days", 30)
+	viper.SetDefault("knowledge.trash.purge_interval_second", 300)
+	viper.SetDefault("knowledge.transfer.archive_dir", "./data/imports")
+	viper.SetDefault("knowledge.transfer.poll_interval_second", 30)
+	viper.SetDefault("knowledge.transfer.max_archive_size_mb", 1024)
 
 	// Provider config defaults
 	viper.SetDefault("provider.catalog_cache_ttl_seconds", 300)
@@ -667,11 +769,18 @@ func LoadConfig() error {
 			Search: SearchConfig{
 				Provider: viper.GetString("knowledge.search.provider"),
 				Elasticsearch: ElasticsearchConfig{
-					Addresses:   viper.GetStringSlice("knowledge.search.elasticsearch.addresses"),
-					Username:    viper.GetString("knowledge.search.elasticsearch.username"),
-					Password:    viper.GetString("knowledge.search.elasticsearch.password"),
-					APIKey:      viper.GetString("knowledge.search.elasticsearch.api_key"),
-					IndexPrefix: viper.GetString("knowledge.search.elasticsearch.index_prefix"),
+					Addresses:         viper.GetStringSlice("knowledge.search.elasticsearch.addresses"),
+					Username:          viper.GetString("knowledge.search.elasticsearch.username"),
+					Password:          viper.GetString("knowledge.search.elasticsearch.password"),
+					APIKey:            viper.GetString("knowledge.search.elasticsearch.api_key"),
+					IndexPrefix:       viper.GetString("knowledge.search.elasticsearch.index_prefix"),
+					IKDictionaryToken: viper.GetString("knowledge.search.elasticsearch.ik_dictionary_token"),
+				},
+				BM25: BM25Config{
+					Dir:            viper.GetString("knowledge.search.bm25.dir"),
+					DictionaryPath: viper.GetString("knowledge.search.bm25.dictionary_path"),
+					FlushThreshold: viper.GetInt("knowledge.search.bm25.flush_threshold"),
+					MergeThreshold: viper.GetInt("knowledge.search.bm25.merge_threshold"),
 				},
 			},
 			VectorStore: VectorStoreConfig{
@@ -686,6 +795,21 @@ func LoadConfig() error {
 					VectorSize: viper.GetInt("knowledge.vector_store.milvus.vector_size"),
 					Distance:   viper.GetString("knowledge.vector_store.milvus.distance"),
 				},
+				Pgvector: PgvectorConfig{
+					TablePrefix:    viper.GetString("knowledge.vector_store.pgvector.table_prefix"),
+					Index:          viper.GetString("knowledge.vector_store.pgvector.index"),
+					Distance:       viper.GetString("knowledge.vector_store.pgvector.distance"),
+					M:              viper.GetInt("knowledge.vector_store.pgvector.m"),
+					EfConstruction: viper.GetInt("knowledge.vector_store.pgvector.ef_construction"),
+					Lists:          viper.GetInt("knowledge.vector_store.pgvector.lists"),
+					Probes:         viper.GetInt("knowledge.vector_store.pgvector.probes"),
+				},
+				Database: DatabaseVectorConfig{
+					Quantization:        viper.GetString("knowledge.vector_store.database.quantization"),
+					RescoreLimit:        viper.GetInt("knowledge.vector_store.database.rescore_limit"),
+					CacheKnowledgeBases: viper.GetInt("knowledge.vector_store.database.cache_knowledge_bases"),
+					CacheTTLSeconds:     viper.GetInt("knowledge.vector_store.database.cache_ttl_seconds"),
+				},
 			},
 			Embedding: EmbeddingConfig{
 				ProviderCode: viper.GetString("knowledge.embedding.provider_code"),
@@ -719,6 +843,19 @@ func LoadConfig() error {
 				FallbackMode:     viper.GetBool("knowledge.long_text.fallback_mode"),
 				RelatedChunkSize: viper.GetInt("knowledge.long_text.related_chunk_size"),
 			},
+			Trash: TrashConfig{
+				RetentionDays:       viper.GetInt("knowledge.trash.retention_days"),
+				PurgeIntervalSecond: viper.GetInt("knowledge.trash.purge_interval_second"),
+			},
+			Reconcile: ReconcileConfig{
+				IntervalHour: viper.GetInt("knowledge.reconcile.interval_hour"),
+				Repair:       viper.GetBool("knowledge.reconcile.repair"),
+			},
+			Transfer: TransferConfig{
+				ArchiveDir:         viper.GetString("knowledge.transfer.archive_dir"),
+				PollIntervalSecond: viper.GetInt("knowledge.transfer.poll_interval_second"),
+				MaxArchiveSizeMB:   viper.GetInt("knowledge.transfer.max_archive_size_mb"),
+			},
 		},
 		Provider: ProviderConfig{
 			CatalogCacheTTLSeconds: viper.GetInt("provider.catalog_cache_ttl_seconds"),
@@ -25,6 +25,7 @@ type ConfigV2 struct {
 	Queue     QueueConfig     `mapstructure:"queue"`
 	Monitor   MonitorConfig   `mapstructure:"monitor"`
 	Knowledge KnowledgeConfig `mapstructure:"knowledge" validate:"required"`
+	Standalone StandaloneConfig `mapstructure:"standalone"`
 }
 
 // ServerConfig 服务器配置
@@ -105,6 +106,13 @@ type LongTextConfig struct {
 	MaxTokens int  `mapstructure:"max_tokens"`
 }
 
+// StandaloneConfig 单机模式配置
+// 开启后数据库、缓存、对象存储、向量与全文索引、消息队列都使用进程内或本地文件实现，数据统一放在DataDir下
+type StandaloneConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	DataDir string `mapstructure:"data_dir"`
+}
+
 // AppConfig 应用基础配置
 type AppConfig struct {
 	Name    string `mapstructure:"name" validate:"required"`
@@ -451,6 +459,10 @@ func (cl *ConfigLoader) setDefaults() {
 	cl.viper.SetDefault("knowledge.rerank.enabled", false)
 	cl.viper.SetDefault("knowledge.long_text.enabled", false)
 	cl.viper.SetDefault("knowledge.long_text.max_tokens", 1000000)
+
+	// 单机模式配置
+	cl.viper.SetDefault("standalone.enabled", false)
+	cl.viper.SetDefault("standalone.data_dir", "./data")
 }
 
 // loadFromEnv 从环境变量加载配置
@@ -128,6 +128,12 @@ func LoadConfigFromConsul(client *Client, prefix string, logger *zap.Logger) (*c
 		cfg.Knowledge.Search.Elasticsearch.Addresses[i] = strings.TrimSpace(cfg.Knowledge.Search.Elasticsearch.Addresses[i])
 	}
 	cfg.Knowledge.Search.Elasticsearch.IndexPrefix = client.GetKVWithDefault(prefix+"/knowledge/search/elasticsearch/index_prefix", "knowledge_chunks")
+	cfg.Knowledge.Search.BM25.Dir = client.GetKVWithDefault(prefix+"/knowledge/search/bm25/dir", "./data/bm25")
+	cfg.Knowledge.Search.BM25.DictionaryPath = client.GetKVWithDefault(prefix+"/knowledge/search/bm25/dictionary_path", "")
+	cfg.Knowledge.Search.BM25.FlushThreshold = parseIntOrDefault(
+		client.GetKVWithDefault(prefix+"/knowledge/search/bm25/flush_threshold", ""), 1000)
+	cfg.Knowledge.Search.BM25.MergeThreshold = parseIntOrDefault(
+		client.GetKVWithDefault(prefix+"/knowledge/search/bm25/merge_threshold", ""), 8)
 
 	// Load Knowledge vector store config
 	cfg.Knowledge.VectorStore.Provider = client.GetKVWithDefault(prefix+"/knowledge/vector_store/provider", "memory")
@@ -247,6 +253,7 @@ func WatchConfig(client *Client, prefix string, callback func(*config.Config) er
 		prefix + "/knowledge/search/provider",
 		prefix + "/knowledge/search/elasticsearch/addresses",
 		prefix + "/knowledge/search/elasticsearch/index_prefix",
+		prefix + "/knowledge/search/bm25/dir",
 		prefix + "/knowledge/vector_store/provider",
 		prefix + "/knowledge/vector_store/milvus/address",
 		prefix + "/knowledge/vector_store/milvus/collection",
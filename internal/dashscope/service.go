@@ -113,6 +113,29 @@ type Error struct {
 	RequestID string `json:"request_id"`
 }
 
+// APIError 接口返回的非200响应，StatusCode用于区分可重试的错误
+type APIError struct {
+	StatusCode int
+	Detail     *Error // 响应体不是错误结构时为nil
+	Body       string
+}
+
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode, Body: string(body)}
+	var errorResp Error
+	if err := json.Unmarshal(body, &errorResp); err == nil {
+		apiErr.Detail = &errorResp
+	}
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	if e.Detail != nil {
+		return fmt.Sprintf("DashScope API错误: %s (code: %s, request_id: %s)", e.Detail.Message, e.Detail.Code, e.Detail.RequestID)
+	}
+	return fmt.Sprintf("DashScope API错误: HTTP %d - %s", e.StatusCode, e.Body)
+}
+
 // NewService 创建DashScope服务
 func NewService(apiKey string) *Service {
 	apiKey = strings.TrimSpace(apiKey)
@@ -171,12 +194,7 @@ func (s *Service) ChatCompletion(ctx context.Context, req ChatRequest) (*ChatRes
 
 	// 检查HTTP状态码
 	if resp.StatusCode != http.StatusOK {
-		var errorResp Error
-		if err := json.Unmarshal(body, &errorResp); err == nil {
-			return nil, fmt.Errorf("DashScope API错误: %s (code: %s, request_id: %s)",
-				errorResp.Message, errorResp.Code, errorResp.RequestID)
-		}
-		return nil, fmt.Errorf("DashScope API错误: HTTP %d - %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp.StatusCode, body)
 	}
 
 	// 解析响应
@@ -234,12 +252,7 @@ func (s *Service) CreateEmbeddings(ctx context.Context, req EmbeddingRequest) (*
 
 	// 检查HTTP状态码
 	if resp.StatusCode != http.StatusOK {
-		var errorResp Error
-		if err := json.Unmarshal(body, &errorResp); err == nil {
-			return nil, fmt.Errorf("DashScope API错误: %s (code: %s, request_id: %s)",
-				errorResp.Message, errorResp.Code, errorResp.RequestID)
-		}
-		return nil, fmt.Errorf("DashScope API错误: HTTP %d - %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp.StatusCode, body)
 	}
 
 	// 解析响应
@@ -297,12 +310,7 @@ func (s *Service) CreateRerank(ctx context.Context, req RerankRequest) (*RerankR
 
 	// 检查HTTP状态码
 	if resp.StatusCode != http.StatusOK {
-		var errorResp Error
-		if err := json.Unmarshal(body, &errorResp); err == nil {
-			return nil, fmt.Errorf("DashScope API错误: %s (code: %s, request_id: %s)",
-				errorResp.Message, errorResp.Code, errorResp.RequestID)
-		}
-		return nil, fmt.Errorf("DashScope API错误: HTTP %d - %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp.StatusCode, body)
 	}
 
 	// 解析响应
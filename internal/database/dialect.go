@@ -0,0 +1,53 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// sqliteScheme 单机模式的数据库连接串前缀，其后为数据库文件路径
+const sqliteScheme = "sqlite://"
+
+// openDialector 按连接串选择驱动：sqlite://路径 使用内嵌SQLite，其余按PostgreSQL连接
+func openDialector(url string) (gorm.Dialector, error) {
+	path, ok := strings.CutPrefix(url, sqliteScheme)
+	if !ok {
+		return postgres.Open(url), nil
+	}
+	if path == "" {
+		return nil, fmt.Errorf("sqlite database path is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+	// WAL让读写互不阻塞；写事务直接取写锁，锁等待交给busy_timeout，避免升级锁时死锁
+	dsn := "file:" + path + "?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	return sqlite.Open(dsn), nil
+}
+
+// IsSQLite 是否为单机模式的SQLite数据库
+func IsSQLite(db *gorm.DB) bool {
+	return db.Dialector.Name() == "sqlite"
+}
+
+// SkipLocked 领取任务子查询的行锁子句，SQLite写事务本身串行，不需要行锁
+func SkipLocked(db *gorm.DB) string {
+	if IsSQLite(db) {
+		return ""
+	}
+	return "FOR UPDATE SKIP LOCKED"
+}
+
+// ILike 不区分大小写的LIKE操作符，SQLite的LIKE对ASCII字符本身不区分大小写
+func ILike(db *gorm.DB) string {
+	if IsSQLite(db) {
+		return "LIKE"
+	}
+	return "ILIKE"
+}
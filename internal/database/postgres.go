@@ -7,7 +7,6 @@ import (
 
 	"github.com/aihub/backend-go/internal/config"
 	"github.com/aihub/backend-go/internal/models"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -20,7 +19,11 @@ func InitDB() (*gorm.DB, error) {
 		return nil, fmt.Errorf("config not loaded")
 	}
 
-	db, err := gorm.Open(postgres.Open(cfg.Database.URL), &gorm.Config{
+	dialector, err := openDialector(cfg.Database.URL)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
 	if err != nil {
@@ -73,16 +76,7 @@ func InitDB() (*gorm.DB, error) {
 func autoMigrate(db *gorm.DB) error {
 	// 迁移知识库服务需要的表（按依赖顺序）
 	// 注意：如果User表不存在，先创建User表（简化版，只包含必要字段）
-	if err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS "users" (
-			"user_id" bigserial PRIMARY KEY,
-			"username" varchar(100) UNIQUE NOT NULL,
-			"email" varchar(200) UNIQUE NOT NULL,
-			"password_hash" varchar(255) NOT NULL,
-			"create_time" timestamptz DEFAULT NOW(),
-			"update_time" timestamptz
-		)
-	`).Error; err != nil {
+	if err := db.Exec(usersTableSQL(db)).Error; err != nil {
 		log.Printf("⚠️  Failed to create users table (may already exist): %v", err)
 	}
 	
@@ -105,7 +99,40 @@ func autoMigrate(db *gorm.DB) error {
 	if err := db.AutoMigrate(&models.KnowledgeChunk{}); err != nil {
 		log.Printf("⚠️  Failed to migrate knowledge_chunks: %v", err)
 	}
+	if err := db.AutoMigrate(&models.KnowledgeSynonym{}, &models.KnowledgeLexiconWord{}); err != nil {
+		log.Printf("⚠️  Failed to migrate knowledge lexicon tables: %v", err)
+	}
+	if err := db.AutoMigrate(&models.SearchEvaluationRun{}); err != nil {
+		log.Printf("⚠️  Failed to migrate search_evaluation_runs: %v", err)
+	}
+	if err := db.AutoMigrate(&models.KnowledgeWebhook{}, &models.KnowledgeWebhookDelivery{}); err != nil {
+		log.Printf("⚠️  Failed to migrate knowledge webhook tables: %v", err)
+	}
+	if err := db.AutoMigrate(&models.OutboxEvent{}); err != nil {
+		log.Printf("⚠️  Failed to migrate outbox_events: %v", err)
+	}
+	if err := db.AutoMigrate(&models.KnowledgeDocumentVersion{}); err != nil {
+		log.Printf("⚠️  Failed to migrate knowledge_document_versions: %v", err)
+	}
+	if err := db.AutoMigrate(&models.KnowledgePurgeTask{}); err != nil {
+		log.Printf("⚠️  Failed to migrate knowledge_purge_tasks: %v", err)
+	}
+	if err := db.AutoMigrate(&models.KnowledgeReconcileRun{}); err != nil {
+		log.Printf("⚠️  Failed to migrate knowledge_reconcile_runs: %v", err)
+	}
+	if err := db.AutoMigrate(&models.KnowledgeImportJob{}); err != nil {
+		log.Printf("⚠️  Failed to migrate knowledge_import_jobs: %v", err)
+	}
 	
+	// 单机模式的SQLite由AutoMigrate直接建出完整表结构，
+	// 下面的补字段与全文检索迁移只适用于PostgreSQL
+	if IsSQLite(db) {
+		if err := db.AutoMigrate(&models.KnowledgeSearch{}); err != nil {
+			log.Printf("⚠️  Failed to migrate knowledge_searches: %v", err)
+		}
+		return nil
+	}
+
 	// 2. 创建文档表（临时禁用外键检查）
 	db.Exec("SET CONSTRAINTS ALL DEFERRED")
 	if err := db.AutoMigrate(&models.KnowledgeDocument{}); err != nil {
@@ -183,6 +210,24 @@ func autoMigrate(db *gorm.DB) error {
 	return nil
 }
 
+// usersTableSQL 简化版users表的建表语句
+func usersTableSQL(db *gorm.DB) string {
+	if IsSQLite(db) {
+		// SQLite驱动迁移时按行解析表定义，列定义需写在同一行
+		return `CREATE TABLE IF NOT EXISTS "users" ("user_id" integer PRIMARY KEY AUTOINCREMENT, "username" varchar(100) UNIQUE NOT NULL, "email" varchar(200) UNIQUE NOT NULL, "password_hash" varchar(255) NOT NULL, "create_time" datetime DEFAULT CURRENT_TIMESTAMP, "update_time" datetime)`
+	}
+	return `
+		CREATE TABLE IF NOT EXISTS "users" (
+			"user_id" bigserial PRIMARY KEY,
+			"username" varchar(100) UNIQUE NOT NULL,
+			"email" varchar(200) UNIQUE NOT NULL,
+			"password_hash" varchar(255) NOT NULL,
+			"create_time" timestamptz DEFAULT NOW(),
+			"update_time" timestamptz
+		)
+	`
+}
+
 // runMigrations 执行数据库迁移脚本
 func runMigrations(db *gorm.DB) error {
 	// 迁移1: 添加超长文本支持字段
@@ -236,10 +281,65 @@ func runMigrations(db *gorm.DB) error {
 	if err := db.Exec(migrationSQL).Error; err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
+
+	// 迁移2: 全文检索（pg_trgm扩展需要相应权限，失败时不影响启动）
+	if err := db.Exec(fulltextMigrationSQL).Error; err != nil {
+		log.Printf("⚠️  Fulltext migration skipped: %v", err)
+	}
 	
 	return nil
 }
 
+// fulltextMigrationSQL 与 migrations/000004_knowledge_fulltext.up.sql、000016_knowledge_fulltext_cjk.up.sql 保持一致
+const fulltextMigrationSQL = `
+	CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+	-- 仅在新增列时回填，search_vector为NULL表示已移出索引
+	DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name='knowledge_chunks' AND column_name='search_vector') THEN
+			ALTER TABLE knowledge_chunks ADD COLUMN search_vector tsvector;
+			UPDATE knowledge_chunks SET search_vector = to_tsvector('simple', coalesce(content, ''));
+		END IF;
+	END $$;
+
+	-- 中日韩二元分词，字符范围与 knowledge.cjkRanges 一致（存量分块由迁移000016重建）
+	CREATE OR REPLACE FUNCTION knowledge_cjk_bigrams(input text) RETURNS text AS $$
+	DECLARE
+		run text;
+		tokens text[] := '{}';
+	BEGIN
+		FOR run IN
+			SELECT m[1] FROM regexp_matches(coalesce(input, ''),
+				'([\u3040-\u30ff\u3400-\u4dbf\u4e00-\u9fff\uac00-\ud7af\uf900-\ufaff\U00020000-\U0002ffff]+)', 'g') AS m
+		LOOP
+			IF char_length(run) = 1 THEN
+				tokens := tokens || run;
+			END IF;
+			FOR i IN 1 .. char_length(run) - 1 LOOP
+				tokens := tokens || substr(run, i, 2);
+			END LOOP;
+		END LOOP;
+		RETURN array_to_string(tokens, ' ');
+	END
+	$$ LANGUAGE plpgsql IMMUTABLE;
+
+	CREATE OR REPLACE FUNCTION knowledge_chunks_search_vector_update() RETURNS trigger AS $$
+	BEGIN
+		NEW.search_vector := to_tsvector('simple', coalesce(NEW.content, '') || ' ' || knowledge_cjk_bigrams(NEW.content));
+		RETURN NEW;
+	END
+	$$ LANGUAGE plpgsql;
+
+	DROP TRIGGER IF EXISTS trg_knowledge_chunks_search_vector ON knowledge_chunks;
+	CREATE TRIGGER trg_knowledge_chunks_search_vector
+	BEFORE INSERT OR UPDATE OF content ON knowledge_chunks
+	FOR EACH ROW EXECUTE FUNCTION knowledge_chunks_search_vector_update();
+
+	CREATE INDEX IF NOT EXISTS idx_knowledge_chunks_search_vector ON knowledge_chunks USING GIN (search_vector);
+	CREATE INDEX IF NOT EXISTS idx_knowledge_chunks_content_trgm ON knowledge_chunks USING GIN (content gin_trgm_ops);
+`
+
 func CloseDB() error {
 	if DB == nil {
 		return nil
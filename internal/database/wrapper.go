@@ -9,7 +9,6 @@ import (
 	"github.com/aihub/backend-go/internal/config"
 	"github.com/aihub/backend-go/internal/interfaces"
 	"github.com/sirupsen/logrus"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -25,7 +24,11 @@ type DatabaseWrapper struct {
 
 // NewDatabase 创建新的数据库实例
 func NewDatabase(cfg *config.Config) (interfaces.DatabaseInterface, error) {
-	db, err := gorm.Open(postgres.Open(cfg.Database.URL), &gorm.Config{
+	dialector, err := openDialector(cfg.Database.URL)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
 	if err != nil {
@@ -128,7 +131,8 @@ func (d *DatabaseWrapper) HealthCheck() error {
 // StartMonitoring 启动监控（健康检查和指标收集）
 func (d *DatabaseWrapper) StartMonitoring(ctx context.Context) {
 	if d.healthChecker != nil {
-		d.healthChecker.Start(ctx)
+		// Start在ctx结束或StopHealthCheck前一直阻塞
+		go d.healthChecker.Start(ctx)
 	}
 
 	if d.metrics != nil {
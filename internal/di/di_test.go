@@ -1,10 +1,17 @@
 package di
 
 import (
+	"path/filepath"
 	"testing"
 
+	"github.com/aihub/backend-go/internal/config"
+	"github.com/aihub/backend-go/internal/database"
+	"github.com/aihub/backend-go/internal/interfaces"
+	"github.com/aihub/backend-go/internal/knowledge"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/dig"
+	"go.uber.org/zap"
 )
 
 func TestDependencyInjectionContainer(t *testing.T) {
@@ -39,3 +46,29 @@ func TestContainerBasicOperations(t *testing.T) {
 
 	t.Log("DI container basic operations test passed!")
 }
+
+func TestRegisterProviders_SearchEngine(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Database.URL = "sqlite://" + filepath.Join(t.TempDir(), "aihub.db")
+	cfg.Knowledge.VectorStore.Provider = "database"
+
+	container := dig.New()
+	require.NoError(t, container.Provide(func() interfaces.ConfigInterface { return &configWrapper{config: cfg} }))
+	require.NoError(t, container.Provide(func() interfaces.LoggerInterface {
+		return &zapLogger{sugar: zap.NewNop().Sugar()}
+	}))
+	require.NoError(t, container.Provide(func(cfg interfaces.ConfigInterface) (interfaces.DatabaseInterface, error) {
+		return database.NewDatabase(cfg.GetConfig().(*config.Config))
+	}))
+	require.NoError(t, container.Provide(newVectorStore))
+	require.NoError(t, container.Provide(newEmbeddingRouter))
+	require.NoError(t, container.Provide(newHybridSearchEngine))
+
+	// 搜索引擎由配置创建，并与启动装配共用同一个向量存储和嵌入模型路由
+	err := container.Invoke(func(engine *knowledge.HybridSearchEngine, store knowledge.VectorStore, router *knowledge.EmbeddingRouter) {
+		require.NotNil(t, engine)
+		assert.IsType(t, &knowledge.DatabaseVectorStore{}, store)
+		assert.Same(t, router, engine.GetEmbeddingRouter())
+	})
+	require.NoError(t, err)
+}
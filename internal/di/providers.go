@@ -3,16 +3,20 @@ package di
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/aihub/backend-go/internal/config"
 	"github.com/aihub/backend-go/internal/database"
 	"github.com/aihub/backend-go/internal/errors"
 	"github.com/aihub/backend-go/internal/interfaces"
 	"github.com/aihub/backend-go/internal/knowledge"
+	"github.com/aihub/backend-go/internal/logger"
+	"github.com/aihub/backend-go/internal/middleware"
 	"github.com/aihub/backend-go/internal/services"
 	"github.com/minio/minio-go/v7"
 	"github.com/sirupsen/logrus"
 	"go.uber.org/dig"
+	"go.uber.org/zap"
 )
 
 // RegisterProviders 注册所有依赖提供者
@@ -28,6 +32,13 @@ func RegisterProviders(container *dig.Container) error {
 		return err
 	}
 
+	// 注册日志，基于全局zap日志
+	if err := container.Provide(func() interfaces.LoggerInterface {
+		return &zapLogger{sugar: logger.GetLogger().Sugar()}
+	}); err != nil {
+		return err
+	}
+
 	// 注册数据库
 	if err := container.Provide(func(cfg interfaces.ConfigInterface) (interfaces.DatabaseInterface, error) {
 		config := cfg.GetConfig().(*config.Config)
@@ -49,11 +60,18 @@ func RegisterProviders(container *dig.Container) error {
 		return err
 	}
 
-	// 注册搜索引擎（临时提供者，之后应该从配置创建）
-	if err := container.Provide(func() *knowledge.HybridSearchEngine {
-		// TODO: 从配置创建搜索引擎
-		return nil // 暂时返回nil
-	}); err != nil {
+	// 注册向量存储，按knowledge.vector_store.provider选择
+	if err := container.Provide(newVectorStore); err != nil {
+		return err
+	}
+
+	// 注册嵌入模型路由，检索、入库、重建、一致性检查与导入共用
+	if err := container.Provide(newEmbeddingRouter); err != nil {
+		return err
+	}
+
+	// 注册搜索引擎，由检索、向量、嵌入与重排配置创建
+	if err := container.Provide(newHybridSearchEngine); err != nil {
 		return err
 	}
 
@@ -78,6 +96,19 @@ func RegisterProviders(container *dig.Container) error {
 		return err
 	}
 
+	// 控制器与启动装配依赖具体类型，与接口共用同一实例
+	if err := container.Provide(func(ds interfaces.DocumentServiceInterface) *services.DocumentService {
+		return ds.(*services.DocumentService)
+	}); err != nil {
+		return err
+	}
+
+	if err := container.Provide(func(ss interfaces.SearchServiceInterface) *services.SearchService {
+		return ss.(*services.SearchService)
+	}); err != nil {
+		return err
+	}
+
 	if err := container.Provide(services.NewPermissionService); err != nil {
 		return err
 	}
@@ -86,6 +117,42 @@ func RegisterProviders(container *dig.Container) error {
 		return err
 	}
 
+	if err := container.Provide(services.NewReindexService); err != nil {
+		return err
+	}
+
+	if err := container.Provide(services.NewLexiconService); err != nil {
+		return err
+	}
+
+	if err := container.Provide(services.NewEvaluationService); err != nil {
+		return err
+	}
+
+	if err := container.Provide(services.NewProgressService); err != nil {
+		return err
+	}
+
+	if err := container.Provide(services.NewWebhookService); err != nil {
+		return err
+	}
+
+	if err := container.Provide(services.NewDocumentVersionService); err != nil {
+		return err
+	}
+
+	if err := container.Provide(services.NewTrashService); err != nil {
+		return err
+	}
+
+	if err := container.Provide(services.NewReconcileService); err != nil {
+		return err
+	}
+
+	if err := container.Provide(services.NewTransferService); err != nil {
+		return err
+	}
+
 	// 注册错误处理器
 	if err := container.Provide(errors.NewErrorHandler); err != nil {
 		return err
@@ -102,6 +169,100 @@ func RegisterProviders(container *dig.Container) error {
 	return nil
 }
 
+// newVectorStore 创建配置的向量存储，未配置或初始化失败时返回nil
+// milvus使用启动流程初始化的全局服务，因此需在中间件初始化之后才首次解析
+func newVectorStore(cfg interfaces.ConfigInterface, db interfaces.DatabaseInterface, log interfaces.LoggerInterface) knowledge.VectorStore {
+	vectorCfg := cfg.GetConfig().(*config.Config).Knowledge.VectorStore
+	milvusService := middleware.GetMilvusService()
+	switch {
+	case vectorCfg.Provider == "pgvector":
+		pgCfg := vectorCfg.Pgvector
+		store, err := knowledge.NewPgvectorVectorStore(db.GetDB(), knowledge.PgvectorOptions{
+			TablePrefix:    pgCfg.TablePrefix,
+			Index:          pgCfg.Index,
+			Distance:       pgCfg.Distance,
+			M:              pgCfg.M,
+			EfConstruction: pgCfg.EfConstruction,
+			Lists:          pgCfg.Lists,
+			Probes:         pgCfg.Probes,
+		})
+		if err != nil {
+			log.Warn("Failed to initialize pgvector", "error", err)
+			return nil
+		}
+		log.Info("pgvector vector store initialized")
+		return store
+	case milvusService != nil:
+		return milvusService.VectorStore()
+	case vectorCfg.Provider == "database":
+		dbCfg := vectorCfg.Database
+		store, err := knowledge.NewDatabaseVectorStoreWithOptions(db.GetDB(), knowledge.DatabaseVectorOptions{
+			Quantization:        dbCfg.Quantization,
+			RescoreLimit:        dbCfg.RescoreLimit,
+			CacheKnowledgeBases: dbCfg.CacheKnowledgeBases,
+			CacheTTL:            time.Duration(dbCfg.CacheTTLSeconds) * time.Second,
+		})
+		if err != nil {
+			log.Warn("Invalid database vector store options, quantization disabled", "error", err)
+			return knowledge.NewDatabaseVectorStore(db.GetDB())
+		}
+		return store
+	}
+	return nil
+}
+
+// providerAPIKey 模型提供方对应的API Key，取自AI配置
+func providerAPIKey(appCfg *config.Config, provider string) string {
+	if provider == "dashscope" {
+		return appCfg.AI.DashScopeAPIKey
+	}
+	return appCfg.AI.OpenAIAPIKey
+}
+
+// newEmbeddingRouter 按knowledge.embedding创建嵌入模型路由，各模型的调度器并发数取自knowledge.max_parallel
+func newEmbeddingRouter(cfg interfaces.ConfigInterface, db interfaces.DatabaseInterface) *knowledge.EmbeddingRouter {
+	appCfg := cfg.GetConfig().(*config.Config)
+	factory := func(profile knowledge.EmbeddingProfile) (knowledge.Embedder, error) {
+		return knowledge.NewProviderEmbedder(profile.Provider, profile.Model, providerAPIKey(appCfg, profile.Provider))
+	}
+	router := knowledge.NewEmbeddingRouter(db.GetDB(), factory, knowledge.EmbeddingProfile{
+		Provider: appCfg.Knowledge.Embedding.ProviderCode,
+		Model:    appCfg.Knowledge.Embedding.ModelCode,
+	})
+	router.SetSchedulerOptions(knowledge.EmbedSchedulerOptions{Concurrency: appCfg.Knowledge.MaxParallel})
+	return router
+}
+
+// newHybridSearchEngine 创建混合检索引擎
+// 全文索引由knowledge.search.provider选择，查询按知识库记录的嵌入模型向量化，未记录时使用knowledge.embedding配置的模型
+func newHybridSearchEngine(cfg interfaces.ConfigInterface, vectorStore knowledge.VectorStore, router *knowledge.EmbeddingRouter, log interfaces.LoggerInterface) *knowledge.HybridSearchEngine {
+	appCfg := cfg.GetConfig().(*config.Config)
+	apiKey := func(provider string) string {
+		return providerAPIKey(appCfg, provider)
+	}
+
+	var embedder knowledge.Embedder
+	if embedding := appCfg.Knowledge.Embedding; embedding.ProviderCode != "" {
+		var err error
+		if embedder, err = knowledge.NewProviderEmbedder(embedding.ProviderCode, embedding.ModelCode, apiKey(embedding.ProviderCode)); err != nil {
+			log.Warn("Failed to create query embedder", "provider", embedding.ProviderCode, "error", err)
+		}
+	}
+
+	var reranker knowledge.Reranker
+	if rerank := appCfg.Knowledge.Rerank; rerank.Enabled {
+		if rerank.ProviderCode == "dashscope" {
+			reranker = knowledge.NewDashScopeReranker(apiKey(rerank.ProviderCode), rerank.ModelCode)
+		} else {
+			log.Warn("Unsupported rerank provider, rerank disabled", "provider", rerank.ProviderCode)
+		}
+	}
+
+	engine := knowledge.NewHybridSearchEngine(middleware.GetFulltextIndexer(), vectorStore, embedder, reranker)
+	engine.SetEmbeddingRouter(router)
+	return engine
+}
+
 // configWrapper 配置包装器，实现ConfigInterface
 type configWrapper struct {
 	config *config.Config
@@ -115,3 +276,22 @@ func (c *configWrapper) Reload() error {
 	// 配置重新加载暂时不支持
 	return nil
 }
+
+// zapLogger 日志包装器，实现LoggerInterface，fields按键值对解析
+type zapLogger struct {
+	sugar *zap.SugaredLogger
+}
+
+func (l *zapLogger) Info(msg string, fields ...interface{})  { l.sugar.Infow(msg, fields...) }
+func (l *zapLogger) Error(msg string, fields ...interface{}) { l.sugar.Errorw(msg, fields...) }
+func (l *zapLogger) Debug(msg string, fields ...interface{}) { l.sugar.Debugw(msg, fields...) }
+func (l *zapLogger) Warn(msg string, fields ...interface{})  { l.sugar.Warnw(msg, fields...) }
+func (l *zapLogger) Fatal(msg string, fields ...interface{}) { l.sugar.Fatalw(msg, fields...) }
+
+func (l *zapLogger) With(fields ...interface{}) interfaces.LoggerInterface {
+	return &zapLogger{sugar: l.sugar.With(fields...)}
+}
+
+func (l *zapLogger) WithError(err error) interfaces.LoggerInterface {
+	return &zapLogger{sugar: l.sugar.With(zap.Error(err))}
+}
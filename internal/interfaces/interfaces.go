@@ -44,7 +44,7 @@ type DocumentServiceInterface interface {
 	ProcessDocument(ctx context.Context, docID uint) error
 	GetDocument(ctx context.Context, docID uint) (interface{}, error)
 	ListDocuments(ctx context.Context, kbID uint, page, limit int) ([]interface{}, int, error)
-	DeleteDocument(ctx context.Context, docID uint) error
+	DeleteDocument(ctx context.Context, docID uint, userID uint) error
 }
 
 // SearchServiceInterface 搜索服务接口
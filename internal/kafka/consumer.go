@@ -15,9 +15,12 @@ import (
 // Consumer Kafka消费者
 type Consumer struct {
 	consumer sarama.ConsumerGroup
+	queue    *MemoryQueue // 单机模式下代替消费者组
 	groupID  string
 	topics   []string
 	handlers map[string]MessageHandler
+	dedupMu  sync.RWMutex
+	dedup    Deduplicator
 	ctx      context.Context
 	cancel   context.CancelFunc
 	wg       sync.WaitGroup
@@ -77,9 +80,36 @@ func (c *Consumer) RegisterHandler(topic string, handler MessageHandler) {
 	logger.Info("注册Kafka消息处理器", zap.String("topic", topic))
 }
 
+// SetDeduplicator 设置事件去重器，设置后带event_id头的重复消息不再交给处理器
+func (c *Consumer) SetDeduplicator(dedup Deduplicator) {
+	if c == nil {
+		return
+	}
+	c.dedupMu.Lock()
+	defer c.dedupMu.Unlock()
+	c.dedup = dedup
+}
+
+func (c *Consumer) deduplicator() Deduplicator {
+	c.dedupMu.RLock()
+	defer c.dedupMu.RUnlock()
+	return c.dedup
+}
+
 // start 启动消费者
 func (c *Consumer) start() {
-	if c == nil || c.consumer == nil {
+	if c == nil {
+		return
+	}
+	if c.queue != nil {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.consumeQueue()
+		}()
+		return
+	}
+	if c.consumer == nil {
 		return
 	}
 
@@ -95,6 +125,7 @@ func (c *Consumer) start() {
 				// 消费消息
 				handler := &consumerGroupHandler{
 					handlers: c.handlers,
+					dedup:    c.deduplicator,
 				}
 				err := c.consumer.Consume(c.ctx, c.topics, handler)
 				if err != nil {
@@ -131,6 +162,7 @@ func (c *Consumer) Close() error {
 // consumerGroupHandler 消费者组处理器
 type consumerGroupHandler struct {
 	handlers map[string]MessageHandler
+	dedup    func() Deduplicator // 消费者启动后才设置去重器，每条消息读取最新值
 }
 
 // Setup 会话开始
@@ -152,17 +184,7 @@ func (h *consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession,
 				return nil
 			}
 
-			// 查找处理器
-			handler, ok := h.handlers[message.Topic]
-			if !ok {
-				logger.Warn("未找到消息处理器", zap.String("topic", message.Topic))
-				session.MarkMessage(message, "")
-				continue
-			}
-
-			// 处理消息
-			ctx := context.Background()
-			if err := handler(ctx, message); err != nil {
+			if err := h.handle(context.Background(), message); err != nil {
 				logger.Error("处理消息失败",
 					zap.String("topic", message.Topic),
 					zap.Int("partition", int(message.Partition)),
@@ -185,6 +207,42 @@ func (h *consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession,
 	}
 }
 
+// handle 去重后交给Topic对应的处理器，处理成功后记录事件ID
+func (h *consumerGroupHandler) handle(ctx context.Context, message *sarama.ConsumerMessage) error {
+	handler, ok := h.handlers[message.Topic]
+	if !ok {
+		logger.Warn("未找到消息处理器", zap.String("topic", message.Topic))
+		return nil
+	}
+
+	var dedup Deduplicator
+	if h.dedup != nil {
+		dedup = h.dedup()
+	}
+	eventID := EventID(message)
+	if eventID != "" && dedup != nil {
+		seen, err := dedup.Seen(ctx, eventID)
+		if err != nil {
+			// 去重存储不可用时继续处理，宁可重复也不丢失
+			logger.Warn("检查事件去重失败", zap.String("event_id", eventID), zap.Error(err))
+		} else if seen {
+			logger.Debug("跳过重复消息", zap.String("topic", message.Topic), zap.String("event_id", eventID))
+			return nil
+		}
+	}
+
+	if err := handler(ctx, message); err != nil {
+		return err
+	}
+
+	if eventID != "" && dedup != nil {
+		if err := dedup.Mark(ctx, eventID); err != nil {
+			logger.Warn("记录已处理事件失败", zap.String("event_id", eventID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
 // KnowledgeProcessMessage 知识库处理消息
 type KnowledgeProcessMessage struct {
 	KnowledgeBaseID uint   `json:"knowledge_base_id"`
@@ -0,0 +1,97 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultDedupTTL 已处理事件ID的保留时间，需覆盖发件箱中继可能重复发布的窗口
+const defaultDedupTTL = 7 * 24 * time.Hour
+
+// Deduplicator 记录已处理的事件ID，发件箱至少一次发布产生的重复消息据此跳过
+type Deduplicator interface {
+	// Seen 事件是否已处理
+	Seen(ctx context.Context, eventID string) (bool, error)
+	// Mark 标记事件已处理，处理成功后调用
+	Mark(ctx context.Context, eventID string) error
+}
+
+// EventID 消息的事件ID，没有event_id头时为空
+func EventID(message *sarama.ConsumerMessage) string {
+	for _, header := range message.Headers {
+		if header != nil && string(header.Key) == HeaderEventID {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+// RedisDeduplicator 基于Redis的去重，多个消费者实例共享
+type RedisDeduplicator struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisDeduplicator 创建Redis去重器，按消费者组隔离，ttl为0时使用默认值
+func NewRedisDeduplicator(client *redis.Client, groupID string, ttl time.Duration) *RedisDeduplicator {
+	if ttl <= 0 {
+		ttl = defaultDedupTTL
+	}
+	return &RedisDeduplicator{client: client, prefix: "kafka:dedup:" + groupID + ":", ttl: ttl}
+}
+
+func (d *RedisDeduplicator) Seen(ctx context.Context, eventID string) (bool, error) {
+	n, err := d.client.Exists(ctx, d.prefix+eventID).Result()
+	return n > 0, err
+}
+
+func (d *RedisDeduplicator) Mark(ctx context.Context, eventID string) error {
+	return d.client.Set(ctx, d.prefix+eventID, 1, d.ttl).Err()
+}
+
+// MemoryDeduplicator 进程内去重，单实例部署或测试使用
+type MemoryDeduplicator struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	seen map[string]time.Time
+}
+
+// NewMemoryDeduplicator 创建进程内去重器，ttl为0时使用默认值
+func NewMemoryDeduplicator(ttl time.Duration) *MemoryDeduplicator {
+	if ttl <= 0 {
+		ttl = defaultDedupTTL
+	}
+	return &MemoryDeduplicator{ttl: ttl, seen: make(map[string]time.Time)}
+}
+
+func (d *MemoryDeduplicator) Seen(ctx context.Context, eventID string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	expiry, ok := d.seen[eventID]
+	if ok && time.Now().After(expiry) {
+		delete(d.seen, eventID)
+		return false, nil
+	}
+	return ok, nil
+}
+
+func (d *MemoryDeduplicator) Mark(ctx context.Context, eventID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	// 顺带清理过期记录，避免长期运行时无限增长
+	if len(d.seen) > 0 && len(d.seen)%1024 == 0 {
+		for id, expiry := range d.seen {
+			if now.After(expiry) {
+				delete(d.seen, id)
+			}
+		}
+	}
+	d.seen[eventID] = now.Add(d.ttl)
+	return nil
+}
@@ -0,0 +1,60 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumerGroupHandler_SkipsDuplicateEvents(t *testing.T) {
+	var handled []string
+	fail := true
+	dedup := NewMemoryDeduplicator(time.Minute)
+	h := &consumerGroupHandler{
+		handlers: map[string]MessageHandler{
+			"knowledge.process": func(ctx context.Context, message *sarama.ConsumerMessage) error {
+				if fail {
+					return errors.New("temporary")
+				}
+				handled = append(handled, string(message.Value))
+				return nil
+			},
+		},
+		dedup: func() Deduplicator { return dedup },
+	}
+	message := func(eventID, value string) *sarama.ConsumerMessage {
+		msg := &sarama.ConsumerMessage{Topic: "knowledge.process", Value: []byte(value)}
+		if eventID != "" {
+			msg.Headers = []*sarama.RecordHeader{{Key: []byte(HeaderEventID), Value: []byte(eventID)}}
+		}
+		return msg
+	}
+	ctx := context.Background()
+
+	// 处理失败时不记录，重投后仍会处理
+	require.Error(t, h.handle(ctx, message("evt-1", "first")))
+	fail = false
+	require.NoError(t, h.handle(ctx, message("evt-1", "first")))
+	require.NoError(t, h.handle(ctx, message("evt-1", "first")))
+	require.NoError(t, h.handle(ctx, message("evt-2", "second")))
+	// 没有事件ID的旧消息不去重
+	require.NoError(t, h.handle(ctx, message("", "legacy")))
+	require.NoError(t, h.handle(ctx, message("", "legacy")))
+
+	assert.Equal(t, []string{"first", "second", "legacy", "legacy"}, handled)
+}
+
+func TestMemoryDeduplicator_Expires(t *testing.T) {
+	dedup := NewMemoryDeduplicator(time.Millisecond)
+	ctx := context.Background()
+	require.NoError(t, dedup.Mark(ctx, "evt"))
+	time.Sleep(5 * time.Millisecond)
+	seen, err := dedup.Seen(ctx, "evt")
+	require.NoError(t, err)
+	assert.False(t, seen)
+}
@@ -0,0 +1,173 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/aihub/backend-go/internal/logger"
+	"go.uber.org/zap"
+)
+
+// errMemoryQueueClosed 队列已关闭
+var errMemoryQueueClosed = errors.New("内存消息队列已关闭")
+
+// MemoryQueue 进程内消息队列，单机模式下代替Kafka
+// 实现sarama.SyncProducer，生产者与发件箱中继照常使用；消息按发送顺序交给同进程的消费者，不持久化，
+// 未投递的消息仍保留在发件箱中，重启后由中继重新发布
+type MemoryQueue struct {
+	mu      sync.Mutex
+	pending []*sarama.ConsumerMessage
+	offsets map[string]int64
+	notify  chan struct{}
+	closed  bool
+}
+
+// NewMemoryQueue 创建内存消息队列
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{
+		offsets: make(map[string]int64),
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+// InitMemoryQueue 用内存队列初始化全局生产者与消费者，消费者随即启动
+func InitMemoryQueue(topic, groupID string, topics []string) error {
+	defaultTopic = topic
+	queue := NewMemoryQueue()
+	globalProducer = &Producer{
+		producer: queue,
+		topic:    topic,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	globalConsumer = &Consumer{
+		queue:    queue,
+		groupID:  groupID,
+		topics:   topics,
+		handlers: make(map[string]MessageHandler),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+
+	logger.Info("内存消息队列初始化成功", zap.String("topic", topic), zap.Strings("topics", topics))
+	go globalConsumer.start()
+	return nil
+}
+
+func (q *MemoryQueue) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	message := &sarama.ConsumerMessage{
+		Topic:     msg.Topic,
+		Timestamp: time.Now(),
+	}
+	if msg.Key != nil {
+		key, err := msg.Key.Encode()
+		if err != nil {
+			return 0, 0, err
+		}
+		message.Key = key
+	}
+	if msg.Value != nil {
+		value, err := msg.Value.Encode()
+		if err != nil {
+			return 0, 0, err
+		}
+		message.Value = value
+	}
+	for i := range msg.Headers {
+		header := msg.Headers[i]
+		message.Headers = append(message.Headers, &header)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return 0, 0, errMemoryQueueClosed
+	}
+	message.Offset = q.offsets[msg.Topic]
+	q.offsets[msg.Topic]++
+	q.pending = append(q.pending, message)
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return 0, message.Offset, nil
+}
+
+func (q *MemoryQueue) SendMessages(msgs []*sarama.ProducerMessage) error {
+	for _, msg := range msgs {
+		if _, _, err := q.SendMessage(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *MemoryQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	return nil
+}
+
+func (q *MemoryQueue) TxnStatus() sarama.ProducerTxnStatusFlag { return sarama.ProducerTxnFlagReady }
+
+func (q *MemoryQueue) IsTransactional() bool { return false }
+
+func (q *MemoryQueue) BeginTxn() error { return sarama.ErrNonTransactedProducer }
+
+func (q *MemoryQueue) CommitTxn() error { return sarama.ErrNonTransactedProducer }
+
+func (q *MemoryQueue) AbortTxn() error { return sarama.ErrNonTransactedProducer }
+
+func (q *MemoryQueue) AddOffsetsToTxn(map[string][]*sarama.PartitionOffsetMetadata, string) error {
+	return sarama.ErrNonTransactedProducer
+}
+
+func (q *MemoryQueue) AddMessageToTxn(*sarama.ConsumerMessage, string, *string) error {
+	return sarama.ErrNonTransactedProducer
+}
+
+// next 取出下一条消息，队列为空时等待，ctx结束时返回false
+func (q *MemoryQueue) next(ctx context.Context) (*sarama.ConsumerMessage, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.pending) > 0 {
+			message := q.pending[0]
+			q.pending[0] = nil
+			q.pending = q.pending[1:]
+			q.mu.Unlock()
+			return message, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-q.notify:
+		}
+	}
+}
+
+// consumeQueue 逐条处理内存队列中的消息，处理失败只记录日志，与消费者组跳过未标记消息的行为一致
+func (c *Consumer) consumeQueue() {
+	handler := &consumerGroupHandler{
+		handlers: c.handlers,
+		dedup:    c.deduplicator,
+	}
+	for {
+		message, ok := c.queue.next(c.ctx)
+		if !ok {
+			logger.Info("内存消息队列消费者停止")
+			return
+		}
+		if err := handler.handle(c.ctx, message); err != nil {
+			logger.Error("处理消息失败",
+				zap.String("topic", message.Topic),
+				zap.Int64("offset", message.Offset),
+				zap.Error(err))
+		}
+	}
+}
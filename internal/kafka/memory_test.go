@@ -0,0 +1,62 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryQueue_DeliversInOrderAndDedups(t *testing.T) {
+	queue := NewMemoryQueue()
+	received := make(chan string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	consumer := &Consumer{
+		queue: queue,
+		handlers: map[string]MessageHandler{
+			"knowledge.process": func(ctx context.Context, message *sarama.ConsumerMessage) error {
+				received <- string(message.Key) + ":" + string(message.Value)
+				return nil
+			},
+		},
+		dedup:  NewMemoryDeduplicator(time.Minute),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	consumer.start()
+
+	send := func(eventID, key, value string) {
+		_, _, err := queue.SendMessage(&sarama.ProducerMessage{
+			Topic:   "knowledge.process",
+			Key:     sarama.StringEncoder(key),
+			Value:   sarama.StringEncoder(value),
+			Headers: []sarama.RecordHeader{{Key: []byte(HeaderEventID), Value: []byte(eventID)}},
+		})
+		require.NoError(t, err)
+	}
+	send("evt-1", "kb-1", "first")
+	send("evt-2", "kb-1", "second")
+	// 中继重发的同一事件只处理一次
+	send("evt-1", "kb-1", "first")
+	send("evt-3", "kb-2", "third")
+
+	var got []string
+	for len(got) < 3 {
+		select {
+		case v := <-received:
+			got = append(got, v)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out, received %v", got)
+		}
+	}
+	require.NoError(t, consumer.Close())
+	assert.Equal(t, []string{"kb-1:first", "kb-1:second", "kb-2:third"}, got)
+	assert.Empty(t, received)
+
+	require.NoError(t, queue.Close())
+	_, _, err := queue.SendMessage(&sarama.ProducerMessage{Topic: "knowledge.process"})
+	assert.ErrorIs(t, err, errMemoryQueueClosed)
+}
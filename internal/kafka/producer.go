@@ -21,8 +21,12 @@ func (p *Producer) GetProducerInstance() sarama.SyncProducer {
 	return p.producer
 }
 
+// HeaderEventID 事件ID消息头，消费方据此去重
+const HeaderEventID = "event_id"
+
 // ConversationMessage 对话消息结构
 type ConversationMessage struct {
+	EventID        string                 `json:"event_id,omitempty"`
 	ConversationID string                 `json:"conversation_id"`
 	UserID         uint                   `json:"user_id"`
 	ModelID        uint                   `json:"model_id"`
@@ -46,10 +50,16 @@ type UsageInfo struct {
 	TotalTokens  int `json:"total_tokens"`
 }
 
-var globalProducer *Producer
+var (
+	globalProducer *Producer
+	defaultTopic   string
+)
 
 // InitProducer 初始化Kafka生产者
 func InitProducer(brokers []string, topic string) error {
+	// 生产者创建失败时发件箱仍按该Topic写入，恢复后由中继发布
+	defaultTopic = topic
+
 	config := sarama.NewConfig()
 	config.Producer.Return.Successes = true
 	config.Producer.RequiredAcks = sarama.WaitForAll
@@ -75,6 +85,11 @@ func GetProducer() *Producer {
 	return globalProducer
 }
 
+// DefaultTopic 对话消息的Topic
+func DefaultTopic() string {
+	return defaultTopic
+}
+
 // SendMessage 发送消息到Kafka
 func (p *Producer) SendMessage(msg *ConversationMessage) error {
 	if p == nil || p.producer == nil {
@@ -90,7 +105,7 @@ func (p *Producer) SendMessage(msg *ConversationMessage) error {
 	// 创建Kafka消息
 	kafkaMsg := &sarama.ProducerMessage{
 		Topic: p.topic,
-		Key:   sarama.StringEncoder(fmt.Sprintf("%d-%s", msg.UserID, msg.ConversationID)),
+		Key:   sarama.StringEncoder(msg.Key()),
 		Value: sarama.ByteEncoder(data),
 		Headers: []sarama.RecordHeader{
 			{
@@ -119,6 +134,36 @@ func (p *Producer) SendMessage(msg *ConversationMessage) error {
 	return nil
 }
 
+// SendRecord 发送已序列化的消息，供发件箱中继使用
+func (p *Producer) SendRecord(topic, key string, value []byte, headers map[string]string) error {
+	if p == nil || p.producer == nil {
+		return fmt.Errorf("Kafka生产者未初始化")
+	}
+
+	kafkaMsg := &sarama.ProducerMessage{
+		Topic: topic,
+		Value: sarama.ByteEncoder(value),
+	}
+	if key != "" {
+		kafkaMsg.Key = sarama.StringEncoder(key)
+	}
+	for k, v := range headers {
+		kafkaMsg.Headers = append(kafkaMsg.Headers, sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+	}
+
+	partition, offset, err := p.producer.SendMessage(kafkaMsg)
+	if err != nil {
+		return fmt.Errorf("发送消息失败: %w", err)
+	}
+
+	logger.Debug("Kafka消息发送成功",
+		zap.String("topic", topic),
+		zap.String("key", key),
+		zap.Int32("partition", partition),
+		zap.Int64("offset", offset))
+	return nil
+}
+
 // Close 关闭生产者
 func (p *Producer) Close() error {
 	if p != nil && p.producer != nil {
@@ -136,7 +181,13 @@ func SendConversationMessage(conversationID string, userID, modelID uint, role,
 		return nil
 	}
 
-	msg := &ConversationMessage{
+	msg := NewConversationMessage(conversationID, userID, modelID, role, content, modelParams, usage)
+	return producer.SendMessage(msg)
+}
+
+// NewConversationMessage 构造对话消息
+func NewConversationMessage(conversationID string, userID, modelID uint, role, content string, modelParams map[string]interface{}, usage *UsageInfo) *ConversationMessage {
+	return &ConversationMessage{
 		ConversationID: conversationID,
 		UserID:         userID,
 		ModelID:        modelID,
@@ -149,6 +200,17 @@ func SendConversationMessage(conversationID string, userID, modelID uint, role,
 		Usage:       usage,
 		Timestamp:   time.Now(),
 	}
+}
 
-	return producer.SendMessage(msg)
+// Key 消息key，同一用户同一对话的消息进入同一分区
+func (m *ConversationMessage) Key() string {
+	return fmt.Sprintf("%d-%s", m.UserID, m.ConversationID)
+}
+
+// Headers 消息头，与SendMessage写入的一致
+func (m *ConversationMessage) Headers() map[string]string {
+	return map[string]string{
+		"user_id":  fmt.Sprintf("%d", m.UserID),
+		"model_id": fmt.Sprintf("%d", m.ModelID),
+	}
 }
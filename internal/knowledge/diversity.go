@@ -0,0 +1,136 @@
+package knowledge
+
+import (
+	"context"
+	"math"
+)
+
+// DiversityOptions 检索结果多样化（最大边际相关性MMR）选项
+type DiversityOptions struct {
+	Lambda         float64 `json:"lambda"`           // 相关性权重0-1，越小结果越分散，默认0.7
+	MaxPerDocument int     `json:"max_per_document"` // 每个文档最多返回的分块数，0为不限制
+	BeforeRerank   bool    `json:"before_rerank"`    // 先多样化再重排序，默认重排序后再多样化
+}
+
+const (
+	defaultMMRLambda    = 0.7
+	diversityPoolFactor = 4   // 候选数量为返回数量的倍数
+	maxDiversityPool    = 100 // 候选数量上限
+)
+
+// diversityPool 多样化的候选数量
+func diversityPool(limit int) int {
+	pool := limit * diversityPoolFactor
+	if pool > maxDiversityPool {
+		pool = maxDiversityPool
+	}
+	if pool < limit {
+		pool = limit
+	}
+	return pool
+}
+
+// selectDiverse 从候选中按MMR选出limit个结果，BeforeRerank时对选出的结果重排序
+func (e *HybridSearchEngine) selectDiverse(ctx context.Context, req HybridSearchRequest, candidates []SearchMatch, limit int) []SearchMatch {
+	var embeddings map[uint][]float32
+	if source, ok := e.vectorStore.(ChunkEmbeddingSource); ok && e.vectorStore.Ready() && len(candidates) > 1 {
+		ids := make([]uint, len(candidates))
+		for i, m := range candidates {
+			ids[i] = m.ChunkID
+		}
+		// 读取失败时使用文本相似度
+		embeddings, _ = source.ChunkEmbeddings(ctx, req.KnowledgeBaseID, ids)
+	}
+
+	results := selectMMR(candidates, embeddings, *req.Diversity, limit)
+	if req.Diversity.BeforeRerank {
+		results = e.applyRerank(ctx, req.Query, results, limit)
+	}
+	return results
+}
+
+// selectMMR 贪心选择：每步选 λ·相关性 − (1−λ)·与已选结果的最大相似度 最高的候选
+// 相关性为候选得分除以最高分；两个分块都有向量时用余弦相似度，否则用分词集合的Jaccard相似度
+func selectMMR(candidates []SearchMatch, embeddings map[uint][]float32, opts DiversityOptions, limit int) []SearchMatch {
+	if limit <= 0 || len(candidates) == 0 {
+		return nil
+	}
+	lambda := opts.Lambda
+	if lambda <= 0 || lambda > 1 {
+		lambda = defaultMMRLambda
+	}
+
+	maxScore := 0.0
+	for _, m := range candidates {
+		maxScore = math.Max(maxScore, m.Score)
+	}
+	relevance := make([]float64, len(candidates))
+	for i, m := range candidates {
+		if maxScore > 0 {
+			relevance[i] = m.Score / maxScore
+		}
+	}
+
+	terms := make([]map[string]struct{}, len(candidates))
+	tokenizer := NewStandardTokenizer(nil)
+	similarity := func(i, j int) float64 {
+		a, b := embeddings[candidates[i].ChunkID], embeddings[candidates[j].ChunkID]
+		if len(a) > 0 && len(a) == len(b) {
+			return math.Max(0, cosineSimilarity(a, b, vectorNorm(a)))
+		}
+		for _, k := range []int{i, j} {
+			if terms[k] == nil {
+				terms[k] = make(map[string]struct{})
+				for _, token := range tokenizer.Tokenize(candidates[k].Content) {
+					terms[k][token.Term] = struct{}{}
+				}
+			}
+		}
+		return jaccard(terms[i], terms[j])
+	}
+
+	selected := make([]SearchMatch, 0, min(limit, len(candidates)))
+	used := make([]bool, len(candidates))
+	maxSim := make([]float64, len(candidates))
+	perDocument := make(map[uint]int)
+	last := -1
+	for len(selected) < limit {
+		best, bestScore := -1, math.Inf(-1)
+		for i := range candidates {
+			if used[i] {
+				continue
+			}
+			if opts.MaxPerDocument > 0 && perDocument[candidates[i].DocumentID] >= opts.MaxPerDocument {
+				continue
+			}
+			if last >= 0 {
+				maxSim[i] = math.Max(maxSim[i], similarity(i, last))
+			}
+			score := lambda*relevance[i] - (1-lambda)*maxSim[i]
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			break
+		}
+		used[best] = true
+		perDocument[candidates[best].DocumentID]++
+		selected = append(selected, candidates[best])
+		last = best
+	}
+	return selected
+}
+
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	intersection := 0
+	for term := range a {
+		if _, ok := b[term]; ok {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
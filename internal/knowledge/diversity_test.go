@@ -0,0 +1,87 @@
+package knowledge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func matchIDs(matches []SearchMatch) []uint {
+	ids := make([]uint, len(matches))
+	for i, m := range matches {
+		ids[i] = m.ChunkID
+	}
+	return ids
+}
+
+func TestSelectMMR_PrefersDissimilarChunks(t *testing.T) {
+	candidates := []SearchMatch{
+		{ChunkID: 1, DocumentID: 1, Score: 1.0},
+		{ChunkID: 2, DocumentID: 1, Score: 0.95},
+		{ChunkID: 3, DocumentID: 2, Score: 0.9},
+	}
+	embeddings := map[uint][]float32{
+		1: {1, 0},
+		2: {0.99, 0.1},
+		3: {0, 1},
+	}
+
+	assert.Equal(t, []uint{1, 3}, matchIDs(selectMMR(candidates, embeddings, DiversityOptions{}, 2)))
+	// λ=1 只看相关性
+	assert.Equal(t, []uint{1, 2}, matchIDs(selectMMR(candidates, embeddings, DiversityOptions{Lambda: 1}, 2)))
+}
+
+func TestSelectMMR_TextFallbackAndDocumentCap(t *testing.T) {
+	candidates := []SearchMatch{
+		{ChunkID: 1, DocumentID: 1, Score: 0.9, Content: "员工年假申请需要提前三天在OA系统提交"},
+		{ChunkID: 2, DocumentID: 2, Score: 0.88, Content: "员工年假申请需要提前三天在OA系统提交审批"},
+		{ChunkID: 3, DocumentID: 3, Score: 0.8, Content: "报销单据需附发票原件"},
+		{ChunkID: 4, DocumentID: 1, Score: 0.7, Content: "年假天数按工龄计算"},
+	}
+
+	// 没有向量时按分词相似度去掉近似重复的分块
+	assert.Equal(t, []uint{1, 3}, matchIDs(selectMMR(candidates, nil, DiversityOptions{Lambda: 0.5}, 2)))
+
+	// 每个文档最多一个分块，候选不足时返回更少的结果
+	capped := selectMMR(candidates, nil, DiversityOptions{Lambda: 1, MaxPerDocument: 1}, 4)
+	assert.Equal(t, []uint{1, 2, 3}, matchIDs(capped))
+}
+
+func TestHybridSearchEngine_DiversityWidensCandidatePool(t *testing.T) {
+	indexer := &limitRecordingIndexer{}
+	for i := uint(1); i <= 12; i++ {
+		indexer.results = append(indexer.results, SearchMatch{ChunkID: i, DocumentID: (i + 1) / 2, Score: 1 - float64(i)/100})
+	}
+	engine := NewHybridSearchEngine(indexer, nil, nil, nil)
+
+	matches, err := engine.Search(context.Background(), HybridSearchRequest{
+		KnowledgeBaseID: 1,
+		Query:           "年假",
+		Limit:           3,
+		Mode:            "fulltext",
+		Diversity:       &DiversityOptions{Lambda: 1, MaxPerDocument: 1},
+	})
+	require.NoError(t, err)
+	// 全文检索召回候选数量的两倍
+	assert.Equal(t, 3*diversityPoolFactor*2, indexer.limit)
+	assert.Equal(t, []uint{1, 3, 5}, matchIDs(matches))
+}
+
+// limitRecordingIndexer 记录请求的数量
+type limitRecordingIndexer struct {
+	NoopFulltextIndexer
+	limit   int
+	results []SearchMatch
+}
+
+func (f *limitRecordingIndexer) Search(ctx context.Context, req FulltextSearchRequest) ([]SearchMatch, error) {
+	f.limit = req.Limit
+	if len(f.results) > req.Limit {
+		return f.results[:req.Limit], nil
+	}
+	return f.results, nil
+}
+
+func (f *limitRecordingIndexer) Ready() bool { return true }
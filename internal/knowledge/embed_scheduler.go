@@ -0,0 +1,356 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aihub/backend-go/internal/dashscope"
+	"github.com/sashabaranov/go-openai"
+)
+
+// EmbedSchedulerOptions 批量向量化调度参数
+type EmbedSchedulerOptions struct {
+	BatchSize         int           // 每批文本数，默认64，不超过提供方上限
+	Concurrency       int           // 并发批次数，默认4
+	RequestsPerSecond float64       // 每秒请求数上限，0表示不限制
+	MaxRetries        int           // 单批失败重试次数，默认2，负数表示不重试
+	RetryBackoff      time.Duration // 重试退避基数（按2的指数增长），默认200ms
+}
+
+const (
+	defaultEmbedBatchSize   = 64
+	defaultEmbedConcurrency = 4
+	defaultEmbedMaxRetries  = 2
+	defaultEmbedBackoff     = 200 * time.Millisecond
+)
+
+var errEmptyEmbedText = errors.New("text is empty")
+
+// BatchEmbedError 部分文本向量化失败
+type BatchEmbedError struct {
+	Failed map[int]error // 输入下标 -> 错误
+}
+
+func (e *BatchEmbedError) Error() string {
+	indexes := make([]int, 0, len(e.Failed))
+	for idx := range e.Failed {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+	return fmt.Sprintf("%d texts failed to embed, first (index %d): %v", len(indexes), indexes[0], e.Failed[indexes[0]])
+}
+
+// embedErrorClass 向量化错误的处理方式
+type embedErrorClass int
+
+const (
+	embedErrorTransient embedErrorClass = iota // 网络错误、限流与服务端错误：退避重试，仍失败时拆批
+	embedErrorInput                            // 请求内容无效（400/413/422）：不重试，直接拆批定位到具体文本
+	embedErrorFatal                            // 鉴权、模型不存在等其他4xx：不重试也不拆批，整批失败
+)
+
+// classifyEmbedError 按提供方返回的HTTP状态码分类错误，无法识别状态码时按暂时性错误处理
+func classifyEmbedError(err error) embedErrorClass {
+	if errors.Is(err, errEmptyEmbedText) {
+		return embedErrorInput
+	}
+	status := embedErrorStatus(err)
+	switch {
+	case status == 0, status == http.StatusRequestTimeout, status == http.StatusTooManyRequests, status >= 500:
+		return embedErrorTransient
+	case status == http.StatusBadRequest, status == http.StatusRequestEntityTooLarge, status == http.StatusUnprocessableEntity:
+		return embedErrorInput
+	case status >= 400:
+		return embedErrorFatal
+	default:
+		return embedErrorTransient
+	}
+}
+
+// embedErrorStatus 提取提供方错误中的HTTP状态码，没有时返回0
+func embedErrorStatus(err error) int {
+	var openaiErr *openai.APIError
+	if errors.As(err, &openaiErr) {
+		return openaiErr.HTTPStatusCode
+	}
+	var requestErr *openai.RequestError
+	if errors.As(err, &requestErr) {
+		return requestErr.HTTPStatusCode
+	}
+	var dashscopeErr *dashscope.APIError
+	if errors.As(err, &dashscopeErr) {
+		return dashscopeErr.StatusCode
+	}
+	return 0
+}
+
+// EmbedScheduler 批量向量化调度器
+// 按提供方上限切分批次，在限速下并发执行；暂时性错误重试仍失败、或请求内容无效时二分拆批，
+// 把失败范围缩小到单条文本，其余文本照常返回结果；鉴权等不可恢复的错误整批失败，不再拆批
+type EmbedScheduler struct {
+	embedder Embedder
+	opts     EmbedSchedulerOptions
+	limiter  *requestLimiter
+}
+
+// NewEmbedScheduler 创建批量向量化调度器
+func NewEmbedScheduler(embedder Embedder, opts EmbedSchedulerOptions) *EmbedScheduler {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultEmbedConcurrency
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	} else if opts.MaxRetries == 0 {
+		opts.MaxRetries = defaultEmbedMaxRetries
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = defaultEmbedBackoff
+	}
+
+	// 不支持批量的Embedder逐条请求
+	batchSize := 1
+	if batcher, ok := embedder.(BatchEmbedder); ok {
+		batchSize = opts.BatchSize
+		if batchSize <= 0 {
+			batchSize = defaultEmbedBatchSize
+		}
+		if limit := batcher.MaxBatchSize(); limit > 0 && batchSize > limit {
+			batchSize = limit
+		}
+	}
+	opts.BatchSize = batchSize
+
+	return &EmbedScheduler{
+		embedder: embedder,
+		opts:     opts,
+		limiter:  newRequestLimiter(opts.RequestsPerSecond),
+	}
+}
+
+// Embedder 获取底层的Embedder
+func (s *EmbedScheduler) Embedder() Embedder {
+	return s.embedder
+}
+
+// BatchSize 实际使用的批大小
+func (s *EmbedScheduler) BatchSize() int {
+	return s.opts.BatchSize
+}
+
+// EmbedProgressFunc 向量化进度回调，done为已完成（含失败）的文本数
+type EmbedProgressFunc func(done, total int)
+
+// EmbedAll 向量化全部文本，结果顺序与输入一致
+// 部分文本最终失败时返回*BatchEmbedError，成功的结果仍然填充（失败位置为nil）
+func (s *EmbedScheduler) EmbedAll(ctx context.Context, texts []string) ([][]float32, error) {
+	return s.EmbedAllWithProgress(ctx, texts, nil)
+}
+
+// EmbedAllWithProgress 同EmbedAll，每个批次完成后回调进度；回调在工作协程中串行调用
+func (s *EmbedScheduler) EmbedAllWithProgress(ctx context.Context, texts []string, progress EmbedProgressFunc) ([][]float32, error) {
+	results := make([][]float32, len(texts))
+	if len(texts) == 0 {
+		return results, nil
+	}
+
+	var (
+		mu   sync.Mutex
+		done int
+	)
+	failed := make(map[int]error)
+	fail := func(idx int, err error) {
+		mu.Lock()
+		failed[idx] = err
+		mu.Unlock()
+	}
+	report := func(n int) {
+		if progress == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		done += n
+		progress(done, len(texts))
+	}
+
+	// 空文本直接记为失败，不占用请求
+	pending := make([]int, 0, len(texts))
+	for i, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			failed[i] = errEmptyEmbedText
+			continue
+		}
+		pending = append(pending, i)
+	}
+	if skipped := len(texts) - len(pending); skipped > 0 {
+		report(skipped)
+	}
+
+	batches := make(chan []int)
+	var wg sync.WaitGroup
+	for w := 0; w < s.opts.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				s.runBatch(ctx, texts, batch, results, fail)
+				report(len(batch))
+			}
+		}()
+	}
+
+	for start := 0; start < len(pending); start += s.opts.BatchSize {
+		end := start + s.opts.BatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		select {
+		case batches <- pending[start:end]:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(batches)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+	if len(failed) > 0 {
+		return results, &BatchEmbedError{Failed: failed}
+	}
+	return results, nil
+}
+
+// runBatch 执行一个批次，重试仍失败或输入无效时二分拆批
+func (s *EmbedScheduler) runBatch(ctx context.Context, texts []string, batch []int, results [][]float32, fail func(int, error)) {
+	inputs := make([]string, len(batch))
+	for i, idx := range batch {
+		inputs[i] = texts[idx]
+	}
+
+	embeddings, err := s.embedWithRetry(ctx, inputs)
+	if err == nil {
+		// 各批次写入不相交的下标，无需加锁
+		for i, idx := range batch {
+			results[idx] = embeddings[i]
+		}
+		return
+	}
+
+	if ctx.Err() != nil || len(batch) == 1 || classifyEmbedError(err) == embedErrorFatal {
+		for _, idx := range batch {
+			fail(idx, err)
+		}
+		return
+	}
+
+	mid := len(batch) / 2
+	s.runBatch(ctx, texts, batch[:mid], results, fail)
+	s.runBatch(ctx, texts, batch[mid:], results, fail)
+}
+
+// embedWithRetry 带限速与指数退避重试的单次请求，只重试暂时性错误
+func (s *EmbedScheduler) embedWithRetry(ctx context.Context, inputs []string) ([][]float32, error) {
+	var lastErr error
+	for attempt := 0; attempt <= s.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleepContext(ctx, s.opts.RetryBackoff<<(attempt-1)); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		embeddings, err := s.embed(ctx, inputs)
+		if err == nil {
+			return embeddings, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if classifyEmbedError(err) != embedErrorTransient {
+			return nil, err
+		}
+	}
+	return nil, lastErr
+}
+
+func (s *EmbedScheduler) embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	if batcher, ok := s.embedder.(BatchEmbedder); ok {
+		embeddings, err := batcher.EmbedBatch(ctx, inputs)
+		if err != nil {
+			return nil, err
+		}
+		if len(embeddings) != len(inputs) {
+			return nil, fmt.Errorf("embedding batch size mismatch: got %d, want %d", len(embeddings), len(inputs))
+		}
+		return embeddings, nil
+	}
+
+	embeddings := make([][]float32, len(inputs))
+	for i, input := range inputs {
+		embedding, err := s.embedder.Embed(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		embeddings[i] = embedding
+	}
+	return embeddings, nil
+}
+
+// requestLimiter 按固定间隔放行请求的限速器
+type requestLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRequestLimiter(perSecond float64) *requestLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &requestLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// wait 等待下一个可用的请求时间片，limiter为nil时不限速
+func (l *requestLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	return sleepContext(ctx, delay)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,176 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aihub/backend-go/internal/dashscope"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBatchEmbedder 按文本长度生成向量，包含"bad"的文本总是失败
+type fakeBatchEmbedder struct {
+	mu       sync.Mutex
+	maxBatch int
+	batches  [][]string
+	flaky    int   // 前flaky次请求失败
+	badErr   error // 包含"bad"的请求返回的错误，为nil时使用普通错误
+	err      error // 不为nil时所有请求都返回该错误
+}
+
+func (e *fakeBatchEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	results, err := e.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+func (e *fakeBatchEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	e.mu.Lock()
+	e.batches = append(e.batches, texts)
+	if e.flaky > 0 {
+		e.flaky--
+		e.mu.Unlock()
+		return nil, errors.New("temporary failure")
+	}
+	e.mu.Unlock()
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	results := make([][]float32, len(texts))
+	for i, text := range texts {
+		if text == "bad" {
+			if e.badErr != nil {
+				return nil, e.badErr
+			}
+			return nil, errors.New("invalid input")
+		}
+		results[i] = []float32{float32(len(text))}
+	}
+	return results, nil
+}
+
+func (e *fakeBatchEmbedder) MaxBatchSize() int { return e.maxBatch }
+func (e *fakeBatchEmbedder) Dimensions() int   { return 1 }
+func (e *fakeBatchEmbedder) Ready() bool       { return true }
+
+func TestEmbedScheduler_SplitsByProviderLimit(t *testing.T) {
+	embedder := &fakeBatchEmbedder{maxBatch: 3}
+	scheduler := NewEmbedScheduler(embedder, EmbedSchedulerOptions{BatchSize: 100, Concurrency: 2})
+
+	texts := []string{"a", "bb", "ccc", "dddd", "eeeee", "ffffff", "g"}
+	results, err := scheduler.EmbedAll(context.Background(), texts)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, scheduler.BatchSize())
+	assert.Len(t, embedder.batches, 3)
+	for i, text := range texts {
+		assert.Equal(t, []float32{float32(len(text))}, results[i])
+	}
+}
+
+func TestEmbedScheduler_IsolatesFailedTexts(t *testing.T) {
+	embedder := &fakeBatchEmbedder{maxBatch: 4}
+	scheduler := NewEmbedScheduler(embedder, EmbedSchedulerOptions{MaxRetries: -1})
+
+	texts := []string{"a", "bad", "ccc", "", "eeeee"}
+	results, err := scheduler.EmbedAll(context.Background(), texts)
+
+	var batchErr *BatchEmbedError
+	require.ErrorAs(t, err, &batchErr)
+	assert.Len(t, batchErr.Failed, 2)
+	assert.Contains(t, batchErr.Failed, 1)
+	assert.Contains(t, batchErr.Failed, 3)
+
+	assert.Equal(t, []float32{1}, results[0])
+	assert.Nil(t, results[1])
+	assert.Equal(t, []float32{3}, results[2])
+	assert.Equal(t, []float32{5}, results[4])
+}
+
+func TestEmbedScheduler_RetriesTransientErrors(t *testing.T) {
+	embedder := &fakeBatchEmbedder{maxBatch: 10, flaky: 2}
+	scheduler := NewEmbedScheduler(embedder, EmbedSchedulerOptions{MaxRetries: 2, RetryBackoff: time.Millisecond})
+
+	results, err := scheduler.EmbedAll(context.Background(), []string{"a", "bb"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1}, {2}}, results)
+	assert.Len(t, embedder.batches, 3)
+}
+
+func TestEmbedScheduler_SplitsInvalidInputWithoutRetry(t *testing.T) {
+	embedder := &fakeBatchEmbedder{maxBatch: 4, badErr: &openai.APIError{HTTPStatusCode: 400, Message: "input too long"}}
+	scheduler := NewEmbedScheduler(embedder, EmbedSchedulerOptions{MaxRetries: 2, RetryBackoff: time.Millisecond})
+
+	results, err := scheduler.EmbedAll(context.Background(), []string{"a", "bad", "ccc", "dddd"})
+	var batchErr *BatchEmbedError
+	require.ErrorAs(t, err, &batchErr)
+	assert.Len(t, batchErr.Failed, 1)
+	assert.Contains(t, batchErr.Failed, 1)
+	assert.Equal(t, []float32{3}, results[2])
+
+	// 输入错误不重试：整批、前半批、[a]、[bad]、后半批各一次请求
+	assert.Len(t, embedder.batches, 5)
+}
+
+func TestEmbedScheduler_FailsBatchOnFatalError(t *testing.T) {
+	embedder := &fakeBatchEmbedder{maxBatch: 4, err: &dashscope.APIError{StatusCode: 401, Body: "invalid api key"}}
+	scheduler := NewEmbedScheduler(embedder, EmbedSchedulerOptions{MaxRetries: 2, RetryBackoff: time.Millisecond})
+
+	_, err := scheduler.EmbedAll(context.Background(), []string{"a", "bb", "ccc", "dddd"})
+	var batchErr *BatchEmbedError
+	require.ErrorAs(t, err, &batchErr)
+	assert.Len(t, batchErr.Failed, 4)
+
+	// 鉴权失败既不重试也不拆批
+	assert.Len(t, embedder.batches, 1)
+}
+
+func TestEmbedScheduler_FallsBackToSingleEmbed(t *testing.T) {
+	var calls int
+	var mu sync.Mutex
+	embedder := &singleEmbedder{embed: func(text string) ([]float32, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		return []float32{float32(len(text))}, nil
+	}}
+	scheduler := NewEmbedScheduler(embedder, EmbedSchedulerOptions{BatchSize: 10})
+
+	results, err := scheduler.EmbedAll(context.Background(), []string{"a", "bb", "ccc"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, scheduler.BatchSize())
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []float32{3}, results[2])
+}
+
+type singleEmbedder struct {
+	embed func(text string) ([]float32, error)
+}
+
+func (e *singleEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return e.embed(text)
+}
+func (e *singleEmbedder) Dimensions() int { return 1 }
+func (e *singleEmbedder) Ready() bool     { return true }
+
+func TestEmbedScheduler_ReportsProgress(t *testing.T) {
+	embedder := &fakeBatchEmbedder{maxBatch: 2}
+	scheduler := NewEmbedScheduler(embedder, EmbedSchedulerOptions{Concurrency: 1})
+
+	var reported [][2]int
+	_, err := scheduler.EmbedAllWithProgress(context.Background(), []string{"a", "", "bb", "ccc"}, func(done, total int) {
+		reported = append(reported, [2]int{done, total})
+	})
+	var batchErr *BatchEmbedError
+	require.ErrorAs(t, err, &batchErr)
+	// 空文本先计入，之后每批完成上报一次
+	assert.Equal(t, [][2]int{{1, 4}, {3, 4}, {4, 4}}, reported)
+}
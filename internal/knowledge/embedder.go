@@ -3,6 +3,7 @@ package knowledge
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"sync"
 
@@ -16,6 +17,15 @@ type Embedder interface {
 	Ready() bool
 }
 
+// BatchEmbedder 支持批量向量化的Embedder（可选接口）
+type BatchEmbedder interface {
+	Embedder
+	// EmbedBatch 批量向量化，结果顺序与输入一致
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+	// MaxBatchSize 单次请求允许的最大文本数
+	MaxBatchSize() int
+}
+
 // NoopEmbedder 默认占位实现
 type NoopEmbedder struct{}
 
@@ -31,6 +41,18 @@ func (n *NoopEmbedder) Ready() bool {
 	return false
 }
 
+// NewProviderEmbedder 按提供商代码创建内置Embedder
+func NewProviderEmbedder(provider, model, apiKey string) (Embedder, error) {
+	switch strings.ToLower(provider) {
+	case "openai":
+		return NewOpenAIEmbedder(apiKey, model), nil
+	case "dashscope":
+		return NewDashScopeEmbedder(apiKey, model), nil
+	default:
+		return nil, fmt.Errorf("unsupported embedding provider: %s", provider)
+	}
+}
+
 var embeddingDimensions = map[string]int{
 	"text-embedding-3-large": 3072,
 	"text-embedding-3-small": 1536,
@@ -96,6 +118,45 @@ func (e *OpenAIEmbedder) Embed(ctx context.Context, text string) ([]float32, err
 	return result, nil
 }
 
+// openAIMaxBatchSize OpenAI单次请求最多2048条输入
+const openAIMaxBatchSize = 2048
+
+// EmbedBatch 批量向量化（并发由调用方控制，不占用Embed的串行锁）
+func (e *OpenAIEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	if e.client == nil {
+		return nil, errors.New("openai client not initialized")
+	}
+
+	resp, err := e.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Model: openai.EmbeddingModel(e.model),
+		Input: texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding response size mismatch: got %d, want %d", len(resp.Data), len(texts))
+	}
+
+	results := make([][]float32, len(texts))
+	for _, data := range resp.Data {
+		if data.Index < 0 || data.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding response index out of range: %d", data.Index)
+		}
+		embedding := make([]float32, len(data.Embedding))
+		copy(embedding, data.Embedding)
+		results[data.Index] = embedding
+	}
+	return results, nil
+}
+
+func (e *OpenAIEmbedder) MaxBatchSize() int {
+	return openAIMaxBatchSize
+}
+
 func (e *OpenAIEmbedder) Dimensions() int {
 	return e.dimensions
 }
@@ -55,6 +55,35 @@ func (e *DashScopeEmbedder) Embed(ctx context.Context, text string) ([]float32,
 	if strings.TrimSpace(text) == "" {
 		return nil, errors.New("text is empty")
 	}
+
+	results, err := e.createEmbeddings(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// EmbedBatch 批量向量化，单批不超过MaxBatchSize
+func (e *DashScopeEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	if len(texts) > e.MaxBatchSize() {
+		return nil, fmt.Errorf("batch size %d exceeds limit %d", len(texts), e.MaxBatchSize())
+	}
+	return e.createEmbeddings(ctx, texts)
+}
+
+// MaxBatchSize v3/v4模型单次最多10条，其余模型最多25条
+func (e *DashScopeEmbedder) MaxBatchSize() int {
+	if e.model == "text-embedding-v3" || e.model == "text-embedding-v4" {
+		return 10
+	}
+	return 25
+}
+
+// createEmbeddings 调用统一服务生成向量，结果按输入顺序返回
+func (e *DashScopeEmbedder) createEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
 	if e.service == nil || !e.service.Ready() {
 		return nil, errors.New("dashscope service not initialized")
 	}
@@ -62,7 +91,7 @@ func (e *DashScopeEmbedder) Embed(ctx context.Context, text string) ([]float32,
 	// 构建请求
 	req := dashscope.EmbeddingRequest{
 		Model:          e.model,
-		Input:          []string{text},
+		Input:          texts,
 		EncodingFormat: "float",
 	}
 
@@ -81,15 +110,31 @@ func (e *DashScopeEmbedder) Embed(ctx context.Context, text string) ([]float32,
 	if len(resp.Data) == 0 {
 		return nil, errors.New("embedding response empty")
 	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding response size mismatch: got %d, want %d", len(resp.Data), len(texts))
+	}
 
 	// 转换float64到float32
-	embedding := resp.Data[0].Embedding
-	result := make([]float32, len(embedding))
-	for i, v := range embedding {
-		result[i] = float32(v)
+	// 按index归位，index缺失或重复时按返回顺序
+	results := make([][]float32, len(texts))
+	for i, data := range resp.Data {
+		pos := data.Index
+		if pos < 0 || pos >= len(texts) || results[pos] != nil {
+			pos = i
+		}
+		embedding := make([]float32, len(data.Embedding))
+		for j, v := range data.Embedding {
+			embedding[j] = float32(v)
+		}
+		results[pos] = embedding
+	}
+	for i, embedding := range results {
+		if embedding == nil {
+			return nil, fmt.Errorf("embedding response missing index %d", i)
+		}
 	}
 
-	return result, nil
+	return results, nil
 }
 
 func (e *DashScopeEmbedder) Dimensions() int {
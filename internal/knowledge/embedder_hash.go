@@ -0,0 +1,81 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"strings"
+)
+
+const defaultHashEmbedderDimensions = 256
+
+// HashEmbedder 基于特征哈希的确定性向量化，不依赖外部服务
+// 相同文本总是得到相同向量，词语重叠越多余弦相似度越高；只用于离线评测与测试，不反映语义相似度
+type HashEmbedder struct {
+	dimensions int
+	tokenizer  Tokenizer
+}
+
+// NewHashEmbedder 创建确定性向量化器，dimensions<=0时为256维
+func NewHashEmbedder(dimensions int) *HashEmbedder {
+	if dimensions <= 0 {
+		dimensions = defaultHashEmbedderDimensions
+	}
+	return &HashEmbedder{dimensions: dimensions, tokenizer: NewStandardTokenizer(nil)}
+}
+
+func (h *HashEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, errors.New("text is empty")
+	}
+
+	vec := make([]float32, h.dimensions)
+	for _, token := range h.tokenizer.Tokenize(text) {
+		hash := fnv.New64a()
+		hash.Write([]byte(token.Term))
+		sum := hash.Sum64()
+		// 最高位决定符号，降低哈希冲突带来的偏差
+		sign := float32(1)
+		if sum>>63 == 1 {
+			sign = -1
+		}
+		vec[sum%uint64(h.dimensions)] += sign
+	}
+
+	norm := vectorNorm(vec)
+	if norm == 0 {
+		// 没有可用的分词时退化为整段文本的哈希
+		hash := fnv.New64a()
+		hash.Write([]byte(text))
+		vec[hash.Sum64()%uint64(h.dimensions)] = 1
+		return vec, nil
+	}
+	for i := range vec {
+		vec[i] = float32(float64(vec[i]) / norm)
+	}
+	return vec, nil
+}
+
+func (h *HashEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	results := make([][]float32, len(texts))
+	for i, text := range texts {
+		vec, err := h.Embed(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = vec
+	}
+	return results, nil
+}
+
+func (h *HashEmbedder) MaxBatchSize() int {
+	return 1000
+}
+
+func (h *HashEmbedder) Dimensions() int {
+	return h.dimensions
+}
+
+func (h *HashEmbedder) Ready() bool {
+	return true
+}
@@ -0,0 +1,296 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrEmbeddingDimensionMismatch 知识库记录的向量维度与当前Embedder不一致，检索会得到无意义的结果
+var ErrEmbeddingDimensionMismatch = errors.New("embedding dimension mismatch")
+
+// EmbeddingProfile 知识库向量所用的嵌入模型
+type EmbeddingProfile struct {
+	Provider   string `json:"provider"`
+	Model      string `json:"model"`
+	Dimensions int    `json:"dimensions"`
+}
+
+// IsZero 是否未记录模型
+func (p EmbeddingProfile) IsZero() bool {
+	return p.Provider == "" && p.Model == ""
+}
+
+func (p EmbeddingProfile) String() string {
+	return p.Provider + "/" + p.Model
+}
+
+// EmbedderFactory 按模型创建Embedder
+type EmbedderFactory func(profile EmbeddingProfile) (Embedder, error)
+
+// QueryEmbedder 查询向量化使用的Embedder及其对应的向量索引版本，Version为0表示当前别名
+type QueryEmbedder struct {
+	Profile  EmbeddingProfile
+	Embedder Embedder
+	Version  int
+}
+
+// VersionSearcher 支持直接检索指定版本的向量存储，用于模型迁移期间双读
+type VersionSearcher interface {
+	SearchVersion(ctx context.Context, version int, req VectorSearchRequest) ([]SearchMatch, error)
+}
+
+// embeddingMigration 迁移中的目标模型与写入的版本
+type embeddingMigration struct {
+	target  EmbeddingProfile
+	version int
+}
+
+// EmbeddingRouter 按知识库记录的嵌入模型路由向量化
+// 知识库未记录模型时使用全局配置，并在首次写入向量时记录下来；
+// 模型迁移期间同时返回新旧两个模型，检索双读两个版本；迁移状态记录在knowledge_bases中，各实例共享
+type EmbeddingRouter struct {
+	db       *gorm.DB
+	factory  EmbedderFactory
+	fallback EmbeddingProfile
+
+	schedulerOpts EmbedSchedulerOptions
+
+	mu         sync.RWMutex
+	embedders  map[string]Embedder // provider/model -> Embedder
+	schedulers map[string]*EmbedScheduler
+}
+
+// NewEmbeddingRouter 创建嵌入模型路由，fallback为全局配置的模型
+func NewEmbeddingRouter(db *gorm.DB, factory EmbedderFactory, fallback EmbeddingProfile) *EmbeddingRouter {
+	return &EmbeddingRouter{
+		db:         db,
+		factory:    factory,
+		fallback:   fallback,
+		embedders:  make(map[string]Embedder),
+		schedulers: make(map[string]*EmbedScheduler),
+	}
+}
+
+// SetSchedulerOptions 设置各模型批量向量化调度器的参数
+func (r *EmbeddingRouter) SetSchedulerOptions(opts EmbedSchedulerOptions) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schedulerOpts = opts
+}
+
+// Fallback 全局配置的模型
+func (r *EmbeddingRouter) Fallback() EmbeddingProfile {
+	return r.fallback
+}
+
+// Profile 读取知识库记录的模型，未记录时返回零值
+func (r *EmbeddingRouter) Profile(ctx context.Context, knowledgeBaseID uint) (EmbeddingProfile, error) {
+	profile, _, err := r.load(ctx, knowledgeBaseID)
+	return profile, err
+}
+
+// load 读取知识库记录的模型与迁移状态，未在迁移时迁移状态为零值
+func (r *EmbeddingRouter) load(ctx context.Context, knowledgeBaseID uint) (EmbeddingProfile, embeddingMigration, error) {
+	var row struct {
+		EmbeddingProvider            string
+		EmbeddingModel               string
+		EmbeddingDimensions          int
+		EmbeddingMigrationProvider   string
+		EmbeddingMigrationModel      string
+		EmbeddingMigrationDimensions int
+		EmbeddingMigrationVersion    int
+	}
+	err := r.db.WithContext(ctx).Table("knowledge_bases").
+		Select("embedding_provider, embedding_model, embedding_dimensions, "+
+			"embedding_migration_provider, embedding_migration_model, embedding_migration_dimensions, embedding_migration_version").
+		Where("knowledge_base_id = ?", knowledgeBaseID).
+		Take(&row).Error
+	if err != nil {
+		return EmbeddingProfile{}, embeddingMigration{}, fmt.Errorf("failed to load embedding profile: %w", err)
+	}
+	profile := EmbeddingProfile{
+		Provider:   row.EmbeddingProvider,
+		Model:      row.EmbeddingModel,
+		Dimensions: row.EmbeddingDimensions,
+	}
+	migration := embeddingMigration{
+		target: EmbeddingProfile{
+			Provider:   row.EmbeddingMigrationProvider,
+			Model:      row.EmbeddingMigrationModel,
+			Dimensions: row.EmbeddingMigrationDimensions,
+		},
+		version: row.EmbeddingMigrationVersion,
+	}
+	return profile, migration, nil
+}
+
+// SaveProfile 记录知识库的模型
+func (r *EmbeddingRouter) SaveProfile(ctx context.Context, knowledgeBaseID uint, profile EmbeddingProfile) error {
+	err := r.db.WithContext(ctx).Table("knowledge_bases").
+		Where("knowledge_base_id = ?", knowledgeBaseID).
+		Updates(map[string]interface{}{
+			"embedding_provider":   profile.Provider,
+			"embedding_model":      profile.Model,
+			"embedding_dimensions": profile.Dimensions,
+			"update_time":          time.Now(),
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to save embedding profile: %w", err)
+	}
+	return nil
+}
+
+// EnsureProfile 返回知识库的模型，未记录时记录全局配置的模型
+// 写入向量前调用，保证之后切换全局配置不会影响已有知识库
+func (r *EmbeddingRouter) EnsureProfile(ctx context.Context, knowledgeBaseID uint) (EmbeddingProfile, Embedder, error) {
+	profile, err := r.Profile(ctx, knowledgeBaseID)
+	if err != nil {
+		return EmbeddingProfile{}, nil, err
+	}
+	if !profile.IsZero() {
+		embedder, err := r.Embedder(profile)
+		return profile, embedder, err
+	}
+
+	profile = r.fallback
+	profile.Dimensions = 0
+	embedder, err := r.Embedder(profile)
+	if err != nil {
+		return EmbeddingProfile{}, nil, err
+	}
+	profile.Dimensions = embedder.Dimensions()
+	if err := r.SaveProfile(ctx, knowledgeBaseID, profile); err != nil {
+		return EmbeddingProfile{}, nil, err
+	}
+	return profile, embedder, nil
+}
+
+// Embedder 获取模型对应的Embedder，记录的维度与Embedder不一致时返回ErrEmbeddingDimensionMismatch
+func (r *EmbeddingRouter) Embedder(profile EmbeddingProfile) (Embedder, error) {
+	if profile.IsZero() {
+		return nil, errors.New("embedding model not configured")
+	}
+	key := profile.String()
+
+	r.mu.RLock()
+	embedder, ok := r.embedders[key]
+	r.mu.RUnlock()
+	if !ok {
+		created, err := r.factory(profile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create embedder %s: %w", key, err)
+		}
+		if !created.Ready() {
+			return nil, fmt.Errorf("embedder %s not ready", key)
+		}
+		r.mu.Lock()
+		if existing, ok := r.embedders[key]; ok {
+			created = existing
+		} else {
+			r.embedders[key] = created
+		}
+		r.mu.Unlock()
+		embedder = created
+	}
+
+	if profile.Dimensions > 0 && embedder.Dimensions() != profile.Dimensions {
+		return nil, fmt.Errorf("%w: %s stored %d, embedder %d",
+			ErrEmbeddingDimensionMismatch, key, profile.Dimensions, embedder.Dimensions())
+	}
+	return embedder, nil
+}
+
+// Scheduler 知识库写入向量使用的调度器，未记录模型时先记录全局配置的模型
+// 迁移期间仍按旧模型写入当前版本，新增分块由迁移的补写阶段写入新版本
+func (r *EmbeddingRouter) Scheduler(ctx context.Context, knowledgeBaseID uint) (*EmbedScheduler, error) {
+	profile, embedder, err := r.EnsureProfile(ctx, knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	return r.scheduler(profile, embedder), nil
+}
+
+// scheduler 每个模型共用一个调度器，限速对同一模型的所有写入生效
+func (r *EmbeddingRouter) scheduler(profile EmbeddingProfile, embedder Embedder) *EmbedScheduler {
+	key := profile.String()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if scheduler, ok := r.schedulers[key]; ok {
+		return scheduler
+	}
+	scheduler := NewEmbedScheduler(embedder, r.schedulerOpts)
+	r.schedulers[key] = scheduler
+	return scheduler
+}
+
+// QueryEmbedders 知识库检索使用的Embedder；迁移期间新模型在前，对应迁移中的版本
+// 迁移期间旧模型不可用时只读新版本，否则返回错误
+func (r *EmbeddingRouter) QueryEmbedders(ctx context.Context, knowledgeBaseID uint) ([]QueryEmbedder, error) {
+	profile, migration, err := r.load(ctx, knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	if profile.IsZero() {
+		profile = r.fallback
+	}
+
+	var embedders []QueryEmbedder
+	if migration.active() && migration.target.String() != profile.String() {
+		if target, err := r.Embedder(migration.target); err == nil {
+			embedders = append(embedders, QueryEmbedder{Profile: migration.target, Embedder: target, Version: migration.version})
+		}
+	}
+
+	embedder, err := r.Embedder(profile)
+	if err != nil {
+		if len(embedders) > 0 {
+			return embedders, nil
+		}
+		return nil, err
+	}
+	return append(embedders, QueryEmbedder{Profile: profile, Embedder: embedder}), nil
+}
+
+// MigrationTarget 知识库迁移中的目标模型
+func (r *EmbeddingRouter) MigrationTarget(ctx context.Context, knowledgeBaseID uint) (EmbeddingProfile, bool) {
+	_, migration, err := r.load(ctx, knowledgeBaseID)
+	if err != nil || !migration.active() {
+		return EmbeddingProfile{}, false
+	}
+	return migration.target, true
+}
+
+// active 是否在迁移中
+func (m embeddingMigration) active() bool {
+	return m.version > 0 && !m.target.IsZero()
+}
+
+// beginMigration 记录迁移中的目标模型与版本，各实例检索时开始双读
+func (r *EmbeddingRouter) beginMigration(ctx context.Context, knowledgeBaseID uint, target EmbeddingProfile, version int) error {
+	return r.saveMigration(ctx, knowledgeBaseID, embeddingMigration{target: target, version: version})
+}
+
+// endMigration 清除迁移状态
+func (r *EmbeddingRouter) endMigration(ctx context.Context, knowledgeBaseID uint) error {
+	return r.saveMigration(ctx, knowledgeBaseID, embeddingMigration{})
+}
+
+func (r *EmbeddingRouter) saveMigration(ctx context.Context, knowledgeBaseID uint, migration embeddingMigration) error {
+	err := r.db.WithContext(ctx).Table("knowledge_bases").
+		Where("knowledge_base_id = ?", knowledgeBaseID).
+		Updates(map[string]interface{}{
+			"embedding_migration_provider":   migration.target.Provider,
+			"embedding_migration_model":      migration.target.Model,
+			"embedding_migration_dimensions": migration.target.Dimensions,
+			"embedding_migration_version":    migration.version,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to save embedding migration: %w", err)
+	}
+	return nil
+}
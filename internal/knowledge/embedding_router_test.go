@@ -0,0 +1,156 @@
+package knowledge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedEmbedder 返回固定维度的向量
+type fixedEmbedder struct {
+	dims int
+}
+
+func (e *fixedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return make([]float32, e.dims), nil
+}
+func (e *fixedEmbedder) Dimensions() int { return e.dims }
+func (e *fixedEmbedder) Ready() bool     { return true }
+
+// versionedSearchStore 按版本返回预设结果，版本0为当前别名
+type versionedSearchStore struct {
+	results map[int][]SearchMatch
+	queries map[int]int // 版本 -> 查询向量维度
+}
+
+func (s *versionedSearchStore) UpsertChunk(ctx context.Context, chunk VectorChunk) (string, error) {
+	return "", nil
+}
+func (s *versionedSearchStore) DeleteDocument(ctx context.Context, kbID uint, documentID uint) error {
+	return nil
+}
+func (s *versionedSearchStore) Search(ctx context.Context, req VectorSearchRequest) ([]SearchMatch, error) {
+	return s.SearchVersion(ctx, 0, req)
+}
+func (s *versionedSearchStore) SearchVersion(ctx context.Context, version int, req VectorSearchRequest) ([]SearchMatch, error) {
+	s.queries[version] = len(req.QueryEmbedding)
+	return s.results[version], nil
+}
+func (s *versionedSearchStore) ScanChunks(ctx context.Context, kbID uint, fn func([]IndexedChunk) error) error {
+	return nil
+}
+func (s *versionedSearchStore) Ready() bool { return true }
+
+func newTestEmbeddingRouter(t *testing.T, dims map[string]int) (*EmbeddingRouter, sqlmock.Sqlmock) {
+	db, mock := newMockGormDB(t)
+	router := NewEmbeddingRouter(db, func(profile EmbeddingProfile) (Embedder, error) {
+		return &fixedEmbedder{dims: dims[profile.Model]}, nil
+	}, EmbeddingProfile{Provider: "openai", Model: "text-embedding-3-small"})
+	return router, mock
+}
+
+func expectProfile(mock sqlmock.Sqlmock, provider, model string, dims int) {
+	expectMigratingProfile(mock, provider, model, dims, EmbeddingProfile{}, 0)
+}
+
+// expectMigratingProfile 返回迁移中的知识库模型，version为0表示未在迁移
+func expectMigratingProfile(mock sqlmock.Sqlmock, provider, model string, dims int, target EmbeddingProfile, version int) {
+	mock.ExpectQuery(`SELECT embedding_provider, embedding_model, embedding_dimensions, embedding_migration_provider.* FROM "knowledge_bases"`).
+		WillReturnRows(sqlmock.NewRows([]string{"embedding_provider", "embedding_model", "embedding_dimensions",
+			"embedding_migration_provider", "embedding_migration_model", "embedding_migration_dimensions", "embedding_migration_version"}).
+			AddRow(provider, model, dims, target.Provider, target.Model, target.Dimensions, version))
+}
+
+func TestEmbeddingRouter_RefusesDimensionMismatch(t *testing.T) {
+	router, mock := newTestEmbeddingRouter(t, map[string]int{"text-embedding-v1": 1536})
+	expectProfile(mock, "dashscope", "text-embedding-v1", 1024)
+	expectProfile(mock, "dashscope", "text-embedding-v1", 1024)
+
+	_, err := router.QueryEmbedders(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrEmbeddingDimensionMismatch)
+
+	engine := NewHybridSearchEngine(nil, &versionedSearchStore{queries: make(map[int]int)}, nil, nil)
+	engine.SetEmbeddingRouter(router)
+	_, err = engine.Search(context.Background(), HybridSearchRequest{KnowledgeBaseID: 1, Query: "报销流程", Mode: "vector"})
+	assert.ErrorIs(t, err, ErrEmbeddingDimensionMismatch)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmbeddingRouter_FallbackProfileRecordedOnce(t *testing.T) {
+	router, mock := newTestEmbeddingRouter(t, map[string]int{"text-embedding-3-small": 1536})
+	expectProfile(mock, "", "", 0)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "knowledge_bases" SET`).
+		WithArgs(1536, "text-embedding-3-small", "openai", sqlmock.AnyArg(), uint(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectProfile(mock, "openai", "text-embedding-3-small", 1536)
+
+	first, err := router.Scheduler(context.Background(), 3)
+	require.NoError(t, err)
+	second, err := router.Scheduler(context.Background(), 3)
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+	assert.Equal(t, 1536, first.Embedder().Dimensions())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHybridSearchEngine_DualReadDuringMigration(t *testing.T) {
+	router, mock := newTestEmbeddingRouter(t, map[string]int{"old": 4, "new": 8})
+	// 迁移状态由执行迁移的实例写入knowledge_bases，其他实例读取后同样双读
+	expectMigratingProfile(mock, "openai", "old", 4, EmbeddingProfile{Provider: "openai", Model: "new", Dimensions: 8}, 2)
+
+	store := &versionedSearchStore{
+		queries: make(map[int]int),
+		results: map[int][]SearchMatch{
+			0: {{ChunkID: 1, Score: 0.95}, {ChunkID: 2, Score: 0.91}},
+			2: {{ChunkID: 2, Score: 0.97}, {ChunkID: 3, Score: 0.92}},
+		},
+	}
+	engine := NewHybridSearchEngine(nil, store, nil, nil)
+	engine.SetEmbeddingRouter(router)
+
+	matches, err := engine.Search(context.Background(), HybridSearchRequest{KnowledgeBaseID: 1, Query: "报销流程", Mode: "vector"})
+	require.NoError(t, err)
+
+	// 新模型查询新版本，旧模型查询当前别名
+	assert.Equal(t, map[int]int{0: 4, 2: 8}, store.queries)
+	ids := make([]uint, len(matches))
+	for i, m := range matches {
+		ids[i] = m.ChunkID
+	}
+	assert.Equal(t, []uint{2, 1, 3}, ids)
+	assert.Equal(t, 0.97, matches[0].Score)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmbeddingRouter_PersistsMigration(t *testing.T) {
+	router, mock := newTestEmbeddingRouter(t, map[string]int{"old": 4, "new": 8})
+	target := EmbeddingProfile{Provider: "openai", Model: "new", Dimensions: 8}
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "knowledge_bases" SET`).
+		WithArgs(8, "new", "openai", 2, uint(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectMigratingProfile(mock, "openai", "old", 4, target, 2)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "knowledge_bases" SET`).
+		WithArgs(0, "", "", 0, uint(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectProfile(mock, "openai", "new", 8)
+
+	ctx := context.Background()
+	require.NoError(t, router.beginMigration(ctx, 1, target, 2))
+	migrating, ok := router.MigrationTarget(ctx, 1)
+	assert.True(t, ok)
+	assert.Equal(t, target, migrating)
+
+	require.NoError(t, router.endMigration(ctx, 1))
+	_, ok = router.MigrationTarget(ctx, 1)
+	assert.False(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
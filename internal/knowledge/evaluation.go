@@ -0,0 +1,295 @@
+package knowledge
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 混合检索的融合方式
+const (
+	FusionAdaptive = "adaptive" // 按查询类型动态调整权重（SmartWeightAdjuster）
+	FusionWeighted = "weighted" // 固定权重
+)
+
+var defaultEvalKs = []int{1, 5, 10}
+
+// EvalQuery 标注的评测查询，指定了分块时按分块判定相关，否则按文档判定
+type EvalQuery struct {
+	ID                  string `json:"id,omitempty"`
+	Query               string `json:"query"`
+	RelevantDocumentIDs []uint `json:"relevant_document_ids,omitempty"`
+	RelevantChunkIDs    []uint `json:"relevant_chunk_ids,omitempty"`
+}
+
+// EvalConfig 一组检索配置，零值字段使用引擎默认值
+type EvalConfig struct {
+	Name            string  `json:"name"`
+	Mode            string  `json:"mode"`                      // auto | hybrid | vector | fulltext
+	Fusion          string  `json:"fusion,omitempty"`          // adaptive | weighted
+	VectorWeight    float64 `json:"vector_weight,omitempty"`   // 固定权重时的向量权重
+	FulltextWeight  float64 `json:"fulltext_weight,omitempty"` // 固定权重时的全文权重
+	Rerank          bool    `json:"rerank"`
+	VectorThreshold float64 `json:"vector_threshold,omitempty"`
+}
+
+// EvalMetrics 一组配置在整个查询集上的指标，召回率与nDCG按k取值
+type EvalMetrics struct {
+	Queries      int             `json:"queries"`
+	Errors       int             `json:"errors"`
+	Recall       map[int]float64 `json:"recall"`
+	NDCG         map[int]float64 `json:"ndcg"`
+	MRR          float64         `json:"mrr"`
+	LatencyP50Ms float64         `json:"latency_p50_ms"`
+	LatencyP90Ms float64         `json:"latency_p90_ms"`
+	LatencyP99Ms float64         `json:"latency_p99_ms"`
+}
+
+// EvalResult 一组配置的评测结果
+type EvalResult struct {
+	Config  EvalConfig  `json:"config"`
+	Metrics EvalMetrics `json:"metrics"`
+}
+
+// DefaultEvalConfigs 默认的配置组合：各检索模式、融合方式与重排序开关
+func DefaultEvalConfigs() []EvalConfig {
+	return []EvalConfig{
+		{Name: "fulltext", Mode: "fulltext"},
+		{Name: "vector", Mode: "vector"},
+		{Name: "hybrid", Mode: "hybrid", Fusion: FusionAdaptive},
+		{Name: "hybrid-weighted", Mode: "hybrid", Fusion: FusionWeighted, VectorWeight: 0.6, FulltextWeight: 0.4},
+		{Name: "hybrid-rerank", Mode: "hybrid", Fusion: FusionAdaptive, Rerank: true},
+		{Name: "auto", Mode: "auto"},
+	}
+}
+
+// ParseEvalQueries 读取JSONL格式的查询集，空行与#开头的行忽略
+func ParseEvalQueries(r io.Reader) ([]EvalQuery, error) {
+	var queries []EvalQuery
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var query EvalQuery
+		if err := json.Unmarshal([]byte(text), &query); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := query.validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if query.ID == "" {
+			query.ID = fmt.Sprintf("q%d", len(queries)+1)
+		}
+		queries = append(queries, query)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return queries, nil
+}
+
+// QuerySetHash 查询集内容的SHA-256，用于判断两次评测是否使用同一查询集
+func QuerySetHash(queries []EvalQuery) string {
+	hash := sha256.New()
+	encoder := json.NewEncoder(hash)
+	for _, q := range queries {
+		_ = encoder.Encode(q)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// DescribeEmbedder 评测记录中的向量化模型描述，如HashEmbedder/256
+func DescribeEmbedder(embedder Embedder) string {
+	if embedder == nil || !embedder.Ready() {
+		return ""
+	}
+	name := strings.TrimPrefix(fmt.Sprintf("%T", embedder), "*knowledge.")
+	return fmt.Sprintf("%s/%d", name, embedder.Dimensions())
+}
+
+func (q EvalQuery) validate() error {
+	if strings.TrimSpace(q.Query) == "" {
+		return fmt.Errorf("query is empty")
+	}
+	if len(q.RelevantDocumentIDs) == 0 && len(q.RelevantChunkIDs) == 0 {
+		return fmt.Errorf("query %q has no relevant documents or chunks", q.Query)
+	}
+	return nil
+}
+
+// Evaluate 在知识库上按各组配置依次运行查询集，查询串行执行以便统计延迟
+func (e *HybridSearchEngine) Evaluate(ctx context.Context, knowledgeBaseID uint, queries []EvalQuery, configs []EvalConfig, ks []int) ([]EvalResult, error) {
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("query set is empty")
+	}
+	for _, q := range queries {
+		if err := q.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if len(configs) == 0 {
+		configs = DefaultEvalConfigs()
+	}
+	if len(ks) == 0 {
+		ks = defaultEvalKs
+	}
+	ks = append([]int(nil), ks...)
+	sort.Ints(ks)
+	limit := ks[len(ks)-1]
+
+	results := make([]EvalResult, 0, len(configs))
+	for i, cfg := range configs {
+		if cfg.Name == "" {
+			cfg.Name = fmt.Sprintf("config-%d", i+1)
+		}
+		engine := e.withEvalConfig(cfg)
+
+		var (
+			latencies []float64
+			ranked    [][]SearchMatch
+			errs      int
+		)
+		for _, q := range queries {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			start := time.Now()
+			matches, err := engine.Search(ctx, HybridSearchRequest{
+				KnowledgeBaseID: knowledgeBaseID,
+				Query:           q.Query,
+				Limit:           limit,
+				Mode:            cfg.Mode,
+				VectorThreshold: cfg.VectorThreshold,
+			})
+			latencies = append(latencies, float64(time.Since(start).Microseconds())/1000)
+			if err != nil {
+				errs++
+				matches = nil
+			}
+			ranked = append(ranked, matches)
+		}
+
+		metrics := computeEvalMetrics(queries, ranked, ks)
+		metrics.Errors = errs
+		metrics.LatencyP50Ms = percentile(latencies, 50)
+		metrics.LatencyP90Ms = percentile(latencies, 90)
+		metrics.LatencyP99Ms = percentile(latencies, 99)
+		results = append(results, EvalResult{Config: cfg, Metrics: metrics})
+	}
+	return results, nil
+}
+
+// withEvalConfig 复制引擎并应用融合与重排序配置，不影响线上检索使用的引擎
+// 评测不做查询转换，知识库开启的改写与多查询扩展会使结果依赖对话模型的输出
+func (e *HybridSearchEngine) withEvalConfig(cfg EvalConfig) *HybridSearchEngine {
+	engine := *e
+	engine.snapshots = newSearchSnapshots()
+	engine.transformer = nil
+	if !cfg.Rerank {
+		engine.reranker = nil
+	}
+	if cfg.Fusion == FusionWeighted {
+		engine.weightAdjuster = nil
+		if cfg.VectorWeight > 0 || cfg.FulltextWeight > 0 {
+			engine.vectorWeight, engine.fulltextWeight = cfg.VectorWeight, cfg.FulltextWeight
+		}
+	}
+	return &engine
+}
+
+// computeEvalMetrics 计算召回率@k、MRR与nDCG@k（二元相关性），同一文档的多个分块只计一次
+func computeEvalMetrics(queries []EvalQuery, ranked [][]SearchMatch, ks []int) EvalMetrics {
+	metrics := EvalMetrics{
+		Queries: len(queries),
+		Recall:  make(map[int]float64, len(ks)),
+		NDCG:    make(map[int]float64, len(ks)),
+	}
+	if len(queries) == 0 {
+		return metrics
+	}
+
+	for i, q := range queries {
+		relevant := make(map[uint]bool)
+		byChunk := len(q.RelevantChunkIDs) > 0
+		if byChunk {
+			for _, id := range q.RelevantChunkIDs {
+				relevant[id] = true
+			}
+		} else {
+			for _, id := range q.RelevantDocumentIDs {
+				relevant[id] = true
+			}
+		}
+
+		// hits[r]为第r名是否命中一个尚未命中过的相关项
+		hits := make([]bool, len(ranked[i]))
+		seen := make(map[uint]bool)
+		for r, m := range ranked[i] {
+			id := m.DocumentID
+			if byChunk {
+				id = m.ChunkID
+			}
+			if relevant[id] && !seen[id] {
+				seen[id] = true
+				hits[r] = true
+			}
+		}
+
+		for r, hit := range hits {
+			if hit {
+				metrics.MRR += 1 / float64(r+1)
+				break
+			}
+		}
+		for _, k := range ks {
+			found, dcg := 0, 0.0
+			for r := 0; r < k && r < len(hits); r++ {
+				if hits[r] {
+					found++
+					dcg += 1 / math.Log2(float64(r+2))
+				}
+			}
+			idcg := 0.0
+			for r := 0; r < k && r < len(relevant); r++ {
+				idcg += 1 / math.Log2(float64(r+2))
+			}
+			metrics.Recall[k] += float64(found) / float64(len(relevant))
+			metrics.NDCG[k] += dcg / idcg
+		}
+	}
+
+	n := float64(len(queries))
+	metrics.MRR /= n
+	for _, k := range ks {
+		metrics.Recall[k] /= n
+		metrics.NDCG[k] /= n
+	}
+	return metrics
+}
+
+// percentile 最近秩法计算百分位数
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,113 @@
+package knowledge
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEvalQueries(t *testing.T) {
+	input := `# 人事制度
+{"query":"年假怎么申请","relevant_document_ids":[1]}
+
+{"id":"sick","query":"病假工资","relevant_chunk_ids":[7,8]}
+`
+	queries, err := ParseEvalQueries(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, queries, 2)
+	assert.Equal(t, "q1", queries[0].ID)
+	assert.Equal(t, "sick", queries[1].ID)
+	assert.Equal(t, []uint{7, 8}, queries[1].RelevantChunkIDs)
+
+	_, err = ParseEvalQueries(strings.NewReader(`{"query":"年假"}`))
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestComputeEvalMetrics(t *testing.T) {
+	queries := []EvalQuery{
+		{Query: "a", RelevantDocumentIDs: []uint{1, 2}},
+		{Query: "b", RelevantChunkIDs: []uint{30}},
+	}
+	ranked := [][]SearchMatch{
+		// 文档1的两个分块只计一次命中
+		{{ChunkID: 10, DocumentID: 1}, {ChunkID: 11, DocumentID: 1}, {ChunkID: 20, DocumentID: 2}},
+		{{ChunkID: 31, DocumentID: 3}, {ChunkID: 30, DocumentID: 3}},
+	}
+
+	metrics := computeEvalMetrics(queries, ranked, []int{1, 3})
+	assert.Equal(t, 2, metrics.Queries)
+	assert.InDelta(t, (0.5+0)/2, metrics.Recall[1], 1e-9)
+	assert.InDelta(t, (1.0+1.0)/2, metrics.Recall[3], 1e-9)
+	assert.InDelta(t, (1+0.5)/2, metrics.MRR, 1e-9)
+	assert.InDelta(t, (1.0+0)/2, metrics.NDCG[1], 1e-9)
+	// a: (1+1/log2(4))/(1+1/log2(3))，b: (1/log2(3))/1
+	ndcgA := (1 + 1/math.Log2(4)) / (1 + 1/math.Log2(3))
+	assert.InDelta(t, (ndcgA+1/math.Log2(3))/2, metrics.NDCG[3], 1e-9)
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{5, 1, 4, 2, 3}
+	assert.Equal(t, 3.0, percentile(values, 50))
+	assert.Equal(t, 5.0, percentile(values, 99))
+	assert.Equal(t, 0.0, percentile(nil, 50))
+}
+
+func TestHybridSearchEngine_Evaluate(t *testing.T) {
+	indexer := &limitRecordingIndexer{results: []SearchMatch{
+		{ChunkID: 1, DocumentID: 1, Score: 0.9},
+		{ChunkID: 2, DocumentID: 2, Score: 0.8},
+	}}
+	engine := NewHybridSearchEngine(indexer, nil, nil, nil)
+	queries := []EvalQuery{{ID: "q1", Query: "年假", RelevantDocumentIDs: []uint{2}}}
+
+	results, err := engine.Evaluate(context.Background(), 1, queries, []EvalConfig{{Mode: "fulltext"}}, []int{5, 1})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "config-1", results[0].Config.Name)
+	m := results[0].Metrics
+	assert.Equal(t, 0, m.Errors)
+	assert.Equal(t, 0.0, m.Recall[1])
+	assert.Equal(t, 1.0, m.Recall[5])
+	assert.InDelta(t, 0.5, m.MRR, 1e-9)
+
+	_, err = engine.Evaluate(context.Background(), 1, nil, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestHybridSearchEngine_EvaluateSkipsQueryTransform(t *testing.T) {
+	db, _ := newMockGormDB(t)
+	model := newFakeChatModel()
+	indexer := &queryRecordingIndexer{}
+	engine := NewHybridSearchEngine(indexer, nil, nil, nil)
+	// 知识库配置了查询转换，评测时仍使用原查询
+	transformer := NewQueryTransformer(model, "", db)
+	transformer.options[1] = transformOptionsEntry{
+		options:   QueryTransformOptions{Rewrite: true, MultiQuery: 2},
+		expiresAt: time.Now().Add(time.Minute),
+	}
+	engine.SetQueryTransformer(transformer)
+
+	queries := []EvalQuery{{ID: "q1", Query: "年假", RelevantDocumentIDs: []uint{2}}}
+	_, err := engine.Evaluate(context.Background(), 1, queries, []EvalConfig{{Mode: "fulltext"}}, []int{5})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"年假"}, indexer.queries)
+	assert.Zero(t, model.calls["rewrite"])
+	assert.Zero(t, model.calls["multi"])
+}
+
+func TestHashEmbedder_Deterministic(t *testing.T) {
+	embedder := NewHashEmbedder(64)
+	a, err := embedder.Embed(context.Background(), "年假申请流程")
+	require.NoError(t, err)
+	b, err := embedder.Embed(context.Background(), "年假申请流程")
+	require.NoError(t, err)
+	assert.Equal(t, a, b)
+	assert.Len(t, a, 64)
+	assert.InDelta(t, 1.0, vectorNorm(a), 1e-6)
+	assert.Equal(t, "HashEmbedder/64", DescribeEmbedder(embedder))
+}
@@ -0,0 +1,92 @@
+package knowledge
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+)
+
+// 检索结果的分面字段
+const (
+	FacetDocument = "document"  // 文档ID
+	FacetFileType = "file_type" // 文件扩展名
+	FacetSource   = "source"    // 文档来源（上传、网页等）
+	FacetTag      = "tag"       // 文档元数据中的tags
+)
+
+// 命中总数的精确程度，与ES的hits.total.relation一致
+const (
+	TotalRelationEqual        = "eq"
+	TotalRelationGreaterEqual = "gte"
+)
+
+const (
+	defaultFacetSize = 10
+	maxFacetSize     = 50
+	facetTrackHits   = 10000 // 精确统计命中数的上限，超过后为下限估计
+)
+
+// FacetRequest 全文检索分面统计请求
+type FacetRequest struct {
+	KnowledgeBaseID uint
+	Query           string
+	Fields          []string // 为空时只统计命中数
+	Size            int      // 每个字段返回的取值数量，默认10
+}
+
+// FacetCount 分面取值及命中的分块数
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// FacetResult 全文检索的命中数与分面统计
+type FacetResult struct {
+	Total         int64
+	TotalRelation string
+	Facets        map[string][]FacetCount
+}
+
+// FacetedIndexer 支持命中数与分面统计的全文索引
+type FacetedIndexer interface {
+	Facets(ctx context.Context, req FacetRequest) (*FacetResult, error)
+}
+
+// ValidFacetField 是否为支持的分面字段
+func ValidFacetField(field string) bool {
+	switch field {
+	case FacetDocument, FacetFileType, FacetSource, FacetTag:
+		return true
+	}
+	return false
+}
+
+func (r FacetRequest) size() int {
+	switch {
+	case r.Size <= 0:
+		return defaultFacetSize
+	case r.Size > maxFacetSize:
+		return maxFacetSize
+	}
+	return r.Size
+}
+
+// fileTypeOf 文件扩展名（小写，不含点）
+func fileTypeOf(path string) string {
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+}
+
+// tagsOf 读取文档元数据中的tags数组
+func tagsOf(metadata map[string]interface{}) []string {
+	raw, ok := metadata["tags"].([]interface{})
+	if !ok {
+		return nil
+	}
+	tags := make([]string, 0, len(raw))
+	for _, item := range raw {
+		if tag, ok := item.(string); ok && strings.TrimSpace(tag) != "" {
+			tags = append(tags, strings.TrimSpace(tag))
+		}
+	}
+	return tags
+}
@@ -0,0 +1,203 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 跨知识库检索的得分归一化方式
+const (
+	FederatedNormalizeMinMax = "minmax" // 各知识库的原始得分分别min-max归一化后合并
+	FederatedNormalizeRRF    = "rrf"    // 倒数排名融合，只使用各知识库内的排名
+	FederatedNormalizeRerank = "rerank" // 合并后用重排序模型统一打分，重排序不可用时退回minmax
+)
+
+// 单个知识库的检索状态
+const (
+	FederatedStatusOK      = "ok"
+	FederatedStatusTimeout = "timeout"
+	FederatedStatusError   = "error"
+)
+
+const (
+	defaultFederatedTimeout = 5 * time.Second
+	maxFederatedParallel    = 8
+	rrfK                    = 60
+)
+
+// FederatedSearchRequest 跨知识库检索请求
+type FederatedSearchRequest struct {
+	KnowledgeBaseIDs []uint
+	Query            string
+	Limit            int
+	Mode             string
+	VectorThreshold  float64
+	Timeout          time.Duration // 单个知识库的超时，默认5秒
+	Normalization    string        // minmax | rrf | rerank，默认配置了重排序时为rerank，否则为minmax
+}
+
+// FederatedMatch 跨知识库检索结果，Score为归一化后的得分
+type FederatedMatch struct {
+	SearchMatch
+	KnowledgeBaseID uint
+	RawScore        float64 // 知识库内的原始得分
+}
+
+// FederatedFacet 单个知识库的检索统计
+type FederatedFacet struct {
+	KnowledgeBaseID uint   `json:"knowledge_base_id"`
+	Status          string `json:"status"`
+	Error           string `json:"error,omitempty"`
+	Candidates      int    `json:"candidates"` // 该知识库召回的数量
+	Returned        int    `json:"returned"`   // 进入最终结果的数量
+	TookMs          int64  `json:"took_ms"`
+}
+
+// FederatedSearchResult 跨知识库检索结果
+type FederatedSearchResult struct {
+	Matches       []FederatedMatch
+	Facets        []FederatedFacet
+	Normalization string
+}
+
+// FederatedSearch 并发检索多个知识库并统一得分
+// 单个知识库超时或失败时记录在Facets中，其余知识库的结果照常返回；全部失败时返回错误
+func (e *HybridSearchEngine) FederatedSearch(ctx context.Context, req FederatedSearchRequest) (*FederatedSearchResult, error) {
+	if len(req.KnowledgeBaseIDs) == 0 {
+		return &FederatedSearchResult{Normalization: req.Normalization}, nil
+	}
+	if req.Limit <= 0 {
+		req.Limit = 10
+	}
+	if req.Timeout <= 0 {
+		req.Timeout = defaultFederatedTimeout
+	}
+	normalization := req.Normalization
+	if normalization == "" || normalization == FederatedNormalizeRerank {
+		normalization = FederatedNormalizeMinMax
+		if e.reranker != nil && e.reranker.Ready() {
+			normalization = FederatedNormalizeRerank
+		}
+	}
+
+	perBase := make([][]SearchMatch, len(req.KnowledgeBaseIDs))
+	facets := make([]FederatedFacet, len(req.KnowledgeBaseIDs))
+	sem := make(chan struct{}, maxFederatedParallel)
+	var wg sync.WaitGroup
+	for i, kbID := range req.KnowledgeBaseIDs {
+		wg.Add(1)
+		go func(i int, kbID uint) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			baseCtx, cancel := context.WithTimeout(ctx, req.Timeout)
+			defer cancel()
+			start := time.Now()
+			matches, err := e.Search(baseCtx, HybridSearchRequest{
+				KnowledgeBaseID: kbID,
+				Query:           req.Query,
+				Limit:           req.Limit,
+				Mode:            req.Mode,
+				VectorThreshold: req.VectorThreshold,
+			})
+
+			facet := FederatedFacet{KnowledgeBaseID: kbID, Status: FederatedStatusOK, TookMs: time.Since(start).Milliseconds()}
+			switch {
+			case err != nil && errors.Is(baseCtx.Err(), context.DeadlineExceeded):
+				facet.Status, facet.Error = FederatedStatusTimeout, err.Error()
+			case err != nil:
+				facet.Status, facet.Error = FederatedStatusError, err.Error()
+			default:
+				facet.Candidates = len(matches)
+				perBase[i] = matches
+			}
+			facets[i] = facet
+		}(i, kbID)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, f := range facets {
+		if f.Status != FederatedStatusOK {
+			failed++
+		}
+	}
+	if failed == len(facets) {
+		return &FederatedSearchResult{Facets: facets, Normalization: normalization},
+			errors.New("all knowledge bases failed: " + facets[0].Error)
+	}
+
+	merged := normalizeFederated(req.KnowledgeBaseIDs, perBase, normalization)
+	if normalization == FederatedNormalizeRerank {
+		merged = e.rerankFederated(ctx, req.Query, merged, req.Limit)
+	}
+	if len(merged) > req.Limit {
+		merged = merged[:req.Limit]
+	}
+
+	index := make(map[uint]int, len(facets))
+	for i, f := range facets {
+		index[f.KnowledgeBaseID] = i
+	}
+	for _, m := range merged {
+		facets[index[m.KnowledgeBaseID]].Returned++
+	}
+	return &FederatedSearchResult{Matches: merged, Facets: facets, Normalization: normalization}, nil
+}
+
+// normalizeFederated 按归一化方式计算各结果的全局得分并排序
+// 各知识库的原始得分量纲不同（如BM25与余弦相似度），minmax在每个知识库内分别归一化后再合并
+// rerank先按minmax排序，作为重排序失败时的结果
+func normalizeFederated(kbIDs []uint, perBase [][]SearchMatch, normalization string) []FederatedMatch {
+	var merged []FederatedMatch
+	for i, matches := range perBase {
+		if len(matches) == 0 {
+			continue
+		}
+		ranked := make([]SearchMatch, len(matches))
+		copy(ranked, matches)
+		sortMatchesByScore(ranked)
+		maxScore, minScore := ranked[0].Score, ranked[len(ranked)-1].Score
+		for rank, m := range ranked {
+			fm := FederatedMatch{SearchMatch: m, KnowledgeBaseID: kbIDs[i], RawScore: m.Score}
+			switch {
+			case normalization == FederatedNormalizeRRF:
+				fm.Score = 1 / float64(rrfK+rank+1)
+			case maxScore > minScore:
+				fm.Score = (m.Score - minScore) / (maxScore - minScore)
+			default:
+				fm.Score = 1
+			}
+			merged = append(merged, fm)
+		}
+	}
+
+	// 原始得分跨知识库不可比较，同分时保持知识库的请求顺序
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Score > merged[j].Score
+	})
+	return merged
+}
+
+// rerankFederated 用重排序模型对合并后的候选统一打分
+func (e *HybridSearchEngine) rerankFederated(ctx context.Context, query string, merged []FederatedMatch, limit int) []FederatedMatch {
+	candidates := make([]SearchMatch, len(merged))
+	byChunk := make(map[uint]FederatedMatch, len(merged))
+	for i, m := range merged {
+		candidates[i] = m.SearchMatch
+		byChunk[m.ChunkID] = m
+	}
+
+	reranked := e.applyRerank(ctx, query, candidates, limit)
+	result := make([]FederatedMatch, 0, len(reranked))
+	for _, m := range reranked {
+		fm := byChunk[m.ChunkID]
+		fm.Score = m.Score
+		result = append(result, fm)
+	}
+	return result
+}
@@ -0,0 +1,126 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// kbIndexer 按知识库返回预设结果，可模拟超时与失败
+type kbIndexer struct {
+	NoopFulltextIndexer
+	results map[uint][]SearchMatch
+	slow    map[uint]bool
+	failing map[uint]bool
+}
+
+func (f *kbIndexer) Search(ctx context.Context, req FulltextSearchRequest) ([]SearchMatch, error) {
+	if f.slow[req.KnowledgeBaseID] {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if f.failing[req.KnowledgeBaseID] {
+		return nil, errors.New("index unavailable")
+	}
+	return f.results[req.KnowledgeBaseID], nil
+}
+
+func (f *kbIndexer) Ready() bool { return true }
+
+func TestNormalizeFederated(t *testing.T) {
+	perBase := [][]SearchMatch{
+		{{ChunkID: 1, Score: 0.3}, {ChunkID: 2, Score: 0.2}},
+		{{ChunkID: 3, Score: 0.9}, {ChunkID: 4, Score: 0.8}},
+	}
+
+	// 每个知识库内分别归一化，同分时保持知识库顺序
+	merged := normalizeFederated([]uint{1, 2}, perBase, FederatedNormalizeMinMax)
+	require.Len(t, merged, 4)
+	assert.Equal(t, []uint{1, 3, 2, 4}, federatedIDs(merged))
+	assert.Equal(t, 1.0, merged[1].Score)
+	assert.Equal(t, 0.9, merged[1].RawScore)
+	assert.Equal(t, uint(2), merged[1].KnowledgeBaseID)
+	assert.Equal(t, 0.0, merged[3].Score)
+
+	// 只有一个得分时全部为1
+	single := normalizeFederated([]uint{1}, [][]SearchMatch{{{ChunkID: 1, Score: 0.4}}}, FederatedNormalizeMinMax)
+	assert.Equal(t, 1.0, single[0].Score)
+
+	rrf := normalizeFederated([]uint{1, 2}, perBase, FederatedNormalizeRRF)
+	assert.Equal(t, []uint{1, 3, 2, 4}, federatedIDs(rrf))
+	assert.InDelta(t, 1.0/61, rrf[0].Score, 1e-9)
+}
+
+func TestNormalizeFederated_InterleavesScoreScales(t *testing.T) {
+	// 知识库1为BM25得分，知识库2为余弦相似度，合并后按各自的相对得分交错排列
+	perBase := [][]SearchMatch{
+		{{ChunkID: 1, Score: 12}, {ChunkID: 2, Score: 6}, {ChunkID: 3, Score: 2}},
+		{{ChunkID: 4, Score: 0.9}, {ChunkID: 5, Score: 0.8}, {ChunkID: 6, Score: 0.1}},
+	}
+
+	merged := normalizeFederated([]uint{1, 2}, perBase, FederatedNormalizeMinMax)
+	assert.Equal(t, []uint{1, 4, 5, 2, 3, 6}, federatedIDs(merged))
+	assert.InDelta(t, 0.875, merged[2].Score, 1e-9)
+	assert.InDelta(t, 0.4, merged[3].Score, 1e-9)
+	assert.Equal(t, 12.0, merged[0].RawScore)
+}
+
+func TestHybridSearchEngine_FederatedSearchPartialFailure(t *testing.T) {
+	indexer := &kbIndexer{
+		results: map[uint][]SearchMatch{
+			1: {{ChunkID: 1, Score: 5}, {ChunkID: 2, Score: 1}},
+			2: {{ChunkID: 3, Score: 0.8}},
+		},
+		slow:    map[uint]bool{3: true},
+		failing: map[uint]bool{4: true},
+	}
+	engine := NewHybridSearchEngine(indexer, nil, nil, nil)
+
+	result, err := engine.FederatedSearch(context.Background(), FederatedSearchRequest{
+		KnowledgeBaseIDs: []uint{1, 2, 3, 4},
+		Query:            "年假",
+		Limit:            2,
+		Mode:             "fulltext",
+		Timeout:          50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, FederatedNormalizeMinMax, result.Normalization)
+	assert.Equal(t, []uint{1, 3}, federatedIDs(result.Matches))
+	require.Len(t, result.Facets, 4)
+	assert.Equal(t, FederatedFacet{KnowledgeBaseID: 1, Status: FederatedStatusOK, Candidates: 2, Returned: 1}, withoutTook(result.Facets[0]))
+	assert.Equal(t, 1, result.Facets[1].Returned)
+	assert.Equal(t, FederatedStatusTimeout, result.Facets[2].Status)
+	assert.Equal(t, FederatedStatusError, result.Facets[3].Status)
+	assert.Contains(t, result.Facets[3].Error, "index unavailable")
+}
+
+func TestHybridSearchEngine_FederatedSearchAllFailed(t *testing.T) {
+	indexer := &kbIndexer{failing: map[uint]bool{1: true, 2: true}}
+	engine := NewHybridSearchEngine(indexer, nil, nil, nil)
+
+	result, err := engine.FederatedSearch(context.Background(), FederatedSearchRequest{
+		KnowledgeBaseIDs: []uint{1, 2},
+		Query:            "年假",
+		Mode:             "fulltext",
+	})
+	require.Error(t, err)
+	assert.Len(t, result.Facets, 2)
+}
+
+func federatedIDs(matches []FederatedMatch) []uint {
+	ids := make([]uint, len(matches))
+	for i, m := range matches {
+		ids[i] = m.ChunkID
+	}
+	return ids
+}
+
+func withoutTook(f FederatedFacet) FederatedFacet {
+	f.TookMs = 0
+	return f
+}
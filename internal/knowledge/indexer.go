@@ -2,6 +2,8 @@ package knowledge
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 )
 
@@ -14,6 +16,8 @@ type FulltextChunk struct {
 	ChunkIndex      int
 	FileName        string
 	FileType        string
+	Source          string   // 文档来源，用于分面统计
+	Tags            []string // 文档标签，用于分面统计
 	Metadata        map[string]interface{}
 	CreatedAt       time.Time
 }
@@ -41,5 +45,24 @@ type FulltextIndexer interface {
 	IndexChunk(ctx context.Context, chunk FulltextChunk) error
 	RemoveDocument(ctx context.Context, knowledgeBaseID uint, documentID uint) error
 	Search(ctx context.Context, req FulltextSearchRequest) ([]SearchMatch, error)
+	// ScanChunks 分批列举知识库中已索引的分块，用于跨存储一致性检查，fn返回错误时停止
+	ScanChunks(ctx context.Context, knowledgeBaseID uint, fn func([]IndexedChunk) error) error
 	Ready() bool
 }
+
+// IndexedChunk 索引中已存在的分块
+type IndexedChunk struct {
+	ChunkID    uint
+	DocumentID uint
+	// ContentHash 索引中分块内容的哈希（ChunkHash），为空表示该存储不保存内容、无法判断是否过期
+	ContentHash string
+}
+
+// scanBatchSize 列举分块时每批的数量
+const scanBatchSize = 1000
+
+// ChunkHash 分块内容哈希，与knowledge_chunks.content_hash一致
+func ChunkHash(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,521 @@
+package knowledge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aihub/backend-go/internal/logger"
+	"go.uber.org/zap"
+)
+
+// BM25Options 内嵌BM25索引参数
+type BM25Options struct {
+	Dir            string        // 段文件目录，为空时只保存在内存中
+	Tokenizer      Tokenizer     // 默认使用不带词典的StandardTokenizer
+	FlushThreshold int           // 内存缓冲达到该分块数时落盘为新段，默认1000
+	FlushInterval  time.Duration // 定期落盘间隔，默认5s
+	MergeThreshold int           // 段数超过该值时后台合并，默认8
+	K1             float64       // 词频饱和参数，默认1.2
+	B              float64       // 长度归一化参数，默认0.75
+}
+
+const (
+	defaultBM25FlushThreshold = 1000
+	defaultBM25FlushInterval  = 5 * time.Second
+	defaultBM25MergeThreshold = 8
+	defaultBM25K1             = 1.2
+	defaultBM25B              = 0.75
+
+	// 高亮片段长度（字数），与ES的fragment_size一致
+	bm25FragmentSize = 150
+	bm25FragmentLead = 40
+)
+
+// BM25Indexer 纯Go实现的内嵌全文索引，无需外部搜索服务
+// 每个知识库一个倒排索引：新写入先进入内存缓冲，达到阈值或定期落盘为不可变段，
+// 删除以标记方式记录在清单中，段数过多时后台合并并清除已删除分块
+type BM25Indexer struct {
+	opts BM25Options
+
+	mu      sync.Mutex
+	indexes map[uint]*bm25Index
+
+	merges    chan *bm25Index
+	stop      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewBM25Indexer 创建BM25索引器并启动后台落盘与合并
+func NewBM25Indexer(opts BM25Options) (*BM25Indexer, error) {
+	if opts.Tokenizer == nil {
+		opts.Tokenizer = NewStandardTokenizer(nil)
+	}
+	if opts.FlushThreshold <= 0 {
+		opts.FlushThreshold = defaultBM25FlushThreshold
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaultBM25FlushInterval
+	}
+	if opts.MergeThreshold <= 1 {
+		opts.MergeThreshold = defaultBM25MergeThreshold
+	}
+	if opts.K1 <= 0 {
+		opts.K1 = defaultBM25K1
+	}
+	if opts.B < 0 || opts.B > 1 || opts.B == 0 {
+		opts.B = defaultBM25B
+	}
+	if opts.Dir != "" {
+		if err := ensureDir(opts.Dir); err != nil {
+			return nil, err
+		}
+	}
+
+	b := &BM25Indexer{
+		opts:    opts,
+		indexes: make(map[uint]*bm25Index),
+		merges:  make(chan *bm25Index, 16),
+		stop:    make(chan struct{}),
+	}
+	b.wg.Add(2)
+	go b.flushLoop()
+	go b.mergeLoop()
+	return b, nil
+}
+
+// IndexChunk 写入分块，已存在的同ID分块会被替换
+func (b *BM25Indexer) IndexChunk(ctx context.Context, chunk FulltextChunk) error {
+	idx, err := b.index(chunk.KnowledgeBaseID)
+	if err != nil {
+		return err
+	}
+
+	doc := &bm25Doc{
+		ChunkID:    chunk.ChunkID,
+		DocumentID: chunk.DocumentID,
+		Content:    chunk.Content,
+		Positions:  make(map[string][]int),
+	}
+	if len(chunk.Metadata) > 0 {
+		metadata, err := json.Marshal(chunk.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal chunk metadata: %w", err)
+		}
+		doc.Metadata = string(metadata)
+	}
+	for _, token := range b.opts.Tokenizer.Tokenize(chunk.Content) {
+		doc.Positions[token.Term] = append(doc.Positions[token.Term], token.Position)
+		doc.Length++
+	}
+
+	idx.mu.Lock()
+	idx.removeChunkLocked(chunk.ChunkID)
+	idx.addLocked(doc)
+	var flushErr error
+	if len(idx.buffer.docs) >= b.opts.FlushThreshold {
+		flushErr = idx.flushLocked()
+	}
+	segments := len(idx.segments)
+	idx.mu.Unlock()
+
+	if flushErr != nil {
+		return flushErr
+	}
+	if segments > b.opts.MergeThreshold {
+		b.scheduleMerge(idx)
+	}
+	return nil
+}
+
+// RemoveDocument 删除文档的全部分块
+func (b *BM25Indexer) RemoveDocument(ctx context.Context, knowledgeBaseID uint, documentID uint) error {
+	idx, err := b.index(knowledgeBaseID)
+	if err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for chunkID := range idx.documents[documentID] {
+		idx.removeChunkLocked(chunkID)
+	}
+	return nil
+}
+
+// Search 按BM25打分检索
+// 查询语法：空格分隔的词按"或"打分，"短语"必须按顺序连续出现，-排除包含该词或短语的分块
+func (b *BM25Indexer) Search(ctx context.Context, req FulltextSearchRequest) ([]SearchMatch, error) {
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, nil
+	}
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+
+	idx, err := b.index(req.KnowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	query := parseBM25Query(req.Query, b.opts.Tokenizer)
+	if len(query.terms) == 0 {
+		return nil, nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	total := len(idx.locations)
+	if total == 0 {
+		return nil, nil
+	}
+	avgLength := float64(idx.totalLength) / float64(total)
+
+	docs := make(map[uint]*bm25Doc)
+	scores := make(map[uint]float64)
+	for _, term := range query.terms {
+		matched := idx.postingsLocked(term)
+		if len(matched) == 0 {
+			continue
+		}
+		df := float64(len(matched))
+		idf := math.Log(1 + (float64(total)-df+0.5)/(df+0.5))
+		for _, doc := range matched {
+			tf := float64(len(doc.Positions[term]))
+			norm := 1 - b.opts.B + b.opts.B*float64(doc.Length)/avgLength
+			scores[doc.ChunkID] += idf * tf * (b.opts.K1 + 1) / (tf + b.opts.K1*norm)
+			docs[doc.ChunkID] = doc
+		}
+	}
+
+	matches := make([]SearchMatch, 0, len(scores))
+	for chunkID, score := range scores {
+		doc := docs[chunkID]
+		if !query.accepts(doc) {
+			continue
+		}
+		matches = append(matches, SearchMatch{
+			ChunkID:    doc.ChunkID,
+			DocumentID: doc.DocumentID,
+			Content:    doc.Content,
+			Score:      score,
+		})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ChunkID < matches[j].ChunkID
+	})
+	if len(matches) > req.Limit {
+		matches = matches[:req.Limit]
+	}
+
+	for i := range matches {
+		doc := docs[matches[i].ChunkID]
+		matches[i].Highlight = bm25Highlight(doc.Content, b.opts.Tokenizer, query.termSet)
+		if doc.Metadata != "" {
+			_ = json.Unmarshal([]byte(doc.Metadata), &matches[i].Metadata)
+		}
+	}
+	return matches, nil
+}
+
+// ScanChunks 按分块ID顺序分批列举索引中的分块
+func (b *BM25Indexer) ScanChunks(ctx context.Context, knowledgeBaseID uint, fn func([]IndexedChunk) error) error {
+	idx, err := b.index(knowledgeBaseID)
+	if err != nil {
+		return err
+	}
+
+	idx.mu.RLock()
+	chunks := make([]IndexedChunk, 0, len(idx.locations))
+	for chunkID, segment := range idx.locations {
+		if doc := segment.docs[chunkID]; doc != nil {
+			chunks = append(chunks, IndexedChunk{
+				ChunkID:     chunkID,
+				DocumentID:  doc.DocumentID,
+				ContentHash: ChunkHash(doc.Content),
+			})
+		}
+	}
+	idx.mu.RUnlock()
+
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].ChunkID < chunks[j].ChunkID })
+	for start := 0; start < len(chunks); start += scanBatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := start + scanBatchSize
+		if end > len(chunks) {
+			end = len(chunks)
+		}
+		if err := fn(chunks[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *BM25Indexer) Ready() bool {
+	select {
+	case <-b.stop:
+		return false
+	default:
+		return true
+	}
+}
+
+// Flush 将所有知识库的内存缓冲落盘
+func (b *BM25Indexer) Flush() error {
+	var firstErr error
+	for _, idx := range b.snapshotIndexes() {
+		idx.mu.Lock()
+		err := idx.flushLocked()
+		idx.mu.Unlock()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Close 停止后台任务并落盘
+func (b *BM25Indexer) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.stop)
+		b.wg.Wait()
+	})
+	return b.Flush()
+}
+
+// index 获取知识库的索引，首次访问时从磁盘加载
+func (b *BM25Indexer) index(knowledgeBaseID uint) (*bm25Index, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if idx, ok := b.indexes[knowledgeBaseID]; ok {
+		return idx, nil
+	}
+	idx, err := openBM25Index(b.opts.Dir, knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	b.indexes[knowledgeBaseID] = idx
+	return idx, nil
+}
+
+func (b *BM25Indexer) snapshotIndexes() []*bm25Index {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	indexes := make([]*bm25Index, 0, len(b.indexes))
+	for _, idx := range b.indexes {
+		indexes = append(indexes, idx)
+	}
+	return indexes
+}
+
+func (b *BM25Indexer) scheduleMerge(idx *bm25Index) {
+	select {
+	case b.merges <- idx:
+	default:
+		// 队列已满时由下一次落盘重新触发
+	}
+}
+
+func (b *BM25Indexer) flushLoop() {
+	defer b.wg.Done()
+	ticker := time.NewTicker(b.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			if err := b.Flush(); err != nil {
+				logger.Warn("Failed to flush bm25 index", zap.Error(err))
+			}
+			for _, idx := range b.snapshotIndexes() {
+				idx.mu.RLock()
+				segments := len(idx.segments)
+				idx.mu.RUnlock()
+				if segments > b.opts.MergeThreshold {
+					b.scheduleMerge(idx)
+				}
+			}
+		}
+	}
+}
+
+func (b *BM25Indexer) mergeLoop() {
+	defer b.wg.Done()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case idx := <-b.merges:
+			if err := idx.merge(); err != nil {
+				logger.Warn("Failed to merge bm25 segments",
+					zap.Uint("knowledge_base_id", idx.knowledgeBaseID), zap.Error(err))
+			}
+		}
+	}
+}
+
+// bm25Query 解析后的查询
+type bm25Query struct {
+	terms    []string            // 参与打分的去重词项
+	termSet  map[string]struct{} // 用于高亮
+	phrases  [][]Token           // 必须匹配的短语
+	excludes [][]Token           // 排除的词或短语
+}
+
+// parseBM25Query 解析查询，引号内为短语，-前缀为排除
+func parseBM25Query(raw string, tokenizer Tokenizer) *bm25Query {
+	q := &bm25Query{termSet: make(map[string]struct{})}
+	addTerms := func(tokens []Token) {
+		for _, token := range tokens {
+			if _, ok := q.termSet[token.Term]; ok {
+				continue
+			}
+			q.termSet[token.Term] = struct{}{}
+			q.terms = append(q.terms, token.Term)
+		}
+	}
+
+	for i := 0; i < len(raw); {
+		switch {
+		case raw[i] == ' ' || raw[i] == '\t' || raw[i] == '\n':
+			i++
+			continue
+		}
+
+		exclude := raw[i] == '-'
+		if exclude {
+			i++
+		}
+		var text string
+		phrase := i < len(raw) && raw[i] == '"'
+		if phrase {
+			end := strings.IndexByte(raw[i+1:], '"')
+			if end < 0 {
+				text, i = raw[i+1:], len(raw)
+			} else {
+				text, i = raw[i+1:i+1+end], i+end+2
+			}
+		} else {
+			end := strings.IndexAny(raw[i:], " \t\n")
+			if end < 0 {
+				end = len(raw) - i
+			}
+			text, i = raw[i:i+end], i+end
+		}
+
+		tokens := tokenizer.Tokenize(text)
+		if len(tokens) == 0 {
+			continue
+		}
+		switch {
+		case exclude:
+			q.excludes = append(q.excludes, tokens)
+		case phrase:
+			q.phrases = append(q.phrases, tokens)
+			addTerms(tokens)
+		default:
+			addTerms(tokens)
+		}
+	}
+	return q
+}
+
+// accepts 检查短语与排除条件
+func (q *bm25Query) accepts(doc *bm25Doc) bool {
+	for _, phrase := range q.phrases {
+		if !doc.matchPhrase(phrase) {
+			return false
+		}
+	}
+	for _, exclude := range q.excludes {
+		if doc.matchPhrase(exclude) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchPhrase 分词在文档中按相同的相对位置出现
+func (d *bm25Doc) matchPhrase(tokens []Token) bool {
+	first := tokens[0]
+	for _, pos := range d.Positions[first.Term] {
+		base := pos - first.Position
+		matched := true
+		for _, token := range tokens[1:] {
+			positions := d.Positions[token.Term]
+			want := base + token.Position
+			i := sort.SearchInts(positions, want)
+			if i == len(positions) || positions[i] != want {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// bm25Highlight 截取第一个命中附近的片段，命中的分词用<mark>标记
+func bm25Highlight(content string, tokenizer Tokenizer, terms map[string]struct{}) string {
+	type span struct{ start, end int }
+	var spans []span
+	for _, token := range tokenizer.Tokenize(content) {
+		if _, ok := terms[token.Term]; !ok {
+			continue
+		}
+		// 二元分词相互重叠，合并为连续的标记
+		if n := len(spans); n > 0 && token.Start <= spans[n-1].end {
+			if token.End > spans[n-1].end {
+				spans[n-1].end = token.End
+			}
+			continue
+		}
+		spans = append(spans, span{token.Start, token.End})
+	}
+	if len(spans) == 0 {
+		return ""
+	}
+
+	start := spans[0].start
+	for n := 0; n < bm25FragmentLead && start > 0; n++ {
+		_, size := utf8.DecodeLastRuneInString(content[:start])
+		start -= size
+	}
+	end := start
+	for n := 0; n < bm25FragmentSize && end < len(content); n++ {
+		_, size := utf8.DecodeRuneInString(content[end:])
+		end += size
+	}
+
+	var sb strings.Builder
+	cursor := start
+	for _, s := range spans {
+		if s.start >= end {
+			break
+		}
+		sb.WriteString(content[cursor:s.start])
+		sb.WriteString("<mark>")
+		sb.WriteString(content[s.start:min(s.end, end)])
+		sb.WriteString("</mark>")
+		cursor = min(s.end, end)
+	}
+	sb.WriteString(content[cursor:end])
+	return sb.String()
+}
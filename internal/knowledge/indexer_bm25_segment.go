@@ -0,0 +1,375 @@
+package knowledge
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const bm25ManifestFile = "manifest.json"
+
+// bm25Doc 索引中的一个分块，段文件以gob格式保存
+type bm25Doc struct {
+	ChunkID    uint
+	DocumentID uint
+	Content    string
+	Metadata   string // JSON
+	Length     int
+	Positions  map[string][]int // 词项 -> 递增的位置列表
+}
+
+// bm25Segment 段：内存缓冲或已落盘的不可变段
+type bm25Segment struct {
+	name     string // 缓冲为空
+	docs     map[uint]*bm25Doc
+	postings map[string]map[uint]struct{}
+	deleted  map[uint]struct{}
+}
+
+func newBM25Segment(name string) *bm25Segment {
+	return &bm25Segment{
+		name:     name,
+		docs:     make(map[uint]*bm25Doc),
+		postings: make(map[string]map[uint]struct{}),
+		deleted:  make(map[uint]struct{}),
+	}
+}
+
+func (s *bm25Segment) add(doc *bm25Doc) {
+	s.docs[doc.ChunkID] = doc
+	for term := range doc.Positions {
+		ids, ok := s.postings[term]
+		if !ok {
+			ids = make(map[uint]struct{})
+			s.postings[term] = ids
+		}
+		ids[doc.ChunkID] = struct{}{}
+	}
+}
+
+// remove 从缓冲中直接删除，只用于尚未落盘的段
+func (s *bm25Segment) remove(chunkID uint) {
+	doc, ok := s.docs[chunkID]
+	if !ok {
+		return
+	}
+	delete(s.docs, chunkID)
+	for term := range doc.Positions {
+		delete(s.postings[term], chunkID)
+		if len(s.postings[term]) == 0 {
+			delete(s.postings, term)
+		}
+	}
+}
+
+// bm25Manifest 知识库索引清单，记录段列表与各段的删除标记
+type bm25Manifest struct {
+	NextGen  int                   `json:"next_gen"`
+	Segments []bm25ManifestSegment `json:"segments"`
+}
+
+type bm25ManifestSegment struct {
+	Name    string `json:"name"`
+	Deleted []uint `json:"deleted,omitempty"`
+}
+
+// bm25Index 单个知识库的倒排索引
+type bm25Index struct {
+	knowledgeBaseID uint
+	dir             string // 为空时不落盘
+
+	mu          sync.RWMutex
+	buffer      *bm25Segment
+	segments    []*bm25Segment
+	locations   map[uint]*bm25Segment      // 分块ID -> 当前所在段
+	documents   map[uint]map[uint]struct{} // 文档ID -> 分块ID
+	totalLength int
+	nextGen     int
+	dirty       bool // 已落盘段的删除标记尚未写入清单
+	merging     bool
+}
+
+// openBM25Index 打开知识库索引，baseDir为空时创建内存索引
+func openBM25Index(baseDir string, knowledgeBaseID uint) (*bm25Index, error) {
+	idx := &bm25Index{
+		knowledgeBaseID: knowledgeBaseID,
+		buffer:          newBM25Segment(""),
+		locations:       make(map[uint]*bm25Segment),
+		documents:       make(map[uint]map[uint]struct{}),
+		nextGen:         1,
+	}
+	if baseDir == "" {
+		return idx, nil
+	}
+
+	idx.dir = filepath.Join(baseDir, fmt.Sprintf("kb_%d", knowledgeBaseID))
+	data, err := os.ReadFile(filepath.Join(idx.dir, bm25ManifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bm25 manifest: %w", err)
+	}
+
+	var manifest bm25Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse bm25 manifest: %w", err)
+	}
+	idx.nextGen = manifest.NextGen
+
+	live := make(map[string]struct{}, len(manifest.Segments))
+	for _, entry := range manifest.Segments {
+		seg, err := readBM25Segment(idx.dir, entry.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, chunkID := range entry.Deleted {
+			seg.deleted[chunkID] = struct{}{}
+		}
+		idx.segments = append(idx.segments, seg)
+		live[entry.Name] = struct{}{}
+
+		for chunkID, doc := range seg.docs {
+			if _, deleted := seg.deleted[chunkID]; !deleted {
+				idx.track(seg, doc)
+			}
+		}
+	}
+
+	// 清理合并中断遗留的段文件
+	files, _ := filepath.Glob(filepath.Join(idx.dir, "seg_*.gob"))
+	for _, file := range files {
+		if _, ok := live[strings.TrimSuffix(filepath.Base(file), ".gob")]; !ok {
+			_ = os.Remove(file)
+		}
+	}
+	return idx, nil
+}
+
+// track 登记分块的位置与统计
+func (idx *bm25Index) track(seg *bm25Segment, doc *bm25Doc) {
+	idx.locations[doc.ChunkID] = seg
+	chunks, ok := idx.documents[doc.DocumentID]
+	if !ok {
+		chunks = make(map[uint]struct{})
+		idx.documents[doc.DocumentID] = chunks
+	}
+	chunks[doc.ChunkID] = struct{}{}
+	idx.totalLength += doc.Length
+}
+
+func (idx *bm25Index) addLocked(doc *bm25Doc) {
+	idx.buffer.add(doc)
+	idx.track(idx.buffer, doc)
+}
+
+// removeChunkLocked 删除分块：缓冲中直接删除，已落盘的段记录删除标记
+func (idx *bm25Index) removeChunkLocked(chunkID uint) {
+	seg, ok := idx.locations[chunkID]
+	if !ok {
+		return
+	}
+	doc := seg.docs[chunkID]
+	if seg == idx.buffer {
+		seg.remove(chunkID)
+	} else {
+		seg.deleted[chunkID] = struct{}{}
+		idx.dirty = true
+	}
+
+	delete(idx.locations, chunkID)
+	if chunks := idx.documents[doc.DocumentID]; chunks != nil {
+		delete(chunks, chunkID)
+		if len(chunks) == 0 {
+			delete(idx.documents, doc.DocumentID)
+		}
+	}
+	idx.totalLength -= doc.Length
+}
+
+// postingsLocked 返回包含词项的有效分块
+func (idx *bm25Index) postingsLocked(term string) []*bm25Doc {
+	var docs []*bm25Doc
+	for _, seg := range append([]*bm25Segment{idx.buffer}, idx.segments...) {
+		for chunkID := range seg.postings[term] {
+			if idx.locations[chunkID] == seg {
+				docs = append(docs, seg.docs[chunkID])
+			}
+		}
+	}
+	return docs
+}
+
+// flushLocked 将缓冲落盘为新段并更新清单
+func (idx *bm25Index) flushLocked() error {
+	if len(idx.buffer.docs) == 0 {
+		if idx.dirty {
+			return idx.writeManifestLocked()
+		}
+		return nil
+	}
+
+	// 缓冲本身转为新段，locations中的指针无需更新
+	seg := idx.buffer
+	seg.name = fmt.Sprintf("seg_%06d", idx.nextGen)
+	if err := writeBM25Segment(idx.dir, seg); err != nil {
+		seg.name = ""
+		return err
+	}
+	idx.nextGen++
+	idx.segments = append(idx.segments, seg)
+	idx.buffer = newBM25Segment("")
+	return idx.writeManifestLocked()
+}
+
+// merge 合并全部已落盘的段，去除已删除的分块
+// 段文件写入期间不持有锁，期间发生的删除在替换时重新标记
+func (idx *bm25Index) merge() error {
+	idx.mu.Lock()
+	if idx.merging || len(idx.segments) < 2 {
+		idx.mu.Unlock()
+		return nil
+	}
+	idx.merging = true
+	sources := append([]*bm25Segment(nil), idx.segments...)
+	merged := newBM25Segment(fmt.Sprintf("seg_%06d", idx.nextGen))
+	idx.nextGen++
+	origin := make(map[uint]*bm25Segment)
+	for _, seg := range sources {
+		for chunkID, doc := range seg.docs {
+			if idx.locations[chunkID] == seg {
+				merged.add(doc)
+				origin[chunkID] = seg
+			}
+		}
+	}
+	idx.mu.Unlock()
+
+	err := writeBM25Segment(idx.dir, merged)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.merging = false
+	if err != nil {
+		return err
+	}
+
+	for chunkID, seg := range origin {
+		if idx.locations[chunkID] == seg {
+			idx.locations[chunkID] = merged
+		} else {
+			merged.deleted[chunkID] = struct{}{}
+		}
+	}
+	remaining := idx.segments[len(sources):]
+	idx.segments = append([]*bm25Segment{merged}, remaining...)
+	if err := idx.writeManifestLocked(); err != nil {
+		return err
+	}
+
+	if idx.dir != "" {
+		for _, seg := range sources {
+			_ = os.Remove(filepath.Join(idx.dir, seg.name+".gob"))
+		}
+	}
+	return nil
+}
+
+func (idx *bm25Index) writeManifestLocked() error {
+	idx.dirty = false
+	if idx.dir == "" {
+		return nil
+	}
+
+	manifest := bm25Manifest{NextGen: idx.nextGen}
+	for _, seg := range idx.segments {
+		entry := bm25ManifestSegment{Name: seg.name}
+		for chunkID := range seg.deleted {
+			entry.Deleted = append(entry.Deleted, chunkID)
+		}
+		sort.Slice(entry.Deleted, func(i, j int) bool { return entry.Deleted[i] < entry.Deleted[j] })
+		manifest.Segments = append(manifest.Segments, entry)
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(idx.dir, bm25ManifestFile), func(f *os.File) error {
+		_, err := f.Write(data)
+		return err
+	}); err != nil {
+		idx.dirty = true
+		return err
+	}
+	return nil
+}
+
+func writeBM25Segment(dir string, seg *bm25Segment) error {
+	if dir == "" {
+		return nil
+	}
+	if err := ensureDir(dir); err != nil {
+		return err
+	}
+
+	docs := make([]*bm25Doc, 0, len(seg.docs))
+	for _, doc := range seg.docs {
+		docs = append(docs, doc)
+	}
+	return writeFileAtomic(filepath.Join(dir, seg.name+".gob"), func(f *os.File) error {
+		return gob.NewEncoder(f).Encode(docs)
+	})
+}
+
+func readBM25Segment(dir, name string) (*bm25Segment, error) {
+	file, err := os.Open(filepath.Join(dir, name+".gob"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bm25 segment %s: %w", name, err)
+	}
+	defer file.Close()
+
+	var docs []*bm25Doc
+	if err := gob.NewDecoder(file).Decode(&docs); err != nil {
+		return nil, fmt.Errorf("failed to decode bm25 segment %s: %w", name, err)
+	}
+	seg := newBM25Segment(name)
+	for _, doc := range docs {
+		seg.add(doc)
+	}
+	return seg, nil
+}
+
+// writeFileAtomic 先写临时文件再重命名，避免进程中断留下不完整的文件
+func writeFileAtomic(path string, write func(f *os.File) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func ensureDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package knowledge
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStandardTokenizer(t *testing.T) {
+	tokenizer := NewStandardTokenizer([]string{"北京大学"})
+
+	var terms []string
+	var positions []int
+	for _, token := range tokenizer.Tokenize("Hello, 北京大学World v2") {
+		terms = append(terms, token.Term)
+		positions = append(positions, token.Position)
+	}
+	assert.Equal(t, []string{"hello", "北京", "北京大学", "京大", "大学", "world", "v2"}, terms)
+	assert.Equal(t, []int{0, 1, 1, 2, 3, 4, 5}, positions)
+
+	tokens := tokenizer.Tokenize("的 API")
+	require.Len(t, tokens, 2)
+	assert.Equal(t, "的", tokens[0].Term)
+	assert.Equal(t, "API", "的 API"[tokens[1].Start:tokens[1].End])
+}
+
+func newTestBM25Indexer(t *testing.T, opts BM25Options) *BM25Indexer {
+	t.Helper()
+	indexer, err := NewBM25Indexer(opts)
+	require.NoError(t, err)
+	t.Cleanup(func() { indexer.Close() })
+	return indexer
+}
+
+func indexTestChunks(t *testing.T, indexer *BM25Indexer, contents map[uint]string) {
+	t.Helper()
+	for id, content := range contents {
+		require.NoError(t, indexer.IndexChunk(context.Background(), FulltextChunk{
+			ChunkID:         id,
+			DocumentID:      id / 10,
+			KnowledgeBaseID: 1,
+			Content:         content,
+			Metadata:        map[string]interface{}{"chunk": float64(id)},
+		}))
+	}
+}
+
+func searchChunkIDs(t *testing.T, indexer *BM25Indexer, query string) []uint {
+	t.Helper()
+	matches, err := indexer.Search(context.Background(), FulltextSearchRequest{KnowledgeBaseID: 1, Query: query})
+	require.NoError(t, err)
+	ids := make([]uint, len(matches))
+	for i, match := range matches {
+		ids[i] = match.ChunkID
+	}
+	return ids
+}
+
+func TestBM25Indexer_Ranking(t *testing.T) {
+	indexer := newTestBM25Indexer(t, BM25Options{})
+	indexTestChunks(t, indexer, map[uint]string{
+		10: "postgres supports full text search",
+		11: "postgres postgres postgres replication",
+		20: "elasticsearch is a search engine",
+	})
+
+	// 词频高的排前面，不相关的分块不返回
+	assert.Equal(t, []uint{11, 10}, searchChunkIDs(t, indexer, "Postgres"))
+
+	// 稀有词权重更高
+	assert.Equal(t, []uint{20, 10}, searchChunkIDs(t, indexer, "engine search"))
+
+	matches, err := indexer.Search(context.Background(), FulltextSearchRequest{KnowledgeBaseID: 1, Query: "replication"})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "postgres postgres postgres <mark>replication</mark>", matches[0].Highlight)
+	assert.Equal(t, float64(11), matches[0].Metadata["chunk"])
+	assert.Equal(t, uint(1), matches[0].DocumentID)
+
+	// 其他知识库互不可见
+	matches, err = indexer.Search(context.Background(), FulltextSearchRequest{KnowledgeBaseID: 2, Query: "postgres"})
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestBM25Indexer_PhraseAndExclusion(t *testing.T) {
+	indexer := newTestBM25Indexer(t, BM25Options{})
+	indexTestChunks(t, indexer, map[uint]string{
+		10: "杭州天气晴朗",
+		20: "天气预报：杭州明天下雨",
+		30: "full text search engine",
+		40: "search the full text",
+	})
+
+	assert.ElementsMatch(t, []uint{10, 20}, searchChunkIDs(t, indexer, "杭州天气"))
+	assert.Equal(t, []uint{10}, searchChunkIDs(t, indexer, `"杭州天气"`))
+	assert.Equal(t, []uint{10}, searchChunkIDs(t, indexer, "杭州 -下雨"))
+	assert.Equal(t, []uint{30}, searchChunkIDs(t, indexer, `"text search"`))
+	assert.Equal(t, []uint{40}, searchChunkIDs(t, indexer, `search -"search engine"`))
+
+	matches, err := indexer.Search(context.Background(), FulltextSearchRequest{KnowledgeBaseID: 1, Query: `"杭州天气"`})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "<mark>杭州天气</mark>晴朗", matches[0].Highlight)
+}
+
+func TestBM25Indexer_PersistAndMerge(t *testing.T) {
+	dir := t.TempDir()
+	indexer, err := NewBM25Indexer(BM25Options{Dir: dir, FlushThreshold: 1, MergeThreshold: 100})
+	require.NoError(t, err)
+
+	indexTestChunks(t, indexer, map[uint]string{
+		10: "alpha beta",
+		11: "alpha gamma",
+		20: "alpha delta",
+		30: "alpha epsilon",
+	})
+	// 更新分块与删除文档只写删除标记
+	require.NoError(t, indexer.IndexChunk(context.Background(), FulltextChunk{
+		ChunkID: 30, DocumentID: 3, KnowledgeBaseID: 1, Content: "omega",
+	}))
+	require.NoError(t, indexer.RemoveDocument(context.Background(), 1, 1))
+	require.NoError(t, indexer.Close())
+
+	reopened := newTestBM25Indexer(t, BM25Options{Dir: dir, MergeThreshold: 100})
+	assert.Equal(t, []uint{20}, searchChunkIDs(t, reopened, "alpha"))
+	assert.Equal(t, []uint{30}, searchChunkIDs(t, reopened, "omega"))
+
+	idx, err := reopened.index(1)
+	require.NoError(t, err)
+	assert.Len(t, idx.segments, 5)
+	require.NoError(t, idx.merge())
+	assert.Len(t, idx.segments, 1)
+	assert.Len(t, idx.segments[0].docs, 2)
+
+	files, err := filepath.Glob(filepath.Join(dir, "kb_1", "seg_*.gob"))
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// 合并后的段可以重新加载
+	require.NoError(t, reopened.Close())
+	again := newTestBM25Indexer(t, BM25Options{Dir: dir})
+	assert.Equal(t, []uint{20}, searchChunkIDs(t, again, "alpha"))
+	assert.Equal(t, []uint{30}, searchChunkIDs(t, again, "omega"))
+}
@@ -5,47 +5,115 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"unicode"
 
 	"gorm.io/gorm"
 )
 
-// DatabaseIndexer 基于PostgreSQL的全文查询退化实现
+// DatabaseIndexer 基于PostgreSQL全文检索的索引实现
+// 依赖迁移000004创建的search_vector列（GIN索引）与pg_trgm扩展：
+// tsvector使用simple配置，中日韩文本额外写入二元分词（迁移000016的触发器对所有写入生效），
+// 检索无结果时退化为pg_trgm相似度匹配
 type DatabaseIndexer struct {
-	db *gorm.DB
+	db       *gorm.DB
+	lexicons LexiconProvider
 }
 
 func NewDatabaseIndexer(db *gorm.DB) FulltextIndexer {
 	return &DatabaseIndexer{db: db}
 }
 
+// SetLexiconProvider 设置知识库词库，检索时按同义词、停用词和自定义词扩展查询
+func (d *DatabaseIndexer) SetLexiconProvider(provider LexiconProvider) {
+	d.lexicons = provider
+}
+
+// tsHeadlineOptions 高亮片段参数，与ES高亮标签保持一致
+const tsHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxFragments=1, MaxWords=35, MinWords=15"
+
+// IndexChunk 重写分块的search_vector（原文 + 中日韩二元分词，文件名权重更高）
+// 分块内容本身已保存在knowledge_chunks表中
 func (d *DatabaseIndexer) IndexChunk(ctx context.Context, chunk FulltextChunk) error {
-	// 数据已经保存在knowledge_chunks表中，不需要额外处理
-	return nil
+	return d.db.WithContext(ctx).Exec(`
+		UPDATE knowledge_chunks
+		SET search_vector = setweight(to_tsvector('simple', ?), 'A') || setweight(to_tsvector('simple', ?), 'B')
+		WHERE chunk_id = ?`,
+		fulltextDocument(chunk.FileName), fulltextDocument(chunk.Content), chunk.ChunkID).Error
 }
 
+// RemoveDocument 将文档的分块移出全文索引（分块数据保留，由文档生命周期管理）
 func (d *DatabaseIndexer) RemoveDocument(ctx context.Context, knowledgeBaseID uint, documentID uint) error {
-	return d.db.WithContext(ctx).Table("knowledge_chunks").Where("document_id = ?", documentID).Delete(nil).Error
+	return d.db.WithContext(ctx).Exec(
+		"UPDATE knowledge_chunks SET search_vector = NULL WHERE document_id = ?", documentID).Error
+}
+
+// ScanChunks 列举已写入search_vector的分块，不区分分块是否激活，内容与分块同行保存，不返回哈希
+func (d *DatabaseIndexer) ScanChunks(ctx context.Context, knowledgeBaseID uint, fn func([]IndexedChunk) error) error {
+	return scanChunkRows(ctx, d.db, knowledgeBaseID, "c.search_vector IS NOT NULL", fn)
+}
+
+// scanChunkRows 按分块ID分批列举knowledge_chunks中满足条件的分块
+func scanChunkRows(ctx context.Context, db *gorm.DB, knowledgeBaseID uint, condition string, fn func([]IndexedChunk) error) error {
+	var lastID uint
+	for {
+		var batch []IndexedChunk
+		err := db.WithContext(ctx).
+			Table("knowledge_chunks AS c").
+			Select("c.chunk_id, c.document_id").
+			Joins("JOIN knowledge_documents d ON c.document_id = d.document_id").
+			Where("d.knowledge_base_id = ? AND c.chunk_id > ?", knowledgeBaseID, lastID).
+			Where(condition).
+			Order("c.chunk_id").
+			Limit(scanBatchSize).
+			Scan(&batch).Error
+		if err != nil {
+			return fmt.Errorf("failed to scan chunks: %w", err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < scanBatchSize {
+			return nil
+		}
+		lastID = batch[len(batch)-1].ChunkID
+	}
 }
 
 func (d *DatabaseIndexer) Search(ctx context.Context, req FulltextSearchRequest) ([]SearchMatch, error) {
 	if strings.TrimSpace(req.Query) == "" {
 		return nil, nil
 	}
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
 
+	// websearch_to_tsquery支持"短语"、or、-排除等语法；ts_rank_cd归一化到0-1
 	var chunks []KnowledgeChunkRecord
-	err := d.db.WithContext(ctx).
-		Table("knowledge_chunks").
-		Select("knowledge_chunks.chunk_id, knowledge_chunks.document_id, knowledge_chunks.content, knowledge_chunks.metadata").
-		Joins("JOIN knowledge_documents ON knowledge_chunks.document_id = knowledge_documents.document_id").
-		Where("knowledge_documents.knowledge_base_id = ?", req.KnowledgeBaseID).
-		Where("knowledge_chunks.content ILIKE ?", "%"+req.Query+"%").
-		Order("knowledge_chunks.chunk_id ASC").
-		Limit(req.Limit).
-		Find(&chunks).Error
+	err := d.db.WithContext(ctx).Raw(`
+		SELECT c.chunk_id, c.document_id, c.content, c.metadata,
+			ts_rank_cd(c.search_vector, q.query, 32) AS score,
+			ts_headline('simple', c.content, q.query, ?) AS highlight
+		FROM knowledge_chunks c
+		JOIN knowledge_documents d ON c.document_id = d.document_id,
+			websearch_to_tsquery('simple', ?) AS q(query)
+		WHERE d.knowledge_base_id = ? AND c.is_active IS NOT FALSE AND c.search_vector @@ q.query
+		ORDER BY score DESC, c.chunk_id ASC
+		LIMIT ?`,
+		tsHeadlineOptions, d.tsQuery(ctx, req.KnowledgeBaseID, req.Query), req.KnowledgeBaseID, req.Limit).
+		Scan(&chunks).Error
 	if err != nil {
 		return nil, fmt.Errorf("database search failed: %w", err)
 	}
 
+	if len(chunks) == 0 {
+		if chunks, err = d.searchTrigram(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+
 	matches := make([]SearchMatch, 0, len(chunks))
 	for _, chunk := range chunks {
 		var metadata map[string]interface{}
@@ -53,28 +121,229 @@ func (d *DatabaseIndexer) Search(ctx context.Context, req FulltextSearchRequest)
 			_ = json.Unmarshal([]byte(chunk.MetadataJSON), &metadata)
 		}
 
+		// 中日韩文本ts_headline无法定位二元分词，退化为子串高亮
+		highlight := chunk.Highlight
+		if !strings.Contains(highlight, "<mark>") {
+			highlight = buildHighlight(chunk.Content, req.Query)
+		}
+
 		matches = append(matches, SearchMatch{
 			ChunkID:    chunk.ChunkID,
 			DocumentID: chunk.DocumentID,
 			Content:    chunk.Content,
-			Score:      0.6,
+			Score:      chunk.Score,
 			Metadata:   metadata,
-			Highlight:  buildHighlight(chunk.Content, req.Query),
+			Highlight:  highlight,
 		})
 	}
 	return matches, nil
 }
 
+// searchTrigram pg_trgm退化检索：子串命中优先，其余按词相似度排序
+func (d *DatabaseIndexer) searchTrigram(ctx context.Context, req FulltextSearchRequest) ([]KnowledgeChunkRecord, error) {
+	pattern := "%" + escapeLike(req.Query) + "%"
+
+	var chunks []KnowledgeChunkRecord
+	err := d.db.WithContext(ctx).Raw(`
+		SELECT c.chunk_id, c.document_id, c.content, c.metadata,
+			CASE WHEN c.content ILIKE ? THEN 0.5 + 0.5 * word_similarity(?, c.content)
+				ELSE 0.5 * word_similarity(?, c.content) END AS score
+		FROM knowledge_chunks c
+		JOIN knowledge_documents d ON c.document_id = d.document_id
+		WHERE d.knowledge_base_id = ? AND c.is_active IS NOT FALSE AND c.search_vector IS NOT NULL
+			AND (c.content ILIKE ? OR ? <% c.content)
+		ORDER BY score DESC, c.chunk_id ASC
+		LIMIT ?`,
+		pattern, req.Query, req.Query, req.KnowledgeBaseID, pattern, req.Query, req.Limit).
+		Scan(&chunks).Error
+	if err != nil {
+		return nil, fmt.Errorf("database trigram search failed: %w", err)
+	}
+	return chunks, nil
+}
+
+// tsQuery 生成websearch_to_tsquery的查询文本：先按知识库词库改写，再做中日韩二元分词
+func (d *DatabaseIndexer) tsQuery(ctx context.Context, knowledgeBaseID uint, query string) string {
+	if d.lexicons != nil {
+		if lexicon, err := d.lexicons.Lexicon(ctx, knowledgeBaseID); err == nil {
+			query = lexicon.FulltextQuery(query)
+		}
+	}
+	return expandCJKQuery(query)
+}
+
+// dbFacetColumns 分面字段对应的取值表达式；标签展开自文档元数据中的tags数组
+var dbFacetColumns = map[string]struct{ expr, join string }{
+	FacetDocument: {expr: "c.document_id::text"},
+	FacetFileType: {expr: `lower(substring(d.file_path from '\.([^./]+)$'))`},
+	FacetSource:   {expr: "d.source"},
+	FacetTag: {expr: "tag.value", join: `CROSS JOIN LATERAL jsonb_array_elements_text(
+			CASE WHEN jsonb_typeof(d.metadata::jsonb->'tags') = 'array' THEN d.metadata::jsonb->'tags' ELSE '[]'::jsonb END) AS tag(value)`},
+}
+
+// Facets 统计全文命中的分块数与分面取值，命中数为精确值
+// 只统计tsvector命中，pg_trgm退化检索的结果不计入
+func (d *DatabaseIndexer) Facets(ctx context.Context, req FacetRequest) (*FacetResult, error) {
+	result := &FacetResult{TotalRelation: TotalRelationEqual, Facets: make(map[string][]FacetCount)}
+	if strings.TrimSpace(req.Query) == "" {
+		return result, nil
+	}
+	query := d.tsQuery(ctx, req.KnowledgeBaseID, req.Query)
+
+	err := d.db.WithContext(ctx).Raw(`
+		SELECT COUNT(*)
+		FROM knowledge_chunks c
+		JOIN knowledge_documents d ON c.document_id = d.document_id,
+			websearch_to_tsquery('simple', ?) AS q(query)
+		WHERE d.knowledge_base_id = ? AND c.is_active IS NOT FALSE AND c.search_vector @@ q.query`,
+		query, req.KnowledgeBaseID).
+		Scan(&result.Total).Error
+	if err != nil {
+		return nil, fmt.Errorf("database facet count failed: %w", err)
+	}
+	if result.Total == 0 {
+		return result, nil
+	}
+
+	for _, field := range req.Fields {
+		column, ok := dbFacetColumns[field]
+		if !ok {
+			continue
+		}
+		var counts []FacetCount
+		err := d.db.WithContext(ctx).Raw(`
+			SELECT `+column.expr+` AS value, COUNT(*) AS count
+			FROM knowledge_chunks c
+			JOIN knowledge_documents d ON c.document_id = d.document_id
+			`+column.join+`,
+				websearch_to_tsquery('simple', ?) AS q(query)
+			WHERE d.knowledge_base_id = ? AND c.is_active IS NOT FALSE AND c.search_vector @@ q.query
+				AND COALESCE(`+column.expr+`, '') <> ''
+			GROUP BY 1
+			ORDER BY count DESC, value ASC
+			LIMIT ?`,
+			query, req.KnowledgeBaseID, req.size()).
+			Scan(&counts).Error
+		if err != nil {
+			return nil, fmt.Errorf("database facet %s failed: %w", field, err)
+		}
+		result.Facets[field] = counts
+	}
+	return result, nil
+}
+
 func (d *DatabaseIndexer) Ready() bool {
 	return d.db != nil
 }
 
+// cjkRanges 中日韩文字范围（假名、汉字、谚文），与迁移000016中knowledge_cjk_bigrams的正则保持一致，
+// 否则查询的二元分词与触发器写入的分词对不上
+var cjkRanges = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x3040, Hi: 0x30ff, Stride: 1},
+		{Lo: 0x3400, Hi: 0x4dbf, Stride: 1},
+		{Lo: 0x4e00, Hi: 0x9fff, Stride: 1},
+		{Lo: 0xac00, Hi: 0xd7af, Stride: 1},
+		{Lo: 0xf900, Hi: 0xfaff, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x20000, Hi: 0x2ffff, Stride: 1},
+	},
+}
+
+// isCJK 是否为中日韩文字（汉字、假名、谚文）
+func isCJK(r rune) bool {
+	return unicode.Is(cjkRanges, r)
+}
+
+// cjkBigrams 将文本中连续的中日韩字符切分为二元分词，返回空格分隔的分词串
+func cjkBigrams(text string) string {
+	var tokens []string
+	var run []rune
+	flush := func() {
+		if len(run) == 1 {
+			tokens = append(tokens, string(run))
+		}
+		for i := 0; i+1 < len(run); i++ {
+			tokens = append(tokens, string(run[i:i+2]))
+		}
+		run = run[:0]
+	}
+	for _, r := range text {
+		if isCJK(r) {
+			run = append(run, r)
+			continue
+		}
+		flush()
+	}
+	flush()
+	return strings.Join(tokens, " ")
+}
+
+// fulltextDocument 生成写入tsvector的文本：原文 + 中日韩二元分词
+func fulltextDocument(text string) string {
+	bigrams := cjkBigrams(text)
+	if bigrams == "" {
+		return text
+	}
+	return text + " " + bigrams
+}
+
+// expandCJKQuery 将查询中的中日韩片段替换为二元分词，保留websearch语法（"短语"、or、-排除）
+// 引号内的片段替换后仍为短语；排除多个二元分词时加引号整体排除
+func expandCJKQuery(query string) string {
+	var b strings.Builder
+	var run []rune
+	var last rune
+	inQuote := false
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		bigrams := cjkBigrams(string(run))
+		if unicode.IsLetter(last) || unicode.IsDigit(last) {
+			b.WriteByte(' ')
+		}
+		if last == '-' && !inQuote && strings.Contains(bigrams, " ") {
+			bigrams = `"` + bigrams + `"`
+		}
+		b.WriteString(bigrams)
+		run = run[:0]
+		last = ' '
+	}
+	for _, r := range query {
+		if isCJK(r) {
+			run = append(run, r)
+			continue
+		}
+		wasRun := len(run) > 0
+		flush()
+		if wasRun && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteByte(' ')
+		}
+		if r == '"' {
+			inQuote = !inQuote
+		}
+		b.WriteRune(r)
+		last = r
+	}
+	flush()
+	return b.String()
+}
+
+// escapeLike 转义LIKE模式中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 // KnowledgeChunkRecord 是数据库查询的最小结构，避免引用模型产生循环
 type KnowledgeChunkRecord struct {
 	ChunkID      uint
 	DocumentID   uint
 	Content      string
-	MetadataJSON string `gorm:"column:metadata"`
+	MetadataJSON string  `gorm:"column:metadata"`
+	Score        float64 `gorm:"column:score"`
+	Highlight    string  `gorm:"column:highlight"`
 }
 
 func buildHighlight(content, query string) string {
@@ -0,0 +1,93 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newPostgresTestDB 连接TEST_DB_URL指定的PostgreSQL，在独立schema中建表，测试结束后删除
+// 未设置TEST_DB_URL时跳过
+func newPostgresTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DB_URL")
+	if dsn == "" {
+		t.Skip("Skipping PostgreSQL test: TEST_DB_URL not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// search_path按连接生效，只使用一个连接
+	sqlDB.SetMaxOpenConns(1)
+
+	schema := fmt.Sprintf("knowledge_test_%d", time.Now().UnixNano())
+	require.NoError(t, db.Exec("CREATE SCHEMA "+schema).Error)
+	t.Cleanup(func() {
+		db.Exec("DROP SCHEMA " + schema + " CASCADE")
+		sqlDB.Close()
+	})
+	require.NoError(t, db.Exec("SET search_path TO "+schema+", public").Error)
+
+	require.NoError(t, db.Exec(`CREATE TABLE knowledge_documents (
+		document_id SERIAL PRIMARY KEY, knowledge_base_id INTEGER NOT NULL,
+		title TEXT, file_path TEXT, source TEXT, metadata TEXT)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE knowledge_chunks (
+		chunk_id SERIAL PRIMARY KEY, document_id INTEGER NOT NULL, content TEXT, chunk_index INTEGER DEFAULT 0,
+		metadata TEXT, vector_id TEXT, embedding TEXT, embedding_code BYTEA, is_active BOOLEAN DEFAULT TRUE,
+		search_vector tsvector)`).Error)
+	return db
+}
+
+// applyMigration 执行migrations目录下的迁移文件
+func applyMigration(t *testing.T, db *gorm.DB, name string) {
+	t.Helper()
+	script, err := os.ReadFile(filepath.Join("..", "..", "migrations", name))
+	require.NoError(t, err)
+	require.NoError(t, db.Exec(string(script)).Error, name)
+}
+
+func TestDatabaseIndexer_PostgresCJKQuery(t *testing.T) {
+	db := newPostgresTestDB(t)
+	applyMigration(t, db, "000004_knowledge_fulltext.up.sql")
+	applyMigration(t, db, "000016_knowledge_fulltext_cjk.up.sql")
+	ctx := context.Background()
+
+	require.NoError(t, db.Exec(`INSERT INTO knowledge_documents (document_id, knowledge_base_id, title) VALUES (1, 7, '季度报告')`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO knowledge_chunks (chunk_id, document_id, content) VALUES
+		(1, 1, '杭州明天天气晴朗，适合出行'), (2, 1, 'コーヒーの淹れ方'), (3, 1, 'unrelated english text')`).Error)
+
+	indexer := NewDatabaseIndexer(db)
+	count := func(query string) int64 {
+		// 分面只统计tsvector命中，不含pg_trgm退化结果
+		result, err := indexer.(*DatabaseIndexer).Facets(ctx, FacetRequest{KnowledgeBaseID: 7, Query: query})
+		require.NoError(t, err)
+		return result.Total
+	}
+
+	// 触发器写入的二元分词与查询分词一致
+	assert.Equal(t, int64(1), count("天气"))
+	assert.Equal(t, int64(1), count(`"明天天气"`))
+	assert.Equal(t, int64(1), count("コーヒー"))
+	assert.Equal(t, int64(0), count("下雨"))
+
+	matches, err := indexer.Search(ctx, FulltextSearchRequest{KnowledgeBaseID: 7, Query: "天气", Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, uint(1), matches[0].ChunkID)
+	assert.Contains(t, matches[0].Highlight, "<mark>")
+
+	// 入库路径通过IndexChunk写入文件名，文件名同样可检索
+	assert.Equal(t, int64(0), count("报告"))
+	require.NoError(t, IndexChunks(ctx, db, indexer, 7, []uint{3}))
+	assert.Equal(t, int64(1), count("报告"))
+}
@@ -0,0 +1,31 @@
+package knowledge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCJKBigrams(t *testing.T) {
+	assert.Equal(t, "杭州 州天 天气", cjkBigrams("杭州天气"))
+	assert.Equal(t, "合同 第 条款", cjkBigrams("合同 第12条款"))
+	assert.Equal(t, "", cjkBigrams("hello world"))
+}
+
+func TestFulltextDocument(t *testing.T) {
+	assert.Equal(t, "hello", fulltextDocument("hello"))
+	assert.Equal(t, "API 文档 文档", fulltextDocument("API 文档"))
+}
+
+func TestExpandCJKQuery(t *testing.T) {
+	// 保留websearch语法，引号内的中文替换为相邻的二元分词
+	assert.Equal(t, `"杭州 州天 天气" -下雨 or weather`, expandCJKQuery(`"杭州天气" -下雨 or weather`))
+	assert.Equal(t, `合同 -"违约 约金"`, expandCJKQuery("合同 -违约金"))
+	assert.Equal(t, "postgres 全文 文检 检索 v2", expandCJKQuery("postgres全文检索v2"))
+}
+
+func TestCJKBigrams_KanaRuns(t *testing.T) {
+	// 长音符与假名同属一个片段，与迁移000016的字符范围一致
+	assert.Equal(t, "コー ーヒ ヒー ーの の淹 淹れ れ方", cjkBigrams("コーヒーの淹れ方"))
+	assert.Equal(t, "한국 국어", cjkBigrams("한국어"))
+}
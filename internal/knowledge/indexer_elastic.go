@@ -13,10 +13,13 @@ import (
 )
 
 // ElasticsearchIndexer 基于ES的全文索引
+// 每个知识库的索引名是别名，指向带版本号的物理索引，重建时切换别名
 type ElasticsearchIndexer struct {
 	client      *elasticsearch.Client
 	indexPrefix string
 	indexCache  map[string]bool
+	pending     map[uint]int // 知识库ID -> 重建中的版本，删除操作同时作用于该版本
+	lexicons    LexiconProvider
 	mu          sync.Mutex
 }
 
@@ -45,6 +48,7 @@ func NewElasticsearchIndexer(addresses []string, username, password, apiKey, ind
 		client:      client,
 		indexPrefix: indexPrefix,
 		indexCache:  make(map[string]bool),
+		pending:     make(map[uint]int),
 	}, nil
 }
 
@@ -78,16 +82,29 @@ func (e *ElasticsearchIndexer) ensureIndex(ctx context.Context, kbID uint) error
 		return nil
 	}
 
-	mapping := map[string]interface{}{
+	// 新知识库直接创建第一个版本并挂上别名
+	if err := e.createIndex(ctx, kbID, versionedName(name, 1), name); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.indexCache[name] = true
+	e.mu.Unlock()
+	return nil
+}
+
+// SetLexiconProvider 设置知识库词库，新建索引时编译进分析器
+func (e *ElasticsearchIndexer) SetLexiconProvider(provider LexiconProvider) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lexicons = provider
+}
+
+// indexMapping 分块索引的设置与映射
+func indexMapping(lexicon *Lexicon) map[string]interface{} {
+	return map[string]interface{}{
 		"settings": map[string]interface{}{
-			"analysis": map[string]interface{}{
-				"analyzer": map[string]interface{}{
-					"ik_max": map[string]interface{}{
-						"type":      "custom",
-						"tokenizer": "ik_max_word",
-					},
-				},
-			},
+			"analysis": analysisSettings(lexicon),
 		},
 		"mappings": map[string]interface{}{
 			"properties": map[string]interface{}{
@@ -95,19 +112,93 @@ func (e *ElasticsearchIndexer) ensureIndex(ctx context.Context, kbID uint) error
 				"document_id":       map[string]interface{}{"type": "keyword"},
 				"chunk_id":          map[string]interface{}{"type": "keyword"},
 				"chunk_index":       map[string]interface{}{"type": "integer"},
-				"content": map[string]interface{}{
-					"type":            "text",
-					"analyzer":        "ik_max",
-					"search_analyzer": "ik_max",
-					"index_options":   "offsets",
-				},
-				"metadata":   map[string]interface{}{"type": "object", "enabled": true},
-				"file_name":  map[string]interface{}{"type": "keyword"},
-				"file_type":  map[string]interface{}{"type": "keyword"},
-				"created_at": map[string]interface{}{"type": "date"},
+				"content":           contentMapping(),
+				"metadata":          map[string]interface{}{"type": "object", "enabled": true},
+				"file_name":         map[string]interface{}{"type": "keyword"},
+				"file_type":         map[string]interface{}{"type": "keyword"},
+				"source":            map[string]interface{}{"type": "keyword"},
+				"tags":              map[string]interface{}{"type": "keyword"},
+				"created_at":        map[string]interface{}{"type": "date"},
+			},
+		},
+	}
+}
+
+func contentMapping() map[string]interface{} {
+	return map[string]interface{}{
+		"type":            "text",
+		"analyzer":        "ik_max",
+		"search_analyzer": "ik_search",
+		"index_options":   "offsets",
+	}
+}
+
+// analysisSettings 分析器设置：同义词与停用词只作用于检索分析器，修改后重新打开索引即可生效
+// 自定义词典由IK远程扩展词典加载（见LexiconService），修改后需要重建索引
+func analysisSettings(lexicon *Lexicon) map[string]interface{} {
+	filters := map[string]interface{}{}
+	searchFilters := []string{"lowercase"}
+	if lexicon != nil && len(lexicon.Synonyms) > 0 {
+		rules := make([]string, 0, len(lexicon.Synonyms))
+		for _, terms := range lexicon.Synonyms {
+			rules = append(rules, strings.Join(terms, ", "))
+		}
+		filters["kb_synonyms"] = map[string]interface{}{
+			"type":     "synonym_graph",
+			"synonyms": rules,
+			"lenient":  true,
+		}
+		searchFilters = append(searchFilters, "kb_synonyms")
+	}
+	if lexicon != nil && len(lexicon.StopWords) > 0 {
+		filters["kb_stop"] = map[string]interface{}{
+			"type":        "stop",
+			"stopwords":   lexicon.StopWords,
+			"ignore_case": true,
+		}
+		searchFilters = append(searchFilters, "kb_stop")
+	}
+
+	analysis := map[string]interface{}{
+		"analyzer": map[string]interface{}{
+			"ik_max": map[string]interface{}{
+				"type":      "custom",
+				"tokenizer": "ik_max_word",
+			},
+			"ik_search": map[string]interface{}{
+				"type":      "custom",
+				"tokenizer": "ik_max_word",
+				"filter":    searchFilters,
 			},
 		},
 	}
+	if len(filters) > 0 {
+		analysis["filter"] = filters
+	}
+	return analysis
+}
+
+// lexicon 获取知识库词库，读取失败时按空词库处理
+func (e *ElasticsearchIndexer) lexicon(ctx context.Context, kbID uint) *Lexicon {
+	e.mu.Lock()
+	provider := e.lexicons
+	e.mu.Unlock()
+	if provider == nil {
+		return nil
+	}
+	lexicon, err := provider.Lexicon(ctx, kbID)
+	if err != nil {
+		return nil
+	}
+	return lexicon
+}
+
+// createIndex 创建物理索引，alias非空时同时挂上别名
+func (e *ElasticsearchIndexer) createIndex(ctx context.Context, kbID uint, name, alias string) error {
+	mapping := indexMapping(e.lexicon(ctx, kbID))
+	if alias != "" {
+		mapping["aliases"] = map[string]interface{}{alias: map[string]interface{}{}}
+	}
 
 	body, _ := json.Marshal(mapping)
 	createReq := esapi.IndicesCreateRequest{
@@ -123,10 +214,6 @@ func (e *ElasticsearchIndexer) ensureIndex(ctx context.Context, kbID uint) error
 	if createResp.IsError() {
 		return fmt.Errorf("create index error: %s", createResp.String())
 	}
-
-	e.mu.Lock()
-	e.indexCache[name] = true
-	e.mu.Unlock()
 	return nil
 }
 
@@ -138,6 +225,11 @@ func (e *ElasticsearchIndexer) IndexChunk(ctx context.Context, chunk FulltextChu
 		return err
 	}
 
+	return e.indexDocument(ctx, e.indexName(chunk.KnowledgeBaseID), chunk, "true")
+}
+
+// indexDocument 写入分块到指定索引（别名或物理索引）
+func (e *ElasticsearchIndexer) indexDocument(ctx context.Context, index string, chunk FulltextChunk, refresh string) error {
 	doc := map[string]interface{}{
 		"chunk_id":          chunk.ChunkID,
 		"document_id":       chunk.DocumentID,
@@ -147,15 +239,17 @@ func (e *ElasticsearchIndexer) IndexChunk(ctx context.Context, chunk FulltextChu
 		"metadata":          chunk.Metadata,
 		"file_name":         chunk.FileName,
 		"file_type":         chunk.FileType,
+		"source":            chunk.Source,
+		"tags":              chunk.Tags,
 		"created_at":        chunk.CreatedAt,
 	}
 
 	payload, _ := json.Marshal(doc)
 	req := esapi.IndexRequest{
-		Index:      e.indexName(chunk.KnowledgeBaseID),
+		Index:      index,
 		DocumentID: fmt.Sprintf("%d", chunk.ChunkID),
 		Body:       bytes.NewReader(payload),
-		Refresh:    "true",
+		Refresh:    refresh,
 	}
 
 	resp, err := req.Do(ctx, e.client)
@@ -190,9 +284,17 @@ func (e *ElasticsearchIndexer) RemoveDocument(ctx context.Context, knowledgeBase
 		},
 	}
 
+	// 重建中的版本同样删除，避免切换别名后已删除的文档重新出现
+	indices := []string{e.indexName(knowledgeBaseID)}
+	e.mu.Lock()
+	if version, ok := e.pending[knowledgeBaseID]; ok {
+		indices = append(indices, versionedName(e.indexName(knowledgeBaseID), version))
+	}
+	e.mu.Unlock()
+
 	body, _ := json.Marshal(query)
 	req := esapi.DeleteByQueryRequest{
-		Index: []string{e.indexName(knowledgeBaseID)},
+		Index: indices,
 		Body:  bytes.NewReader(body),
 	}
 
@@ -220,45 +322,10 @@ func (e *ElasticsearchIndexer) Search(ctx context.Context, req FulltextSearchReq
 		return nil, err
 	}
 
-	// 优先使用 match_phrase 精确短语匹配，无结果则降级为 match 模糊匹配
-	// 使用 should 子句，match_phrase 的 boost 更高，优先匹配
-	boolQuery := map[string]interface{}{
-		"must": []interface{}{
-			map[string]interface{}{
-				"term": map[string]interface{}{
-					"knowledge_base_id": req.KnowledgeBaseID,
-				},
-			},
-		},
-		"should": []interface{}{
-			// 精确短语匹配（优先级最高）
-			map[string]interface{}{
-				"match_phrase": map[string]interface{}{
-					"content": map[string]interface{}{
-						"query": req.Query,
-						"boost": 3.0, // 提高精确匹配的权重
-					},
-				},
-			},
-			// 模糊关键词匹配（降级策略）
-			map[string]interface{}{
-				"match": map[string]interface{}{
-					"content": map[string]interface{}{
-						"query":                req.Query,
-						"operator":             "and",
-						"minimum_should_match": "70%",
-						"boost":                1.0,
-					},
-				},
-			},
-		},
-		"minimum_should_match": 1, // 至少匹配一个 should 子句
-	}
-
 	body := map[string]interface{}{
 		"size": req.Limit,
 		"query": map[string]interface{}{
-			"bool": boolQuery,
+			"bool": fulltextBoolQuery(req.KnowledgeBaseID, req.Query),
 		},
 		"highlight": map[string]interface{}{
 			"fields": map[string]interface{}{
@@ -331,10 +398,449 @@ func (e *ElasticsearchIndexer) Search(ctx context.Context, req FulltextSearchReq
 	return matches, nil
 }
 
+// ScanChunks 按chunk_id排序用search_after分批列举索引中的分块，索引不存在时没有分块
+func (e *ElasticsearchIndexer) ScanChunks(ctx context.Context, knowledgeBaseID uint, fn func([]IndexedChunk) error) error {
+	if e.client == nil {
+		return nil
+	}
+
+	ignoreUnavailable := true
+	var after []interface{}
+	for {
+		body := map[string]interface{}{
+			"size":    scanBatchSize,
+			"_source": []string{"document_id", "content"},
+			"query":   map[string]interface{}{"match_all": map[string]interface{}{}},
+			"sort":    []interface{}{map[string]interface{}{"chunk_id": "asc"}},
+		}
+		if after != nil {
+			body["search_after"] = after
+		}
+		payload, _ := json.Marshal(body)
+		req := esapi.SearchRequest{
+			Index:             []string{e.indexName(knowledgeBaseID)},
+			Body:              bytes.NewReader(payload),
+			IgnoreUnavailable: &ignoreUnavailable,
+		}
+
+		resp, err := req.Do(ctx, e.client)
+		if err != nil {
+			return err
+		}
+		var result struct {
+			Hits struct {
+				Hits []struct {
+					ID     string `json:"_id"`
+					Source struct {
+						DocumentID json.Number `json:"document_id"`
+						Content    string      `json:"content"`
+					} `json:"_source"`
+					Sort []interface{} `json:"sort"`
+				} `json:"hits"`
+			} `json:"hits"`
+		}
+		if resp.IsError() {
+			resp.Body.Close()
+			return fmt.Errorf("scan chunks error: %s", resp.String())
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		hits := result.Hits.Hits
+		if len(hits) == 0 {
+			return nil
+		}
+		batch := make([]IndexedChunk, 0, len(hits))
+		for _, hit := range hits {
+			batch = append(batch, IndexedChunk{
+				ChunkID:     uint(parseUint(hit.ID)),
+				DocumentID:  uint(parseUint(hit.Source.DocumentID.String())),
+				ContentHash: ChunkHash(hit.Source.Content),
+			})
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(hits) < scanBatchSize {
+			return nil
+		}
+		after = hits[len(hits)-1].Sort
+	}
+}
+
+// fulltextBoolQuery 检索与分面统计共用的查询条件
+func fulltextBoolQuery(knowledgeBaseID uint, query string) map[string]interface{} {
+	// 优先使用 match_phrase 精确短语匹配，无结果则降级为 match 模糊匹配
+	// 使用 should 子句，match_phrase 的 boost 更高，优先匹配
+	return map[string]interface{}{
+		"must": []interface{}{
+			map[string]interface{}{
+				"term": map[string]interface{}{
+					"knowledge_base_id": knowledgeBaseID,
+				},
+			},
+		},
+		"should": []interface{}{
+			// 精确短语匹配（优先级最高）
+			map[string]interface{}{
+				"match_phrase": map[string]interface{}{
+					"content": map[string]interface{}{
+						"query": query,
+						"boost": 3.0, // 提高精确匹配的权重
+					},
+				},
+			},
+			// 模糊关键词匹配（降级策略）
+			map[string]interface{}{
+				"match": map[string]interface{}{
+					"content": map[string]interface{}{
+						"query":                query,
+						"operator":             "and",
+						"minimum_should_match": "70%",
+						"boost":                1.0,
+					},
+				},
+			},
+		},
+		"minimum_should_match": 1, // 至少匹配一个 should 子句
+	}
+}
+
+// esFacetFields 分面字段对应的索引字段
+var esFacetFields = map[string]string{
+	FacetDocument: "document_id",
+	FacetFileType: "file_type",
+	FacetSource:   "source",
+	FacetTag:      "tags",
+}
+
+// Facets 使用terms聚合统计分面，命中数超过facetTrackHits时为下限估计
+// source与tags字段在重建索引后才会写入旧索引
+func (e *ElasticsearchIndexer) Facets(ctx context.Context, req FacetRequest) (*FacetResult, error) {
+	if e.client == nil {
+		return &FacetResult{TotalRelation: TotalRelationEqual}, nil
+	}
+	if err := e.ensureIndex(ctx, req.KnowledgeBaseID); err != nil {
+		return nil, err
+	}
+
+	aggs := map[string]interface{}{}
+	for _, field := range req.Fields {
+		if name, ok := esFacetFields[field]; ok {
+			aggs[field] = map[string]interface{}{
+				"terms": map[string]interface{}{"field": name, "size": req.size()},
+			}
+		}
+	}
+	body := map[string]interface{}{
+		"size":             0,
+		"track_total_hits": facetTrackHits,
+		"query": map[string]interface{}{
+			"bool": fulltextBoolQuery(req.KnowledgeBaseID, req.Query),
+		},
+	}
+	if len(aggs) > 0 {
+		body["aggs"] = aggs
+	}
+
+	payload, _ := json.Marshal(body)
+	resp, err := esapi.SearchRequest{
+		Index: []string{e.indexName(req.KnowledgeBaseID)},
+		Body:  bytes.NewReader(payload),
+	}.Do(ctx, e.client)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.IsError() {
+		return nil, fmt.Errorf("facet search error: %s", resp.String())
+	}
+
+	var result struct {
+		Hits struct {
+			Total struct {
+				Value    int64  `json:"value"`
+				Relation string `json:"relation"`
+			} `json:"total"`
+		} `json:"hits"`
+		Aggregations map[string]struct {
+			Buckets []struct {
+				Key      interface{} `json:"key"`
+				DocCount int64       `json:"doc_count"`
+			} `json:"buckets"`
+		} `json:"aggregations"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	facets := &FacetResult{
+		Total:         result.Hits.Total.Value,
+		TotalRelation: result.Hits.Total.Relation,
+		Facets:        make(map[string][]FacetCount, len(result.Aggregations)),
+	}
+	if facets.TotalRelation == "" {
+		facets.TotalRelation = TotalRelationEqual
+	}
+	for field, agg := range result.Aggregations {
+		counts := make([]FacetCount, 0, len(agg.Buckets))
+		for _, bucket := range agg.Buckets {
+			counts = append(counts, FacetCount{Value: fmt.Sprintf("%v", bucket.Key), Count: bucket.DocCount})
+		}
+		facets.Facets[field] = counts
+	}
+	return facets, nil
+}
+
 func (e *ElasticsearchIndexer) Ready() bool {
 	return e.client != nil
 }
 
+// CurrentVersion 别名当前指向的版本
+func (e *ElasticsearchIndexer) CurrentVersion(ctx context.Context, knowledgeBaseID uint) (int, error) {
+	alias := e.indexName(knowledgeBaseID)
+	targets, err := e.aliasTargets(ctx, alias)
+	if err != nil {
+		return 0, err
+	}
+	version := 0
+	for _, target := range targets {
+		if v := parseVersion(alias, target); v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// CreateVersion 创建新版本的物理索引，同名的残留索引（上次重建中断）会先删除
+func (e *ElasticsearchIndexer) CreateVersion(ctx context.Context, knowledgeBaseID uint, version int) error {
+	name := versionedName(e.indexName(knowledgeBaseID), version)
+	if err := e.deleteIndex(ctx, name); err != nil {
+		return err
+	}
+	if err := e.createIndex(ctx, knowledgeBaseID, name, ""); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.pending[knowledgeBaseID] = version
+	e.mu.Unlock()
+	return nil
+}
+
+// IndexChunkVersion 写入分块到指定版本，不立即刷新
+func (e *ElasticsearchIndexer) IndexChunkVersion(ctx context.Context, version int, chunk FulltextChunk) error {
+	return e.indexDocument(ctx, versionedName(e.indexName(chunk.KnowledgeBaseID), version), chunk, "false")
+}
+
+// SwapVersion 原子地将别名切换到新版本并删除旧索引
+// 旧的未版本化索引与别名同名，同一请求中通过remove_index删除后再添加别名
+func (e *ElasticsearchIndexer) SwapVersion(ctx context.Context, knowledgeBaseID uint, version int) error {
+	alias := e.indexName(knowledgeBaseID)
+	name := versionedName(alias, version)
+
+	refreshReq := esapi.IndicesRefreshRequest{Index: []string{name}}
+	refreshResp, err := refreshReq.Do(ctx, e.client)
+	if err != nil {
+		return err
+	}
+	refreshResp.Body.Close()
+
+	targets, err := e.aliasTargets(ctx, alias)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		existsReq := esapi.IndicesExistsRequest{Index: []string{alias}}
+		existsResp, err := existsReq.Do(ctx, e.client)
+		if err != nil {
+			return err
+		}
+		existsResp.Body.Close()
+		if existsResp.StatusCode == 200 {
+			targets = []string{alias}
+		}
+	}
+
+	actions := make([]interface{}, 0, len(targets)+1)
+	for _, target := range targets {
+		if target == name {
+			continue
+		}
+		actions = append(actions, map[string]interface{}{
+			"remove_index": map[string]interface{}{"index": target},
+		})
+	}
+	actions = append(actions, map[string]interface{}{
+		"add": map[string]interface{}{"index": name, "alias": alias},
+	})
+
+	body, _ := json.Marshal(map[string]interface{}{"actions": actions})
+	req := esapi.IndicesUpdateAliasesRequest{Body: bytes.NewReader(body)}
+	resp, err := req.Do(ctx, e.client)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.IsError() {
+		return fmt.Errorf("swap alias error: %s", resp.String())
+	}
+
+	e.mu.Lock()
+	delete(e.pending, knowledgeBaseID)
+	e.indexCache[alias] = true
+	e.mu.Unlock()
+	return nil
+}
+
+// DropVersion 删除未启用的版本
+func (e *ElasticsearchIndexer) DropVersion(ctx context.Context, knowledgeBaseID uint, version int) error {
+	e.mu.Lock()
+	if e.pending[knowledgeBaseID] == version {
+		delete(e.pending, knowledgeBaseID)
+	}
+	e.mu.Unlock()
+
+	if version <= 0 {
+		return nil
+	}
+	return e.deleteIndex(ctx, versionedName(e.indexName(knowledgeBaseID), version))
+}
+
+// ApplyLexicon 将词库编译进知识库现有索引的检索分析器
+// 分析器设置只能在索引关闭时修改，关闭期间该知识库的全文检索会短暂失败；自定义词典的变更需要重建索引
+func (e *ElasticsearchIndexer) ApplyLexicon(ctx context.Context, knowledgeBaseID uint, lexicon *Lexicon) error {
+	if e.client == nil {
+		return nil
+	}
+	alias := e.indexName(knowledgeBaseID)
+	targets, err := e.aliasTargets(ctx, alias)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		// 启用版本化之前创建的索引直接使用知识库索引名
+		existsResp, err := esapi.IndicesExistsRequest{Index: []string{alias}}.Do(ctx, e.client)
+		if err != nil {
+			return err
+		}
+		existsResp.Body.Close()
+		if existsResp.StatusCode == 200 {
+			targets = append(targets, alias)
+		}
+	}
+	e.mu.Lock()
+	if version, ok := e.pending[knowledgeBaseID]; ok {
+		targets = append(targets, versionedName(alias, version))
+	}
+	e.mu.Unlock()
+
+	for _, index := range targets {
+		if err := e.updateAnalysis(ctx, index, lexicon); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateAnalysis 关闭索引、更新分析器设置后重新打开，并把content的检索分析器指向ik_search
+func (e *ElasticsearchIndexer) updateAnalysis(ctx context.Context, index string, lexicon *Lexicon) error {
+	closeResp, err := esapi.IndicesCloseRequest{Index: []string{index}}.Do(ctx, e.client)
+	if err != nil {
+		return err
+	}
+	closeResp.Body.Close()
+	if closeResp.IsError() {
+		return fmt.Errorf("close index error: %s", closeResp.String())
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"analysis": analysisSettings(lexicon)})
+	settingsResp, settingsErr := esapi.IndicesPutSettingsRequest{
+		Index: []string{index},
+		Body:  bytes.NewReader(body),
+	}.Do(ctx, e.client)
+	if settingsErr == nil {
+		defer settingsResp.Body.Close()
+		if settingsResp.IsError() {
+			settingsErr = fmt.Errorf("update index settings error: %s", settingsResp.String())
+		}
+	}
+
+	// 无论设置是否成功都要重新打开索引
+	openResp, err := esapi.IndicesOpenRequest{Index: []string{index}}.Do(ctx, e.client)
+	if err != nil {
+		return err
+	}
+	openResp.Body.Close()
+	if openResp.IsError() {
+		return fmt.Errorf("open index error: %s", openResp.String())
+	}
+	if settingsErr != nil {
+		return settingsErr
+	}
+
+	body, _ = json.Marshal(map[string]interface{}{
+		"properties": map[string]interface{}{"content": contentMapping()},
+	})
+	mappingResp, err := esapi.IndicesPutMappingRequest{
+		Index: []string{index},
+		Body:  bytes.NewReader(body),
+	}.Do(ctx, e.client)
+	if err != nil {
+		return err
+	}
+	defer mappingResp.Body.Close()
+	if mappingResp.IsError() {
+		return fmt.Errorf("update index mapping error: %s", mappingResp.String())
+	}
+	return nil
+}
+
+// aliasTargets 别名指向的物理索引，别名不存在时返回空
+func (e *ElasticsearchIndexer) aliasTargets(ctx context.Context, alias string) ([]string, error) {
+	req := esapi.IndicesGetAliasRequest{Name: []string{alias}}
+	resp, err := req.Do(ctx, e.client)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 404 {
+		return nil, nil
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("get alias error: %s", resp.String())
+	}
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	targets := make([]string, 0, len(result))
+	for index := range result {
+		targets = append(targets, index)
+	}
+	return targets, nil
+}
+
+// deleteIndex 删除物理索引，索引不存在时忽略
+func (e *ElasticsearchIndexer) deleteIndex(ctx context.Context, name string) error {
+	req := esapi.IndicesDeleteRequest{Index: []string{name}}
+	resp, err := req.Do(ctx, e.client)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.IsError() && resp.StatusCode != 404 {
+		return fmt.Errorf("delete index error: %s", resp.String())
+	}
+	return nil
+}
+
 // NoopFulltextIndexer 默认占位实现
 type NoopFulltextIndexer struct{}
 
@@ -350,6 +856,10 @@ func (n *NoopFulltextIndexer) Search(ctx context.Context, req FulltextSearchRequ
 	return nil, nil
 }
 
+func (n *NoopFulltextIndexer) ScanChunks(ctx context.Context, knowledgeBaseID uint, fn func([]IndexedChunk) error) error {
+	return nil
+}
+
 func (n *NoopFulltextIndexer) Ready() bool {
 	return false
 }
@@ -0,0 +1,355 @@
+package knowledge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Lexicon 知识库的同义词、停用词与自定义词典
+// ES通过分析器应用词库，数据库全文检索与向量检索在查询时扩展
+type Lexicon struct {
+	Synonyms   [][]string `json:"synonyms"`   // 同义词组，组内词语互相等价
+	StopWords  []string   `json:"stop_words"` // 停用词，查询时去除
+	Dictionary []string   `json:"dictionary"` // 自定义词，作为整词匹配
+}
+
+// LexiconProvider 按知识库获取词库
+type LexiconProvider interface {
+	Lexicon(ctx context.Context, knowledgeBaseID uint) (*Lexicon, error)
+}
+
+// LexiconAware 使用知识库词库的组件（全文索引、检索引擎）
+type LexiconAware interface {
+	SetLexiconProvider(provider LexiconProvider)
+}
+
+// LexiconAnalyzer 将词库编译进索引分析器的全文索引，词库修改后调用
+type LexiconAnalyzer interface {
+	ApplyLexicon(ctx context.Context, knowledgeBaseID uint, lexicon *Lexicon) error
+}
+
+// maxQueryVariants 同义词展开的查询变体上限
+const maxQueryVariants = 8
+
+// IsEmpty 词库是否为空
+func (l *Lexicon) IsEmpty() bool {
+	return l == nil || (len(l.Synonyms) == 0 && len(l.StopWords) == 0 && len(l.Dictionary) == 0)
+}
+
+// FulltextQuery 数据库全文检索（websearch语法）的查询扩展
+// 去除停用词，自定义词加引号整体匹配；命中同义词时生成各同义词的查询变体并用or连接
+func (l *Lexicon) FulltextQuery(query string) string {
+	if l.IsEmpty() {
+		return query
+	}
+	query = l.removeStopWords(query)
+	variants := l.queryVariants(query)
+	for i, variant := range variants {
+		variants[i] = l.quoteDictionaryWords(variant)
+	}
+	return strings.Join(variants, " or ")
+}
+
+// ExpandTerms 向量检索的查询扩展：去除停用词，并在查询后附加命中的同义词
+func (l *Lexicon) ExpandTerms(query string) string {
+	if l.IsEmpty() {
+		return query
+	}
+	query = l.removeStopWords(query)
+	lower := strings.ToLower(query)
+
+	var extras []string
+	for _, match := range l.synonymMatches(lower) {
+		for _, term := range l.Synonyms[match.group] {
+			if len(termPositions(lower, strings.ToLower(term))) == 0 {
+				extras = append(extras, term)
+			}
+		}
+	}
+	if len(extras) == 0 {
+		return query
+	}
+	return query + " " + strings.Join(extras, " ")
+}
+
+// removeStopWords 去除以空白分隔的停用词，全部是停用词时保留原查询
+func (l *Lexicon) removeStopWords(query string) string {
+	if len(l.StopWords) == 0 {
+		return query
+	}
+	stop := make(map[string]bool, len(l.StopWords))
+	for _, word := range l.StopWords {
+		stop[strings.ToLower(word)] = true
+	}
+
+	fields := strings.Fields(query)
+	var kept []string
+	for _, field := range fields {
+		if !stop[strings.ToLower(field)] {
+			kept = append(kept, field)
+		}
+	}
+	if len(kept) == 0 {
+		return query
+	}
+	return strings.Join(kept, " ")
+}
+
+// synonymMatch 查询中命中的同义词
+type synonymMatch struct {
+	start, end int
+	group      int
+}
+
+// synonymMatches 在小写查询中查找同义词，较长的词优先，命中位置互不重叠
+func (l *Lexicon) synonymMatches(lower string) []synonymMatch {
+	type candidate struct {
+		term  string
+		group int
+	}
+	var candidates []candidate
+	for group, terms := range l.Synonyms {
+		for _, term := range terms {
+			if term = strings.ToLower(strings.TrimSpace(term)); term != "" {
+				candidates = append(candidates, candidate{term: term, group: group})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return len(candidates[i].term) > len(candidates[j].term)
+	})
+
+	var matches []synonymMatch
+	for _, c := range candidates {
+		for _, start := range termPositions(lower, c.term) {
+			end := start + len(c.term)
+			overlaps := false
+			for _, m := range matches {
+				if start < m.end && m.start < end {
+					overlaps = true
+					break
+				}
+			}
+			if !overlaps {
+				matches = append(matches, synonymMatch{start: start, end: end, group: c.group})
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+	return matches
+}
+
+// queryVariants 同义词替换生成的查询变体（小写），第一个为原查询
+func (l *Lexicon) queryVariants(query string) []string {
+	lower := strings.ToLower(query)
+	matches := l.synonymMatches(lower)
+	if len(matches) == 0 {
+		return []string{query}
+	}
+
+	variants := []string{""}
+	last := 0
+	for _, m := range matches {
+		prefix := lower[last:m.start]
+		original := lower[m.start:m.end]
+		next := make([]string, 0, len(variants))
+		for _, v := range variants {
+			next = append(next, v+prefix+original)
+		}
+		for _, v := range variants {
+			for _, term := range l.Synonyms[m.group] {
+				term = strings.ToLower(strings.TrimSpace(term))
+				if term == "" || term == original || len(next) >= maxQueryVariants {
+					continue
+				}
+				next = append(next, v+prefix+term)
+			}
+		}
+		variants = next
+		last = m.end
+	}
+	for i := range variants {
+		variants[i] += lower[last:]
+	}
+	return variants
+}
+
+// quoteDictionaryWords 给查询中的自定义词加引号，已在引号内的不处理
+func (l *Lexicon) quoteDictionaryWords(query string) string {
+	if len(l.Dictionary) == 0 {
+		return query
+	}
+	lower := strings.ToLower(query)
+	var spans [][2]int
+	for _, word := range l.Dictionary {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word == "" {
+			continue
+		}
+		for _, start := range termPositions(lower, word) {
+			if strings.Count(query[:start], `"`)%2 == 1 {
+				continue
+			}
+			spans = append(spans, [2]int{start, start + len(word)})
+		}
+	}
+	if len(spans) == 0 {
+		return query
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		if span[0] < last {
+			continue
+		}
+		b.WriteString(query[last:span[0]])
+		b.WriteString(`"` + query[span[0]:span[1]] + `"`)
+		last = span[1]
+	}
+	b.WriteString(query[last:])
+	return b.String()
+}
+
+// termPositions 查找词在文本中的位置；非中日韩词需要在词边界上
+func termPositions(text, term string) []int {
+	if term == "" || len(text) != len(strings.ToLower(text)) {
+		return nil
+	}
+	cjk := strings.IndexFunc(term, isCJK) >= 0
+	var positions []int
+	for offset := 0; offset < len(text); {
+		idx := strings.Index(text[offset:], term)
+		if idx < 0 {
+			break
+		}
+		start := offset + idx
+		end := start + len(term)
+		if cjk || (!wordRuneBefore(text, start) && !wordRuneAfter(text, end)) {
+			positions = append(positions, start)
+		}
+		offset = end
+	}
+	return positions
+}
+
+func wordRuneBefore(text string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(text[:i])
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func wordRuneAfter(text string, i int) bool {
+	if i >= len(text) {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(text[i:])
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// LexiconStore 从数据库读取知识库词库，短期缓存；修改词库后调用Invalidate
+type LexiconStore struct {
+	db  *gorm.DB
+	ttl time.Duration
+
+	mu    sync.Mutex
+	cache map[uint]lexiconEntry
+}
+
+type lexiconEntry struct {
+	lexicon  *Lexicon
+	loadedAt time.Time
+}
+
+// NewLexiconStore 创建词库存储，ttl默认1分钟
+func NewLexiconStore(db *gorm.DB, ttl time.Duration) *LexiconStore {
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	return &LexiconStore{
+		db:    db,
+		ttl:   ttl,
+		cache: make(map[uint]lexiconEntry),
+	}
+}
+
+// Lexicon 获取知识库词库
+func (s *LexiconStore) Lexicon(ctx context.Context, knowledgeBaseID uint) (*Lexicon, error) {
+	s.mu.Lock()
+	entry, ok := s.cache[knowledgeBaseID]
+	s.mu.Unlock()
+	if ok && time.Since(entry.loadedAt) < s.ttl {
+		return entry.lexicon, nil
+	}
+
+	lexicon, err := s.LoadLexicon(ctx, knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.cache[knowledgeBaseID] = lexiconEntry{lexicon: lexicon, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return lexicon, nil
+}
+
+// Invalidate 清除知识库词库缓存
+func (s *LexiconStore) Invalidate(knowledgeBaseID uint) {
+	s.mu.Lock()
+	delete(s.cache, knowledgeBaseID)
+	s.mu.Unlock()
+}
+
+// LoadLexicon 从数据库读取知识库词库（不使用缓存）
+func (s *LexiconStore) LoadLexicon(ctx context.Context, knowledgeBaseID uint) (*Lexicon, error) {
+	var synonyms []struct {
+		Terms string
+	}
+	if err := s.db.WithContext(ctx).Table("knowledge_synonyms").
+		Select("terms").
+		Where("knowledge_base_id = ?", knowledgeBaseID).
+		Order("synonym_id").
+		Scan(&synonyms).Error; err != nil {
+		return nil, fmt.Errorf("failed to load synonyms: %w", err)
+	}
+
+	var words []struct {
+		Kind string
+		Word string
+	}
+	if err := s.db.WithContext(ctx).Table("knowledge_lexicon_words").
+		Select("kind, word").
+		Where("knowledge_base_id = ?", knowledgeBaseID).
+		Order("word_id").
+		Scan(&words).Error; err != nil {
+		return nil, fmt.Errorf("failed to load lexicon words: %w", err)
+	}
+
+	lexicon := &Lexicon{}
+	for _, row := range synonyms {
+		var terms []string
+		if err := json.Unmarshal([]byte(row.Terms), &terms); err == nil && len(terms) > 1 {
+			lexicon.Synonyms = append(lexicon.Synonyms, terms)
+		}
+	}
+	for _, row := range words {
+		switch row.Kind {
+		case "stopword":
+			lexicon.StopWords = append(lexicon.StopWords, row.Word)
+		case "dictionary":
+			lexicon.Dictionary = append(lexicon.Dictionary, row.Word)
+		}
+	}
+	return lexicon, nil
+}
@@ -0,0 +1,73 @@
+package knowledge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testLexicon() *Lexicon {
+	return &Lexicon{
+		Synonyms:   [][]string{{"报销", "报账"}, {"VPN", "virtual private network"}},
+		StopWords:  []string{"the", "of"},
+		Dictionary: []string{"数据中台"},
+	}
+}
+
+func TestLexicon_FulltextQuery(t *testing.T) {
+	lex := testLexicon()
+
+	assert.Equal(t, "报销流程 or 报账流程", lex.FulltextQuery("报销流程"))
+	assert.Equal(t, "vpn setup or virtual private network setup", lex.FulltextQuery("the VPN setup"))
+	// 非中日韩同义词需要在词边界上
+	assert.Equal(t, "vpnclient", lex.FulltextQuery("vpnclient"))
+	assert.Equal(t, `"数据中台" 架构`, lex.FulltextQuery("数据中台 架构"))
+	// 全部是停用词时保留原查询
+	assert.Equal(t, "the of", lex.FulltextQuery("the of"))
+
+	var empty *Lexicon
+	assert.Equal(t, "报销流程", empty.FulltextQuery("报销流程"))
+}
+
+func TestLexicon_QueryVariantsCapped(t *testing.T) {
+	lex := &Lexicon{Synonyms: [][]string{
+		{"a", "b", "c", "d"},
+		{"x", "y", "z", "w"},
+	}}
+	variants := lex.queryVariants("a x")
+	assert.Len(t, variants, maxQueryVariants)
+	assert.Equal(t, "a x", variants[0])
+}
+
+func TestLexicon_ExpandTerms(t *testing.T) {
+	lex := testLexicon()
+	assert.Equal(t, "报销 流程 报账", lex.ExpandTerms("报销 流程"))
+	assert.Equal(t, "VPN virtual private network", lex.ExpandTerms("the VPN"))
+	assert.Equal(t, "年假", lex.ExpandTerms("年假"))
+}
+
+type staticLexicons map[uint]*Lexicon
+
+func (s staticLexicons) Lexicon(ctx context.Context, kbID uint) (*Lexicon, error) {
+	return s[kbID], nil
+}
+
+func TestAnalysisSettings_CompilesLexicon(t *testing.T) {
+	settings := analysisSettings(testLexicon())
+	filters := settings["filter"].(map[string]interface{})
+	assert.Equal(t, []string{"报销, 报账", "VPN, virtual private network"}, filters["kb_synonyms"].(map[string]interface{})["synonyms"])
+	assert.Equal(t, []string{"the", "of"}, filters["kb_stop"].(map[string]interface{})["stopwords"])
+	search := settings["analyzer"].(map[string]interface{})["ik_search"].(map[string]interface{})
+	assert.Equal(t, []string{"lowercase", "kb_synonyms", "kb_stop"}, search["filter"])
+
+	// 空词库不生成过滤器
+	settings = analysisSettings(nil)
+	_, ok := settings["filter"]
+	assert.False(t, ok)
+
+	e := &ElasticsearchIndexer{}
+	e.SetLexiconProvider(staticLexicons{1: testLexicon()})
+	assert.Equal(t, testLexicon(), e.lexicon(context.Background(), 1))
+	assert.Nil(t, e.lexicon(context.Background(), 2))
+}
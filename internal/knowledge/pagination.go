@@ -0,0 +1,224 @@
+package knowledge
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid search cursor")
+	ErrCursorExpired = errors.New("search cursor expired")
+)
+
+const (
+	defaultPageSize       = 10
+	maxPageSize           = 50
+	maxPagedCandidates    = 200 // 首页召回并缓存的候选数量上限
+	searchSnapshotTTL     = 10 * time.Minute
+	maxSearchSnapshots    = 1000
+	searchSnapshotIDBytes = 12
+)
+
+// PageRequest 分页检索请求
+// 首页（Cursor为空）召回融合后的候选列表并缓存，后续页按游标读取缓存，翻页期间结果顺序不变
+type PageRequest struct {
+	HybridSearchRequest
+	PageSize int
+	Cursor   string
+	Facets   []string // 需要统计的分面字段，见FacetDocument等
+}
+
+// SearchPage 一页检索结果
+type SearchPage struct {
+	Matches       []SearchMatch
+	NextCursor    string // 为空时没有下一页
+	Offset        int
+	Total         int64  // 命中总数估计
+	TotalRelation string // eq | gte
+	Facets        map[string][]FacetCount
+}
+
+// searchSnapshot 首页检索的候选列表与统计，游标引用同一快照
+type searchSnapshot struct {
+	knowledgeBaseID uint
+	query           string
+	matches         []SearchMatch
+	total           int64
+	totalRelation   string
+	facets          map[string][]FacetCount
+	expiresAt       time.Time
+}
+
+// searchSnapshots 进程内的快照缓存，多实例部署时翻页请求需要路由到同一实例
+type searchSnapshots struct {
+	mu    sync.Mutex
+	items map[string]*searchSnapshot
+}
+
+func newSearchSnapshots() *searchSnapshots {
+	return &searchSnapshots{items: make(map[string]*searchSnapshot)}
+}
+
+func (s *searchSnapshots) put(snapshot *searchSnapshot) string {
+	buf := make([]byte, searchSnapshotIDBytes)
+	_, _ = rand.Read(buf)
+	id := hex.EncodeToString(buf)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.items) >= maxSearchSnapshots {
+		now := time.Now()
+		var oldestID string
+		var oldest time.Time
+		for key, item := range s.items {
+			if now.After(item.expiresAt) {
+				delete(s.items, key)
+				continue
+			}
+			if oldestID == "" || item.expiresAt.Before(oldest) {
+				oldestID, oldest = key, item.expiresAt
+			}
+		}
+		if len(s.items) >= maxSearchSnapshots {
+			delete(s.items, oldestID)
+		}
+	}
+	s.items[id] = snapshot
+	return id
+}
+
+func (s *searchSnapshots) get(id string) (*searchSnapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot, ok := s.items[id]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(snapshot.expiresAt) {
+		delete(s.items, id)
+		return nil, false
+	}
+	return snapshot, true
+}
+
+// encodeCursor 游标为快照ID与偏移量，对客户端不透明
+func encodeCursor(snapshotID string, offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(snapshotID + ":" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (string, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+	id, offsetText, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return "", 0, ErrInvalidCursor
+	}
+	offset, err := strconv.Atoi(offsetText)
+	if err != nil || offset < 0 {
+		return "", 0, ErrInvalidCursor
+	}
+	return id, offset, nil
+}
+
+// SearchPage 分页检索，Facets只统计全文检索的命中
+// 候选不足上限时总数为候选数量（精确）；否则取全文命中数与候选数量的较大值作为下限估计
+func (e *HybridSearchEngine) SearchPage(ctx context.Context, req PageRequest) (*SearchPage, error) {
+	if req.PageSize <= 0 {
+		req.PageSize = defaultPageSize
+	}
+	if req.PageSize > maxPageSize {
+		req.PageSize = maxPageSize
+	}
+
+	if req.Cursor != "" {
+		id, offset, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		snapshot, ok := e.snapshots.get(id)
+		if !ok {
+			return nil, ErrCursorExpired
+		}
+		if snapshot.knowledgeBaseID != req.KnowledgeBaseID || snapshot.query != req.Query {
+			return nil, ErrInvalidCursor
+		}
+		return snapshot.page(id, offset, req.PageSize), nil
+	}
+
+	var (
+		facets    *FacetResult
+		facetErr  error
+		wg        sync.WaitGroup
+		faceted   FacetedIndexer
+		canFacets bool
+	)
+	if e.indexer != nil && e.indexer.Ready() {
+		faceted, canFacets = e.indexer.(FacetedIndexer)
+	}
+	if canFacets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			facets, facetErr = faceted.Facets(ctx, FacetRequest{
+				KnowledgeBaseID: req.KnowledgeBaseID,
+				Query:           req.Query,
+				Fields:          req.Facets,
+			})
+		}()
+	}
+
+	search := req.HybridSearchRequest
+	search.Limit = maxPagedCandidates
+	matches, err := e.Search(ctx, search)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &searchSnapshot{
+		knowledgeBaseID: req.KnowledgeBaseID,
+		query:           req.Query,
+		matches:         append([]SearchMatch(nil), matches...),
+		total:           int64(len(matches)),
+		totalRelation:   TotalRelationEqual,
+		expiresAt:       time.Now().Add(searchSnapshotTTL),
+	}
+	// 分面统计失败不影响检索结果
+	if facetErr == nil && facets != nil {
+		snapshot.facets = facets.Facets
+	}
+	if len(matches) >= maxPagedCandidates {
+		snapshot.totalRelation = TotalRelationGreaterEqual
+		if facets != nil && facets.Total > snapshot.total {
+			snapshot.total = facets.Total
+		}
+	}
+	return snapshot.page(e.snapshots.put(snapshot), 0, req.PageSize), nil
+}
+
+func (s *searchSnapshot) page(id string, offset, size int) *SearchPage {
+	page := &SearchPage{
+		Offset:        offset,
+		Total:         s.total,
+		TotalRelation: s.totalRelation,
+		Facets:        s.facets,
+	}
+	if offset >= len(s.matches) {
+		return page
+	}
+	end := min(offset+size, len(s.matches))
+	page.Matches = s.matches[offset:end]
+	if end < len(s.matches) {
+		page.NextCursor = encodeCursor(id, end)
+	}
+	return page
+}
@@ -0,0 +1,137 @@
+package knowledge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// facetedIndexer 返回预设结果与分面统计，记录检索次数
+type facetedIndexer struct {
+	limitRecordingIndexer
+	searches int
+	facets   FacetResult
+}
+
+func (f *facetedIndexer) Search(ctx context.Context, req FulltextSearchRequest) ([]SearchMatch, error) {
+	f.searches++
+	return f.limitRecordingIndexer.Search(ctx, req)
+}
+
+func (f *facetedIndexer) Facets(ctx context.Context, req FacetRequest) (*FacetResult, error) {
+	result := f.facets
+	return &result, nil
+}
+
+func newFacetedIndexer(n int) *facetedIndexer {
+	indexer := &facetedIndexer{facets: FacetResult{
+		Total:         int64(n),
+		TotalRelation: TotalRelationEqual,
+		Facets:        map[string][]FacetCount{FacetFileType: {{Value: "pdf", Count: int64(n)}}},
+	}}
+	for i := 1; i <= n; i++ {
+		indexer.results = append(indexer.results, SearchMatch{ChunkID: uint(i), DocumentID: uint(i), Score: 1 - float64(i)/1000})
+	}
+	return indexer
+}
+
+func TestHybridSearchEngine_SearchPageCursor(t *testing.T) {
+	indexer := newFacetedIndexer(5)
+	engine := NewHybridSearchEngine(indexer, nil, nil, nil)
+	req := PageRequest{
+		HybridSearchRequest: HybridSearchRequest{KnowledgeBaseID: 1, Query: "年假", Mode: "fulltext"},
+		PageSize:            2,
+		Facets:              []string{FacetFileType},
+	}
+
+	first, err := engine.SearchPage(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1, 2}, matchIDs(first.Matches))
+	assert.Equal(t, int64(5), first.Total)
+	assert.Equal(t, TotalRelationEqual, first.TotalRelation)
+	assert.Equal(t, []FacetCount{{Value: "pdf", Count: 5}}, first.Facets[FacetFileType])
+	require.NotEmpty(t, first.NextCursor)
+
+	// 翻页读取快照，不再检索，索引变化不影响顺序
+	indexer.results[2], indexer.results[3] = indexer.results[3], indexer.results[2]
+	req.Cursor = first.NextCursor
+	second, err := engine.SearchPage(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{3, 4}, matchIDs(second.Matches))
+	assert.Equal(t, 2, second.Offset)
+	assert.Equal(t, 1, indexer.searches)
+
+	req.Cursor = second.NextCursor
+	last, err := engine.SearchPage(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{5}, matchIDs(last.Matches))
+	assert.Empty(t, last.NextCursor)
+}
+
+func TestHybridSearchEngine_SearchPageInvalidCursor(t *testing.T) {
+	engine := NewHybridSearchEngine(newFacetedIndexer(3), nil, nil, nil)
+	req := PageRequest{HybridSearchRequest: HybridSearchRequest{KnowledgeBaseID: 1, Query: "年假", Mode: "fulltext"}, PageSize: 1}
+	first, err := engine.SearchPage(context.Background(), req)
+	require.NoError(t, err)
+
+	// 游标与查询不匹配
+	req.Cursor, req.Query = first.NextCursor, "病假"
+	_, err = engine.SearchPage(context.Background(), req)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	req.Cursor = "not-a-cursor"
+	_, err = engine.SearchPage(context.Background(), req)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	req.Cursor = encodeCursor("missing", 1)
+	_, err = engine.SearchPage(context.Background(), req)
+	assert.ErrorIs(t, err, ErrCursorExpired)
+}
+
+func TestHybridSearchEngine_SearchPageTotalEstimate(t *testing.T) {
+	indexer := newFacetedIndexer(maxPagedCandidates + 50)
+	indexer.facets.Total = 5000
+	engine := NewHybridSearchEngine(indexer, nil, nil, nil)
+
+	page, err := engine.SearchPage(context.Background(), PageRequest{
+		HybridSearchRequest: HybridSearchRequest{KnowledgeBaseID: 1, Query: "年假", Mode: "fulltext"},
+	})
+	require.NoError(t, err)
+	// 候选达到上限时使用全文命中数作为下限估计
+	assert.Len(t, page.Matches, defaultPageSize)
+	assert.Equal(t, int64(5000), page.Total)
+	assert.Equal(t, TotalRelationGreaterEqual, page.TotalRelation)
+}
+
+func TestDatabaseIndexer_Facets(t *testing.T) {
+	db, mock := newMockGormDB(t)
+	mock.ExpectQuery(`SELECT COUNT\(\*\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+	mock.ExpectQuery(`SELECT d.source AS value, COUNT\(\*\) AS count`).
+		WillReturnRows(sqlmock.NewRows([]string{"value", "count"}).AddRow("upload", 5).AddRow("web", 2))
+	mock.ExpectQuery(`jsonb_array_elements_text`).
+		WillReturnRows(sqlmock.NewRows([]string{"value", "count"}).AddRow("hr", 4))
+
+	indexer := NewDatabaseIndexer(db).(*DatabaseIndexer)
+	result, err := indexer.Facets(context.Background(), FacetRequest{
+		KnowledgeBaseID: 1,
+		Query:           "年假",
+		Fields:          []string{FacetSource, FacetTag, "unknown"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), result.Total)
+	assert.Equal(t, TotalRelationEqual, result.TotalRelation)
+	assert.Equal(t, []FacetCount{{Value: "upload", Count: 5}, {Value: "web", Count: 2}}, result.Facets[FacetSource])
+	assert.Equal(t, []FacetCount{{Value: "hr", Count: 4}}, result.Facets[FacetTag])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTagsAndFileType(t *testing.T) {
+	assert.Equal(t, []string{"hr", "policy"}, tagsOf(map[string]interface{}{"tags": []interface{}{"hr", " policy ", "", 3}}))
+	assert.Nil(t, tagsOf(map[string]interface{}{"tags": "hr"}))
+	assert.Equal(t, "pdf", fileTypeOf("/data/员工手册.PDF"))
+	assert.Equal(t, "", fileTypeOf("README"))
+}
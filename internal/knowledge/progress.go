@@ -0,0 +1,343 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 文档处理阶段
+const (
+	ProgressStageParse  = "parse"  // 解析原文
+	ProgressStageChunk  = "chunk"  // 分块
+	ProgressStageEmbed  = "embed"  // 向量化，Current/Total为已完成/全部分块数
+	ProgressStageIndex  = "index"  // 写入索引
+	ProgressStageDone   = "done"   // 处理完成
+	ProgressStageFailed = "failed" // 处理失败，Reason为失败原因
+)
+
+const (
+	defaultProgressStreamLength = 1000           // 每个知识库保留的事件数
+	defaultProgressTTL          = 24 * time.Hour // 事件与最新状态的保留时间
+)
+
+// ErrInvalidProgressID 事件ID格式错误
+var ErrInvalidProgressID = errors.New("invalid progress event id")
+
+// ProgressEvent 文档处理进度事件
+// ID与Redis Stream的条目ID格式一致（毫秒时间戳-序号），同一知识库内单调递增
+type ProgressEvent struct {
+	ID              string    `json:"id"`
+	KnowledgeBaseID uint      `json:"knowledge_base_id"`
+	DocumentID      uint      `json:"document_id"`
+	Stage           string    `json:"stage"`
+	Current         int       `json:"current,omitempty"`
+	Total           int       `json:"total,omitempty"`
+	Progress        float64   `json:"progress"` // 整体进度0-1
+	Reason          string    `json:"reason,omitempty"`
+	Time            time.Time `json:"time"`
+}
+
+// Finished 文档是否已处理结束（完成或失败）
+func (e ProgressEvent) Finished() bool {
+	return e.Stage == ProgressStageDone || e.Stage == ProgressStageFailed
+}
+
+// ProgressBroker 进度事件的发布与订阅，按知识库分流
+type ProgressBroker interface {
+	// Publish 发布事件并更新文档的最新状态，返回带ID的事件
+	Publish(ctx context.Context, event ProgressEvent) (ProgressEvent, error)
+	// Latest 文档的最新状态，documentID为0时返回知识库下全部文档
+	Latest(ctx context.Context, knowledgeBaseID, documentID uint) ([]ProgressEvent, error)
+	// Cursor 知识库当前最后一个事件的ID，没有事件时为"0-0"
+	Cursor(ctx context.Context, knowledgeBaseID uint) (string, error)
+	// Read 读取afterID之后的事件，没有新事件时最多等待block
+	Read(ctx context.Context, knowledgeBaseID uint, afterID string, block time.Duration) ([]ProgressEvent, error)
+}
+
+// 各阶段在整体进度中的区间，向量化占大部分
+var progressStageRanges = map[string][2]float64{
+	ProgressStageParse: {0, 0.1},
+	ProgressStageChunk: {0.1, 0.2},
+	ProgressStageEmbed: {0.2, 0.9},
+	ProgressStageIndex: {0.9, 1},
+}
+
+// StageProgress 按阶段与阶段内完成数计算整体进度
+func StageProgress(stage string, current, total int) float64 {
+	switch stage {
+	case ProgressStageDone, ProgressStageFailed:
+		return 1
+	}
+	r, ok := progressStageRanges[stage]
+	if !ok {
+		return 0
+	}
+	if total <= 0 {
+		return r[0]
+	}
+	if current > total {
+		current = total
+	}
+	return r[0] + (r[1]-r[0])*float64(current)/float64(total)
+}
+
+// DocumentProgress 单个文档的进度上报器，发布失败只记录不影响处理
+// nil接收者的方法不做任何事，未配置进度推送时可直接调用
+type DocumentProgress struct {
+	broker          ProgressBroker
+	knowledgeBaseID uint
+	documentID      uint
+	onError         func(error)
+
+	mu        sync.Mutex
+	lastEmbed time.Time
+}
+
+// embedReportInterval 向量化进度的最小上报间隔，避免大文档刷屏
+const embedReportInterval = 500 * time.Millisecond
+
+// NewDocumentProgress 创建文档进度上报器，broker为nil时返回nil
+func NewDocumentProgress(broker ProgressBroker, knowledgeBaseID, documentID uint, onError func(error)) *DocumentProgress {
+	if broker == nil {
+		return nil
+	}
+	return &DocumentProgress{
+		broker:          broker,
+		knowledgeBaseID: knowledgeBaseID,
+		documentID:      documentID,
+		onError:         onError,
+	}
+}
+
+// Stage 进入新阶段
+func (p *DocumentProgress) Stage(ctx context.Context, stage string) {
+	p.publish(ctx, ProgressEvent{Stage: stage})
+}
+
+// Embed 向量化进度，全部完成时总会上报
+func (p *DocumentProgress) Embed(ctx context.Context, done, total int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	if done < total && time.Since(p.lastEmbed) < embedReportInterval {
+		p.mu.Unlock()
+		return
+	}
+	p.lastEmbed = time.Now()
+	p.mu.Unlock()
+	p.publish(ctx, ProgressEvent{Stage: ProgressStageEmbed, Current: done, Total: total})
+}
+
+// Done 处理完成
+func (p *DocumentProgress) Done(ctx context.Context) {
+	p.publish(ctx, ProgressEvent{Stage: ProgressStageDone})
+}
+
+// Fail 处理失败
+func (p *DocumentProgress) Fail(ctx context.Context, err error) {
+	event := ProgressEvent{Stage: ProgressStageFailed}
+	if err != nil {
+		event.Reason = err.Error()
+	}
+	p.publish(ctx, event)
+}
+
+func (p *DocumentProgress) publish(ctx context.Context, event ProgressEvent) {
+	if p == nil {
+		return
+	}
+	event.KnowledgeBaseID = p.knowledgeBaseID
+	event.DocumentID = p.documentID
+	event.Progress = StageProgress(event.Stage, event.Current, event.Total)
+	// 处理上下文取消时仍要发布失败事件
+	if _, err := p.broker.Publish(context.WithoutCancel(ctx), event); err != nil && p.onError != nil {
+		p.onError(err)
+	}
+}
+
+// progressID 解析后的事件ID
+type progressID struct {
+	ms, seq uint64
+}
+
+func parseProgressID(id string) (progressID, error) {
+	msPart, seqPart, found := strings.Cut(id, "-")
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return progressID{}, fmt.Errorf("%w: %q", ErrInvalidProgressID, id)
+	}
+	var seq uint64
+	if found {
+		if seq, err = strconv.ParseUint(seqPart, 10, 64); err != nil {
+			return progressID{}, fmt.Errorf("%w: %q", ErrInvalidProgressID, id)
+		}
+	}
+	return progressID{ms: ms, seq: seq}, nil
+}
+
+// ValidProgressID 是否为合法的事件ID（毫秒时间戳-序号，序号可省略）
+func ValidProgressID(id string) bool {
+	_, err := parseProgressID(id)
+	return err == nil
+}
+
+func (id progressID) after(other progressID) bool {
+	return id.ms > other.ms || (id.ms == other.ms && id.seq > other.seq)
+}
+
+func (id progressID) String() string {
+	return fmt.Sprintf("%d-%d", id.ms, id.seq)
+}
+
+// MemoryProgressBroker 进程内的进度事件存储，未配置Redis时使用，不跨副本共享、重启后丢失
+type MemoryProgressBroker struct {
+	maxLen int
+	ttl    time.Duration
+
+	mu     sync.Mutex
+	topics map[uint]*memoryProgressTopic
+}
+
+type memoryProgressTopic struct {
+	lastID progressID
+	events []ProgressEvent
+	latest map[uint]ProgressEvent
+	notify chan struct{} // 有新事件时关闭并替换
+}
+
+// NewMemoryProgressBroker 创建进程内进度事件存储，maxLen<=0时每个知识库保留1000个事件
+func NewMemoryProgressBroker(maxLen int) *MemoryProgressBroker {
+	if maxLen <= 0 {
+		maxLen = defaultProgressStreamLength
+	}
+	return &MemoryProgressBroker{
+		maxLen: maxLen,
+		ttl:    defaultProgressTTL,
+		topics: make(map[uint]*memoryProgressTopic),
+	}
+}
+
+func (b *MemoryProgressBroker) topic(knowledgeBaseID uint) *memoryProgressTopic {
+	t, ok := b.topics[knowledgeBaseID]
+	if !ok {
+		t = &memoryProgressTopic{
+			latest: make(map[uint]ProgressEvent),
+			notify: make(chan struct{}),
+		}
+		b.topics[knowledgeBaseID] = t
+	}
+	return t
+}
+
+func (b *MemoryProgressBroker) Publish(ctx context.Context, event ProgressEvent) (ProgressEvent, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(event.KnowledgeBaseID)
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	id := progressID{ms: uint64(event.Time.UnixMilli())}
+	if !id.after(t.lastID) {
+		id = progressID{ms: t.lastID.ms, seq: t.lastID.seq + 1}
+	}
+	t.lastID = id
+	event.ID = id.String()
+
+	t.events = append(t.events, event)
+	if len(t.events) > b.maxLen {
+		t.events = append([]ProgressEvent(nil), t.events[len(t.events)-b.maxLen:]...)
+	}
+	t.latest[event.DocumentID] = event
+	for docID, latest := range t.latest {
+		if time.Since(latest.Time) > b.ttl {
+			delete(t.latest, docID)
+		}
+	}
+
+	close(t.notify)
+	t.notify = make(chan struct{})
+	return event, nil
+}
+
+func (b *MemoryProgressBroker) Latest(ctx context.Context, knowledgeBaseID, documentID uint) ([]ProgressEvent, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[knowledgeBaseID]
+	if !ok {
+		return nil, nil
+	}
+	var events []ProgressEvent
+	for docID, event := range t.latest {
+		if (documentID == 0 || docID == documentID) && time.Since(event.Time) <= b.ttl {
+			events = append(events, event)
+		}
+	}
+	sortProgressEvents(events)
+	return events, nil
+}
+
+func (b *MemoryProgressBroker) Cursor(ctx context.Context, knowledgeBaseID uint) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if t, ok := b.topics[knowledgeBaseID]; ok {
+		return t.lastID.String(), nil
+	}
+	return progressID{}.String(), nil
+}
+
+func (b *MemoryProgressBroker) Read(ctx context.Context, knowledgeBaseID uint, afterID string, block time.Duration) ([]ProgressEvent, error) {
+	after, err := parseProgressID(afterID)
+	if err != nil {
+		return nil, err
+	}
+
+	var timeout <-chan time.Time
+	if block > 0 {
+		timer := time.NewTimer(block)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		b.mu.Lock()
+		t := b.topic(knowledgeBaseID)
+		var events []ProgressEvent
+		for _, event := range t.events {
+			if id, _ := parseProgressID(event.ID); id.after(after) {
+				events = append(events, event)
+			}
+		}
+		notify := t.notify
+		b.mu.Unlock()
+
+		if len(events) > 0 || block <= 0 {
+			return events, nil
+		}
+		select {
+		case <-notify:
+		case <-timeout:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// sortProgressEvents 按事件ID排序
+func sortProgressEvents(events []ProgressEvent) {
+	sort.Slice(events, func(i, j int) bool {
+		a, _ := parseProgressID(events[i].ID)
+		b, _ := parseProgressID(events[j].ID)
+		return b.after(a)
+	})
+}
@@ -0,0 +1,161 @@
+package knowledge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisProgressBroker 基于Redis Stream的进度事件存储，多副本共享，重启后保留
+// 每个知识库一个Stream（knowledge:progress:{kbID}），最新状态保存在同名:latest哈希中，字段为文档ID
+type RedisProgressBroker struct {
+	client *redis.Client
+	maxLen int64
+	ttl    time.Duration
+}
+
+// NewRedisProgressBroker 创建Redis进度事件存储，maxLen<=0时每个知识库保留约1000个事件
+func NewRedisProgressBroker(client *redis.Client, maxLen int64) *RedisProgressBroker {
+	if maxLen <= 0 {
+		maxLen = defaultProgressStreamLength
+	}
+	return &RedisProgressBroker{
+		client: client,
+		maxLen: maxLen,
+		ttl:    defaultProgressTTL,
+	}
+}
+
+func progressStreamKey(knowledgeBaseID uint) string {
+	return fmt.Sprintf("knowledge:progress:%d", knowledgeBaseID)
+}
+
+func progressLatestKey(knowledgeBaseID uint) string {
+	return fmt.Sprintf("knowledge:progress:%d:latest", knowledgeBaseID)
+}
+
+func (b *RedisProgressBroker) Publish(ctx context.Context, event ProgressEvent) (ProgressEvent, error) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	event.ID = ""
+	data, err := json.Marshal(event)
+	if err != nil {
+		return event, err
+	}
+
+	streamKey := progressStreamKey(event.KnowledgeBaseID)
+	id, err := b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: streamKey,
+		MaxLen: b.maxLen,
+		Approx: true,
+		Values: map[string]interface{}{"event": data},
+	}).Result()
+	if err != nil {
+		return event, fmt.Errorf("failed to append progress event: %w", err)
+	}
+	event.ID = id
+
+	// 最新状态带上事件ID，客户端可从该ID继续订阅
+	latest, err := json.Marshal(event)
+	if err != nil {
+		return event, err
+	}
+	latestKey := progressLatestKey(event.KnowledgeBaseID)
+	_, err = b.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, latestKey, strconv.FormatUint(uint64(event.DocumentID), 10), latest)
+		pipe.Expire(ctx, latestKey, b.ttl)
+		pipe.Expire(ctx, streamKey, b.ttl)
+		return nil
+	})
+	if err != nil {
+		return event, fmt.Errorf("failed to save latest progress: %w", err)
+	}
+	return event, nil
+}
+
+func (b *RedisProgressBroker) Latest(ctx context.Context, knowledgeBaseID, documentID uint) ([]ProgressEvent, error) {
+	latestKey := progressLatestKey(knowledgeBaseID)
+
+	var values []string
+	if documentID > 0 {
+		value, err := b.client.HGet(ctx, latestKey, strconv.FormatUint(uint64(documentID), 10)).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	} else {
+		all, err := b.client.HGetAll(ctx, latestKey).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range all {
+			values = append(values, value)
+		}
+	}
+
+	events := make([]ProgressEvent, 0, len(values))
+	for _, value := range values {
+		var event ProgressEvent
+		if err := json.Unmarshal([]byte(value), &event); err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+	sortProgressEvents(events)
+	return events, nil
+}
+
+func (b *RedisProgressBroker) Cursor(ctx context.Context, knowledgeBaseID uint) (string, error) {
+	messages, err := b.client.XRevRangeN(ctx, progressStreamKey(knowledgeBaseID), "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+	if len(messages) == 0 {
+		return progressID{}.String(), nil
+	}
+	return messages[0].ID, nil
+}
+
+func (b *RedisProgressBroker) Read(ctx context.Context, knowledgeBaseID uint, afterID string, block time.Duration) ([]ProgressEvent, error) {
+	if _, err := parseProgressID(afterID); err != nil {
+		return nil, err
+	}
+	// XREAD的BLOCK 0表示无限等待，不等待时不能传0
+	if block <= 0 {
+		block = -1
+	}
+	streams, err := b.client.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{progressStreamKey(knowledgeBaseID), afterID},
+		Count:   100,
+		Block:   block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var events []ProgressEvent
+	for _, stream := range streams {
+		for _, message := range stream.Messages {
+			data, _ := message.Values["event"].(string)
+			var event ProgressEvent
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				continue
+			}
+			event.ID = message.ID
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,106 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStageProgress(t *testing.T) {
+	assert.Equal(t, 0.0, StageProgress(ProgressStageParse, 0, 0))
+	assert.InDelta(t, 0.55, StageProgress(ProgressStageEmbed, 5, 10), 1e-9)
+	assert.InDelta(t, 0.9, StageProgress(ProgressStageEmbed, 12, 10), 1e-9)
+	assert.Equal(t, 1.0, StageProgress(ProgressStageFailed, 0, 0))
+}
+
+func TestMemoryProgressBroker_PublishAndRead(t *testing.T) {
+	ctx := context.Background()
+	broker := NewMemoryProgressBroker(2)
+
+	cursor, err := broker.Cursor(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "0-0", cursor)
+
+	now := time.Now()
+	first, err := broker.Publish(ctx, ProgressEvent{KnowledgeBaseID: 1, DocumentID: 10, Stage: ProgressStageParse, Time: now})
+	require.NoError(t, err)
+	// 同一毫秒内的事件按序号递增
+	second, err := broker.Publish(ctx, ProgressEvent{KnowledgeBaseID: 1, DocumentID: 10, Stage: ProgressStageChunk, Time: now})
+	require.NoError(t, err)
+	_, err = broker.Publish(ctx, ProgressEvent{KnowledgeBaseID: 2, DocumentID: 20, Stage: ProgressStageParse})
+	require.NoError(t, err)
+
+	events, err := broker.Read(ctx, 1, first.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, second.ID, events[0].ID)
+
+	// 超出保留长度的旧事件被丢弃，最新状态保留
+	third, err := broker.Publish(ctx, ProgressEvent{KnowledgeBaseID: 1, DocumentID: 11, Stage: ProgressStageDone})
+	require.NoError(t, err)
+	events, err = broker.Read(ctx, 1, "0-0", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{second.ID, third.ID}, []string{events[0].ID, events[1].ID})
+
+	latest, err := broker.Latest(ctx, 1, 0)
+	require.NoError(t, err)
+	require.Len(t, latest, 2)
+	assert.Equal(t, ProgressStageChunk, latest[0].Stage)
+	assert.True(t, latest[1].Finished())
+
+	latest, err = broker.Latest(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, latest, 1)
+	assert.Equal(t, second.ID, latest[0].ID)
+
+	_, err = broker.Read(ctx, 1, "abc", 0)
+	assert.ErrorIs(t, err, ErrInvalidProgressID)
+}
+
+func TestMemoryProgressBroker_ReadBlocksUntilPublish(t *testing.T) {
+	ctx := context.Background()
+	broker := NewMemoryProgressBroker(0)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		broker.Publish(ctx, ProgressEvent{KnowledgeBaseID: 1, DocumentID: 10, Stage: ProgressStageParse})
+	}()
+	events, err := broker.Read(ctx, 1, "0-0", time.Second)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	// 超时返回空
+	events, err = broker.Read(ctx, 1, events[0].ID, 10*time.Millisecond)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestDocumentProgress(t *testing.T) {
+	ctx := context.Background()
+	broker := NewMemoryProgressBroker(0)
+	progress := NewDocumentProgress(broker, 1, 10, nil)
+
+	progress.Stage(ctx, ProgressStageChunk)
+	progress.Embed(ctx, 0, 4)
+	// 上报间隔内的中间进度被合并，全部完成时总会上报
+	progress.Embed(ctx, 2, 4)
+	progress.Embed(ctx, 4, 4)
+	progress.Fail(ctx, errors.New("embedding service unavailable"))
+
+	events, err := broker.Read(ctx, 1, "0-0", 0)
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	assert.Equal(t, 4, events[2].Current)
+	assert.InDelta(t, 0.9, events[2].Progress, 1e-9)
+	assert.Equal(t, ProgressStageFailed, events[3].Stage)
+	assert.Equal(t, "embedding service unavailable", events[3].Reason)
+
+	// 未配置推送时为nil，调用不做任何事
+	var none *DocumentProgress = NewDocumentProgress(nil, 1, 10, nil)
+	assert.Nil(t, none)
+	none.Done(ctx)
+}
@@ -0,0 +1,308 @@
+package knowledge
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aihub/backend-go/internal/dashscope"
+	"gorm.io/gorm"
+)
+
+// ChatModel 查询改写使用的对话模型，dashscope.Service实现了该接口
+type ChatModel interface {
+	ChatCompletion(ctx context.Context, req dashscope.ChatRequest) (*dashscope.ChatResponse, error)
+	Ready() bool
+}
+
+// ChatTurn 对话历史中的一轮消息
+type ChatTurn struct {
+	Role    string `json:"role"` // user | assistant
+	Content string `json:"content"`
+}
+
+// QueryTransformOptions 检索前的查询转换，各阶段可单独开启
+// 请求未指定时使用知识库配置（knowledge_bases.config中的query_transform）
+type QueryTransformOptions struct {
+	Rewrite    bool `json:"rewrite"`     // 结合对话历史把追问改写为独立查询
+	MultiQuery int  `json:"multi_query"` // 额外生成的相似查询数量，结果合并
+	HyDE       bool `json:"hyde"`        // 向量检索使用生成的假设答案
+}
+
+// IsZero 是否未开启任何阶段
+func (o QueryTransformOptions) IsZero() bool {
+	return !o.Rewrite && o.MultiQuery <= 0 && !o.HyDE
+}
+
+// TransformedQuery 查询转换结果
+type TransformedQuery struct {
+	Original     string   `json:"original"`
+	Standalone   string   `json:"standalone"`             // 改写后的独立查询，未改写时等于原查询
+	Variants     []string `json:"variants,omitempty"`     // 多查询扩展生成的查询
+	Hypothetical string   `json:"hypothetical,omitempty"` // HyDE生成的假设答案
+}
+
+// Queries 参与检索的全部查询，独立查询在前
+func (t *TransformedQuery) Queries() []string {
+	return append([]string{t.Standalone}, t.Variants...)
+}
+
+const (
+	maxMultiQueries          = 5
+	maxHistoryTurns          = 6
+	queryTransformCacheSize  = 2048
+	queryTransformCacheTTL   = 10 * time.Minute
+	queryTransformOptionsTTL = time.Minute
+	queryTransformTimeout    = 15 * time.Second
+)
+
+const (
+	rewritePrompt = "你是检索查询改写助手。根据对话历史，把用户最后的问题改写为一个不依赖上下文、可以直接用于检索的独立问题。" +
+		"补全代词和省略的主语，保持原问题的语言，不要回答问题。只输出改写后的问题。"
+	multiQueryPrompt = "你是检索查询扩展助手。为用户的问题生成%d个表述不同但含义相同的检索查询，" +
+		"可以使用同义词、换一种问法或补充关键术语。保持原问题的语言，每行一个查询，不要编号，不要输出其他内容。"
+	hydePrompt = "请写一段简洁的文字直接回答下面的问题，就像它摘自一份相关的知识库文档。" +
+		"不确定时也给出最可能的答案，不超过200字，不要说明这是假设。"
+)
+
+// listMarkerPattern 模型输出中的列表符号或编号前缀，如"- "、"1. "、"2、"、"3)"
+var listMarkerPattern = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.、)])\s*`)
+
+// QueryTransformer 基于对话模型的查询转换：追问改写、多查询扩展与HyDE
+// 模型输出按阶段缓存，调用失败时该阶段跳过，检索使用原查询
+type QueryTransformer struct {
+	model     ChatModel
+	modelName string
+	db        *gorm.DB // 读取知识库默认配置，为nil时只使用请求指定的选项
+
+	mu      sync.Mutex
+	cache   map[string]transformCacheEntry
+	options map[uint]transformOptionsEntry
+}
+
+type transformCacheEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+type transformOptionsEntry struct {
+	options   QueryTransformOptions
+	expiresAt time.Time
+}
+
+// NewQueryTransformer 创建查询转换器，modelName为空时使用qwen-turbo
+func NewQueryTransformer(model ChatModel, modelName string, db *gorm.DB) *QueryTransformer {
+	if modelName == "" {
+		modelName = "qwen-turbo"
+	}
+	return &QueryTransformer{
+		model:     model,
+		modelName: modelName,
+		db:        db,
+		cache:     make(map[string]transformCacheEntry),
+		options:   make(map[uint]transformOptionsEntry),
+	}
+}
+
+// Ready 对话模型是否可用
+func (t *QueryTransformer) Ready() bool {
+	return t != nil && t.model != nil && t.model.Ready()
+}
+
+// Options 解析本次检索的转换选项：请求指定时优先，否则读取知识库配置
+func (t *QueryTransformer) Options(ctx context.Context, knowledgeBaseID uint, requested *QueryTransformOptions) QueryTransformOptions {
+	var opts QueryTransformOptions
+	if requested != nil {
+		opts = *requested
+	} else {
+		opts = t.knowledgeBaseOptions(ctx, knowledgeBaseID)
+	}
+	if opts.MultiQuery > maxMultiQueries {
+		opts.MultiQuery = maxMultiQueries
+	}
+	return opts
+}
+
+// knowledgeBaseOptions 读取知识库配置中的query_transform，短期缓存；读取失败按未开启处理
+func (t *QueryTransformer) knowledgeBaseOptions(ctx context.Context, knowledgeBaseID uint) QueryTransformOptions {
+	if t.db == nil || knowledgeBaseID == 0 {
+		return QueryTransformOptions{}
+	}
+	t.mu.Lock()
+	entry, ok := t.options[knowledgeBaseID]
+	t.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.options
+	}
+
+	var config string
+	err := t.db.WithContext(ctx).Table("knowledge_bases").
+		Select("config").
+		Where("knowledge_base_id = ?", knowledgeBaseID).
+		Scan(&config).Error
+	if err != nil {
+		return QueryTransformOptions{}
+	}
+	var parsed struct {
+		QueryTransform QueryTransformOptions `json:"query_transform"`
+	}
+	if config != "" {
+		_ = json.Unmarshal([]byte(config), &parsed)
+	}
+
+	t.mu.Lock()
+	t.options[knowledgeBaseID] = transformOptionsEntry{options: parsed.QueryTransform, expiresAt: time.Now().Add(queryTransformOptionsTTL)}
+	t.mu.Unlock()
+	return parsed.QueryTransform
+}
+
+// Transform 执行查询转换：先结合历史改写，再并发生成相似查询与假设答案
+func (t *QueryTransformer) Transform(ctx context.Context, query string, history []ChatTurn, opts QueryTransformOptions) *TransformedQuery {
+	result := &TransformedQuery{Original: query, Standalone: query}
+	if !t.Ready() || opts.IsZero() {
+		return result
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTransformTimeout)
+	defer cancel()
+
+	if opts.Rewrite && len(history) > 0 {
+		if rewritten, err := t.rewrite(ctx, query, history); err == nil && rewritten != "" {
+			result.Standalone = rewritten
+		}
+	}
+
+	var wg sync.WaitGroup
+	if opts.MultiQuery > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if variants, err := t.multiQuery(ctx, result.Standalone, opts.MultiQuery); err == nil {
+				result.Variants = variants
+			}
+		}()
+	}
+	if opts.HyDE {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if answer, err := t.hypothetical(ctx, result.Standalone); err == nil {
+				result.Hypothetical = answer
+			}
+		}()
+	}
+	wg.Wait()
+	return result
+}
+
+func (t *QueryTransformer) rewrite(ctx context.Context, query string, history []ChatTurn) (string, error) {
+	if len(history) > maxHistoryTurns {
+		history = history[len(history)-maxHistoryTurns:]
+	}
+	var b strings.Builder
+	for _, turn := range history {
+		role := "用户"
+		if turn.Role == "assistant" {
+			role = "助手"
+		}
+		b.WriteString(role + "：" + strings.TrimSpace(turn.Content) + "\n")
+	}
+	b.WriteString("用户最后的问题：" + query)
+
+	output, err := t.complete(ctx, "rewrite", rewritePrompt, b.String())
+	if err != nil {
+		return "", err
+	}
+	return firstLine(output), nil
+}
+
+func (t *QueryTransformer) multiQuery(ctx context.Context, query string, n int) ([]string, error) {
+	output, err := t.complete(ctx, fmt.Sprintf("multi:%d", n), fmt.Sprintf(multiQueryPrompt, n), query)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{strings.ToLower(query): true}
+	var variants []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(listMarkerPattern.ReplaceAllString(line, ""))
+		if line == "" || seen[strings.ToLower(line)] {
+			continue
+		}
+		seen[strings.ToLower(line)] = true
+		variants = append(variants, line)
+		if len(variants) == n {
+			break
+		}
+	}
+	return variants, nil
+}
+
+func (t *QueryTransformer) hypothetical(ctx context.Context, query string) (string, error) {
+	output, err := t.complete(ctx, "hyde", hydePrompt, query)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// complete 调用对话模型，相同阶段与输入的结果在缓存有效期内复用
+func (t *QueryTransformer) complete(ctx context.Context, stage, system, user string) (string, error) {
+	sum := sha1.Sum([]byte(stage + "\x00" + user))
+	key := hex.EncodeToString(sum[:])
+
+	t.mu.Lock()
+	entry, ok := t.cache[key]
+	t.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.value, nil
+	}
+
+	temperature := 0.2
+	resp, err := t.model.ChatCompletion(ctx, dashscope.ChatRequest{
+		Model: t.modelName,
+		Messages: []dashscope.ChatMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		},
+		Temperature: &temperature,
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp == nil || len(resp.Choices) == 0 {
+		return "", errors.New("chat response empty")
+	}
+	value := strings.TrimSpace(resp.Choices[0].Message.Content)
+
+	t.mu.Lock()
+	if len(t.cache) >= queryTransformCacheSize {
+		now := time.Now()
+		for k, e := range t.cache {
+			if now.After(e.expiresAt) {
+				delete(t.cache, k)
+			}
+		}
+		if len(t.cache) >= queryTransformCacheSize {
+			t.cache = make(map[string]transformCacheEntry)
+		}
+	}
+	t.cache[key] = transformCacheEntry{value: value, expiresAt: time.Now().Add(queryTransformCacheTTL)}
+	t.mu.Unlock()
+	return value, nil
+}
+
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
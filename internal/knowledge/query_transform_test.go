@@ -0,0 +1,168 @@
+package knowledge
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aihub/backend-go/internal/dashscope"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChatModel 按系统提示词区分阶段返回预设输出
+type fakeChatModel struct {
+	mu    sync.Mutex
+	calls map[string]int
+	last  map[string]string // 阶段 -> 最近一次的用户输入
+	multi string            // 非空时替换多查询扩展的输出
+}
+
+func newFakeChatModel() *fakeChatModel {
+	return &fakeChatModel{calls: make(map[string]int), last: make(map[string]string)}
+}
+
+func (m *fakeChatModel) ChatCompletion(ctx context.Context, req dashscope.ChatRequest) (*dashscope.ChatResponse, error) {
+	system, user := req.Messages[0].Content, req.Messages[1].Content
+	var stage, output string
+	switch {
+	case strings.Contains(system, "改写"):
+		stage, output = "rewrite", "年假怎么申请\n"
+	case strings.Contains(system, "扩展"):
+		stage, output = "multi", "1. 年假申请流程\n- 年假怎么申请\n如何请年休假\n多余的查询"
+		if m.multi != "" {
+			output = m.multi
+		}
+	default:
+		stage, output = "hyde", "员工在OA系统提交年假申请，经直属主管审批后生效。"
+	}
+	m.mu.Lock()
+	m.calls[stage]++
+	m.last[stage] = user
+	m.mu.Unlock()
+	return &dashscope.ChatResponse{Choices: []dashscope.ChatChoice{{Message: dashscope.ChatMessage{Role: "assistant", Content: output}}}}, nil
+}
+
+func (m *fakeChatModel) Ready() bool { return true }
+
+// queryRecordingIndexer 记录全文检索的查询，按查询返回预设结果
+type queryRecordingIndexer struct {
+	NoopFulltextIndexer
+	mu      sync.Mutex
+	queries []string
+	results map[string][]SearchMatch
+}
+
+func (f *queryRecordingIndexer) Search(ctx context.Context, req FulltextSearchRequest) ([]SearchMatch, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries = append(f.queries, req.Query)
+	return f.results[req.Query], nil
+}
+
+func (f *queryRecordingIndexer) Ready() bool { return true }
+
+// textRecordingEmbedder 记录被向量化的文本
+type textRecordingEmbedder struct {
+	fixedEmbedder
+	mu    sync.Mutex
+	texts []string
+}
+
+func (e *textRecordingEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	e.mu.Lock()
+	e.texts = append(e.texts, text)
+	e.mu.Unlock()
+	return e.fixedEmbedder.Embed(ctx, text)
+}
+
+func TestHybridSearchEngine_RewriteAndMultiQuery(t *testing.T) {
+	model := newFakeChatModel()
+	indexer := &queryRecordingIndexer{results: map[string][]SearchMatch{
+		"年假怎么申请": {{ChunkID: 1, Score: 0.8}},
+		"年假申请流程": {{ChunkID: 1, Score: 0.9}, {ChunkID: 2, Score: 0.7}},
+		"如何请年休假": {{ChunkID: 3, Score: 0.6}},
+	}}
+	engine := NewHybridSearchEngine(indexer, nil, nil, nil)
+	engine.SetQueryTransformer(NewQueryTransformer(model, "", nil))
+
+	req := HybridSearchRequest{
+		KnowledgeBaseID: 1,
+		Query:           "那年假呢",
+		Mode:            "fulltext",
+		History:         []ChatTurn{{Role: "user", Content: "病假怎么请"}, {Role: "assistant", Content: "在OA提交病假单"}},
+		Transform:       &QueryTransformOptions{Rewrite: true, MultiQuery: 2},
+	}
+	matches, transformed, err := engine.SearchTransformed(context.Background(), req)
+	require.NoError(t, err)
+
+	assert.Equal(t, "年假怎么申请", transformed.Standalone)
+	// 与独立查询重复的扩展被去掉，编号前缀被清理
+	assert.Equal(t, []string{"年假申请流程", "如何请年休假"}, transformed.Variants)
+	assert.Contains(t, model.last["rewrite"], "病假怎么请")
+	assert.Equal(t, "年假怎么申请", model.last["multi"])
+	assert.ElementsMatch(t, []string{"年假怎么申请", "年假申请流程", "如何请年休假"}, indexer.queries)
+
+	ids := make([]uint, len(matches))
+	for i, m := range matches {
+		ids[i] = m.ChunkID
+	}
+	assert.Equal(t, []uint{1, 2, 3}, ids)
+	assert.Equal(t, 0.9, matches[0].Score)
+
+	// 相同输入命中缓存
+	_, _, err = engine.SearchTransformed(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, 1, model.calls["rewrite"])
+	assert.Equal(t, 1, model.calls["multi"])
+}
+
+func TestQueryTransformer_MultiQueryKeepsLeadingDigits(t *testing.T) {
+	model := newFakeChatModel()
+	model.multi = "1. 2024年年假政策\n2、3月份请假流程\n3) 5G网络报销\n* 404错误处理"
+	transformer := NewQueryTransformer(model, "", nil)
+
+	variants, err := transformer.multiQuery(context.Background(), "年假", 4)
+	require.NoError(t, err)
+	// 只去掉编号和列表符号，查询本身以数字开头时保留
+	assert.Equal(t, []string{"2024年年假政策", "3月份请假流程", "5G网络报销", "404错误处理"}, variants)
+}
+
+func TestHybridSearchEngine_HyDEEmbedsHypotheticalAnswer(t *testing.T) {
+	model := newFakeChatModel()
+	indexer := &queryRecordingIndexer{}
+	embedder := &textRecordingEmbedder{fixedEmbedder: fixedEmbedder{dims: 4}}
+	store := &versionedSearchStore{queries: make(map[int]int), results: map[int][]SearchMatch{0: {{ChunkID: 5, Score: 0.95}}}}
+	engine := NewHybridSearchEngine(indexer, store, embedder, nil)
+	engine.SetQueryTransformer(NewQueryTransformer(model, "", nil))
+
+	_, transformed, err := engine.SearchTransformed(context.Background(), HybridSearchRequest{
+		KnowledgeBaseID: 1,
+		Query:           "年假怎么申请",
+		Mode:            "hybrid",
+		Transform:       &QueryTransformOptions{HyDE: true},
+	})
+	require.NoError(t, err)
+
+	// 向量检索使用假设答案，全文检索仍使用原查询
+	assert.Equal(t, []string{transformed.Hypothetical}, embedder.texts)
+	assert.Equal(t, []string{"年假怎么申请"}, indexer.queries)
+	assert.Zero(t, model.calls["rewrite"])
+}
+
+func TestQueryTransformer_KnowledgeBaseOptions(t *testing.T) {
+	db, mock := newMockGormDB(t)
+	mock.ExpectQuery(`SELECT config FROM "knowledge_bases"`).
+		WillReturnRows(sqlmock.NewRows([]string{"config"}).AddRow(`{"query_transform":{"rewrite":true,"multi_query":9}}`))
+
+	transformer := NewQueryTransformer(newFakeChatModel(), "", db)
+	opts := transformer.Options(context.Background(), 2, nil)
+	assert.Equal(t, QueryTransformOptions{Rewrite: true, MultiQuery: maxMultiQueries}, opts)
+	// 缓存有效期内不再查询
+	assert.Equal(t, opts, transformer.Options(context.Background(), 2, nil))
+	// 请求指定的选项优先
+	assert.Equal(t, QueryTransformOptions{HyDE: true}, transformer.Options(context.Background(), 2, &QueryTransformOptions{HyDE: true}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,540 @@
+package knowledge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 一致性检查的存储名称
+const (
+	ReconcileFulltext = "fulltext"
+	ReconcileVector   = "vector"
+)
+
+// 差异类型
+const (
+	DiscrepancyMissing = "missing" // 数据库中的有效分块在存储中不存在
+	DiscrepancyOrphan  = "orphan"  // 存储中的数据在数据库中没有对应的有效记录
+	DiscrepancyStale   = "stale"   // 存储中的内容与数据库不一致
+)
+
+// maxReportedDiscrepancies 报告中保留的差异明细上限，计数不受影响
+const maxReportedDiscrepancies = 1000
+
+// Discrepancy 单条差异
+type Discrepancy struct {
+	Store      string `json:"store"`
+	Kind       string `json:"kind"`
+	DocumentID uint   `json:"document_id,omitempty"`
+	ChunkID    uint   `json:"chunk_id,omitempty"`
+	Object     string `json:"object,omitempty"` // 对象存储中的键
+}
+
+// StoreReport 单个存储的检查结果
+type StoreReport struct {
+	Store        string `json:"store"`
+	Checked      int    `json:"checked"` // 存储中检查的条目数
+	Missing      int    `json:"missing"`
+	Orphaned     int    `json:"orphaned"`
+	Stale        int    `json:"stale"`
+	Repaired     int    `json:"repaired"`
+	RepairFailed int    `json:"repair_failed"`
+	Error        string `json:"error,omitempty"`
+}
+
+// Discrepancies 差异总数
+func (r StoreReport) Discrepancies() int {
+	return r.Missing + r.Orphaned + r.Stale
+}
+
+// ReconcileReport 知识库的一致性检查报告
+type ReconcileReport struct {
+	KnowledgeBaseID uint          `json:"knowledge_base_id"`
+	Repair          bool          `json:"repair"`
+	Chunks          int           `json:"chunks"` // 数据库中的有效分块数
+	Stores          []StoreReport `json:"stores"`
+	Discrepancies   []Discrepancy `json:"discrepancies,omitempty"`
+	Truncated       bool          `json:"truncated,omitempty"` // 差异明细超过上限，只保留了前面的部分
+	StartedAt       time.Time     `json:"started_at"`
+	FinishedAt      time.Time     `json:"finished_at"`
+}
+
+// TotalDiscrepancies 各存储的差异总数
+func (r *ReconcileReport) TotalDiscrepancies() int {
+	total := 0
+	for _, store := range r.Stores {
+		total += store.Discrepancies()
+	}
+	return total
+}
+
+// TotalRepaired 各存储修复的差异总数
+func (r *ReconcileReport) TotalRepaired() int {
+	total := 0
+	for _, store := range r.Stores {
+		total += store.Repaired
+	}
+	return total
+}
+
+// Errors 检查失败的存储及原因
+func (r *ReconcileReport) Errors() []string {
+	var errs []string
+	for _, store := range r.Stores {
+		if store.Error != "" {
+			errs = append(errs, store.Store+": "+store.Error)
+		}
+	}
+	return errs
+}
+
+func (r *ReconcileReport) record(d Discrepancy) {
+	if len(r.Discrepancies) >= maxReportedDiscrepancies {
+		r.Truncated = true
+		return
+	}
+	r.Discrepancies = append(r.Discrepancies, d)
+}
+
+// ExpectedChunk 数据库中的有效分块
+type ExpectedChunk struct {
+	DocumentID  uint
+	ContentHash string // 为空表示未记录哈希，不判断是否过期
+}
+
+// ExpectedDocument 数据库中的文档
+type ExpectedDocument struct {
+	FilePath string
+	Trashed  bool // 在回收站中，数据在清理前保留在各存储中，不检查
+}
+
+// ReconcileState 数据库中知识库的期望状态
+type ReconcileState struct {
+	KnowledgeBaseID uint
+	Chunks          map[uint]ExpectedChunk    // 未删除文档的有效分块
+	Documents       map[uint]ExpectedDocument // 全部文档，包括回收站中的文档
+	MaxChunkID      uint                      // 读取状态前的最大分块ID，之后写入的分块不检查
+	LoadedAt        time.Time                 // 开始读取状态的时间，之后写入的对象不检查
+}
+
+// StoreChecker 参与一致性检查的其他存储，例如分块缓存与对象存储
+type StoreChecker interface {
+	Name() string
+	// Check 对比存储与期望状态，计数写入report，差异明细交给record；repair为true时修复差异
+	Check(ctx context.Context, state *ReconcileState, repair bool, report *StoreReport, record func(Discrepancy)) error
+}
+
+// Reconciler 跨存储一致性检查
+// 以knowledge_chunks中的有效分块为准，对比全文索引、向量存储及其他存储中的数据：
+// 缺失或内容过期的分块重新写入，文档已不存在的数据按文档删除，文档仍存在但混入无效分块时删除后重写整篇文档
+type Reconciler struct {
+	db       *gorm.DB
+	indexer  FulltextIndexer
+	store    VectorStore
+	router   *EmbeddingRouter
+	checkers []StoreChecker
+}
+
+// NewReconciler 创建一致性检查器，indexer或store为nil时跳过对应存储
+func NewReconciler(db *gorm.DB, indexer FulltextIndexer, store VectorStore) *Reconciler {
+	return &Reconciler{db: db, indexer: indexer, store: store}
+}
+
+// SetEmbeddingRouter 设置嵌入模型路由，修复向量时为没有保存向量的分块重新向量化
+func (r *Reconciler) SetEmbeddingRouter(router *EmbeddingRouter) {
+	r.router = router
+}
+
+// AddChecker 增加参与检查的存储
+func (r *Reconciler) AddChecker(checker StoreChecker) {
+	r.checkers = append(r.checkers, checker)
+}
+
+// Run 检查知识库，repair为true时同时修复
+// 单个存储检查失败只记录在报告中，返回的错误表示无法读取期望状态或ctx已取消
+func (r *Reconciler) Run(ctx context.Context, knowledgeBaseID uint, repair bool) (*ReconcileReport, error) {
+	report := &ReconcileReport{
+		KnowledgeBaseID: knowledgeBaseID,
+		Repair:          repair,
+		StartedAt:       time.Now(),
+	}
+	state, err := r.loadState(ctx, knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	report.Chunks = len(state.Chunks)
+
+	if r.indexer != nil {
+		report.Stores = append(report.Stores, r.checkIndex(ctx, state, repair, report, indexTarget{
+			name:   ReconcileFulltext,
+			ready:  r.indexer.Ready,
+			scan:   r.indexer.ScanChunks,
+			remove: r.indexer.RemoveDocument,
+			write:  r.writeFulltext,
+		}))
+	}
+	if r.store != nil {
+		report.Stores = append(report.Stores, r.checkIndex(ctx, state, repair, report, indexTarget{
+			name:   ReconcileVector,
+			ready:  r.store.Ready,
+			scan:   r.store.ScanChunks,
+			remove: r.store.DeleteDocument,
+			write:  r.writeVectors,
+		}))
+	}
+	for _, checker := range r.checkers {
+		store := StoreReport{Store: checker.Name()}
+		if err := checker.Check(ctx, state, repair, &store, report.record); err != nil {
+			store.Error = err.Error()
+		}
+		report.Stores = append(report.Stores, store)
+	}
+
+	report.FinishedAt = time.Now()
+	return report, ctx.Err()
+}
+
+// loadState 读取知识库的文档与有效分块
+// 先记录最大分块ID，检查期间新写入的分块ID更大，不会被当作无效数据删除
+func (r *Reconciler) loadState(ctx context.Context, kbID uint) (*ReconcileState, error) {
+	loadedAt := time.Now()
+	var maxChunkID uint
+	err := r.db.WithContext(ctx).
+		Table("knowledge_chunks").
+		Select("COALESCE(MAX(chunk_id), 0)").
+		Scan(&maxChunkID).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chunk high-water mark: %w", err)
+	}
+
+	var docs []struct {
+		DocumentID uint
+		FilePath   string
+		Trashed    bool
+	}
+	err = r.db.WithContext(ctx).
+		Table("knowledge_documents").
+		Select("document_id, file_path, deleted_at IS NOT NULL AS trashed").
+		Where("knowledge_base_id = ?", kbID).
+		Scan(&docs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load documents: %w", err)
+	}
+
+	state := &ReconcileState{
+		KnowledgeBaseID: kbID,
+		Chunks:          make(map[uint]ExpectedChunk),
+		Documents:       make(map[uint]ExpectedDocument, len(docs)),
+		MaxChunkID:      maxChunkID,
+		LoadedAt:        loadedAt,
+	}
+	for _, doc := range docs {
+		state.Documents[doc.DocumentID] = ExpectedDocument{FilePath: doc.FilePath, Trashed: doc.Trashed}
+	}
+
+	var lastID uint
+	for {
+		var chunks []struct {
+			ChunkID     uint
+			DocumentID  uint
+			ContentHash string
+		}
+		err := r.db.WithContext(ctx).
+			Table("knowledge_chunks AS c").
+			Select("c.chunk_id, c.document_id, c.content_hash").
+			Joins("JOIN knowledge_documents d ON c.document_id = d.document_id").
+			Where("d.knowledge_base_id = ? AND d.deleted_at IS NULL AND c.is_active IS NOT FALSE AND c.chunk_id > ?", kbID, lastID).
+			Order("c.chunk_id").
+			Limit(scanBatchSize).
+			Scan(&chunks).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to load chunks: %w", err)
+		}
+		for _, chunk := range chunks {
+			state.Chunks[chunk.ChunkID] = ExpectedChunk{DocumentID: chunk.DocumentID, ContentHash: chunk.ContentHash}
+		}
+		if len(chunks) < scanBatchSize {
+			return state, nil
+		}
+		lastID = chunks[len(chunks)-1].ChunkID
+	}
+}
+
+// indexTarget 按分块索引的存储（全文索引、向量存储）
+type indexTarget struct {
+	name   string
+	ready  func() bool
+	scan   func(ctx context.Context, kbID uint, fn func([]IndexedChunk) error) error
+	remove func(ctx context.Context, kbID uint, documentID uint) error
+	// write 写入分块，返回写入失败的分块ID
+	write func(ctx context.Context, kbID uint, chunks []reindexChunk) (map[uint]bool, error)
+}
+
+func (r *Reconciler) checkIndex(ctx context.Context, state *ReconcileState, repair bool, report *ReconcileReport, target indexTarget) StoreReport {
+	result := StoreReport{Store: target.name}
+	if !target.ready() {
+		result.Error = "store not ready"
+		return result
+	}
+	record := func(kind string, documentID, chunkID uint) {
+		report.record(Discrepancy{Store: target.name, Kind: kind, DocumentID: documentID, ChunkID: chunkID})
+	}
+
+	seen := make(map[uint]bool, len(state.Chunks))
+	orphans := make(map[uint]int)  // 文档ID -> 无效分块数
+	rewrite := make(map[uint]bool) // 需要写入的分块ID -> 是否为差异（重写整篇文档时其余分块不计入）
+	var candidates []IndexedChunk  // 不在期望状态中的分块，确认后才算无效
+	err := target.scan(ctx, state.KnowledgeBaseID, func(batch []IndexedChunk) error {
+		for _, chunk := range batch {
+			if chunk.ChunkID > state.MaxChunkID {
+				continue
+			}
+			result.Checked++
+			if doc, ok := state.Documents[chunk.DocumentID]; ok && doc.Trashed {
+				continue
+			}
+			expected, ok := state.Chunks[chunk.ChunkID]
+			if !ok || expected.DocumentID != chunk.DocumentID {
+				candidates = append(candidates, chunk)
+				continue
+			}
+			seen[chunk.ChunkID] = true
+			if chunk.ContentHash != "" && expected.ContentHash != "" && chunk.ContentHash != expected.ContentHash {
+				result.Stale++
+				rewrite[chunk.ChunkID] = true
+				record(DiscrepancyStale, chunk.DocumentID, chunk.ChunkID)
+			}
+		}
+		return ctx.Err()
+	})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	// 读取状态后提交的分块（ID不一定更大）重新查询，仍有效的不算无效数据
+	current, err := r.activeChunks(ctx, state.KnowledgeBaseID, candidates)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	for _, chunk := range candidates {
+		if documentID, ok := current[chunk.ChunkID]; ok && documentID == chunk.DocumentID {
+			continue
+		}
+		result.Orphaned++
+		orphans[chunk.DocumentID]++
+		record(DiscrepancyOrphan, chunk.DocumentID, chunk.ChunkID)
+	}
+
+	missing := make([]uint, 0)
+	for chunkID := range state.Chunks {
+		if !seen[chunkID] {
+			missing = append(missing, chunkID)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+	for _, chunkID := range missing {
+		result.Missing++
+		rewrite[chunkID] = true
+		record(DiscrepancyMissing, state.Chunks[chunkID].DocumentID, chunkID)
+	}
+
+	if repair {
+		if err := r.repairIndex(ctx, state, target, orphans, rewrite, &result); err != nil {
+			result.Error = err.Error()
+		}
+	}
+	return result
+}
+
+// activeChunks 查询分块当前是否有效，返回有效分块ID -> 文档ID
+func (r *Reconciler) activeChunks(ctx context.Context, kbID uint, chunks []IndexedChunk) (map[uint]uint, error) {
+	current := make(map[uint]uint)
+	for start := 0; start < len(chunks); start += scanBatchSize {
+		end := start + scanBatchSize
+		if end > len(chunks) {
+			end = len(chunks)
+		}
+		ids := make([]uint, 0, end-start)
+		for _, chunk := range chunks[start:end] {
+			ids = append(ids, chunk.ChunkID)
+		}
+
+		var rows []struct {
+			ChunkID    uint
+			DocumentID uint
+		}
+		err := r.db.WithContext(ctx).
+			Table("knowledge_chunks AS c").
+			Select("c.chunk_id, c.document_id").
+			Joins("JOIN knowledge_documents d ON c.document_id = d.document_id").
+			Where("d.knowledge_base_id = ? AND d.deleted_at IS NULL AND c.is_active IS NOT FALSE AND c.chunk_id IN ?", kbID, ids).
+			Scan(&rows).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to recheck chunks: %w", err)
+		}
+		for _, row := range rows {
+			current[row.ChunkID] = row.DocumentID
+		}
+	}
+	return current, nil
+}
+
+// repairIndex 先按文档删除无效数据，再写入缺失、过期以及需要重写的分块
+// 删除后重写的是文档当前的有效分块，检查期间重新处理的文档不会丢失新分块
+func (r *Reconciler) repairIndex(ctx context.Context, state *ReconcileState, target indexTarget, orphans map[uint]int, rewrite map[uint]bool, result *StoreReport) error {
+	for _, documentID := range sortedKeys(orphans) {
+		if err := target.remove(ctx, state.KnowledgeBaseID, documentID); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			result.RepairFailed += orphans[documentID]
+			continue
+		}
+		result.Repaired += orphans[documentID]
+
+		if _, exists := state.Documents[documentID]; !exists {
+			continue
+		}
+		var chunkIDs []uint
+		err := r.db.WithContext(ctx).
+			Table("knowledge_chunks AS c").
+			Joins("JOIN knowledge_documents d ON c.document_id = d.document_id").
+			Where("d.document_id = ? AND d.knowledge_base_id = ? AND d.deleted_at IS NULL AND c.is_active IS NOT FALSE", documentID, state.KnowledgeBaseID).
+			Pluck("c.chunk_id", &chunkIDs).Error
+		if err != nil {
+			return fmt.Errorf("failed to load document chunks: %w", err)
+		}
+		for _, chunkID := range chunkIDs {
+			if _, ok := rewrite[chunkID]; !ok {
+				rewrite[chunkID] = false
+			}
+		}
+	}
+
+	ids := sortedKeys(rewrite)
+	for start := 0; start < len(ids); start += reindexBatchSize {
+		end := start + reindexBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch := ids[start:end]
+
+		var chunks []reindexChunk
+		err := r.db.WithContext(ctx).
+			Table("knowledge_chunks AS c").
+			Joins("JOIN knowledge_documents d ON c.document_id = d.document_id").
+			Where("d.knowledge_base_id = ? AND d.deleted_at IS NULL AND c.is_active IS NOT FALSE AND c.chunk_id IN ?", state.KnowledgeBaseID, batch).
+			Select(reindexChunkColumns).
+			Order("c.chunk_id").
+			Scan(&chunks).Error
+		if err != nil {
+			return fmt.Errorf("failed to load chunks: %w", err)
+		}
+		// 检查期间被删除或停用的分块不再写入
+		failed, err := target.write(ctx, state.KnowledgeBaseID, chunks)
+		if err != nil {
+			return err
+		}
+		for _, chunk := range chunks {
+			if !rewrite[chunk.ChunkID] {
+				continue
+			}
+			if failed[chunk.ChunkID] {
+				result.RepairFailed++
+			} else {
+				result.Repaired++
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) writeFulltext(ctx context.Context, kbID uint, chunks []reindexChunk) (map[uint]bool, error) {
+	failed := make(map[uint]bool)
+	for _, chunk := range chunks {
+		if err := r.indexer.IndexChunk(ctx, chunk.fulltextChunk(kbID)); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			failed[chunk.ChunkID] = true
+		}
+	}
+	return failed, nil
+}
+
+// writeVectors 写入向量，优先使用数据库中保存且维度一致的向量，其余按知识库的嵌入模型重新生成
+func (r *Reconciler) writeVectors(ctx context.Context, kbID uint, chunks []reindexChunk) (map[uint]bool, error) {
+	var scheduler *EmbedScheduler
+	if r.router != nil {
+		var err error
+		if scheduler, err = r.router.Scheduler(ctx, kbID); err != nil {
+			return nil, fmt.Errorf("failed to create embedder: %w", err)
+		}
+	}
+
+	embeddings := make([][]float32, len(chunks))
+	var pending []int
+	for i, chunk := range chunks {
+		if chunk.Embedding != "" {
+			var embedding []float32
+			if json.Unmarshal([]byte(chunk.Embedding), &embedding) == nil && len(embedding) > 0 &&
+				(scheduler == nil || len(embedding) == scheduler.Embedder().Dimensions()) {
+				embeddings[i] = embedding
+				continue
+			}
+		}
+		pending = append(pending, i)
+	}
+	if len(pending) > 0 && scheduler != nil {
+		texts := make([]string, len(pending))
+		for i, idx := range pending {
+			texts[i] = chunks[idx].Content
+		}
+		results, err := scheduler.EmbedAll(ctx, texts)
+		var batchErr *BatchEmbedError
+		if err != nil && !errors.As(err, &batchErr) {
+			return nil, fmt.Errorf("failed to embed chunks: %w", err)
+		}
+		for i, idx := range pending {
+			embeddings[idx] = results[i]
+		}
+	}
+
+	failed := make(map[uint]bool)
+	for i, chunk := range chunks {
+		if embeddings[i] == nil {
+			failed[chunk.ChunkID] = true
+			continue
+		}
+		if _, err := r.store.UpsertChunk(ctx, VectorChunk{
+			ChunkID:         chunk.ChunkID,
+			DocumentID:      chunk.DocumentID,
+			KnowledgeBaseID: kbID,
+			Text:            chunk.Content,
+			Embedding:       embeddings[i],
+		}); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			failed[chunk.ChunkID] = true
+		}
+	}
+	return failed, nil
+}
+
+func sortedKeys[V any](m map[uint]V) []uint {
+	keys := make([]uint, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
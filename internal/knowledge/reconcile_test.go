@@ -0,0 +1,210 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeScanIndexer 预设索引中的分块，记录修复时的删除与写入
+type fakeScanIndexer struct {
+	NoopFulltextIndexer
+	chunks  []IndexedChunk
+	removed []uint
+	indexed []uint
+}
+
+func (f *fakeScanIndexer) Ready() bool { return true }
+
+func (f *fakeScanIndexer) ScanChunks(ctx context.Context, kbID uint, fn func([]IndexedChunk) error) error {
+	return fn(f.chunks)
+}
+
+func (f *fakeScanIndexer) RemoveDocument(ctx context.Context, kbID uint, documentID uint) error {
+	f.removed = append(f.removed, documentID)
+	return nil
+}
+
+func (f *fakeScanIndexer) IndexChunk(ctx context.Context, chunk FulltextChunk) error {
+	f.indexed = append(f.indexed, chunk.ChunkID)
+	return nil
+}
+
+// fakeScanVectorStore 预设向量存储中的分块，记录写入的向量
+type fakeScanVectorStore struct {
+	ready    bool
+	chunks   []IndexedChunk
+	upserted map[uint][]float32
+}
+
+func (f *fakeScanVectorStore) UpsertChunk(ctx context.Context, chunk VectorChunk) (string, error) {
+	f.upserted[chunk.ChunkID] = chunk.Embedding
+	return "", nil
+}
+
+func (f *fakeScanVectorStore) DeleteDocument(ctx context.Context, kbID uint, documentID uint) error {
+	return nil
+}
+
+func (f *fakeScanVectorStore) Search(ctx context.Context, req VectorSearchRequest) ([]SearchMatch, error) {
+	return nil, nil
+}
+
+func (f *fakeScanVectorStore) ScanChunks(ctx context.Context, kbID uint, fn func([]IndexedChunk) error) error {
+	return fn(f.chunks)
+}
+
+func (f *fakeScanVectorStore) Ready() bool { return f.ready }
+
+// fakeChecker 记录一条差异后返回预设错误
+type fakeChecker struct {
+	err error
+}
+
+func (f *fakeChecker) Name() string { return "object" }
+
+func (f *fakeChecker) Check(ctx context.Context, state *ReconcileState, repair bool, report *StoreReport, record func(Discrepancy)) error {
+	report.Missing++
+	record(Discrepancy{Store: f.Name(), Kind: DiscrepancyMissing, Object: state.Documents[10].FilePath})
+	return f.err
+}
+
+// expectReconcileState 文档10有效、文档11在回收站中，文档10有分块100-102，读取时最大分块ID为300
+func expectReconcileState(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`SELECT COALESCE\(MAX\(chunk_id\), 0\) FROM "knowledge_chunks"`).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(300))
+	mock.ExpectQuery(`SELECT document_id, file_path, deleted_at IS NOT NULL AS trashed FROM "knowledge_documents"`).
+		WithArgs(uint(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"document_id", "file_path", "trashed"}).
+			AddRow(10, "knowledge-bases/1/a.txt", false).
+			AddRow(11, "knowledge-bases/1/b.txt", true))
+	mock.ExpectQuery(`SELECT c.chunk_id, c.document_id, c.content_hash FROM knowledge_chunks AS c`).
+		WithArgs(uint(1), uint(0)).
+		WillReturnRows(sqlmock.NewRows([]string{"chunk_id", "document_id", "content_hash"}).
+			AddRow(100, 10, ChunkHash("alpha")).
+			AddRow(101, 10, ChunkHash("beta")).
+			AddRow(102, 10, ChunkHash("gamma")))
+}
+
+func TestReconciler_RepairsFulltextIndex(t *testing.T) {
+	db, mock := newMockGormDB(t)
+	expectReconcileState(mock)
+	mock.ExpectQuery(`SELECT c.chunk_id, c.document_id FROM knowledge_chunks AS c .*c.chunk_id IN \(\$2,\$3\)`).
+		WithArgs(uint(1), uint(200), uint(300)).
+		WillReturnRows(sqlmock.NewRows([]string{"chunk_id", "document_id"}))
+	mock.ExpectQuery(`SELECT "c"."chunk_id" FROM knowledge_chunks AS c .*d.document_id = \$1`).
+		WithArgs(uint(10), uint(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"chunk_id"}).AddRow(100).AddRow(101).AddRow(102))
+	chunkColumns := []string{"chunk_id", "document_id", "content", "chunk_index", "file_name"}
+	mock.ExpectQuery(`SELECT c.chunk_id, c.document_id, c.content.*c.chunk_id IN \(\$2,\$3,\$4\)`).
+		WithArgs(uint(1), uint(100), uint(101), uint(102)).
+		WillReturnRows(sqlmock.NewRows(chunkColumns).
+			AddRow(100, 10, "alpha", 0, "a.txt").
+			AddRow(101, 10, "beta", 1, "a.txt").
+			AddRow(102, 10, "gamma", 2, "a.txt"))
+
+	indexer := &fakeScanIndexer{chunks: []IndexedChunk{
+		{ChunkID: 100, DocumentID: 10, ContentHash: ChunkHash("alpha")},
+		{ChunkID: 101, DocumentID: 10, ContentHash: ChunkHash("old beta")},
+		{ChunkID: 200, DocumentID: 10, ContentHash: ChunkHash("removed")},
+		{ChunkID: 300, DocumentID: 99},
+		{ChunkID: 110, DocumentID: 11}, // 回收站中的文档不检查
+	}}
+
+	report, err := NewReconciler(db, indexer, nil).Run(context.Background(), 1, true)
+	require.NoError(t, err)
+	require.Len(t, report.Stores, 1)
+
+	store := report.Stores[0]
+	assert.Equal(t, ReconcileFulltext, store.Store)
+	assert.Equal(t, 5, store.Checked)
+	assert.Equal(t, 1, store.Missing)
+	assert.Equal(t, 2, store.Orphaned)
+	assert.Equal(t, 1, store.Stale)
+	assert.Equal(t, 4, store.Repaired)
+	assert.Zero(t, store.RepairFailed)
+	assert.Equal(t, 3, report.Chunks)
+	assert.Equal(t, 4, report.TotalDiscrepancies())
+
+	// 混入无效分块的文档10整篇重写，文档99已不存在只删除
+	assert.Equal(t, []uint{10, 99}, indexer.removed)
+	assert.Equal(t, []uint{100, 101, 102}, indexer.indexed)
+	assert.Contains(t, report.Discrepancies, Discrepancy{Store: ReconcileFulltext, Kind: DiscrepancyMissing, DocumentID: 10, ChunkID: 102})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReconciler_KeepsChunksWrittenDuringCheck(t *testing.T) {
+	db, mock := newMockGormDB(t)
+	expectReconcileState(mock)
+	// 分块250在读取状态后才提交，重新查询时已有效
+	mock.ExpectQuery(`SELECT c.chunk_id, c.document_id FROM knowledge_chunks AS c .*c.chunk_id IN \(\$2\)`).
+		WithArgs(uint(1), uint(250)).
+		WillReturnRows(sqlmock.NewRows([]string{"chunk_id", "document_id"}).AddRow(250, 10))
+
+	indexer := &fakeScanIndexer{chunks: []IndexedChunk{
+		{ChunkID: 100, DocumentID: 10},
+		{ChunkID: 101, DocumentID: 10},
+		{ChunkID: 102, DocumentID: 10},
+		{ChunkID: 250, DocumentID: 10},
+		{ChunkID: 400, DocumentID: 12}, // 读取状态后新建的文档
+	}}
+
+	report, err := NewReconciler(db, indexer, nil).Run(context.Background(), 1, true)
+	require.NoError(t, err)
+	require.Len(t, report.Stores, 1)
+
+	store := report.Stores[0]
+	assert.Equal(t, 4, store.Checked)
+	assert.Zero(t, store.Discrepancies())
+	assert.Empty(t, indexer.removed)
+	assert.Empty(t, indexer.indexed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReconciler_RepairsVectorsFromStoredEmbeddings(t *testing.T) {
+	db, mock := newMockGormDB(t)
+	expectReconcileState(mock)
+	mock.ExpectQuery(`c.chunk_id IN \(\$2,\$3\)`).
+		WithArgs(uint(1), uint(101), uint(102)).
+		WillReturnRows(sqlmock.NewRows([]string{"chunk_id", "document_id", "content", "embedding"}).
+			AddRow(101, 10, "beta", "[0.5,0.25]").
+			AddRow(102, 10, "gamma", ""))
+
+	store := &fakeScanVectorStore{ready: true, upserted: make(map[uint][]float32), chunks: []IndexedChunk{
+		{ChunkID: 100, DocumentID: 10},
+	}}
+
+	report, err := NewReconciler(db, nil, store).Run(context.Background(), 1, true)
+	require.NoError(t, err)
+	require.Len(t, report.Stores, 1)
+
+	result := report.Stores[0]
+	assert.Equal(t, 2, result.Missing)
+	assert.Equal(t, 1, result.Repaired)
+	// 没有保存向量且未配置嵌入模型路由时无法修复
+	assert.Equal(t, 1, result.RepairFailed)
+	assert.Equal(t, map[uint][]float32{101: {0.5, 0.25}}, store.upserted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReconciler_ReportsStoreErrors(t *testing.T) {
+	db, mock := newMockGormDB(t)
+	expectReconcileState(mock)
+
+	reconciler := NewReconciler(db, nil, &fakeScanVectorStore{})
+	reconciler.AddChecker(&fakeChecker{err: errors.New("bucket unavailable")})
+
+	report, err := reconciler.Run(context.Background(), 1, false)
+	require.NoError(t, err)
+	require.Len(t, report.Stores, 2)
+
+	assert.Equal(t, "store not ready", report.Stores[0].Error)
+	assert.Equal(t, 1, report.Stores[1].Missing)
+	assert.Equal(t, []string{"vector: store not ready", "object: bucket unavailable"}, report.Errors())
+	assert.Equal(t, []Discrepancy{{Store: "object", Kind: DiscrepancyMissing, Object: "knowledge-bases/1/a.txt"}}, report.Discrepancies)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,609 @@
+package knowledge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// VersionedIndex 版本化的物理索引
+// 检索与写入都通过别名进行，重建时写入新版本，完成后原子切换别名并删除旧版本
+type VersionedIndex interface {
+	// CurrentVersion 别名当前指向的版本，0表示尚未版本化的旧索引或索引不存在
+	CurrentVersion(ctx context.Context, knowledgeBaseID uint) (int, error)
+	// SwapVersion 将别名切换到指定版本并删除旧版本
+	SwapVersion(ctx context.Context, knowledgeBaseID uint, version int) error
+	// DropVersion 删除未启用的版本（重建取消或失败时清理）
+	DropVersion(ctx context.Context, knowledgeBaseID uint, version int) error
+}
+
+// VersionedFulltextIndexer 支持版本化重建的全文索引
+type VersionedFulltextIndexer interface {
+	FulltextIndexer
+	VersionedIndex
+	CreateVersion(ctx context.Context, knowledgeBaseID uint, version int) error
+	IndexChunkVersion(ctx context.Context, version int, chunk FulltextChunk) error
+}
+
+// VersionedVectorStore 支持版本化重建的向量存储，新版本可以使用不同的向量维度
+type VersionedVectorStore interface {
+	VectorStore
+	VersionedIndex
+	CreateVersion(ctx context.Context, knowledgeBaseID uint, version int, dimension int) error
+	UpsertChunkVersion(ctx context.Context, version int, chunk VectorChunk) error
+}
+
+var (
+	ErrReindexInProgress = errors.New("reindex already in progress")
+	ErrReindexNotFound   = errors.New("reindex job not found")
+	// ErrReindexUnsupported 索引不支持版本化重建；数据库与pgvector向量存储的版本化操作总是返回该错误
+	ErrReindexUnsupported = errors.New("index does not support versioned reindex")
+)
+
+// ReindexStatus 重建任务状态
+type ReindexStatus string
+
+const (
+	ReindexRunning   ReindexStatus = "running"
+	ReindexSucceeded ReindexStatus = "succeeded"
+	ReindexFailed    ReindexStatus = "failed"
+	ReindexCancelled ReindexStatus = "cancelled"
+)
+
+// ReindexRequest 重建请求
+type ReindexRequest struct {
+	KnowledgeBaseID uint `json:"knowledge_base_id"`
+	Fulltext        bool `json:"fulltext"`
+	Vector          bool `json:"vector"`
+	Reembed         bool `json:"reembed"` // 强制重新向量化；未保存向量或维度不一致的分块总会重新向量化
+
+	// Embedding 迁移到的嵌入模型，隐含Vector与Reembed；切换成功后记录为知识库的模型
+	Embedding *EmbeddingProfile `json:"embedding,omitempty"`
+}
+
+// ReindexJob 重建任务进度
+type ReindexJob struct {
+	ID              string         `json:"id"`
+	Request         ReindexRequest `json:"request"`
+	Status          ReindexStatus  `json:"status"`
+	Total           int            `json:"total"`
+	Processed       int            `json:"processed"`
+	Failed          int            `json:"failed"`
+	FulltextVersion int            `json:"fulltext_version,omitempty"`
+	VectorVersion   int            `json:"vector_version,omitempty"`
+	Error           string         `json:"error,omitempty"`
+	StartedAt       time.Time      `json:"started_at"`
+	FinishedAt      *time.Time     `json:"finished_at,omitempty"`
+
+	scheduler *EmbedScheduler
+	// pending 重新生成的向量，切换版本并记录模型后才写回分块；
+	// 迁移未完成时分块保留旧模型的向量，避免之后的复用混入新模型的向量
+	pending map[uint][]float32
+	swapped bool // 已切换版本，之后生成的向量直接写回
+}
+
+const reindexBatchSize = 200
+
+// Reindexer 后台重建知识库索引
+// 从knowledge_chunks读取分块写入新版本索引，期间检索仍使用旧版本；
+// 全量写入后补写重建期间新增或修改的分块，然后切换别名，切换后再补写一次切换前写入旧版本的分块
+type Reindexer struct {
+	db        *gorm.DB
+	indexer   FulltextIndexer
+	store     VectorStore
+	scheduler *EmbedScheduler
+	router    *EmbeddingRouter
+
+	mu      sync.Mutex
+	jobs    map[uint]*ReindexJob // 知识库ID -> 最近一次任务
+	cancels map[uint]context.CancelFunc
+}
+
+// NewReindexer 创建重建器，scheduler为nil且未设置嵌入模型路由时不能重建向量索引
+func NewReindexer(db *gorm.DB, indexer FulltextIndexer, store VectorStore, scheduler *EmbedScheduler) *Reindexer {
+	return &Reindexer{
+		db:        db,
+		indexer:   indexer,
+		store:     store,
+		scheduler: scheduler,
+		jobs:      make(map[uint]*ReindexJob),
+		cancels:   make(map[uint]context.CancelFunc),
+	}
+}
+
+// SetEmbeddingRouter 设置嵌入模型路由，设置后向量重建使用知识库记录的模型，并支持模型迁移
+func (r *Reindexer) SetEmbeddingRouter(router *EmbeddingRouter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.router = router
+}
+
+// Start 启动重建任务，同一知识库同时只能有一个任务
+func (r *Reindexer) Start(req ReindexRequest) (ReindexJob, error) {
+	var scheduler *EmbedScheduler
+	if req.Embedding != nil {
+		req.Vector, req.Reembed = true, true
+	} else if !req.Fulltext && !req.Vector {
+		req.Fulltext, req.Vector = true, true
+	}
+	if req.Fulltext {
+		if _, ok := r.indexer.(VersionedFulltextIndexer); !ok {
+			return ReindexJob{}, fmt.Errorf("fulltext: %w", ErrReindexUnsupported)
+		}
+	}
+	if req.Vector {
+		v, ok := r.store.(VersionedVectorStore)
+		if !ok {
+			return ReindexJob{}, fmt.Errorf("vector: %w", ErrReindexUnsupported)
+		}
+		// 数据库与pgvector存储实现了版本化接口，但版本化操作返回ErrReindexUnsupported
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		_, err := v.CurrentVersion(ctx, req.KnowledgeBaseID)
+		cancel()
+		if errors.Is(err, ErrReindexUnsupported) {
+			return ReindexJob{}, fmt.Errorf("vector: %w", err)
+		}
+		if scheduler, err = r.vectorScheduler(&req); err != nil {
+			return ReindexJob{}, err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if job, ok := r.jobs[req.KnowledgeBaseID]; ok && job.Status == ReindexRunning {
+		return *job, ErrReindexInProgress
+	}
+
+	job := &ReindexJob{
+		ID:        fmt.Sprintf("reindex-%d-%d", req.KnowledgeBaseID, time.Now().UnixNano()),
+		Request:   req,
+		Status:    ReindexRunning,
+		StartedAt: time.Now(),
+		scheduler: scheduler,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.jobs[req.KnowledgeBaseID] = job
+	r.cancels[req.KnowledgeBaseID] = cancel
+
+	go r.run(ctx, job)
+	return *job, nil
+}
+
+// vectorScheduler 向量重建使用的调度器：迁移时使用目标模型，否则使用知识库记录的模型
+// 未设置嵌入模型路由时使用全局调度器
+func (r *Reindexer) vectorScheduler(req *ReindexRequest) (*EmbedScheduler, error) {
+	r.mu.Lock()
+	router := r.router
+	r.mu.Unlock()
+
+	switch {
+	case req.Embedding != nil:
+		if router == nil {
+			return nil, errors.New("embedding router not configured")
+		}
+		embedder, err := router.Embedder(*req.Embedding)
+		if err != nil {
+			return nil, err
+		}
+		profile := *req.Embedding
+		profile.Dimensions = embedder.Dimensions()
+		req.Embedding = &profile
+		return router.scheduler(profile, embedder), nil
+	case router != nil:
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return router.Scheduler(ctx, req.KnowledgeBaseID)
+	case r.scheduler == nil:
+		return nil, errors.New("embedder not configured")
+	default:
+		return r.scheduler, nil
+	}
+}
+
+// Status 获取知识库最近一次重建任务
+func (r *Reindexer) Status(knowledgeBaseID uint) (ReindexJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[knowledgeBaseID]
+	if !ok {
+		return ReindexJob{}, ErrReindexNotFound
+	}
+	return *job, nil
+}
+
+// Cancel 取消运行中的重建任务，新版本索引会被删除，别名保持不变
+func (r *Reindexer) Cancel(knowledgeBaseID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[knowledgeBaseID]
+	if !ok || job.Status != ReindexRunning {
+		return ErrReindexNotFound
+	}
+	r.cancels[knowledgeBaseID]()
+	return nil
+}
+
+func (r *Reindexer) update(job *ReindexJob, fn func(job *ReindexJob)) {
+	r.mu.Lock()
+	fn(job)
+	r.mu.Unlock()
+}
+
+func (r *Reindexer) run(ctx context.Context, job *ReindexJob) {
+	kbID := job.Request.KnowledgeBaseID
+	err := r.reindex(ctx, job)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	job.FinishedAt = &now
+	switch {
+	case err == nil:
+		job.Status = ReindexSucceeded
+	case ctx.Err() != nil:
+		job.Status = ReindexCancelled
+	default:
+		job.Status = ReindexFailed
+		job.Error = err.Error()
+	}
+	r.cancels[kbID]()
+	delete(r.cancels, kbID)
+}
+
+func (r *Reindexer) reindex(ctx context.Context, job *ReindexJob) (err error) {
+	kbID := job.Request.KnowledgeBaseID
+	var indexer VersionedFulltextIndexer
+	var store VersionedVectorStore
+
+	// 失败或取消时删除已创建的新版本，使用独立的context保证清理执行
+	defer func() {
+		if err == nil {
+			return
+		}
+		cleanup, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if indexer != nil {
+			_ = indexer.DropVersion(cleanup, kbID, job.FulltextVersion)
+		}
+		if store != nil {
+			_ = store.DropVersion(cleanup, kbID, job.VectorVersion)
+		}
+	}()
+
+	if job.Request.Fulltext {
+		v := r.indexer.(VersionedFulltextIndexer)
+		current, err := v.CurrentVersion(ctx, kbID)
+		if err != nil {
+			return fmt.Errorf("failed to get fulltext index version: %w", err)
+		}
+		r.update(job, func(job *ReindexJob) { job.FulltextVersion = current + 1 })
+		if err := v.CreateVersion(ctx, kbID, current+1); err != nil {
+			return fmt.Errorf("failed to create fulltext index version: %w", err)
+		}
+		indexer = v
+	}
+	if job.Request.Vector {
+		v := r.store.(VersionedVectorStore)
+		current, err := v.CurrentVersion(ctx, kbID)
+		if err != nil {
+			return fmt.Errorf("failed to get vector index version: %w", err)
+		}
+		r.update(job, func(job *ReindexJob) { job.VectorVersion = current + 1 })
+		if err := v.CreateVersion(ctx, kbID, current+1, job.scheduler.Embedder().Dimensions()); err != nil {
+			return fmt.Errorf("failed to create vector index version: %w", err)
+		}
+		store = v
+
+		// 迁移期间检索双读新旧两个版本，迁移状态在删除新版本之前清除
+		if target := job.Request.Embedding; target != nil {
+			if err := r.router.beginMigration(ctx, kbID, *target, current+1); err != nil {
+				return err
+			}
+			defer func() {
+				end, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				_ = r.router.endMigration(end, kbID)
+			}()
+		}
+	}
+
+	var total int64
+	if err := r.chunkQuery(ctx, kbID).Count(&total).Error; err != nil {
+		return fmt.Errorf("failed to count chunks: %w", err)
+	}
+	r.update(job, func(job *ReindexJob) { job.Total = int(total) })
+
+	// 全量写入，之后补写重建期间新增（ID更大）或修改的分块
+	since := func(lastID uint, at time.Time) func(db *gorm.DB, afterID uint) *gorm.DB {
+		return func(db *gorm.DB, afterID uint) *gorm.DB {
+			return db.Where("c.chunk_id > ? AND (c.chunk_id > ? OR c.update_time >= ?)", afterID, lastID, at)
+		}
+	}
+	lastID, err := r.copyChunks(ctx, job, indexer, store, func(db *gorm.DB, afterID uint) *gorm.DB {
+		return db.Where("c.chunk_id > ?", afterID)
+	})
+	if err != nil {
+		return err
+	}
+	catchUpAt := time.Now()
+	caughtUp, err := r.copyChunks(ctx, job, indexer, store, since(lastID, job.StartedAt))
+	if err != nil {
+		return err
+	}
+	if caughtUp < lastID {
+		caughtUp = lastID
+	}
+	if job.Failed > 0 {
+		return fmt.Errorf("%d chunks failed to index", job.Failed)
+	}
+
+	// 切换成功的版本已在使用，之后出错也不能删除
+	swappedIndexer, swappedStore := indexer, store
+	if indexer != nil {
+		if err := indexer.SwapVersion(ctx, kbID, job.FulltextVersion); err != nil {
+			return fmt.Errorf("failed to swap fulltext index: %w", err)
+		}
+		indexer = nil
+	}
+	if store != nil {
+		if err := store.SwapVersion(ctx, kbID, job.VectorVersion); err != nil {
+			return fmt.Errorf("failed to swap vector index: %w", err)
+		}
+		store = nil
+	}
+	if target := job.Request.Embedding; target != nil {
+		save, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := r.router.SaveProfile(save, kbID, *target); err != nil {
+			return err
+		}
+	}
+	job.swapped = true
+	if err := r.savePending(ctx, job); err != nil {
+		return err
+	}
+
+	// 补写开始后到切换前的写入仍经别名进入旧版本，切换后再补写一次
+	if _, err := r.copyChunks(ctx, job, swappedIndexer, swappedStore, since(caughtUp, catchUpAt)); err != nil {
+		return err
+	}
+	if job.Failed > 0 {
+		return fmt.Errorf("%d chunks failed to index after swap", job.Failed)
+	}
+	return nil
+}
+
+// reindexChunk 重建读取的分块
+type reindexChunk struct {
+	ChunkID          uint
+	DocumentID       uint
+	Content          string
+	ChunkIndex       int
+	Metadata         string
+	Embedding        string
+	FileName         string
+	FilePath         string
+	Source           string
+	DocumentMetadata string // 文档元数据，读取其中的tags
+}
+
+// reindexChunkColumns 读取reindexChunk的列，查询需要关联knowledge_documents d
+const reindexChunkColumns = "c.chunk_id, c.document_id, c.content, c.chunk_index, c.metadata, c.embedding, d.title AS file_name, d.file_path, d.source, d.metadata AS document_metadata"
+
+// fulltextChunk 转换为全文索引的分块
+func (c reindexChunk) fulltextChunk(kbID uint) FulltextChunk {
+	var metadata map[string]interface{}
+	if c.Metadata != "" {
+		_ = json.Unmarshal([]byte(c.Metadata), &metadata)
+	}
+	var documentMetadata map[string]interface{}
+	if c.DocumentMetadata != "" {
+		_ = json.Unmarshal([]byte(c.DocumentMetadata), &documentMetadata)
+	}
+	return FulltextChunk{
+		ChunkID:         c.ChunkID,
+		DocumentID:      c.DocumentID,
+		KnowledgeBaseID: kbID,
+		Content:         c.Content,
+		ChunkIndex:      c.ChunkIndex,
+		FileName:        c.FileName,
+		FileType:        fileTypeOf(c.FilePath),
+		Source:          c.Source,
+		Tags:            tagsOf(documentMetadata),
+		Metadata:        metadata,
+	}
+}
+
+// IndexChunks 读取分块及所属文档信息写入全文索引，用于文档入库与更新
+func IndexChunks(ctx context.Context, db *gorm.DB, indexer FulltextIndexer, kbID uint, chunkIDs []uint) error {
+	if indexer == nil || !indexer.Ready() || len(chunkIDs) == 0 {
+		return nil
+	}
+	var chunks []reindexChunk
+	err := db.WithContext(ctx).
+		Table("knowledge_chunks AS c").
+		Select(reindexChunkColumns).
+		Joins("JOIN knowledge_documents d ON c.document_id = d.document_id").
+		Where("c.chunk_id IN ?", chunkIDs).
+		Scan(&chunks).Error
+	if err != nil {
+		return fmt.Errorf("failed to load chunks: %w", err)
+	}
+	for _, chunk := range chunks {
+		if err := indexer.IndexChunk(ctx, chunk.fulltextChunk(kbID)); err != nil {
+			return fmt.Errorf("failed to index chunk %d: %w", chunk.ChunkID, err)
+		}
+	}
+	return nil
+}
+
+func (r *Reindexer) chunkQuery(ctx context.Context, kbID uint) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Table("knowledge_chunks AS c").
+		Joins("JOIN knowledge_documents d ON c.document_id = d.document_id").
+		Where("d.knowledge_base_id = ? AND c.is_active IS NOT FALSE", kbID)
+}
+
+// copyChunks 按分块ID分批写入新版本，返回最后处理的分块ID
+func (r *Reindexer) copyChunks(ctx context.Context, job *ReindexJob, indexer VersionedFulltextIndexer, store VersionedVectorStore,
+	filter func(db *gorm.DB, afterID uint) *gorm.DB) (uint, error) {
+	kbID := job.Request.KnowledgeBaseID
+	var lastID uint
+	for {
+		if err := ctx.Err(); err != nil {
+			return lastID, err
+		}
+
+		var chunks []reindexChunk
+		err := filter(r.chunkQuery(ctx, kbID), lastID).
+			Select(reindexChunkColumns).
+			Order("c.chunk_id").
+			Limit(reindexBatchSize).
+			Scan(&chunks).Error
+		if err != nil {
+			return lastID, fmt.Errorf("failed to load chunks: %w", err)
+		}
+		if len(chunks) == 0 {
+			return lastID, nil
+		}
+		lastID = chunks[len(chunks)-1].ChunkID
+
+		failed := 0
+		if indexer != nil {
+			for _, chunk := range chunks {
+				if err := indexer.IndexChunkVersion(ctx, job.FulltextVersion, chunk.fulltextChunk(kbID)); err != nil {
+					if ctx.Err() != nil {
+						return lastID, ctx.Err()
+					}
+					failed++
+				}
+			}
+		}
+		if store != nil {
+			n, err := r.copyVectors(ctx, job, store, chunks)
+			if err != nil {
+				return lastID, err
+			}
+			failed += n
+		}
+
+		r.update(job, func(job *ReindexJob) {
+			job.Processed += len(chunks)
+			job.Failed += failed
+		})
+	}
+}
+
+// copyVectors 写入一批分块的向量，复用已保存且维度一致的向量，其余重新生成；返回失败数
+func (r *Reindexer) copyVectors(ctx context.Context, job *ReindexJob, store VersionedVectorStore, chunks []reindexChunk) (int, error) {
+	dimension := job.scheduler.Embedder().Dimensions()
+	embeddings := make([][]float32, len(chunks))
+	var pending []int
+	for i, chunk := range chunks {
+		if !job.Request.Reembed && chunk.Embedding != "" {
+			var embedding []float32
+			if json.Unmarshal([]byte(chunk.Embedding), &embedding) == nil && len(embedding) == dimension {
+				embeddings[i] = embedding
+				continue
+			}
+		}
+		pending = append(pending, i)
+	}
+
+	reembedded := make([]bool, len(chunks))
+	if len(pending) > 0 {
+		texts := make([]string, len(pending))
+		for i, idx := range pending {
+			texts[i] = chunks[idx].Content
+		}
+		results, err := job.scheduler.EmbedAll(ctx, texts)
+		var batchErr *BatchEmbedError
+		if err != nil && !errors.As(err, &batchErr) {
+			return 0, fmt.Errorf("failed to embed chunks: %w", err)
+		}
+		for i, idx := range pending {
+			embeddings[idx] = results[i]
+			reembedded[idx] = results[i] != nil
+		}
+	}
+
+	failed := 0
+	for i, chunk := range chunks {
+		if embeddings[i] == nil {
+			failed++
+			continue
+		}
+		if err := store.UpsertChunkVersion(ctx, job.VectorVersion, VectorChunk{
+			ChunkID:         chunk.ChunkID,
+			DocumentID:      chunk.DocumentID,
+			KnowledgeBaseID: job.Request.KnowledgeBaseID,
+			Text:            chunk.Content,
+			Embedding:       embeddings[i],
+		}); err != nil {
+			if ctx.Err() != nil {
+				return failed, ctx.Err()
+			}
+			failed++
+			continue
+		}
+		// 新生成的向量写回分块，之后的重建与一致性修复可以直接复用；切换前先暂存
+		if reembedded[i] {
+			if !job.swapped {
+				if job.pending == nil {
+					job.pending = make(map[uint][]float32)
+				}
+				job.pending[chunk.ChunkID] = embeddings[i]
+				continue
+			}
+			if err := r.saveEmbedding(ctx, chunk.ChunkID, embeddings[i]); err != nil {
+				return failed, err
+			}
+		}
+	}
+	return failed, nil
+}
+
+// savePending 写回切换前暂存的向量
+func (r *Reindexer) savePending(ctx context.Context, job *ReindexJob) error {
+	for chunkID, embedding := range job.pending {
+		if err := r.saveEmbedding(ctx, chunkID, embedding); err != nil {
+			return err
+		}
+		delete(job.pending, chunkID)
+	}
+	return nil
+}
+
+// saveEmbedding 保存分块的向量
+func (r *Reindexer) saveEmbedding(ctx context.Context, chunkID uint, embedding []float32) error {
+	data, err := json.Marshal(embedding)
+	if err != nil {
+		return err
+	}
+	err = r.db.WithContext(ctx).Table("knowledge_chunks").
+		Where("chunk_id = ?", chunkID).
+		Update("embedding", string(data)).Error
+	if err != nil {
+		return fmt.Errorf("failed to save embedding for chunk %d: %w", chunkID, err)
+	}
+	return nil
+}
+
+// versionedName 物理索引名：别名_v版本号
+func versionedName(alias string, version int) string {
+	return fmt.Sprintf("%s_v%d", alias, version)
+}
+
+// parseVersion 从物理索引名解析版本号，与别名同名的旧索引为0
+func parseVersion(alias, name string) int {
+	var version int
+	if _, err := fmt.Sscanf(name, alias+"_v%d", &version); err != nil {
+		return 0
+	}
+	return version
+}
@@ -0,0 +1,270 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// fakeVersionedIndexer 记录各版本写入的分块
+type fakeVersionedIndexer struct {
+	NoopFulltextIndexer
+	mu       sync.Mutex
+	current  int
+	versions map[int][]uint
+	dropped  []int
+	block    bool
+}
+
+func (f *fakeVersionedIndexer) CurrentVersion(ctx context.Context, kbID uint) (int, error) {
+	return f.current, nil
+}
+
+func (f *fakeVersionedIndexer) CreateVersion(ctx context.Context, kbID uint, version int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.versions[version] = nil
+	return nil
+}
+
+func (f *fakeVersionedIndexer) IndexChunkVersion(ctx context.Context, version int, chunk FulltextChunk) error {
+	if f.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.versions[version] = append(f.versions[version], chunk.ChunkID)
+	return nil
+}
+
+func (f *fakeVersionedIndexer) SwapVersion(ctx context.Context, kbID uint, version int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.current = version
+	return nil
+}
+
+func (f *fakeVersionedIndexer) DropVersion(ctx context.Context, kbID uint, version int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.dropped = append(f.dropped, version)
+	return nil
+}
+
+func newMockGormDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	require.NoError(t, err)
+	return db, mock
+}
+
+func waitReindex(t *testing.T, r *Reindexer, kbID uint) ReindexJob {
+	t.Helper()
+	var job ReindexJob
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = r.Status(kbID)
+		return err == nil && job.Status != ReindexRunning
+	}, 2*time.Second, 5*time.Millisecond)
+	return job
+}
+
+func TestReindexer_SwapsAfterCopy(t *testing.T) {
+	db, mock := newMockGormDB(t)
+	indexer := &fakeVersionedIndexer{current: 1, versions: make(map[int][]uint)}
+
+	chunkColumns := []string{"chunk_id", "document_id", "content", "chunk_index", "metadata", "embedding", "file_name"}
+	mock.ExpectQuery(`SELECT count\(\*\) FROM knowledge_chunks AS c`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery(`SELECT c.chunk_id.*c.chunk_id > \$2 ORDER BY c.chunk_id`).
+		WithArgs(uint(7), uint(0)).
+		WillReturnRows(sqlmock.NewRows(chunkColumns).
+			AddRow(3, 1, "alpha", 0, `{"page":1}`, "", "a.txt").
+			AddRow(5, 1, "beta", 1, "", "", "a.txt"))
+	mock.ExpectQuery(`SELECT c.chunk_id.*c.chunk_id > \$2 ORDER BY c.chunk_id`).
+		WithArgs(uint(7), uint(5)).
+		WillReturnRows(sqlmock.NewRows(chunkColumns))
+	// 补写重建期间新增或修改的分块
+	mock.ExpectQuery(`c.update_time >= \$4`).
+		WillReturnRows(sqlmock.NewRows(chunkColumns).AddRow(9, 2, "gamma", 0, "", "", "b.txt"))
+	mock.ExpectQuery(`c.update_time >= \$4`).
+		WillReturnRows(sqlmock.NewRows(chunkColumns))
+	// 补写开始后仍写入旧版本的分块在切换后再次补写
+	mock.ExpectQuery(`c.update_time >= \$4`).
+		WithArgs(uint(7), uint(0), uint(9), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(chunkColumns).AddRow(11, 2, "delta", 1, "", "", "b.txt"))
+	mock.ExpectQuery(`c.update_time >= \$4`).
+		WillReturnRows(sqlmock.NewRows(chunkColumns))
+
+	r := NewReindexer(db, indexer, nil, nil)
+	job, err := r.Start(ReindexRequest{KnowledgeBaseID: 7, Fulltext: true})
+	require.NoError(t, err)
+
+	_, err = r.Start(ReindexRequest{KnowledgeBaseID: 7, Fulltext: true})
+	assert.ErrorIs(t, err, ErrReindexInProgress)
+
+	job = waitReindex(t, r, 7)
+	assert.Equal(t, ReindexSucceeded, job.Status, job.Error)
+	assert.Equal(t, 2, job.FulltextVersion)
+	assert.Equal(t, 2, job.Total)
+	assert.Equal(t, 4, job.Processed)
+	assert.Equal(t, 2, indexer.current)
+	assert.Equal(t, []uint{3, 5, 9, 11}, indexer.versions[2])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// fakeVersionedStore 记录各版本写入的向量
+type fakeVersionedStore struct {
+	versionedSearchStore
+	mu       sync.Mutex
+	current  int
+	versions map[int][]uint
+	swapErr  error
+}
+
+func (f *fakeVersionedStore) CurrentVersion(ctx context.Context, kbID uint) (int, error) {
+	return f.current, nil
+}
+
+func (f *fakeVersionedStore) CreateVersion(ctx context.Context, kbID uint, version int, dimension int) error {
+	return nil
+}
+
+func (f *fakeVersionedStore) UpsertChunkVersion(ctx context.Context, version int, chunk VectorChunk) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.versions[version] = append(f.versions[version], chunk.ChunkID)
+	return nil
+}
+
+func (f *fakeVersionedStore) SwapVersion(ctx context.Context, kbID uint, version int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.swapErr != nil {
+		return f.swapErr
+	}
+	f.current = version
+	return nil
+}
+
+func (f *fakeVersionedStore) DropVersion(ctx context.Context, kbID uint, version int) error {
+	return nil
+}
+
+func TestReindexer_SavesReembeddedVectors(t *testing.T) {
+	db, mock := newMockGormDB(t)
+	store := &fakeVersionedStore{current: 1, versions: make(map[int][]uint)}
+
+	chunkColumns := []string{"chunk_id", "document_id", "content", "chunk_index", "metadata", "embedding", "file_name"}
+	mock.ExpectQuery(`SELECT count\(\*\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery(`SELECT c.chunk_id.*c.chunk_id > \$2 ORDER BY c.chunk_id`).
+		WillReturnRows(sqlmock.NewRows(chunkColumns).
+			AddRow(3, 1, "alpha", 0, "", "", "a.txt").
+			AddRow(5, 1, "beta", 1, "", "[1,0,0]", "a.txt"))
+	mock.ExpectQuery(`SELECT c.chunk_id.*c.chunk_id > \$2 ORDER BY c.chunk_id`).
+		WillReturnRows(sqlmock.NewRows(chunkColumns))
+	mock.ExpectQuery(`c.update_time >= \$4`).
+		WillReturnRows(sqlmock.NewRows(chunkColumns))
+	// 切换版本后才写回新生成的向量，保存的向量直接复用
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "knowledge_chunks" SET "embedding"=\$1 WHERE chunk_id = \$2`).
+		WithArgs("[0,0,0]", uint(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`c.update_time >= \$4`).
+		WillReturnRows(sqlmock.NewRows(chunkColumns))
+
+	r := NewReindexer(db, nil, store, NewEmbedScheduler(&fixedEmbedder{dims: 3}, EmbedSchedulerOptions{}))
+	_, err := r.Start(ReindexRequest{KnowledgeBaseID: 7, Vector: true})
+	require.NoError(t, err)
+
+	job := waitReindex(t, r, 7)
+	assert.Equal(t, ReindexSucceeded, job.Status, job.Error)
+	assert.Equal(t, 2, store.current)
+	assert.Equal(t, []uint{3, 5}, store.versions[2])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReindexer_KeepsStoredVectorsWhenSwapFails(t *testing.T) {
+	db, mock := newMockGormDB(t)
+	store := &fakeVersionedStore{current: 1, versions: make(map[int][]uint), swapErr: errors.New("alias unavailable")}
+
+	chunkColumns := []string{"chunk_id", "document_id", "content", "chunk_index", "metadata", "embedding", "file_name"}
+	mock.ExpectQuery(`SELECT count\(\*\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`SELECT c.chunk_id.*c.chunk_id > \$2 ORDER BY c.chunk_id`).
+		WillReturnRows(sqlmock.NewRows(chunkColumns).AddRow(3, 1, "alpha", 0, "", "[1,0]", "a.txt"))
+	mock.ExpectQuery(`SELECT c.chunk_id.*c.chunk_id > \$2 ORDER BY c.chunk_id`).
+		WillReturnRows(sqlmock.NewRows(chunkColumns))
+	mock.ExpectQuery(`c.update_time >= \$4`).
+		WillReturnRows(sqlmock.NewRows(chunkColumns))
+
+	// 切换失败时分块保留原有向量，不写回新生成的向量
+	r := NewReindexer(db, nil, store, NewEmbedScheduler(&fixedEmbedder{dims: 3}, EmbedSchedulerOptions{}))
+	_, err := r.Start(ReindexRequest{KnowledgeBaseID: 7, Vector: true, Reembed: true})
+	require.NoError(t, err)
+
+	job := waitReindex(t, r, 7)
+	assert.Equal(t, ReindexFailed, job.Status)
+	assert.Equal(t, 1, store.current)
+	assert.Equal(t, []uint{3}, store.versions[2])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReindexer_CancelDropsVersion(t *testing.T) {
+	db, mock := newMockGormDB(t)
+	indexer := &fakeVersionedIndexer{current: 3, versions: make(map[int][]uint), block: true}
+
+	mock.ExpectQuery(`SELECT count\(\*\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`SELECT c.chunk_id`).
+		WillReturnRows(sqlmock.NewRows([]string{"chunk_id", "document_id", "content"}).AddRow(1, 1, "alpha"))
+
+	r := NewReindexer(db, indexer, nil, nil)
+	_, err := r.Start(ReindexRequest{KnowledgeBaseID: 1, Fulltext: true})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		job, _ := r.Status(1)
+		return job.FulltextVersion == 4
+	}, time.Second, 5*time.Millisecond)
+	require.NoError(t, r.Cancel(1))
+
+	job := waitReindex(t, r, 1)
+	assert.Equal(t, ReindexCancelled, job.Status)
+	assert.Equal(t, 3, indexer.current)
+	assert.Equal(t, []int{4}, indexer.dropped)
+	assert.ErrorIs(t, r.Cancel(1), ErrReindexNotFound)
+}
+
+func TestReindexer_RequiresVersionedBackends(t *testing.T) {
+	r := NewReindexer(nil, &NoopFulltextIndexer{}, nil, nil)
+	_, err := r.Start(ReindexRequest{KnowledgeBaseID: 1, Fulltext: true})
+	assert.ErrorIs(t, err, ErrReindexUnsupported)
+
+	// 数据库与pgvector存储的版本化操作明确返回不支持
+	for _, store := range []VectorStore{NewDatabaseVectorStore(nil), &PgvectorVectorStore{}} {
+		r = NewReindexer(nil, &NoopFulltextIndexer{}, store, nil)
+		_, err = r.Start(ReindexRequest{KnowledgeBaseID: 1, Vector: true})
+		assert.ErrorIs(t, err, ErrReindexUnsupported)
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	assert.Equal(t, "kb_vectors_5_v3", versionedName("kb_vectors_5", 3))
+	assert.Equal(t, 3, parseVersion("kb_vectors_5", "kb_vectors_5_v3"))
+	assert.Equal(t, 0, parseVersion("kb_vectors_5", "kb_vectors_5"))
+}
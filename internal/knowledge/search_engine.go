@@ -3,10 +3,12 @@ package knowledge
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 )
 
 // HybridSearchRequest 混合检索请求
@@ -17,6 +19,22 @@ type HybridSearchRequest struct {
 	SearchType      string  // fulltext | vector | hybrid (兼容旧接口)
 	Mode            string  // auto | fulltext | vector | hybrid (新接口)
 	VectorThreshold float64 // 向量检索相似度阈值，默认0.9
+
+	History   []ChatTurn             // 对话历史，用于追问改写
+	Transform *QueryTransformOptions // 查询转换选项，为nil时使用知识库配置
+	Diversity *DiversityOptions      // 结果多样化（MMR），为nil时不启用
+
+	vectorText        string // HyDE生成的假设答案，非空时向量检索使用它代替Query
+	skipRerank        bool   // 多样化在重排序前进行时，融合阶段跳过重排序
+	excludedDocuments []uint // 排除的文档，向量检索时交给存储过滤
+}
+
+// vectorQuery 向量检索使用的文本
+func (r HybridSearchRequest) vectorQuery() string {
+	if r.vectorText != "" {
+		return r.vectorText
+	}
+	return r.Query
 }
 
 // QueryType 查询类型枚举
@@ -203,7 +221,17 @@ type HybridSearchEngine struct {
 	relatedChunkSize int                  // 关联块数量（前后各N块，默认1）
 	vectorWeight     float64              // 向量检索权重（默认0.6）
 	fulltextWeight   float64              // 全文检索权重（默认0.4）
-	weightAdjuster   *SmartWeightAdjuster // 智能权重调整器
+	weightAdjuster   *SmartWeightAdjuster // 智能权重调整器，为nil时使用固定权重
+	router           *EmbeddingRouter     // 按知识库路由查询向量化，为nil时使用embedder
+	lexicons         LexiconProvider      // 知识库词库，向量检索前扩展查询
+	transformer      *QueryTransformer    // 检索前的查询转换，为nil时不转换
+	snapshots        *searchSnapshots     // 分页检索的候选快照
+	documentFilter   DocumentFilter       // 排除的文档，为nil时不过滤
+}
+
+// DocumentFilter 检索时需要排除的文档，如回收站中仍保留索引数据的文档
+type DocumentFilter interface {
+	ExcludedDocuments(ctx context.Context, knowledgeBaseID uint) (map[uint]bool, error)
 }
 
 func NewHybridSearchEngine(indexer FulltextIndexer, vectorStore VectorStore, embedder Embedder, reranker Reranker) *HybridSearchEngine {
@@ -216,9 +244,15 @@ func NewHybridSearchEngine(indexer FulltextIndexer, vectorStore VectorStore, emb
 		vectorWeight:     0.6,                      // 向量检索权重60%
 		fulltextWeight:   0.4,                      // 全文检索权重40%
 		weightAdjuster:   NewSmartWeightAdjuster(), // 智能权重调整器
+		snapshots:        newSearchSnapshots(),
 	}
 }
 
+// SetDocumentFilter 设置检索结果的文档过滤
+func (e *HybridSearchEngine) SetDocumentFilter(filter DocumentFilter) {
+	e.documentFilter = filter
+}
+
 // SetRelatedChunkSize 设置关联块数量
 func (e *HybridSearchEngine) SetRelatedChunkSize(size int) {
 	if size >= 0 {
@@ -261,6 +295,100 @@ func (e *HybridSearchEngine) SetEmbedder(embedder Embedder) {
 	e.embedder = embedder
 }
 
+// SetEmbeddingRouter 设置嵌入模型路由，查询使用知识库记录的模型向量化
+func (e *HybridSearchEngine) SetEmbeddingRouter(router *EmbeddingRouter) {
+	e.router = router
+}
+
+// GetEmbeddingRouter 获取嵌入模型路由
+func (e *HybridSearchEngine) GetEmbeddingRouter() *EmbeddingRouter {
+	return e.router
+}
+
+// SetQueryTransformer 设置查询转换器（追问改写、多查询扩展、HyDE）
+func (e *HybridSearchEngine) SetQueryTransformer(transformer *QueryTransformer) {
+	e.transformer = transformer
+}
+
+// SetLexiconProvider 设置知识库词库，向量检索前去除停用词并附加同义词
+func (e *HybridSearchEngine) SetLexiconProvider(provider LexiconProvider) {
+	e.lexicons = provider
+}
+
+// queryEmbedders 解析知识库检索使用的Embedder，未设置路由时使用全局Embedder
+func (e *HybridSearchEngine) queryEmbedders(ctx context.Context, kbID uint) ([]QueryEmbedder, error) {
+	if e.router == nil {
+		return []QueryEmbedder{{Embedder: e.embedder}}, nil
+	}
+	return e.router.QueryEmbedders(ctx, kbID)
+}
+
+// searchVectors 向量检索；模型迁移期间双读新旧版本，同一分块取较高得分
+func (e *HybridSearchEngine) searchVectors(ctx context.Context, embedders []QueryEmbedder, query string, req VectorSearchRequest) ([]SearchMatch, error) {
+	var (
+		results   []SearchMatch
+		firstErr  error
+		succeeded int
+	)
+	if e.lexicons != nil {
+		if lexicon, err := e.lexicons.Lexicon(ctx, req.KnowledgeBaseID); err == nil {
+			query = lexicon.ExpandTerms(query)
+		}
+	}
+
+	seen := make(map[uint]int)
+	for _, qe := range embedders {
+		matches, err := e.searchVectorsWith(ctx, qe, query, req)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		succeeded++
+		for _, m := range matches {
+			if idx, ok := seen[m.ChunkID]; ok {
+				if m.Score > results[idx].Score {
+					results[idx] = m
+				}
+				continue
+			}
+			seen[m.ChunkID] = len(results)
+			results = append(results, m)
+		}
+	}
+	if succeeded == 0 {
+		if firstErr == nil {
+			firstErr = errors.New("no embedder available")
+		}
+		return nil, firstErr
+	}
+
+	if len(embedders) > 1 {
+		sortMatchesByScore(results)
+		if req.Limit > 0 && len(results) > req.Limit {
+			results = results[:req.Limit]
+		}
+	}
+	return results, nil
+}
+
+func (e *HybridSearchEngine) searchVectorsWith(ctx context.Context, qe QueryEmbedder, query string, req VectorSearchRequest) ([]SearchMatch, error) {
+	embedding, err := qe.Embedder.Embed(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	req.QueryEmbedding = embedding
+	if qe.Version == 0 {
+		return e.vectorStore.Search(ctx, req)
+	}
+	searcher, ok := e.vectorStore.(VersionSearcher)
+	if !ok {
+		return nil, errors.New("vector store does not support versioned search")
+	}
+	return searcher.SearchVersion(ctx, qe.Version, req)
+}
+
 // detectQueryType 检测查询类型
 func (e *HybridSearchEngine) detectQueryType(query string) string {
 	query = strings.TrimSpace(query)
@@ -305,9 +433,147 @@ func (e *HybridSearchEngine) normalizeScore(score float64, maxScore float64) flo
 }
 
 func (e *HybridSearchEngine) Search(ctx context.Context, req HybridSearchRequest) ([]SearchMatch, error) {
+	matches, _, err := e.SearchTransformed(ctx, req)
+	return matches, err
+}
+
+// SearchTransformed 检索并返回查询转换结果
+// 开启多查询扩展时各查询并发检索，按分块去重保留最高分后合并
+func (e *HybridSearchEngine) SearchTransformed(ctx context.Context, req HybridSearchRequest) ([]SearchMatch, *TransformedQuery, error) {
 	if strings.TrimSpace(req.Query) == "" {
-		return nil, errors.New("query cannot be empty")
+		return nil, nil, errors.New("query cannot be empty")
+	}
+
+	transformed := &TransformedQuery{Original: req.Query, Standalone: req.Query}
+	if e.transformer.Ready() {
+		opts := e.transformer.Options(ctx, req.KnowledgeBaseID, req.Transform)
+		transformed = e.transformer.Transform(ctx, req.Query, req.History, opts)
+	}
+
+	queries := transformed.Queries()
+	if len(queries) == 1 {
+		req.Query = transformed.Standalone
+		req.vectorText = transformed.Hypothetical
+		matches, err := e.search(ctx, req)
+		return matches, transformed, err
+	}
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = 10
 	}
+
+	// 多样化在合并后统一进行，各查询只召回候选
+	results := make([][]SearchMatch, len(queries))
+	errs := make([]error, len(queries))
+	var wg sync.WaitGroup
+	for i, query := range queries {
+		sub := req
+		sub.Query = query
+		if req.Diversity != nil {
+			sub.Diversity = nil
+			sub.Limit = diversityPool(limit)
+			sub.skipRerank = req.Diversity.BeforeRerank
+		}
+		if i == 0 {
+			sub.vectorText = transformed.Hypothetical
+		}
+		wg.Add(1)
+		go func(i int, sub HybridSearchRequest) {
+			defer wg.Done()
+			results[i], errs[i] = e.search(ctx, sub)
+		}(i, sub)
+	}
+	wg.Wait()
+
+	var merged []SearchMatch
+	seen := make(map[uint]int)
+	succeeded := 0
+	for i, matches := range results {
+		if errs[i] != nil {
+			continue
+		}
+		succeeded++
+		for _, m := range matches {
+			if idx, ok := seen[m.ChunkID]; ok {
+				if m.Score > merged[idx].Score {
+					merged[idx] = m
+				}
+				continue
+			}
+			seen[m.ChunkID] = len(merged)
+			merged = append(merged, m)
+		}
+	}
+	if succeeded == 0 {
+		return nil, transformed, errs[0]
+	}
+
+	sortMatchesByScore(merged)
+	if req.Diversity != nil {
+		req.Query = transformed.Standalone
+		return e.selectDiverse(ctx, req, merged, limit), transformed, nil
+	}
+	if len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged, transformed, nil
+}
+
+// search 使用单个查询检索，启用多样化时先召回更多候选再按MMR选择
+func (e *HybridSearchEngine) search(ctx context.Context, req HybridSearchRequest) ([]SearchMatch, error) {
+	if req.Diversity == nil {
+		return e.retrieve(ctx, req)
+	}
+	limit := req.Limit
+	if limit == 0 {
+		limit = 10
+	}
+
+	pool := req
+	pool.Limit = diversityPool(limit)
+	pool.skipRerank = req.Diversity.BeforeRerank
+	candidates, err := e.retrieve(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	return e.selectDiverse(ctx, req, candidates, limit), nil
+}
+
+// retrieve 单个查询的召回与融合，排除过滤器指定的文档
+func (e *HybridSearchEngine) retrieve(ctx context.Context, req HybridSearchRequest) ([]SearchMatch, error) {
+	if e.documentFilter == nil {
+		return e.recall(ctx, req)
+	}
+	excluded, err := e.documentFilter.ExcludedDocuments(ctx, req.KnowledgeBaseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load excluded documents: %w", err)
+	}
+	if len(excluded) == 0 {
+		return e.recall(ctx, req)
+	}
+	req.excludedDocuments = make([]uint, 0, len(excluded))
+	for documentID := range excluded {
+		req.excludedDocuments = append(req.excludedDocuments, documentID)
+	}
+	sort.Slice(req.excludedDocuments, func(i, j int) bool { return req.excludedDocuments[i] < req.excludedDocuments[j] })
+
+	// 全文检索结果仍在此过滤
+	matches, err := e.recall(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	filtered := matches[:0]
+	for _, match := range matches {
+		if !excluded[match.DocumentID] {
+			filtered = append(filtered, match)
+		}
+	}
+	return filtered, nil
+}
+
+// recall 单个查询的召回与融合
+func (e *HybridSearchEngine) recall(ctx context.Context, req HybridSearchRequest) ([]SearchMatch, error) {
 	if req.Limit == 0 {
 		req.Limit = 10
 	}
@@ -331,9 +597,21 @@ func (e *HybridSearchEngine) Search(ctx context.Context, req HybridSearchRequest
 		}
 	}
 
-	useVector := e.vectorStore != nil && e.vectorStore.Ready() && e.embedder != nil && e.embedder.Ready()
+	useVector := e.vectorStore != nil && e.vectorStore.Ready() && (e.router != nil || e.embedder != nil && e.embedder.Ready())
 	useFulltext := e.indexer != nil && e.indexer.Ready()
 
+	// 知识库记录的向量维度与模型不一致时拒绝检索，其余无可用Embedder的情况降级为全文检索
+	var embedders []QueryEmbedder
+	if useVector {
+		var err error
+		if embedders, err = e.queryEmbedders(ctx, req.KnowledgeBaseID); err != nil {
+			if errors.Is(err, ErrEmbeddingDimensionMismatch) {
+				return nil, err
+			}
+			useVector = false
+		}
+	}
+
 	// 根据模式决定使用哪些引擎
 	switch mode {
 	case "vector":
@@ -347,10 +625,10 @@ func (e *HybridSearchEngine) Search(ctx context.Context, req HybridSearchRequest
 		queryType := e.detectQueryType(req.Query)
 		if queryType == "keyword_short" {
 			// 短查询+关键词型：优先全文，不足则补充向量
-			return e.searchAutoKeywordShort(ctx, req, useVector, useFulltext)
+			return e.searchAutoKeywordShort(ctx, req, embedders, useVector, useFulltext)
 		} else if queryType == "natural_long" {
 			// 长查询+自然语言型：优先向量，再用ES过滤，不足则补充全文
-			return e.searchAutoNaturalLong(ctx, req, useVector, useFulltext)
+			return e.searchAutoNaturalLong(ctx, req, embedders, useVector, useFulltext)
 		} else {
 			// 模糊查询：直接混合检索
 			mode = "hybrid"
@@ -369,16 +647,13 @@ func (e *HybridSearchEngine) Search(ctx context.Context, req HybridSearchRequest
 
 	// 执行向量检索
 	if useVector {
-		embedding, err := e.embedder.Embed(ctx, req.Query)
-		if err != nil {
-			return nil, err
-		}
-		vectorResults, err = e.vectorStore.Search(ctx, VectorSearchRequest{
+		vectorResults, err = e.searchVectors(ctx, embedders, req.vectorQuery(), VectorSearchRequest{
 			KnowledgeBaseID: req.KnowledgeBaseID,
-			QueryEmbedding:  embedding,
 			Limit:           req.Limit * 2, // 获取更多候选结果
 			CandidateLimit:  req.Limit * 20,
 			Threshold:       req.VectorThreshold,
+
+			ExcludeDocumentIDs: req.excludedDocuments,
 		})
 		if err != nil {
 			// 向量检索失败，降级为仅全文检索
@@ -438,7 +713,7 @@ func (e *HybridSearchEngine) Search(ctx context.Context, req HybridSearchRequest
 }
 
 // searchAutoKeywordShort 自动适配：短查询+关键词型
-func (e *HybridSearchEngine) searchAutoKeywordShort(ctx context.Context, req HybridSearchRequest, useVector, useFulltext bool) ([]SearchMatch, error) {
+func (e *HybridSearchEngine) searchAutoKeywordShort(ctx context.Context, req HybridSearchRequest, embedders []QueryEmbedder, useVector, useFulltext bool) ([]SearchMatch, error) {
 	var allResults []SearchMatch
 
 	// 1. 优先全文精准匹配
@@ -455,25 +730,23 @@ func (e *HybridSearchEngine) searchAutoKeywordShort(ctx context.Context, req Hyb
 
 	// 2. 如果结果不足，补充向量检索
 	if len(allResults) < req.Limit && useVector {
-		embedding, err := e.embedder.Embed(ctx, req.Query)
+		vectorResults, err := e.searchVectors(ctx, embedders, req.vectorQuery(), VectorSearchRequest{
+			KnowledgeBaseID: req.KnowledgeBaseID,
+			Limit:           req.Limit - len(allResults),
+			CandidateLimit:  req.Limit * 20,
+			Threshold:       req.VectorThreshold,
+
+			ExcludeDocumentIDs: req.excludedDocuments,
+		})
 		if err == nil {
-			vectorResults, err := e.vectorStore.Search(ctx, VectorSearchRequest{
-				KnowledgeBaseID: req.KnowledgeBaseID,
-				QueryEmbedding:  embedding,
-				Limit:           req.Limit - len(allResults),
-				CandidateLimit:  req.Limit * 20,
-				Threshold:       req.VectorThreshold,
-			})
-			if err == nil {
-				// 去重：检查是否已存在
-				existingIDs := make(map[uint]bool)
-				for _, r := range allResults {
-					existingIDs[r.ChunkID] = true
-				}
-				for _, r := range vectorResults {
-					if !existingIDs[r.ChunkID] {
-						allResults = append(allResults, r)
-					}
+			// 去重：检查是否已存在
+			existingIDs := make(map[uint]bool)
+			for _, r := range allResults {
+				existingIDs[r.ChunkID] = true
+			}
+			for _, r := range vectorResults {
+				if !existingIDs[r.ChunkID] {
+					allResults = append(allResults, r)
 				}
 			}
 		}
@@ -489,54 +762,52 @@ func (e *HybridSearchEngine) searchAutoKeywordShort(ctx context.Context, req Hyb
 }
 
 // searchAutoNaturalLong 自动适配：长查询+自然语言型
-func (e *HybridSearchEngine) searchAutoNaturalLong(ctx context.Context, req HybridSearchRequest, useVector, useFulltext bool) ([]SearchMatch, error) {
+func (e *HybridSearchEngine) searchAutoNaturalLong(ctx context.Context, req HybridSearchRequest, embedders []QueryEmbedder, useVector, useFulltext bool) ([]SearchMatch, error) {
 	var allResults []SearchMatch
 
 	// 1. 优先向量检索（0.9-1，按降序排序）
 	if useVector {
-		embedding, err := e.embedder.Embed(ctx, req.Query)
+		vectorResults, err := e.searchVectors(ctx, embedders, req.vectorQuery(), VectorSearchRequest{
+			KnowledgeBaseID: req.KnowledgeBaseID,
+			Limit:           req.Limit * 2,
+			CandidateLimit:  req.Limit * 20,
+			Threshold:       req.VectorThreshold,
+
+			ExcludeDocumentIDs: req.excludedDocuments,
+		})
 		if err == nil {
-			vectorResults, err := e.vectorStore.Search(ctx, VectorSearchRequest{
-				KnowledgeBaseID: req.KnowledgeBaseID,
-				QueryEmbedding:  embedding,
-				Limit:           req.Limit * 2,
-				CandidateLimit:  req.Limit * 20,
-				Threshold:       req.VectorThreshold,
-			})
-			if err == nil {
-				// 2. 用ES过滤结果中包含查询核心关键词的文档
-				if useFulltext {
-					// 提取查询关键词（简单实现：按空格分割）
-					keywords := strings.Fields(req.Query)
-					if len(keywords) > 0 {
-						// 使用ES搜索这些关键词，过滤向量结果
-						fullResults, err := e.indexer.Search(ctx, FulltextSearchRequest{
-							KnowledgeBaseID: req.KnowledgeBaseID,
-							Query:           strings.Join(keywords[:min(3, len(keywords))], " "), // 取前3个关键词
-							Limit:           req.Limit * 2,
-						})
-						if err == nil {
-							// 构建文档ID集合
-							docIDs := make(map[uint]bool)
-							for _, r := range fullResults {
-								docIDs[r.DocumentID] = true
-							}
-							// 过滤向量结果：只保留在ES结果中的文档
-							for _, r := range vectorResults {
-								if docIDs[r.DocumentID] {
-									allResults = append(allResults, r)
-								}
+			// 2. 用ES过滤结果中包含查询核心关键词的文档
+			if useFulltext {
+				// 提取查询关键词（简单实现：按空格分割）
+				keywords := strings.Fields(req.Query)
+				if len(keywords) > 0 {
+					// 使用ES搜索这些关键词，过滤向量结果
+					fullResults, err := e.indexer.Search(ctx, FulltextSearchRequest{
+						KnowledgeBaseID: req.KnowledgeBaseID,
+						Query:           strings.Join(keywords[:min(3, len(keywords))], " "), // 取前3个关键词
+						Limit:           req.Limit * 2,
+					})
+					if err == nil {
+						// 构建文档ID集合
+						docIDs := make(map[uint]bool)
+						for _, r := range fullResults {
+							docIDs[r.DocumentID] = true
+						}
+						// 过滤向量结果：只保留在ES结果中的文档
+						for _, r := range vectorResults {
+							if docIDs[r.DocumentID] {
+								allResults = append(allResults, r)
 							}
-						} else {
-							// ES失败，直接使用向量结果
-							allResults = vectorResults
 						}
 					} else {
+						// ES失败，直接使用向量结果
 						allResults = vectorResults
 					}
 				} else {
 					allResults = vectorResults
 				}
+			} else {
+				allResults = vectorResults
 			}
 		}
 	}
@@ -573,8 +844,11 @@ func (e *HybridSearchEngine) searchAutoNaturalLong(ctx context.Context, req Hybr
 
 // mergeResults 混合检索：加权融合（全文×0.6 + 向量×0.4）
 func (e *HybridSearchEngine) mergeResults(ctx context.Context, req HybridSearchRequest, vectorResults, fullResults []SearchMatch) ([]SearchMatch, error) {
-	// 智能权重调整：根据查询类型动态调整权重
-	vectorWeight, fulltextWeight := e.weightAdjuster.AdjustWeights(req.Query, e.vectorWeight, e.fulltextWeight)
+	// 智能权重调整：根据查询类型动态调整权重，未设置调整器时使用固定权重
+	vectorWeight, fulltextWeight := e.vectorWeight, e.fulltextWeight
+	if e.weightAdjuster != nil {
+		vectorWeight, fulltextWeight = e.weightAdjuster.AdjustWeights(req.Query, e.vectorWeight, e.fulltextWeight)
+	}
 
 	// 归一化全文检索得分
 	var maxFullScore float64
@@ -623,7 +897,9 @@ func (e *HybridSearchEngine) mergeResults(ctx context.Context, req HybridSearchR
 	sortMatchesByScore(results)
 
 	// 应用rerank（如果配置了）
-	results = e.applyRerank(ctx, req.Query, results, req.Limit)
+	if !req.skipRerank {
+		results = e.applyRerank(ctx, req.Query, results, req.Limit)
+	}
 
 	// 最终截取TopK
 	if len(results) > req.Limit {
@@ -0,0 +1,64 @@
+package knowledge
+
+import (
+	"context"
+	"time"
+)
+
+// SnapshotSearchRequest 对不在索引中的文本快照检索，如文档的历史版本
+type SnapshotSearchRequest struct {
+	KnowledgeBaseID uint
+	DocumentID      uint
+	Chunks          []Chunk
+	Query           string
+	Limit           int
+	Metadata        map[string]interface{} // 附加到每个结果的元数据
+	Tokenizer       Tokenizer              // 为空时使用BM25默认分词器
+}
+
+// SearchSnapshot 在临时的内存BM25索引上检索快照分块，结果的ChunkID为分块序号+1
+// 历史版本的分块与向量不保留，审计场景只做全文检索
+func SearchSnapshot(ctx context.Context, req SnapshotSearchRequest) ([]SearchMatch, error) {
+	if len(req.Chunks) == 0 {
+		return nil, nil
+	}
+	indexer, err := NewBM25Indexer(BM25Options{Tokenizer: req.Tokenizer, FlushInterval: time.Hour})
+	if err != nil {
+		return nil, err
+	}
+	defer indexer.Close()
+
+	for i, chunk := range req.Chunks {
+		err := indexer.IndexChunk(ctx, FulltextChunk{
+			ChunkID:         uint(i + 1),
+			DocumentID:      req.DocumentID,
+			KnowledgeBaseID: req.KnowledgeBaseID,
+			Content:         chunk.Text,
+			ChunkIndex:      i,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	matches, err := indexer.Search(ctx, FulltextSearchRequest{
+		KnowledgeBaseID: req.KnowledgeBaseID,
+		Query:           req.Query,
+		Limit:           req.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range matches {
+		metadata := make(map[string]interface{}, len(req.Metadata)+1)
+		for k, v := range matches[i].Metadata {
+			metadata[k] = v
+		}
+		for k, v := range req.Metadata {
+			metadata[k] = v
+		}
+		metadata["chunk_index"] = int(matches[i].ChunkID) - 1
+		matches[i].Metadata = metadata
+	}
+	return matches, nil
+}
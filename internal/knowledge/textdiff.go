@@ -0,0 +1,254 @@
+package knowledge
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 差异行类型
+const (
+	DiffEqual  = "equal"
+	DiffDelete = "delete"
+	DiffInsert = "insert"
+)
+
+// maxDiffEdits Myers算法的编辑距离上限，超过时中间部分按整体替换输出，避免超大文档耗尽内存
+const maxDiffEdits = 2000
+
+// DiffLine 差异中的一行，行号从1开始，不存在的一侧为0
+type DiffLine struct {
+	Op      string `json:"op"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+	Text    string `json:"text"`
+}
+
+// DiffStats 差异统计
+type DiffStats struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+}
+
+// DiffText 按行比较两段文本
+func DiffText(oldText, newText string) []DiffLine {
+	return DiffLines(splitLines(oldText), splitLines(newText))
+}
+
+// DiffLines 按行比较，使用Myers最短编辑脚本
+func DiffLines(a, b []string) []DiffLine {
+	// 去掉公共前后缀，只对中间部分求编辑脚本
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]DiffLine, 0, len(a)+len(b)-prefix-suffix)
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, DiffLine{Op: DiffEqual, OldLine: i + 1, NewLine: i + 1, Text: a[i]})
+	}
+	lines = append(lines, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix, prefix)...)
+	for i := suffix; i > 0; i-- {
+		oldIdx, newIdx := len(a)-i, len(b)-i
+		lines = append(lines, DiffLine{Op: DiffEqual, OldLine: oldIdx + 1, NewLine: newIdx + 1, Text: a[oldIdx]})
+	}
+	return lines
+}
+
+// CountDiff 统计新增与删除的行数
+func CountDiff(lines []DiffLine) DiffStats {
+	var stats DiffStats
+	for _, line := range lines {
+		switch line.Op {
+		case DiffInsert:
+			stats.Added++
+		case DiffDelete:
+			stats.Removed++
+		}
+	}
+	return stats
+}
+
+// UnifiedDiff 输出unified格式的差异，context为每处修改前后保留的相同行数
+func UnifiedDiff(oldLabel, newLabel string, lines []DiffLine, context int) string {
+	if context < 0 {
+		context = 0
+	}
+	var changed []int
+	for i, line := range lines {
+		if line.Op != DiffEqual {
+			changed = append(changed, i)
+		}
+	}
+	if len(changed) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldLabel, newLabel)
+	for i := 0; i < len(changed); {
+		start := max(changed[i]-context, 0)
+		end := changed[i]
+		// 相邻修改间的相同行不超过2*context时合并为一个块
+		for i < len(changed) && changed[i]-end <= 2*context+1 {
+			end = changed[i]
+			i++
+		}
+		end = min(end+context, len(lines)-1)
+		writeHunk(&sb, lines, start, end+1)
+	}
+	return sb.String()
+}
+
+func writeHunk(sb *strings.Builder, lines []DiffLine, start, end int) {
+	oldBefore, newBefore := 0, 0
+	for _, line := range lines[:start] {
+		if line.Op != DiffInsert {
+			oldBefore++
+		}
+		if line.Op != DiffDelete {
+			newBefore++
+		}
+	}
+	oldCount, newCount := 0, 0
+	for _, line := range lines[start:end] {
+		if line.Op != DiffInsert {
+			oldCount++
+		}
+		if line.Op != DiffDelete {
+			newCount++
+		}
+	}
+	// 一侧没有行时，按unified约定起始行号为插入位置的前一行
+	oldStart, newStart := oldBefore, newBefore
+	if oldCount > 0 {
+		oldStart++
+	}
+	if newCount > 0 {
+		newStart++
+	}
+
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+	for _, line := range lines[start:end] {
+		switch line.Op {
+		case DiffEqual:
+			sb.WriteString(" ")
+		case DiffDelete:
+			sb.WriteString("-")
+		case DiffInsert:
+			sb.WriteString("+")
+		}
+		sb.WriteString(line.Text)
+		sb.WriteString("\n")
+	}
+}
+
+// myersDiff 求a到b的最短编辑脚本，行号加上偏移量
+func myersDiff(a, b []string, oldOffset, newOffset int) []DiffLine {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+	if n == 0 || m == 0 {
+		return replaceAll(a, b, oldOffset, newOffset)
+	}
+
+	limit := min(n+m, maxDiffEdits)
+	offset := limit + 1
+	v := make([]int, 2*offset+1)
+	// trace[d]保存第d轮开始前k∈[-(d-1), d-1]的最远x，用于回溯
+	trace := make([][]int, 0, 16)
+	found := false
+	for d := 0; d <= limit && !found; d++ {
+		if d == 0 {
+			trace = append(trace, nil)
+		} else {
+			snapshot := make([]int, 2*d-1)
+			copy(snapshot, v[offset-(d-1):offset+d])
+			trace = append(trace, snapshot)
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return replaceAll(a, b, oldOffset, newOffset)
+	}
+
+	// 从终点回溯，逆序收集
+	var reversed []DiffLine
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		snapshot := trace[d]
+		at := func(k int) int { return snapshot[k+d-1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, DiffLine{Op: DiffEqual, OldLine: oldOffset + x + 1, NewLine: newOffset + y + 1, Text: a[x]})
+		}
+		if x == prevX {
+			y--
+			reversed = append(reversed, DiffLine{Op: DiffInsert, NewLine: newOffset + y + 1, Text: b[y]})
+		} else {
+			x--
+			reversed = append(reversed, DiffLine{Op: DiffDelete, OldLine: oldOffset + x + 1, Text: a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		reversed = append(reversed, DiffLine{Op: DiffEqual, OldLine: oldOffset + x + 1, NewLine: newOffset + y + 1, Text: a[x]})
+	}
+
+	lines := make([]DiffLine, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+	return lines
+}
+
+func replaceAll(a, b []string, oldOffset, newOffset int) []DiffLine {
+	lines := make([]DiffLine, 0, len(a)+len(b))
+	for i, text := range a {
+		lines = append(lines, DiffLine{Op: DiffDelete, OldLine: oldOffset + i + 1, Text: text})
+	}
+	for i, text := range b {
+		lines = append(lines, DiffLine{Op: DiffInsert, NewLine: newOffset + i + 1, Text: text})
+	}
+	return lines
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,94 @@
+package knowledge
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// applyDiff 用差异还原两侧文本，验证编辑脚本正确
+func applyDiff(lines []DiffLine) (oldLines, newLines []string) {
+	for _, line := range lines {
+		if line.Op != DiffInsert {
+			oldLines = append(oldLines, line.Text)
+		}
+		if line.Op != DiffDelete {
+			newLines = append(newLines, line.Text)
+		}
+	}
+	return oldLines, newLines
+}
+
+func TestDiffText(t *testing.T) {
+	oldText := "a\nb\nc\nd\ne\nf\n"
+	newText := "a\nc\nd\nx\ne\nf\ng\n"
+
+	lines := DiffText(oldText, newText)
+	oldLines, newLines := applyDiff(lines)
+	assert.Equal(t, splitLines(oldText), oldLines)
+	assert.Equal(t, splitLines(newText), newLines)
+	assert.Equal(t, DiffStats{Added: 2, Removed: 1}, CountDiff(lines))
+
+	for _, line := range lines {
+		switch line.Op {
+		case DiffDelete:
+			assert.Equal(t, "b", line.Text)
+			assert.Equal(t, 2, line.OldLine)
+		case DiffInsert:
+			assert.Contains(t, []string{"x", "g"}, line.Text)
+		}
+	}
+}
+
+func TestDiffText_EdgeCases(t *testing.T) {
+	assert.Empty(t, DiffText("", ""))
+	assert.Equal(t, DiffStats{Added: 2}, CountDiff(DiffText("", "a\nb")))
+	assert.Equal(t, DiffStats{Removed: 2}, CountDiff(DiffText("a\r\nb\r\n", "")))
+	assert.Equal(t, DiffStats{}, CountDiff(DiffText("same\n", "same")))
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	var oldLines, newLines []string
+	for i := 1; i <= 20; i++ {
+		line := "line " + strings.Repeat("x", i%3)
+		oldLines = append(oldLines, line)
+		newLines = append(newLines, line)
+	}
+	newLines[1] = "changed"
+	newLines[17] = "changed too"
+
+	out := UnifiedDiff("v1", "v2", DiffLines(oldLines, newLines), 2)
+	assert.True(t, strings.HasPrefix(out, "--- v1\n+++ v2\n"))
+	// 两处修改相距较远，输出两个块
+	assert.Equal(t, 2, strings.Count(out, "@@ -"))
+	assert.Contains(t, out, "@@ -1,4 +1,4 @@\n")
+	assert.Contains(t, out, "+changed\n")
+	assert.Contains(t, out, "@@ -16,5 +16,5 @@\n")
+
+	assert.Empty(t, UnifiedDiff("v1", "v2", DiffLines(oldLines, oldLines), 3))
+}
+
+func TestSearchSnapshot(t *testing.T) {
+	chunks := []Chunk{
+		{Index: 0, Text: "apple banana"},
+		{Index: 1, Text: "golang concurrency channels"},
+		{Index: 2, Text: "banana bread recipe"},
+	}
+	matches, err := SearchSnapshot(context.Background(), SnapshotSearchRequest{
+		KnowledgeBaseID: 1,
+		DocumentID:      9,
+		Chunks:          chunks,
+		Query:           "golang",
+		Limit:           5,
+		Metadata:        map[string]interface{}{"version": 2},
+	})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, uint(2), matches[0].ChunkID)
+	assert.Equal(t, uint(9), matches[0].DocumentID)
+	assert.Equal(t, 1, matches[0].Metadata["chunk_index"])
+	assert.Equal(t, 2, matches[0].Metadata["version"])
+}
@@ -0,0 +1,146 @@
+package knowledge
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token 分词结果
+type Token struct {
+	Term     string
+	Position int // 词位置，用于短语匹配；词典词与其首字的二元分词位置相同
+	Start    int // 在原文中的字节偏移
+	End      int
+}
+
+// Tokenizer 全文索引分词器
+type Tokenizer interface {
+	Tokenize(text string) []Token
+}
+
+// StandardTokenizer 标准分词器
+// 字母数字按Unicode词边界切分并转小写；中日韩文本输出相邻二元分词，
+// 同时输出命中词典的长词（细粒度切分，类似ik_max_word）
+type StandardTokenizer struct {
+	dict       map[string]struct{}
+	maxWordLen int // 词典最长词的字数
+}
+
+// NewStandardTokenizer 创建标准分词器，words为可选的中文词典
+func NewStandardTokenizer(words []string) *StandardTokenizer {
+	t := &StandardTokenizer{dict: make(map[string]struct{}, len(words))}
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		n := utf8.RuneCountInString(word)
+		if n < 2 {
+			continue
+		}
+		t.dict[word] = struct{}{}
+		if n > t.maxWordLen {
+			t.maxWordLen = n
+		}
+	}
+	return t
+}
+
+// LoadDictionary 读取词典文件，每行一个词，#开头为注释
+func LoadDictionary(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dictionary: %w", err)
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// 兼容"词 词频 词性"格式的词典
+		if idx := strings.IndexAny(line, " \t"); idx > 0 {
+			line = line[:idx]
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dictionary: %w", err)
+	}
+	return words, nil
+}
+
+// Tokenize 分词
+func (t *StandardTokenizer) Tokenize(text string) []Token {
+	var tokens []Token
+	position := 0
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case isCJK(r):
+			end := i
+			var offsets []int
+			for end < len(text) {
+				r, size := utf8.DecodeRuneInString(text[end:])
+				if !isCJK(r) {
+					break
+				}
+				offsets = append(offsets, end)
+				end += size
+			}
+			offsets = append(offsets, end)
+			tokens = t.appendCJK(tokens, text, offsets, position)
+			// n个字产生n-1个二元分词，单字占一个位置
+			if chars := len(offsets) - 1; chars > 1 {
+				position += chars - 1
+			} else {
+				position++
+			}
+			i = end
+		case isWordRune(r):
+			end := i + size
+			for end < len(text) {
+				r, size := utf8.DecodeRuneInString(text[end:])
+				if !isWordRune(r) || isCJK(r) {
+					break
+				}
+				end += size
+			}
+			tokens = append(tokens, Token{Term: strings.ToLower(text[i:end]), Position: position, Start: i, End: end})
+			position++
+			i = end
+		default:
+			i += size
+		}
+	}
+	return tokens
+}
+
+// appendCJK 输出一段连续中日韩文本的分词，offsets为每个字的起始偏移（末尾附结束偏移）
+func (t *StandardTokenizer) appendCJK(tokens []Token, text string, offsets []int, base int) []Token {
+	chars := len(offsets) - 1
+	if chars == 1 {
+		return append(tokens, Token{Term: text[offsets[0]:offsets[1]], Position: base, Start: offsets[0], End: offsets[1]})
+	}
+
+	for i := 0; i+1 < chars; i++ {
+		tokens = append(tokens, Token{Term: text[offsets[i]:offsets[i+2]], Position: base + i, Start: offsets[i], End: offsets[i+2]})
+		// 二元分词之外再输出以该字开头的词典长词
+		for n := 3; n <= t.maxWordLen && i+n <= chars; n++ {
+			word := text[offsets[i]:offsets[i+n]]
+			if _, ok := t.dict[word]; ok {
+				tokens = append(tokens, Token{Term: word, Position: base + i, Start: offsets[i], End: offsets[i+n]})
+			}
+		}
+	}
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
@@ -0,0 +1,118 @@
+package knowledge
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// cachedVector 缓存中的分块向量，量化时只保留编码
+type cachedVector struct {
+	chunkID    uint
+	documentID uint
+	code       []byte
+	vector     []float32
+}
+
+// kbVectors 一个知识库的全部向量
+type kbVectors struct {
+	knowledgeBaseID uint
+	loadedAt        time.Time
+	vectors         []cachedVector
+}
+
+// kbVectorLoad 进行中的加载，同一知识库的并发查询共享结果
+type kbVectorLoad struct {
+	done    chan struct{}
+	vectors *kbVectors
+	err     error
+}
+
+// kbVectorCache 进程内按知识库缓存向量，LRU淘汰
+// 本进程的写入与删除使缓存立即失效；其他实例的写入依赖有效期，过期后重新加载
+type kbVectorCache struct {
+	max int
+	ttl time.Duration
+
+	mu       sync.Mutex
+	entries  map[uint]*list.Element
+	lru      *list.List
+	versions map[uint]uint64 // 每个知识库的失效次数，加载期间发生失效时不写入缓存
+	loads    map[uint]*kbVectorLoad
+}
+
+func newKBVectorCache(max int, ttl time.Duration) *kbVectorCache {
+	return &kbVectorCache{
+		max:      max,
+		ttl:      ttl,
+		entries:  make(map[uint]*list.Element),
+		lru:      list.New(),
+		versions: make(map[uint]uint64),
+		loads:    make(map[uint]*kbVectorLoad),
+	}
+}
+
+// get 返回知识库的向量，未缓存或已过期时调用load加载
+func (c *kbVectorCache) get(ctx context.Context, knowledgeBaseID uint, load func(context.Context) ([]cachedVector, error)) (*kbVectors, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[knowledgeBaseID]; ok {
+		entry := elem.Value.(*kbVectors)
+		if time.Since(entry.loadedAt) < c.ttl {
+			c.lru.MoveToFront(elem)
+			c.mu.Unlock()
+			return entry, nil
+		}
+		c.removeLocked(elem)
+	}
+	if pending, ok := c.loads[knowledgeBaseID]; ok {
+		c.mu.Unlock()
+		select {
+		case <-pending.done:
+			return pending.vectors, pending.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	pending := &kbVectorLoad{done: make(chan struct{})}
+	c.loads[knowledgeBaseID] = pending
+	version := c.versions[knowledgeBaseID]
+	c.mu.Unlock()
+
+	// 加载结果由等待中的查询共享，不随发起查询的取消而中断
+	vectors, err := load(context.WithoutCancel(ctx))
+	if err == nil {
+		pending.vectors = &kbVectors{knowledgeBaseID: knowledgeBaseID, loadedAt: time.Now(), vectors: vectors}
+	}
+	pending.err = err
+
+	c.mu.Lock()
+	if c.loads[knowledgeBaseID] == pending {
+		delete(c.loads, knowledgeBaseID)
+	}
+	if err == nil && c.versions[knowledgeBaseID] == version {
+		c.entries[knowledgeBaseID] = c.lru.PushFront(pending.vectors)
+		for c.lru.Len() > c.max {
+			c.removeLocked(c.lru.Back())
+		}
+	}
+	c.mu.Unlock()
+	close(pending.done)
+	return pending.vectors, err
+}
+
+// invalidate 使知识库的缓存失效，进行中的加载结果不再写入，之后的查询也不再等待它
+func (c *kbVectorCache) invalidate(knowledgeBaseID uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.versions[knowledgeBaseID]++
+	delete(c.loads, knowledgeBaseID)
+	if elem, ok := c.entries[knowledgeBaseID]; ok {
+		c.removeLocked(elem)
+	}
+}
+
+func (c *kbVectorCache) removeLocked(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*kbVectors).knowledgeBaseID)
+}
@@ -0,0 +1,160 @@
+package knowledge
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// 向量量化方案
+const (
+	QuantizationNone   = "none"
+	QuantizationInt8   = "int8"
+	QuantizationBinary = "binary"
+)
+
+// 编码首字节标记量化方案，切换方案后旧编码不会被误用
+const (
+	quantizedCodeInt8   byte = 1
+	quantizedCodeBinary byte = 2
+
+	// int8编码：方案(1) + 缩放系数(4) + 原始范数(4) + 每维1字节
+	int8CodeHeaderSize = 9
+	// 二值编码：方案(1) + 维度(4) + 每维1位
+	binaryCodeHeaderSize = 5
+)
+
+// normalizeQuantization 规范化量化方案名称，空值视为不量化
+func normalizeQuantization(scheme string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(scheme)) {
+	case "", QuantizationNone:
+		return QuantizationNone, nil
+	case QuantizationInt8:
+		return QuantizationInt8, nil
+	case QuantizationBinary:
+		return QuantizationBinary, nil
+	default:
+		return "", fmt.Errorf("unsupported vector quantization %q", scheme)
+	}
+}
+
+// quantizeVector 按方案编码向量，不量化时返回nil
+func quantizeVector(scheme string, vec []float32) []byte {
+	switch scheme {
+	case QuantizationInt8:
+		return quantizeInt8(vec)
+	case QuantizationBinary:
+		return quantizeBinary(vec)
+	default:
+		return nil
+	}
+}
+
+// quantizedCodeMatches 编码是否由该方案生成
+func quantizedCodeMatches(scheme string, code []byte) bool {
+	switch scheme {
+	case QuantizationInt8:
+		return len(code) >= int8CodeHeaderSize && code[0] == quantizedCodeInt8
+	case QuantizationBinary:
+		return len(code) >= binaryCodeHeaderSize && code[0] == quantizedCodeBinary
+	default:
+		return false
+	}
+}
+
+// quantizeInt8 标量量化：每维按向量内最大绝对值线性映射到[-127, 127]，并保存原始范数用于余弦归一化
+func quantizeInt8(vec []float32) []byte {
+	var maxAbs float64
+	for _, v := range vec {
+		if a := math.Abs(float64(v)); a > maxAbs {
+			maxAbs = a
+		}
+	}
+	code := make([]byte, int8CodeHeaderSize+len(vec))
+	code[0] = quantizedCodeInt8
+	scale := maxAbs / 127
+	binary.LittleEndian.PutUint32(code[1:], math.Float32bits(float32(scale)))
+	binary.LittleEndian.PutUint32(code[5:], math.Float32bits(float32(vectorNorm(vec))))
+	if scale == 0 {
+		return code
+	}
+	for i, v := range vec {
+		code[int8CodeHeaderSize+i] = byte(int8(math.Round(float64(v) / scale)))
+	}
+	return code
+}
+
+// quantizeBinary 二值量化：每维只保留符号位，用汉明距离估计夹角
+func quantizeBinary(vec []float32) []byte {
+	code := make([]byte, binaryCodeHeaderSize+(len(vec)+7)/8)
+	code[0] = quantizedCodeBinary
+	binary.LittleEndian.PutUint32(code[1:], uint32(len(vec)))
+	for i, v := range vec {
+		if v > 0 {
+			code[binaryCodeHeaderSize+i/8] |= 1 << (i % 8)
+		}
+	}
+	return code
+}
+
+// quantizedQuery 预处理后的查询向量，扫描候选时对每个编码复用
+type quantizedQuery struct {
+	scheme string
+	vector []float32
+	norm   float64
+	code   []byte // 二值方案下查询自身的编码
+}
+
+func newQuantizedQuery(scheme string, vec []float32) *quantizedQuery {
+	q := &quantizedQuery{scheme: scheme, vector: vec, norm: vectorNorm(vec)}
+	if scheme == QuantizationBinary {
+		q.code = quantizeBinary(vec)
+	}
+	return q
+}
+
+// similarity 估计与编码向量的余弦相似度，维度不一致时返回false
+// int8使用非对称计算（浮点查询乘量化向量），二值使用cos(π·汉明距离/维度)
+func (q *quantizedQuery) similarity(code []byte) (float64, bool) {
+	switch q.scheme {
+	case QuantizationInt8:
+		values := code[int8CodeHeaderSize:]
+		if len(values) != len(q.vector) {
+			return 0, false
+		}
+		scale := math.Float32frombits(binary.LittleEndian.Uint32(code[1:]))
+		norm := math.Float32frombits(binary.LittleEndian.Uint32(code[5:]))
+		if q.norm == 0 || norm == 0 {
+			return 0, true
+		}
+		var dot float32
+		for i, v := range values {
+			dot += q.vector[i] * float32(int8(v))
+		}
+		return float64(dot*scale) / (q.norm * float64(norm)), true
+	case QuantizationBinary:
+		if len(code) != len(q.code) || binary.LittleEndian.Uint32(code[1:]) != uint32(len(q.vector)) {
+			return 0, false
+		}
+		var distance int
+		for i := binaryCodeHeaderSize; i < len(code); i++ {
+			distance += bits.OnesCount8(code[i] ^ q.code[i])
+		}
+		return math.Cos(math.Pi * float64(distance) / float64(len(q.vector))), true
+	default:
+		return 0, false
+	}
+}
+
+// vectorSimilarity 缓存中向量的相似度：有编码时用量化估计，否则用原始向量精确计算
+func (q *quantizedQuery) vectorSimilarity(v cachedVector) (float64, bool) {
+	if v.code != nil {
+		return q.similarity(v.code)
+	}
+	if len(v.vector) != len(q.vector) {
+		return 0, false
+	}
+	return cosineSimilarity(q.vector, v.vector, q.norm), true
+}
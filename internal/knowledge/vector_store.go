@@ -18,6 +18,9 @@ type VectorSearchRequest struct {
 	Limit           int
 	CandidateLimit  int
 	Threshold       float64 // 相似度阈值，仅返回 >= Threshold 的结果
+
+	DocumentIDs        []uint // 非空时只检索这些文档的分块
+	ExcludeDocumentIDs []uint // 排除的文档，如回收站中仍保留向量的文档
 }
 
 // VectorStore 向量存储抽象
@@ -25,5 +28,22 @@ type VectorStore interface {
 	UpsertChunk(ctx context.Context, chunk VectorChunk) (string, error)
 	DeleteDocument(ctx context.Context, knowledgeBaseID uint, documentID uint) error
 	Search(ctx context.Context, req VectorSearchRequest) ([]SearchMatch, error)
+	// ScanChunks 分批列举知识库中已写入向量的分块，用于跨存储一致性检查，fn返回错误时停止
+	ScanChunks(ctx context.Context, knowledgeBaseID uint, fn func([]IndexedChunk) error) error
 	Ready() bool
 }
+
+// BatchVectorStore 支持批量写入的向量存储（可选接口），返回的向量ID与chunks一一对应
+type BatchVectorStore interface {
+	UpsertChunks(ctx context.Context, chunks []VectorChunk) ([]string, error)
+}
+
+// ChunkVectorDeleter 支持按分块删除向量的存储（可选接口），用于文档增量更新时移除被删除的分块
+type ChunkVectorDeleter interface {
+	DeleteChunks(ctx context.Context, knowledgeBaseID uint, chunkIDs []uint) error
+}
+
+// ChunkEmbeddingSource 支持按分块读取向量的存储（可选接口），用于检索结果多样化
+type ChunkEmbeddingSource interface {
+	ChunkEmbeddings(ctx context.Context, knowledgeBaseID uint, chunkIDs []uint) (map[uint][]float32, error)
+}
@@ -1,21 +1,57 @@
 package knowledge
 
 import (
+	"container/heap"
 	"context"
 	"encoding/json"
 	"fmt"
 	"math"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+// DatabaseVectorOptions 数据库向量存储的量化与缓存配置
+type DatabaseVectorOptions struct {
+	Quantization        string        // none（默认）、int8或binary，编码写入knowledge_chunks.embedding_code
+	RescoreLimit        int           // 量化扫描后用原始向量重新打分的候选数，0时int8为Limit的4倍、binary为10倍
+	CacheKnowledgeBases int           // 进程内缓存向量的知识库数，0不缓存
+	CacheTTL            time.Duration // 缓存有效期，限制其他实例写入后的不一致时间，0使用默认5分钟
+}
+
+// defaultVectorCacheTTL 向量缓存默认有效期
+const defaultVectorCacheTTL = 5 * time.Minute
+
 // DatabaseVectorStore 基于PostgreSQL的退化向量存储
+// 开启量化或缓存后检索分两步：先扫描量化编码（或缓存）选出候选，再读取候选的原始向量精确打分
 type DatabaseVectorStore struct {
-	db *gorm.DB
+	db    *gorm.DB
+	opts  DatabaseVectorOptions
+	cache *kbVectorCache
 }
 
 func NewDatabaseVectorStore(db *gorm.DB) VectorStore {
-	return &DatabaseVectorStore{db: db}
+	return &DatabaseVectorStore{db: db, opts: DatabaseVectorOptions{Quantization: QuantizationNone}}
+}
+
+// NewDatabaseVectorStoreWithOptions 创建带量化与缓存配置的数据库向量存储
+func NewDatabaseVectorStoreWithOptions(db *gorm.DB, opts DatabaseVectorOptions) (*DatabaseVectorStore, error) {
+	scheme, err := normalizeQuantization(opts.Quantization)
+	if err != nil {
+		return nil, err
+	}
+	opts.Quantization = scheme
+	if opts.RescoreLimit < 0 || opts.CacheKnowledgeBases < 0 {
+		return nil, fmt.Errorf("invalid database vector store options: rescore limit %d, cache size %d", opts.RescoreLimit, opts.CacheKnowledgeBases)
+	}
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = defaultVectorCacheTTL
+	}
+	store := &DatabaseVectorStore{db: db, opts: opts}
+	if opts.CacheKnowledgeBases > 0 {
+		store.cache = newKBVectorCache(opts.CacheKnowledgeBases, opts.CacheTTL)
+	}
+	return store, nil
 }
 
 func (s *DatabaseVectorStore) UpsertChunk(ctx context.Context, chunk VectorChunk) (string, error) {
@@ -32,22 +68,56 @@ func (s *DatabaseVectorStore) UpsertChunk(ctx context.Context, chunk VectorChunk
 	err = s.db.WithContext(ctx).Table("knowledge_chunks").
 		Where("chunk_id = ?", chunk.ChunkID).
 		Updates(map[string]interface{}{
-			"vector_id": vectorID,
-			"embedding": string(embeddingJSON),
+			"vector_id":      vectorID,
+			"embedding":      string(embeddingJSON),
+			"embedding_code": quantizeVector(s.opts.Quantization, chunk.Embedding),
 		}).Error
 	if err != nil {
 		return "", err
 	}
+	s.invalidate(chunk.KnowledgeBaseID)
 	return vectorID, nil
 }
 
 func (s *DatabaseVectorStore) DeleteDocument(ctx context.Context, knowledgeBaseID uint, documentID uint) error {
-	return s.db.WithContext(ctx).Table("knowledge_chunks").
+	err := s.db.WithContext(ctx).Table("knowledge_chunks").
 		Where("document_id = ?", documentID).
 		Updates(map[string]interface{}{
-			"vector_id": "",
-			"embedding": "",
+			"vector_id":      "",
+			"embedding":      "",
+			"embedding_code": nil,
 		}).Error
+	if err != nil {
+		return err
+	}
+	s.invalidate(knowledgeBaseID)
+	return nil
+}
+
+// DeleteChunks 清除指定分块的向量
+func (s *DatabaseVectorStore) DeleteChunks(ctx context.Context, knowledgeBaseID uint, chunkIDs []uint) error {
+	if len(chunkIDs) == 0 {
+		return nil
+	}
+	err := s.db.WithContext(ctx).Table("knowledge_chunks").
+		Where("chunk_id IN ?", chunkIDs).
+		Updates(map[string]interface{}{
+			"vector_id":      "",
+			"embedding":      "",
+			"embedding_code": nil,
+		}).Error
+	if err != nil {
+		return err
+	}
+	s.invalidate(knowledgeBaseID)
+	return nil
+}
+
+// invalidate 写入或删除后使知识库的向量缓存失效
+func (s *DatabaseVectorStore) invalidate(knowledgeBaseID uint) {
+	if s.cache != nil {
+		s.cache.invalidate(knowledgeBaseID)
+	}
 }
 
 func (s *DatabaseVectorStore) Search(ctx context.Context, req VectorSearchRequest) ([]SearchMatch, error) {
@@ -57,19 +127,56 @@ func (s *DatabaseVectorStore) Search(ctx context.Context, req VectorSearchReques
 	if req.Limit == 0 {
 		req.Limit = 10
 	}
+	if s.opts.Quantization == QuantizationNone && s.cache == nil {
+		return s.searchExact(ctx, req)
+	}
+
+	query := newQuantizedQuery(s.opts.Quantization, req.QueryEmbedding)
+	if query.norm == 0 {
+		return nil, fmt.Errorf("query embedding norm is zero")
+	}
+	chunkIDs, err := s.candidates(ctx, req, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunkIDs) == 0 {
+		return nil, nil
+	}
+
+	// 第二步：读取候选的原始向量精确打分，已删除的分块在此自然剔除
+	var rows []chunkEmbeddingRecord
+	err = s.db.WithContext(ctx).
+		Table("knowledge_chunks").
+		Select("chunk_id, document_id, content, embedding, metadata").
+		Where("chunk_id IN ?", chunkIDs).
+		Where("embedding IS NOT NULL AND CAST(embedding AS TEXT) <> ''").
+		Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("vector search failed: %w", err)
+	}
+	return scoreChunkRows(req, rows, query.norm), nil
+}
+
+// searchExact 读取至多CandidateLimit个分块的JSON向量逐一计算余弦相似度
+func (s *DatabaseVectorStore) searchExact(ctx context.Context, req VectorSearchRequest) ([]SearchMatch, error) {
 	if req.CandidateLimit == 0 {
 		req.CandidateLimit = req.Limit * 20
 	}
 
-	var rows []chunkEmbeddingRecord
-	err := s.db.WithContext(ctx).
+	query := s.db.WithContext(ctx).
 		Table("knowledge_chunks").
 		Select("knowledge_chunks.chunk_id, knowledge_chunks.document_id, knowledge_chunks.content, knowledge_chunks.embedding, knowledge_chunks.metadata").
 		Joins("JOIN knowledge_documents ON knowledge_chunks.document_id = knowledge_documents.document_id").
 		Where("knowledge_documents.knowledge_base_id = ?", req.KnowledgeBaseID).
-		Where("knowledge_chunks.embedding IS NOT NULL AND knowledge_chunks.embedding::text <> ''").
-		Limit(req.CandidateLimit).
-		Find(&rows).Error
+		Where("knowledge_chunks.embedding IS NOT NULL AND CAST(knowledge_chunks.embedding AS TEXT) <> ''")
+	if len(req.DocumentIDs) > 0 {
+		query = query.Where("knowledge_chunks.document_id IN ?", req.DocumentIDs)
+	}
+	if len(req.ExcludeDocumentIDs) > 0 {
+		query = query.Where("knowledge_chunks.document_id NOT IN ?", req.ExcludeDocumentIDs)
+	}
+	var rows []chunkEmbeddingRecord
+	err := query.Limit(req.CandidateLimit).Find(&rows).Error
 	if err != nil {
 		return nil, fmt.Errorf("vector search failed: %w", err)
 	}
@@ -78,7 +185,11 @@ func (s *DatabaseVectorStore) Search(ctx context.Context, req VectorSearchReques
 	if queryNorm == 0 {
 		return nil, fmt.Errorf("query embedding norm is zero")
 	}
+	return scoreChunkRows(req, rows, queryNorm), nil
+}
 
+// scoreChunkRows 按原始向量计算相似度，过滤阈值后按相似度降序取前Limit个
+func scoreChunkRows(req VectorSearchRequest, rows []chunkEmbeddingRecord, queryNorm float64) []SearchMatch {
 	// 应用阈值过滤
 	threshold := req.Threshold
 	if threshold == 0 {
@@ -96,7 +207,7 @@ func (s *DatabaseVectorStore) Search(ctx context.Context, req VectorSearchReques
 			_ = json.Unmarshal([]byte(row.MetadataJSON), &metadata)
 		}
 		score := cosineSimilarity(req.QueryEmbedding, embedding, queryNorm)
-		
+
 		// 仅保留相似度 >= threshold 的结果
 		if score >= threshold {
 			results = append(results, SearchMatch{
@@ -114,13 +225,220 @@ func (s *DatabaseVectorStore) Search(ctx context.Context, req VectorSearchReques
 	if len(results) > req.Limit {
 		results = results[:req.Limit]
 	}
-	return results, nil
+	return results
+}
+
+// candidates 第一步：按量化编码（未量化时为缓存的原始向量）估计相似度，返回需要精确打分的分块
+func (s *DatabaseVectorStore) candidates(ctx context.Context, req VectorSearchRequest, query *quantizedQuery) ([]uint, error) {
+	n := s.rescoreLimit(req.Limit)
+	top := make(candidateHeap, 0, n)
+	consider := func(v cachedVector) {
+		if score, ok := query.vectorSimilarity(v); ok {
+			top.offer(scoredCandidate{chunkID: v.chunkID, score: score}, n)
+		}
+	}
+
+	if s.cache != nil {
+		entry, err := s.cache.get(ctx, req.KnowledgeBaseID, func(ctx context.Context) ([]cachedVector, error) {
+			var vectors []cachedVector
+			err := s.scanVectors(ctx, req.KnowledgeBaseID, nil, nil, func(batch []cachedVector) error {
+				vectors = append(vectors, batch...)
+				return nil
+			})
+			return vectors, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		include := uintSet(req.DocumentIDs)
+		exclude := uintSet(req.ExcludeDocumentIDs)
+		for _, v := range entry.vectors {
+			if (include != nil && !include[v.documentID]) || exclude[v.documentID] {
+				continue
+			}
+			consider(v)
+		}
+	} else {
+		err := s.scanVectors(ctx, req.KnowledgeBaseID, req.DocumentIDs, req.ExcludeDocumentIDs, func(batch []cachedVector) error {
+			for _, v := range batch {
+				consider(v)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	chunkIDs := make([]uint, len(top))
+	for i, c := range top {
+		chunkIDs[i] = c.chunkID
+	}
+	return chunkIDs, nil
+}
+
+// rescoreLimit 第一步保留的候选数，未量化时第一步已是精确分数
+func (s *DatabaseVectorStore) rescoreLimit(limit int) int {
+	n := s.opts.RescoreLimit
+	switch {
+	case s.opts.Quantization == QuantizationNone:
+		n = limit
+	case n == 0 && s.opts.Quantization == QuantizationBinary:
+		n = limit * 10
+	case n == 0:
+		n = limit * 4
+	}
+	if n < limit {
+		n = limit
+	}
+	return n
+}
+
+// scanVectors 按分块ID分批读取知识库的量化编码，批次之间不保留数据
+// 缺少当前方案编码的分块（开启量化前写入或未量化）读取JSON向量，量化时现场编码
+func (s *DatabaseVectorStore) scanVectors(ctx context.Context, knowledgeBaseID uint, documentIDs, excludeDocumentIDs []uint, fn func([]cachedVector) error) error {
+	var lastID uint
+	for {
+		query := s.db.WithContext(ctx).
+			Table("knowledge_chunks AS c").
+			Select("c.chunk_id, c.document_id, c.embedding_code").
+			Joins("JOIN knowledge_documents d ON c.document_id = d.document_id").
+			Where("d.knowledge_base_id = ? AND c.chunk_id > ?", knowledgeBaseID, lastID).
+			// 有编码时不再读取JSON列判断是否为空
+			Where("c.embedding_code IS NOT NULL OR (c.embedding IS NOT NULL AND CAST(c.embedding AS TEXT) <> '')")
+		if len(documentIDs) > 0 {
+			query = query.Where("c.document_id IN ?", documentIDs)
+		}
+		if len(excludeDocumentIDs) > 0 {
+			query = query.Where("c.document_id NOT IN ?", excludeDocumentIDs)
+		}
+		var rows []chunkCodeRecord
+		if err := query.Order("c.chunk_id").Limit(scanBatchSize).Scan(&rows).Error; err != nil {
+			return fmt.Errorf("vector search failed: %w", err)
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		var missing []uint
+		for _, row := range rows {
+			if !quantizedCodeMatches(s.opts.Quantization, row.EmbeddingCode) {
+				missing = append(missing, row.ChunkID)
+			}
+		}
+		embeddings, err := s.ChunkEmbeddings(ctx, knowledgeBaseID, missing)
+		if err != nil {
+			return err
+		}
+
+		vectors := make([]cachedVector, 0, len(rows))
+		for _, row := range rows {
+			v := cachedVector{chunkID: row.ChunkID, documentID: row.DocumentID}
+			if quantizedCodeMatches(s.opts.Quantization, row.EmbeddingCode) {
+				v.code = row.EmbeddingCode
+			} else if embedding, ok := embeddings[row.ChunkID]; !ok {
+				continue
+			} else if s.opts.Quantization == QuantizationNone {
+				v.vector = embedding
+			} else {
+				v.code = quantizeVector(s.opts.Quantization, embedding)
+			}
+			vectors = append(vectors, v)
+		}
+		if err := fn(vectors); err != nil {
+			return err
+		}
+		if len(rows) < scanBatchSize {
+			return nil
+		}
+		lastID = rows[len(rows)-1].ChunkID
+	}
+}
+
+func uintSet(ids []uint) map[uint]bool {
+	if len(ids) == 0 {
+		return nil
+	}
+	set := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// scoredCandidate 第一步的候选及估计相似度
+type scoredCandidate struct {
+	chunkID uint
+	score   float64
+}
+
+// candidateHeap 保留估计相似度最高的N个候选，堆顶为其中最低分
+type candidateHeap []scoredCandidate
+
+func (h candidateHeap) Len() int            { return len(h) }
+func (h candidateHeap) Less(i, j int) bool  { return h[i].score < h[j].score }
+func (h candidateHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *candidateHeap) Push(x interface{}) { *h = append(*h, x.(scoredCandidate)) }
+func (h *candidateHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+func (h *candidateHeap) offer(c scoredCandidate, n int) {
+	if len(*h) < n {
+		heap.Push(h, c)
+		return
+	}
+	if c.score > (*h)[0].score {
+		(*h)[0] = c
+		heap.Fix(h, 0)
+	}
+}
+
+// ScanChunks 列举已保存向量的分块，向量与分块同行保存，不返回哈希
+func (s *DatabaseVectorStore) ScanChunks(ctx context.Context, knowledgeBaseID uint, fn func([]IndexedChunk) error) error {
+	return scanChunkRows(ctx, s.db, knowledgeBaseID, "c.embedding IS NOT NULL AND CAST(c.embedding AS TEXT) <> ''", fn)
+}
+
+// ChunkEmbeddings 读取分块向量，没有向量的分块不在结果中
+func (s *DatabaseVectorStore) ChunkEmbeddings(ctx context.Context, knowledgeBaseID uint, chunkIDs []uint) (map[uint][]float32, error) {
+	if len(chunkIDs) == 0 {
+		return nil, nil
+	}
+	var rows []chunkEmbeddingRecord
+	err := s.db.WithContext(ctx).
+		Table("knowledge_chunks").
+		Select("chunk_id, embedding").
+		Where("chunk_id IN ?", chunkIDs).
+		Where("embedding IS NOT NULL AND CAST(embedding AS TEXT) <> ''").
+		Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chunk embeddings: %w", err)
+	}
+
+	embeddings := make(map[uint][]float32, len(rows))
+	for _, row := range rows {
+		var embedding []float32
+		if err := json.Unmarshal([]byte(row.EmbeddingJSON), &embedding); err == nil && len(embedding) > 0 {
+			embeddings[row.ChunkID] = embedding
+		}
+	}
+	return embeddings, nil
 }
 
 func (s *DatabaseVectorStore) Ready() bool {
 	return s.db != nil
 }
 
+// chunkCodeRecord 量化扫描读取的分块编码
+type chunkCodeRecord struct {
+	ChunkID       uint
+	DocumentID    uint
+	EmbeddingCode []byte `gorm:"column:embedding_code"`
+}
+
 type chunkEmbeddingRecord struct {
 	ChunkID       uint
 	DocumentID    uint
@@ -164,3 +482,34 @@ func cosineSimilarity(a, b []float32, normA float64) float64 {
 
 	return dot / (normA * math.Sqrt(normB))
 }
+
+// errDatabaseVectorVersions 数据库向量存储不支持版本化重建
+var errDatabaseVectorVersions = fmt.Errorf("database vector store: %w", ErrReindexUnsupported)
+
+// 向量与分块同行保存在knowledge_chunks中，没有可以切换的物理索引，版本化操作均返回ErrReindexUnsupported；
+// 更换嵌入模型或维度需要删除文档向量后重新入库
+
+// CurrentVersion 不支持版本化重建，返回ErrReindexUnsupported
+func (s *DatabaseVectorStore) CurrentVersion(ctx context.Context, knowledgeBaseID uint) (int, error) {
+	return 0, errDatabaseVectorVersions
+}
+
+// CreateVersion 不支持版本化重建，返回ErrReindexUnsupported
+func (s *DatabaseVectorStore) CreateVersion(ctx context.Context, knowledgeBaseID uint, version int, dimension int) error {
+	return errDatabaseVectorVersions
+}
+
+// UpsertChunkVersion 不支持版本化重建，返回ErrReindexUnsupported
+func (s *DatabaseVectorStore) UpsertChunkVersion(ctx context.Context, version int, chunk VectorChunk) error {
+	return errDatabaseVectorVersions
+}
+
+// SwapVersion 不支持版本化重建，返回ErrReindexUnsupported
+func (s *DatabaseVectorStore) SwapVersion(ctx context.Context, knowledgeBaseID uint, version int) error {
+	return errDatabaseVectorVersions
+}
+
+// DropVersion 不支持版本化重建，返回ErrReindexUnsupported
+func (s *DatabaseVectorStore) DropVersion(ctx context.Context, knowledgeBaseID uint, version int) error {
+	return errDatabaseVectorVersions
+}
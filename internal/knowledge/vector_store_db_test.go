@@ -0,0 +1,306 @@
+package knowledge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newSQLiteVectorDB 创建只含向量检索所需列的SQLite库
+func newSQLiteVectorDB(tb testing.TB) *gorm.DB {
+	tb.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(tb.TempDir(), "vectors.db")+"?_pragma=synchronous(OFF)&_pragma=journal_mode(MEMORY)"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(tb, err)
+	require.NoError(tb, db.Exec(`CREATE TABLE knowledge_documents (document_id INTEGER PRIMARY KEY, knowledge_base_id INTEGER NOT NULL)`).Error)
+	require.NoError(tb, db.Exec(`CREATE TABLE knowledge_chunks (chunk_id INTEGER PRIMARY KEY, document_id INTEGER NOT NULL, content TEXT, vector_id TEXT, embedding TEXT, embedding_code BLOB, metadata TEXT)`).Error)
+	return db
+}
+
+// seedVectorChunks 写入分块及JSON向量，scheme非none时同时写入编码；分块ID从1开始，每100个分块一个文档
+func seedVectorChunks(tb testing.TB, db *gorm.DB, knowledgeBaseID uint, vectors [][]float32, scheme string) {
+	tb.Helper()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i, vec := range vectors {
+			documentID := uint(i/100 + 1)
+			if i%100 == 0 {
+				if err := tx.Exec(`INSERT INTO knowledge_documents (document_id, knowledge_base_id) VALUES (?, ?)`, documentID, knowledgeBaseID).Error; err != nil {
+					return err
+				}
+			}
+			embedding, _ := json.Marshal(vec)
+			if err := tx.Exec(`INSERT INTO knowledge_chunks (chunk_id, document_id, content, embedding, embedding_code) VALUES (?, ?, ?, ?, ?)`,
+				i+1, documentID, fmt.Sprintf("chunk %d", i+1), string(embedding), quantizeVector(scheme, vec)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(tb, err)
+}
+
+// clusteredVectors 生成围绕若干中心分布的随机向量，近似真实嵌入的聚簇结构
+func clusteredVectors(rng *rand.Rand, n, dim, clusters int) [][]float32 {
+	centers := make([][]float32, clusters)
+	for i := range centers {
+		centers[i] = make([]float32, dim)
+		for j := range centers[i] {
+			centers[i][j] = float32(rng.NormFloat64())
+		}
+	}
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		center := centers[rng.Intn(clusters)]
+		vectors[i] = make([]float32, dim)
+		for j := range vectors[i] {
+			vectors[i][j] = center[j] + float32(rng.NormFloat64()*0.6)
+		}
+	}
+	return vectors
+}
+
+// exactTopK 暴力计算与查询最相似的k个分块ID
+func exactTopK(vectors [][]float32, query []float32, k int) []uint {
+	norm := vectorNorm(query)
+	ids := make([]uint, len(vectors))
+	scores := make([]float64, len(vectors))
+	for i, vec := range vectors {
+		ids[i] = uint(i + 1)
+		scores[i] = cosineSimilarity(query, vec, norm)
+	}
+	sort.Slice(ids, func(a, b int) bool { return scores[ids[a]-1] > scores[ids[b]-1] })
+	return ids[:k]
+}
+
+func recallAt(matches []SearchMatch, expected []uint) float64 {
+	want := uintSet(expected)
+	hits := 0
+	for _, m := range matches {
+		if want[m.ChunkID] {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(expected))
+}
+
+func TestQuantizeVector(t *testing.T) {
+	vec := []float32{0.5, -0.25, 0.1, 0, -0.9, 0.3, 0.7, -0.2, 0.05}
+	exact := cosineSimilarity(vec, vec, vectorNorm(vec))
+
+	code := quantizeVector(QuantizationInt8, vec)
+	require.True(t, quantizedCodeMatches(QuantizationInt8, code))
+	assert.False(t, quantizedCodeMatches(QuantizationBinary, code))
+	score, ok := newQuantizedQuery(QuantizationInt8, vec).similarity(code)
+	require.True(t, ok)
+	assert.InDelta(t, exact, score, 0.01)
+
+	code = quantizeVector(QuantizationBinary, vec)
+	require.True(t, quantizedCodeMatches(QuantizationBinary, code))
+	assert.Len(t, code, binaryCodeHeaderSize+2)
+	score, ok = newQuantizedQuery(QuantizationBinary, vec).similarity(code)
+	require.True(t, ok)
+	assert.InDelta(t, 1, score, 1e-9)
+	opposite := make([]float32, len(vec))
+	for i, v := range vec {
+		opposite[i] = -v
+	}
+	// 值为0的一维两者编码相同，其余维度相反
+	score, _ = newQuantizedQuery(QuantizationBinary, opposite).similarity(code)
+	assert.Less(t, score, -0.9)
+
+	// 维度不一致的编码不参与打分
+	_, ok = newQuantizedQuery(QuantizationInt8, vec[:4]).similarity(quantizeVector(QuantizationInt8, vec))
+	assert.False(t, ok)
+	_, ok = newQuantizedQuery(QuantizationBinary, vec[:4]).similarity(quantizeVector(QuantizationBinary, vec))
+	assert.False(t, ok)
+
+	assert.Nil(t, quantizeVector(QuantizationNone, vec))
+	_, err := normalizeQuantization("pq")
+	assert.Error(t, err)
+}
+
+func TestDatabaseVectorStore_QuantizedSearchRescoresExactly(t *testing.T) {
+	for _, scheme := range []string{QuantizationInt8, QuantizationBinary} {
+		t.Run(scheme, func(t *testing.T) {
+			db := newSQLiteVectorDB(t)
+			vectors := clusteredVectors(rand.New(rand.NewSource(1)), 300, 32, 8)
+			// 前100个分块在开启量化前写入，只有JSON向量
+			seedVectorChunks(t, db, 7, vectors[:100], QuantizationNone)
+			store, err := NewDatabaseVectorStoreWithOptions(db, DatabaseVectorOptions{Quantization: scheme})
+			require.NoError(t, err)
+			for i, vec := range vectors[100:] {
+				chunkID := uint(i + 101)
+				documentID := uint(i/100 + 2)
+				if i%100 == 0 {
+					require.NoError(t, db.Exec(`INSERT INTO knowledge_documents (document_id, knowledge_base_id) VALUES (?, 7)`, documentID).Error)
+				}
+				require.NoError(t, db.Exec(`INSERT INTO knowledge_chunks (chunk_id, document_id, content) VALUES (?, ?, ?)`, chunkID, documentID, "c").Error)
+				_, err := store.UpsertChunk(context.Background(), VectorChunk{ChunkID: chunkID, DocumentID: documentID, KnowledgeBaseID: 7, Embedding: vec})
+				require.NoError(t, err)
+			}
+
+			query := vectors[42]
+			matches, err := store.Search(context.Background(), VectorSearchRequest{KnowledgeBaseID: 7, QueryEmbedding: query, Limit: 10, Threshold: -1})
+			require.NoError(t, err)
+			require.Len(t, matches, 10)
+			assert.Equal(t, uint(42+1), matches[0].ChunkID)
+			// 返回的是原始向量的精确分数
+			assert.InDelta(t, 1, matches[0].Score, 1e-6)
+			assert.GreaterOrEqual(t, recallAt(matches, exactTopK(vectors, query, 10)), 0.8)
+
+			// 文档过滤在量化扫描阶段生效
+			matches, err = store.Search(context.Background(), VectorSearchRequest{
+				KnowledgeBaseID: 7, QueryEmbedding: query, Limit: 10, Threshold: -1,
+				DocumentIDs: []uint{1, 2}, ExcludeDocumentIDs: []uint{1},
+			})
+			require.NoError(t, err)
+			require.NotEmpty(t, matches)
+			for _, m := range matches {
+				assert.Equal(t, uint(2), m.DocumentID)
+			}
+		})
+	}
+}
+
+func TestDatabaseVectorStore_CacheInvalidatedOnWrite(t *testing.T) {
+	db := newSQLiteVectorDB(t)
+	seedVectorChunks(t, db, 7, [][]float32{{1, 0, 0}, {0, 1, 0}}, QuantizationInt8)
+	store, err := NewDatabaseVectorStoreWithOptions(db, DatabaseVectorOptions{Quantization: QuantizationInt8, CacheKnowledgeBases: 2})
+	require.NoError(t, err)
+	ctx := context.Background()
+	search := func() []SearchMatch {
+		matches, err := store.Search(ctx, VectorSearchRequest{KnowledgeBaseID: 7, QueryEmbedding: []float32{0, 0, 1}, Limit: 5, Threshold: 0.5})
+		require.NoError(t, err)
+		return matches
+	}
+	assert.Empty(t, search())
+
+	// 绕过存储写入的分块在缓存有效期内不可见
+	require.NoError(t, db.Exec(`INSERT INTO knowledge_chunks (chunk_id, document_id, content) VALUES (3, 1, 'c')`).Error)
+	require.NoError(t, db.Exec(`UPDATE knowledge_chunks SET embedding = '[0,0,1]' WHERE chunk_id = 3`).Error)
+	assert.Empty(t, search())
+
+	_, err = store.UpsertChunk(ctx, VectorChunk{ChunkID: 3, DocumentID: 1, KnowledgeBaseID: 7, Embedding: []float32{0, 0.1, 1}})
+	require.NoError(t, err)
+	matches := search()
+	require.Len(t, matches, 1)
+	assert.Equal(t, uint(3), matches[0].ChunkID)
+
+	require.NoError(t, store.DeleteDocument(ctx, 7, 1))
+	assert.Empty(t, search())
+}
+
+func TestDatabaseVectorStore_DeleteChunks(t *testing.T) {
+	db := newSQLiteVectorDB(t)
+	seedVectorChunks(t, db, 7, [][]float32{{1, 0, 0}, {0.9, 0.1, 0}, {0, 1, 0}}, QuantizationNone)
+	store, err := NewDatabaseVectorStoreWithOptions(db, DatabaseVectorOptions{CacheKnowledgeBases: 2})
+	require.NoError(t, err)
+	ctx := context.Background()
+	search := func() []SearchMatch {
+		matches, err := store.Search(ctx, VectorSearchRequest{KnowledgeBaseID: 7, QueryEmbedding: []float32{1, 0, 0}, Limit: 5, Threshold: 0.5})
+		require.NoError(t, err)
+		return matches
+	}
+	require.Len(t, search(), 2)
+
+	require.NoError(t, store.DeleteChunks(ctx, 7, []uint{1}))
+	matches := search()
+	require.Len(t, matches, 1)
+	assert.Equal(t, uint(2), matches[0].ChunkID)
+}
+
+func TestKBVectorCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newKBVectorCache(2, defaultVectorCacheTTL)
+	ctx := context.Background()
+	loads := map[uint]int{}
+	get := func(kb uint) {
+		_, err := cache.get(ctx, kb, func(context.Context) ([]cachedVector, error) {
+			loads[kb]++
+			return []cachedVector{{chunkID: kb}}, nil
+		})
+		require.NoError(t, err)
+	}
+	get(1)
+	get(2)
+	get(1)
+	get(3) // 淘汰最久未用的2
+	get(1)
+	get(2)
+	assert.Equal(t, map[uint]int{1: 1, 2: 2, 3: 1}, loads)
+
+	cache.invalidate(1)
+	get(1)
+	assert.Equal(t, 2, loads[1])
+}
+
+// BenchmarkDatabaseVectorStore_Search 比较精确扫描JSON向量与量化两步检索的延迟和召回率
+// go test -run=^$ -bench=DatabaseVectorStore_Search ./internal/knowledge/
+func BenchmarkDatabaseVectorStore_Search(b *testing.B) {
+	const (
+		size    = 5000
+		dim     = 256
+		queries = 50
+		k       = 10
+	)
+	rng := rand.New(rand.NewSource(42))
+	vectors := clusteredVectors(rng, size, dim, 50)
+	queryVectors := make([][]float32, queries)
+	truth := make([][]uint, queries)
+	for i := range queryVectors {
+		base := vectors[rng.Intn(size)]
+		queryVectors[i] = make([]float32, dim)
+		for j := range base {
+			queryVectors[i][j] = base[j] + float32(rng.NormFloat64()*0.8)
+		}
+		truth[i] = exactTopK(vectors, queryVectors[i], k)
+	}
+
+	cases := []struct {
+		name           string
+		opts           DatabaseVectorOptions
+		candidateLimit int
+	}{
+		// 现有默认行为：只读取Limit*20个分块
+		{name: "exact-default", opts: DatabaseVectorOptions{}},
+		{name: "exact", opts: DatabaseVectorOptions{}, candidateLimit: size},
+		{name: "int8", opts: DatabaseVectorOptions{Quantization: QuantizationInt8}},
+		{name: "binary", opts: DatabaseVectorOptions{Quantization: QuantizationBinary}},
+		{name: "exact-cached", opts: DatabaseVectorOptions{CacheKnowledgeBases: 1}},
+		{name: "int8-cached", opts: DatabaseVectorOptions{Quantization: QuantizationInt8, CacheKnowledgeBases: 1}},
+		{name: "binary-cached", opts: DatabaseVectorOptions{Quantization: QuantizationBinary, CacheKnowledgeBases: 1}},
+	}
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			db := newSQLiteVectorDB(b)
+			seedVectorChunks(b, db, 1, vectors, tc.opts.Quantization)
+			store, err := NewDatabaseVectorStoreWithOptions(db, tc.opts)
+			require.NoError(b, err)
+			ctx := context.Background()
+
+			var recall float64
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				q := i % queries
+				matches, err := store.Search(ctx, VectorSearchRequest{
+					KnowledgeBaseID: 1, QueryEmbedding: queryVectors[q], Limit: k, CandidateLimit: tc.candidateLimit, Threshold: -1,
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+				recall += recallAt(matches, truth[q])
+			}
+			b.ReportMetric(recall/float64(b.N), "recall@10")
+		})
+	}
+}
@@ -2,9 +2,13 @@ package knowledge
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/milvus-io/milvus-sdk-go/v2/client"
@@ -24,12 +28,16 @@ type MilvusOptions struct {
 	Timeout         time.Duration
 }
 
+// milvusVectorStore 每个知识库的集合名是别名，指向带版本号的物理集合，重建时切换别名
 type milvusVectorStore struct {
-	milvusClient    client.Client
+	milvusClient     client.Client
 	collectionPrefix string
-	vectorSize      int
-	distance        string
-	database        string
+	vectorSize       int
+	distance         string
+	database         string
+
+	mu      sync.Mutex
+	pending map[uint]int // 知识库ID -> 重建中的版本，删除操作同时作用于该版本
 }
 
 // NewMilvusVectorStore 创建Milvus向量存储
@@ -85,6 +93,7 @@ func NewMilvusVectorStore(opts MilvusOptions) (VectorStore, error) {
 		vectorSize:      opts.VectorSize,
 		distance:        formatMilvusDistance(opts.Distance),
 		database:        opts.Database,
+		pending:         make(map[uint]int),
 	}, nil
 }
 
@@ -103,20 +112,64 @@ func (s *milvusVectorStore) collectionName(kbID uint) string {
 	return fmt.Sprintf("%s_%d", s.collectionPrefix, kbID)
 }
 
-func (s *milvusVectorStore) ensureCollection(ctx context.Context, kbID uint) error {
+// ensureCollection 确保知识库的集合存在，返回其向量维度
+// 新知识库直接创建第一个版本并挂上别名
+func (s *milvusVectorStore) ensureCollection(ctx context.Context, kbID uint) (int, error) {
 	name := s.collectionName(kbID)
 
-	// 检查集合是否存在
+	collection, exists, err := s.describeCollection(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		return collectionDimension(collection, s.vectorSize), nil
+	}
+
+	physical := versionedName(name, 1)
+	if err := s.createCollection(ctx, physical, kbID, s.vectorSize); err != nil {
+		return 0, err
+	}
+	if err := s.milvusClient.CreateAlias(ctx, physical, name); err != nil {
+		return 0, fmt.Errorf("failed to create collection alias: %w", err)
+	}
+	return s.vectorSize, nil
+}
+
+// describeCollection 查询集合（或别名指向的集合），不存在时exists为false
+func (s *milvusVectorStore) describeCollection(ctx context.Context, name string) (*entity.Collection, bool, error) {
 	hasCollection, err := s.milvusClient.HasCollection(ctx, name)
 	if err != nil {
-		return fmt.Errorf("failed to check collection: %w", err)
+		return nil, false, fmt.Errorf("failed to check collection: %w", err)
 	}
+	if !hasCollection {
+		return nil, false, nil
+	}
+	collection, err := s.milvusClient.DescribeCollection(ctx, name)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to describe collection: %w", err)
+	}
+	return collection, true, nil
+}
 
-	if hasCollection {
-		return nil
+// collectionDimension 读取集合向量字段的维度
+func collectionDimension(collection *entity.Collection, fallback int) int {
+	if collection == nil || collection.Schema == nil {
+		return fallback
 	}
+	for _, field := range collection.Schema.Fields {
+		if field.DataType != entity.FieldTypeFloatVector {
+			continue
+		}
+		var dim int
+		if _, err := fmt.Sscanf(field.TypeParams["dim"], "%d", &dim); err == nil && dim > 0 {
+			return dim
+		}
+	}
+	return fallback
+}
 
-	// 创建集合
+// createCollection 创建物理集合并建立向量索引
+func (s *milvusVectorStore) createCollection(ctx context.Context, name string, kbID uint, dimension int) error {
 	schema := &entity.Schema{
 		CollectionName: name,
 		Description:    fmt.Sprintf("Knowledge base %d vectors", kbID),
@@ -150,7 +203,7 @@ func (s *milvusVectorStore) ensureCollection(ctx context.Context, kbID uint) err
 				Name:     "vector",
 				DataType: entity.FieldTypeFloatVector,
 				TypeParams: map[string]string{
-					"dim": fmt.Sprintf("%d", s.vectorSize),
+					"dim": fmt.Sprintf("%d", dimension),
 				},
 			},
 		},
@@ -198,23 +251,24 @@ func (s *milvusVectorStore) UpsertChunk(ctx context.Context, chunk VectorChunk)
 	if len(chunk.Embedding) == 0 {
 		return "", fmt.Errorf("embedding is empty")
 	}
-	if len(chunk.Embedding) != s.vectorSize {
-		embedding := make([]float32, s.vectorSize)
-		copy(embedding, chunk.Embedding)
-		if len(chunk.Embedding) < s.vectorSize {
-			// 如果向量维度不足，用0填充
-			for i := len(chunk.Embedding); i < s.vectorSize; i++ {
-				embedding[i] = 0
-			}
-		}
-		chunk.Embedding = embedding
-	}
 
-	if err := s.ensureCollection(ctx, chunk.KnowledgeBaseID); err != nil {
+	dimension, err := s.ensureCollection(ctx, chunk.KnowledgeBaseID)
+	if err != nil {
+		return "", err
+	}
+	if err := s.insertChunk(ctx, s.collectionName(chunk.KnowledgeBaseID), dimension, chunk, true); err != nil {
 		return "", err
 	}
+	return fmt.Sprintf("milvus_%d", chunk.ChunkID), nil
+}
 
-	collectionName := s.collectionName(chunk.KnowledgeBaseID)
+// insertChunk 写入分块到指定集合（别名或物理集合），向量维度不一致时截断或补0
+func (s *milvusVectorStore) insertChunk(ctx context.Context, collectionName string, dimension int, chunk VectorChunk, flush bool) error {
+	if len(chunk.Embedding) != dimension {
+		embedding := make([]float32, dimension)
+		copy(embedding, chunk.Embedding)
+		chunk.Embedding = embedding
+	}
 
 	// 准备数据列
 	idColumn := entity.NewColumnInt64("id", []int64{int64(chunk.ChunkID)})
@@ -222,41 +276,60 @@ func (s *milvusVectorStore) UpsertChunk(ctx context.Context, chunk VectorChunk)
 	documentIDColumn := entity.NewColumnInt64("document_id", []int64{int64(chunk.DocumentID)})
 	knowledgeBaseIDColumn := entity.NewColumnInt64("knowledge_base_id", []int64{int64(chunk.KnowledgeBaseID)})
 	contentColumn := entity.NewColumnVarChar("content", []string{chunk.Text})
-	vectorColumn := entity.NewColumnFloatVector("vector", s.vectorSize, [][]float32{chunk.Embedding})
+	vectorColumn := entity.NewColumnFloatVector("vector", dimension, [][]float32{chunk.Embedding})
 
 	// 插入数据
 	_, err := s.milvusClient.Insert(ctx, collectionName, "", idColumn, chunkIDColumn, documentIDColumn, knowledgeBaseIDColumn, contentColumn, vectorColumn)
 	if err != nil {
-		return "", fmt.Errorf("milvus insert failed: %w", err)
+		return fmt.Errorf("milvus insert failed: %w", err)
 	}
 
 	// 刷新数据
-	if err := s.milvusClient.Flush(ctx, collectionName, false); err != nil {
-		// 刷新失败不影响插入，只记录警告
-		fmt.Printf("warning: failed to flush collection %s: %v\n", collectionName, err)
+	if flush {
+		if err := s.milvusClient.Flush(ctx, collectionName, false); err != nil {
+			// 刷新失败不影响插入，只记录警告
+			fmt.Printf("warning: failed to flush collection %s: %v\n", collectionName, err)
+		}
 	}
-
-	return fmt.Sprintf("milvus_%d", chunk.ChunkID), nil
+	return nil
 }
 
 func (s *milvusVectorStore) DeleteDocument(ctx context.Context, knowledgeBaseID uint, documentID uint) error {
-	if err := s.ensureCollection(ctx, knowledgeBaseID); err != nil {
-		return err
-	}
+	return s.deleteWhere(ctx, knowledgeBaseID, fmt.Sprintf("document_id == %d", documentID))
+}
 
-	collectionName := s.collectionName(knowledgeBaseID)
+// DeleteChunks 删除指定分块的向量
+func (s *milvusVectorStore) DeleteChunks(ctx context.Context, knowledgeBaseID uint, chunkIDs []uint) error {
+	if len(chunkIDs) == 0 {
+		return nil
+	}
+	return s.deleteWhere(ctx, knowledgeBaseID, "chunk_id in "+milvusIDList(chunkIDs))
+}
 
-	// 构建删除表达式
-	expr := fmt.Sprintf("document_id == %d", documentID)
+// deleteWhere 按表达式删除知识库集合中的向量
+func (s *milvusVectorStore) deleteWhere(ctx context.Context, knowledgeBaseID uint, expr string) error {
+	if _, err := s.ensureCollection(ctx, knowledgeBaseID); err != nil {
+		return err
+	}
 
-	// 删除数据
-	if err := s.milvusClient.Delete(ctx, collectionName, "", expr); err != nil {
-		return fmt.Errorf("milvus delete failed: %w", err)
+	// 重建中的版本同样删除，避免切换别名后已删除的数据重新出现
+	collections := []string{s.collectionName(knowledgeBaseID)}
+	s.mu.Lock()
+	if version, ok := s.pending[knowledgeBaseID]; ok {
+		collections = append(collections, versionedName(s.collectionName(knowledgeBaseID), version))
 	}
+	s.mu.Unlock()
 
-	// 刷新数据
-	if err := s.milvusClient.Flush(ctx, collectionName, false); err != nil {
-		fmt.Printf("warning: failed to flush after delete: %v\n", err)
+	for _, collectionName := range collections {
+		// 删除数据
+		if err := s.milvusClient.Delete(ctx, collectionName, "", expr); err != nil {
+			return fmt.Errorf("milvus delete failed: %w", err)
+		}
+
+		// 刷新数据
+		if err := s.milvusClient.Flush(ctx, collectionName, false); err != nil {
+			fmt.Printf("warning: failed to flush after delete: %v\n", err)
+		}
 	}
 
 	return nil
@@ -266,15 +339,29 @@ func (s *milvusVectorStore) Search(ctx context.Context, req VectorSearchRequest)
 	if len(req.QueryEmbedding) == 0 {
 		return nil, nil
 	}
-	if err := s.ensureCollection(ctx, req.KnowledgeBaseID); err != nil {
+	if _, err := s.ensureCollection(ctx, req.KnowledgeBaseID); err != nil {
 		return nil, err
 	}
+	return s.searchCollection(ctx, s.collectionName(req.KnowledgeBaseID), req)
+}
+
+// SearchVersion 检索指定版本的物理集合（模型迁移期间双读），集合按需加载
+func (s *milvusVectorStore) SearchVersion(ctx context.Context, version int, req VectorSearchRequest) ([]SearchMatch, error) {
+	if len(req.QueryEmbedding) == 0 {
+		return nil, nil
+	}
+	name := versionedName(s.collectionName(req.KnowledgeBaseID), version)
+	if err := s.milvusClient.LoadCollection(ctx, name, false); err != nil {
+		return nil, fmt.Errorf("failed to load collection %s: %w", name, err)
+	}
+	return s.searchCollection(ctx, name, req)
+}
+
+func (s *milvusVectorStore) searchCollection(ctx context.Context, collectionName string, req VectorSearchRequest) ([]SearchMatch, error) {
 	if req.Limit == 0 {
 		req.Limit = 10
 	}
 
-	collectionName := s.collectionName(req.KnowledgeBaseID)
-
 	// 执行搜索 - 使用HNSW搜索参数
 	sp, _ := entity.NewIndexHNSWSearchParam(64)
 	// 将 []float32 转换为 entity.Vector
@@ -283,7 +370,7 @@ func (s *milvusVectorStore) Search(ctx context.Context, req VectorSearchRequest)
 		ctx,
 		collectionName,
 		[]string{},
-		"",
+		milvusDocumentExpr(req),
 		[]string{"chunk_id", "document_id", "knowledge_base_id", "content"},
 		[]entity.Vector{queryVector},
 		"vector",
@@ -410,6 +497,109 @@ func (s *milvusVectorStore) Search(ctx context.Context, req VectorSearchRequest)
 	return results, nil
 }
 
+// milvusDocumentExpr 按文档过滤的标量表达式，没有过滤条件时为空
+func milvusDocumentExpr(req VectorSearchRequest) string {
+	var conditions []string
+	if len(req.DocumentIDs) > 0 {
+		conditions = append(conditions, "document_id in "+milvusIDList(req.DocumentIDs))
+	}
+	if len(req.ExcludeDocumentIDs) > 0 {
+		conditions = append(conditions, "document_id not in "+milvusIDList(req.ExcludeDocumentIDs))
+	}
+	return strings.Join(conditions, " && ")
+}
+
+func milvusIDList(ids []uint) string {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return "[" + strings.Join(values, ",") + "]"
+}
+
+// ChunkEmbeddings 按分块ID读取当前版本集合中的向量
+func (s *milvusVectorStore) ChunkEmbeddings(ctx context.Context, knowledgeBaseID uint, chunkIDs []uint) (map[uint][]float32, error) {
+	if len(chunkIDs) == 0 {
+		return nil, nil
+	}
+	expr := "chunk_id in " + milvusIDList(chunkIDs)
+
+	resultSet, err := s.milvusClient.Query(ctx, s.collectionName(knowledgeBaseID), nil, expr, []string{"chunk_id", "vector"})
+	if err != nil {
+		return nil, fmt.Errorf("milvus query failed: %w", err)
+	}
+	idColumn, ok := resultSet.GetColumn("chunk_id").(*entity.ColumnInt64)
+	if !ok {
+		return nil, fmt.Errorf("milvus query returned no chunk_id column")
+	}
+	vectorColumn, ok := resultSet.GetColumn("vector").(*entity.ColumnFloatVector)
+	if !ok {
+		return nil, fmt.Errorf("milvus query returned no vector column")
+	}
+
+	embeddings := make(map[uint][]float32, idColumn.Len())
+	vectors := vectorColumn.Data()
+	for i, id := range idColumn.Data() {
+		if i < len(vectors) {
+			embeddings[uint(id)] = vectors[i]
+		}
+	}
+	return embeddings, nil
+}
+
+// ScanChunks 用查询迭代器按主键分批列举当前版本集合中的分块，集合不存在时没有分块
+func (s *milvusVectorStore) ScanChunks(ctx context.Context, knowledgeBaseID uint, fn func([]IndexedChunk) error) error {
+	name := s.collectionName(knowledgeBaseID)
+	if _, exists, err := s.describeCollection(ctx, name); err != nil || !exists {
+		return err
+	}
+
+	iterator, err := s.milvusClient.QueryIterator(ctx, client.NewQueryIteratorOption(name).
+		WithOutputFields("chunk_id", "document_id", "content").
+		WithBatchSize(scanBatchSize))
+	if err != nil {
+		return fmt.Errorf("milvus query iterator failed: %w", err)
+	}
+	for {
+		resultSet, err := iterator.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("milvus query iterator failed: %w", err)
+		}
+
+		chunkColumn, ok := resultSet.GetColumn("chunk_id").(*entity.ColumnInt64)
+		if !ok {
+			return fmt.Errorf("milvus query returned no chunk_id column")
+		}
+		documentColumn, ok := resultSet.GetColumn("document_id").(*entity.ColumnInt64)
+		if !ok {
+			return fmt.Errorf("milvus query returned no document_id column")
+		}
+		contentColumn, ok := resultSet.GetColumn("content").(*entity.ColumnVarChar)
+		if !ok {
+			return fmt.Errorf("milvus query returned no content column")
+		}
+
+		chunkIDs, documentIDs, contents := chunkColumn.Data(), documentColumn.Data(), contentColumn.Data()
+		batch := make([]IndexedChunk, 0, len(chunkIDs))
+		for i, chunkID := range chunkIDs {
+			chunk := IndexedChunk{ChunkID: uint(chunkID)}
+			if i < len(documentIDs) {
+				chunk.DocumentID = uint(documentIDs[i])
+			}
+			if i < len(contents) {
+				chunk.ContentHash = ChunkHash(contents[i])
+			}
+			batch = append(batch, chunk)
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+}
+
 func (s *milvusVectorStore) Ready() bool {
 	if s.milvusClient == nil {
 		return false
@@ -421,3 +611,120 @@ func (s *milvusVectorStore) Ready() bool {
 	return err == nil
 }
 
+// CurrentVersion 别名当前指向的版本
+func (s *milvusVectorStore) CurrentVersion(ctx context.Context, knowledgeBaseID uint) (int, error) {
+	alias := s.collectionName(knowledgeBaseID)
+	collection, exists, err := s.describeCollection(ctx, alias)
+	if err != nil || !exists {
+		return 0, err
+	}
+	return parseVersion(alias, collection.Name), nil
+}
+
+// CreateVersion 创建新版本的物理集合，同名的残留集合（上次重建中断）会先删除
+func (s *milvusVectorStore) CreateVersion(ctx context.Context, knowledgeBaseID uint, version int, dimension int) error {
+	if dimension <= 0 {
+		dimension = s.vectorSize
+	}
+	name := versionedName(s.collectionName(knowledgeBaseID), version)
+	if err := s.dropCollection(ctx, name); err != nil {
+		return err
+	}
+	if err := s.createCollection(ctx, name, knowledgeBaseID, dimension); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.pending[knowledgeBaseID] = version
+	s.mu.Unlock()
+	return nil
+}
+
+// UpsertChunkVersion 写入分块到指定版本，由SwapVersion统一刷新
+func (s *milvusVectorStore) UpsertChunkVersion(ctx context.Context, version int, chunk VectorChunk) error {
+	if len(chunk.Embedding) == 0 {
+		return fmt.Errorf("embedding is empty")
+	}
+	name := versionedName(s.collectionName(chunk.KnowledgeBaseID), version)
+	collection, exists, err := s.describeCollection(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("collection %s not found", name)
+	}
+	return s.insertChunk(ctx, name, collectionDimension(collection, s.vectorSize), chunk, false)
+}
+
+// SwapVersion 将别名切换到新版本并删除旧集合
+// 已版本化的集合通过AlterAlias原子切换；未版本化的旧集合与别名同名，
+// 需要先删除旧集合再创建别名，两步之间检索会短暂失败
+func (s *milvusVectorStore) SwapVersion(ctx context.Context, knowledgeBaseID uint, version int) error {
+	alias := s.collectionName(knowledgeBaseID)
+	name := versionedName(alias, version)
+
+	if err := s.milvusClient.Flush(ctx, name, false); err != nil {
+		return fmt.Errorf("failed to flush collection %s: %w", name, err)
+	}
+	if err := s.milvusClient.LoadCollection(ctx, name, false); err != nil {
+		return fmt.Errorf("failed to load collection %s: %w", name, err)
+	}
+
+	current, exists, err := s.describeCollection(ctx, alias)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !exists:
+		err = s.milvusClient.CreateAlias(ctx, name, alias)
+	case current.Name == alias:
+		if err = s.milvusClient.DropCollection(ctx, alias); err == nil {
+			err = s.milvusClient.CreateAlias(ctx, name, alias)
+		}
+	default:
+		err = s.milvusClient.AlterAlias(ctx, name, alias)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to swap collection alias: %w", err)
+	}
+
+	if exists && current.Name != alias && current.Name != name {
+		if err := s.dropCollection(ctx, current.Name); err != nil {
+			fmt.Printf("warning: failed to drop old collection %s: %v\n", current.Name, err)
+		}
+	}
+
+	s.mu.Lock()
+	delete(s.pending, knowledgeBaseID)
+	s.mu.Unlock()
+	return nil
+}
+
+// DropVersion 删除未启用的版本
+func (s *milvusVectorStore) DropVersion(ctx context.Context, knowledgeBaseID uint, version int) error {
+	s.mu.Lock()
+	if s.pending[knowledgeBaseID] == version {
+		delete(s.pending, knowledgeBaseID)
+	}
+	s.mu.Unlock()
+
+	if version <= 0 {
+		return nil
+	}
+	return s.dropCollection(ctx, versionedName(s.collectionName(knowledgeBaseID), version))
+}
+
+// dropCollection 删除物理集合，集合不存在时忽略
+func (s *milvusVectorStore) dropCollection(ctx context.Context, name string) error {
+	hasCollection, err := s.milvusClient.HasCollection(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to check collection: %w", err)
+	}
+	if !hasCollection {
+		return nil
+	}
+	if err := s.milvusClient.DropCollection(ctx, name); err != nil {
+		return fmt.Errorf("failed to drop collection %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,685 @@
+package knowledge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// PgvectorOptions pgvector向量存储配置
+type PgvectorOptions struct {
+	TablePrefix    string // 每个向量维度一张表：<前缀>_<维度>，默认knowledge_vectors
+	Index          string // 近似索引：hnsw（默认）或ivfflat
+	Distance       string // COSINE（默认）、L2或IP
+	M              int    // HNSW每层的连接数，0使用默认值16
+	EfConstruction int    // HNSW建索引时的候选数，0使用默认值64
+	Lists          int    // IVFFlat聚类数，0使用默认值100
+	Probes         int    // IVFFlat检索时探查的聚类数，0使用默认值10
+}
+
+const (
+	// pgvectorMaxIndexDimensions HNSW与IVFFlat支持的最大维度，更高维度的表只能顺序扫描
+	pgvectorMaxIndexDimensions = 2000
+	// pgvectorMaxEfSearch hnsw.ef_search的上限
+	pgvectorMaxEfSearch = 1000
+	// pgvectorUpsertBatchSize 批量写入时每条INSERT的行数
+	pgvectorUpsertBatchSize = 500
+	// pgvectorExactScanRows 不支持迭代扫描时，过滤后行数不超过该值则改为精确扫描
+	pgvectorExactScanRows = 10000
+)
+
+var pgvectorIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// pgvectorDistance 距离度量对应的运算符与索引操作符类
+type pgvectorDistance struct {
+	operator string
+	opclass  string
+}
+
+var pgvectorDistances = map[string]pgvectorDistance{
+	"COSINE": {operator: "<=>", opclass: "vector_cosine_ops"},
+	"L2":     {operator: "<->", opclass: "vector_l2_ops"},
+	"IP":     {operator: "<#>", opclass: "vector_ip_ops"},
+}
+
+// PgvectorVectorStore 基于pgvector扩展的向量存储
+// 向量保存在vector(n)列中，每个维度一张表并建立HNSW或IVFFlat索引，表在首次写入该维度时创建；
+// 检索在SQL中按知识库与文档过滤并按距离排序，内容与元数据从knowledge_chunks读取
+type PgvectorVectorStore struct {
+	db       *gorm.DB
+	opts     PgvectorOptions
+	distance pgvectorDistance
+	// iterativeScan 扩展版本不低于0.8，近似索引过滤后结果不足时可继续扫描
+	iterativeScan bool
+
+	mu       sync.Mutex
+	tables   map[int]bool // 已存在的维度表，nil表示尚未从数据库加载
+	createMu sync.Mutex   // 串行创建维度表，并发的CREATE TABLE IF NOT EXISTS可能冲突
+}
+
+// NewPgvectorVectorStore 创建pgvector向量存储，数据库未安装vector扩展时尝试创建
+func NewPgvectorVectorStore(db *gorm.DB, opts PgvectorOptions) (*PgvectorVectorStore, error) {
+	if opts.TablePrefix == "" {
+		opts.TablePrefix = "knowledge_vectors"
+	}
+	if !pgvectorIdentifier.MatchString(opts.TablePrefix) {
+		return nil, fmt.Errorf("invalid pgvector table prefix %q", opts.TablePrefix)
+	}
+	opts.Index = strings.ToLower(opts.Index)
+	if opts.Index == "" {
+		opts.Index = "hnsw"
+	}
+	if opts.Index != "hnsw" && opts.Index != "ivfflat" {
+		return nil, fmt.Errorf("unsupported pgvector index %q", opts.Index)
+	}
+	distance, ok := pgvectorDistances[formatMilvusDistance(opts.Distance)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported pgvector distance %q", opts.Distance)
+	}
+	if opts.M == 0 {
+		opts.M = 16
+	}
+	if opts.EfConstruction == 0 {
+		opts.EfConstruction = 64
+	}
+	if opts.Lists == 0 {
+		opts.Lists = 100
+	}
+	if opts.Probes == 0 {
+		opts.Probes = 10
+	}
+
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS vector").Error; err != nil {
+		return nil, fmt.Errorf("failed to enable pgvector extension: %w", err)
+	}
+	var version string
+	if err := db.Raw("SELECT extversion FROM pg_extension WHERE extname = 'vector'").Scan(&version).Error; err != nil {
+		return nil, fmt.Errorf("failed to read pgvector version: %w", err)
+	}
+	return &PgvectorVectorStore{db: db, opts: opts, distance: distance, iterativeScan: pgvectorSupportsIterativeScan(version)}, nil
+}
+
+// pgvectorSupportsIterativeScan 迭代索引扫描自pgvector 0.8.0起支持
+func pgvectorSupportsIterativeScan(version string) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	return major > 0 || minor >= 8
+}
+
+func (s *PgvectorVectorStore) tableName(dimension int) string {
+	return fmt.Sprintf("%s_%d", s.opts.TablePrefix, dimension)
+}
+
+// dimensions 已存在的维度表，首次调用时从数据库目录加载
+func (s *PgvectorVectorStore) dimensions(ctx context.Context) ([]int, error) {
+	return s.loadDimensions(ctx, false)
+}
+
+// freshDimensions 重新从数据库目录加载维度表，包含其他副本在本进程加载之后创建的表；
+// 删除与列举向量前使用，避免遗漏这些表中的向量
+func (s *PgvectorVectorStore) freshDimensions(ctx context.Context) ([]int, error) {
+	return s.loadDimensions(ctx, true)
+}
+
+func (s *PgvectorVectorStore) loadDimensions(ctx context.Context, refresh bool) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tables == nil || refresh {
+		var names []string
+		err := s.db.WithContext(ctx).Raw(
+			"SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_name LIKE ?",
+			escapeLike(s.opts.TablePrefix+"_")+"%").
+			Scan(&names).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pgvector tables: %w", err)
+		}
+		tables := make(map[int]bool, len(names))
+		added := make(map[int]bool)
+		for _, name := range names {
+			if dimension, err := strconv.Atoi(strings.TrimPrefix(name, s.opts.TablePrefix+"_")); err == nil && dimension > 0 {
+				tables[dimension] = true
+				if !s.tables[dimension] {
+					added[dimension] = true
+				}
+			}
+		}
+		// 只检查新发现的表的索引
+		if len(added) > 0 {
+			if err := s.syncIndexes(ctx, added); err != nil {
+				return nil, err
+			}
+		}
+		s.tables = tables
+	}
+	dimensions := make([]int, 0, len(s.tables))
+	for dimension := range s.tables {
+		dimensions = append(dimensions, dimension)
+	}
+	sort.Ints(dimensions)
+	return dimensions, nil
+}
+
+// hasDimension 是否已有该维度的表，缓存中没有时重新加载一次（表可能由其他副本创建）
+func (s *PgvectorVectorStore) hasDimension(ctx context.Context, dimension int) (bool, error) {
+	dimensions, err := s.dimensions(ctx)
+	if err != nil {
+		return false, err
+	}
+	if containsDimension(dimensions, dimension) {
+		return true, nil
+	}
+	if dimensions, err = s.freshDimensions(ctx); err != nil {
+		return false, err
+	}
+	return containsDimension(dimensions, dimension), nil
+}
+
+func containsDimension(dimensions []int, dimension int) bool {
+	i := sort.SearchInts(dimensions, dimension)
+	return i < len(dimensions) && dimensions[i] == dimension
+}
+
+// ensureTable 创建维度表与索引，超过索引维度上限时只建表
+func (s *PgvectorVectorStore) ensureTable(ctx context.Context, dimension int) error {
+	s.createMu.Lock()
+	defer s.createMu.Unlock()
+	exists, err := s.hasDimension(ctx, dimension)
+	if err != nil || exists {
+		return err
+	}
+
+	table := s.tableName(dimension)
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			chunk_id bigint PRIMARY KEY,
+			knowledge_base_id bigint NOT NULL,
+			document_id bigint NOT NULL,
+			embedding vector(%d) NOT NULL,
+			update_time timestamptz NOT NULL DEFAULT NOW()
+		)`, table, dimension),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_kb_doc ON %s (knowledge_base_id, document_id)", table, table),
+	}
+	if dimension <= pgvectorMaxIndexDimensions {
+		statements = append(statements, s.indexStatement(table))
+	}
+	for _, statement := range statements {
+		if err := s.db.WithContext(ctx).Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to create pgvector table %s: %w", table, err)
+		}
+	}
+
+	s.mu.Lock()
+	s.tables[dimension] = true
+	s.mu.Unlock()
+	return nil
+}
+
+// syncIndexes 为已有的维度表补建或重建近似索引，使索引类型与距离度量与当前配置一致
+// 迁移回填的表与更换配置前创建的表都在首次加载时处理
+func (s *PgvectorVectorStore) syncIndexes(ctx context.Context, tables map[int]bool) error {
+	var indexes []struct {
+		IndexName string
+		IndexDef  string
+	}
+	err := s.db.WithContext(ctx).Raw(
+		"SELECT indexname AS index_name, indexdef AS index_def FROM pg_indexes WHERE schemaname = current_schema() AND tablename LIKE ?",
+		escapeLike(s.opts.TablePrefix+"_")+"%").
+		Scan(&indexes).Error
+	if err != nil {
+		return fmt.Errorf("failed to list pgvector indexes: %w", err)
+	}
+	definitions := make(map[string]string, len(indexes))
+	for _, index := range indexes {
+		definitions[index.IndexName] = index.IndexDef
+	}
+
+	dimensions := make([]int, 0, len(tables))
+	for dimension := range tables {
+		dimensions = append(dimensions, dimension)
+	}
+	sort.Ints(dimensions)
+	for _, dimension := range dimensions {
+		if dimension > pgvectorMaxIndexDimensions {
+			continue
+		}
+		table := s.tableName(dimension)
+		definition, exists := definitions["idx_"+table+"_embedding"]
+		if exists && s.indexMatches(definition) {
+			continue
+		}
+		if exists {
+			if err := s.db.WithContext(ctx).Exec("DROP INDEX IF EXISTS idx_" + table + "_embedding").Error; err != nil {
+				return fmt.Errorf("failed to drop pgvector index on %s: %w", table, err)
+			}
+		}
+		if err := s.db.WithContext(ctx).Exec(s.indexStatement(table)).Error; err != nil {
+			return fmt.Errorf("failed to create pgvector index on %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// indexMatches 已有索引的类型与操作符类是否与配置一致，不比较m等构建参数
+func (s *PgvectorVectorStore) indexMatches(definition string) bool {
+	return strings.Contains(definition, "USING "+s.opts.Index+" ") && strings.Contains(definition, s.distance.opclass)
+}
+
+func (s *PgvectorVectorStore) indexStatement(table string) string {
+	if s.opts.Index == "ivfflat" {
+		return fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_embedding ON %s USING ivfflat (embedding %s) WITH (lists = %d)",
+			table, table, s.distance.opclass, s.opts.Lists)
+	}
+	return fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_embedding ON %s USING hnsw (embedding %s) WITH (m = %d, ef_construction = %d)",
+		table, table, s.distance.opclass, s.opts.M, s.opts.EfConstruction)
+}
+
+func (s *PgvectorVectorStore) UpsertChunk(ctx context.Context, chunk VectorChunk) (string, error) {
+	ids, err := s.UpsertChunks(ctx, []VectorChunk{chunk})
+	if err != nil {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+// UpsertChunks 按维度分组批量写入，同一分块在其他维度表中的旧向量（更换模型前写入）一并删除
+func (s *PgvectorVectorStore) UpsertChunks(ctx context.Context, chunks []VectorChunk) ([]string, error) {
+	byDimension := make(map[int][]VectorChunk)
+	for _, chunk := range chunks {
+		if len(chunk.Embedding) == 0 {
+			return nil, fmt.Errorf("embedding is empty for chunk %d", chunk.ChunkID)
+		}
+		byDimension[len(chunk.Embedding)] = append(byDimension[len(chunk.Embedding)], chunk)
+	}
+	for dimension := range byDimension {
+		if err := s.ensureTable(ctx, dimension); err != nil {
+			return nil, err
+		}
+	}
+	dimensions, err := s.freshDimensions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for dimension, group := range byDimension {
+			for start := 0; start < len(group); start += pgvectorUpsertBatchSize {
+				end := start + pgvectorUpsertBatchSize
+				if end > len(group) {
+					end = len(group)
+				}
+				if err := s.insertBatch(tx, dimension, group[start:end]); err != nil {
+					return err
+				}
+			}
+
+			chunkIDs := make([]uint, len(group))
+			for i, chunk := range group {
+				chunkIDs[i] = chunk.ChunkID
+			}
+			for _, other := range dimensions {
+				if other == dimension {
+					continue
+				}
+				if err := tx.Exec("DELETE FROM "+s.tableName(other)+" WHERE chunk_id IN ?", chunkIDs).Error; err != nil {
+					return fmt.Errorf("failed to remove stale vectors: %w", err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		ids[i] = fmt.Sprintf("pg_%d", chunk.ChunkID)
+	}
+	return ids, nil
+}
+
+func (s *PgvectorVectorStore) insertBatch(tx *gorm.DB, dimension int, chunks []VectorChunk) error {
+	var b strings.Builder
+	b.WriteString("INSERT INTO " + s.tableName(dimension) + " (chunk_id, knowledge_base_id, document_id, embedding) VALUES ")
+	args := make([]interface{}, 0, len(chunks)*4)
+	for i, chunk := range chunks {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(?, ?, ?, ?::vector)")
+		args = append(args, chunk.ChunkID, chunk.KnowledgeBaseID, chunk.DocumentID, formatVector(chunk.Embedding))
+	}
+	b.WriteString(` ON CONFLICT (chunk_id) DO UPDATE SET knowledge_base_id = EXCLUDED.knowledge_base_id,
+		document_id = EXCLUDED.document_id, embedding = EXCLUDED.embedding, update_time = NOW()`)
+	if err := tx.Exec(b.String(), args...).Error; err != nil {
+		return fmt.Errorf("failed to upsert vectors: %w", err)
+	}
+	return nil
+}
+
+func (s *PgvectorVectorStore) DeleteDocument(ctx context.Context, knowledgeBaseID uint, documentID uint) error {
+	dimensions, err := s.freshDimensions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, dimension := range dimensions {
+		err := s.db.WithContext(ctx).Exec(
+			"DELETE FROM "+s.tableName(dimension)+" WHERE knowledge_base_id = ? AND document_id = ?",
+			knowledgeBaseID, documentID).Error
+		if err != nil {
+			return fmt.Errorf("failed to delete document vectors: %w", err)
+		}
+	}
+	return nil
+}
+
+// DeleteChunks 删除指定分块的向量
+func (s *PgvectorVectorStore) DeleteChunks(ctx context.Context, knowledgeBaseID uint, chunkIDs []uint) error {
+	if len(chunkIDs) == 0 {
+		return nil
+	}
+	dimensions, err := s.freshDimensions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, dimension := range dimensions {
+		err := s.db.WithContext(ctx).Exec(
+			"DELETE FROM "+s.tableName(dimension)+" WHERE knowledge_base_id = ? AND chunk_id IN ?",
+			knowledgeBaseID, chunkIDs).Error
+		if err != nil {
+			return fmt.Errorf("failed to delete chunk vectors: %w", err)
+		}
+	}
+	return nil
+}
+
+// Search 在查询向量维度的表中检索，近似索引的候选数按CandidateLimit设置
+func (s *PgvectorVectorStore) Search(ctx context.Context, req VectorSearchRequest) ([]SearchMatch, error) {
+	if len(req.QueryEmbedding) == 0 {
+		return nil, nil
+	}
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+	if req.CandidateLimit == 0 {
+		req.CandidateLimit = req.Limit * 20
+	}
+	dimension := len(req.QueryEmbedding)
+	exists, err := s.hasDimension(ctx, dimension)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	table := s.tableName(dimension)
+	filter := "knowledge_base_id = ?"
+	filterArgs := []interface{}{req.KnowledgeBaseID}
+	if len(req.DocumentIDs) > 0 {
+		filter += " AND document_id IN ?"
+		filterArgs = append(filterArgs, req.DocumentIDs)
+	}
+	if len(req.ExcludeDocumentIDs) > 0 {
+		filter += " AND document_id NOT IN ?"
+		filterArgs = append(filterArgs, req.ExcludeDocumentIDs)
+	}
+	args := append([]interface{}{formatVector(req.QueryEmbedding)}, filterArgs...)
+	args = append(args, req.Limit)
+
+	var rows []pgvectorMatchRecord
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 带过滤条件时近似索引先取候选再过滤，候选数不足会漏掉结果：
+		// 支持迭代扫描时继续扫描索引直到结果足够，否则过滤后行数较少时改为精确扫描
+		exact := false
+		if !s.iterativeScan && dimension <= pgvectorMaxIndexDimensions {
+			var count int64
+			err := tx.Raw("SELECT count(*) FROM (SELECT 1 FROM "+table+" WHERE "+filter+" LIMIT ?) f",
+				append(filterArgs, pgvectorExactScanRows+1)...).
+				Scan(&count).Error
+			if err != nil {
+				return err
+			}
+			exact = count <= pgvectorExactScanRows
+		}
+		for _, setting := range s.searchSettings(req.CandidateLimit, exact) {
+			if err := tx.Exec(setting).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Raw(`
+			SELECT v.chunk_id, v.document_id, c.content, c.metadata, v.distance
+			FROM (
+				SELECT chunk_id, document_id, embedding `+s.distance.operator+` ?::vector AS distance
+				FROM `+table+`
+				WHERE `+filter+`
+				ORDER BY distance
+				LIMIT ?
+			) v
+			JOIN knowledge_chunks c ON c.chunk_id = v.chunk_id
+			ORDER BY v.distance, v.chunk_id`, args...).
+			Scan(&rows).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("pgvector search failed: %w", err)
+	}
+
+	threshold := req.Threshold
+	if threshold == 0 {
+		threshold = 0.9 // 默认阈值0.9
+	}
+	results := make([]SearchMatch, 0, len(rows))
+	for _, row := range rows {
+		score := s.similarity(row.Distance)
+		if score < threshold {
+			continue
+		}
+		var metadata map[string]interface{}
+		if row.MetadataJSON != "" {
+			_ = json.Unmarshal([]byte(row.MetadataJSON), &metadata)
+		}
+		results = append(results, SearchMatch{
+			ChunkID:    row.ChunkID,
+			DocumentID: row.DocumentID,
+			Content:    row.Content,
+			Score:      score,
+			Metadata:   metadata,
+		})
+	}
+	return results, nil
+}
+
+// searchSettings 本次检索事务的索引参数，精确扫描时禁用索引扫描（按知识库过滤仍可走位图扫描）
+// 迭代扫描中HNSW保持严格距离顺序，IVFFlat只支持relaxed_order，外层查询会重新排序
+func (s *PgvectorVectorStore) searchSettings(candidateLimit int, exact bool) []string {
+	if exact {
+		return []string{"SET LOCAL enable_indexscan = off"}
+	}
+	if s.opts.Index == "ivfflat" {
+		settings := []string{fmt.Sprintf("SET LOCAL ivfflat.probes = %d", s.opts.Probes)}
+		if s.iterativeScan {
+			settings = append(settings, "SET LOCAL ivfflat.iterative_scan = relaxed_order")
+		}
+		return settings
+	}
+	efSearch := candidateLimit
+	if efSearch < 40 {
+		efSearch = 40
+	}
+	if efSearch > pgvectorMaxEfSearch {
+		efSearch = pgvectorMaxEfSearch
+	}
+	settings := []string{fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", efSearch)}
+	if s.iterativeScan {
+		settings = append(settings, "SET LOCAL hnsw.iterative_scan = strict_order")
+	}
+	return settings
+}
+
+// similarity 距离转换为越大越相似的分数：余弦为1-距离，内积运算符返回负内积，L2为1/(1+距离)
+func (s *PgvectorVectorStore) similarity(distance float64) float64 {
+	switch s.distance.operator {
+	case "<#>":
+		return -distance
+	case "<->":
+		return 1 / (1 + distance)
+	default:
+		return 1 - distance
+	}
+}
+
+// ScanChunks 按维度表依次分批列举知识库的分块，不保存内容，不返回哈希
+func (s *PgvectorVectorStore) ScanChunks(ctx context.Context, knowledgeBaseID uint, fn func([]IndexedChunk) error) error {
+	dimensions, err := s.freshDimensions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, dimension := range dimensions {
+		var lastID uint
+		for {
+			var batch []IndexedChunk
+			err := s.db.WithContext(ctx).
+				Table(s.tableName(dimension)).
+				Select("chunk_id, document_id").
+				Where("knowledge_base_id = ? AND chunk_id > ?", knowledgeBaseID, lastID).
+				Order("chunk_id").
+				Limit(scanBatchSize).
+				Scan(&batch).Error
+			if err != nil {
+				return fmt.Errorf("failed to scan pgvector chunks: %w", err)
+			}
+			if len(batch) == 0 {
+				break
+			}
+			if err := fn(batch); err != nil {
+				return err
+			}
+			if len(batch) < scanBatchSize {
+				break
+			}
+			lastID = batch[len(batch)-1].ChunkID
+		}
+	}
+	return nil
+}
+
+// ChunkEmbeddings 读取分块向量，没有向量的分块不在结果中
+func (s *PgvectorVectorStore) ChunkEmbeddings(ctx context.Context, knowledgeBaseID uint, chunkIDs []uint) (map[uint][]float32, error) {
+	if len(chunkIDs) == 0 {
+		return nil, nil
+	}
+	dimensions, err := s.dimensions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	embeddings := make(map[uint][]float32, len(chunkIDs))
+	for _, dimension := range dimensions {
+		var rows []chunkEmbeddingRecord
+		err := s.db.WithContext(ctx).
+			Table(s.tableName(dimension)).
+			Select("chunk_id, embedding::text AS embedding").
+			Where("knowledge_base_id = ? AND chunk_id IN ?", knowledgeBaseID, chunkIDs).
+			Scan(&rows).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to load chunk embeddings: %w", err)
+		}
+		for _, row := range rows {
+			if embedding, err := parseVector(row.EmbeddingJSON); err == nil && len(embedding) > 0 {
+				embeddings[row.ChunkID] = embedding
+			}
+		}
+	}
+	return embeddings, nil
+}
+
+func (s *PgvectorVectorStore) Ready() bool {
+	return s.db != nil
+}
+
+type pgvectorMatchRecord struct {
+	ChunkID      uint
+	DocumentID   uint
+	Content      string
+	MetadataJSON string `gorm:"column:metadata"`
+	Distance     float64
+}
+
+// formatVector 向量的文本表示，如[0.1,0.2]
+func formatVector(vec []float32) string {
+	b := make([]byte, 0, len(vec)*10+2)
+	b = append(b, '[')
+	for i, v := range vec {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = strconv.AppendFloat(b, float64(v), 'g', -1, 32)
+	}
+	return string(append(b, ']'))
+}
+
+// parseVector 解析vector的文本表示
+func parseVector(text string) ([]float32, error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "[") || !strings.HasSuffix(text, "]") {
+		return nil, fmt.Errorf("invalid vector %q", text)
+	}
+	text = strings.TrimSpace(text[1 : len(text)-1])
+	if text == "" {
+		return nil, nil
+	}
+	parts := strings.Split(text, ",")
+	vec := make([]float32, len(parts))
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vector element %q: %w", part, err)
+		}
+		vec[i] = float32(v)
+	}
+	return vec, nil
+}
+
+// errPgvectorVersions pgvector向量存储不支持版本化重建
+var errPgvectorVersions = fmt.Errorf("pgvector store: %w", ErrReindexUnsupported)
+
+// 表按向量维度共享，知识库没有独立的物理索引可以切换，版本化操作均返回ErrReindexUnsupported；
+// 更换嵌入模型或维度需要删除文档向量后重新入库
+
+// CurrentVersion 不支持版本化重建，返回ErrReindexUnsupported
+func (s *PgvectorVectorStore) CurrentVersion(ctx context.Context, knowledgeBaseID uint) (int, error) {
+	return 0, errPgvectorVersions
+}
+
+// CreateVersion 不支持版本化重建，返回ErrReindexUnsupported
+func (s *PgvectorVectorStore) CreateVersion(ctx context.Context, knowledgeBaseID uint, version int, dimension int) error {
+	return errPgvectorVersions
+}
+
+// UpsertChunkVersion 不支持版本化重建，返回ErrReindexUnsupported
+func (s *PgvectorVectorStore) UpsertChunkVersion(ctx context.Context, version int, chunk VectorChunk) error {
+	return errPgvectorVersions
+}
+
+// SwapVersion 不支持版本化重建，返回ErrReindexUnsupported
+func (s *PgvectorVectorStore) SwapVersion(ctx context.Context, knowledgeBaseID uint, version int) error {
+	return errPgvectorVersions
+}
+
+// DropVersion 不支持版本化重建，返回ErrReindexUnsupported
+func (s *PgvectorVectorStore) DropVersion(ctx context.Context, knowledgeBaseID uint, version int) error {
+	return errPgvectorVersions
+}
@@ -0,0 +1,53 @@
+package knowledge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPgvectorVectorStore_PostgresFilteredSearch(t *testing.T) {
+	db := newPostgresTestDB(t)
+	var available bool
+	require.NoError(t, db.Raw("SELECT EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'vector')").Scan(&available).Error)
+	if !available {
+		t.Skip("Skipping pgvector test: vector extension not available")
+	}
+	ctx := context.Background()
+
+	// 知识库8的1000个分块都比知识库7的分块更接近查询向量
+	require.NoError(t, db.Exec(`INSERT INTO knowledge_documents (document_id, knowledge_base_id, title) VALUES (1, 7, 'small'), (2, 8, 'large')`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO knowledge_chunks (chunk_id, document_id, content, embedding) VALUES
+		(1, 1, 'a', '[0,1,0,0]'), (2, 1, 'b', '[0,0,1,0]'), (3, 1, 'c', '[0.5,0.5,0,0]')`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO knowledge_chunks (chunk_id, document_id, content, embedding)
+		SELECT 100 + g, 2, 'noise', '[1,' || (g * 0.0001) || ',0,0]' FROM generate_series(1, 1000) g`).Error)
+	require.NoError(t, db.Exec(`ALTER TABLE knowledge_chunks ALTER COLUMN embedding TYPE json USING embedding::json`).Error)
+	applyMigration(t, db, "000014_knowledge_pgvector.up.sql")
+
+	// 迁移回填后由存储按配置建立索引
+	store, err := NewPgvectorVectorStore(db, PgvectorOptions{Index: "hnsw", Distance: "L2"})
+	require.NoError(t, err)
+	dimensions, err := store.dimensions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{4}, dimensions)
+	var definition string
+	require.NoError(t, db.Raw("SELECT indexdef FROM pg_indexes WHERE schemaname = current_schema() AND indexname = 'idx_knowledge_vectors_4_embedding'").Scan(&definition).Error)
+	assert.Contains(t, definition, "USING hnsw")
+	assert.Contains(t, definition, "vector_l2_ops")
+
+	// 近似索引的候选全部属于知识库8，过滤后仍应返回知识库7的全部分块
+	matches, err := store.Search(ctx, VectorSearchRequest{
+		KnowledgeBaseID: 7,
+		QueryEmbedding:  []float32{1, 0, 0, 0},
+		Limit:           3,
+		Threshold:       -1,
+	})
+	require.NoError(t, err)
+	require.Len(t, matches, 3)
+	assert.Equal(t, uint(3), matches[0].ChunkID)
+	for _, match := range matches {
+		assert.Equal(t, uint(1), match.DocumentID)
+	}
+}
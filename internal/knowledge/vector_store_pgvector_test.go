@@ -0,0 +1,231 @@
+package knowledge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatVector(t *testing.T) {
+	text := formatVector([]float32{0.25, -1, 1e-7})
+	assert.Equal(t, "[0.25,-1,1e-07]", text)
+
+	vec, err := parseVector(text)
+	require.NoError(t, err)
+	assert.Equal(t, []float32{0.25, -1, 1e-7}, vec)
+
+	_, err = parseVector("0.25,1")
+	assert.Error(t, err)
+}
+
+// newMockPgvectorStore 创建pgvector 0.8.0上的存储，已有的维度表都带默认的HNSW余弦索引
+func newMockPgvectorStore(t *testing.T, opts PgvectorOptions, tables ...string) (*PgvectorVectorStore, sqlmock.Sqlmock) {
+	t.Helper()
+	return newMockPgvectorStoreVersion(t, "0.8.0", opts, tables...)
+}
+
+func newMockPgvectorStoreVersion(t *testing.T, version string, opts PgvectorOptions, tables ...string) (*PgvectorVectorStore, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock := newMockGormDB(t)
+	mock.ExpectExec(`CREATE EXTENSION IF NOT EXISTS vector`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT extversion FROM pg_extension WHERE extname = 'vector'`).
+		WillReturnRows(sqlmock.NewRows([]string{"extversion"}).AddRow(version))
+	store, err := NewPgvectorVectorStore(db, opts)
+	require.NoError(t, err)
+
+	rows := sqlmock.NewRows([]string{"table_name"})
+	indexes := sqlmock.NewRows([]string{"index_name", "index_def"})
+	for _, table := range tables {
+		rows.AddRow(table)
+		indexes.AddRow("idx_"+table+"_embedding",
+			"CREATE INDEX idx_"+table+"_embedding ON public."+table+" USING hnsw (embedding vector_cosine_ops) WITH (m='16', ef_construction='64')")
+	}
+	mock.ExpectQuery(`SELECT table_name FROM information_schema.tables`).
+		WithArgs(`knowledge\_vectors\_%`).
+		WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT indexname AS index_name, indexdef AS index_def FROM pg_indexes`).
+		WithArgs(`knowledge\_vectors\_%`).
+		WillReturnRows(indexes)
+	return store, mock
+}
+
+// expectPgvectorTables 重新加载维度表时返回的表
+func expectPgvectorTables(mock sqlmock.Sqlmock, tables ...string) {
+	rows := sqlmock.NewRows([]string{"table_name"})
+	for _, table := range tables {
+		rows.AddRow(table)
+	}
+	mock.ExpectQuery(`SELECT table_name FROM information_schema.tables`).WillReturnRows(rows)
+}
+
+func TestPgvectorVectorStore_UpsertChunksCreatesDimensionTable(t *testing.T) {
+	store, mock := newMockPgvectorStore(t, PgvectorOptions{}, "knowledge_vectors_3", "knowledge_vectors_tmp")
+	// 缓存中没有2维表，建表前重新加载确认
+	expectPgvectorTables(mock, "knowledge_vectors_3", "knowledge_vectors_tmp")
+
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS knowledge_vectors_2 \(.*embedding vector\(2\) NOT NULL`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE INDEX IF NOT EXISTS idx_knowledge_vectors_2_kb_doc ON knowledge_vectors_2 \(knowledge_base_id, document_id\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE INDEX IF NOT EXISTS idx_knowledge_vectors_2_embedding ON knowledge_vectors_2 USING hnsw \(embedding vector_cosine_ops\) WITH \(m = 16, ef_construction = 64\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	expectPgvectorTables(mock, "knowledge_vectors_2", "knowledge_vectors_3", "knowledge_vectors_tmp")
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO knowledge_vectors_2 .* VALUES \(\$1, \$2, \$3, \$4::vector\), \(\$5, \$6, \$7, \$8::vector\) ON CONFLICT \(chunk_id\) DO UPDATE`).
+		WithArgs(uint(11), uint(7), uint(3), "[0.1,0.2]", uint(12), uint(7), uint(3), "[0.3,0.4]").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	// 更换模型前写入的旧维度向量一并删除
+	mock.ExpectExec(`DELETE FROM knowledge_vectors_3 WHERE chunk_id IN \(\$1,\$2\)`).
+		WithArgs(uint(11), uint(12)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ids, err := store.UpsertChunks(context.Background(), []VectorChunk{
+		{ChunkID: 11, DocumentID: 3, KnowledgeBaseID: 7, Embedding: []float32{0.1, 0.2}},
+		{ChunkID: 12, DocumentID: 3, KnowledgeBaseID: 7, Embedding: []float32{0.3, 0.4}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pg_11", "pg_12"}, ids)
+
+	dimensions, err := store.dimensions(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 3}, dimensions)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPgvectorVectorStore_SearchFiltersInSQL(t *testing.T) {
+	store, mock := newMockPgvectorStore(t, PgvectorOptions{}, "knowledge_vectors_2")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SET LOCAL hnsw.ef_search = 200`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SET LOCAL hnsw.iterative_scan = strict_order`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`embedding <=> \$1::vector AS distance FROM knowledge_vectors_2 WHERE knowledge_base_id = \$2 AND document_id IN \(\$3,\$4\) AND document_id NOT IN \(\$5\) ORDER BY distance LIMIT \$6`).
+		WithArgs("[1,0]", uint(7), uint(3), uint(4), uint(4), 10).
+		WillReturnRows(sqlmock.NewRows([]string{"chunk_id", "document_id", "content", "metadata", "distance"}).
+			AddRow(11, 3, "alpha", `{"page":2}`, 0.05).
+			AddRow(12, 3, "beta", "", 0.5))
+	mock.ExpectCommit()
+
+	matches, err := store.Search(context.Background(), VectorSearchRequest{
+		KnowledgeBaseID:    7,
+		QueryEmbedding:     []float32{1, 0},
+		DocumentIDs:        []uint{3, 4},
+		ExcludeDocumentIDs: []uint{4},
+	})
+	require.NoError(t, err)
+	// 默认阈值0.9过滤掉距离0.5的分块
+	require.Len(t, matches, 1)
+	assert.Equal(t, uint(11), matches[0].ChunkID)
+	assert.InDelta(t, 0.95, matches[0].Score, 1e-9)
+	assert.Equal(t, float64(2), matches[0].Metadata["page"])
+
+	// 没有该维度的表时没有结果
+	expectPgvectorTables(mock, "knowledge_vectors_2")
+	matches, err = store.Search(context.Background(), VectorSearchRequest{KnowledgeBaseID: 7, QueryEmbedding: []float32{1, 0, 0}})
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPgvectorVectorStore_SeesTablesCreatedByOtherReplicas(t *testing.T) {
+	store, mock := newMockPgvectorStore(t, PgvectorOptions{}, "knowledge_vectors_2")
+	dimensions, err := store.dimensions(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, dimensions)
+
+	// 其他副本更换模型后创建了4维表，删除文档时一并删除其中的向量
+	expectPgvectorTables(mock, "knowledge_vectors_2", "knowledge_vectors_4")
+	mock.ExpectQuery(`FROM pg_indexes`).
+		WillReturnRows(sqlmock.NewRows([]string{"index_name", "index_def"}).
+			AddRow("idx_knowledge_vectors_4_embedding", "CREATE INDEX idx_knowledge_vectors_4_embedding ON public.knowledge_vectors_4 USING hnsw (embedding vector_cosine_ops)"))
+	mock.ExpectExec(`DELETE FROM knowledge_vectors_2 WHERE knowledge_base_id = \$1 AND document_id = \$2`).
+		WithArgs(uint(7), uint(3)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM knowledge_vectors_4 WHERE knowledge_base_id = \$1 AND document_id = \$2`).
+		WithArgs(uint(7), uint(3)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	require.NoError(t, store.DeleteDocument(context.Background(), 7, 3))
+
+	// 缓存已包含新表，检索不再重新加载
+	exists, err := store.hasDimension(context.Background(), 4)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPgvectorVectorStore_SearchExactScanWithoutIterativeScan(t *testing.T) {
+	store, mock := newMockPgvectorStoreVersion(t, "0.7.4", PgvectorOptions{}, "knowledge_vectors_2")
+	assert.False(t, store.iterativeScan)
+
+	// 过滤后行数较少时禁用近似索引
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT count\(\*\) FROM \(SELECT 1 FROM knowledge_vectors_2 WHERE knowledge_base_id = \$1 LIMIT \$2\) f`).
+		WithArgs(uint(7), pgvectorExactScanRows+1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(30))
+	mock.ExpectExec(`SET LOCAL enable_indexscan = off`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`FROM knowledge_vectors_2 WHERE knowledge_base_id = \$2 ORDER BY distance LIMIT \$3`).
+		WithArgs("[1,0]", uint(7), 10).
+		WillReturnRows(sqlmock.NewRows([]string{"chunk_id", "document_id", "content", "metadata", "distance"}).
+			AddRow(11, 3, "alpha", "", 0.05))
+	mock.ExpectCommit()
+
+	matches, err := store.Search(context.Background(), VectorSearchRequest{KnowledgeBaseID: 7, QueryEmbedding: []float32{1, 0}})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+
+	// 行数较多时仍走近似索引
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT count\(\*\) FROM \(SELECT 1 FROM knowledge_vectors_2`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(pgvectorExactScanRows + 1))
+	mock.ExpectExec(`SET LOCAL hnsw.ef_search = 200`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`FROM knowledge_vectors_2 WHERE knowledge_base_id = \$2 ORDER BY distance LIMIT \$3`).
+		WillReturnRows(sqlmock.NewRows([]string{"chunk_id", "document_id", "content", "metadata", "distance"}))
+	mock.ExpectCommit()
+
+	_, err = store.Search(context.Background(), VectorSearchRequest{KnowledgeBaseID: 7, QueryEmbedding: []float32{1, 0}})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPgvectorVectorStore_RebuildsMismatchedIndex(t *testing.T) {
+	db, mock := newMockGormDB(t)
+	mock.ExpectExec(`CREATE EXTENSION IF NOT EXISTS vector`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT extversion`).WillReturnRows(sqlmock.NewRows([]string{"extversion"}).AddRow("0.8.0"))
+	store, err := NewPgvectorVectorStore(db, PgvectorOptions{Index: "ivfflat", Distance: "L2"})
+	require.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT table_name FROM information_schema.tables`).
+		WillReturnRows(sqlmock.NewRows([]string{"table_name"}).AddRow("knowledge_vectors_2").AddRow("knowledge_vectors_3"))
+	// 迁移回填的表带HNSW余弦索引，3维表没有索引
+	mock.ExpectQuery(`FROM pg_indexes`).
+		WillReturnRows(sqlmock.NewRows([]string{"index_name", "index_def"}).
+			AddRow("idx_knowledge_vectors_2_embedding", "CREATE INDEX idx_knowledge_vectors_2_embedding ON public.knowledge_vectors_2 USING hnsw (embedding vector_cosine_ops)"))
+	mock.ExpectExec(`DROP INDEX IF EXISTS idx_knowledge_vectors_2_embedding`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE INDEX IF NOT EXISTS idx_knowledge_vectors_2_embedding ON knowledge_vectors_2 USING ivfflat \(embedding vector_l2_ops\) WITH \(lists = 100\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE INDEX IF NOT EXISTS idx_knowledge_vectors_3_embedding ON knowledge_vectors_3 USING ivfflat \(embedding vector_l2_ops\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	dimensions, err := store.dimensions(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 3}, dimensions)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPgvectorSupportsIterativeScan(t *testing.T) {
+	assert.True(t, pgvectorSupportsIterativeScan("0.8.0"))
+	assert.True(t, pgvectorSupportsIterativeScan("1.0.1"))
+	assert.False(t, pgvectorSupportsIterativeScan("0.7.4"))
+	assert.False(t, pgvectorSupportsIterativeScan(""))
+}
+
+func TestNewPgvectorVectorStore_RejectsInvalidOptions(t *testing.T) {
+	db, _ := newMockGormDB(t)
+	_, err := NewPgvectorVectorStore(db, PgvectorOptions{TablePrefix: "vectors; DROP TABLE x"})
+	assert.Error(t, err)
+	_, err = NewPgvectorVectorStore(db, PgvectorOptions{Index: "flat"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/aihub/backend-go/internal/config"
+	"github.com/aihub/backend-go/internal/database"
+	"github.com/aihub/backend-go/internal/knowledge"
+)
+
+var globalFulltextIndexer knowledge.FulltextIndexer
+
+// NewFulltextIndexer 按knowledge.search.provider创建全文索引器
+// elasticsearch：外部ES集群；postgres：数据库全文检索；bm25：内嵌BM25索引
+func NewFulltextIndexer() (knowledge.FulltextIndexer, error) {
+	if globalFulltextIndexer != nil {
+		return globalFulltextIndexer, nil
+	}
+
+	cfg := config.GetAppConfig().Knowledge.Search
+	var indexer knowledge.FulltextIndexer
+	switch cfg.Provider {
+	case "bm25":
+		var words []string
+		if cfg.BM25.DictionaryPath != "" {
+			dict, err := knowledge.LoadDictionary(cfg.BM25.DictionaryPath)
+			if err != nil {
+				return nil, err
+			}
+			words = dict
+		}
+		bm25, err := knowledge.NewBM25Indexer(knowledge.BM25Options{
+			Dir:            cfg.BM25.Dir,
+			Tokenizer:      knowledge.NewStandardTokenizer(words),
+			FlushThreshold: cfg.BM25.FlushThreshold,
+			MergeThreshold: cfg.BM25.MergeThreshold,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create bm25 indexer: %w", err)
+		}
+		indexer = bm25
+	case "postgres":
+		if database.DB == nil {
+			return nil, fmt.Errorf("database not initialized")
+		}
+		indexer = knowledge.NewDatabaseIndexer(database.DB)
+	case "elasticsearch", "":
+		esService, err := NewElasticsearchService()
+		if err != nil {
+			return nil, err
+		}
+		indexer = esService.indexer
+	default:
+		return nil, fmt.Errorf("unsupported knowledge search provider: %s", cfg.Provider)
+	}
+
+	globalFulltextIndexer = indexer
+	return indexer, nil
+}
+
+// GetFulltextIndexer 获取全局全文索引器
+func GetFulltextIndexer() knowledge.FulltextIndexer {
+	return globalFulltextIndexer
+}
+
+// CloseFulltextIndexer 关闭全文索引器，内嵌索引在此落盘
+func CloseFulltextIndexer() error {
+	if closer, ok := globalFulltextIndexer.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -8,7 +8,9 @@ import (
 	"github.com/IBM/sarama"
 	"github.com/aihub/backend-go/internal/kafka"
 	"github.com/aihub/backend-go/internal/logger"
+	"github.com/aihub/backend-go/internal/outbox"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // KafkaService Kafka事件服务
@@ -25,6 +27,7 @@ func NewKafkaService() *KafkaService {
 
 // EventMessage 标准事件消息格式
 type EventMessage struct {
+	EventID   string                 `json:"event_id,omitempty"` // 发件箱发布的事件带ID，消费方据此去重
 	EventType string                 `json:"event_type"`
 	Timestamp time.Time              `json:"timestamp"`
 	UserID    *uint                  `json:"user_id,omitempty"`
@@ -63,7 +66,7 @@ type AIChatEvent struct {
 type KnowledgeProcessEvent struct {
 	KnowledgeBaseID uint   `json:"knowledge_base_id"`
 	DocumentID      uint   `json:"document_id,omitempty"`
-	Action          string `json:"action"` // process, index, sync
+	Action          string `json:"action"` // upload, process, index, sync
 	UserID          uint   `json:"user_id"`
 }
 
@@ -176,10 +179,8 @@ func (s *KafkaService) PublishWorkflowEvent(event WorkflowEvent) error {
 	return s.SendMessage(topic, msg)
 }
 
-// PublishAIChatEvent 发布AI聊天事件
-func (s *KafkaService) PublishAIChatEvent(event AIChatEvent) error {
-	topic := "ai.chat.request"
-
+// PublishAIChatEventTx 在调用方事务tx中把AI聊天事件写入发件箱，提交后由中继发布
+func (s *KafkaService) PublishAIChatEventTx(tx *gorm.DB, event AIChatEvent) error {
 	msg := EventMessage{
 		EventType: "ai.chat.request",
 		Timestamp: time.Now(),
@@ -187,21 +188,21 @@ func (s *KafkaService) PublishAIChatEvent(event AIChatEvent) error {
 		Data:      event,
 	}
 
-	return s.SendMessage(topic, msg)
+	return s.enqueue(tx, "ai.chat.request", event.SessionID, msg)
 }
 
-// PublishKnowledgeProcessEvent 发布知识库处理事件
-func (s *KafkaService) PublishKnowledgeProcessEvent(event KnowledgeProcessEvent) error {
+// PublishKnowledgeProcessEventTx 在调用方事务tx中写入知识库处理事件，同一知识库的事件按写入顺序发布
+func (s *KafkaService) PublishKnowledgeProcessEventTx(tx *gorm.DB, event KnowledgeProcessEvent) error {
 	topic := fmt.Sprintf("knowledge.%s", event.Action)
 
 	msg := EventMessage{
-		EventType: fmt.Sprintf("knowledge.%s", event.Action),
+		EventType: topic,
 		Timestamp: time.Now(),
 		UserID:    &event.UserID,
 		Data:      event,
 	}
 
-	return s.SendMessage(topic, msg)
+	return s.enqueue(tx, topic, fmt.Sprintf("kb-%d", event.KnowledgeBaseID), msg)
 }
 
 // PublishOrderEvent 发布订单事件
@@ -218,12 +219,12 @@ func (s *KafkaService) PublishOrderEvent(orderID string, eventType string, userI
 	return s.SendMessage(topic, msg)
 }
 
-// PublishTokenEvent 发布Token事件
-func (s *KafkaService) PublishTokenEvent(userID uint, eventType string, amount int64) error {
+// PublishTokenEventTx 在调用方事务tx中写入Token事件，同一用户的事件按写入顺序发布
+func (s *KafkaService) PublishTokenEventTx(tx *gorm.DB, userID uint, eventType string, amount int64) error {
 	topic := fmt.Sprintf("token.%s", eventType)
 
 	msg := EventMessage{
-		EventType: fmt.Sprintf("token.%s", eventType),
+		EventType: topic,
 		Timestamp: time.Now(),
 		UserID:    &userID,
 		Data: map[string]interface{}{
@@ -232,7 +233,25 @@ func (s *KafkaService) PublishTokenEvent(userID uint, eventType string, amount i
 		},
 	}
 
-	return s.SendMessage(topic, msg)
+	return s.enqueue(tx, topic, fmt.Sprintf("user-%d", userID), msg)
+}
+
+// enqueue 在状态变更所在的事务tx中写入发件箱，事务回滚时事件一并丢弃；发件箱未启用时直接发送
+func (s *KafkaService) enqueue(tx *gorm.DB, topic, key string, msg EventMessage) error {
+	if !outbox.Enabled() {
+		return s.SendMessageWithKey(topic, key, msg)
+	}
+	if tx == nil {
+		return fmt.Errorf("outbox event %s requires a transaction", topic)
+	}
+
+	msg.EventID = outbox.NewEventID()
+	return outbox.Enqueue(tx, outbox.Message{
+		EventID: msg.EventID,
+		Topic:   topic,
+		Key:     key,
+		Value:   msg,
+	})
 }
 
 // SendBatch 批量发送消息
@@ -123,3 +123,8 @@ func (s *MilvusService) Ready() bool {
 }
 
 
+
+// VectorStore 获取底层向量存储
+func (s *MilvusService) VectorStore() knowledge.VectorStore {
+	return s.vectorStore
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // 文档处理状态常量
@@ -212,6 +214,20 @@ type KnowledgeBase struct {
 	CreateTime      time.Time `gorm:"column:create_time" json:"create_time"`
 	UpdateTime      time.Time `gorm:"column:update_time" json:"update_time"`
 
+	// 向量所用的嵌入模型，首次写入向量时记录，检索按此模型向量化
+	EmbeddingProvider   string `gorm:"column:embedding_provider;size:50" json:"embedding_provider"`
+	EmbeddingModel      string `gorm:"column:embedding_model;size:100" json:"embedding_model"`
+	EmbeddingDimensions int    `gorm:"column:embedding_dimensions;default:0" json:"embedding_dimensions"`
+
+	// 迁移中的目标模型与写入的向量索引版本，迁移结束后清空，各实例检索时据此双读新旧版本
+	EmbeddingMigrationProvider   string `gorm:"column:embedding_migration_provider;size:50" json:"-"`
+	EmbeddingMigrationModel      string `gorm:"column:embedding_migration_model;size:100" json:"-"`
+	EmbeddingMigrationDimensions int    `gorm:"column:embedding_migration_dimensions;default:0" json:"-"`
+	EmbeddingMigrationVersion    int    `gorm:"column:embedding_migration_version;default:0" json:"-"`
+
+	// 移入回收站的时间，查询默认排除；保留期满后由清理任务删除各存储中的数据
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index" json:"deleted_at,omitempty"`
+
 	// 关系
 	Documents []KnowledgeDocument `gorm:"foreignKey:KnowledgeBaseID"`
 	Searches  []KnowledgeSearch   `gorm:"foreignKey:KnowledgeBaseID"`
@@ -245,6 +261,9 @@ type KnowledgeDocument struct {
 	LastProcessedAt time.Time `gorm:"column:last_processed_at" json:"last_processed_at"`            // 最后处理时间
 	ChangeType      string    `gorm:"column:change_type;size:20;default:'full'" json:"change_type"` // 变更类型：full, incremental, append
 
+	// 移入回收站的时间，查询默认排除
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index" json:"deleted_at,omitempty"`
+
 	// 关系
 	Chunks []KnowledgeChunk `gorm:"foreignKey:DocumentID"`
 }
@@ -262,6 +281,7 @@ type KnowledgeChunk struct {
 	ChunkIndex          int               `gorm:"not null;index" json:"chunk_index"`
 	VectorID            string            `gorm:"size:255;not null" json:"vector_id"`
 	Embedding           string            `gorm:"type:json" json:"embedding"`
+	EmbeddingCode       []byte            `gorm:"column:embedding_code" json:"-"` // 量化后的向量编码，数据库向量存储开启量化时写入
 	Metadata            string            `gorm:"type:json" json:"metadata"`
 	TokenCount          int               `gorm:"column:token_count;default:0" json:"token_count"`                     // 当前块的token数
 	PrevChunkID         *uint             `gorm:"column:prev_chunk_id;index" json:"prev_chunk_id"`                     // 前一个块的ID
@@ -297,6 +317,261 @@ func (KnowledgeSearch) TableName() string {
 	return "knowledge_searches"
 }
 
+// KnowledgeSynonym 知识库同义词组，组内词语检索时互相等价
+type KnowledgeSynonym struct {
+	SynonymID       uint        `gorm:"primaryKey;column:synonym_id" json:"synonym_id"`
+	KnowledgeBaseID uint        `gorm:"column:knowledge_base_id;not null;index" json:"knowledge_base_id"`
+	Terms           StringArray `gorm:"type:jsonb;column:terms;not null" json:"terms"`
+	CreateTime      time.Time   `gorm:"column:create_time" json:"create_time"`
+	UpdateTime      time.Time   `gorm:"column:update_time" json:"update_time"`
+}
+
+func (KnowledgeSynonym) TableName() string {
+	return "knowledge_synonyms"
+}
+
+// 知识库词库类型
+const (
+	LexiconKindStopWord   = "stopword"
+	LexiconKindDictionary = "dictionary"
+)
+
+// KnowledgeLexiconWord 知识库停用词与自定义词典
+type KnowledgeLexiconWord struct {
+	WordID          uint      `gorm:"primaryKey;column:word_id" json:"word_id"`
+	KnowledgeBaseID uint      `gorm:"column:knowledge_base_id;not null;uniqueIndex:idx_knowledge_lexicon_word" json:"knowledge_base_id"`
+	Kind            string    `gorm:"size:20;not null;uniqueIndex:idx_knowledge_lexicon_word" json:"kind"` // stopword | dictionary
+	Word            string    `gorm:"size:100;not null;uniqueIndex:idx_knowledge_lexicon_word" json:"word"`
+	CreateTime      time.Time `gorm:"column:create_time" json:"create_time"`
+}
+
+func (KnowledgeLexiconWord) TableName() string {
+	return "knowledge_lexicon_words"
+}
+
+// SearchEvaluationRun 检索效果评测记录，保存各组配置在查询集上的指标，用于跨时间对比
+type SearchEvaluationRun struct {
+	RunID           uint      `gorm:"primaryKey;column:run_id" json:"run_id"`
+	KnowledgeBaseID uint      `gorm:"column:knowledge_base_id;not null;index" json:"knowledge_base_id"`
+	Name            string    `gorm:"size:100" json:"name"`
+	QuerySetHash    string    `gorm:"column:query_set_hash;size:64;index" json:"query_set_hash"` // 查询集内容的SHA-256，相同查询集的结果可直接对比
+	QueryCount      int       `gorm:"column:query_count" json:"query_count"`
+	Embedder        string    `gorm:"size:100" json:"embedder"`          // 评测使用的向量化模型
+	Results         string    `gorm:"type:json;not null" json:"results"` // []knowledge.EvalResult
+	CreatedBy       uint      `gorm:"column:created_by" json:"created_by"`
+	CreateTime      time.Time `gorm:"column:create_time" json:"create_time"`
+}
+
+func (SearchEvaluationRun) TableName() string {
+	return "search_evaluation_runs"
+}
+
+// KnowledgeWebhook 知识库事件的Webhook订阅，连续失败过多时自动停用
+type KnowledgeWebhook struct {
+	WebhookID           uint        `gorm:"primaryKey;column:webhook_id" json:"webhook_id"`
+	KnowledgeBaseID     uint        `gorm:"column:knowledge_base_id;not null;index" json:"knowledge_base_id"`
+	URL                 string      `gorm:"column:url;size:500;not null" json:"url"`
+	Events              StringArray `gorm:"type:jsonb;column:events;not null" json:"events"` // 订阅的事件类型，为空表示全部
+	Secret              string      `gorm:"size:100;not null" json:"-"`                      // HMAC-SHA256签名密钥，只在创建时返回
+	Description         string      `gorm:"size:200" json:"description"`
+	Enabled             bool        `gorm:"default:true" json:"enabled"`
+	ConsecutiveFailures int         `gorm:"column:consecutive_failures;default:0" json:"consecutive_failures"`
+	DisabledReason      string      `gorm:"column:disabled_reason;size:200" json:"disabled_reason,omitempty"`
+	DisabledAt          *time.Time  `gorm:"column:disabled_at" json:"disabled_at,omitempty"`
+	CreatedBy           uint        `gorm:"column:created_by" json:"created_by"`
+	CreateTime          time.Time   `gorm:"column:create_time" json:"create_time"`
+	UpdateTime          time.Time   `gorm:"column:update_time" json:"update_time"`
+}
+
+func (KnowledgeWebhook) TableName() string {
+	return "knowledge_webhooks"
+}
+
+// Webhook投递状态
+const (
+	WebhookDeliveryPending   = "pending"   // 等待投递或重试
+	WebhookDeliverySucceeded = "succeeded" // 接收方返回2xx
+	WebhookDeliveryFailed    = "failed"    // 重试次数用尽
+	WebhookDeliverySkipped   = "skipped"   // 订阅已停用或删除
+)
+
+// KnowledgeWebhookDelivery Webhook投递记录，同时作为待投递队列
+type KnowledgeWebhookDelivery struct {
+	DeliveryID      uint       `gorm:"primaryKey;column:delivery_id" json:"delivery_id"`
+	WebhookID       uint       `gorm:"column:webhook_id;not null;index" json:"webhook_id"`
+	KnowledgeBaseID uint       `gorm:"column:knowledge_base_id;not null" json:"knowledge_base_id"`
+	EventID         string     `gorm:"column:event_id;size:64;not null;index" json:"event_id"` // 重放时不变，接收方据此去重
+	EventType       string     `gorm:"column:event_type;size:50;not null" json:"event_type"`
+	Payload         string     `gorm:"type:text;not null" json:"payload"`
+	Status          string     `gorm:"size:20;not null;index:idx_webhook_deliveries_due" json:"status"`
+	Attempts        int        `gorm:"default:0" json:"attempts"`
+	NextAttemptAt   time.Time  `gorm:"column:next_attempt_at;index:idx_webhook_deliveries_due" json:"next_attempt_at"`
+	LastStatusCode  int        `gorm:"column:last_status_code" json:"last_status_code,omitempty"`
+	LastError       string     `gorm:"column:last_error;size:500" json:"last_error,omitempty"`
+	DeliveredAt     *time.Time `gorm:"column:delivered_at" json:"delivered_at,omitempty"`
+	ReplayOf        *uint      `gorm:"column:replay_of" json:"replay_of,omitempty"`
+	CreateTime      time.Time  `gorm:"column:create_time" json:"create_time"`
+	UpdateTime      time.Time  `gorm:"column:update_time" json:"update_time"`
+}
+
+func (KnowledgeWebhookDelivery) TableName() string {
+	return "knowledge_webhook_deliveries"
+}
+
+// Outbox事件状态
+const (
+	OutboxPending   = "pending"   // 等待发布或重试
+	OutboxSending   = "sending"   // 已被中继领取，领取超时后可被重新领取
+	OutboxPublished = "published" // 已写入Kafka
+	OutboxDead      = "dead"      // 超过最大发布次数，不再重试，需人工处理后改回pending
+)
+
+// OutboxEvent 事务发件箱，与业务数据在同一事务中写入，由后台中继按聚合键顺序发布到Kafka
+type OutboxEvent struct {
+	OutboxID      uint       `gorm:"primaryKey;column:outbox_id" json:"outbox_id"`
+	EventID       string     `gorm:"column:event_id;size:64;not null;uniqueIndex" json:"event_id"` // 消费方据此去重
+	Topic         string     `gorm:"size:200;not null" json:"topic"`
+	AggregateKey  string     `gorm:"column:aggregate_key;size:200;not null;index" json:"aggregate_key"` // Kafka消息key，同一key内按写入顺序发布
+	Payload       string     `gorm:"type:text;not null" json:"payload"`
+	Headers       JSONB      `gorm:"type:jsonb" json:"headers,omitempty"`
+	Status        string     `gorm:"size:20;not null;index:idx_outbox_events_pending" json:"status"`
+	Attempts      int        `gorm:"default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"column:next_attempt_at" json:"next_attempt_at"`
+	LastError     string     `gorm:"column:last_error;size:500" json:"last_error,omitempty"`
+	PublishedAt   *time.Time `gorm:"column:published_at" json:"published_at,omitempty"`
+	CreateTime    time.Time  `gorm:"column:create_time" json:"create_time"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// 回收站条目类型
+const (
+	TrashItemDocument      = "document"
+	TrashItemKnowledgeBase = "knowledge_base"
+)
+
+// 清理任务状态
+const (
+	PurgePending   = "pending"   // 等待保留期满或重试
+	PurgeCompleted = "completed" // 各存储中的数据已删除
+)
+
+// KnowledgePurgeTask 回收站条目的清理任务，保留期满后由后台任务逐个存储删除数据
+type KnowledgePurgeTask struct {
+	TaskID          uint        `gorm:"primaryKey;column:task_id" json:"task_id"`
+	ItemType        string      `gorm:"column:item_type;size:20;not null" json:"item_type"`
+	KnowledgeBaseID uint        `gorm:"column:knowledge_base_id;not null;index" json:"knowledge_base_id"`
+	DocumentID      uint        `gorm:"column:document_id;default:0" json:"document_id,omitempty"` // 删除知识库时为0
+	Title           string      `gorm:"size:200" json:"title"`
+	Status          string      `gorm:"size:20;not null;index:idx_purge_tasks_due" json:"status"`
+	PurgeAfter      time.Time   `gorm:"column:purge_after;index:idx_purge_tasks_due" json:"purge_after"`
+	PurgedStores    StringArray `gorm:"type:jsonb;column:purged_stores" json:"purged_stores,omitempty"` // 已完成清理的存储，重试时跳过
+	Attempts        int         `gorm:"default:0" json:"attempts"`
+	NextAttemptAt   time.Time   `gorm:"column:next_attempt_at" json:"next_attempt_at"`
+	LastError       string      `gorm:"column:last_error;size:500" json:"last_error,omitempty"`
+	DeletedBy       uint        `gorm:"column:deleted_by" json:"deleted_by"`
+	CompletedAt     *time.Time  `gorm:"column:completed_at" json:"completed_at,omitempty"`
+	CreateTime      time.Time   `gorm:"column:create_time" json:"create_time"`
+	UpdateTime      time.Time   `gorm:"column:update_time" json:"update_time"`
+}
+
+func (KnowledgePurgeTask) TableName() string {
+	return "knowledge_purge_tasks"
+}
+
+// 一致性检查状态
+const (
+	ReconcileRunning   = "running"
+	ReconcileSucceeded = "succeeded"
+	ReconcileFailed    = "failed"
+)
+
+// KnowledgeReconcileRun 知识库跨存储一致性检查记录，同一知识库同时只能有一个running记录
+type KnowledgeReconcileRun struct {
+	RunID           uint       `gorm:"primaryKey;column:run_id" json:"run_id"`
+	KnowledgeBaseID uint       `gorm:"column:knowledge_base_id;not null;index;uniqueIndex:idx_reconcile_runs_running,where:status = 'running'" json:"knowledge_base_id"`
+	Repair          bool       `gorm:"default:false" json:"repair"`
+	Status          string     `gorm:"size:20;not null" json:"status"`
+	Discrepancies   int        `gorm:"default:0" json:"discrepancies"`
+	Repaired        int        `gorm:"default:0" json:"repaired"`
+	Report          string     `gorm:"type:text" json:"report,omitempty"` // ReconcileReport的JSON
+	Error           string     `gorm:"size:500" json:"error,omitempty"`
+	StartedAt       time.Time  `gorm:"column:started_at;index" json:"started_at"`
+	FinishedAt      *time.Time `gorm:"column:finished_at" json:"finished_at,omitempty"`
+}
+
+func (KnowledgeReconcileRun) TableName() string {
+	return "knowledge_reconcile_runs"
+}
+
+// 知识库导入任务状态
+const (
+	ImportPending   = "pending"
+	ImportRunning   = "running"
+	ImportSucceeded = "succeeded"
+	ImportFailed    = "failed"
+)
+
+// 知识库导入阶段
+const (
+	ImportStagePrepare   = "prepare"   // 创建知识库
+	ImportStageDocuments = "documents" // 逐个导入文档与分块
+	ImportStageIndex     = "index"     // 写入全文索引与向量
+	ImportStageDone      = "done"
+)
+
+// KnowledgeImportJob 知识库归档导入任务，按阶段与已导入的文档数记录进度，中断后从断点继续
+type KnowledgeImportJob struct {
+	JobID                 uint       `gorm:"primaryKey;column:job_id" json:"job_id"`
+	OwnerID               uint       `gorm:"column:owner_id;not null;index" json:"owner_id"`
+	ArchiveKey            string     `gorm:"column:archive_key;size:500;not null" json:"-"`
+	ArchiveName           string     `gorm:"column:archive_name;size:255" json:"archive_name"`
+	Name                  string     `gorm:"size:200" json:"name,omitempty"` // 新知识库名称，为空时使用归档中的名称
+	SourceKnowledgeBaseID uint       `gorm:"column:source_knowledge_base_id" json:"source_knowledge_base_id"`
+	KnowledgeBaseID       uint       `gorm:"column:knowledge_base_id;default:0" json:"knowledge_base_id"` // 创建的知识库，准备阶段完成前为0
+	Status                string     `gorm:"size:20;not null;index:idx_import_jobs_claim" json:"status"`
+	Stage                 string     `gorm:"size:20;not null" json:"stage"`
+	ReuseEmbeddings       bool       `gorm:"column:reuse_embeddings;default:false" json:"reuse_embeddings"` // 模型一致，直接使用归档中的向量
+	TotalDocuments        int        `gorm:"column:total_documents;default:0" json:"total_documents"`
+	ImportedDocuments     int        `gorm:"column:imported_documents;default:0" json:"imported_documents"`
+	TotalChunks           int        `gorm:"column:total_chunks;default:0" json:"total_chunks"`
+	ImportedChunks        int        `gorm:"column:imported_chunks;default:0" json:"imported_chunks"`
+	IndexedChunks         int        `gorm:"column:indexed_chunks;default:0" json:"indexed_chunks"`
+	Attempts              int        `gorm:"default:0" json:"attempts"`
+	LeaseUntil            time.Time  `gorm:"column:lease_until;index:idx_import_jobs_claim" json:"-"`
+	Error                 string     `gorm:"size:500" json:"error,omitempty"`
+	CreateTime            time.Time  `gorm:"column:create_time" json:"create_time"`
+	UpdateTime            time.Time  `gorm:"column:update_time" json:"update_time"`
+	FinishedAt            *time.Time `gorm:"column:finished_at" json:"finished_at,omitempty"`
+}
+
+func (KnowledgeImportJob) TableName() string {
+	return "knowledge_import_jobs"
+}
+
+// KnowledgeDocumentVersion 文档版本快照，每个版本的源文件与提取文本都保留
+type KnowledgeDocumentVersion struct {
+	VersionID       uint      `gorm:"primaryKey;column:version_id" json:"version_id"`
+	DocumentID      uint      `gorm:"column:document_id;not null;uniqueIndex:idx_document_versions_doc_version" json:"document_id"`
+	KnowledgeBaseID uint      `gorm:"column:knowledge_base_id;not null;index" json:"knowledge_base_id"`
+	Version         int       `gorm:"not null;uniqueIndex:idx_document_versions_doc_version" json:"version"`
+	Title           string    `gorm:"size:200;not null" json:"title"`
+	ContentHash     string    `gorm:"column:content_hash;size:64" json:"content_hash"`
+	ChangeType      string    `gorm:"column:change_type;size:20" json:"change_type"`
+	ContentLength   int       `gorm:"column:content_length" json:"content_length"`
+	TextObjectKey   string    `gorm:"column:text_object_key;size:500" json:"text_object_key,omitempty"`     // MinIO中的提取文本
+	SourceObjectKey string    `gorm:"column:source_object_key;size:500" json:"source_object_key,omitempty"` // MinIO中的源文件副本
+	Content         string    `gorm:"type:text" json:"-"`                                                   // 对象存储不可用时内联保存文本
+	RestoredFrom    *int      `gorm:"column:restored_from" json:"restored_from,omitempty"`                  // 由哪个版本恢复而来
+	CreateTime      time.Time `gorm:"column:create_time" json:"create_time"`
+}
+
+func (KnowledgeDocumentVersion) TableName() string {
+	return "knowledge_document_versions"
+}
+
 // ChatSession 聊天会话
 type ChatSession struct {
 	SessionID  uint      `gorm:"primaryKey;column:session_id" json:"session_id"`
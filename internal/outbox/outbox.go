@@ -0,0 +1,94 @@
+package outbox
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/aihub/backend-go/internal/kafka"
+	"github.com/aihub/backend-go/internal/models"
+	"gorm.io/gorm"
+)
+
+// enabled 启动流程在Kafka启用时打开，未启用时Enqueue不写入，避免发件箱无人消费而持续增长；
+// 未启用时调用方应直接发送
+var enabled atomic.Bool
+
+// SetEnabled 设置是否写入发件箱
+func SetEnabled(v bool) {
+	enabled.Store(v)
+}
+
+// Enabled 是否写入发件箱
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Message 待发布的Kafka消息
+type Message struct {
+	EventID string // 为空时自动生成；需要在消息体中携带事件ID时先用NewEventID生成
+	Topic   string
+	Key     string            // 聚合键，同一键的消息按写入顺序发布到同一分区
+	Value   interface{}       // []byte原样写入，其他类型序列化为JSON
+	Headers map[string]string // 附加消息头，event_id头自动添加
+}
+
+// NewEventID 生成事件ID
+func NewEventID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// Enqueue 在调用方的事务中写入发件箱，事务提交后由Relay发布
+// 未启用时不写入直接返回
+func Enqueue(tx *gorm.DB, msgs ...Message) error {
+	if !Enabled() || len(msgs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	events := make([]models.OutboxEvent, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.Topic == "" {
+			return fmt.Errorf("outbox message topic is required")
+		}
+		payload, err := encodeValue(msg.Value)
+		if err != nil {
+			return fmt.Errorf("failed to encode outbox message: %w", err)
+		}
+		eventID := msg.EventID
+		if eventID == "" {
+			eventID = NewEventID()
+		}
+		headers := models.JSONB{kafka.HeaderEventID: eventID}
+		for k, v := range msg.Headers {
+			headers[k] = v
+		}
+		events = append(events, models.OutboxEvent{
+			EventID:       eventID,
+			Topic:         msg.Topic,
+			AggregateKey:  msg.Key,
+			Payload:       string(payload),
+			Headers:       headers,
+			Status:        models.OutboxPending,
+			NextAttemptAt: now,
+			CreateTime:    now,
+		})
+	}
+	return tx.Create(&events).Error
+}
+
+func encodeValue(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return json.Marshal(v)
+	}
+}
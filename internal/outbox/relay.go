@@ -0,0 +1,203 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aihub/backend-go/internal/logger"
+	"github.com/aihub/backend-go/internal/models"
+	"go.uber.org/zap"
+)
+
+// Sender 消息发送方，*kafka.Producer实现了该接口
+type Sender interface {
+	SendRecord(topic, key string, value []byte, headers map[string]string) error
+}
+
+// Options 中继参数，零值使用默认值
+type Options struct {
+	BatchSize    int           // 每次读取的事件数，默认100
+	PollInterval time.Duration // 轮询间隔，默认1s
+	BaseBackoff  time.Duration // 发布失败后的退避基数（按2的指数增长），默认1s
+	MaxBackoff   time.Duration // 单次退避上限，默认5m
+	MaxAttempts  int           // 最大发布次数，超过后标记为dead不再重试，默认20
+	ClaimTimeout time.Duration // 领取后未完成发送时重新领取的等待时间，默认5m
+	Retention    time.Duration // 已发布事件的保留时间，默认7天
+}
+
+const (
+	defaultRelayBatchSize    = 100
+	defaultRelayPollInterval = time.Second
+	defaultRelayBaseBackoff  = time.Second
+	defaultRelayMaxBackoff   = 5 * time.Minute
+	defaultRelayMaxAttempts  = 20
+	defaultRelayClaimTimeout = 5 * time.Minute
+	defaultRelayRetention    = 7 * 24 * time.Hour
+	relayPurgeInterval       = time.Hour
+	maxOutboxErrorLength     = 500
+)
+
+// Relay 发件箱中继，把已提交的事件发布到Kafka
+// 事件先领取并提交再发送，发送期间不占用数据库事务；同一聚合键的事件按写入顺序逐条发布，
+// 某条失败时该键后续事件等待重试，其他键不受影响；超过最大发布次数的事件标记为dead，该键后续事件继续发布。
+// 发布成功但标记失败、或领取超时后被重新领取时事件会重复发布（至少一次），消费方按事件ID去重
+type Relay struct {
+	store  Store
+	sender Sender
+	opts   Options
+}
+
+// NewRelay 创建中继
+func NewRelay(store Store, sender Sender, opts Options) *Relay {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultRelayBatchSize
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultRelayPollInterval
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = defaultRelayBaseBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultRelayMaxBackoff
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultRelayMaxAttempts
+	}
+	if opts.ClaimTimeout <= 0 {
+		opts.ClaimTimeout = defaultRelayClaimTimeout
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = defaultRelayRetention
+	}
+	return &Relay{store: store, sender: sender, opts: opts}
+}
+
+// Run 后台发布循环，直到ctx取消
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+	lastPurge := time.Now()
+
+	for {
+		for {
+			n, err := r.RunOnce(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Warn("Failed to relay outbox events", zap.Error(err))
+				}
+				break
+			}
+			if n < r.opts.BatchSize {
+				break
+			}
+		}
+
+		if time.Since(lastPurge) >= relayPurgeInterval {
+			lastPurge = time.Now()
+			if n, err := r.store.Purge(ctx, time.Now().Add(-r.opts.Retention)); err != nil {
+				logger.Warn("Failed to purge outbox events", zap.Error(err))
+			} else if n > 0 {
+				logger.Info("Purged published outbox events", zap.Int64("count", n))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 领取并发布一批到期事件，返回领取的事件数；其他副本正在领取时返回0
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+	events, err := r.store.Claim(ctx, now, r.opts.ClaimTimeout, r.opts.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+
+	// 同一键的后续事件必须等失败的事件发布成功，放回待发布
+	blocked := make(map[string]bool)
+	var released []uint
+	for i := range events {
+		event := &events[i]
+		if blocked[event.AggregateKey] || ctx.Err() != nil {
+			released = append(released, event.OutboxID)
+			continue
+		}
+
+		sendErr := r.sender.SendRecord(event.Topic, event.AggregateKey, []byte(event.Payload), headerStrings(event.Headers))
+		if sendErr == nil {
+			if err := r.store.MarkPublished(ctx, event.OutboxID, time.Now()); err != nil {
+				return len(events), fmt.Errorf("failed to mark outbox event published: %w", err)
+			}
+			continue
+		}
+
+		attempts := event.Attempts + 1
+		lastError := truncate(sendErr.Error(), maxOutboxErrorLength)
+		if attempts >= r.opts.MaxAttempts {
+			if err := r.store.MarkDead(ctx, event.OutboxID, attempts, lastError); err != nil {
+				return len(events), fmt.Errorf("failed to record dead outbox event: %w", err)
+			}
+			logger.Error("Outbox event exceeded max attempts, moved to dead letter",
+				zap.String("event_id", event.EventID),
+				zap.String("topic", event.Topic),
+				zap.String("key", event.AggregateKey),
+				zap.Int("attempts", attempts),
+				zap.Error(sendErr))
+			continue
+		}
+
+		blocked[event.AggregateKey] = true
+		if err := r.store.MarkFailed(ctx, event.OutboxID, attempts, now.Add(r.backoff(attempts)), lastError); err != nil {
+			return len(events), fmt.Errorf("failed to record outbox failure: %w", err)
+		}
+		logger.Warn("Failed to publish outbox event",
+			zap.String("event_id", event.EventID),
+			zap.String("topic", event.Topic),
+			zap.Int("attempts", attempts),
+			zap.Error(sendErr))
+	}
+
+	// 领取超时后也会被重新领取，这里尽早放回；ctx取消时也要执行
+	if err := r.store.Release(context.WithoutCancel(ctx), released, now); err != nil {
+		return len(events), fmt.Errorf("failed to release outbox events: %w", err)
+	}
+	return len(events), nil
+}
+
+// backoff 第attempt次失败后的等待时间
+func (r *Relay) backoff(attempt int) time.Duration {
+	wait := r.opts.BaseBackoff
+	for i := 1; i < attempt && wait < r.opts.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > r.opts.MaxBackoff {
+		wait = r.opts.MaxBackoff
+	}
+	return wait
+}
+
+func headerStrings(headers models.JSONB) map[string]string {
+	result := make(map[string]string, len(headers))
+	for k, v := range headers {
+		if s, ok := v.(string); ok {
+			result[k] = s
+		} else {
+			result[k] = fmt.Sprint(v)
+		}
+	}
+	return result
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,306 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aihub/backend-go/internal/kafka"
+	"github.com/aihub/backend-go/internal/models"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// memoryStore 进程内存储，Claim的筛选规则与GormStore一致
+type memoryStore struct {
+	mu     sync.Mutex
+	events []*models.OutboxEvent
+}
+
+func (s *memoryStore) add(key string, payload string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, &models.OutboxEvent{
+		OutboxID:     uint(len(s.events) + 1),
+		EventID:      NewEventID(),
+		Topic:        "test",
+		AggregateKey: key,
+		Payload:      payload,
+		Headers:      models.JSONB{kafka.HeaderEventID: "id-" + payload},
+		Status:       models.OutboxPending,
+	})
+}
+
+func (s *memoryStore) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	open := func(e *models.OutboxEvent) bool {
+		return e.Status == models.OutboxPending || e.Status == models.OutboxSending
+	}
+	delayed := make(map[string]bool)
+	var claimed []models.OutboxEvent
+	for _, event := range s.events {
+		if len(claimed) == limit {
+			break
+		}
+		if !open(event) {
+			continue
+		}
+		if event.NextAttemptAt.After(now) {
+			delayed[event.AggregateKey] = true
+			continue
+		}
+		if !delayed[event.AggregateKey] {
+			claimed = append(claimed, *event)
+			event.Status = models.OutboxSending
+			event.NextAttemptAt = now.Add(lease)
+		}
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) Release(ctx context.Context, outboxIDs []uint, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range outboxIDs {
+		if event := s.events[id-1]; event.Status == models.OutboxSending {
+			event.Status = models.OutboxPending
+			event.NextAttemptAt = at
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) MarkPublished(ctx context.Context, outboxID uint, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events[outboxID-1].Status = models.OutboxPublished
+	s.events[outboxID-1].PublishedAt = &at
+	return nil
+}
+
+func (s *memoryStore) MarkFailed(ctx context.Context, outboxID uint, attempts int, next time.Time, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	event := s.events[outboxID-1]
+	event.Status = models.OutboxPending
+	event.Attempts = attempts
+	event.NextAttemptAt = next
+	event.LastError = lastError
+	return nil
+}
+
+func (s *memoryStore) MarkDead(ctx context.Context, outboxID uint, attempts int, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	event := s.events[outboxID-1]
+	event.Status = models.OutboxDead
+	event.Attempts = attempts
+	event.LastError = lastError
+	return nil
+}
+
+func (s *memoryStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (s *memoryStore) dueNow() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, event := range s.events {
+		event.NextAttemptAt = time.Time{}
+	}
+}
+
+// fakeSender 记录发送顺序，fail中的消息体发送失败
+type fakeSender struct {
+	mu      sync.Mutex
+	sent    map[string][]string
+	headers []map[string]string
+	fail    map[string]bool
+}
+
+func newFakeSender() *fakeSender {
+	return &fakeSender{sent: make(map[string][]string), fail: make(map[string]bool)}
+}
+
+func (f *fakeSender) SendRecord(topic, key string, value []byte, headers map[string]string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail[string(value)] {
+		return errors.New("broker unavailable")
+	}
+	f.sent[key] = append(f.sent[key], string(value))
+	f.headers = append(f.headers, headers)
+	return nil
+}
+
+func TestRelay_PublishesInOrderPerKey(t *testing.T) {
+	store := &memoryStore{}
+	store.add("a", "a1")
+	store.add("b", "b1")
+	store.add("a", "a2")
+	store.add("b", "b2")
+	store.add("a", "a3")
+	sender := newFakeSender()
+	sender.fail["a2"] = true
+
+	relay := NewRelay(store, sender, Options{BaseBackoff: time.Minute})
+	n, err := relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+
+	// a2失败后a3不能越过它发布，b不受影响
+	assert.Equal(t, []string{"a1"}, sender.sent["a"])
+	assert.Equal(t, []string{"b1", "b2"}, sender.sent["b"])
+	failed := store.events[2]
+	assert.Equal(t, models.OutboxPending, failed.Status)
+	assert.Equal(t, 1, failed.Attempts)
+	assert.Contains(t, failed.LastError, "broker unavailable")
+	// 领取后未发送的事件放回待发布
+	assert.Equal(t, models.OutboxPending, store.events[4].Status)
+
+	// 退避期间整个键都不会被领取
+	n, err = relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	delete(sender.fail, "a2")
+	store.dueNow()
+	_, err = relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a1", "a2", "a3"}, sender.sent["a"])
+	for _, event := range store.events {
+		assert.Equal(t, models.OutboxPublished, event.Status)
+	}
+
+	ids := make([]string, 0, len(sender.headers))
+	for _, headers := range sender.headers {
+		ids = append(ids, headers[kafka.HeaderEventID])
+	}
+	sort.Strings(ids)
+	assert.Equal(t, []string{"id-a1", "id-a2", "id-a3", "id-b1", "id-b2"}, ids)
+}
+
+func TestRelay_DeadLettersAfterMaxAttempts(t *testing.T) {
+	store := &memoryStore{}
+	store.add("a", "a1")
+	store.add("a", "a2")
+	sender := newFakeSender()
+	sender.fail["a1"] = true
+
+	relay := NewRelay(store, sender, Options{MaxAttempts: 2})
+	_, err := relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, models.OutboxPending, store.events[0].Status)
+	assert.Empty(t, sender.sent["a"])
+
+	// 第二次失败达到上限，不再重试，同一键的后续事件继续发布
+	store.dueNow()
+	_, err = relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, models.OutboxDead, store.events[0].Status)
+	assert.Equal(t, 2, store.events[0].Attempts)
+	assert.Equal(t, []string{"a2"}, sender.sent["a"])
+	assert.Equal(t, models.OutboxPublished, store.events[1].Status)
+
+	store.dueNow()
+	n, err := relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestGormStore_ClaimCommitsBeforeSending(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "outbox.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec(`CREATE TABLE outbox_events (
+		outbox_id INTEGER PRIMARY KEY, event_id TEXT NOT NULL, topic TEXT NOT NULL, aggregate_key TEXT NOT NULL,
+		payload TEXT NOT NULL, headers TEXT, status TEXT NOT NULL, attempts INTEGER DEFAULT 0,
+		next_attempt_at DATETIME, last_error TEXT, published_at DATETIME, create_time DATETIME)`).Error)
+	store := NewGormStore(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	SetEnabled(true)
+	defer SetEnabled(false)
+	require.NoError(t, Enqueue(db,
+		Message{Topic: "t", Key: "a", Value: "a1"},
+		Message{Topic: "t", Key: "b", Value: "b1"},
+		Message{Topic: "t", Key: "a", Value: "a2"},
+	))
+
+	claimed, err := store.Claim(ctx, now.Add(time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 3)
+
+	// 领取已提交，租约内不会被再次领取
+	again, err := store.Claim(ctx, now.Add(2*time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	// a1失败退避，a2放回后仍排在a1之后；b1发布
+	require.NoError(t, store.MarkFailed(ctx, claimed[0].OutboxID, 1, now.Add(time.Hour), "broker unavailable"))
+	require.NoError(t, store.MarkPublished(ctx, claimed[1].OutboxID, now))
+	require.NoError(t, store.Release(ctx, []uint{claimed[2].OutboxID}, now))
+	again, err = store.Claim(ctx, now.Add(2*time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	// a1进入死信后不再阻塞a2
+	require.NoError(t, store.MarkDead(ctx, claimed[0].OutboxID, 2, "broker unavailable"))
+	again, err = store.Claim(ctx, now.Add(2*time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	assert.Equal(t, claimed[2].OutboxID, again[0].OutboxID)
+
+	// 中继在发送期间退出，租约过期后可被重新领取
+	again, err = store.Claim(ctx, now.Add(2*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	assert.Equal(t, claimed[2].OutboxID, again[0].OutboxID)
+	assert.Equal(t, models.OutboxSending, again[0].Status)
+}
+
+func TestRelay_Backoff(t *testing.T) {
+	relay := NewRelay(&memoryStore{}, newFakeSender(), Options{BaseBackoff: time.Second, MaxBackoff: 10 * time.Second})
+	assert.Equal(t, time.Second, relay.backoff(1))
+	assert.Equal(t, 8*time.Second, relay.backoff(4))
+	assert.Equal(t, 10*time.Second, relay.backoff(20))
+}
+
+func TestEnqueue(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	require.NoError(t, err)
+
+	msg := Message{EventID: "evt-1", Topic: "knowledge.process", Key: "kb-7", Value: map[string]int{"kb": 7}}
+
+	// 未启用时不写入
+	SetEnabled(false)
+	require.NoError(t, Enqueue(db, msg))
+
+	SetEnabled(true)
+	defer SetEnabled(false)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "outbox_events"`)).
+		WithArgs("evt-1", "knowledge.process", "kb-7", `{"kb":7}`, sqlmock.AnyArg(), models.OutboxPending,
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"outbox_id"}).AddRow(1))
+	mock.ExpectCommit()
+	require.NoError(t, Enqueue(db, msg))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Error(t, Enqueue(db, Message{Key: "kb-7", Value: "x"}))
+}
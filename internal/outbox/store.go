@@ -0,0 +1,137 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/aihub/backend-go/internal/database"
+	"github.com/aihub/backend-go/internal/models"
+	"gorm.io/gorm"
+)
+
+// relayLockKey 领取事件时的Postgres advisory lock键，多个副本的领取串行执行，保证同一聚合键的顺序
+const relayLockKey int64 = 0x6f7574626f78 // "outbox"
+
+// Store 发件箱存储
+type Store interface {
+	// Claim 领取一批到期事件并提交，领取的事件标记为发送中，lease内不会被再次领取；
+	// 聚合键中有更早的事件在退避或发送中时，不领取该键的后续事件；其他副本正在领取时返回空
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxEvent, error)
+	// Release 把已领取但未发送的事件放回待发布
+	Release(ctx context.Context, outboxIDs []uint, at time.Time) error
+	// MarkPublished 标记事件已发布
+	MarkPublished(ctx context.Context, outboxID uint, at time.Time) error
+	// MarkFailed 记录发布失败，next之前不再重试
+	MarkFailed(ctx context.Context, outboxID uint, attempts int, next time.Time, lastError string) error
+	// MarkDead 超过最大发布次数，不再重试
+	MarkDead(ctx context.Context, outboxID uint, attempts int, lastError string) error
+	// Purge 删除before之前已发布的事件
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+// GormStore 基于数据库的发件箱存储
+type GormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore 创建数据库存储
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+func (s *GormStore) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 事务级锁只在领取期间持有，随提交释放，发送时不占用事务和锁；单机SQLite写事务本身串行
+		if !database.IsSQLite(tx) {
+			var acquired bool
+			if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", relayLockKey).Scan(&acquired).Error; err != nil {
+				return err
+			}
+			if !acquired {
+				return nil
+			}
+		}
+
+		err := tx.Raw(`
+			SELECT * FROM outbox_events o
+			WHERE o.status IN (?, ?) AND o.next_attempt_at <= ? AND NOT EXISTS (
+				SELECT 1 FROM outbox_events b
+				WHERE b.aggregate_key = o.aggregate_key AND b.outbox_id < o.outbox_id
+					AND b.status IN (?, ?) AND b.next_attempt_at > ?
+			)
+			ORDER BY o.outbox_id
+			LIMIT ?`,
+			models.OutboxPending, models.OutboxSending, now,
+			models.OutboxPending, models.OutboxSending, now, limit).
+			Scan(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(events))
+		for i, event := range events {
+			ids[i] = event.OutboxID
+		}
+		return tx.Model(&models.OutboxEvent{}).
+			Where("outbox_id IN ?", ids).
+			Updates(map[string]interface{}{
+				"status":          models.OutboxSending,
+				"next_attempt_at": now.Add(lease),
+			}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (s *GormStore) Release(ctx context.Context, outboxIDs []uint, at time.Time) error {
+	if len(outboxIDs) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Model(&models.OutboxEvent{}).
+		Where("outbox_id IN ? AND status = ?", outboxIDs, models.OutboxSending).
+		Updates(map[string]interface{}{
+			"status":          models.OutboxPending,
+			"next_attempt_at": at,
+		}).Error
+}
+
+func (s *GormStore) MarkPublished(ctx context.Context, outboxID uint, at time.Time) error {
+	return s.db.WithContext(ctx).Model(&models.OutboxEvent{}).
+		Where("outbox_id = ?", outboxID).
+		Updates(map[string]interface{}{
+			"status":       models.OutboxPublished,
+			"published_at": at,
+			"last_error":   "",
+		}).Error
+}
+
+func (s *GormStore) MarkFailed(ctx context.Context, outboxID uint, attempts int, next time.Time, lastError string) error {
+	return s.db.WithContext(ctx).Model(&models.OutboxEvent{}).
+		Where("outbox_id = ?", outboxID).
+		Updates(map[string]interface{}{
+			"status":          models.OutboxPending,
+			"attempts":        attempts,
+			"next_attempt_at": next,
+			"last_error":      lastError,
+		}).Error
+}
+
+func (s *GormStore) MarkDead(ctx context.Context, outboxID uint, attempts int, lastError string) error {
+	return s.db.WithContext(ctx).Model(&models.OutboxEvent{}).
+		Where("outbox_id = ?", outboxID).
+		Updates(map[string]interface{}{
+			"status":     models.OutboxDead,
+			"attempts":   attempts,
+			"last_error": lastError,
+		}).Error
+}
+
+func (s *GormStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).
+		Where("status = ? AND published_at < ?", models.OutboxPublished, before).
+		Delete(&models.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
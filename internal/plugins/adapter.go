@@ -6,6 +6,9 @@ import (
 	"github.com/aihub/backend-go/internal/knowledge"
 )
 
+// pluginEmbedBatchSize 插件批量向量化的单批上限（插件内部可再按提供方限制拆分）
+const pluginEmbedBatchSize = 25
+
 // EmbedderAdapter 将EmbedderPlugin适配为knowledge.Embedder
 type EmbedderAdapter struct {
 	plugin EmbedderPlugin
@@ -21,6 +24,16 @@ func (a *EmbedderAdapter) Embed(ctx context.Context, text string) ([]float32, er
 	return a.plugin.Embed(ctx, text)
 }
 
+// EmbedBatch 批量向量化
+func (a *EmbedderAdapter) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return a.plugin.EmbedBatch(ctx, texts)
+}
+
+// MaxBatchSize 单次批量请求的最大文本数
+func (a *EmbedderAdapter) MaxBatchSize() int {
+	return pluginEmbedBatchSize
+}
+
 // Dimensions 获取向量维度
 func (a *EmbedderAdapter) Dimensions() int {
 	return a.plugin.Dimensions()
@@ -0,0 +1,238 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultCallQueueTimeout 未配置调用超时时等待并发许可的最长时间
+const defaultCallQueueTimeout = 10 * time.Second
+
+var (
+	// ErrPluginBusy 插件并发调用已达上限，等待许可超时
+	ErrPluginBusy = errors.New("plugin concurrency limit reached")
+	// ErrPluginCallTimeout 插件调用超过配置的超时
+	ErrPluginCallTimeout = errors.New("plugin call timed out")
+)
+
+// CallLimit 插件调用限制
+type CallLimit struct {
+	Timeout       time.Duration // 单次调用超时，0表示不限制
+	MaxConcurrent int           // 最大并发调用数，0表示不限制
+}
+
+// PluginCallStats 插件调用统计
+type PluginCallStats struct {
+	TotalCalls    int64         `json:"total_calls"`
+	ErrorCalls    int64         `json:"error_calls"`
+	RejectedCalls int64         `json:"rejected_calls"` // 因并发限制被拒绝的调用
+	InFlight      int64         `json:"in_flight"`
+	AvgLatency    time.Duration `json:"avg_latency"`
+	MaxLatency    time.Duration `json:"max_latency"`
+	RequestBytes  int64         `json:"request_bytes"`
+	ResponseBytes int64         `json:"response_bytes"`
+	LastError     string        `json:"last_error,omitempty"`
+	LastCalledAt  time.Time     `json:"last_called_at"`
+}
+
+// pluginCallState 单个插件的调用状态
+type pluginCallState struct {
+	mu           sync.Mutex
+	stats        PluginCallStats
+	totalLatency time.Duration
+	sem          chan struct{} // 并发信号量，为nil时不限制
+}
+
+// callStatsRegistry 插件调用统计注册表
+type callStatsRegistry struct {
+	mu     sync.Mutex
+	states map[string]*pluginCallState
+}
+
+func newCallStatsRegistry() *callStatsRegistry {
+	return &callStatsRegistry{states: make(map[string]*pluginCallState)}
+}
+
+// state 获取（或创建）插件的调用状态，并发上限变化时重建信号量
+func (r *callStatsRegistry) state(pluginID string, maxConcurrent int) *pluginCallState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, exists := r.states[pluginID]
+	if !exists {
+		state = &pluginCallState{}
+		r.states[pluginID] = state
+	}
+
+	state.mu.Lock()
+	if maxConcurrent <= 0 {
+		state.sem = nil
+	} else if cap(state.sem) != maxConcurrent {
+		state.sem = make(chan struct{}, maxConcurrent)
+	}
+	state.mu.Unlock()
+
+	return state
+}
+
+// snapshot 获取插件的调用统计
+func (r *callStatsRegistry) snapshot(pluginID string) PluginCallStats {
+	r.mu.Lock()
+	state, exists := r.states[pluginID]
+	r.mu.Unlock()
+	if !exists {
+		return PluginCallStats{}
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.stats
+}
+
+// semaphore 获取当前的并发信号量
+func (s *pluginCallState) semaphore() chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sem
+}
+
+func (s *pluginCallState) begin() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.InFlight++
+	s.stats.LastCalledAt = time.Now()
+}
+
+func (s *pluginCallState) reject() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.RejectedCalls++
+}
+
+// finish 记录一次调用的结果
+func (s *pluginCallState) finish(latency time.Duration, requestBytes, responseBytes int, errMsg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.InFlight--
+	s.stats.TotalCalls++
+	s.stats.RequestBytes += int64(requestBytes)
+	s.stats.ResponseBytes += int64(responseBytes)
+	s.totalLatency += latency
+	s.stats.AvgLatency = s.totalLatency / time.Duration(s.stats.TotalCalls)
+	if latency > s.stats.MaxLatency {
+		s.stats.MaxLatency = latency
+	}
+	if errMsg != "" {
+		s.stats.ErrorCalls++
+		s.stats.LastError = errMsg
+	}
+}
+
+// callSlot 一次受限插件调用：已应用超时并占用并发许可
+type callSlot struct {
+	state   *pluginCallState
+	parent  context.Context // 应用插件超时前的上下文
+	ctx     context.Context
+	cancel  context.CancelFunc
+	timeout time.Duration
+	release func()
+}
+
+// beginCall 应用插件的调用超时并获取并发许可
+// 等待许可的时间受调用超时或调用方期限约束，两者都没有时最多等待CallQueueTimeout
+func (m *PluginManager) beginCall(ctx context.Context, pluginID string) (*callSlot, error) {
+	limit := m.CallLimit(pluginID)
+	slot := &callSlot{
+		state:   m.callStats.state(pluginID, limit.MaxConcurrent),
+		parent:  ctx,
+		cancel:  func() {},
+		release: func() {},
+	}
+	if limit.Timeout > 0 {
+		slot.timeout = limit.Timeout
+		ctx, slot.cancel = context.WithTimeout(ctx, limit.Timeout)
+	}
+	slot.ctx = ctx
+
+	if sem := slot.state.semaphore(); sem != nil {
+		waitCtx := ctx
+		if _, ok := ctx.Deadline(); !ok {
+			wait := m.config.CallQueueTimeout
+			if wait <= 0 {
+				wait = defaultCallQueueTimeout
+			}
+			var cancel context.CancelFunc
+			waitCtx, cancel = context.WithTimeout(ctx, wait)
+			defer cancel()
+		}
+		select {
+		case sem <- struct{}{}:
+			slot.release = func() { <-sem }
+		case <-waitCtx.Done():
+			slot.cancel()
+			slot.state.reject()
+			return nil, fmt.Errorf("%w: plugin %s (%d)", ErrPluginBusy, pluginID, cap(sem))
+		}
+	}
+
+	slot.state.begin()
+	return slot, nil
+}
+
+// timedOut 调用是否因插件自身的超时（而非调用方取消）结束
+func (s *callSlot) timedOut() bool {
+	return s.timeout > 0 && errors.Is(s.ctx.Err(), context.DeadlineExceeded) && s.parent.Err() == nil
+}
+
+// end 释放许可并记录调用结果
+func (s *callSlot) end(latency time.Duration, requestBytes, responseBytes int, errMsg string) {
+	s.cancel()
+	s.release()
+	s.state.finish(latency, requestBytes, responseBytes, errMsg)
+}
+
+// LimitCall 在插件的调用限制下执行fn，供gRPC以外的入口（如HTTP接口）使用，调用计入插件统计
+func (m *PluginManager) LimitCall(ctx context.Context, pluginID string, fn func(ctx context.Context) error) error {
+	start := time.Now()
+	slot, err := m.beginCall(ctx, pluginID)
+	if err != nil {
+		return err
+	}
+
+	err = fn(slot.ctx)
+	if slot.timedOut() {
+		err = fmt.Errorf("%w: plugin %s (%s)", ErrPluginCallTimeout, pluginID, slot.timeout)
+	}
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+	slot.end(time.Since(start), 0, 0, errMsg)
+	return err
+}
+
+// CallLimit 获取插件的调用限制（插件单独配置优先于默认配置）
+func (m *PluginManager) CallLimit(pluginID string) CallLimit {
+	limit := CallLimit{
+		Timeout:       m.config.CallTimeout,
+		MaxConcurrent: m.config.MaxConcurrentCalls,
+	}
+	if override, exists := m.config.CallLimits[pluginID]; exists {
+		if override.Timeout > 0 {
+			limit.Timeout = override.Timeout
+		}
+		if override.MaxConcurrent > 0 {
+			limit.MaxConcurrent = override.MaxConcurrent
+		}
+	}
+	return limit
+}
+
+// CallStats 获取插件的调用统计
+func (m *PluginManager) CallStats(pluginID string) PluginCallStats {
+	return m.callStats.snapshot(pluginID)
+}
@@ -2,6 +2,7 @@ package plugins
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -15,11 +16,11 @@ import (
 type PluginServiceClient struct {
 	baseURL    string
 	httpClient *http.Client
-	userID     uint
+	principal  Principal // 以签名令牌转发给插件服务的调用方身份
 }
 
 // NewPluginServiceClient 创建插件服务客户端
-func NewPluginServiceClient(baseURL string, userID uint) *PluginServiceClient {
+func NewPluginServiceClient(baseURL string, principal Principal) *PluginServiceClient {
 	if baseURL == "" {
 		baseURL = "http://plugin-service:8002" // 默认内部服务地址
 	}
@@ -28,10 +29,37 @@ func NewPluginServiceClient(baseURL string, userID uint) *PluginServiceClient {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		userID: userID,
+		principal: principal,
 	}
 }
 
+// WithPrincipal 返回以指定身份调用的客户端副本
+func (c *PluginServiceClient) WithPrincipal(principal Principal) *PluginServiceClient {
+	clone := *c
+	clone.principal = principal
+	return &clone
+}
+
+// forContext 上下文中记录了调用方身份时，返回以该身份调用的客户端
+func (c *PluginServiceClient) forContext(ctx context.Context) *PluginServiceClient {
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		return c.WithPrincipal(principal)
+	}
+	return c
+}
+
+// do 附加身份令牌后发送请求
+func (c *PluginServiceClient) do(req *http.Request) (*http.Response, error) {
+	authorization, err := principalAuthorization(c.principal)
+	if err != nil {
+		return nil, err
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return c.httpClient.Do(req)
+}
+
 // UploadPlugin 上传插件
 func (c *PluginServiceClient) UploadPlugin(fileReader io.Reader, filename string) (map[string]interface{}, error) {
 	// 读取文件内容到内存（因为需要多次使用）
@@ -64,9 +92,8 @@ func (c *PluginServiceClient) UploadPlugin(fileReader io.Reader, filename string
 	}
 
 	req.Header.Set("Content-Type", formWriter.FormDataContentType())
-	req.Header.Set("X-User-Id", fmt.Sprintf("%d", c.userID))
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("请求失败: %w", err)
 	}
@@ -120,9 +147,8 @@ func (c *PluginServiceClient) ListPlugins() ([]map[string]interface{}, error) {
 		return nil, fmt.Errorf("创建请求失败: %w", err)
 	}
 
-	req.Header.Set("X-User-Id", fmt.Sprintf("%d", c.userID))
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("请求失败: %w", err)
 	}
@@ -163,9 +189,8 @@ func (c *PluginServiceClient) GetModels(pluginID, apiKey string) (map[string][]s
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-User-Id", fmt.Sprintf("%d", c.userID))
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("请求失败: %w", err)
 	}
@@ -200,9 +225,8 @@ func (c *PluginServiceClient) EnablePlugin(pluginID string) error {
 		return fmt.Errorf("创建请求失败: %w", err)
 	}
 
-	req.Header.Set("X-User-Id", fmt.Sprintf("%d", c.userID))
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("请求失败: %w", err)
 	}
@@ -228,9 +252,8 @@ func (c *PluginServiceClient) DisablePlugin(pluginID string) error {
 		return fmt.Errorf("创建请求失败: %w", err)
 	}
 
-	req.Header.Set("X-User-Id", fmt.Sprintf("%d", c.userID))
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("请求失败: %w", err)
 	}
@@ -256,9 +279,8 @@ func (c *PluginServiceClient) DeletePlugin(pluginID string) error {
 		return fmt.Errorf("创建请求失败: %w", err)
 	}
 
-	req.Header.Set("X-User-Id", fmt.Sprintf("%d", c.userID))
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("请求失败: %w", err)
 	}
@@ -291,7 +313,7 @@ func (c *PluginServiceClient) Embed(pluginID, text string) ([]float32, int, erro
 
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, 0, fmt.Errorf("请求失败: %w", err)
 	}
@@ -335,7 +357,7 @@ func (c *PluginServiceClient) Rerank(pluginID, query string, documents []RerankD
 
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("请求失败: %w", err)
 	}
@@ -373,9 +395,8 @@ func (c *PluginServiceClient) UpdatePluginConfig(pluginID string, config map[str
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-User-Id", fmt.Sprintf("%d", c.userID))
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("请求失败: %w", err)
 	}
@@ -401,9 +422,8 @@ func (c *PluginServiceClient) GetPluginConfig(pluginID string) (map[string]inter
 		return nil, fmt.Errorf("创建请求失败: %w", err)
 	}
 
-	req.Header.Set("X-User-Id", fmt.Sprintf("%d", c.userID))
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("请求失败: %w", err)
 	}
@@ -0,0 +1,248 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+
+	plugin_service "github.com/aihub/backend-go/proto"
+)
+
+// chatRequestToProto 转换聊天请求到proto格式
+func chatRequestToProto(pluginID string, req ChatRequest) (*plugin_service.ChatRequest, error) {
+	protoReq := &plugin_service.ChatRequest{
+		PluginId: pluginID,
+		Model:    req.Model,
+		Messages: chatMessagesToProto(req.Messages),
+		Params: &plugin_service.SamplingParams{
+			Temperature: req.Temperature,
+			MaxTokens:   int32(req.MaxTokens),
+			TopP:        req.TopP,
+			TopK:        int32(req.TopK),
+			Stop:        req.Stop,
+		},
+		ToolChoice: req.ToolChoice,
+	}
+
+	for _, tool := range req.Tools {
+		protoReq.Tools = append(protoReq.Tools, &plugin_service.ToolDefinition{
+			Type:           tool.Type,
+			Name:           tool.Function.Name,
+			Description:    tool.Function.Description,
+			ParametersJson: tool.Function.Parameters,
+		})
+	}
+
+	if len(req.ExtraParams) > 0 {
+		extra, err := json.Marshal(req.ExtraParams)
+		if err != nil {
+			return nil, fmt.Errorf("序列化插件参数失败: %w", err)
+		}
+		protoReq.ExtraParamsJson = extra
+	}
+
+	return protoReq, nil
+}
+
+// chatRequestFromProto 转换proto聊天请求到内部格式
+func chatRequestFromProto(req *plugin_service.ChatRequest) (ChatRequest, error) {
+	chatReq := ChatRequest{
+		Model:      req.Model,
+		Messages:   chatMessagesFromProto(req.Messages),
+		ToolChoice: req.ToolChoice,
+	}
+
+	if params := req.Params; params != nil {
+		chatReq.Temperature = params.Temperature
+		chatReq.MaxTokens = int(params.MaxTokens)
+		chatReq.TopP = params.TopP
+		chatReq.TopK = int(params.TopK)
+		chatReq.Stop = params.Stop
+	}
+
+	for _, tool := range req.Tools {
+		toolType := tool.Type
+		if toolType == "" {
+			toolType = "function"
+		}
+		chatReq.Tools = append(chatReq.Tools, ChatTool{
+			Type: toolType,
+			Function: ChatToolFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  json.RawMessage(tool.ParametersJson),
+			},
+		})
+	}
+
+	if len(req.ExtraParamsJson) > 0 {
+		if err := json.Unmarshal(req.ExtraParamsJson, &chatReq.ExtraParams); err != nil {
+			return ChatRequest{}, fmt.Errorf("解析插件参数失败: %w", err)
+		}
+	}
+
+	return chatReq, nil
+}
+
+// chatMessagesToProto 转换聊天消息到proto格式
+func chatMessagesToProto(messages []ChatMessage) []*plugin_service.ChatMessage {
+	protoMessages := make([]*plugin_service.ChatMessage, 0, len(messages))
+	for _, msg := range messages {
+		protoMessages = append(protoMessages, chatMessageToProto(msg))
+	}
+	return protoMessages
+}
+
+// chatMessagesFromProto 转换proto聊天消息到内部格式
+func chatMessagesFromProto(messages []*plugin_service.ChatMessage) []ChatMessage {
+	result := make([]ChatMessage, 0, len(messages))
+	for _, msg := range messages {
+		result = append(result, chatMessageFromProto(msg))
+	}
+	return result
+}
+
+func chatMessageToProto(msg ChatMessage) *plugin_service.ChatMessage {
+	protoMsg := &plugin_service.ChatMessage{
+		Role:       msg.Role,
+		Content:    msg.Content,
+		Name:       msg.Name,
+		ToolCallId: msg.ToolCallID,
+	}
+	for _, call := range msg.ToolCalls {
+		protoMsg.ToolCalls = append(protoMsg.ToolCalls, &plugin_service.ToolCall{
+			Id:        call.ID,
+			Type:      call.Type,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+			Index:     int32(call.Index),
+		})
+	}
+	return protoMsg
+}
+
+func chatMessageFromProto(msg *plugin_service.ChatMessage) ChatMessage {
+	if msg == nil {
+		return ChatMessage{}
+	}
+	result := ChatMessage{
+		Role:       msg.Role,
+		Content:    msg.Content,
+		Name:       msg.Name,
+		ToolCallID: msg.ToolCallId,
+	}
+	for _, call := range msg.ToolCalls {
+		result.ToolCalls = append(result.ToolCalls, ToolCall{
+			Index: int(call.Index),
+			ID:    call.Id,
+			Type:  call.Type,
+			Function: ToolCallFunction{
+				Name:      call.Name,
+				Arguments: call.Arguments,
+			},
+		})
+	}
+	return result
+}
+
+func chatUsageToProto(usage ChatUsage) *plugin_service.ChatUsage {
+	return &plugin_service.ChatUsage{
+		PromptTokens:     int32(usage.PromptTokens),
+		CompletionTokens: int32(usage.CompletionTokens),
+		TotalTokens:      int32(usage.TotalTokens),
+	}
+}
+
+func chatUsageFromProto(usage *plugin_service.ChatUsage) ChatUsage {
+	if usage == nil {
+		return ChatUsage{}
+	}
+	return ChatUsage{
+		PromptTokens:     int(usage.PromptTokens),
+		CompletionTokens: int(usage.CompletionTokens),
+		TotalTokens:      int(usage.TotalTokens),
+	}
+}
+
+// chatResponseToProto 转换聊天响应到proto格式
+func chatResponseToProto(resp *ChatResponse) *plugin_service.ChatResponse {
+	protoResp := &plugin_service.ChatResponse{
+		Success: true,
+		Id:      resp.ID,
+		Model:   resp.Model,
+		Usage:   chatUsageToProto(resp.Usage),
+	}
+	for _, choice := range resp.Choices {
+		protoResp.Choices = append(protoResp.Choices, &plugin_service.ChatChoice{
+			Index:        int32(choice.Index),
+			Message:      chatMessageToProto(choice.Message),
+			FinishReason: choice.FinishReason,
+		})
+	}
+	return protoResp
+}
+
+// chatResponseFromProto 转换proto聊天响应到内部格式
+func chatResponseFromProto(resp *plugin_service.ChatResponse) *ChatResponse {
+	result := &ChatResponse{
+		ID:    resp.Id,
+		Model: resp.Model,
+		Usage: chatUsageFromProto(resp.Usage),
+	}
+	for _, choice := range resp.Choices {
+		result.Choices = append(result.Choices, ChatChoice{
+			Index:        int(choice.Index),
+			Message:      chatMessageFromProto(choice.Message),
+			FinishReason: choice.FinishReason,
+		})
+	}
+	return result
+}
+
+// chatStreamChunkToProto 将插件输出的分片拆分为proto分片（每个候选一个）
+// 只携带用量的分片（如OpenAI的include_usage）会单独发送
+func chatStreamChunkToProto(chunk *ChatStreamChunk) []*plugin_service.ChatStreamChunk {
+	protoChunks := make([]*plugin_service.ChatStreamChunk, 0, len(chunk.Choices)+1)
+	for _, choice := range chunk.Choices {
+		protoChunks = append(protoChunks, &plugin_service.ChatStreamChunk{
+			Id:           chunk.ID,
+			Model:        chunk.Model,
+			Index:        int32(choice.Index),
+			Delta:        chatMessageToProto(choice.Delta),
+			FinishReason: choice.FinishReason,
+		})
+	}
+
+	if chunk.Usage != nil {
+		if len(protoChunks) > 0 {
+			protoChunks[len(protoChunks)-1].Usage = chatUsageToProto(*chunk.Usage)
+		} else {
+			protoChunks = append(protoChunks, &plugin_service.ChatStreamChunk{
+				Id:    chunk.ID,
+				Model: chunk.Model,
+				Usage: chatUsageToProto(*chunk.Usage),
+			})
+		}
+	}
+
+	return protoChunks
+}
+
+// chatStreamChunkFromProto 转换proto分片到内部格式
+func chatStreamChunkFromProto(chunk *plugin_service.ChatStreamChunk) ChatStreamChunk {
+	result := ChatStreamChunk{
+		ID:    chunk.Id,
+		Model: chunk.Model,
+	}
+	if chunk.Delta != nil || chunk.FinishReason != "" {
+		result.Choices = []ChatStreamChoice{{
+			Index:        int(chunk.Index),
+			Delta:        chatMessageFromProto(chunk.Delta),
+			FinishReason: chunk.FinishReason,
+		}}
+	}
+	if chunk.Usage != nil {
+		usage := chatUsageFromProto(chunk.Usage)
+		result.Usage = &usage
+	}
+	return result
+}
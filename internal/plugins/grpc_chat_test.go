@@ -0,0 +1,164 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	plugin_service "github.com/aihub/backend-go/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type stubChatPlugin struct {
+	stubPlugin
+	lastRequest ChatRequest
+}
+
+func (p *stubChatPlugin) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	p.lastRequest = req
+	return &ChatResponse{
+		ID:    "chat-1",
+		Model: req.Model,
+		Choices: []ChatChoice{{
+			Message: ChatMessage{
+				Role: "assistant",
+				ToolCalls: []ToolCall{{
+					ID:       "call-1",
+					Type:     "function",
+					Function: ToolCallFunction{Name: "get_weather", Arguments: `{"city":"杭州"}`},
+				}},
+			},
+			FinishReason: "tool_calls",
+		}},
+		Usage: ChatUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}, nil
+}
+
+func (p *stubChatPlugin) ChatStream(ctx context.Context, req ChatRequest, onChunk func([]byte) error) error {
+	p.lastRequest = req
+	chunks := []string{
+		`{"id":"s-1","model":"m","choices":[{"index":0,"delta":{"role":"assistant","content":"你"}}]}`,
+		`{"id":"s-1","model":"m","choices":[{"index":0,"delta":{"content":"好"},"finish_reason":"stop"}]}`,
+		`{"id":"s-1","model":"m","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`,
+	}
+	for _, chunk := range chunks {
+		if err := onChunk([]byte(chunk)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newTestGRPCClient 启动带拦截器的内存gRPC服务并返回客户端
+func newTestGRPCClient(t *testing.T, config ManagerConfig, plugins ...Plugin) (*PluginGRPCClient, *PluginManager) {
+	t.Helper()
+
+	manager := &PluginManager{
+		registry:  NewPluginRegistry(),
+		config:    &config,
+		versions:  make(map[string]*versionHistory),
+		callStats: newCallStatsRegistry(),
+		tenancies: make(map[string]*PluginTenancy),
+	}
+	for _, plugin := range plugins {
+		require.NoError(t, manager.registry.Register(plugin))
+		require.NoError(t, manager.registry.UpdateState(plugin.Metadata().ID, StateActive, nil))
+	}
+
+	interceptor := NewPluginCallInterceptor(manager)
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(interceptor.Unary()),
+		grpc.ChainStreamInterceptor(interceptor.Stream()),
+	)
+	plugin_service.RegisterPluginServiceServer(server, NewPluginGRPCServer(manager, nil))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(TraceUnaryClientInterceptor(), PrincipalUnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(TraceStreamClientInterceptor(), PrincipalStreamClientInterceptor()))
+	require.NoError(t, err)
+
+	client := &PluginGRPCClient{conn: conn, client: plugin_service.NewPluginServiceClient(conn)}
+	t.Cleanup(func() { client.Close() })
+	return client, manager
+}
+
+func TestPluginGRPC_Chat(t *testing.T) {
+	plugin := &stubChatPlugin{stubPlugin: stubPlugin{metadata: PluginMetadata{ID: "chat"}}}
+	client, _ := newTestGRPCClient(t, ManagerConfig{}, plugin)
+
+	resp, err := client.Chat(context.Background(), "chat", ChatRequest{
+		Model:       "qwen-plus",
+		Messages:    []ChatMessage{{Role: "user", Content: "杭州天气"}},
+		Temperature: 0.2,
+		Stop:        []string{"\n\n"},
+		Tools: []ChatTool{{
+			Type: "function",
+			Function: ChatToolFunction{
+				Name:       "get_weather",
+				Parameters: json.RawMessage(`{"type":"object"}`),
+			},
+		}},
+		ExtraParams: map[string]interface{}{"enable_search": true},
+	})
+	require.NoError(t, err)
+
+	// 请求参数完整传到插件
+	assert.Equal(t, "qwen-plus", plugin.lastRequest.Model)
+	assert.Equal(t, 0.2, plugin.lastRequest.Temperature)
+	assert.Equal(t, []string{"\n\n"}, plugin.lastRequest.Stop)
+	require.Len(t, plugin.lastRequest.Tools, 1)
+	assert.JSONEq(t, `{"type":"object"}`, string(plugin.lastRequest.Tools[0].Function.Parameters))
+	assert.Equal(t, true, plugin.lastRequest.ExtraParams["enable_search"])
+
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "tool_calls", resp.Choices[0].FinishReason)
+	require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	assert.Equal(t, `{"city":"杭州"}`, resp.Choices[0].Message.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, 15, resp.Usage.TotalTokens)
+}
+
+func TestPluginGRPC_ChatStream(t *testing.T) {
+	plugin := &stubChatPlugin{stubPlugin: stubPlugin{metadata: PluginMetadata{ID: "chat"}}}
+	client, _ := newTestGRPCClient(t, ManagerConfig{}, plugin)
+
+	var content string
+	var usage *ChatUsage
+	err := client.ChatStream(context.Background(), "chat", ChatRequest{
+		Messages: []ChatMessage{{Role: "user", Content: "hi"}},
+	}, func(chunk ChatStreamChunk) error {
+		for _, choice := range chunk.Choices {
+			content += choice.Delta.Content
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.True(t, plugin.lastRequest.Stream)
+	assert.Equal(t, "你好", content)
+	require.NotNil(t, usage)
+	assert.Equal(t, 5, usage.TotalTokens)
+}
+
+func TestPluginGRPC_ChatUnsupportedPlugin(t *testing.T) {
+	client, _ := newTestGRPCClient(t, ManagerConfig{}, &stubPlugin{metadata: PluginMetadata{ID: "embed-only"}})
+
+	_, err := client.Chat(context.Background(), "embed-only", ChatRequest{
+		Messages: []ChatMessage{{Role: "user", Content: "hi"}},
+	})
+	assert.Error(t, err)
+}
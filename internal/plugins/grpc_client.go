@@ -2,7 +2,9 @@ package plugins
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 
 	plugin_service "github.com/aihub/backend-go/proto"
@@ -25,7 +27,11 @@ func NewPluginGRPCClient(address string) (*PluginGRPCClient, error) {
 		}
 	}
 
-	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(TraceUnaryClientInterceptor(), PrincipalUnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(TraceStreamClientInterceptor(), PrincipalStreamClientInterceptor()),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("连接插件服务失败: %w", err)
 	}
@@ -131,6 +137,55 @@ func (c *PluginGRPCClient) Rerank(ctx context.Context, pluginID, query string, d
 	return results, nil
 }
 
+// Chat 聊天（非流式）
+func (c *PluginGRPCClient) Chat(ctx context.Context, pluginID string, req ChatRequest) (*ChatResponse, error) {
+	protoReq, err := chatRequestToProto(pluginID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Chat(ctx, protoReq)
+	if err != nil {
+		return nil, fmt.Errorf("聊天失败: %w", err)
+	}
+
+	if !resp.Success {
+		return nil, fmt.Errorf("聊天失败: %s", resp.Error)
+	}
+
+	return chatResponseFromProto(resp), nil
+}
+
+// ChatStream 流式聊天，每收到一个分片调用一次onChunk
+func (c *PluginGRPCClient) ChatStream(ctx context.Context, pluginID string, req ChatRequest, onChunk func(ChatStreamChunk) error) error {
+	protoReq, err := chatRequestToProto(pluginID, req)
+	if err != nil {
+		return err
+	}
+
+	// 提前返回时取消流，通知服务端停止生成
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := c.client.ChatStream(ctx, protoReq)
+	if err != nil {
+		return fmt.Errorf("流式聊天失败: %w", err)
+	}
+
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("流式聊天失败: %w", err)
+		}
+		if err := onChunk(chatStreamChunkFromProto(chunk)); err != nil {
+			return err
+		}
+	}
+}
+
 // GetPluginInfo 获取插件信息（用于查找插件）
 func (c *PluginGRPCClient) GetPluginInfo(ctx context.Context, pluginID string) (*PluginMetadata, error) {
 	req := &plugin_service.ListPluginsRequest{}
@@ -0,0 +1,353 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	plugin_service "github.com/aihub/backend-go/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const tracerName = "github.com/aihub/backend-go/internal/plugins"
+
+// tracePropagator 固定使用W3C Trace Context，与全局传播器配置无关
+var tracePropagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// limitedMethods 调用插件实现的方法，受超时和并发限制并计入插件调用统计
+var limitedMethods = map[string]bool{
+	plugin_service.PluginService_Embed_FullMethodName:      true,
+	plugin_service.PluginService_EmbedBatch_FullMethodName: true,
+	plugin_service.PluginService_Rerank_FullMethodName:     true,
+	plugin_service.PluginService_Chat_FullMethodName:       true,
+	plugin_service.PluginService_ChatStream_FullMethodName: true,
+}
+
+// pluginMetrics 插件调用的Prometheus指标
+type pluginMetrics struct {
+	duration *prometheus.HistogramVec
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	payload  *prometheus.HistogramVec
+	inflight *prometheus.GaugeVec
+}
+
+var (
+	defaultMetricsOnce sync.Once
+	defaultMetrics     *pluginMetrics
+)
+
+// defaultPluginMetrics 获取注册到默认Registry的指标（单例）
+func defaultPluginMetrics() *pluginMetrics {
+	defaultMetricsOnce.Do(func() {
+		defaultMetrics = newPluginMetrics(prometheus.DefaultRegisterer)
+	})
+	return defaultMetrics
+}
+
+func newPluginMetrics(registerer prometheus.Registerer) *pluginMetrics {
+	m := &pluginMetrics{
+		duration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "plugin_grpc_request_duration_seconds",
+				Help:    "Duration of plugin service gRPC calls",
+				Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+			},
+			[]string{"plugin_id", "method"},
+		),
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "plugin_grpc_requests_total",
+				Help: "Total number of plugin service gRPC calls",
+			},
+			[]string{"plugin_id", "method", "code"},
+		),
+		errors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "plugin_grpc_errors_total",
+				Help: "Total number of failed plugin service gRPC calls",
+			},
+			[]string{"plugin_id", "method", "code"},
+		),
+		payload: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "plugin_grpc_payload_bytes",
+				Help:    "Size of plugin service gRPC payloads",
+				Buckets: prometheus.ExponentialBuckets(256, 4, 10), // 256B ~ 64MB
+			},
+			[]string{"plugin_id", "method", "direction"}, // direction: request, response
+		),
+		inflight: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "plugin_grpc_inflight_requests",
+				Help: "Number of plugin service gRPC calls in progress",
+			},
+			[]string{"plugin_id", "method"},
+		),
+	}
+
+	registerer.MustRegister(m.duration, m.requests, m.errors, m.payload, m.inflight)
+	return m
+}
+
+// PluginCallInterceptor 插件服务gRPC拦截器
+// 记录指标、传播链路追踪，并对插件调用执行超时与并发限制
+type PluginCallInterceptor struct {
+	pluginMgr *PluginManager
+	metrics   *pluginMetrics
+	tracer    trace.Tracer
+}
+
+// NewPluginCallInterceptor 创建插件服务gRPC拦截器
+func NewPluginCallInterceptor(pluginMgr *PluginManager) *PluginCallInterceptor {
+	return &PluginCallInterceptor{
+		pluginMgr: pluginMgr,
+		metrics:   defaultPluginMetrics(),
+		tracer:    otel.Tracer(tracerName),
+	}
+}
+
+// Unary 一元调用拦截器
+func (i *PluginCallInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		call := i.begin(ctx, info.FullMethod, req)
+		if call.rejected != nil {
+			return nil, call.finish(nil, call.rejected, 0, 0)
+		}
+
+		resp, err := handler(call.ctx, req)
+		return resp, call.finish(resp, err, messageSize(req), messageSize(resp))
+	}
+}
+
+// Stream 流式调用拦截器
+// 服务端流在处理函数读取请求后才能确定插件ID，因此在首次RecvMsg时开始计量
+func (i *PluginCallInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		stream := &callStream{ServerStream: ss, ctx: ss.Context()}
+		stream.onFirstRecv = func(req interface{}) error {
+			stream.call = i.begin(stream.ctx, info.FullMethod, req)
+			stream.ctx = stream.call.ctx
+			return stream.call.rejected
+		}
+
+		err := handler(srv, stream)
+		if stream.call == nil {
+			return err
+		}
+		return stream.call.finish(nil, err, stream.recvBytes, stream.sentBytes)
+	}
+}
+
+// pluginCall 一次被拦截的调用
+type pluginCall struct {
+	interceptor *PluginCallInterceptor
+	pluginID    string
+	metricID    string // 指标标签中的插件ID
+	method      string
+	start       time.Time
+	ctx         context.Context
+	span        trace.Span
+	slot        *callSlot // 仅受限方法有值
+	rejected    error
+}
+
+// begin 开始一次调用：提取追踪上下文、应用超时并获取并发许可
+func (i *PluginCallInterceptor) begin(ctx context.Context, fullMethod string, req interface{}) *pluginCall {
+	call := &pluginCall{
+		interceptor: i,
+		pluginID:    requestPluginID(req),
+		method:      fullMethod[strings.LastIndex(fullMethod, "/")+1:],
+		start:       time.Now(),
+	}
+	// 插件ID来自请求，只有已安装的插件才作为指标标签和统计键，避免任意取值撑大标签基数
+	installed := call.pluginID != "" && i.pluginMgr != nil && i.pluginMgr.installed(call.pluginID)
+	switch {
+	case call.pluginID == "":
+		call.metricID = "-"
+	case installed:
+		call.metricID = call.pluginID
+	default:
+		call.metricID = "unknown"
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = tracePropagator.Extract(ctx, metadataCarrier(md))
+	}
+	ctx, call.span = i.tracer.Start(ctx, fullMethod,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.method", call.method),
+			attribute.String("plugin.id", call.pluginID),
+		))
+
+	i.metrics.inflight.WithLabelValues(call.metricID, call.method).Inc()
+
+	if limitedMethods[fullMethod] && installed {
+		slot, err := i.pluginMgr.beginCall(ctx, call.pluginID)
+		if err != nil {
+			call.rejected = status.Error(codes.ResourceExhausted, fmt.Sprintf("插件 %s 并发调用已达上限: %v", call.pluginID, err))
+		} else {
+			call.slot = slot
+			ctx = slot.ctx
+		}
+	}
+
+	call.ctx = ctx
+	return call
+}
+
+// finish 结束调用并记录指标，返回最终错误
+func (c *pluginCall) finish(resp interface{}, err error, requestBytes, responseBytes int) error {
+	latency := time.Since(c.start)
+
+	// 插件超时（而非调用方超时）转换为明确的错误
+	if c.slot != nil && c.slot.timedOut() {
+		err = status.Errorf(codes.DeadlineExceeded, "插件 %s 调用超时(%s)", c.pluginID, c.slot.timeout)
+	}
+
+	code := status.Code(err).String()
+	errMsg := ""
+	if err != nil {
+		errMsg = status.Convert(err).Message()
+	} else if failed, ok := resp.(interface{ GetSuccess() bool }); ok && !failed.GetSuccess() {
+		// 插件执行失败时服务仍返回Success=false的响应
+		code = "PluginError"
+		if withError, ok := resp.(interface{ GetError() string }); ok {
+			errMsg = withError.GetError()
+		}
+		if errMsg == "" {
+			errMsg = "plugin call failed"
+		}
+	}
+
+	metrics := c.interceptor.metrics
+	metrics.inflight.WithLabelValues(c.metricID, c.method).Dec()
+	metrics.duration.WithLabelValues(c.metricID, c.method).Observe(latency.Seconds())
+	metrics.requests.WithLabelValues(c.metricID, c.method, code).Inc()
+	metrics.payload.WithLabelValues(c.metricID, c.method, "request").Observe(float64(requestBytes))
+	metrics.payload.WithLabelValues(c.metricID, c.method, "response").Observe(float64(responseBytes))
+	if errMsg != "" {
+		metrics.errors.WithLabelValues(c.metricID, c.method, code).Inc()
+		c.span.SetStatus(otelcodes.Error, errMsg)
+	}
+	c.span.SetAttributes(attribute.String("rpc.grpc.status_code", code))
+	c.span.End()
+
+	if c.slot != nil {
+		c.slot.end(latency, requestBytes, responseBytes, errMsg)
+	}
+
+	return err
+}
+
+// callStream 包装服务端流，记录收发字节数并替换上下文
+type callStream struct {
+	grpc.ServerStream
+	ctx         context.Context
+	call        *pluginCall
+	onFirstRecv func(req interface{}) error
+	recvBytes   int
+	sentBytes   int
+}
+
+func (s *callStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *callStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	s.recvBytes += messageSize(m)
+	if s.onFirstRecv != nil {
+		onFirstRecv := s.onFirstRecv
+		s.onFirstRecv = nil
+		return onFirstRecv(m)
+	}
+	return nil
+}
+
+func (s *callStream) SendMsg(m interface{}) error {
+	if err := s.ServerStream.SendMsg(m); err != nil {
+		return err
+	}
+	s.sentBytes += messageSize(m)
+	return nil
+}
+
+// TraceUnaryClientInterceptor 将调用方的链路追踪上下文注入gRPC元数据
+func TraceUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(injectTraceContext(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// TraceStreamClientInterceptor 将调用方的链路追踪上下文注入流式调用的gRPC元数据
+func TraceStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(injectTraceContext(ctx), desc, cc, method, opts...)
+	}
+}
+
+func injectTraceContext(ctx context.Context) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	tracePropagator.Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// metadataCarrier 适配gRPC元数据为OpenTelemetry的TextMapCarrier
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// requestPluginID 从请求中获取插件ID
+func requestPluginID(req interface{}) string {
+	if withID, ok := req.(interface{ GetPluginId() string }); ok {
+		return withID.GetPluginId()
+	}
+	return ""
+}
+
+func messageSize(m interface{}) int {
+	if msg, ok := m.(proto.Message); ok && msg != nil {
+		return proto.Size(msg)
+	}
+	return 0
+}
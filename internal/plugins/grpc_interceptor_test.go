@@ -0,0 +1,197 @@
+package plugins
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	plugin_service "github.com/aihub/backend-go/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type stubEmbedderPlugin struct {
+	stubPlugin
+	embed func(ctx context.Context, text string) ([]float32, error)
+}
+
+func (p *stubEmbedderPlugin) Embed(ctx context.Context, text string) ([]float32, error) {
+	return p.embed(ctx, text)
+}
+
+func (p *stubEmbedderPlugin) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return nil, nil
+}
+
+func (p *stubEmbedderPlugin) Dimensions() int                           { return 2 }
+func (p *stubEmbedderPlugin) GetModels(apiKey string) ([]string, error) { return nil, nil }
+
+func newStubEmbedder(id string, embed func(ctx context.Context, text string) ([]float32, error)) *stubEmbedderPlugin {
+	return &stubEmbedderPlugin{stubPlugin: stubPlugin{metadata: PluginMetadata{ID: id}}, embed: embed}
+}
+
+func TestPluginCallInterceptor_RecordsStats(t *testing.T) {
+	plugin := newStubEmbedder("embedder", func(ctx context.Context, text string) ([]float32, error) {
+		return []float32{0.1, 0.2}, nil
+	})
+	client, manager := newTestGRPCClient(t, ManagerConfig{}, plugin)
+
+	_, _, err := client.Embed(context.Background(), "embedder", "hello")
+	require.NoError(t, err)
+
+	stats := manager.CallStats("embedder")
+	assert.Equal(t, int64(1), stats.TotalCalls)
+	assert.Equal(t, int64(0), stats.ErrorCalls)
+	assert.Equal(t, int64(0), stats.InFlight)
+	assert.Positive(t, stats.RequestBytes)
+	assert.Positive(t, stats.ResponseBytes)
+
+	// 统计通过ListPlugins返回
+	resp, err := client.client.ListPlugins(context.Background(), &plugin_service.ListPluginsRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.Plugins, 1)
+	assert.Equal(t, int64(1), resp.Plugins[0].Stats.TotalCalls)
+}
+
+func TestPluginCallInterceptor_EnforcesTimeout(t *testing.T) {
+	plugin := newStubEmbedder("slow", func(ctx context.Context, text string) ([]float32, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	client, manager := newTestGRPCClient(t, ManagerConfig{
+		CallLimits: map[string]CallLimit{"slow": {Timeout: 20 * time.Millisecond}},
+	}, plugin)
+
+	_, err := client.client.Embed(context.Background(), &plugin_service.EmbedRequest{PluginId: "slow", Text: "hello"})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+	stats := manager.CallStats("slow")
+	assert.Equal(t, int64(1), stats.ErrorCalls)
+}
+
+func TestPluginCallInterceptor_EnforcesConcurrencyLimit(t *testing.T) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	plugin := newStubEmbedder("busy", func(ctx context.Context, text string) ([]float32, error) {
+		close(started)
+		<-unblock
+		return []float32{1, 0}, nil
+	})
+	client, manager := newTestGRPCClient(t, ManagerConfig{
+		CallTimeout:        50 * time.Millisecond,
+		MaxConcurrentCalls: 1,
+		CallLimits:         map[string]CallLimit{"busy": {Timeout: time.Second}},
+	}, plugin)
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := client.Embed(context.Background(), "busy", "first")
+		done <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.client.Embed(ctx, &plugin_service.EmbedRequest{PluginId: "busy", Text: "second"})
+	assert.Error(t, err)
+
+	// 服务端在感知到调用方取消后才记录拒绝，先等待拒绝再释放第一个调用，避免第二个调用抢到许可
+	require.Eventually(t, func() bool {
+		return manager.CallStats("busy").RejectedCalls == 1
+	}, time.Second, 10*time.Millisecond)
+
+	close(unblock)
+	require.NoError(t, <-done)
+	assert.Equal(t, int64(1), manager.CallStats("busy").TotalCalls)
+}
+
+func TestPluginCallInterceptor_PropagatesTraceContext(t *testing.T) {
+	var received trace.SpanContext
+	plugin := newStubEmbedder("traced", func(ctx context.Context, text string) ([]float32, error) {
+		received = trace.SpanContextFromContext(ctx)
+		return []float32{1, 0}, nil
+	})
+	client, _ := newTestGRPCClient(t, ManagerConfig{}, plugin)
+
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01, 0x02, 0x03},
+		SpanID:     trace.SpanID{0x04, 0x05},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), parent)
+
+	_, _, err := client.Embed(ctx, "traced", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, parent.TraceID(), received.TraceID())
+}
+
+func TestPluginManager_LimitCallBoundsQueueWait(t *testing.T) {
+	plugin := newStubEmbedder("queued", nil)
+	_, manager := newTestGRPCClient(t, ManagerConfig{
+		MaxConcurrentCalls: 1,
+		CallQueueTimeout:   30 * time.Millisecond,
+	}, plugin)
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- manager.LimitCall(context.Background(), "queued", func(ctx context.Context) error {
+			close(started)
+			<-unblock
+			return nil
+		})
+	}()
+	<-started
+
+	// 没有调用超时也没有调用方期限时，排队等待同样有上限
+	err := manager.LimitCall(context.Background(), "queued", func(ctx context.Context) error {
+		t.Fatal("call should not run while the plugin is busy")
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrPluginBusy)
+
+	close(unblock)
+	require.NoError(t, <-done)
+
+	stats := manager.CallStats("queued")
+	assert.Equal(t, int64(1), stats.TotalCalls)
+	assert.Equal(t, int64(1), stats.RejectedCalls)
+}
+
+func TestPluginCallInterceptor_UnknownPluginLabel(t *testing.T) {
+	_, manager := newTestGRPCClient(t, ManagerConfig{})
+	interceptor := NewPluginCallInterceptor(manager)
+	registry := prometheus.NewRegistry()
+	interceptor.metrics = newPluginMetrics(registry)
+
+	info := &grpc.UnaryServerInfo{FullMethod: plugin_service.PluginService_Embed_FullMethodName}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "plugin not found")
+	}
+	for _, id := range []string{"random-1", "random-2"} {
+		_, err := interceptor.Unary()(context.Background(), &plugin_service.EmbedRequest{PluginId: id}, info, handler)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	}
+
+	// 未安装插件的ID不作为指标标签，也不产生调用统计
+	assert.Equal(t, 2.0, testutil.ToFloat64(interceptor.metrics.requests.WithLabelValues("unknown", "Embed", "NotFound")))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "plugin_id" {
+					assert.Equal(t, "unknown", label.GetValue())
+				}
+			}
+		}
+	}
+	assert.Zero(t, manager.CallStats("random-1").TotalCalls)
+}
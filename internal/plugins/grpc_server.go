@@ -3,15 +3,19 @@ package plugins
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
+	"time"
 
 	"github.com/aihub/backend-go/internal/middleware"
 	plugin_service "github.com/aihub/backend-go/proto"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -30,12 +34,41 @@ func NewPluginGRPCServer(pluginMgr *PluginManager, minioSvc *middleware.MinIOSer
 	}
 }
 
+// principalFromContext 从元数据中的签名令牌获取调用方身份
+// 请求中的user_id等字段由客户端填写，不作为身份依据；未携带令牌时视为匿名的内部调用，令牌无效时拒绝
+func principalFromContext(ctx context.Context) (Principal, error) {
+	var authorization string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(MetadataAuthorization); len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+	principal, err := VerifyAuthorization(authorization)
+	if err != nil {
+		return Principal{}, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return principal, nil
+}
+
+// authzError 转换授权错误为gRPC状态
+func authzError(err error) error {
+	switch {
+	case errors.Is(err, ErrPluginAccessDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, ErrPluginDisabled):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Error(codes.NotFound, fmt.Sprintf("插件不存在: %v", err))
+}
+
 // UploadPlugin 上传插件
 func (s *PluginGRPCServer) UploadPlugin(ctx context.Context, req *plugin_service.UploadPluginRequest) (*plugin_service.UploadPluginResponse, error) {
 	if len(req.FileContent) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "文件内容不能为空")
 	}
 
+	// 文件名由客户端提供，只保留最后一级，避免写出临时目录
+	req.Filename = filepath.Base(req.Filename)
 	if filepath.Ext(req.Filename) != ".xpkg" {
 		return nil, status.Error(codes.InvalidArgument, "只支持.xpkg格式的插件文件")
 	}
@@ -68,9 +101,30 @@ func (s *PluginGRPCServer) UploadPlugin(ctx context.Context, req *plugin_service
 
 	pluginID := metadata.ID
 
-	// 上传到MinIO
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	visibility := DefaultVisibility(principal)
+	if req.Visibility != "" {
+		if visibility, err = ParseVisibility(req.Visibility); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	// 先检查权限，避免覆盖他人插件的存储文件
+	if s.pluginMgr != nil {
+		if err := s.pluginMgr.AuthorizeInstall(principal, pluginID, visibility); err != nil {
+			if errors.Is(err, ErrPluginAccessDenied) {
+				return nil, status.Error(codes.PermissionDenied, err.Error())
+			}
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	// 上传到MinIO（按版本存储，保留历史版本用于回滚）
 	if s.minioSvc != nil {
-		objectKey := fmt.Sprintf("plugins/%s/%s", pluginID, req.Filename)
+		objectKey := fmt.Sprintf("plugins/%s/%s/%s", pluginID, metadata.Version, req.Filename)
 		reader := bytes.NewReader(req.FileContent)
 		if err := s.minioSvc.UploadFile("plugins", objectKey, reader, int64(len(req.FileContent)), "application/zip"); err != nil {
 			return nil, status.Error(codes.Internal, fmt.Sprintf("上传到MinIO失败: %v", err))
@@ -78,26 +132,33 @@ func (s *PluginGRPCServer) UploadPlugin(ctx context.Context, req *plugin_service
 		log.Printf("[plugin-grpc] Plugin uploaded to MinIO: %s", objectKey)
 	}
 
-	// 加载插件
+	// 安装新版本并热切换（已有版本时不中断服务）
+	var previousVersion string
 	if s.pluginMgr != nil {
-		if err := s.pluginMgr.LoadPlugin(tempPath); err != nil {
-			errMsg := err.Error()
-			if strings.Contains(errMsg, "plugin: not implemented") ||
-				strings.Contains(errMsg, "cannot load") ||
-				strings.Contains(errMsg, "incompatible") {
+		previousVersion = s.pluginMgr.ActiveVersion(pluginID)
+		if _, err := s.pluginMgr.InstallPluginAs(principal, tempPath, req.Filename, visibility); err != nil {
+			if errors.Is(err, ErrPluginAccessDenied) {
+				return nil, status.Error(codes.PermissionDenied, err.Error())
+			}
+			if errors.Is(err, ErrPluginVersionExists) {
+				return nil, status.Error(codes.AlreadyExists, fmt.Sprintf("插件版本已存在: %v", err))
+			}
+			if errors.Is(err, ErrPluginIncompatible) {
 				return nil, status.Error(codes.InvalidArgument, fmt.Sprintf(
 					"插件平台不兼容: %v", err))
 			}
 			return nil, status.Error(codes.Internal, fmt.Sprintf("加载插件失败: %v", err))
 		}
-		log.Printf("[plugin-grpc] Plugin loaded: %s", req.Filename)
+		log.Printf("[plugin-grpc] Plugin loaded: %s (version %s)", req.Filename, metadata.Version)
 	}
 
 	return &plugin_service.UploadPluginResponse{
-		Success:  true,
-		Message:  "插件上传并加载成功",
-		PluginId: pluginID,
-		Filename: req.Filename,
+		Success:         true,
+		Message:         "插件上传并加载成功",
+		PluginId:        pluginID,
+		Filename:        req.Filename,
+		Version:         metadata.Version,
+		PreviousVersion: previousVersion,
 	}, nil
 }
 
@@ -110,7 +171,11 @@ func (s *PluginGRPCServer) ListPlugins(ctx context.Context, req *plugin_service.
 		}, nil
 	}
 
-	entries := s.pluginMgr.ListPlugins()
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries := s.pluginMgr.ListPluginsFor(principal)
 	plugins := make([]*plugin_service.PluginInfo, 0, len(entries))
 
 	for _, entry := range entries {
@@ -123,7 +188,7 @@ func (s *PluginGRPCServer) ListPlugins(ctx context.Context, req *plugin_service.
 			})
 		}
 
-		plugins = append(plugins, &plugin_service.PluginInfo{
+		info := &plugin_service.PluginInfo{
 			Id:           meta.ID,
 			Name:         meta.Name,
 			Version:      meta.Version,
@@ -133,7 +198,15 @@ func (s *PluginGRPCServer) ListPlugins(ctx context.Context, req *plugin_service.
 			Provider:     meta.Provider,
 			State:        string(entry.State),
 			Capabilities: capabilities,
-		})
+			Stats:        s.pluginStats(meta.ID),
+		}
+		tenancy := s.pluginMgr.Tenancy(meta.ID)
+		info.OwnerId = uint32(tenancy.OwnerID)
+		info.OrgId = uint32(tenancy.OrgID)
+		info.Visibility = string(tenancy.Visibility)
+		info.Enabled = s.pluginMgr.IsEnabledFor(principal, meta.ID)
+
+		plugins = append(plugins, info)
 	}
 
 	return &plugin_service.ListPluginsResponse{
@@ -142,12 +215,44 @@ func (s *PluginGRPCServer) ListPlugins(ctx context.Context, req *plugin_service.
 	}, nil
 }
 
+// pluginStats 获取插件调用统计
+func (s *PluginGRPCServer) pluginStats(pluginID string) *plugin_service.PluginStats {
+	stats := s.pluginMgr.CallStats(pluginID)
+	limit := s.pluginMgr.CallLimit(pluginID)
+
+	protoStats := &plugin_service.PluginStats{
+		TotalCalls:    stats.TotalCalls,
+		ErrorCalls:    stats.ErrorCalls,
+		RejectedCalls: stats.RejectedCalls,
+		InFlight:      stats.InFlight,
+		AvgLatencyMs:  float64(stats.AvgLatency) / float64(time.Millisecond),
+		MaxLatencyMs:  float64(stats.MaxLatency) / float64(time.Millisecond),
+		RequestBytes:  stats.RequestBytes,
+		ResponseBytes: stats.ResponseBytes,
+		LastError:     stats.LastError,
+		TimeoutMs:     limit.Timeout.Milliseconds(),
+		MaxConcurrent: int32(limit.MaxConcurrent),
+	}
+	if !stats.LastCalledAt.IsZero() {
+		protoStats.LastCalledAt = stats.LastCalledAt.Unix()
+	}
+	return protoStats
+}
+
 // GetModels 获取插件支持的模型
 func (s *PluginGRPCServer) GetModels(ctx context.Context, req *plugin_service.GetModelsRequest) (*plugin_service.GetModelsResponse, error) {
 	if s.pluginMgr == nil {
 		return nil, status.Error(codes.Internal, "插件管理器未初始化")
 	}
 
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.pluginMgr.AuthorizeView(principal, req.PluginId); err != nil {
+		return nil, authzError(err)
+	}
+
 	plugin, err := s.pluginMgr.GetPlugin(req.PluginId)
 	if err != nil {
 		return nil, status.Error(codes.NotFound, fmt.Sprintf("插件不存在: %v", err))
@@ -208,13 +313,27 @@ func (s *PluginGRPCServer) EnablePlugin(ctx context.Context, req *plugin_service
 		return nil, status.Error(codes.Internal, "插件管理器未初始化")
 	}
 
-	if err := s.pluginMgr.EnablePlugin(req.PluginId); err != nil {
+	scope, err := ParseOverlayScope(req.Scope)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scope, err = s.pluginMgr.SetEnabledFor(principal, req.PluginId, scope, true)
+	if err != nil {
+		if errors.Is(err, ErrPluginAccessDenied) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
 		return nil, status.Error(codes.Internal, fmt.Sprintf("启用插件失败: %v", err))
 	}
 
 	return &plugin_service.EnablePluginResponse{
 		Success: true,
 		Message: "插件已启用",
+		Scope:   string(scope),
 	}, nil
 }
 
@@ -224,13 +343,27 @@ func (s *PluginGRPCServer) DisablePlugin(ctx context.Context, req *plugin_servic
 		return nil, status.Error(codes.Internal, "插件管理器未初始化")
 	}
 
-	if err := s.pluginMgr.DisablePlugin(req.PluginId); err != nil {
+	scope, err := ParseOverlayScope(req.Scope)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scope, err = s.pluginMgr.SetEnabledFor(principal, req.PluginId, scope, false)
+	if err != nil {
+		if errors.Is(err, ErrPluginAccessDenied) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
 		return nil, status.Error(codes.Internal, fmt.Sprintf("禁用插件失败: %v", err))
 	}
 
 	return &plugin_service.DisablePluginResponse{
 		Success: true,
 		Message: "插件已禁用",
+		Scope:   string(scope),
 	}, nil
 }
 
@@ -240,6 +373,14 @@ func (s *PluginGRPCServer) DeletePlugin(ctx context.Context, req *plugin_service
 		return nil, status.Error(codes.Internal, "插件管理器未初始化")
 	}
 
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.pluginMgr.AuthorizeManage(principal, req.PluginId); err != nil {
+		return nil, authzError(err)
+	}
+
 	// 卸载插件
 	if err := s.pluginMgr.UnloadPlugin(req.PluginId); err != nil {
 		return nil, status.Error(codes.Internal, fmt.Sprintf("卸载插件失败: %v", err))
@@ -273,10 +414,21 @@ func (s *PluginGRPCServer) Embed(ctx context.Context, req *plugin_service.EmbedR
 		return nil, status.Error(codes.InvalidArgument, "文本不能为空")
 	}
 
-	embedder, err := s.pluginMgr.GetEmbedderPlugin(req.PluginId)
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	callConfig, err := s.pluginMgr.AuthorizeCall(principal, req.PluginId)
+	if err != nil {
+		return nil, authzError(err)
+	}
+	ctx = WithCallConfig(ctx, callConfig)
+
+	embedder, release, err := s.pluginMgr.AcquireEmbedderPlugin(req.PluginId)
 	if err != nil {
 		return nil, status.Error(codes.NotFound, fmt.Sprintf("插件不存在或不支持向量化: %v", err))
 	}
+	defer release()
 
 	embedding, err := embedder.Embed(ctx, req.Text)
 	if err != nil {
@@ -299,10 +451,21 @@ func (s *PluginGRPCServer) EmbedBatch(ctx context.Context, req *plugin_service.E
 		return nil, status.Error(codes.Internal, "插件管理器未初始化")
 	}
 
-	embedder, err := s.pluginMgr.GetEmbedderPlugin(req.PluginId)
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	callConfig, err := s.pluginMgr.AuthorizeCall(principal, req.PluginId)
+	if err != nil {
+		return nil, authzError(err)
+	}
+	ctx = WithCallConfig(ctx, callConfig)
+
+	embedder, release, err := s.pluginMgr.AcquireEmbedderPlugin(req.PluginId)
 	if err != nil {
 		return nil, status.Error(codes.NotFound, fmt.Sprintf("插件不存在或不支持向量化: %v", err))
 	}
+	defer release()
 
 	results := make([]*plugin_service.EmbeddingResult, 0, len(req.Texts))
 	for _, text := range req.Texts {
@@ -334,10 +497,21 @@ func (s *PluginGRPCServer) Rerank(ctx context.Context, req *plugin_service.Reran
 		return nil, status.Error(codes.InvalidArgument, "查询文本不能为空")
 	}
 
-	reranker, err := s.pluginMgr.GetRerankerPlugin(req.PluginId)
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	callConfig, err := s.pluginMgr.AuthorizeCall(principal, req.PluginId)
+	if err != nil {
+		return nil, authzError(err)
+	}
+	ctx = WithCallConfig(ctx, callConfig)
+
+	reranker, release, err := s.pluginMgr.AcquireRerankerPlugin(req.PluginId)
 	if err != nil {
 		return nil, status.Error(codes.NotFound, fmt.Sprintf("插件不存在或不支持重排序: %v", err))
 	}
+	defer release()
 
 	// 转换proto文档到内部格式
 	documents := make([]RerankDocument, 0, len(req.Documents))
@@ -376,3 +550,208 @@ func (s *PluginGRPCServer) Rerank(ctx context.Context, req *plugin_service.Reran
 		Results: protoResults,
 	}, nil
 }
+
+// ListPluginVersions 列出插件的已安装版本
+func (s *PluginGRPCServer) ListPluginVersions(ctx context.Context, req *plugin_service.ListPluginVersionsRequest) (*plugin_service.ListPluginVersionsResponse, error) {
+	if s.pluginMgr == nil {
+		return nil, status.Error(codes.Internal, "插件管理器未初始化")
+	}
+
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.pluginMgr.AuthorizeView(principal, req.PluginId); err != nil {
+		return nil, authzError(err)
+	}
+
+	versions, err := s.pluginMgr.ListPluginVersions(req.PluginId)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("插件不存在: %v", err))
+	}
+
+	infos := make([]*plugin_service.PluginVersionInfo, 0, len(versions))
+	var activeVersion string
+	for _, v := range versions {
+		if v.Active {
+			activeVersion = v.Version
+		}
+		infos = append(infos, &plugin_service.PluginVersionInfo{
+			Version:     v.Version,
+			Filename:    v.Filename,
+			Active:      v.Active,
+			InstalledAt: v.InstalledAt,
+			ActivatedAt: v.ActivatedAt,
+		})
+	}
+
+	return &plugin_service.ListPluginVersionsResponse{
+		Success:       true,
+		Versions:      infos,
+		ActiveVersion: activeVersion,
+	}, nil
+}
+
+// ActivateVersion 切换插件的当前版本
+func (s *PluginGRPCServer) ActivateVersion(ctx context.Context, req *plugin_service.ActivateVersionRequest) (*plugin_service.ActivateVersionResponse, error) {
+	if s.pluginMgr == nil {
+		return nil, status.Error(codes.Internal, "插件管理器未初始化")
+	}
+
+	if req.Version == "" {
+		return nil, status.Error(codes.InvalidArgument, "版本号不能为空")
+	}
+
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.pluginMgr.AuthorizeManage(principal, req.PluginId); err != nil {
+		return nil, authzError(err)
+	}
+
+	previousVersion := s.pluginMgr.ActiveVersion(req.PluginId)
+
+	if err := s.pluginMgr.ActivateVersion(req.PluginId, req.Version); err != nil {
+		if errors.Is(err, ErrPluginVersionNotFound) {
+			return nil, status.Error(codes.NotFound, fmt.Sprintf("插件版本不存在: %v", err))
+		}
+		return nil, status.Error(codes.Internal, fmt.Sprintf("切换版本失败: %v", err))
+	}
+
+	return &plugin_service.ActivateVersionResponse{
+		Success:         true,
+		Message:         "插件版本已切换",
+		Version:         req.Version,
+		PreviousVersion: previousVersion,
+	}, nil
+}
+
+// Rollback 回滚到上一个版本
+func (s *PluginGRPCServer) Rollback(ctx context.Context, req *plugin_service.RollbackRequest) (*plugin_service.RollbackResponse, error) {
+	if s.pluginMgr == nil {
+		return nil, status.Error(codes.Internal, "插件管理器未初始化")
+	}
+
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.pluginMgr.AuthorizeManage(principal, req.PluginId); err != nil {
+		return nil, authzError(err)
+	}
+
+	previousVersion := s.pluginMgr.ActiveVersion(req.PluginId)
+
+	version, err := s.pluginMgr.Rollback(req.PluginId)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, fmt.Sprintf("回滚失败: %v", err))
+	}
+
+	return &plugin_service.RollbackResponse{
+		Success:         true,
+		Message:         "插件已回滚",
+		Version:         version.Version,
+		PreviousVersion: previousVersion,
+	}, nil
+}
+
+// Chat 聊天（非流式）
+func (s *PluginGRPCServer) Chat(ctx context.Context, req *plugin_service.ChatRequest) (*plugin_service.ChatResponse, error) {
+	if s.pluginMgr == nil {
+		return nil, status.Error(codes.Internal, "插件管理器未初始化")
+	}
+
+	if len(req.Messages) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "消息不能为空")
+	}
+
+	chatReq, err := chatRequestFromProto(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	callConfig, err := s.pluginMgr.AuthorizeCall(principal, req.PluginId)
+	if err != nil {
+		return nil, authzError(err)
+	}
+	ctx = WithCallConfig(ctx, callConfig)
+
+	chat, release, err := s.pluginMgr.AcquireChatPlugin(req.PluginId)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("插件不存在或不支持聊天: %v", err))
+	}
+	defer release()
+
+	resp, err := chat.Chat(ctx, chatReq)
+	if err != nil {
+		return &plugin_service.ChatResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return chatResponseToProto(resp), nil
+}
+
+// ChatStream 流式聊天，插件输出的每个分片解析后转发给调用方
+func (s *PluginGRPCServer) ChatStream(req *plugin_service.ChatRequest, stream grpc.ServerStreamingServer[plugin_service.ChatStreamChunk]) error {
+	if s.pluginMgr == nil {
+		return status.Error(codes.Internal, "插件管理器未初始化")
+	}
+
+	if len(req.Messages) == 0 {
+		return status.Error(codes.InvalidArgument, "消息不能为空")
+	}
+
+	chatReq, err := chatRequestFromProto(req)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	chatReq.Stream = true
+
+	ctx := stream.Context()
+	principal, err := principalFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	callConfig, err := s.pluginMgr.AuthorizeCall(principal, req.PluginId)
+	if err != nil {
+		return authzError(err)
+	}
+
+	chat, release, err := s.pluginMgr.AcquireChatPlugin(req.PluginId)
+	if err != nil {
+		return status.Error(codes.NotFound, fmt.Sprintf("插件不存在或不支持聊天: %v", err))
+	}
+	defer release()
+
+	err = chat.ChatStream(WithCallConfig(ctx, callConfig), chatReq, func(data []byte) error {
+		var chunk ChatStreamChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("解析流式分片失败: %w", err)
+		}
+		for _, protoChunk := range chatStreamChunkToProto(&chunk) {
+			if err := stream.Send(protoChunk); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// 调用方取消或超时
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Error(codes.Internal, fmt.Sprintf("流式聊天失败: %v", err))
+	}
+
+	return nil
+}
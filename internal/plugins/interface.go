@@ -2,6 +2,7 @@ package plugins
 
 import (
 	"context"
+	"encoding/json"
 )
 
 // PluginCapability 插件能力类型
@@ -102,34 +103,88 @@ type RerankResult struct {
 type ChatRequest struct {
 	Model       string                 `json:"model"`
 	Messages    []ChatMessage          `json:"messages"`
-	Temperature float64               `json:"temperature,omitempty"`
+	Temperature float64                `json:"temperature,omitempty"`
 	MaxTokens   int                    `json:"max_tokens,omitempty"`
 	TopP        float64                `json:"top_p,omitempty"`
 	TopK        int                    `json:"top_k,omitempty"`
+	Stop        []string               `json:"stop,omitempty"`
 	Stream      bool                   `json:"stream,omitempty"`
+	Tools       []ChatTool             `json:"tools,omitempty"`
+	ToolChoice  string                 `json:"tool_choice,omitempty"`  // auto, none 或指定函数名
 	ExtraParams map[string]interface{} `json:"extra_params,omitempty"` // 插件特定参数
 }
 
 // ChatMessage 聊天消息
 type ChatMessage struct {
-	Role    string `json:"role"`    // user, assistant, system
-	Content string `json:"content"`
+	Role       string     `json:"role"` // user, assistant, system, tool
+	Content    string     `json:"content"`
+	Name       string     `json:"name,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"` // role为tool时对应的调用ID
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // 模型发起的工具调用
+}
+
+// ChatTool 工具定义（兼容OpenAI格式）
+type ChatTool struct {
+	Type     string           `json:"type"` // 目前仅支持 function
+	Function ChatToolFunction `json:"function"`
+}
+
+// ChatToolFunction 函数定义
+type ChatToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"` // JSON Schema
+}
+
+// ToolCall 工具调用
+type ToolCall struct {
+	Index    int              `json:"index,omitempty"` // 流式增量中的序号
+	ID       string           `json:"id,omitempty"`
+	Type     string           `json:"type,omitempty"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction 工具调用的函数及参数
+type ToolCallFunction struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"` // JSON格式的参数
+}
+
+// ChatUsage Token用量
+type ChatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ChatChoice 聊天候选
+type ChatChoice struct {
+	Index        int         `json:"index"`
+	Message      ChatMessage `json:"message"`
+	FinishReason string      `json:"finish_reason"`
 }
 
 // ChatResponse 聊天响应
 type ChatResponse struct {
-	ID      string `json:"id"`
-	Model   string `json:"model"`
-	Choices []struct {
-		Index        int         `json:"index"`
-		Message      ChatMessage `json:"message"`
-		FinishReason string      `json:"finish_reason"`
-	} `json:"choices"`
-	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage"`
+	ID      string       `json:"id"`
+	Model   string       `json:"model"`
+	Choices []ChatChoice `json:"choices"`
+	Usage   ChatUsage    `json:"usage"`
+}
+
+// ChatStreamChoice 流式分片中的增量候选
+type ChatStreamChoice struct {
+	Index        int         `json:"index"`
+	Delta        ChatMessage `json:"delta"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+// ChatStreamChunk 流式聊天分片（ChatStream回调收到的JSON格式，兼容OpenAI）
+type ChatStreamChunk struct {
+	ID      string             `json:"id"`
+	Model   string             `json:"model"`
+	Choices []ChatStreamChoice `json:"choices"`
+	Usage   *ChatUsage         `json:"usage,omitempty"`
 }
 
 // EmbedderPlugin 向量化插件接口
@@ -4,6 +4,7 @@ import (
 	"archive/zip"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -98,7 +99,7 @@ func (l *PluginLoader) LoadPlugin(xpkgPath string) (Plugin, string, error) {
 					runtimeArch = "amd64" // 默认假设是amd64
 				}
 			}
-			return nil, "", fmt.Errorf("插件架构不匹配: %w\n\n"+
+			return nil, "", fmt.Errorf("%w: 插件架构不匹配: %w\n\n"+
 				"错误详情: %s\n"+
 				"当前运行环境: %s/%s\n"+
 				"解决方案:\n"+
@@ -107,10 +108,10 @@ func (l *PluginLoader) LoadPlugin(xpkgPath string) (Plugin, string, error) {
 				"3. 使用以下命令在Docker容器中编译插件:\n"+
 				"   docker run --rm -v $(pwd):/workspace -w /workspace/examples/plugins/dashscope \\\n"+
 				"   golang:1.25-alpine sh -c 'apk add --no-cache gcc musl-dev && \\\n"+
-				"   CGO_ENABLED=1 go build -buildmode=plugin -o plugin.so plugin.go'", err, errMsg, runtimeOS, runtimeArch)
+				"   CGO_ENABLED=1 go build -buildmode=plugin -o plugin.so plugin.go'", ErrPluginIncompatible, err, errMsg, runtimeOS, runtimeArch)
 		}
 		os.RemoveAll(extractDir) // 加载失败时删除
-		return nil, "", fmt.Errorf("failed to open plugin: %w", err)
+		return nil, "", fmt.Errorf("%w: failed to open plugin: %w", ErrPluginIncompatible, err)
 	}
 
 	// 6. 查找插件符号
@@ -208,6 +209,38 @@ func (l *PluginLoader) ExtractXpkg(xpkgPath string) (string, error) {
 	return l.extractXpkg(xpkgPath)
 }
 
+// ReadManifest 直接从xpkg包中读取manifest.json（不解压整个包）
+func (l *PluginLoader) ReadManifest(xpkgPath string) (*PluginMetadata, error) {
+	r, err := zip.OpenReader(xpkgPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open xpkg: %w", err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.Name != "manifest.json" {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open manifest: %w", err)
+		}
+		defer rc.Close()
+
+		var metadata PluginMetadata
+		if err := json.NewDecoder(rc).Decode(&metadata); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		if err := validateMetadata(&metadata); err != nil {
+			return nil, fmt.Errorf("invalid metadata: %w", err)
+		}
+		return &metadata, nil
+	}
+
+	return nil, fmt.Errorf("manifest.json not found in xpkg")
+}
+
 // verifyChecksum 验证文件校验和
 func (l *PluginLoader) verifyChecksum(filePath, expectedChecksum string) error {
 	file, err := os.Open(filePath)
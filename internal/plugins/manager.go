@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
@@ -13,14 +14,31 @@ type PluginManager struct {
 	registry *PluginRegistry
 	loader   *PluginLoader
 	config   *ManagerConfig
+
+	versionsMu sync.Mutex
+	versions   map[string]*versionHistory // plugin_id -> 版本历史
+
+	callStats *callStatsRegistry // 插件调用统计与并发控制
+
+	tenancyMu sync.RWMutex
+	tenancies map[string]*PluginTenancy // plugin_id -> 归属与覆盖设置
+
+	// openPlugin 加载xpkg中的插件实例，默认使用loader，测试中替换
+	openPlugin func(xpkgPath string) (Plugin, string, error)
 }
 
 // ManagerConfig 管理器配置
 type ManagerConfig struct {
-	PluginDir    string // 插件目录
-	TempDir      string // 临时目录
-	AutoDiscover bool   // 自动发现插件
-	AutoLoad     bool   // 自动加载插件
+	PluginDir    string        // 插件目录
+	TempDir      string        // 临时目录
+	AutoDiscover bool          // 自动发现插件
+	AutoLoad     bool          // 自动加载插件
+	DrainTimeout time.Duration // 版本切换时等待进行中调用完成的最长时间
+
+	CallTimeout        time.Duration        // 插件调用的默认超时，0表示不限制
+	MaxConcurrentCalls int                  // 单个插件的默认最大并发调用数，0表示不限制
+	CallLimits         map[string]CallLimit // 按插件覆盖的调用限制
+	CallQueueTimeout   time.Duration        // 没有调用超时与调用方期限时等待并发许可的最长时间，0使用默认10秒
 }
 
 // NewPluginManager 创建插件管理器
@@ -32,6 +50,12 @@ func NewPluginManager(config ManagerConfig) (*PluginManager, error) {
 	if config.TempDir == "" {
 		config.TempDir = "./tmp/plugins"
 	}
+	if config.DrainTimeout == 0 {
+		config.DrainTimeout = 30 * time.Second
+	}
+	if config.CallQueueTimeout == 0 {
+		config.CallQueueTimeout = defaultCallQueueTimeout
+	}
 
 	// 创建目录
 	if err := os.MkdirAll(config.PluginDir, 0755); err != nil {
@@ -42,10 +66,14 @@ func NewPluginManager(config ManagerConfig) (*PluginManager, error) {
 	}
 
 	manager := &PluginManager{
-		registry: NewPluginRegistry(),
-		loader:   NewPluginLoader(config.PluginDir, config.TempDir),
-		config:   &config,
+		registry:  NewPluginRegistry(),
+		loader:    NewPluginLoader(config.PluginDir, config.TempDir),
+		config:    &config,
+		versions:  make(map[string]*versionHistory),
+		callStats: newCallStatsRegistry(),
+		tenancies: make(map[string]*PluginTenancy),
 	}
+	manager.openPlugin = manager.loader.LoadPlugin
 
 	// 自动发现和加载插件
 	if config.AutoDiscover {
@@ -58,6 +86,8 @@ func NewPluginManager(config ManagerConfig) (*PluginManager, error) {
 }
 
 // DiscoverAndLoad 发现并加载所有插件
+// 同一插件的多个版本都会登记到版本历史中，按持久化的版本状态激活上次的当前版本；
+// 没有版本状态时激活版本号最高的版本
 func (m *PluginManager) DiscoverAndLoad() error {
 	pluginFiles, err := m.loader.DiscoverPlugins()
 	if err != nil {
@@ -66,79 +96,35 @@ func (m *PluginManager) DiscoverAndLoad() error {
 
 	log.Printf("[plugin] Found %d plugin(s)", len(pluginFiles))
 
+	latest := make(map[string]string) // plugin_id -> 最高版本
 	for _, xpkgPath := range pluginFiles {
-		if err := m.LoadPlugin(xpkgPath); err != nil {
-			log.Printf("[plugin] Failed to load plugin %s: %v", xpkgPath, err)
+		version, err := m.registerVersion(xpkgPath, filepath.Base(xpkgPath))
+		if err != nil {
+			log.Printf("[plugin] Failed to register plugin %s: %v", xpkgPath, err)
 			continue
 		}
-	}
-
-	return nil
-}
-
-// LoadPlugin 加载单个插件
-func (m *PluginManager) LoadPlugin(xpkgPath string) error {
-	pluginID := filepath.Base(xpkgPath)
-	log.Printf("[plugin] Loading plugin: %s", pluginID)
-
-	// 更新状态
-	entry, _ := m.registry.Get(pluginID)
-	if entry != nil {
-		m.registry.UpdateState(pluginID, StateLoading, nil)
-	}
-
-	// 加载插件（返回插件实例和解压目录）
-	plugin, extractDir, err := m.loader.LoadPlugin(xpkgPath)
-	if err != nil {
-		if entry != nil {
-			m.registry.UpdateState(pluginID, StateError, err)
+		if current, ok := latest[version.PluginID]; !ok || compareVersions(version.Version, current) > 0 {
+			latest[version.PluginID] = version.Version
 		}
-		return fmt.Errorf("failed to load plugin: %w", err)
 	}
 
-	// 注册插件
-	if entry == nil {
-		if err := m.registry.Register(plugin); err != nil {
-			// 注册失败，清理解压目录
-			os.RemoveAll(extractDir)
-			return fmt.Errorf("failed to register plugin: %w", err)
+	for pluginID, version := range latest {
+		m.loadTenancy(pluginID)
+		if err := m.restoreVersions(pluginID, version); err != nil {
+			log.Printf("[plugin] Failed to load plugin %s@%s: %v", pluginID, version, err)
 		}
-		entry, _ = m.registry.Get(pluginID)
-		// 保存解压目录路径
-		entry.ExtractDir = extractDir
-	} else {
-		// 如果已存在条目，更新解压目录路径
-		entry.ExtractDir = extractDir
 	}
 
-	// 更新状态
-	m.registry.UpdateState(pluginID, StateInitializing, nil)
+	return nil
+}
 
-	// 初始化插件
-	config := entry.Config
-	if err := plugin.Initialize(config); err != nil {
-		m.registry.UpdateState(pluginID, StateError, err)
-		// 初始化失败，清理解压目录
-		if extractDir != "" {
-			os.RemoveAll(extractDir)
-		}
-		return fmt.Errorf("failed to initialize plugin: %w", err)
-	}
+// LoadPlugin 加载单个插件（作为新版本安装并激活）
+func (m *PluginManager) LoadPlugin(xpkgPath string) error {
+	log.Printf("[plugin] Loading plugin: %s", filepath.Base(xpkgPath))
 
-	// 启用插件
-	if config.Enabled {
-		if err := plugin.Enable(); err != nil {
-			m.registry.UpdateState(pluginID, StateError, err)
-			return fmt.Errorf("failed to enable plugin: %w", err)
-		}
-		m.registry.UpdateState(pluginID, StateActive, nil)
-	} else {
-		m.registry.UpdateState(pluginID, StateReady, nil)
+	if _, err := m.InstallPlugin(xpkgPath, filepath.Base(xpkgPath)); err != nil {
+		return fmt.Errorf("failed to load plugin: %w", err)
 	}
-
-	entry.LoadedAt = time.Now().Unix()
-	log.Printf("[plugin] Plugin %s loaded successfully", pluginID)
-
 	return nil
 }
 
@@ -152,7 +138,10 @@ func (m *PluginManager) UnloadPlugin(pluginID string) error {
 	// 更新状态
 	m.registry.UpdateState(pluginID, StateUnloading, nil)
 
-	// 清理插件资源
+	// 等待进行中的调用完成后清理插件资源
+	if entry.calls != nil && !entry.calls.drain(m.config.DrainTimeout) {
+		log.Printf("[plugin] Timed out draining plugin %s, unloading anyway", pluginID)
+	}
 	if err := entry.Plugin.Cleanup(); err != nil {
 		log.Printf("[plugin] Failed to cleanup plugin %s: %v", pluginID, err)
 	}
@@ -174,10 +163,27 @@ func (m *PluginManager) UnloadPlugin(pluginID string) error {
 		}
 	}
 
+	// 删除所有已安装版本
+	m.versionsMu.Lock()
+	delete(m.versions, pluginID)
+	m.versionsMu.Unlock()
+	m.removeTenancy(pluginID)
+	if dir, err := m.pluginPath(pluginID); err != nil {
+		log.Printf("[plugin] Refusing to remove plugin versions of %s: %v", pluginID, err)
+	} else if err := os.RemoveAll(dir); err != nil {
+		log.Printf("[plugin] Failed to remove plugin versions of %s: %v", pluginID, err)
+	}
+
 	log.Printf("[plugin] Plugin %s unloaded", pluginID)
 	return nil
 }
 
+// installed 插件是否已安装（在注册表中）
+func (m *PluginManager) installed(pluginID string) bool {
+	_, err := m.registry.Get(pluginID)
+	return err == nil
+}
+
 // GetPlugin 获取插件
 func (m *PluginManager) GetPlugin(pluginID string) (Plugin, error) {
 	entry, err := m.registry.Get(pluginID)
@@ -278,6 +284,7 @@ func (m *PluginManager) EnablePlugin(pluginID string) error {
 	config.Enabled = true
 	m.registry.UpdateConfig(pluginID, config)
 	m.registry.UpdateState(pluginID, StateActive, nil)
+	m.saveVersions(pluginID)
 
 	return nil
 }
@@ -298,6 +305,7 @@ func (m *PluginManager) DisablePlugin(pluginID string) error {
 	config.Enabled = false
 	m.registry.UpdateConfig(pluginID, config)
 	m.registry.UpdateState(pluginID, StateDisabled, nil)
+	m.saveVersions(pluginID)
 
 	return nil
 }
@@ -315,6 +323,7 @@ func (m *PluginManager) ReloadPluginConfig(pluginID string, config PluginConfig)
 	}
 
 	m.registry.UpdateConfig(pluginID, config)
+	m.saveVersions(pluginID)
 	return nil
 }
 
@@ -5,8 +5,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"regexp"
+	"strings"
 )
 
+// pluginPathSegment 插件ID与版本号会用作存储路径和对象键，只允许这些字符
+var pluginPathSegment = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
 // LoadMetadataFromManifest 从manifest.json加载插件元数据
 func LoadMetadataFromManifest(manifestPath string) (*PluginMetadata, error) {
 	file, err := os.Open(manifestPath)
@@ -38,18 +43,32 @@ func validateMetadata(m *PluginMetadata) error {
 	if m.ID == "" {
 		return fmt.Errorf("metadata.id is required")
 	}
+	if err := validatePathSegment("metadata.id", m.ID); err != nil {
+		return err
+	}
 	if m.Name == "" {
 		return fmt.Errorf("metadata.name is required")
 	}
 	if m.Version == "" {
 		return fmt.Errorf("metadata.version is required")
 	}
+	if err := validatePathSegment("metadata.version", m.Version); err != nil {
+		return err
+	}
 	if len(m.Capabilities) == 0 {
 		return fmt.Errorf("metadata.capabilities cannot be empty")
 	}
 	return nil
 }
 
+// validatePathSegment 校验值可以安全地作为单级路径使用
+func validatePathSegment(field, value string) error {
+	if !pluginPathSegment.MatchString(value) || strings.Contains(value, "..") || value == "." {
+		return fmt.Errorf("%w: %s %q", ErrInvalidPluginPath, field, value)
+	}
+	return nil
+}
+
 // HasCapability 检查插件是否支持指定能力
 func (m *PluginMetadata) HasCapability(capType PluginCapabilityType) bool {
 	for _, cap := range m.Capabilities {
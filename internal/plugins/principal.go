@@ -0,0 +1,135 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/aihub/backend-go/internal/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// MetadataAuthorization 携带调用方身份令牌的gRPC元数据键
+const MetadataAuthorization = "authorization"
+
+// principalTokenTTL 转发身份时签发的令牌有效期
+const principalTokenTTL = 5 * time.Minute
+
+// ErrInvalidPrincipal 身份令牌无效
+var ErrInvalidPrincipal = errors.New("invalid principal token")
+
+// PrincipalAuth 签发与校验调用方身份令牌
+// 与主服务共用JWT密钥，插件服务只信任令牌中的用户、组织与角色，不信任请求头或请求体中的身份字段
+type PrincipalAuth struct {
+	jwt *auth.JWTService
+}
+
+// NewPrincipalAuth 创建身份令牌服务，密钥为空时返回nil（调用方均视为匿名）
+func NewPrincipalAuth(secret string) *PrincipalAuth {
+	if secret == "" {
+		return nil
+	}
+	return &PrincipalAuth{jwt: auth.NewJWTService(secret, "aihub-backend", principalTokenTTL)}
+}
+
+// Sign 为调用方身份签发短期令牌
+func (a *PrincipalAuth) Sign(p Principal) (string, error) {
+	return a.jwt.GenerateOrgToken(p.UserID, p.OrgID, "", "", p.Roles)
+}
+
+// Verify 校验令牌并返回其中的调用方身份
+func (a *PrincipalAuth) Verify(token string) (Principal, error) {
+	claims, err := a.jwt.ValidateToken(token)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidPrincipal, err)
+	}
+	return Principal{UserID: claims.UserID, OrgID: claims.OrgID, Roles: claims.Roles}, nil
+}
+
+var defaultPrincipalAuth atomic.Pointer[PrincipalAuth]
+
+// SetPrincipalAuth 设置进程内签发与校验身份令牌使用的服务，在启动时调用
+func SetPrincipalAuth(a *PrincipalAuth) {
+	defaultPrincipalAuth.Store(a)
+}
+
+// VerifyAuthorization 校验Authorization头（Bearer令牌）
+// 未携带时返回匿名身份，携带但无效时返回错误
+func VerifyAuthorization(header string) (Principal, error) {
+	if header == "" {
+		return Principal{}, nil
+	}
+	token, err := auth.ExtractTokenFromHeader(header)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidPrincipal, err)
+	}
+	a := defaultPrincipalAuth.Load()
+	if a == nil {
+		return Principal{}, fmt.Errorf("%w: principal verification is not configured", ErrInvalidPrincipal)
+	}
+	return a.Verify(token)
+}
+
+// principalAuthorization 为身份签发令牌，返回Authorization头的值；匿名或未配置密钥时返回空
+func principalAuthorization(p Principal) (string, error) {
+	a := defaultPrincipalAuth.Load()
+	if a == nil || p.UserID == 0 {
+		return "", nil
+	}
+	token, err := a.Sign(p)
+	if err != nil {
+		return "", fmt.Errorf("签发身份令牌失败: %w", err)
+	}
+	return "Bearer " + token, nil
+}
+
+type principalContextKey struct{}
+
+// ContextWithPrincipal 记录调用方身份，插件客户端调用时以签名令牌转发给插件服务
+func ContextWithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, p)
+}
+
+// PrincipalFromContext 获取ContextWithPrincipal记录的调用方身份
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalContextKey{}).(Principal)
+	return p, ok
+}
+
+// withPrincipalMetadata 将上下文中的调用方身份签名后写入gRPC元数据
+func withPrincipalMetadata(ctx context.Context) (context.Context, error) {
+	p, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return ctx, nil
+	}
+	authorization, err := principalAuthorization(p)
+	if err != nil || authorization == "" {
+		return ctx, err
+	}
+	return metadata.AppendToOutgoingContext(ctx, MetadataAuthorization, authorization), nil
+}
+
+// PrincipalUnaryClientInterceptor 在每次调用时为上下文中的调用方身份签发令牌
+func PrincipalUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, err := withPrincipalMetadata(ctx)
+		if err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// PrincipalStreamClientInterceptor 流式调用的身份转发
+func PrincipalStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, err := withPrincipalMetadata(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
@@ -29,6 +29,8 @@ type PluginEntry struct {
 	LoadedAt    int64       `json:"loaded_at"`
 	LastUsedAt  int64       `json:"last_used_at"`
 	ExtractDir  string      `json:"-"` // 解压目录路径，用于生命周期管理
+
+	calls *callTracker // 进行中的调用计数，用于版本切换时排空
 }
 
 // PluginRegistry 插件注册表
@@ -70,11 +72,40 @@ func (r *PluginRegistry) Register(plugin Plugin) error {
 			PluginID: pluginID,
 			Enabled:  true,
 		},
+		calls: newCallTracker(),
 	}
 
 	// 添加到注册表
 	r.plugins[pluginID] = entry
+	r.indexLocked(pluginID, metadata)
+
+	return nil
+}
+
+// Replace 原子替换插件条目（不存在时直接注册），返回被替换的旧条目
+func (r *PluginRegistry) Replace(entry *PluginEntry) *PluginEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pluginID := entry.Metadata.ID
+	if entry.calls == nil {
+		entry.calls = newCallTracker()
+	}
+
+	old := r.plugins[pluginID]
+	if old != nil {
+		// 新版本的能力声明可能变化，重建索引
+		r.unindexLocked(pluginID, old.Metadata)
+	}
+
+	r.plugins[pluginID] = entry
+	r.indexLocked(pluginID, entry.Metadata)
+
+	return old
+}
 
+// indexLocked 建立能力与提供商索引（调用方需持有写锁）
+func (r *PluginRegistry) indexLocked(pluginID string, metadata PluginMetadata) {
 	// 按能力类型索引
 	for _, cap := range metadata.Capabilities {
 		r.byType[cap.Type] = append(r.byType[cap.Type], pluginID)
@@ -84,22 +115,10 @@ func (r *PluginRegistry) Register(plugin Plugin) error {
 	if metadata.Provider != "" {
 		r.byProvider[metadata.Provider] = append(r.byProvider[metadata.Provider], pluginID)
 	}
-
-	return nil
 }
 
-// Unregister 注销插件
-func (r *PluginRegistry) Unregister(pluginID string) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	entry, exists := r.plugins[pluginID]
-	if !exists {
-		return fmt.Errorf("plugin %s not found", pluginID)
-	}
-
-	// 清理索引
-	metadata := entry.Metadata
+// unindexLocked 清理能力与提供商索引（调用方需持有写锁）
+func (r *PluginRegistry) unindexLocked(pluginID string, metadata PluginMetadata) {
 	for _, cap := range metadata.Capabilities {
 		ids := r.byType[cap.Type]
 		for i, id := range ids {
@@ -119,6 +138,20 @@ func (r *PluginRegistry) Unregister(pluginID string) error {
 			}
 		}
 	}
+}
+
+// Unregister 注销插件
+func (r *PluginRegistry) Unregister(pluginID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, exists := r.plugins[pluginID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrPluginNotFound, pluginID)
+	}
+
+	// 清理索引
+	r.unindexLocked(pluginID, entry.Metadata)
 
 	// 从注册表移除
 	delete(r.plugins, pluginID)
@@ -133,7 +166,7 @@ func (r *PluginRegistry) Get(pluginID string) (*PluginEntry, error) {
 
 	entry, exists := r.plugins[pluginID]
 	if !exists {
-		return nil, fmt.Errorf("plugin %s not found", pluginID)
+		return nil, fmt.Errorf("%w: %s", ErrPluginNotFound, pluginID)
 	}
 
 	return entry, nil
@@ -193,7 +226,7 @@ func (r *PluginRegistry) UpdateState(pluginID string, state PluginState, err err
 
 	entry, exists := r.plugins[pluginID]
 	if !exists {
-		return fmt.Errorf("plugin %s not found", pluginID)
+		return fmt.Errorf("%w: %s", ErrPluginNotFound, pluginID)
 	}
 
 	entry.State = state
@@ -213,7 +246,7 @@ func (r *PluginRegistry) UpdateConfig(pluginID string, config PluginConfig) erro
 
 	entry, exists := r.plugins[pluginID]
 	if !exists {
-		return fmt.Errorf("plugin %s not found", pluginID)
+		return fmt.Errorf("%w: %s", ErrPluginNotFound, pluginID)
 	}
 
 	entry.Config = config
@@ -6,6 +6,7 @@ import (
 	"os"
 	"sync"
 
+	"github.com/aihub/backend-go/internal/dashscope"
 	"github.com/aihub/backend-go/internal/knowledge"
 )
 
@@ -65,15 +66,19 @@ func (a *PluginServiceEmbedderAdapter) Embed(ctx context.Context, text string) (
 
 	// 降级到HTTP
 	if a.httpClient != nil {
-		embedding, _, err := a.httpClient.Embed(a.pluginID, text)
+		embedding, _, err := a.httpClient.forContext(ctx).Embed(a.pluginID, text)
 		return embedding, err
 	}
 
 	return nil, fmt.Errorf("插件服务客户端未初始化")
 }
 
-// EmbedBatch 批量向量化
+// EmbedBatch 批量向量化（gRPC一次请求，HTTP降级为逐条请求）
 func (a *PluginServiceEmbedderAdapter) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if a.useGRPC && a.grpcClient != nil {
+		return a.grpcClient.EmbedBatch(ctx, a.pluginID, texts)
+	}
+
 	results := make([][]float32, 0, len(texts))
 	for _, text := range texts {
 		embedding, err := a.Embed(ctx, text)
@@ -85,6 +90,11 @@ func (a *PluginServiceEmbedderAdapter) EmbedBatch(ctx context.Context, texts []s
 	return results, nil
 }
 
+// MaxBatchSize 单次批量请求的最大文本数
+func (a *PluginServiceEmbedderAdapter) MaxBatchSize() int {
+	return pluginEmbedBatchSize
+}
+
 // Dimensions 获取向量维度
 func (a *PluginServiceEmbedderAdapter) Dimensions() int {
 	return a.dims
@@ -140,7 +150,7 @@ func (a *PluginServiceRerankerAdapter) Rerank(ctx context.Context, query string,
 	if a.useGRPC && a.grpcClient != nil {
 		results, err = a.grpcClient.Rerank(ctx, a.pluginID, query, pluginDocs)
 	} else if a.httpClient != nil {
-		results, err = a.httpClient.Rerank(a.pluginID, query, pluginDocs)
+		results, err = a.httpClient.forContext(ctx).Rerank(a.pluginID, query, pluginDocs)
 	} else {
 		return nil, fmt.Errorf("插件服务客户端未初始化")
 	}
@@ -171,3 +181,114 @@ func (a *PluginServiceRerankerAdapter) Ready() bool {
 	return (a.grpcClient != nil || a.httpClient != nil) && a.pluginID != ""
 }
 
+// PluginServiceChatAdapter 通过插件服务gRPC调用聊天插件
+// 实现与dashscope.Service相同的ChatCompletion接口，可直接替换AIChatService的模型后端
+type PluginServiceChatAdapter struct {
+	grpcClient *PluginGRPCClient
+	pluginID   string
+}
+
+// NewPluginServiceChatAdapter 创建插件服务聊天适配器
+func NewPluginServiceChatAdapter(pluginID string) *PluginServiceChatAdapter {
+	adapter := &PluginServiceChatAdapter{
+		pluginID: pluginID,
+	}
+
+	if grpcClient, err := getGRPCClient(); err == nil {
+		adapter.grpcClient = grpcClient
+	}
+
+	return adapter
+}
+
+// ChatCompletion 调用聊天插件
+func (a *PluginServiceChatAdapter) ChatCompletion(ctx context.Context, req dashscope.ChatRequest) (*dashscope.ChatResponse, error) {
+	if a.grpcClient == nil {
+		return nil, fmt.Errorf("插件服务客户端未初始化")
+	}
+
+	resp, err := a.grpcClient.Chat(ctx, a.pluginID, chatRequestFromDashScope(req))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dashscope.ChatResponse{
+		ID:     resp.ID,
+		Object: "chat.completion",
+		Model:  resp.Model,
+		Usage: dashscope.ChatUsage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+		},
+	}
+	for _, choice := range resp.Choices {
+		result.Choices = append(result.Choices, dashscope.ChatChoice{
+			Index: choice.Index,
+			Message: dashscope.ChatMessage{
+				Role:    choice.Message.Role,
+				Content: choice.Message.Content,
+			},
+			FinishReason: choice.FinishReason,
+		})
+	}
+
+	return result, nil
+}
+
+// ChatCompletionStream 流式调用聊天插件，onDelta接收增量文本，返回最终用量
+func (a *PluginServiceChatAdapter) ChatCompletionStream(ctx context.Context, req dashscope.ChatRequest, onDelta func(content string) error) (*dashscope.ChatUsage, error) {
+	if a.grpcClient == nil {
+		return nil, fmt.Errorf("插件服务客户端未初始化")
+	}
+
+	usage := &dashscope.ChatUsage{}
+	err := a.grpcClient.ChatStream(ctx, a.pluginID, chatRequestFromDashScope(req), func(chunk ChatStreamChunk) error {
+		if chunk.Usage != nil {
+			usage.PromptTokens = chunk.Usage.PromptTokens
+			usage.CompletionTokens = chunk.Usage.CompletionTokens
+			usage.TotalTokens = chunk.Usage.TotalTokens
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			if err := onDelta(choice.Delta.Content); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
+
+// Ready 检查是否就绪
+func (a *PluginServiceChatAdapter) Ready() bool {
+	return a.grpcClient != nil && a.pluginID != ""
+}
+
+// chatRequestFromDashScope 转换DashScope聊天请求到插件格式
+func chatRequestFromDashScope(req dashscope.ChatRequest) ChatRequest {
+	chatReq := ChatRequest{
+		Model:    req.Model,
+		Messages: make([]ChatMessage, 0, len(req.Messages)),
+		Stream:   req.Stream,
+	}
+	for _, msg := range req.Messages {
+		chatReq.Messages = append(chatReq.Messages, ChatMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		})
+	}
+	if req.MaxTokens != nil {
+		chatReq.MaxTokens = *req.MaxTokens
+	}
+	if req.Temperature != nil {
+		chatReq.Temperature = *req.Temperature
+	}
+	return chatReq
+}
@@ -0,0 +1,492 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Visibility 插件可见范围
+type Visibility string
+
+const (
+	VisibilityPrivate Visibility = "private" // 仅所有者可见
+	VisibilityOrg     Visibility = "org"     // 所有者所在组织可见
+	VisibilityGlobal  Visibility = "global"  // 所有用户可见（仅管理员可安装）
+)
+
+// OverlayScope 启用状态与配置的覆盖范围
+type OverlayScope string
+
+const (
+	ScopeUser   OverlayScope = "user"   // 仅对当前用户生效
+	ScopeOrg    OverlayScope = "org"    // 对当前组织生效
+	ScopeGlobal OverlayScope = "global" // 修改插件本身（需管理权限）
+)
+
+const (
+	RoleAdmin    = "admin"     // 平台管理员
+	RoleOrgAdmin = "org_admin" // 组织管理员
+)
+
+var (
+	// ErrPluginAccessDenied 无权访问插件
+	ErrPluginAccessDenied = errors.New("plugin access denied")
+	// ErrPluginDisabled 插件对调用方禁用
+	ErrPluginDisabled = errors.New("plugin disabled")
+)
+
+// Principal 调用方身份
+// UserID为0表示未携带身份的内部调用，只能使用全局插件
+type Principal struct {
+	UserID uint
+	OrgID  uint
+	Roles  []string
+}
+
+// IsAdmin 是否为平台管理员
+func (p Principal) IsAdmin() bool {
+	return p.hasRole(RoleAdmin)
+}
+
+// IsOrgAdmin 是否为所在组织的管理员
+func (p Principal) IsOrgAdmin() bool {
+	return p.OrgID != 0 && (p.hasRole(RoleOrgAdmin) || p.IsAdmin())
+}
+
+func (p Principal) hasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// PluginOverlay 用户或组织对插件的覆盖设置
+type PluginOverlay struct {
+	Enabled  *bool                  `json:"enabled,omitempty"`
+	Settings map[string]interface{} `json:"settings,omitempty"`
+}
+
+// PluginTenancy 插件的归属与覆盖设置
+// 没有归属记录的插件（如自动发现的插件）视为系统安装的全局插件
+type PluginTenancy struct {
+	OwnerID    uint                      `json:"owner_id"`
+	OrgID      uint                      `json:"org_id,omitempty"`
+	Visibility Visibility                `json:"visibility"`
+	Overlays   map[string]*PluginOverlay `json:"overlays,omitempty"` // key: user:<id> 或 org:<id>
+}
+
+func userOverlayKey(userID uint) string { return fmt.Sprintf("user:%d", userID) }
+func orgOverlayKey(orgID uint) string   { return fmt.Sprintf("org:%d", orgID) }
+
+// ParseVisibility 解析可见范围
+func ParseVisibility(value string) (Visibility, error) {
+	switch Visibility(value) {
+	case VisibilityPrivate, VisibilityOrg, VisibilityGlobal:
+		return Visibility(value), nil
+	}
+	return "", fmt.Errorf("invalid visibility %q (expected private, org or global)", value)
+}
+
+// ParseOverlayScope 解析覆盖范围，为空时返回空值由调用方决定默认范围
+func ParseOverlayScope(value string) (OverlayScope, error) {
+	switch OverlayScope(value) {
+	case "", ScopeUser, ScopeOrg, ScopeGlobal:
+		return OverlayScope(value), nil
+	}
+	return "", fmt.Errorf("invalid scope %q (expected user, org or global)", value)
+}
+
+// Tenancy 获取插件的归属信息（副本）
+func (m *PluginManager) Tenancy(pluginID string) PluginTenancy {
+	m.tenancyMu.RLock()
+	defer m.tenancyMu.RUnlock()
+
+	t, exists := m.tenancies[pluginID]
+	if !exists {
+		return PluginTenancy{Visibility: VisibilityGlobal}
+	}
+	return *t
+}
+
+// CanView 调用方是否可以看到并使用插件
+func (m *PluginManager) CanView(p Principal, pluginID string) bool {
+	t := m.Tenancy(pluginID)
+	switch {
+	case p.IsAdmin(), t.Visibility == VisibilityGlobal:
+		return true
+	case p.UserID != 0 && t.OwnerID == p.UserID:
+		return true
+	case t.Visibility == VisibilityOrg:
+		return p.OrgID != 0 && t.OrgID == p.OrgID
+	}
+	return false
+}
+
+// CanManage 调用方是否可以管理插件（升级、回滚、全局启停、删除）
+func (m *PluginManager) CanManage(p Principal, pluginID string) bool {
+	t := m.Tenancy(pluginID)
+	switch {
+	case p.IsAdmin():
+		return true
+	case t.Visibility == VisibilityGlobal:
+		return false
+	case p.UserID != 0 && t.OwnerID == p.UserID:
+		return true
+	case t.Visibility == VisibilityOrg:
+		return p.IsOrgAdmin() && t.OrgID == p.OrgID
+	}
+	return false
+}
+
+// AuthorizeView 检查调用方是否可以查看插件
+func (m *PluginManager) AuthorizeView(p Principal, pluginID string) error {
+	if !m.CanView(p, pluginID) {
+		return fmt.Errorf("%w: user %d cannot access plugin %s", ErrPluginAccessDenied, p.UserID, pluginID)
+	}
+	return nil
+}
+
+// AuthorizeManage 检查调用方是否可以管理插件
+func (m *PluginManager) AuthorizeManage(p Principal, pluginID string) error {
+	if !m.CanManage(p, pluginID) {
+		return fmt.Errorf("%w: user %d cannot manage plugin %s", ErrPluginAccessDenied, p.UserID, pluginID)
+	}
+	return nil
+}
+
+// AuthorizeCall 检查调用方是否可以调用插件，返回叠加覆盖设置后的配置
+func (m *PluginManager) AuthorizeCall(p Principal, pluginID string) (PluginConfig, error) {
+	if _, err := m.registry.Get(pluginID); err != nil {
+		return PluginConfig{}, err
+	}
+	if err := m.AuthorizeView(p, pluginID); err != nil {
+		return PluginConfig{}, err
+	}
+	if !m.IsEnabledFor(p, pluginID) {
+		return PluginConfig{}, fmt.Errorf("%w: plugin %s is disabled for user %d", ErrPluginDisabled, pluginID, p.UserID)
+	}
+	return m.EffectiveConfig(p, pluginID)
+}
+
+// DefaultVisibility 上传时未指定可见范围的默认值：管理员为全局，其他用户为私有
+func DefaultVisibility(p Principal) Visibility {
+	if p.IsAdmin() {
+		return VisibilityGlobal
+	}
+	return VisibilityPrivate
+}
+
+// AuthorizeInstall 检查调用方是否可以安装插件
+// 已安装的插件（升级）需要管理权限，新插件按可见范围检查
+func (m *PluginManager) AuthorizeInstall(p Principal, pluginID string, visibility Visibility) error {
+	if _, err := m.registry.Get(pluginID); err == nil {
+		return m.AuthorizeManage(p, pluginID)
+	}
+
+	switch visibility {
+	case VisibilityGlobal:
+		if !p.IsAdmin() {
+			return fmt.Errorf("%w: only admins can install global plugins", ErrPluginAccessDenied)
+		}
+	case VisibilityOrg:
+		if p.OrgID == 0 {
+			return fmt.Errorf("%w: org visibility requires an organization", ErrPluginAccessDenied)
+		}
+	case VisibilityPrivate:
+		if p.UserID == 0 {
+			return fmt.Errorf("%w: private plugins require a user", ErrPluginAccessDenied)
+		}
+	default:
+		return fmt.Errorf("invalid visibility %q", visibility)
+	}
+	return nil
+}
+
+// InstallPluginAs 以调用方身份安装插件（新插件或已有插件的新版本）
+// 升级已有插件时保留原有归属，visibility仅对新插件生效
+func (m *PluginManager) InstallPluginAs(p Principal, xpkgPath, filename string, visibility Visibility) (*PluginVersion, error) {
+	metadata, err := m.loader.ReadManifest(xpkgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	_, getErr := m.registry.Get(metadata.ID)
+	upgrade := getErr == nil
+	if err := m.AuthorizeInstall(p, metadata.ID, visibility); err != nil {
+		return nil, err
+	}
+
+	version, err := m.InstallPlugin(xpkgPath, filename)
+	if err != nil || upgrade {
+		return version, err
+	}
+
+	t := &PluginTenancy{OwnerID: p.UserID, Visibility: visibility}
+	if visibility == VisibilityOrg {
+		t.OrgID = p.OrgID
+	}
+	m.tenancyMu.Lock()
+	m.tenancies[metadata.ID] = t
+	err = m.saveTenancyLocked(metadata.ID)
+	m.tenancyMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return version, nil
+}
+
+// ListPluginsFor 列出调用方可见的插件
+func (m *PluginManager) ListPluginsFor(p Principal) []*PluginEntry {
+	entries := m.registry.List()
+	visible := make([]*PluginEntry, 0, len(entries))
+	for _, entry := range entries {
+		if m.CanView(p, entry.Metadata.ID) {
+			visible = append(visible, entry)
+		}
+	}
+	return visible
+}
+
+// IsEnabledFor 插件对调用方是否启用
+// 插件本身禁用时对所有人禁用，否则依次看用户覆盖、组织覆盖
+func (m *PluginManager) IsEnabledFor(p Principal, pluginID string) bool {
+	entry, err := m.registry.Get(pluginID)
+	if err != nil || (entry.State != StateActive && entry.State != StateReady) {
+		return false
+	}
+
+	m.tenancyMu.RLock()
+	defer m.tenancyMu.RUnlock()
+
+	t, exists := m.tenancies[pluginID]
+	if !exists {
+		return true
+	}
+	if p.UserID != 0 {
+		if overlay, ok := t.Overlays[userOverlayKey(p.UserID)]; ok && overlay.Enabled != nil {
+			return *overlay.Enabled
+		}
+	}
+	if p.OrgID != 0 {
+		if overlay, ok := t.Overlays[orgOverlayKey(p.OrgID)]; ok && overlay.Enabled != nil {
+			return *overlay.Enabled
+		}
+	}
+	return true
+}
+
+// defaultScope 未指定范围时：可管理插件的调用方修改插件本身，否则只修改自己的覆盖设置
+func (m *PluginManager) defaultScope(p Principal, pluginID string, scope OverlayScope) OverlayScope {
+	if scope != "" {
+		return scope
+	}
+	if m.CanManage(p, pluginID) {
+		return ScopeGlobal
+	}
+	return ScopeUser
+}
+
+// SetEnabledFor 在指定范围内启用或禁用插件，返回实际生效的范围
+func (m *PluginManager) SetEnabledFor(p Principal, pluginID string, scope OverlayScope, enabled bool) (OverlayScope, error) {
+	if _, err := m.registry.Get(pluginID); err != nil {
+		return "", err
+	}
+	scope = m.defaultScope(p, pluginID, scope)
+
+	if scope == ScopeGlobal {
+		if err := m.AuthorizeManage(p, pluginID); err != nil {
+			return "", err
+		}
+		if enabled {
+			return scope, m.EnablePlugin(pluginID)
+		}
+		return scope, m.DisablePlugin(pluginID)
+	}
+
+	err := m.updateOverlay(p, pluginID, scope, func(overlay *PluginOverlay) {
+		overlay.Enabled = &enabled
+	})
+	return scope, err
+}
+
+// UpdateConfigFor 在指定范围内更新插件设置，返回实际生效的范围
+// 全局范围会重新加载插件配置，用户/组织范围只保存覆盖设置，调用时叠加
+func (m *PluginManager) UpdateConfigFor(p Principal, pluginID string, scope OverlayScope, settings map[string]interface{}) (OverlayScope, error) {
+	entry, err := m.registry.Get(pluginID)
+	if err != nil {
+		return "", err
+	}
+	scope = m.defaultScope(p, pluginID, scope)
+
+	if scope == ScopeGlobal {
+		if err := m.AuthorizeManage(p, pluginID); err != nil {
+			return "", err
+		}
+		config := entry.Config
+		config.Settings = mergeSettings(config.Settings, settings)
+		return scope, m.ReloadPluginConfig(pluginID, config)
+	}
+
+	err = m.updateOverlay(p, pluginID, scope, func(overlay *PluginOverlay) {
+		overlay.Settings = mergeSettings(overlay.Settings, settings)
+	})
+	return scope, err
+}
+
+// mergeSettings 合并设置，返回新的map
+func mergeSettings(base, updates map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(updates))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range updates {
+		merged[k] = v
+	}
+	return merged
+}
+
+// updateOverlay 修改用户或组织的覆盖设置并持久化
+func (m *PluginManager) updateOverlay(p Principal, pluginID string, scope OverlayScope, update func(*PluginOverlay)) error {
+	if err := m.AuthorizeView(p, pluginID); err != nil {
+		return err
+	}
+
+	var key string
+	switch scope {
+	case ScopeUser:
+		if p.UserID == 0 {
+			return fmt.Errorf("%w: user scope requires a user", ErrPluginAccessDenied)
+		}
+		key = userOverlayKey(p.UserID)
+	case ScopeOrg:
+		if !p.IsOrgAdmin() {
+			return fmt.Errorf("%w: only org admins can change org settings", ErrPluginAccessDenied)
+		}
+		key = orgOverlayKey(p.OrgID)
+	default:
+		return fmt.Errorf("invalid scope %q", scope)
+	}
+
+	m.tenancyMu.Lock()
+	defer m.tenancyMu.Unlock()
+
+	t, exists := m.tenancies[pluginID]
+	if !exists {
+		t = &PluginTenancy{Visibility: VisibilityGlobal}
+		m.tenancies[pluginID] = t
+	}
+	if t.Overlays == nil {
+		t.Overlays = make(map[string]*PluginOverlay)
+	}
+	overlay, exists := t.Overlays[key]
+	if !exists {
+		overlay = &PluginOverlay{}
+		t.Overlays[key] = overlay
+	}
+	update(overlay)
+
+	return m.saveTenancyLocked(pluginID)
+}
+
+// EffectiveConfig 获取调用方视角的插件配置：插件配置 < 组织覆盖 < 用户覆盖
+func (m *PluginManager) EffectiveConfig(p Principal, pluginID string) (PluginConfig, error) {
+	entry, err := m.registry.Get(pluginID)
+	if err != nil {
+		return PluginConfig{}, err
+	}
+
+	config := entry.Config
+	settings := make(map[string]interface{}, len(config.Settings))
+	for k, v := range config.Settings {
+		settings[k] = v
+	}
+
+	m.tenancyMu.RLock()
+	if t, exists := m.tenancies[pluginID]; exists {
+		var keys []string
+		if p.OrgID != 0 {
+			keys = append(keys, orgOverlayKey(p.OrgID))
+		}
+		if p.UserID != 0 {
+			keys = append(keys, userOverlayKey(p.UserID))
+		}
+		for _, key := range keys {
+			if overlay, ok := t.Overlays[key]; ok {
+				for k, v := range overlay.Settings {
+					settings[k] = v
+				}
+			}
+		}
+	}
+	m.tenancyMu.RUnlock()
+
+	config.Settings = settings
+	config.Enabled = m.IsEnabledFor(p, pluginID)
+	return config, nil
+}
+
+// tenancyPath 插件归属信息的存储路径（与版本文件同目录，删除插件时一并清理）
+func (m *PluginManager) tenancyPath(pluginID string) string {
+	return filepath.Join(m.config.PluginDir, pluginID, "tenancy.json")
+}
+
+// saveTenancyLocked 持久化插件归属信息，调用方需持有tenancyMu
+func (m *PluginManager) saveTenancyLocked(pluginID string) error {
+	data, err := json.MarshalIndent(m.tenancies[pluginID], "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal tenancy: %w", err)
+	}
+	path := m.tenancyPath(pluginID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create tenancy dir: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save tenancy: %w", err)
+	}
+	return nil
+}
+
+// loadTenancy 从磁盘加载插件归属信息
+func (m *PluginManager) loadTenancy(pluginID string) {
+	data, err := os.ReadFile(m.tenancyPath(pluginID))
+	if err != nil {
+		return
+	}
+
+	var t PluginTenancy
+	if err := json.Unmarshal(data, &t); err != nil {
+		return
+	}
+
+	m.tenancyMu.Lock()
+	m.tenancies[pluginID] = &t
+	m.tenancyMu.Unlock()
+}
+
+// removeTenancy 删除插件归属信息
+func (m *PluginManager) removeTenancy(pluginID string) {
+	m.tenancyMu.Lock()
+	delete(m.tenancies, pluginID)
+	m.tenancyMu.Unlock()
+}
+
+type callConfigKey struct{}
+
+// WithCallConfig 将调用方视角的插件配置附加到上下文
+func WithCallConfig(ctx context.Context, config PluginConfig) context.Context {
+	return context.WithValue(ctx, callConfigKey{}, config)
+}
+
+// CallConfigFromContext 获取本次调用的插件配置（叠加了用户/组织覆盖设置）
+// 插件可以据此使用调用方自己的设置（如API Key），而不是安装时的全局设置
+func CallConfigFromContext(ctx context.Context) (PluginConfig, bool) {
+	config, ok := ctx.Value(callConfigKey{}).(PluginConfig)
+	return config, ok
+}
@@ -0,0 +1,195 @@
+package plugins
+
+import (
+	"context"
+	"testing"
+
+	plugin_service "github.com/aihub/backend-go/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newTenancyTestManager 创建已注册插件并设置归属的管理器
+func newTenancyTestManager(t *testing.T, tenancies map[string]*PluginTenancy) *PluginManager {
+	t.Helper()
+
+	manager := &PluginManager{
+		registry:  NewPluginRegistry(),
+		config:    &ManagerConfig{PluginDir: t.TempDir()},
+		tenancies: make(map[string]*PluginTenancy),
+	}
+	for id, tenancy := range tenancies {
+		plugin := &stubPlugin{metadata: PluginMetadata{ID: id}}
+		require.NoError(t, manager.registry.Register(plugin))
+		require.NoError(t, manager.registry.UpdateState(id, StateActive, nil))
+		if tenancy != nil {
+			manager.tenancies[id] = tenancy
+		}
+	}
+	return manager
+}
+
+func TestPluginTenancy_Visibility(t *testing.T) {
+	manager := newTenancyTestManager(t, map[string]*PluginTenancy{
+		"global":  nil,
+		"org":     {OwnerID: 2, OrgID: 10, Visibility: VisibilityOrg},
+		"private": {OwnerID: 2, Visibility: VisibilityPrivate},
+	})
+
+	owner := Principal{UserID: 2, OrgID: 10}
+	colleague := Principal{UserID: 3, OrgID: 10}
+	outsider := Principal{UserID: 4, OrgID: 20}
+	admin := Principal{UserID: 5, Roles: []string{RoleAdmin}}
+
+	assert.True(t, manager.CanView(outsider, "global"))
+	assert.True(t, manager.CanView(colleague, "org"))
+	assert.False(t, manager.CanView(outsider, "org"))
+	assert.True(t, manager.CanView(owner, "private"))
+	assert.False(t, manager.CanView(colleague, "private"))
+	assert.True(t, manager.CanView(admin, "private"))
+
+	assert.Len(t, manager.ListPluginsFor(outsider), 1)
+	assert.Len(t, manager.ListPluginsFor(colleague), 2)
+	assert.Len(t, manager.ListPluginsFor(admin), 3)
+}
+
+func TestPluginTenancy_ManageRules(t *testing.T) {
+	manager := newTenancyTestManager(t, map[string]*PluginTenancy{
+		"global": nil,
+		"org":    {OwnerID: 2, OrgID: 10, Visibility: VisibilityOrg},
+	})
+
+	orgAdmin := Principal{UserID: 3, OrgID: 10, Roles: []string{RoleOrgAdmin}}
+	member := Principal{UserID: 4, OrgID: 10}
+
+	// 全局插件只有管理员可以管理
+	assert.False(t, manager.CanManage(Principal{UserID: 2}, "global"))
+	assert.True(t, manager.CanManage(Principal{UserID: 9, Roles: []string{RoleAdmin}}, "global"))
+
+	assert.True(t, manager.CanManage(Principal{UserID: 2}, "org"))
+	assert.True(t, manager.CanManage(orgAdmin, "org"))
+	assert.ErrorIs(t, manager.AuthorizeManage(member, "org"), ErrPluginAccessDenied)
+}
+
+func TestPluginTenancy_AuthorizeInstall(t *testing.T) {
+	manager := newTenancyTestManager(t, map[string]*PluginTenancy{
+		"mine": {OwnerID: 2, Visibility: VisibilityPrivate},
+	})
+
+	user := Principal{UserID: 3, OrgID: 10}
+	assert.ErrorIs(t, manager.AuthorizeInstall(user, "new", VisibilityGlobal), ErrPluginAccessDenied)
+	assert.NoError(t, manager.AuthorizeInstall(user, "new", VisibilityOrg))
+	assert.NoError(t, manager.AuthorizeInstall(user, "new", VisibilityPrivate))
+	assert.NoError(t, manager.AuthorizeInstall(Principal{UserID: 1, Roles: []string{RoleAdmin}}, "new", VisibilityGlobal))
+
+	// 升级他人的插件需要管理权限
+	assert.ErrorIs(t, manager.AuthorizeInstall(user, "mine", VisibilityPrivate), ErrPluginAccessDenied)
+	assert.NoError(t, manager.AuthorizeInstall(Principal{UserID: 2}, "mine", VisibilityPrivate))
+}
+
+func TestPluginTenancy_Overlays(t *testing.T) {
+	manager := newTenancyTestManager(t, map[string]*PluginTenancy{"global": nil})
+	entry, err := manager.registry.Get("global")
+	require.NoError(t, err)
+	entry.Config.Settings = map[string]interface{}{"api_key": "base", "model": "m1"}
+
+	orgAdmin := Principal{UserID: 3, OrgID: 10, Roles: []string{RoleOrgAdmin}}
+	member := Principal{UserID: 4, OrgID: 10}
+
+	// 普通用户不能修改组织设置，默认只修改自己的覆盖设置
+	_, err = manager.UpdateConfigFor(member, "global", ScopeOrg, map[string]interface{}{"model": "m2"})
+	assert.ErrorIs(t, err, ErrPluginAccessDenied)
+
+	scope, err := manager.UpdateConfigFor(orgAdmin, "global", ScopeOrg, map[string]interface{}{"model": "m2"})
+	require.NoError(t, err)
+	assert.Equal(t, ScopeOrg, scope)
+
+	scope, err = manager.UpdateConfigFor(member, "global", "", map[string]interface{}{"api_key": "mine"})
+	require.NoError(t, err)
+	assert.Equal(t, ScopeUser, scope)
+
+	config, err := manager.EffectiveConfig(member, "global")
+	require.NoError(t, err)
+	assert.Equal(t, "mine", config.Settings["api_key"])
+	assert.Equal(t, "m2", config.Settings["model"])
+
+	// 基础配置不受覆盖设置影响
+	config, err = manager.EffectiveConfig(Principal{UserID: 5}, "global")
+	require.NoError(t, err)
+	assert.Equal(t, "base", config.Settings["api_key"])
+	assert.Equal(t, "m1", config.Settings["model"])
+
+	// 用户禁用只影响自己
+	_, err = manager.SetEnabledFor(member, "global", ScopeUser, false)
+	require.NoError(t, err)
+	assert.False(t, manager.IsEnabledFor(member, "global"))
+	assert.True(t, manager.IsEnabledFor(orgAdmin, "global"))
+
+	_, err = manager.AuthorizeCall(member, "global")
+	assert.ErrorIs(t, err, ErrPluginDisabled)
+
+	// 覆盖设置持久化后可重新加载
+	manager.removeTenancy("global")
+	manager.loadTenancy("global")
+	assert.False(t, manager.IsEnabledFor(member, "global"))
+}
+
+func TestPluginGRPC_TenancyAuthorization(t *testing.T) {
+	plugin := newStubEmbedder("private", func(ctx context.Context, text string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	})
+	client, manager := newTestGRPCClient(t, ManagerConfig{PluginDir: t.TempDir()}, plugin)
+	manager.tenancies["private"] = &PluginTenancy{OwnerID: 2, Visibility: VisibilityPrivate}
+	usePrincipalAuth(t, "test-secret")
+
+	_, _, err := client.Embed(ContextWithPrincipal(context.Background(), Principal{UserID: 2}), "private", "hello")
+	require.NoError(t, err)
+
+	ctx := ContextWithPrincipal(context.Background(), Principal{UserID: 3})
+	_, _, err = client.Embed(ctx, "private", "hello")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.client.DeletePlugin(ctx, &plugin_service.DeletePluginRequest{PluginId: "private"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	resp, err := client.client.ListPlugins(ctx, &plugin_service.ListPluginsRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Plugins)
+}
+
+func usePrincipalAuth(t *testing.T, secret string) {
+	t.Helper()
+	SetPrincipalAuth(NewPrincipalAuth(secret))
+	t.Cleanup(func() { SetPrincipalAuth(nil) })
+}
+
+func TestPluginGRPC_PrincipalRequiresSignedToken(t *testing.T) {
+	plugin := newStubEmbedder("private", func(ctx context.Context, text string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	})
+	client, manager := newTestGRPCClient(t, ManagerConfig{PluginDir: t.TempDir()}, plugin)
+	manager.tenancies["private"] = &PluginTenancy{OwnerID: 2, Visibility: VisibilityPrivate}
+	usePrincipalAuth(t, "test-secret")
+
+	// 请求体中的user_id与自行填写的身份元数据不被信任
+	spoofed := metadata.AppendToOutgoingContext(context.Background(), "x-user-id", "2", "x-user-roles", RoleAdmin)
+	_, err := client.client.Embed(spoofed, &plugin_service.EmbedRequest{PluginId: "private", Text: "hello", UserId: 2})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// 其他密钥签发的令牌被拒绝
+	forged, err := NewPrincipalAuth("other-secret").Sign(Principal{UserID: 2, Roles: []string{RoleAdmin}})
+	require.NoError(t, err)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), MetadataAuthorization, "Bearer "+forged)
+	_, err = client.client.Embed(ctx, &plugin_service.EmbedRequest{PluginId: "private", Text: "hello"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// 组织与角色来自令牌
+	token, err := NewPrincipalAuth("test-secret").Sign(Principal{UserID: 5, OrgID: 7, Roles: []string{RoleAdmin}})
+	require.NoError(t, err)
+	principal, err := VerifyAuthorization("Bearer " + token)
+	require.NoError(t, err)
+	assert.Equal(t, Principal{UserID: 5, OrgID: 7, Roles: []string{RoleAdmin}}, principal)
+}
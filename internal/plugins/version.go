@@ -0,0 +1,693 @@
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrPluginNotFound 插件未安装
+	ErrPluginNotFound = errors.New("plugin not found")
+	// ErrPluginVersionNotFound 插件的指定版本未安装
+	ErrPluginVersionNotFound = errors.New("plugin version not found")
+	// ErrPluginVersionExists 插件的同一版本已安装
+	ErrPluginVersionExists = errors.New("plugin version already installed")
+	// ErrNoPreviousVersion 没有可以回滚到的版本
+	ErrNoPreviousVersion = errors.New("no previous plugin version")
+	// ErrPluginIncompatible 插件二进制无法在当前运行环境加载
+	ErrPluginIncompatible = errors.New("plugin incompatible with runtime")
+	// ErrInvalidPluginPath 插件ID或版本号不能安全地用作存储路径
+	ErrInvalidPluginPath = errors.New("invalid plugin id or version")
+)
+
+// PluginVersion 插件的一个已安装版本
+type PluginVersion struct {
+	PluginID    string         `json:"plugin_id"`
+	Version     string         `json:"version"`
+	Filename    string         `json:"filename"` // 上传时的原始文件名
+	Metadata    PluginMetadata `json:"metadata"`
+	Config      PluginConfig   `json:"-"` // 该版本最近一次停用时的配置快照
+	InstalledAt int64          `json:"installed_at"`
+	ActivatedAt int64          `json:"activated_at"`
+	Active      bool           `json:"active"`
+
+	xpkgPath  string // 本地存储的xpkg路径
+	hasConfig bool   // 是否已有配置快照（回滚时恢复）
+}
+
+// versionHistory 单个插件的版本历史
+type versionHistory struct {
+	mu          sync.Mutex // 串行化同一插件的版本切换
+	versions    map[string]*PluginVersion
+	activations []string // 激活顺序（栈顶为当前版本），用于回滚
+}
+
+// versionState 持久化的版本状态，重启后恢复当前版本、激活顺序与各版本的配置快照
+type versionState struct {
+	Active      string                   `json:"active"`
+	Activations []string                 `json:"activations"`
+	Versions    map[string]versionRecord `json:"versions"`
+}
+
+// versionRecord 单个版本的持久化信息，当前版本的Config为其运行中的配置
+type versionRecord struct {
+	Filename    string        `json:"filename"`
+	InstalledAt int64         `json:"installed_at"`
+	ActivatedAt int64         `json:"activated_at"`
+	Config      *PluginConfig `json:"config,omitempty"`
+}
+
+func newVersionHistory() *versionHistory {
+	return &versionHistory{
+		versions: make(map[string]*PluginVersion),
+	}
+}
+
+// current 当前激活的版本
+func (h *versionHistory) current() *PluginVersion {
+	for _, v := range h.versions {
+		if v.Active {
+			return v
+		}
+	}
+	return nil
+}
+
+// callTracker 统计插件条目上进行中的调用，版本切换时用于排空旧版本
+type callTracker struct {
+	mu       sync.Mutex
+	inflight int
+	draining bool
+	idle     chan struct{}
+}
+
+func newCallTracker() *callTracker {
+	return &callTracker{idle: make(chan struct{})}
+}
+
+// acquire 登记一次调用，条目已进入排空状态时返回false
+func (t *callTracker) acquire() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.draining {
+		return false
+	}
+	t.inflight++
+	return true
+}
+
+// release 结束一次调用
+func (t *callTracker) release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inflight--
+	if t.inflight == 0 && t.draining {
+		close(t.idle)
+	}
+}
+
+// drain 拒绝新调用并等待进行中的调用完成，超时返回false
+func (t *callTracker) drain(timeout time.Duration) bool {
+	t.mu.Lock()
+	if !t.draining {
+		t.draining = true
+		if t.inflight == 0 {
+			close(t.idle)
+		}
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-t.idle:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// InstallPlugin 安装插件包：保存为新版本并原子切换为当前版本
+// 同一插件ID的旧版本会保留在存储中，可通过ActivateVersion/Rollback切回
+func (m *PluginManager) InstallPlugin(xpkgPath, filename string) (*PluginVersion, error) {
+	version, err := m.registerVersion(xpkgPath, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.ActivateVersion(version.PluginID, version.Version); err != nil {
+		// 激活失败，撤销本次安装，当前版本保持不变
+		m.removeVersion(version.PluginID, version.Version)
+		return nil, err
+	}
+
+	return version, nil
+}
+
+// restoreVersions 按持久化的版本状态恢复插件：恢复激活顺序与配置快照，激活上次的当前版本
+// 没有状态文件或上次的版本已不存在时激活fallback（版本号最高的版本）
+func (m *PluginManager) restoreVersions(pluginID, fallback string) error {
+	hist := m.history(pluginID)
+	hist.mu.Lock()
+	defer hist.mu.Unlock()
+
+	active := fallback
+	if state, err := m.loadVersionState(pluginID); err != nil {
+		log.Printf("[plugin] Failed to load versions of %s, activating %s: %v", pluginID, fallback, err)
+	} else if state != nil {
+		for version, record := range state.Versions {
+			v, exists := hist.versions[version]
+			if !exists {
+				continue
+			}
+			v.Filename = record.Filename
+			v.InstalledAt = record.InstalledAt
+			v.ActivatedAt = record.ActivatedAt
+			if record.Config != nil {
+				v.Config = *record.Config
+				v.hasConfig = true
+			}
+		}
+		for _, version := range state.Activations {
+			if _, exists := hist.versions[version]; exists {
+				hist.activations = append(hist.activations, version)
+			}
+		}
+		if _, exists := hist.versions[state.Active]; exists {
+			active = state.Active
+		}
+	}
+
+	v, exists := hist.versions[active]
+	if !exists {
+		return fmt.Errorf("%w: %s@%s", ErrPluginVersionNotFound, pluginID, active)
+	}
+	if err := m.activateLocked(hist, v); err != nil {
+		return err
+	}
+	if n := len(hist.activations); n == 0 || hist.activations[n-1] != active {
+		hist.activations = append(hist.activations, active)
+	}
+	m.saveVersionsLocked(pluginID, hist)
+	return nil
+}
+
+// versionsPath 版本状态的存储路径，与tenancy.json同目录
+func (m *PluginManager) versionsPath(pluginID string) (string, error) {
+	dir, err := m.pluginPath(pluginID)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "versions.json"), nil
+}
+
+// loadVersionState 读取版本状态，文件不存在时返回nil
+func (m *PluginManager) loadVersionState(pluginID string) (*versionState, error) {
+	path, err := m.versionsPath(pluginID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state versionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// saveVersions 持久化插件的版本状态
+func (m *PluginManager) saveVersions(pluginID string) {
+	hist := m.history(pluginID)
+	hist.mu.Lock()
+	defer hist.mu.Unlock()
+	m.saveVersionsLocked(pluginID, hist)
+}
+
+// saveVersionsLocked 持久化版本状态，调用方需持有hist.mu；版本切换已生效，写入失败只记录日志
+func (m *PluginManager) saveVersionsLocked(pluginID string, hist *versionHistory) {
+	state := versionState{
+		Activations: hist.activations,
+		Versions:    make(map[string]versionRecord, len(hist.versions)),
+	}
+	for version, v := range hist.versions {
+		record := versionRecord{Filename: v.Filename, InstalledAt: v.InstalledAt, ActivatedAt: v.ActivatedAt}
+		if v.hasConfig {
+			config := v.Config
+			record.Config = &config
+		}
+		if v.Active {
+			state.Active = version
+			if entry, err := m.registry.Get(pluginID); err == nil {
+				config := entry.Config
+				record.Config = &config
+			}
+		}
+		state.Versions[version] = record
+	}
+
+	path, err := m.versionsPath(pluginID)
+	if err == nil {
+		var data []byte
+		if data, err = json.MarshalIndent(state, "", "  "); err == nil {
+			err = os.WriteFile(path, data, 0644)
+		}
+	}
+	if err != nil {
+		log.Printf("[plugin] Failed to save versions of %s: %v", pluginID, err)
+	}
+}
+
+// registerVersion 将xpkg保存到版本存储目录并记录版本信息（不激活）
+func (m *PluginManager) registerVersion(xpkgPath, filename string) (*PluginVersion, error) {
+	metadata, err := m.loader.ReadManifest(xpkgPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if filename == "" {
+		filename = filepath.Base(xpkgPath)
+	}
+
+	hist := m.history(metadata.ID)
+	hist.mu.Lock()
+	defer hist.mu.Unlock()
+
+	if _, exists := hist.versions[metadata.Version]; exists {
+		return nil, fmt.Errorf("%w: %s@%s", ErrPluginVersionExists, metadata.ID, metadata.Version)
+	}
+
+	storedPath, err := m.versionPath(metadata.ID, metadata.Version)
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Clean(xpkgPath) != filepath.Clean(storedPath) {
+		if err := copyFile(xpkgPath, storedPath); err != nil {
+			return nil, fmt.Errorf("failed to store plugin version: %w", err)
+		}
+	}
+
+	version := &PluginVersion{
+		PluginID:    metadata.ID,
+		Version:     metadata.Version,
+		Filename:    filename,
+		Metadata:    *metadata,
+		InstalledAt: time.Now().Unix(),
+		xpkgPath:    storedPath,
+	}
+	hist.versions[metadata.Version] = version
+
+	log.Printf("[plugin] Plugin %s version %s installed", metadata.ID, metadata.Version)
+	return version, nil
+}
+
+// removeVersion 删除一个未激活的版本及其存储文件
+func (m *PluginManager) removeVersion(pluginID, version string) {
+	hist := m.history(pluginID)
+	hist.mu.Lock()
+	defer hist.mu.Unlock()
+
+	v, exists := hist.versions[version]
+	if !exists || v.Active {
+		return
+	}
+	delete(hist.versions, version)
+	dir := filepath.Dir(v.xpkgPath)
+	if !m.withinPluginDir(dir) {
+		log.Printf("[plugin] Refusing to remove %s outside plugin dir", dir)
+		return
+	}
+	os.RemoveAll(dir)
+}
+
+// ActivateVersion 将指定版本切换为当前版本
+func (m *PluginManager) ActivateVersion(pluginID, version string) error {
+	hist := m.history(pluginID)
+	hist.mu.Lock()
+	defer hist.mu.Unlock()
+
+	v, exists := hist.versions[version]
+	if !exists {
+		return fmt.Errorf("%w: %s@%s", ErrPluginVersionNotFound, pluginID, version)
+	}
+	if v.Active {
+		return nil
+	}
+
+	if err := m.activateLocked(hist, v); err != nil {
+		return err
+	}
+	hist.activations = append(hist.activations, version)
+	m.saveVersionsLocked(pluginID, hist)
+	return nil
+}
+
+// Rollback 回滚到上一个激活过的版本，并恢复该版本当时的配置
+func (m *PluginManager) Rollback(pluginID string) (*PluginVersion, error) {
+	hist := m.history(pluginID)
+	hist.mu.Lock()
+	defer hist.mu.Unlock()
+
+	current := hist.current()
+	if current == nil {
+		return nil, fmt.Errorf("%w: plugin %s has no active version", ErrPluginNotFound, pluginID)
+	}
+
+	// 从激活栈中找到上一个仍然存在的不同版本
+	stack := hist.activations
+	for len(stack) > 0 && stack[len(stack)-1] == current.Version {
+		stack = stack[:len(stack)-1]
+	}
+	for len(stack) > 0 {
+		previous, exists := hist.versions[stack[len(stack)-1]]
+		if exists && previous.Version != current.Version {
+			if err := m.activateLocked(hist, previous); err != nil {
+				return nil, err
+			}
+			hist.activations = stack
+			m.saveVersionsLocked(pluginID, hist)
+			log.Printf("[plugin] Plugin %s rolled back from %s to %s", pluginID, current.Version, previous.Version)
+			return previous, nil
+		}
+		stack = stack[:len(stack)-1]
+	}
+
+	return nil, fmt.Errorf("%w: plugin %s has no previous version to roll back to", ErrNoPreviousVersion, pluginID)
+}
+
+// ListPluginVersions 列出插件的所有已安装版本（按版本号降序）
+func (m *PluginManager) ListPluginVersions(pluginID string) ([]*PluginVersion, error) {
+	m.versionsMu.Lock()
+	hist, exists := m.versions[pluginID]
+	m.versionsMu.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrPluginNotFound, pluginID)
+	}
+
+	hist.mu.Lock()
+	defer hist.mu.Unlock()
+
+	versions := make([]*PluginVersion, 0, len(hist.versions))
+	for _, v := range hist.versions {
+		copied := *v
+		versions = append(versions, &copied)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i].Version, versions[j].Version) > 0
+	})
+
+	return versions, nil
+}
+
+// ActiveVersion 当前激活的版本号（插件未安装时为空）
+func (m *PluginManager) ActiveVersion(pluginID string) string {
+	entry, err := m.registry.Get(pluginID)
+	if err != nil {
+		return ""
+	}
+	return entry.Metadata.Version
+}
+
+// activateLocked 加载并初始化目标版本，原子替换注册表条目后排空并清理旧版本
+// 调用方需持有hist.mu
+func (m *PluginManager) activateLocked(hist *versionHistory, v *PluginVersion) error {
+	plugin, extractDir, err := m.openPlugin(v.xpkgPath)
+	if err != nil {
+		return fmt.Errorf("failed to load plugin: %w", err)
+	}
+	if plugin.Metadata().ID != v.PluginID {
+		os.RemoveAll(extractDir)
+		return fmt.Errorf("plugin ID mismatch: expected=%s, actual=%s", v.PluginID, plugin.Metadata().ID)
+	}
+
+	// 配置优先使用该版本自己的快照（回滚），否则沿用当前版本的配置（升级）
+	config := PluginConfig{PluginID: v.PluginID, Enabled: true}
+	current, _ := m.registry.Get(v.PluginID)
+	if v.hasConfig {
+		config = v.Config
+	} else if current != nil {
+		config = current.Config
+	}
+
+	if err := plugin.Initialize(config); err != nil {
+		os.RemoveAll(extractDir)
+		return fmt.Errorf("failed to initialize plugin: %w", err)
+	}
+
+	state := StateReady
+	if config.Enabled {
+		if err := plugin.Enable(); err != nil {
+			plugin.Cleanup()
+			os.RemoveAll(extractDir)
+			return fmt.Errorf("failed to enable plugin: %w", err)
+		}
+		state = StateActive
+	}
+
+	entry := &PluginEntry{
+		Plugin:     plugin,
+		Metadata:   plugin.Metadata(),
+		State:      state,
+		Config:     config,
+		LoadedAt:   time.Now().Unix(),
+		ExtractDir: extractDir,
+		calls:      newCallTracker(),
+	}
+
+	old := m.registry.Replace(entry)
+
+	if previous := hist.current(); previous != nil {
+		previous.Active = false
+		if old != nil {
+			previous.Config = old.Config
+			previous.hasConfig = true
+		}
+	}
+	v.Active = true
+	v.ActivatedAt = entry.LoadedAt
+
+	if old != nil {
+		m.retireEntry(old)
+	}
+
+	log.Printf("[plugin] Plugin %s version %s activated", v.PluginID, v.Version)
+	return nil
+}
+
+// retireEntry 等待旧版本上的调用完成后清理其资源
+func (m *PluginManager) retireEntry(entry *PluginEntry) {
+	if entry.calls != nil && !entry.calls.drain(m.config.DrainTimeout) {
+		log.Printf("[plugin] Timed out draining plugin %s version %s, cleaning up anyway",
+			entry.Metadata.ID, entry.Metadata.Version)
+	}
+
+	if err := entry.Plugin.Cleanup(); err != nil {
+		log.Printf("[plugin] Failed to cleanup plugin %s version %s: %v", entry.Metadata.ID, entry.Metadata.Version, err)
+	}
+	if entry.ExtractDir != "" {
+		if err := os.RemoveAll(entry.ExtractDir); err != nil {
+			log.Printf("[plugin] Failed to remove extract dir %s: %v", entry.ExtractDir, err)
+		}
+	}
+}
+
+// acquire 获取可用的插件条目并登记一次调用
+func (m *PluginManager) acquire(pluginID string) (*PluginEntry, error) {
+	// 条目可能恰好在获取后被替换并进入排空，此时重新获取新条目
+	for attempt := 0; attempt < 3; attempt++ {
+		entry, err := m.registry.Get(pluginID)
+		if err != nil {
+			return nil, err
+		}
+		if entry.State != StateActive && entry.State != StateReady {
+			return nil, fmt.Errorf("plugin %s is not ready (state: %s)", pluginID, entry.State)
+		}
+		if entry.calls == nil || entry.calls.acquire() {
+			return entry, nil
+		}
+	}
+	return nil, fmt.Errorf("plugin %s is switching versions, please retry", pluginID)
+}
+
+// AcquireEmbedderPlugin 获取向量化插件并登记调用，调用结束后必须执行release
+// 版本切换会等待已登记的调用完成后再清理旧版本
+func (m *PluginManager) AcquireEmbedderPlugin(pluginID string) (EmbedderPlugin, func(), error) {
+	entry, err := m.acquire(pluginID)
+	if err != nil {
+		return nil, nil, err
+	}
+	release := entry.release
+
+	embedder, ok := entry.Plugin.(EmbedderPlugin)
+	if !ok {
+		release()
+		return nil, nil, fmt.Errorf("plugin %s does not implement EmbedderPlugin", pluginID)
+	}
+	return embedder, release, nil
+}
+
+// AcquireRerankerPlugin 获取重排序插件并登记调用，调用结束后必须执行release
+func (m *PluginManager) AcquireRerankerPlugin(pluginID string) (RerankerPlugin, func(), error) {
+	entry, err := m.acquire(pluginID)
+	if err != nil {
+		return nil, nil, err
+	}
+	release := entry.release
+
+	reranker, ok := entry.Plugin.(RerankerPlugin)
+	if !ok {
+		release()
+		return nil, nil, fmt.Errorf("plugin %s does not implement RerankerPlugin", pluginID)
+	}
+	return reranker, release, nil
+}
+
+// AcquireChatPlugin 获取聊天插件并登记调用，调用结束后必须执行release
+func (m *PluginManager) AcquireChatPlugin(pluginID string) (ChatPlugin, func(), error) {
+	entry, err := m.acquire(pluginID)
+	if err != nil {
+		return nil, nil, err
+	}
+	release := entry.release
+
+	chat, ok := entry.Plugin.(ChatPlugin)
+	if !ok {
+		release()
+		return nil, nil, fmt.Errorf("plugin %s does not implement ChatPlugin", pluginID)
+	}
+	return chat, release, nil
+}
+
+// release 结束一次登记的调用
+func (e *PluginEntry) release() {
+	if e.calls != nil {
+		e.calls.release()
+	}
+}
+
+// history 获取（或创建）插件的版本历史
+func (m *PluginManager) history(pluginID string) *versionHistory {
+	m.versionsMu.Lock()
+	defer m.versionsMu.Unlock()
+
+	hist, exists := m.versions[pluginID]
+	if !exists {
+		hist = newVersionHistory()
+		m.versions[pluginID] = hist
+	}
+	return hist
+}
+
+// versionPath 版本化的本地存储路径：<PluginDir>/<id>/<version>/<id>@<version>.xpkg
+// 文件名包含版本号，保证不同版本解压到不同目录；ID与版本号来自上传的manifest，必须校验后才能拼接路径
+func (m *PluginManager) versionPath(pluginID, version string) (string, error) {
+	if err := validatePathSegment("plugin id", pluginID); err != nil {
+		return "", err
+	}
+	if err := validatePathSegment("version", version); err != nil {
+		return "", err
+	}
+	path := filepath.Join(m.config.PluginDir, pluginID, version, fmt.Sprintf("%s@%s.xpkg", pluginID, version))
+	if !m.withinPluginDir(path) {
+		return "", fmt.Errorf("%w: %s@%s", ErrInvalidPluginPath, pluginID, version)
+	}
+	return path, nil
+}
+
+// pluginPath 插件所有版本的存储目录<PluginDir>/<id>
+func (m *PluginManager) pluginPath(pluginID string) (string, error) {
+	if err := validatePathSegment("plugin id", pluginID); err != nil {
+		return "", err
+	}
+	path := filepath.Join(m.config.PluginDir, pluginID)
+	if !m.withinPluginDir(path) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidPluginPath, pluginID)
+	}
+	return path, nil
+}
+
+// withinPluginDir 清理后的路径是否位于插件目录之下（不含插件目录本身）
+func (m *PluginManager) withinPluginDir(path string) bool {
+	base, err := filepath.Abs(m.config.PluginDir)
+	if err != nil {
+		return false
+	}
+	target, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(base, target)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	return !filepath.IsAbs(rel)
+}
+
+// copyFile 复制文件
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// compareVersions 比较两个版本号（semver风格，逐段数字比较），返回-1/0/1
+func compareVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var sa, sb string
+		if i < len(pa) {
+			sa = pa[i]
+		}
+		if i < len(pb) {
+			sb = pb[i]
+		}
+
+		na, errA := strconv.Atoi(sa)
+		nb, errB := strconv.Atoi(sb)
+		if errA == nil && errB == nil {
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+			continue
+		}
+		if c := strings.Compare(sa, sb); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
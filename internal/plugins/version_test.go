@@ -0,0 +1,233 @@
+package plugins
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareVersions(t *testing.T) {
+	assert.Equal(t, 0, compareVersions("1.0.0", "1.0.0"))
+	assert.Equal(t, 1, compareVersions("1.10.0", "1.9.3"))
+	assert.Equal(t, -1, compareVersions("v1.2.0", "1.2.1"))
+	assert.Equal(t, 1, compareVersions("2.0", "1.9.9"))
+}
+
+func TestCallTracker_DrainWaitsForInflightCalls(t *testing.T) {
+	tracker := newCallTracker()
+	require.True(t, tracker.acquire())
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		tracker.release()
+	}()
+
+	assert.True(t, tracker.drain(time.Second))
+	// 排空后不再接受新调用
+	assert.False(t, tracker.acquire())
+}
+
+func TestCallTracker_DrainTimeout(t *testing.T) {
+	tracker := newCallTracker()
+	require.True(t, tracker.acquire())
+
+	assert.False(t, tracker.drain(10*time.Millisecond))
+	tracker.release()
+}
+
+type stubPlugin struct {
+	metadata PluginMetadata
+}
+
+func (p *stubPlugin) Metadata() PluginMetadata                 { return p.metadata }
+func (p *stubPlugin) Initialize(config PluginConfig) error     { return nil }
+func (p *stubPlugin) ValidateConfig(config PluginConfig) error { return nil }
+func (p *stubPlugin) Ready() bool                              { return true }
+func (p *stubPlugin) Enable() error                            { return nil }
+func (p *stubPlugin) Disable() error                           { return nil }
+func (p *stubPlugin) ReloadConfig(config PluginConfig) error   { return nil }
+func (p *stubPlugin) Cleanup() error                           { return nil }
+
+func TestPluginRegistry_ReplaceReindexesCapabilities(t *testing.T) {
+	registry := NewPluginRegistry()
+
+	v1 := &stubPlugin{metadata: PluginMetadata{
+		ID:           "demo",
+		Version:      "1.0.0",
+		Capabilities: []PluginCapability{{Type: CapabilityEmbedding}},
+	}}
+	require.NoError(t, registry.Register(v1))
+
+	v2 := &stubPlugin{metadata: PluginMetadata{
+		ID:           "demo",
+		Version:      "2.0.0",
+		Capabilities: []PluginCapability{{Type: CapabilityRerank}},
+	}}
+	old := registry.Replace(&PluginEntry{Plugin: v2, Metadata: v2.Metadata(), State: StateActive})
+
+	require.NotNil(t, old)
+	assert.Equal(t, "1.0.0", old.Metadata.Version)
+	assert.Empty(t, registry.GetByType(CapabilityEmbedding))
+	require.Len(t, registry.GetByType(CapabilityRerank), 1)
+
+	entry, err := registry.Get("demo")
+	require.NoError(t, err)
+	assert.Equal(t, "2.0.0", entry.Metadata.Version)
+}
+
+// writeTestXpkg 生成只含manifest.json的插件包
+func writeTestXpkg(t *testing.T, dir, id, version string) string {
+	t.Helper()
+	path := filepath.Join(dir, fmt.Sprintf("upload-%d.xpkg", time.Now().UnixNano()))
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	zw := zip.NewWriter(file)
+	w, err := zw.Create("manifest.json")
+	require.NoError(t, err)
+	require.NoError(t, json.NewEncoder(w).Encode(PluginMetadata{
+		ID:           id,
+		Name:         id,
+		Version:      version,
+		Capabilities: []PluginCapability{{Type: CapabilityEmbedding}},
+	}))
+	require.NoError(t, zw.Close())
+	require.NoError(t, file.Close())
+	return path
+}
+
+// newVersionTestManager 创建用stubPlugin代替.so加载的管理器，failLoad中的版本加载失败
+func newVersionTestManager(t *testing.T, failLoad map[string]bool) *PluginManager {
+	t.Helper()
+	return newVersionTestManagerIn(t, filepath.Join(t.TempDir(), "plugins"), failLoad)
+}
+
+func newVersionTestManagerIn(t *testing.T, pluginDir string, failLoad map[string]bool) *PluginManager {
+	t.Helper()
+	manager, err := NewPluginManager(ManagerConfig{
+		PluginDir:    pluginDir,
+		TempDir:      t.TempDir(),
+		DrainTimeout: time.Second,
+	})
+	require.NoError(t, err)
+	manager.openPlugin = func(xpkgPath string) (Plugin, string, error) {
+		metadata, err := manager.loader.ReadManifest(xpkgPath)
+		if err != nil {
+			return nil, "", err
+		}
+		if failLoad[metadata.Version] {
+			return nil, "", fmt.Errorf("%w: bad binary", ErrPluginIncompatible)
+		}
+		return &stubPlugin{metadata: *metadata}, "", nil
+	}
+	return manager
+}
+
+func TestPluginManager_InstallActivateRollback(t *testing.T) {
+	manager := newVersionTestManager(t, map[string]bool{"3.0.0": true})
+	uploads := t.TempDir()
+
+	_, err := manager.InstallPlugin(writeTestXpkg(t, uploads, "demo", "1.0.0"), "demo.xpkg")
+	require.NoError(t, err)
+	v2, err := manager.InstallPlugin(writeTestXpkg(t, uploads, "demo", "2.0.0"), "demo.xpkg")
+	require.NoError(t, err)
+	assert.Equal(t, "2.0.0", manager.ActiveVersion("demo"))
+	assert.FileExists(t, v2.xpkgPath)
+
+	_, err = manager.InstallPlugin(writeTestXpkg(t, uploads, "demo", "2.0.0"), "demo.xpkg")
+	assert.ErrorIs(t, err, ErrPluginVersionExists)
+
+	// 加载失败的版本不保留，当前版本不变
+	_, err = manager.InstallPlugin(writeTestXpkg(t, uploads, "demo", "3.0.0"), "demo.xpkg")
+	assert.ErrorIs(t, err, ErrPluginIncompatible)
+	assert.Equal(t, "2.0.0", manager.ActiveVersion("demo"))
+	assert.NoDirExists(t, filepath.Join(manager.config.PluginDir, "demo", "3.0.0"))
+
+	require.NoError(t, manager.ActivateVersion("demo", "1.0.0"))
+	assert.Equal(t, "1.0.0", manager.ActiveVersion("demo"))
+	assert.ErrorIs(t, manager.ActivateVersion("demo", "9.9.9"), ErrPluginVersionNotFound)
+
+	previous, err := manager.Rollback("demo")
+	require.NoError(t, err)
+	assert.Equal(t, "2.0.0", previous.Version)
+	previous, err = manager.Rollback("demo")
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0", previous.Version)
+	_, err = manager.Rollback("demo")
+	assert.ErrorIs(t, err, ErrNoPreviousVersion)
+	_, err = manager.Rollback("missing")
+	assert.ErrorIs(t, err, ErrPluginNotFound)
+
+	versions, err := manager.ListPluginVersions("demo")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.True(t, versions[1].Active)
+}
+
+func TestPluginManager_RestoresVersionsAfterRestart(t *testing.T) {
+	manager := newVersionTestManager(t, nil)
+	uploads := t.TempDir()
+
+	_, err := manager.InstallPlugin(writeTestXpkg(t, uploads, "demo", "1.0.0"), "demo-1.xpkg")
+	require.NoError(t, err)
+	_, err = manager.InstallPlugin(writeTestXpkg(t, uploads, "demo", "2.0.0"), "demo-2.xpkg")
+	require.NoError(t, err)
+	require.NoError(t, manager.ReloadPluginConfig("demo", PluginConfig{
+		PluginID: "demo",
+		Enabled:  true,
+		Settings: map[string]interface{}{"model": "v2-large"},
+	}))
+	_, err = manager.Rollback("demo")
+	require.NoError(t, err)
+	require.Equal(t, "1.0.0", manager.ActiveVersion("demo"))
+
+	// 重启后保持回滚后的版本，而不是版本号最高的版本
+	restarted := newVersionTestManagerIn(t, manager.config.PluginDir, nil)
+	require.NoError(t, restarted.DiscoverAndLoad())
+	assert.Equal(t, "1.0.0", restarted.ActiveVersion("demo"))
+
+	versions, err := restarted.ListPluginVersions("demo")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, "demo-2.xpkg", versions[0].Filename)
+
+	// 再切回2.0.0时恢复该版本停用前的配置
+	require.NoError(t, restarted.ActivateVersion("demo", "2.0.0"))
+	entry, err := restarted.registry.Get("demo")
+	require.NoError(t, err)
+	assert.Equal(t, "v2-large", entry.Config.Settings["model"])
+	previous, err := restarted.Rollback("demo")
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0", previous.Version)
+}
+
+func TestPluginManager_InstallRejectsPathTraversal(t *testing.T) {
+	manager := newVersionTestManager(t, nil)
+	uploads := t.TempDir()
+
+	for _, tc := range []struct{ id, version string }{
+		{"../..", "1.0.0"},
+		{"demo", "../../escape"},
+		{"..", "1.0.0"},
+		{"demo/evil", "1.0.0"},
+		{"demo", ".."},
+	} {
+		_, err := manager.InstallPlugin(writeTestXpkg(t, uploads, tc.id, tc.version), "demo.xpkg")
+		assert.ErrorIs(t, err, ErrInvalidPluginPath, "%s@%s", tc.id, tc.version)
+	}
+	entries, err := os.ReadDir(filepath.Dir(manager.config.PluginDir))
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "nothing written next to the plugin dir")
+
+	_, err = manager.pluginPath("..")
+	assert.ErrorIs(t, err, ErrInvalidPluginPath)
+	assert.False(t, manager.withinPluginDir(manager.config.PluginDir))
+	assert.False(t, manager.withinPluginDir(filepath.Join(manager.config.PluginDir, "..", "x")))
+	assert.True(t, manager.withinPluginDir(filepath.Join(manager.config.PluginDir, "demo")))
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 
+	"github.com/aihub/backend-go/internal/database"
 	"github.com/aihub/backend-go/internal/models"
 	"gorm.io/gorm"
 )
@@ -45,7 +46,8 @@ func (r *knowledgeBaseRepository) GetByUserID(ctx context.Context, userID uint,
 	query := r.db.WithContext(ctx).Model(&models.KnowledgeBase{}).Where("owner_id = ?", userID)
 
 	if search != "" {
-		query = query.Where("name ILIKE ? OR description ILIKE ?", "%"+search+"%", "%"+search+"%")
+		like := database.ILike(r.db)
+		query = query.Where("name "+like+" ? OR description "+like+" ?", "%"+search+"%", "%"+search+"%")
 	}
 
 	// 获取总数
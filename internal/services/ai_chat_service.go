@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/aihub/backend-go/internal/config"
@@ -11,13 +12,24 @@ import (
 	"github.com/aihub/backend-go/internal/kafka"
 	"github.com/aihub/backend-go/internal/logger"
 	"github.com/aihub/backend-go/internal/models"
+	"github.com/aihub/backend-go/internal/outbox"
+	"github.com/aihub/backend-go/internal/plugins"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
+// ChatCompletionService AI聊天服务使用的模型接口
+// dashscope.Service 和 plugins.PluginServiceChatAdapter 均实现了该接口
+type ChatCompletionService interface {
+	ChatCompletion(ctx context.Context, req dashscope.ChatRequest) (*dashscope.ChatResponse, error)
+	Ready() bool
+}
+
 // AIChatService AI聊天服务（合并了ConversationService的功能）
 type AIChatService struct {
 	config *config.AIConfig
 	logger *zap.Logger
+	chat   ChatCompletionService // 为空时使用全局DashScope服务
 }
 
 // Conversation 对话结构
@@ -76,11 +88,32 @@ type AIChatRequest struct {
 }
 
 // NewAIChatService 创建AI聊天服务
+// 设置 AI_CHAT_PLUGIN_ID 时通过插件服务的聊天插件生成回复
 func NewAIChatService() *AIChatService {
-	return &AIChatService{
+	service := &AIChatService{
 		config: &config.GetAppConfig().AI,
 		logger: logger.Logger,
 	}
+	if pluginID := os.Getenv("AI_CHAT_PLUGIN_ID"); pluginID != "" {
+		service.chat = plugins.NewPluginServiceChatAdapter(pluginID)
+	}
+	return service
+}
+
+// SetChatService 设置聊天模型后端
+func (s *AIChatService) SetChatService(chat ChatCompletionService) {
+	s.chat = chat
+}
+
+// chatService 获取当前聊天模型后端
+func (s *AIChatService) chatService() ChatCompletionService {
+	if s.chat != nil {
+		return s.chat
+	}
+	if service := dashscope.GetGlobalService(); service != nil {
+		return service
+	}
+	return nil
 }
 
 // Chat 执行聊天（兼容原有接口，内部调用SendMessage）
@@ -171,7 +204,13 @@ func (s *AIChatService) SendMessage(req *SendMessageRequest) (*ConversationRespo
 		TokenCount:     s.estimateTokenCount(req.Content),
 		CreatedAt:      time.Now(),
 	}
-	if err := database.DB.Create(userMessage).Error; err != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(userMessage).Error; err != nil {
+			return err
+		}
+		return s.sendToKafka(tx, req, userMessage, nil)
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to save user message: %w", err)
 	}
 
@@ -199,27 +238,17 @@ func (s *AIChatService) SendMessage(req *SendMessageRequest) (*ConversationRespo
 		TokenCount:     response.TokenCount,
 		CreatedAt:      time.Now(),
 	}
-	if err := database.DB.Create(aiMessage).Error; err != nil {
+	// Kafka消息与AI响应在同一事务写入发件箱，提交后由中继发布
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(aiMessage).Error; err != nil {
+			return err
+		}
+		return s.sendToKafka(tx, req, aiMessage, response.Usage)
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to save AI message: %w", err)
 	}
 
-	// 5. 发送消息到 Kafka（异步记录）
-	go func() {
-		if err := s.sendToKafka(req, userMsg, &Message{
-			ID:             aiMessage.ID,
-			ConversationID: aiMessage.ConversationID,
-			UserID:         aiMessage.UserID,
-			Role:           aiMessage.Role,
-			Content:        aiMessage.Content,
-			TokenCount:     aiMessage.TokenCount,
-			CreatedAt:      aiMessage.CreatedAt,
-		}, response.Usage); err != nil {
-			s.logger.Error("Failed to send message to Kafka",
-				zap.Uint("conversation_id", req.ConversationID),
-				zap.Error(err))
-		}
-	}()
-
 	return &ConversationResponse{
 		ConversationID: req.ConversationID,
 		MessageID:      aiMessage.ID,
@@ -277,10 +306,10 @@ func (s *AIChatService) GetMessages(conversationID, userID uint, limit, offset i
 
 // generateAIResponse 生成 AI 响应
 func (s *AIChatService) generateAIResponse(conversation *Conversation, userMessage *Message, modelParams map[string]interface{}) (*ConversationResponse, error) {
-	// 获取全局DashScope服务
-	dashscopeService := dashscope.GetGlobalService()
-	if dashscopeService == nil || !dashscopeService.Ready() {
-		return nil, fmt.Errorf("DashScope service not available")
+	// 获取聊天模型后端（默认为全局DashScope服务）
+	chatService := s.chatService()
+	if chatService == nil || !chatService.Ready() {
+		return nil, fmt.Errorf("chat service not available")
 	}
 
 	// 确定使用的模型
@@ -312,10 +341,11 @@ func (s *AIChatService) generateAIResponse(conversation *Conversation, userMessa
 		chatReq.Temperature = &temperature
 	}
 
-	// 调用DashScope API
-	chatResp, err := dashscopeService.ChatCompletion(context.Background(), chatReq)
+	// 调用模型API，插件后端按会话所属用户鉴权
+	ctx := plugins.ContextWithPrincipal(context.Background(), plugins.Principal{UserID: conversation.UserID})
+	chatResp, err := chatService.ChatCompletion(ctx, chatReq)
 	if err != nil {
-		s.logger.Error("Failed to call DashScope ChatCompletion",
+		s.logger.Error("Failed to call ChatCompletion",
 			zap.Error(err),
 			zap.String("model", model))
 		return nil, fmt.Errorf("AI service call failed: %w", err)
@@ -349,38 +379,39 @@ func (s *AIChatService) generateAIResponse(conversation *Conversation, userMessa
 	return response, nil
 }
 
-// sendToKafka 发送消息到 Kafka
-func (s *AIChatService) sendToKafka(req *SendMessageRequest, userMessage, aiMessage *Message, usage *kafka.UsageInfo) error {
-	// 发送用户消息
-	if err := kafka.SendConversationMessage(
+// sendToKafka 在事务tx中把对话消息写入发件箱，同一对话的消息按写入顺序发布到Kafka
+// 发件箱未启用时直接发送，发送失败只记录日志，不影响消息保存
+func (s *AIChatService) sendToKafka(tx *gorm.DB, req *SendMessageRequest, message *models.ConversationMessage, usage *kafka.UsageInfo) error {
+	msg := kafka.NewConversationMessage(
 		fmt.Sprintf("%d", req.ConversationID),
 		req.UserID,
 		0, // modelID
-		"user",
-		userMessage.Content,
+		message.Role,
+		message.Content,
 		req.ModelParams,
-		nil, // 用户消息没有 token 统计
-	); err != nil {
-		return fmt.Errorf("failed to send user message to Kafka: %w", err)
+		usage, // 用户消息为nil
+	)
+	if !outbox.Enabled() {
+		if producer := kafka.GetProducer(); producer != nil {
+			if err := producer.SendMessage(msg); err != nil {
+				s.logger.Error("Failed to send message to Kafka",
+					zap.Uint("conversation_id", req.ConversationID),
+					zap.Error(err))
+			}
+		}
+		return nil
 	}
-
-	// 发送 AI 响应消息
-	if err := kafka.SendConversationMessage(
-		fmt.Sprintf("%d", req.ConversationID),
-		req.UserID,
-		0, // modelID
-		"assistant",
-		aiMessage.Content,
-		req.ModelParams,
-		usage,
-	); err != nil {
-		return fmt.Errorf("failed to send AI message to Kafka: %w", err)
+	msg.EventID = outbox.NewEventID()
+
+	if err := outbox.Enqueue(tx, outbox.Message{
+		EventID: msg.EventID,
+		Topic:   kafka.DefaultTopic(),
+		Key:     msg.Key(),
+		Value:   msg,
+		Headers: msg.Headers(),
+	}); err != nil {
+		return fmt.Errorf("failed to enqueue %s message for Kafka: %w", message.Role, err)
 	}
-
-	s.logger.Info("Messages sent to Kafka",
-		zap.Uint("conversation_id", req.ConversationID),
-		zap.Uint("user_id", req.UserID))
-
 	return nil
 }
 
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aihub/backend-go/internal/knowledge"
+	"github.com/aihub/backend-go/internal/logger"
+	"github.com/aihub/backend-go/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ingestionScheduler 写入向量使用的调度器：设置了嵌入模型路由时按知识库记录的模型选择
+func ingestionScheduler(ctx context.Context, router *knowledge.EmbeddingRouter, scheduler *knowledge.EmbedScheduler, kbID uint) (*knowledge.EmbedScheduler, error) {
+	if router == nil {
+		return scheduler, nil
+	}
+	return router.Scheduler(ctx, kbID)
+}
+
+// embedAndStoreChunks 批量生成分块向量并写入向量存储，返回成功写入的数量
+// 部分分块向量化失败时，其余分块照常写入，返回的错误中包含失败数量；progress为nil时不上报进度
+func embedAndStoreChunks(ctx context.Context, db *gorm.DB, scheduler *knowledge.EmbedScheduler, store knowledge.VectorStore, kbID uint, chunks []*models.KnowledgeChunk, progress *knowledge.DocumentProgress) (int, error) {
+	if len(chunks) == 0 || !store.Ready() {
+		return 0, nil
+	}
+
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Content
+	}
+
+	progress.Embed(ctx, 0, len(texts))
+	embeddings, embedErr := scheduler.EmbedAllWithProgress(ctx, texts, func(done, total int) {
+		progress.Embed(ctx, done, total)
+	})
+	var batchErr *knowledge.BatchEmbedError
+	if embedErr != nil && !errors.As(embedErr, &batchErr) {
+		return 0, fmt.Errorf("failed to embed chunks: %w", embedErr)
+	}
+
+	progress.Stage(ctx, knowledge.ProgressStageIndex)
+
+	var embedded []*models.KnowledgeChunk
+	var vectors []knowledge.VectorChunk
+	for i, chunk := range chunks {
+		if embeddings[i] == nil {
+			continue
+		}
+		embedded = append(embedded, chunk)
+		vectors = append(vectors, knowledge.VectorChunk{
+			ChunkID:         chunk.ChunkID,
+			DocumentID:      chunk.DocumentID,
+			KnowledgeBaseID: kbID,
+			Text:            chunk.Content,
+			Embedding:       embeddings[i],
+		})
+	}
+
+	// 支持批量写入的存储一次写入全部向量，否则逐个写入
+	var vectorIDs []string
+	if batch, ok := store.(knowledge.BatchVectorStore); ok && len(vectors) > 0 {
+		ids, err := batch.UpsertChunks(ctx, vectors)
+		if err != nil {
+			return 0, fmt.Errorf("failed to upsert vectors: %w", err)
+		}
+		vectorIDs = ids
+	}
+
+	indexed := 0
+	for i, chunk := range embedded {
+		var vectorID string
+		if vectorIDs != nil {
+			vectorID = vectorIDs[i]
+		} else {
+			id, err := store.UpsertChunk(ctx, vectors[i])
+			if err != nil {
+				return indexed, fmt.Errorf("failed to upsert vector for chunk %d: %w", chunk.ChunkID, err)
+			}
+			vectorID = id
+		}
+		if err := db.Model(chunk).Update("vector_id", vectorID).Error; err != nil {
+			return indexed, fmt.Errorf("failed to save vector id for chunk %d: %w", chunk.ChunkID, err)
+		}
+		indexed++
+	}
+
+	logger.Info("Chunks indexed",
+		zap.Uint("kb_id", kbID),
+		zap.Int("indexed", indexed),
+		zap.Int("total", len(chunks)),
+		zap.Int("batch_size", scheduler.BatchSize()))
+
+	if batchErr != nil {
+		return indexed, fmt.Errorf("failed to embed %d of %d chunks: %w", len(batchErr.Failed), len(chunks), batchErr)
+	}
+	return indexed, nil
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -11,8 +12,13 @@ import (
 
 	"github.com/aihub/backend-go/internal/errors"
 	"github.com/aihub/backend-go/internal/interfaces"
+	"github.com/aihub/backend-go/internal/knowledge"
+	"github.com/aihub/backend-go/internal/middleware"
 	"github.com/aihub/backend-go/internal/models"
+	"github.com/aihub/backend-go/internal/trash"
+	"github.com/aihub/backend-go/internal/webhook"
 	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
 )
 
 // DocumentService 文档服务
@@ -20,6 +26,27 @@ type DocumentService struct {
 	db     interfaces.DatabaseInterface
 	logger interfaces.LoggerInterface
 	storage *minio.Client
+
+	// 分块与向量化（可选，未设置时只统计token）
+	chunker        *knowledge.Chunker
+	embedScheduler *knowledge.EmbedScheduler
+	vectorStore    knowledge.VectorStore
+	embedRouter    *knowledge.EmbeddingRouter
+
+	// 全文索引（可选）
+	indexer knowledge.FulltextIndexer
+
+	// 处理进度推送（可选）
+	progressBroker knowledge.ProgressBroker
+
+	// Webhook事件发布（可选）
+	webhookPublisher webhook.Publisher
+
+	// 版本快照（可选）
+	versionRecorder VersionRecorder
+
+	// Kafka事件发布（可选），事件与文档状态在同一事务写入发件箱
+	kafkaService *middleware.KafkaService
 }
 
 // DocumentInfo 文档信息
@@ -59,6 +86,56 @@ func NewDocumentService(db interfaces.DatabaseInterface, logger interfaces.Logge
 	}
 }
 
+// SetIngestionPipeline 设置文档处理使用的分块器、批量向量化调度器与向量存储
+func (s *DocumentService) SetIngestionPipeline(chunker *knowledge.Chunker, scheduler *knowledge.EmbedScheduler, store knowledge.VectorStore) {
+	s.chunker = chunker
+	s.embedScheduler = scheduler
+	s.vectorStore = store
+}
+
+// SetEmbeddingRouter 设置嵌入模型路由，设置后按知识库记录的模型生成向量
+func (s *DocumentService) SetEmbeddingRouter(router *knowledge.EmbeddingRouter) {
+	s.embedRouter = router
+}
+
+// SetFulltextIndexer 设置全文索引，设置后新生成的分块写入全文索引
+func (s *DocumentService) SetFulltextIndexer(indexer knowledge.FulltextIndexer) {
+	s.indexer = indexer
+}
+
+// SetProgressBroker 设置文档处理进度的推送目标，设置后处理各阶段发布进度事件
+func (s *DocumentService) SetProgressBroker(broker knowledge.ProgressBroker) {
+	s.progressBroker = broker
+}
+
+// SetWebhookPublisher 设置Webhook事件发布器，设置后文档处理完成、失败与删除时通知订阅方
+func (s *DocumentService) SetWebhookPublisher(publisher webhook.Publisher) {
+	s.webhookPublisher = publisher
+}
+
+// SetVersionRecorder 设置版本快照记录，设置后文档处理完成时保存首个版本
+func (s *DocumentService) SetVersionRecorder(recorder VersionRecorder) {
+	s.versionRecorder = recorder
+}
+
+// SetKafkaService 设置Kafka事件服务，设置后文档上传与处理完成时发布知识库处理事件
+func (s *DocumentService) SetKafkaService(kafkaService *middleware.KafkaService) {
+	s.kafkaService = kafkaService
+}
+
+// publishProcessEvent 在事务tx中写入文档的知识库处理事件
+func (s *DocumentService) publishProcessEvent(tx *gorm.DB, doc *models.KnowledgeDocument, userID uint, action string) error {
+	if s.kafkaService == nil {
+		return nil
+	}
+	return s.kafkaService.PublishKnowledgeProcessEventTx(tx, middleware.KnowledgeProcessEvent{
+		KnowledgeBaseID: doc.KnowledgeBaseID,
+		DocumentID:      doc.DocumentID,
+		Action:          action,
+		UserID:          userID,
+	})
+}
+
 // UploadFile 上传单个文件
 func (s *DocumentService) UploadFile(kbID, userID uint, file interface{}, headerMap map[string]string) (*DocumentInfo, error) {
 	// 验证知识库权限
@@ -111,7 +188,12 @@ func (s *DocumentService) UploadDocuments(kbID, userID uint, req UploadDocuments
 			doc.Metadata = string(metadataBytes)
 		}
 
-		err := gormDB.Create(doc).Error
+		err := gormDB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(doc).Error; err != nil {
+				return err
+			}
+			return s.publishProcessEvent(tx, doc, userID, "upload")
+		})
 		if err != nil {
 			s.logger.Error("Failed to create document", "error", err, "title", docUpload.Title)
 			continue
@@ -157,10 +239,14 @@ func (s *DocumentService) ProcessDocuments(kbID, userID uint) error {
 	}
 
 	// 处理每个文档
+	ctx := context.Background()
 	for _, doc := range documents {
-		err := s.processDocument(&doc)
+		progress := s.documentProgress(&doc)
+		err := s.processDocument(ctx, &doc, progress)
 		if err != nil {
 			s.logger.Error("Failed to process document", "error", err, "docID", doc.DocumentID)
+			progress.Fail(ctx, err)
+			s.publishDocumentEvent(ctx, webhook.EventDocumentFailed, &doc, err)
 			// 继续处理其他文档
 			continue
 		}
@@ -168,23 +254,106 @@ func (s *DocumentService) ProcessDocuments(kbID, userID uint) error {
 		// 更新文档状态
 		doc.Status = "completed"
 		doc.UpdateTime = time.Now()
-		gormDB.Save(&doc)
+		if err := gormDB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&doc).Error; err != nil {
+				return err
+			}
+			return s.publishProcessEvent(tx, &doc, userID, "process")
+		}); err != nil {
+			s.logger.Error("Failed to save processed document", "error", err, "docID", doc.DocumentID)
+		}
+		s.recordVersion(ctx, &doc)
+		progress.Done(ctx)
+		s.publishDocumentEvent(ctx, webhook.EventDocumentCompleted, &doc, nil)
 	}
 
 	s.logger.Info("Documents processed", "kbID", kbID, "userID", userID, "processed", len(documents))
 	return nil
 }
 
-// processDocument 处理单个文档
-func (s *DocumentService) processDocument(doc *models.KnowledgeDocument) error {
-	// 这里实现文档处理逻辑（分块、向量化等）
-	// 暂时只是一个占位符
-
+// processDocument 处理单个文档（分块、批量向量化）
+func (s *DocumentService) processDocument(ctx context.Context, doc *models.KnowledgeDocument, progress *knowledge.DocumentProgress) error {
 	s.logger.Info("Processing document", "docID", doc.DocumentID, "title", doc.Title)
+	progress.Stage(ctx, knowledge.ProgressStageParse)
 
 	// 计算token数量（简化实现）
 	doc.TotalTokens = len(strings.Fields(doc.Content))
 
+	if s.chunker == nil {
+		return nil
+	}
+
+	gormDB := s.db.GetDB()
+	if err := s.removePreviousChunks(ctx, gormDB, doc); err != nil {
+		return err
+	}
+
+	progress.Stage(ctx, knowledge.ProgressStageChunk)
+	chunks := s.chunker.SplitDocument(ctx, doc.Title, doc.Content)
+	records := make([]*models.KnowledgeChunk, 0, len(chunks))
+	for i, chunk := range chunks {
+		record := &models.KnowledgeChunk{
+			DocumentID:    doc.DocumentID,
+			Content:       chunk.Text,
+			ChunkIndex:    i,
+			ChunkPosition: i,
+			TokenCount:    chunk.TokenCount,
+			ContentHash:   calculateChunkHash(chunk.Text),
+			CreateTime:    time.Now(),
+			UpdateTime:    time.Now(),
+			IsActive:      true,
+		}
+		if err := gormDB.Create(record).Error; err != nil {
+			return fmt.Errorf("failed to create chunk: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	chunkIDs := make([]uint, len(records))
+	for i, record := range records {
+		chunkIDs[i] = record.ChunkID
+	}
+	if err := knowledge.IndexChunks(ctx, gormDB, s.indexer, doc.KnowledgeBaseID, chunkIDs); err != nil {
+		return err
+	}
+
+	if (s.embedScheduler == nil && s.embedRouter == nil) || s.vectorStore == nil {
+		return nil
+	}
+	scheduler, err := ingestionScheduler(ctx, s.embedRouter, s.embedScheduler, doc.KnowledgeBaseID)
+	if err != nil {
+		return err
+	}
+	indexed, err := embedAndStoreChunks(ctx, gormDB, scheduler, s.vectorStore, doc.KnowledgeBaseID, records, progress)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("Document chunks indexed", "docID", doc.DocumentID, "chunks", len(records), "indexed", indexed)
+	return nil
+}
+
+// removePreviousChunks 重新处理文档前删除上一次生成的分块及其向量与全文索引
+func (s *DocumentService) removePreviousChunks(ctx context.Context, gormDB *gorm.DB, doc *models.KnowledgeDocument) error {
+	var count int64
+	if err := gormDB.Model(&models.KnowledgeChunk{}).Where("document_id = ?", doc.DocumentID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count previous chunks: %w", err)
+	}
+	if count == 0 {
+		return nil
+	}
+	if s.vectorStore != nil && s.vectorStore.Ready() {
+		if err := s.vectorStore.DeleteDocument(ctx, doc.KnowledgeBaseID, doc.DocumentID); err != nil {
+			return fmt.Errorf("failed to delete previous vectors: %w", err)
+		}
+	}
+	if s.indexer != nil && s.indexer.Ready() {
+		if err := s.indexer.RemoveDocument(ctx, doc.KnowledgeBaseID, doc.DocumentID); err != nil {
+			return fmt.Errorf("failed to remove previous chunks from fulltext index: %w", err)
+		}
+	}
+	if err := gormDB.Where("document_id = ?", doc.DocumentID).Delete(&models.KnowledgeChunk{}).Error; err != nil {
+		return fmt.Errorf("failed to delete previous chunks: %w", err)
+	}
 	return nil
 }
 
@@ -239,7 +408,7 @@ func (s *DocumentService) GetDocumentDetail(kbID, docID, userID uint) (interface
 	gormDB := s.db.GetDB()
 
 	var doc models.KnowledgeDocument
-	err := gormDB.Where("id = ? AND knowledge_base_id = ?", docID, kbID).First(&doc).Error
+	err := gormDB.Where("document_id = ? AND knowledge_base_id = ?", docID, kbID).First(&doc).Error
 	if err != nil {
 		s.logger.Error("Failed to get document detail", "error", err, "docID", docID, "kbID", kbID)
 		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to retrieve document detail").WithCause(err)
@@ -271,7 +440,7 @@ func (s *DocumentService) validateKnowledgeBaseAccess(kbID, userID uint) error {
 	gormDB := s.db.GetDB()
 
 	var count int64
-	err := gormDB.Model(&models.KnowledgeBase{}).Where("id = ? AND owner_id = ?", kbID, userID).Count(&count).Error
+	err := gormDB.Model(&models.KnowledgeBase{}).Where("knowledge_base_id = ? AND owner_id = ?", kbID, userID).Count(&count).Error
 	if err != nil {
 		s.logger.Error("Failed to validate knowledge base access", "error", err, "kbID", kbID, "userID", userID)
 		return fmt.Errorf("failed to validate access: %w", err)
@@ -369,24 +538,113 @@ func (s *DocumentService) ProcessDocument(ctx context.Context, docID uint) error
 	}
 
 	// 调用现有的处理方法
-	return s.processDocument(&doc)
+	progress := s.documentProgress(&doc)
+	if err := s.processDocument(ctx, &doc, progress); err != nil {
+		progress.Fail(ctx, err)
+		s.publishDocumentEvent(ctx, webhook.EventDocumentFailed, &doc, err)
+		return err
+	}
+	s.recordVersion(ctx, &doc)
+	progress.Done(ctx)
+	s.publishDocumentEvent(ctx, webhook.EventDocumentCompleted, &doc, nil)
+	return nil
 }
 
-// DeleteDocument 删除文档
-func (s *DocumentService) DeleteDocument(ctx context.Context, docID uint) error {
-	// 验证文档存在
+// documentProgress 文档的进度上报器，未设置推送目标时为nil
+func (s *DocumentService) documentProgress(doc *models.KnowledgeDocument) *knowledge.DocumentProgress {
+	docID := doc.DocumentID
+	return knowledge.NewDocumentProgress(s.progressBroker, doc.KnowledgeBaseID, docID, func(err error) {
+		s.logger.Warn("Failed to publish document progress", "error", err, "docID", docID)
+	})
+}
+
+// DeleteDocument 将文档移入回收站，保留期满后由清理任务删除各存储中的数据
+func (s *DocumentService) DeleteDocument(ctx context.Context, docID uint, userID uint) error {
 	var doc models.KnowledgeDocument
 	if err := s.db.GetDB().Where("document_id = ?", docID).First(&doc).Error; err != nil {
 		s.logger.Error("Failed to find document for deletion", "error", err, "docID", docID)
 		return errors.NewNotFoundError("document")
 	}
 
-	// 删除文档记录
-	if err := s.db.GetDB().Delete(&doc).Error; err != nil {
-		s.logger.Error("Failed to delete document", "error", err, "docID", docID)
+	return s.moveToTrash(ctx, &doc, userID)
+}
+
+// TrashDocument 校验知识库归属后将文档移入回收站
+func (s *DocumentService) TrashDocument(ctx context.Context, kbID, docID, userID uint) error {
+	gormDB := s.db.GetDB()
+
+	var count int64
+	err := gormDB.Model(&models.KnowledgeBase{}).
+		Where("knowledge_base_id = ? AND owner_id = ?", kbID, userID).
+		Count(&count).Error
+	if err != nil {
+		s.logger.Error("Failed to validate knowledge base access", "error", err, "kbID", kbID, "userID", userID)
+		return errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to validate access").WithCause(err)
+	}
+	if count == 0 {
+		return errors.NewNotFoundError("knowledge base")
+	}
+
+	var doc models.KnowledgeDocument
+	if err := gormDB.Where("document_id = ? AND knowledge_base_id = ?", docID, kbID).First(&doc).Error; err != nil {
+		return errors.NewNotFoundError("document")
+	}
+	return s.moveToTrash(ctx, &doc, userID)
+}
+
+func (s *DocumentService) moveToTrash(ctx context.Context, doc *models.KnowledgeDocument, userID uint) error {
+	var task *models.KnowledgePurgeTask
+	err := s.db.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		task, err = trash.MoveToTrash(tx, trash.Item{
+			Type:            models.TrashItemDocument,
+			KnowledgeBaseID: doc.KnowledgeBaseID,
+			DocumentID:      doc.DocumentID,
+			Title:           doc.Title,
+			DeletedBy:       userID,
+		})
+		return err
+	})
+	if stderrors.Is(err, trash.ErrNotFound) {
+		return errors.NewNotFoundError("document")
+	}
+	if err != nil {
+		s.logger.Error("Failed to delete document", "error", err, "docID", doc.DocumentID)
 		return errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to delete document").WithCause(err)
 	}
 
-	s.logger.Info("Document deleted successfully", "docID", docID)
+	s.publishDocumentEvent(ctx, webhook.EventDocumentDeleted, doc, nil)
+	s.logger.Info("Document moved to trash", "docID", doc.DocumentID, "taskID", task.TaskID, "purgeAfter", task.PurgeAfter)
 	return nil
 }
+
+// recordVersion 保存处理完成的文档版本，失败只记录日志
+func (s *DocumentService) recordVersion(ctx context.Context, doc *models.KnowledgeDocument) {
+	if s.versionRecorder == nil {
+		return
+	}
+	if err := s.versionRecorder.RecordVersion(ctx, doc); err != nil {
+		s.logger.Warn("Failed to record document version", "error", err, "docID", doc.DocumentID)
+	}
+}
+
+// publishDocumentEvent 发布文档事件，失败只记录日志不影响文档处理
+func (s *DocumentService) publishDocumentEvent(ctx context.Context, eventType string, doc *models.KnowledgeDocument, cause error) {
+	if s.webhookPublisher == nil {
+		return
+	}
+	data := map[string]interface{}{
+		"title":        doc.Title,
+		"source":       doc.Source,
+		"status":       doc.Status,
+		"total_tokens": doc.TotalTokens,
+	}
+	if cause != nil {
+		data["status"] = "failed"
+		data["error"] = cause.Error()
+	}
+	event := webhook.NewEvent(eventType, doc.KnowledgeBaseID, doc.DocumentID, data)
+	if err := s.webhookPublisher.Publish(context.WithoutCancel(ctx), event); err != nil {
+		s.logger.Warn("Failed to publish webhook event", "error", err, "event", eventType, "docID", doc.DocumentID)
+	}
+}
@@ -0,0 +1,339 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aihub/backend-go/internal/errors"
+	"github.com/aihub/backend-go/internal/interfaces"
+	"github.com/aihub/backend-go/internal/knowledge"
+	"github.com/aihub/backend-go/internal/models"
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm/clause"
+)
+
+// documentVersionBucket 版本快照所在的存储桶，与文档源文件相同
+const documentVersionBucket = "aihub"
+
+// defaultDiffContext unified差异默认保留的上下文行数
+const defaultDiffContext = 3
+
+// DocumentVersionService 文档版本历史：快照保存、差异对比、版本恢复与按版本检索
+type DocumentVersionService struct {
+	db      interfaces.DatabaseInterface
+	logger  interfaces.LoggerInterface
+	storage *minio.Client
+
+	mu      sync.RWMutex
+	updater *IncrementalUpdater
+	chunker *knowledge.Chunker
+}
+
+// DocumentVersionDiff 两个版本之间的文本差异
+type DocumentVersionDiff struct {
+	DocumentID   uint                `json:"document_id"`
+	From         int                 `json:"from"`
+	To           int                 `json:"to"`
+	FromTitle    string              `json:"from_title"`
+	ToTitle      string              `json:"to_title"`
+	TitleChanged bool                `json:"title_changed"`
+	Stats        knowledge.DiffStats `json:"stats"`
+	Unified      string              `json:"unified"`
+}
+
+// NewDocumentVersionService 创建文档版本服务，storage为空时版本文本内联保存在数据库中
+func NewDocumentVersionService(db interfaces.DatabaseInterface, logger interfaces.LoggerInterface, storage *minio.Client) *DocumentVersionService {
+	return &DocumentVersionService{
+		db:      db,
+		logger:  logger,
+		storage: storage,
+	}
+}
+
+// SetUpdater 设置增量更新器，恢复版本时通过增量路径重建索引
+func (s *DocumentVersionService) SetUpdater(updater *IncrementalUpdater) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.updater = updater
+}
+
+// SetChunker 设置按版本检索时使用的分块器，未设置时使用动态分块器
+func (s *DocumentVersionService) SetChunker(chunker *knowledge.Chunker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chunker = chunker
+}
+
+func (s *DocumentVersionService) getUpdater() *IncrementalUpdater {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.updater
+}
+
+func (s *DocumentVersionService) getChunker() *knowledge.Chunker {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.chunker == nil {
+		return knowledge.NewDynamicChunker()
+	}
+	return s.chunker
+}
+
+// RecordVersion 保存文档当前版本的快照，同一版本只保存一次
+func (s *DocumentVersionService) RecordVersion(ctx context.Context, doc *models.KnowledgeDocument) error {
+	gormDB := s.db.GetDB().WithContext(ctx)
+	version := max(doc.Version, 1)
+
+	var count int64
+	err := gormDB.Model(&models.KnowledgeDocumentVersion{}).
+		Where("document_id = ? AND version = ?", doc.DocumentID, version).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("failed to check document version: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	record := models.KnowledgeDocumentVersion{
+		DocumentID:      doc.DocumentID,
+		KnowledgeBaseID: doc.KnowledgeBaseID,
+		Version:         version,
+		Title:           doc.Title,
+		ContentHash:     doc.ContentHash,
+		ChangeType:      doc.ChangeType,
+		ContentLength:   len(doc.Content),
+		CreateTime:      time.Now(),
+	}
+	if record.ContentHash == "" {
+		record.ContentHash = calculateChunkHash(doc.Content)
+	}
+	s.storeVersionObjects(ctx, doc, &record)
+
+	// 并发写入同一版本时以先写入的为准
+	if err := gormDB.Clauses(clause.OnConflict{DoNothing: true}).Create(&record).Error; err != nil {
+		return fmt.Errorf("failed to save document version: %w", err)
+	}
+	s.logger.Info("Document version recorded", "docID", doc.DocumentID, "version", version)
+	return nil
+}
+
+// storeVersionObjects 将提取文本与源文件副本写入对象存储，失败时文本改为内联保存
+func (s *DocumentVersionService) storeVersionObjects(ctx context.Context, doc *models.KnowledgeDocument, record *models.KnowledgeDocumentVersion) {
+	if s.storage == nil {
+		record.Content = doc.Content
+		return
+	}
+
+	textKey := versionObjectKey(doc.KnowledgeBaseID, doc.DocumentID, record.Version, "content.txt")
+	_, err := s.storage.PutObject(ctx, documentVersionBucket, textKey, strings.NewReader(doc.Content), int64(len(doc.Content)), minio.PutObjectOptions{
+		ContentType: "text/plain; charset=utf-8",
+	})
+	if err != nil {
+		s.logger.Warn("Failed to store version text, keeping it inline", "error", err, "docID", doc.DocumentID, "version", record.Version)
+		record.Content = doc.Content
+	} else {
+		record.TextObjectKey = textKey
+	}
+
+	// 源文件路径在重新上传同名文件时会被覆盖，按版本复制一份
+	if doc.FilePath == "" {
+		return
+	}
+	sourceKey := versionObjectKey(doc.KnowledgeBaseID, doc.DocumentID, record.Version, "source"+filepath.Ext(doc.FilePath))
+	_, err = s.storage.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: documentVersionBucket, Object: sourceKey},
+		minio.CopySrcOptions{Bucket: documentVersionBucket, Object: doc.FilePath})
+	if err != nil {
+		s.logger.Warn("Failed to copy version source object", "error", err, "docID", doc.DocumentID, "filePath", doc.FilePath)
+		return
+	}
+	record.SourceObjectKey = sourceKey
+}
+
+// ListVersions 文档的版本列表，按版本号倒序
+func (s *DocumentVersionService) ListVersions(ctx context.Context, kbID, docID, userID uint) ([]models.KnowledgeDocumentVersion, error) {
+	if _, err := s.findDocument(ctx, kbID, docID, userID); err != nil {
+		return nil, err
+	}
+	versions := []models.KnowledgeDocumentVersion{}
+	err := s.db.GetDB().WithContext(ctx).
+		Where("document_id = ?", docID).
+		Order("version DESC").
+		Find(&versions).Error
+	if err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to retrieve document versions").WithCause(err)
+	}
+	return versions, nil
+}
+
+// Diff 对比两个版本的文本，contextLines小于0时使用默认上下文行数
+func (s *DocumentVersionService) Diff(ctx context.Context, kbID, docID, userID uint, from, to, contextLines int) (*DocumentVersionDiff, error) {
+	if _, err := s.findDocument(ctx, kbID, docID, userID); err != nil {
+		return nil, err
+	}
+	fromVersion, fromText, err := s.loadVersion(ctx, docID, from)
+	if err != nil {
+		return nil, err
+	}
+	toVersion, toText, err := s.loadVersion(ctx, docID, to)
+	if err != nil {
+		return nil, err
+	}
+	if contextLines < 0 {
+		contextLines = defaultDiffContext
+	}
+
+	lines := knowledge.DiffText(fromText, toText)
+	return &DocumentVersionDiff{
+		DocumentID:   docID,
+		From:         from,
+		To:           to,
+		FromTitle:    fromVersion.Title,
+		ToTitle:      toVersion.Title,
+		TitleChanged: fromVersion.Title != toVersion.Title,
+		Stats:        knowledge.CountDiff(lines),
+		Unified:      knowledge.UnifiedDiff(fmt.Sprintf("v%d", from), fmt.Sprintf("v%d", to), lines, contextLines),
+	}, nil
+}
+
+// Restore 将文档恢复为指定版本的内容，通过增量更新路径重建索引并生成新版本
+func (s *DocumentVersionService) Restore(ctx context.Context, kbID, docID, userID uint, version int) (*models.KnowledgeDocumentVersion, error) {
+	updater := s.getUpdater()
+	if updater == nil {
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Document restore is not available")
+	}
+	doc, err := s.findDocument(ctx, kbID, docID, userID)
+	if err != nil {
+		return nil, err
+	}
+	target, text, err := s.loadVersion(ctx, docID, version)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Content == text && doc.Title == target.Title {
+		return nil, errors.NewValidationError("Document already matches this version")
+	}
+
+	// 历史数据可能缺少当前版本的快照，恢复前补齐
+	if err := s.RecordVersion(ctx, doc); err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to record current version").WithCause(err)
+	}
+	if err := updater.UpdateDocument(ctx, docID, text, target.Title); err != nil {
+		s.logger.Error("Failed to restore document version", "error", err, "docID", docID, "version", version)
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to restore document version").WithCause(err)
+	}
+
+	gormDB := s.db.GetDB().WithContext(ctx)
+	var restored models.KnowledgeDocument
+	if err := gormDB.Where("document_id = ?", docID).First(&restored).Error; err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to reload document").WithCause(err)
+	}
+	if err := s.RecordVersion(ctx, &restored); err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to record restored version").WithCause(err)
+	}
+	var record models.KnowledgeDocumentVersion
+	err = gormDB.Where("document_id = ? AND version = ?", docID, max(restored.Version, 1)).First(&record).Error
+	if err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to retrieve restored version").WithCause(err)
+	}
+	if err := gormDB.Model(&record).Update("restored_from", version).Error; err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to update restored version").WithCause(err)
+	}
+	record.RestoredFrom = &version
+
+	s.logger.Info("Document version restored", "docID", docID, "from", version, "newVersion", record.Version, "userID", userID)
+	return &record, nil
+}
+
+// SearchAsOfVersion 在文档指定版本的文本上检索，用于审计时查看历史内容
+// 历史版本不保留向量，只做全文检索
+func (s *DocumentVersionService) SearchAsOfVersion(ctx context.Context, kbID, docID, userID uint, version int, query string, topK int) ([]knowledge.SearchMatch, error) {
+	if _, err := s.findDocument(ctx, kbID, docID, userID); err != nil {
+		return nil, err
+	}
+	record, text, err := s.loadVersion(ctx, docID, version)
+	if err != nil {
+		return nil, err
+	}
+	if topK <= 0 {
+		topK = 10
+	}
+
+	chunks := s.getChunker().SplitDocument(ctx, record.Title, text)
+	matches, err := knowledge.SearchSnapshot(ctx, knowledge.SnapshotSearchRequest{
+		KnowledgeBaseID: kbID,
+		DocumentID:      docID,
+		Chunks:          chunks,
+		Query:           query,
+		Limit:           topK,
+		Metadata: map[string]interface{}{
+			"title":         record.Title,
+			"as_of_version": record.Version,
+		},
+	})
+	if err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Version search failed").WithCause(err)
+	}
+	return matches, nil
+}
+
+// loadVersion 读取版本记录与文本
+func (s *DocumentVersionService) loadVersion(ctx context.Context, docID uint, version int) (*models.KnowledgeDocumentVersion, string, error) {
+	var record models.KnowledgeDocumentVersion
+	err := s.db.GetDB().WithContext(ctx).
+		Where("document_id = ? AND version = ?", docID, version).
+		First(&record).Error
+	if err != nil {
+		return nil, "", errors.NewNotFoundError("document version")
+	}
+	if record.TextObjectKey == "" {
+		return &record, record.Content, nil
+	}
+	if s.storage == nil {
+		return nil, "", errors.NewSystemError(errors.ErrCodeExternalService, "Storage client not available")
+	}
+
+	object, err := s.storage.GetObject(ctx, documentVersionBucket, record.TextObjectKey, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, "", errors.NewSystemError(errors.ErrCodeExternalService, "Failed to read version text").WithCause(err)
+	}
+	defer object.Close()
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, object); err != nil {
+		return nil, "", errors.NewSystemError(errors.ErrCodeExternalService, "Failed to read version text").WithCause(err)
+	}
+	return &record, buf.String(), nil
+}
+
+// findDocument 校验知识库归属并读取文档
+func (s *DocumentVersionService) findDocument(ctx context.Context, kbID, docID, userID uint) (*models.KnowledgeDocument, error) {
+	gormDB := s.db.GetDB().WithContext(ctx)
+	var count int64
+	err := gormDB.Model(&models.KnowledgeBase{}).
+		Where("knowledge_base_id = ? AND owner_id = ?", kbID, userID).
+		Count(&count).Error
+	if err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to validate access").WithCause(err)
+	}
+	if count == 0 {
+		return nil, errors.NewNotFoundError("knowledge base")
+	}
+
+	var doc models.KnowledgeDocument
+	if err := gormDB.Where("document_id = ? AND knowledge_base_id = ?", docID, kbID).First(&doc).Error; err != nil {
+		return nil, errors.NewNotFoundError("document")
+	}
+	return &doc, nil
+}
+
+// versionObjectKey 版本对象路径，与源文件同在知识库目录下
+func versionObjectKey(kbID, docID uint, version int, name string) string {
+	return fmt.Sprintf("knowledge-bases/%d/versions/%d/v%d/%s", kbID, docID, version, name)
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/aihub/backend-go/internal/errors"
+	"github.com/aihub/backend-go/internal/interfaces"
+	"github.com/aihub/backend-go/internal/knowledge"
+	"github.com/aihub/backend-go/internal/models"
+)
+
+// maxEvaluationQueries 接口同步运行的查询数量上限，更大的查询集使用cmd/evaluate离线运行
+const maxEvaluationQueries = 200
+
+// EvaluationService 检索效果评测服务
+type EvaluationService struct {
+	db           interfaces.DatabaseInterface
+	logger       interfaces.LoggerInterface
+	searchEngine *knowledge.HybridSearchEngine
+}
+
+// NewEvaluationService 创建检索效果评测服务
+func NewEvaluationService(db interfaces.DatabaseInterface, logger interfaces.LoggerInterface, searchEngine *knowledge.HybridSearchEngine) *EvaluationService {
+	return &EvaluationService{
+		db:           db,
+		logger:       logger,
+		searchEngine: searchEngine,
+	}
+}
+
+// EvaluationRequest 评测请求，查询集可以直接给出或使用JSONL文本
+type EvaluationRequest struct {
+	Name         string                 `json:"name"`
+	Queries      []knowledge.EvalQuery  `json:"queries"`
+	QueriesJSONL string                 `json:"queries_jsonl"`
+	Configs      []knowledge.EvalConfig `json:"configs"` // 为空时使用默认配置组合
+	Ks           []int                  `json:"ks"`      // 默认1,5,10
+}
+
+// EvaluationRun 评测记录与解析后的结果
+type EvaluationRun struct {
+	models.SearchEvaluationRun
+	Results []knowledge.EvalResult `json:"results"`
+}
+
+// RunEvaluation 在知识库上运行查询集并保存结果
+func (s *EvaluationService) RunEvaluation(ctx context.Context, kbID, userID uint, req EvaluationRequest) (*EvaluationRun, error) {
+	if s.searchEngine == nil {
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Search engine not configured")
+	}
+	if err := s.checkAccess(ctx, kbID, userID); err != nil {
+		return nil, err
+	}
+
+	queries := req.Queries
+	if strings.TrimSpace(req.QueriesJSONL) != "" {
+		parsed, err := knowledge.ParseEvalQueries(strings.NewReader(req.QueriesJSONL))
+		if err != nil {
+			return nil, errors.NewValidationError("Invalid query set: " + err.Error())
+		}
+		queries = append(queries, parsed...)
+	}
+	if len(queries) == 0 {
+		return nil, errors.NewValidationError("Query set is empty")
+	}
+	if len(queries) > maxEvaluationQueries {
+		return nil, errors.NewValidationError("Query set too large, use the evaluate command for more than 200 queries")
+	}
+	for _, k := range req.Ks {
+		if k <= 0 || k > 100 {
+			return nil, errors.NewValidationError("k must be between 1 and 100")
+		}
+	}
+
+	results, err := s.searchEngine.Evaluate(ctx, kbID, queries, req.Configs, req.Ks)
+	if err != nil {
+		return nil, errors.NewValidationError(err.Error())
+	}
+
+	encoded, err := json.Marshal(results)
+	if err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeOperationFailed, "Failed to encode evaluation results").WithCause(err)
+	}
+	run := models.SearchEvaluationRun{
+		KnowledgeBaseID: kbID,
+		Name:            req.Name,
+		QuerySetHash:    knowledge.QuerySetHash(queries),
+		QueryCount:      len(queries),
+		Embedder:        knowledge.DescribeEmbedder(s.searchEngine.GetEmbedder()),
+		Results:         string(encoded),
+		CreatedBy:       userID,
+		CreateTime:      time.Now(),
+	}
+	if err := s.db.GetDB().WithContext(ctx).Create(&run).Error; err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to save evaluation run").WithCause(err)
+	}
+
+	s.logger.Info("Search evaluation completed", "kbID", kbID, "runID", run.RunID, "queries", len(queries), "configs", len(results))
+	return &EvaluationRun{SearchEvaluationRun: run, Results: results}, nil
+}
+
+// ListRuns 知识库的评测记录，按时间倒序；querySetHash非空时只返回同一查询集的记录
+func (s *EvaluationService) ListRuns(ctx context.Context, kbID, userID uint, querySetHash string, limit int) ([]EvaluationRun, error) {
+	if err := s.checkAccess(ctx, kbID, userID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	query := s.db.GetDB().WithContext(ctx).Where("knowledge_base_id = ?", kbID)
+	if querySetHash != "" {
+		query = query.Where("query_set_hash = ?", querySetHash)
+	}
+	var runs []models.SearchEvaluationRun
+	if err := query.Order("run_id DESC").Limit(limit).Find(&runs).Error; err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to retrieve evaluation runs").WithCause(err)
+	}
+
+	views := make([]EvaluationRun, 0, len(runs))
+	for _, run := range runs {
+		views = append(views, toEvaluationRun(run))
+	}
+	return views, nil
+}
+
+// GetRun 获取单次评测记录
+func (s *EvaluationService) GetRun(ctx context.Context, kbID, runID, userID uint) (*EvaluationRun, error) {
+	if err := s.checkAccess(ctx, kbID, userID); err != nil {
+		return nil, err
+	}
+	var run models.SearchEvaluationRun
+	err := s.db.GetDB().WithContext(ctx).
+		Where("run_id = ? AND knowledge_base_id = ?", runID, kbID).
+		First(&run).Error
+	if err != nil {
+		return nil, errors.NewNotFoundError("evaluation run")
+	}
+	view := toEvaluationRun(run)
+	return &view, nil
+}
+
+func toEvaluationRun(run models.SearchEvaluationRun) EvaluationRun {
+	view := EvaluationRun{SearchEvaluationRun: run}
+	_ = json.Unmarshal([]byte(run.Results), &view.Results)
+	return view
+}
+
+// checkAccess 验证用户是否为知识库所有者
+func (s *EvaluationService) checkAccess(ctx context.Context, kbID, userID uint) error {
+	var count int64
+	err := s.db.GetDB().WithContext(ctx).Model(&models.KnowledgeBase{}).
+		Where("knowledge_base_id = ? AND owner_id = ?", kbID, userID).
+		Count(&count).Error
+	if err != nil {
+		return errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to validate access").WithCause(err)
+	}
+	if count == 0 {
+		return errors.NewNotFoundError("knowledge base")
+	}
+	return nil
+}
@@ -21,6 +21,16 @@ type IncrementalUpdater struct {
 	tokenCounter   *TokenCounter
 	changeDetector *ChangeDetector
 	mergeStrategy  *MergeStrategy
+	embedScheduler *knowledge.EmbedScheduler  // 批量向量化调度器（可选）
+	vectorStore    knowledge.VectorStore      // 向量存储（可选）
+	embedRouter    *knowledge.EmbeddingRouter // 嵌入模型路由（可选）
+	indexer        knowledge.FulltextIndexer  // 全文索引（可选）
+	versions       VersionRecorder            // 版本快照（可选）
+}
+
+// VersionRecorder 保存文档版本快照，同一版本重复调用不产生新记录
+type VersionRecorder interface {
+	RecordVersion(ctx context.Context, doc *models.KnowledgeDocument) error
 }
 
 // NewIncrementalUpdater 创建增量更新器
@@ -33,6 +43,27 @@ func NewIncrementalUpdater(chunker *knowledge.Chunker, tokenCounter *TokenCounte
 	}
 }
 
+// SetVectorIndexing 设置向量化调度器与向量存储，设置后新增和修改的分块会批量生成向量
+func (iu *IncrementalUpdater) SetVectorIndexing(scheduler *knowledge.EmbedScheduler, store knowledge.VectorStore) {
+	iu.embedScheduler = scheduler
+	iu.vectorStore = store
+}
+
+// SetEmbeddingRouter 设置嵌入模型路由，设置后按知识库记录的模型生成向量
+func (iu *IncrementalUpdater) SetEmbeddingRouter(router *knowledge.EmbeddingRouter) {
+	iu.embedRouter = router
+}
+
+// SetFulltextIndexer 设置全文索引，设置后新增和修改的分块写入全文索引
+func (iu *IncrementalUpdater) SetFulltextIndexer(indexer knowledge.FulltextIndexer) {
+	iu.indexer = indexer
+}
+
+// SetVersionRecorder 设置版本快照记录，设置后更新前后的内容都会保留
+func (iu *IncrementalUpdater) SetVersionRecorder(recorder VersionRecorder) {
+	iu.versions = recorder
+}
+
 // UpdateDocument 增量更新文档
 func (iu *IncrementalUpdater) UpdateDocument(ctx context.Context, docID uint, newContent string, newTitle string) error {
 	// 获取当前文档
@@ -50,20 +81,41 @@ func (iu *IncrementalUpdater) UpdateDocument(ctx context.Context, docID uint, ne
 		return nil
 	}
 
+	// 更新会覆盖原内容，先确保当前版本已有快照
+	if err := iu.recordVersion(ctx, &doc); err != nil {
+		return err
+	}
+
 	// 检测变更类型
 	changeType := iu.changeDetector.DetectChangeType(doc.Content, newContent, doc.Title, newTitle)
 
 	// 根据变更类型选择更新策略
+	var err error
 	switch changeType {
 	case ChangeTypeFull:
-		return iu.performFullUpdate(ctx, &doc, newContent, newTitle, newContentHash)
+		err = iu.performFullUpdate(ctx, &doc, newContent, newTitle, newContentHash)
 	case ChangeTypeIncremental:
-		return iu.performIncrementalUpdate(ctx, &doc, newContent, newTitle, newContentHash)
+		err = iu.performIncrementalUpdate(ctx, &doc, newContent, newTitle, newContentHash)
 	case ChangeTypeAppend:
-		return iu.performAppendUpdate(ctx, &doc, newContent, newTitle, newContentHash)
+		err = iu.performAppendUpdate(ctx, &doc, newContent, newTitle, newContentHash)
 	default:
-		return iu.performFullUpdate(ctx, &doc, newContent, newTitle, newContentHash)
+		err = iu.performFullUpdate(ctx, &doc, newContent, newTitle, newContentHash)
+	}
+	if err != nil {
+		return err
+	}
+	return iu.recordVersion(ctx, &doc)
+}
+
+// recordVersion 保存版本快照，未设置记录器时跳过
+func (iu *IncrementalUpdater) recordVersion(ctx context.Context, doc *models.KnowledgeDocument) error {
+	if iu.versions == nil {
+		return nil
 	}
+	if err := iu.versions.RecordVersion(ctx, doc); err != nil {
+		return fmt.Errorf("failed to record document version %d: %w", doc.Version, err)
+	}
+	return nil
 }
 
 // performFullUpdate 执行全量更新
@@ -96,8 +148,40 @@ func (iu *IncrementalUpdater) performFullUpdate(ctx context.Context, doc *models
 		return fmt.Errorf("failed to update document: %w", err)
 	}
 
-	// 存储新分块（这里应该调用现有的存储逻辑）
-	// 由于篇幅限制，这里简化处理，实际应该调用ProcessDocuments中的逻辑
+	// 存储新分块
+	newChunks := make([]*models.KnowledgeChunk, 0, len(chunks))
+	for i, chunk := range chunks {
+		newChunk := &models.KnowledgeChunk{
+			DocumentID:    doc.DocumentID,
+			Content:       chunk.Text,
+			ChunkIndex:    i,
+			ChunkPosition: i,
+			TokenCount:    chunk.TokenCount,
+			ContentHash:   iu.calculateContentHash(chunk.Text),
+			CreateTime:    time.Now(),
+			UpdateTime:    time.Now(),
+			IsActive:      true,
+		}
+		if err := database.DB.Create(newChunk).Error; err != nil {
+			return fmt.Errorf("failed to create chunk: %w", err)
+		}
+		newChunks = append(newChunks, newChunk)
+	}
+
+	// 旧向量整体失效，重新批量生成
+	if iu.vectorStore != nil && iu.vectorStore.Ready() {
+		if err := iu.vectorStore.DeleteDocument(ctx, doc.KnowledgeBaseID, doc.DocumentID); err != nil {
+			logger.Warn("Failed to delete old vectors", zap.Uint("doc_id", doc.DocumentID), zap.Error(err))
+		}
+	}
+	if iu.indexer != nil && iu.indexer.Ready() {
+		if err := iu.indexer.RemoveDocument(ctx, doc.KnowledgeBaseID, doc.DocumentID); err != nil {
+			logger.Warn("Failed to remove old chunks from fulltext index", zap.Uint("doc_id", doc.DocumentID), zap.Error(err))
+		}
+	}
+	if err := iu.indexChunks(ctx, doc.KnowledgeBaseID, newChunks); err != nil {
+		return err
+	}
 
 	logger.Info("Full update completed", zap.Uint("doc_id", doc.DocumentID), zap.Int("chunks", len(chunks)))
 	return nil
@@ -121,7 +205,7 @@ func (iu *IncrementalUpdater) performIncrementalUpdate(ctx context.Context, doc
 	added, removed, modified := iu.mergeStrategy.ComputeChunkDiff(existingChunks, newChunks)
 
 	// 应用增量更改
-	if err := iu.applyIncrementalChanges(ctx, doc.DocumentID, added, removed, modified); err != nil {
+	if err := iu.applyIncrementalChanges(ctx, doc.KnowledgeBaseID, doc.DocumentID, added, removed, modified); err != nil {
 		return fmt.Errorf("failed to apply incremental changes: %w", err)
 	}
 
@@ -174,6 +258,7 @@ func (iu *IncrementalUpdater) performAppendUpdate(ctx context.Context, doc *mode
 	}
 
 	// 存储追加的分块
+	newChunks := make([]*models.KnowledgeChunk, 0, len(appendedChunks))
 	for i, chunk := range appendedChunks {
 		newChunk := &models.KnowledgeChunk{
 			DocumentID:    doc.DocumentID,
@@ -190,6 +275,11 @@ func (iu *IncrementalUpdater) performAppendUpdate(ctx context.Context, doc *mode
 		if err := database.DB.Create(newChunk).Error; err != nil {
 			return fmt.Errorf("failed to create appended chunk: %w", err)
 		}
+		newChunks = append(newChunks, newChunk)
+	}
+
+	if err := iu.indexChunks(ctx, doc.KnowledgeBaseID, newChunks); err != nil {
+		return err
 	}
 
 	// 更新文档元数据
@@ -213,14 +303,23 @@ func (iu *IncrementalUpdater) performAppendUpdate(ctx context.Context, doc *mode
 }
 
 // applyIncrementalChanges 应用增量更改
-func (iu *IncrementalUpdater) applyIncrementalChanges(ctx context.Context, docID uint, added, removed, modified []ChunkChange) error {
+func (iu *IncrementalUpdater) applyIncrementalChanges(ctx context.Context, kbID, docID uint, added, removed, modified []ChunkChange) error {
+	// 新增和修改的分块统一批量生成向量
+	var toIndex []*models.KnowledgeChunk
+
 	// 处理删除的分块
+	removedIDs := make([]uint, 0, len(removed))
 	for _, change := range removed {
 		if err := database.DB.Model(&models.KnowledgeChunk{}).
 			Where("chunk_id = ?", change.OldChunk.ChunkID).
 			Update("is_active", false).Error; err != nil {
 			return fmt.Errorf("failed to deactivate chunk %d: %w", change.OldChunk.ChunkID, err)
 		}
+		removedIDs = append(removedIDs, change.OldChunk.ChunkID)
+	}
+	reindexAll, err := iu.deleteChunkVectors(ctx, kbID, docID, removedIDs)
+	if err != nil {
+		return err
 	}
 
 	// 处理修改的分块
@@ -233,8 +332,7 @@ func (iu *IncrementalUpdater) applyIncrementalChanges(ctx context.Context, docID
 		if err := database.DB.Save(change.OldChunk).Error; err != nil {
 			return fmt.Errorf("failed to update chunk %d: %w", change.OldChunk.ChunkID, err)
 		}
-
-		// TODO: 更新向量和索引
+		toIndex = append(toIndex, change.OldChunk)
 	}
 
 	// 处理新增的分块
@@ -254,11 +352,64 @@ func (iu *IncrementalUpdater) applyIncrementalChanges(ctx context.Context, docID
 		if err := database.DB.Create(newChunk).Error; err != nil {
 			return fmt.Errorf("failed to create new chunk: %w", err)
 		}
+		toIndex = append(toIndex, newChunk)
+	}
 
-		// TODO: 生成向量和索引
+	if reindexAll {
+		var active []*models.KnowledgeChunk
+		if err := database.DB.Where("document_id = ? AND is_active = ?", docID, true).
+			Find(&active).Error; err != nil {
+			return fmt.Errorf("failed to load active chunks: %w", err)
+		}
+		toIndex = active
 	}
 
-	return nil
+	return iu.indexChunks(ctx, kbID, toIndex)
+}
+
+// deleteChunkVectors 删除已移除分块的向量；存储不支持按分块删除时删除整篇文档的向量，
+// 返回true表示需要重新索引文档的全部激活分块
+func (iu *IncrementalUpdater) deleteChunkVectors(ctx context.Context, kbID, docID uint, chunkIDs []uint) (bool, error) {
+	if len(chunkIDs) == 0 || iu.vectorStore == nil || !iu.vectorStore.Ready() {
+		return false, nil
+	}
+	if deleter, ok := iu.vectorStore.(knowledge.ChunkVectorDeleter); ok {
+		if err := deleter.DeleteChunks(ctx, kbID, chunkIDs); err != nil {
+			return false, fmt.Errorf("failed to delete vectors of removed chunks: %w", err)
+		}
+		return false, nil
+	}
+	// 无法重新生成向量时保留旧向量，避免整篇文档不可检索
+	if iu.embedScheduler == nil && iu.embedRouter == nil {
+		logger.Warn("Vector store cannot delete chunks, stale vectors kept",
+			zap.Uint("doc_id", docID), zap.Int("chunks", len(chunkIDs)))
+		return false, nil
+	}
+	if err := iu.vectorStore.DeleteDocument(ctx, kbID, docID); err != nil {
+		return false, fmt.Errorf("failed to delete document vectors: %w", err)
+	}
+	return true, nil
+}
+
+// indexChunks 将分块写入全文索引，并批量生成分块向量写入向量存储
+func (iu *IncrementalUpdater) indexChunks(ctx context.Context, kbID uint, chunks []*models.KnowledgeChunk) error {
+	chunkIDs := make([]uint, len(chunks))
+	for i, chunk := range chunks {
+		chunkIDs[i] = chunk.ChunkID
+	}
+	if err := knowledge.IndexChunks(ctx, database.DB, iu.indexer, kbID, chunkIDs); err != nil {
+		return err
+	}
+
+	if (iu.embedScheduler == nil && iu.embedRouter == nil) || iu.vectorStore == nil {
+		return nil
+	}
+	scheduler, err := ingestionScheduler(ctx, iu.embedRouter, iu.embedScheduler, kbID)
+	if err != nil {
+		return err
+	}
+	_, err = embedAndStoreChunks(ctx, database.DB, scheduler, iu.vectorStore, kbID, chunks, nil)
+	return err
 }
 
 // calculateContentHash 计算内容哈希
@@ -1,18 +1,27 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	stderrors "errors"
 	"time"
 
 	"github.com/aihub/backend-go/internal/errors"
 	"github.com/aihub/backend-go/internal/interfaces"
+	"github.com/aihub/backend-go/internal/database"
 	"github.com/aihub/backend-go/internal/models"
+	"github.com/aihub/backend-go/internal/trash"
+	"github.com/aihub/backend-go/internal/webhook"
+	"gorm.io/gorm"
 )
 
 // KnowledgeBaseService 知识库服务
 type KnowledgeBaseService struct {
 	db     interfaces.DatabaseInterface
 	logger interfaces.LoggerInterface
+
+	// Webhook事件发布（可选）
+	webhookPublisher webhook.Publisher
 }
 
 // KnowledgeBase 知识库
@@ -48,6 +57,11 @@ func NewKnowledgeBaseService(db interfaces.DatabaseInterface, logger interfaces.
 	}
 }
 
+// SetWebhookPublisher 设置Webhook事件发布器，设置后知识库修改与删除时通知订阅方
+func (s *KnowledgeBaseService) SetWebhookPublisher(publisher webhook.Publisher) {
+	s.webhookPublisher = publisher
+}
+
 // GetKnowledgeBases 获取知识库列表
 func (s *KnowledgeBaseService) GetKnowledgeBases(userID uint, page, limit int, search string) ([]*KnowledgeBase, int, error) {
 	gormDB := s.db.GetDB()
@@ -56,7 +70,8 @@ func (s *KnowledgeBaseService) GetKnowledgeBases(userID uint, page, limit int, s
 	query := gormDB.Where("owner_id = ?", userID)
 
 	if search != "" {
-		query = query.Where("name ILIKE ? OR description ILIKE ?", "%"+search+"%", "%"+search+"%")
+		like := database.ILike(gormDB)
+		query = query.Where("name "+like+" ? OR description "+like+" ?", "%"+search+"%", "%"+search+"%")
 	}
 
 	var total int64
@@ -91,7 +106,7 @@ func (s *KnowledgeBaseService) GetKnowledgeBase(id, userID uint) (*KnowledgeBase
 	gormDB := s.db.GetDB()
 
 	var kb models.KnowledgeBase
-	err := gormDB.Where("id = ? AND owner_id = ?", id, userID).First(&kb).Error
+	err := gormDB.Where("knowledge_base_id = ? AND owner_id = ?", id, userID).First(&kb).Error
 	if err != nil {
 		s.logger.Error("Failed to get knowledge base", "error", err, "id", id, "userID", userID)
 		return nil, errors.NewNotFoundError("knowledge base")
@@ -152,7 +167,7 @@ func (s *KnowledgeBaseService) UpdateKnowledgeBase(id, userID uint, req UpdateKn
 	gormDB := s.db.GetDB()
 
 	var kb models.KnowledgeBase
-	err := gormDB.Where("id = ? AND owner_id = ?", id, userID).First(&kb).Error
+	err := gormDB.Where("knowledge_base_id = ? AND owner_id = ?", id, userID).First(&kb).Error
 	if err != nil {
 		s.logger.Error("Failed to find knowledge base for update", "error", err, "id", id, "userID", userID)
 		return nil, errors.NewNotFoundError("knowledge base")
@@ -183,6 +198,10 @@ func (s *KnowledgeBaseService) UpdateKnowledgeBase(id, userID uint, req UpdateKn
 		}
 
 	s.logger.Info("Knowledge base updated", "id", id, "name", kb.Name)
+	s.publishEvent(webhook.EventKnowledgeBaseUpdated, kb.KnowledgeBaseID, map[string]interface{}{
+		"name":        kb.Name,
+		"description": kb.Description,
+	})
 
 	return &KnowledgeBase{
 		ID:             kb.KnowledgeBaseID,
@@ -195,20 +214,49 @@ func (s *KnowledgeBaseService) UpdateKnowledgeBase(id, userID uint, req UpdateKn
 	}, nil
 }
 
-// DeleteKnowledgeBase 删除知识库
+// DeleteKnowledgeBase 将知识库移入回收站，保留期满后由清理任务删除各存储中的数据
 func (s *KnowledgeBaseService) DeleteKnowledgeBase(id, userID uint) error {
 	gormDB := s.db.GetDB()
 
-	result := gormDB.Where("id = ? AND owner_id = ?", id, userID).Delete(&models.KnowledgeBase{})
-	if result.Error != nil {
-		s.logger.Error("Failed to delete knowledge base", "error", result.Error, "id", id, "userID", userID)
-		return errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to delete knowledge base").WithCause(result.Error)
+	var kb models.KnowledgeBase
+	if err := gormDB.Where("knowledge_base_id = ? AND owner_id = ?", id, userID).First(&kb).Error; err != nil {
+		return errors.NewNotFoundError("knowledge base")
 	}
 
-	if result.RowsAffected == 0 {
+	var task *models.KnowledgePurgeTask
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		task, err = trash.MoveToTrash(tx, trash.Item{
+			Type:            models.TrashItemKnowledgeBase,
+			KnowledgeBaseID: id,
+			Title:           kb.Name,
+			DeletedBy:       userID,
+		})
+		return err
+	})
+	if stderrors.Is(err, trash.ErrNotFound) {
 		return errors.NewNotFoundError("knowledge base")
 	}
+	if err != nil {
+		s.logger.Error("Failed to delete knowledge base", "error", err, "id", id, "userID", userID)
+		return errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to delete knowledge base").WithCause(err)
+	}
 
-	s.logger.Info("Knowledge base deleted", "id", id, "userID", userID)
+	s.logger.Info("Knowledge base moved to trash", "id", id, "userID", userID, "purgeAfter", task.PurgeAfter)
+	s.publishEvent(webhook.EventKnowledgeBaseDeleted, id, map[string]interface{}{
+		"trash_task_id": task.TaskID,
+		"purge_after":   task.PurgeAfter,
+	})
 	return nil
 }
+
+// publishEvent 发布知识库事件，失败只记录日志
+func (s *KnowledgeBaseService) publishEvent(eventType string, kbID uint, data map[string]interface{}) {
+	if s.webhookPublisher == nil {
+		return
+	}
+	event := webhook.NewEvent(eventType, kbID, 0, data)
+	if err := s.webhookPublisher.Publish(context.Background(), event); err != nil {
+		s.logger.Warn("Failed to publish webhook event", "error", err, "event", eventType, "kbID", kbID)
+	}
+}
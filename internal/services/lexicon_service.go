@@ -0,0 +1,344 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aihub/backend-go/internal/errors"
+	"github.com/aihub/backend-go/internal/interfaces"
+	"github.com/aihub/backend-go/internal/knowledge"
+	"github.com/aihub/backend-go/internal/models"
+	"gorm.io/gorm"
+)
+
+// dictionaryReindexDelay 自定义词典修改后延迟重建全文索引，等待IK插件（默认每分钟）重新加载远程词典
+const dictionaryReindexDelay = 70 * time.Second
+
+// 词库输入限制
+const (
+	maxLexiconTermLength = 100
+	maxSynonymTerms      = 50
+	maxLexiconWords      = 5000
+)
+
+// LexiconService 知识库同义词、停用词与自定义词典管理
+type LexiconService struct {
+	db             interfaces.DatabaseInterface
+	logger         interfaces.LoggerInterface
+	reindexService *ReindexService
+
+	mu      sync.Mutex
+	store   *knowledge.LexiconStore
+	indexer knowledge.FulltextIndexer
+	pending map[uint]*time.Timer // 知识库ID -> 待执行的词典重建
+	ikToken string               // IK远程词典共享密钥
+}
+
+// LexiconInfo 知识库词库
+type LexiconInfo struct {
+	Synonyms   []models.KnowledgeSynonym `json:"synonyms"`
+	StopWords  []string                  `json:"stop_words"`
+	Dictionary []string                  `json:"dictionary"`
+}
+
+// NewLexiconService 创建词库服务
+func NewLexiconService(db interfaces.DatabaseInterface, logger interfaces.LoggerInterface, reindexService *ReindexService) *LexiconService {
+	return &LexiconService{
+		db:             db,
+		logger:         logger,
+		reindexService: reindexService,
+		pending:        make(map[uint]*time.Timer),
+	}
+}
+
+// SetBackends 设置词库缓存与全文索引，词库修改后清除缓存并更新索引分析器
+func (s *LexiconService) SetBackends(store *knowledge.LexiconStore, indexer knowledge.FulltextIndexer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+	s.indexer = indexer
+}
+
+// GetLexicon 获取知识库词库
+func (s *LexiconService) GetLexicon(ctx context.Context, kbID, userID uint) (*LexiconInfo, error) {
+	if err := s.checkAccess(ctx, kbID, userID); err != nil {
+		return nil, err
+	}
+	gormDB := s.db.GetDB().WithContext(ctx)
+
+	info := &LexiconInfo{Synonyms: []models.KnowledgeSynonym{}, StopWords: []string{}, Dictionary: []string{}}
+	if err := gormDB.Where("knowledge_base_id = ?", kbID).Order("synonym_id").Find(&info.Synonyms).Error; err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to load synonyms").WithCause(err)
+	}
+
+	var words []models.KnowledgeLexiconWord
+	if err := gormDB.Where("knowledge_base_id = ?", kbID).Order("word_id").Find(&words).Error; err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to load lexicon words").WithCause(err)
+	}
+	for _, w := range words {
+		switch w.Kind {
+		case models.LexiconKindStopWord:
+			info.StopWords = append(info.StopWords, w.Word)
+		case models.LexiconKindDictionary:
+			info.Dictionary = append(info.Dictionary, w.Word)
+		}
+	}
+	return info, nil
+}
+
+// CreateSynonym 新增同义词组
+func (s *LexiconService) CreateSynonym(ctx context.Context, kbID, userID uint, terms []string) (*models.KnowledgeSynonym, error) {
+	if err := s.checkAccess(ctx, kbID, userID); err != nil {
+		return nil, err
+	}
+	terms, err := normalizeSynonymTerms(terms)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	synonym := &models.KnowledgeSynonym{
+		KnowledgeBaseID: kbID,
+		Terms:           models.StringArray(terms),
+		CreateTime:      now,
+		UpdateTime:      now,
+	}
+	if err := s.db.GetDB().WithContext(ctx).Create(synonym).Error; err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to create synonym").WithCause(err)
+	}
+
+	s.logger.Info("Synonym created", "kbID", kbID, "synonymID", synonym.SynonymID, "terms", len(terms))
+	s.applyAnalyzer(ctx, kbID)
+	return synonym, nil
+}
+
+// UpdateSynonym 修改同义词组
+func (s *LexiconService) UpdateSynonym(ctx context.Context, kbID, userID, synonymID uint, terms []string) (*models.KnowledgeSynonym, error) {
+	if err := s.checkAccess(ctx, kbID, userID); err != nil {
+		return nil, err
+	}
+	terms, err := normalizeSynonymTerms(terms)
+	if err != nil {
+		return nil, err
+	}
+
+	gormDB := s.db.GetDB().WithContext(ctx)
+	var synonym models.KnowledgeSynonym
+	if err := gormDB.Where("synonym_id = ? AND knowledge_base_id = ?", synonymID, kbID).First(&synonym).Error; err != nil {
+		return nil, errors.NewNotFoundError("synonym")
+	}
+
+	synonym.Terms = models.StringArray(terms)
+	synonym.UpdateTime = time.Now()
+	if err := gormDB.Save(&synonym).Error; err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to update synonym").WithCause(err)
+	}
+
+	s.logger.Info("Synonym updated", "kbID", kbID, "synonymID", synonymID, "terms", len(terms))
+	s.applyAnalyzer(ctx, kbID)
+	return &synonym, nil
+}
+
+// DeleteSynonym 删除同义词组
+func (s *LexiconService) DeleteSynonym(ctx context.Context, kbID, userID, synonymID uint) error {
+	if err := s.checkAccess(ctx, kbID, userID); err != nil {
+		return err
+	}
+
+	result := s.db.GetDB().WithContext(ctx).
+		Where("synonym_id = ? AND knowledge_base_id = ?", synonymID, kbID).
+		Delete(&models.KnowledgeSynonym{})
+	if result.Error != nil {
+		return errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to delete synonym").WithCause(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.NewNotFoundError("synonym")
+	}
+
+	s.logger.Info("Synonym deleted", "kbID", kbID, "synonymID", synonymID)
+	s.applyAnalyzer(ctx, kbID)
+	return nil
+}
+
+// ReplaceWords 整体替换停用词或自定义词典，返回去重后的词列表
+// 停用词只影响检索分析器，立即生效；自定义词典影响分词结果，延迟重建全文索引
+func (s *LexiconService) ReplaceWords(ctx context.Context, kbID, userID uint, kind string, words []string) ([]string, error) {
+	if kind != models.LexiconKindStopWord && kind != models.LexiconKindDictionary {
+		return nil, errors.NewValidationError("Unsupported lexicon kind: " + kind)
+	}
+	if err := s.checkAccess(ctx, kbID, userID); err != nil {
+		return nil, err
+	}
+	words, err := normalizeLexiconWords(words)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	err = s.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("knowledge_base_id = ? AND kind = ?", kbID, kind).
+			Delete(&models.KnowledgeLexiconWord{}).Error; err != nil {
+			return err
+		}
+		if len(words) == 0 {
+			return nil
+		}
+		rows := make([]models.KnowledgeLexiconWord, len(words))
+		for i, word := range words {
+			rows[i] = models.KnowledgeLexiconWord{KnowledgeBaseID: kbID, Kind: kind, Word: word, CreateTime: now}
+		}
+		return tx.CreateInBatches(rows, 500).Error
+	})
+	if err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to save lexicon words").WithCause(err)
+	}
+
+	s.logger.Info("Lexicon words replaced", "kbID", kbID, "kind", kind, "count", len(words))
+	s.applyAnalyzer(ctx, kbID)
+	if kind == models.LexiconKindDictionary {
+		s.scheduleReindex(kbID)
+	}
+	return words, nil
+}
+
+// SetIKDictionaryToken 设置IK远程词典的共享密钥，为空时词典接口不可用
+func (s *LexiconService) SetIKDictionaryToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ikToken = token
+}
+
+// DictionaryText IK远程扩展词典内容：所有知识库自定义词的并集，每行一个词
+// IK扩展词典在ES节点上全局生效，无法按知识库隔离，因此只对持有共享密钥的ES节点开放
+func (s *LexiconService) DictionaryText(ctx context.Context, token string) (string, error) {
+	s.mu.Lock()
+	expected := s.ikToken
+	s.mu.Unlock()
+	if expected == "" {
+		return "", errors.NewNotFoundError("IK dictionary")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		return "", errors.NewAccessDeniedError()
+	}
+
+	var words []string
+	err := s.db.GetDB().WithContext(ctx).Model(&models.KnowledgeLexiconWord{}).
+		Where("kind = ?", models.LexiconKindDictionary).
+		Distinct("word").
+		Order("word").
+		Pluck("word", &words).Error
+	if err != nil {
+		return "", errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to load dictionary").WithCause(err)
+	}
+	if len(words) == 0 {
+		return "", nil
+	}
+	return strings.Join(words, "\n") + "\n", nil
+}
+
+// checkAccess 验证用户是否为知识库所有者
+func (s *LexiconService) checkAccess(ctx context.Context, kbID, userID uint) error {
+	var count int64
+	err := s.db.GetDB().WithContext(ctx).Model(&models.KnowledgeBase{}).
+		Where("knowledge_base_id = ? AND owner_id = ?", kbID, userID).
+		Count(&count).Error
+	if err != nil {
+		return errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to validate access").WithCause(err)
+	}
+	if count == 0 {
+		return errors.NewNotFoundError("knowledge base")
+	}
+	return nil
+}
+
+// applyAnalyzer 清除词库缓存并更新全文索引分析器；失败只记录日志，下次重建索引时会重新编译
+func (s *LexiconService) applyAnalyzer(ctx context.Context, kbID uint) {
+	s.mu.Lock()
+	store, indexer := s.store, s.indexer
+	s.mu.Unlock()
+	if store == nil {
+		return
+	}
+	store.Invalidate(kbID)
+
+	analyzer, ok := indexer.(knowledge.LexiconAnalyzer)
+	if !ok {
+		return
+	}
+	lexicon, err := store.Lexicon(ctx, kbID)
+	if err != nil {
+		s.logger.Error("Failed to load lexicon", "error", err, "kbID", kbID)
+		return
+	}
+	if err := analyzer.ApplyLexicon(ctx, kbID, lexicon); err != nil {
+		s.logger.Error("Failed to apply lexicon to fulltext index", "error", err, "kbID", kbID)
+	}
+}
+
+// scheduleReindex 延迟重建知识库全文索引，期间的多次修改合并为一次
+func (s *LexiconService) scheduleReindex(kbID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.indexer.(knowledge.LexiconAnalyzer); !ok || s.reindexService == nil {
+		return
+	}
+	if timer, ok := s.pending[kbID]; ok {
+		timer.Stop()
+	}
+	s.pending[kbID] = time.AfterFunc(dictionaryReindexDelay, func() {
+		s.mu.Lock()
+		delete(s.pending, kbID)
+		s.mu.Unlock()
+
+		_, err := s.reindexService.StartReindex(context.Background(), knowledge.ReindexRequest{KnowledgeBaseID: kbID, Fulltext: true})
+		if err != nil {
+			s.logger.Error("Failed to reindex after dictionary change", "error", err, "kbID", kbID)
+		}
+	})
+}
+
+// normalizeSynonymTerms 去除空白与重复词，同义词组至少两个不同的词
+func normalizeSynonymTerms(terms []string) ([]string, error) {
+	if len(terms) > maxSynonymTerms {
+		return nil, errors.NewValidationError("Too many synonym terms")
+	}
+	terms, err := normalizeLexiconWords(terms)
+	if err != nil {
+		return nil, err
+	}
+	if len(terms) < 2 {
+		return nil, errors.NewValidationError("A synonym set needs at least two distinct terms")
+	}
+	return terms, nil
+}
+
+// normalizeLexiconWords 去除空白与重复词（不区分大小写），拒绝包含ES同义词规则分隔符的词
+func normalizeLexiconWords(words []string) ([]string, error) {
+	if len(words) > maxLexiconWords {
+		return nil, errors.NewValidationError("Too many lexicon words")
+	}
+	seen := make(map[string]bool, len(words))
+	result := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.Join(strings.Fields(word), " ")
+		if word == "" {
+			continue
+		}
+		if strings.ContainsAny(word, ",\"\\") || strings.Contains(word, "=>") {
+			return nil, errors.NewValidationError("Lexicon words must not contain commas, quotes, backslashes or '=>'")
+		}
+		if len([]rune(word)) > maxLexiconTermLength {
+			return nil, errors.NewValidationError("Lexicon word too long")
+		}
+		key := strings.ToLower(word)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, word)
+	}
+	return result, nil
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aihub/backend-go/internal/errors"
+	"github.com/aihub/backend-go/internal/interfaces"
+	"github.com/aihub/backend-go/internal/knowledge"
+	"github.com/aihub/backend-go/internal/models"
+)
+
+// ProgressService 文档处理进度查询与订阅服务
+type ProgressService struct {
+	db     interfaces.DatabaseInterface
+	logger interfaces.LoggerInterface
+
+	mu     sync.RWMutex
+	broker knowledge.ProgressBroker
+}
+
+// NewProgressService 创建进度服务，默认使用进程内事件存储，配置Redis后通过SetBroker替换
+func NewProgressService(db interfaces.DatabaseInterface, logger interfaces.LoggerInterface) *ProgressService {
+	return &ProgressService{
+		db:     db,
+		logger: logger,
+		broker: knowledge.NewMemoryProgressBroker(0),
+	}
+}
+
+// SetBroker 设置进度事件存储
+func (s *ProgressService) SetBroker(broker knowledge.ProgressBroker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.broker = broker
+}
+
+// Broker 当前的进度事件存储，文档处理流程向其发布事件
+func (s *ProgressService) Broker() knowledge.ProgressBroker {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.broker
+}
+
+// Latest 文档处理的最新状态，docID为0时返回知识库下全部文档
+func (s *ProgressService) Latest(ctx context.Context, kbID, docID, userID uint) ([]knowledge.ProgressEvent, error) {
+	if err := s.checkAccess(ctx, kbID, userID); err != nil {
+		return nil, err
+	}
+	events, err := s.Broker().Latest(ctx, kbID, docID)
+	if err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Failed to read progress").WithCause(err)
+	}
+	if events == nil {
+		events = []knowledge.ProgressEvent{}
+	}
+	return events, nil
+}
+
+// ProgressSubscription 进度事件订阅，记录读取位置
+type ProgressSubscription struct {
+	broker   knowledge.ProgressBroker
+	kbID     uint
+	docID    uint
+	cursor   string
+	snapshot []knowledge.ProgressEvent
+}
+
+// Subscribe 订阅知识库（docID非0时为单个文档）的进度事件
+// lastEventID为空时先返回各文档的最新状态，再推送之后的事件；非空时从该事件之后继续（断线重连）
+func (s *ProgressService) Subscribe(ctx context.Context, kbID, docID, userID uint, lastEventID string) (*ProgressSubscription, error) {
+	if err := s.checkAccess(ctx, kbID, userID); err != nil {
+		return nil, err
+	}
+	broker := s.Broker()
+	sub := &ProgressSubscription{broker: broker, kbID: kbID, docID: docID, cursor: lastEventID}
+	if lastEventID != "" {
+		if !knowledge.ValidProgressID(lastEventID) {
+			return nil, errors.NewValidationError("Invalid Last-Event-ID")
+		}
+		return sub, nil
+	}
+
+	// 先取位置再取快照，两者之间发布的事件会重复推送但不会遗漏
+	cursor, err := broker.Cursor(ctx, kbID)
+	if err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Failed to read progress").WithCause(err)
+	}
+	snapshot, err := broker.Latest(ctx, kbID, docID)
+	if err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Failed to read progress").WithCause(err)
+	}
+	sub.cursor = cursor
+	sub.snapshot = snapshot
+	return sub, nil
+}
+
+// Next 读取下一批事件，没有新事件时最多等待block，超时返回空
+func (sub *ProgressSubscription) Next(ctx context.Context, block time.Duration) ([]knowledge.ProgressEvent, error) {
+	if sub.snapshot != nil {
+		events := sub.snapshot
+		sub.snapshot = nil
+		if len(events) > 0 {
+			return events, nil
+		}
+	}
+
+	deadline := time.Now().Add(block)
+	for {
+		events, err := sub.broker.Read(ctx, sub.kbID, sub.cursor, time.Until(deadline))
+		if err != nil {
+			return nil, err
+		}
+		if len(events) == 0 {
+			return nil, nil
+		}
+		sub.cursor = events[len(events)-1].ID
+		if sub.docID == 0 {
+			return events, nil
+		}
+
+		filtered := events[:0]
+		for _, event := range events {
+			if event.DocumentID == sub.docID {
+				filtered = append(filtered, event)
+			}
+		}
+		if len(filtered) > 0 || time.Until(deadline) <= 0 {
+			return filtered, nil
+		}
+	}
+}
+
+// checkAccess 验证用户是否为知识库所有者
+func (s *ProgressService) checkAccess(ctx context.Context, kbID, userID uint) error {
+	var count int64
+	err := s.db.GetDB().WithContext(ctx).Model(&models.KnowledgeBase{}).
+		Where("knowledge_base_id = ? AND owner_id = ?", kbID, userID).
+		Count(&count).Error
+	if err != nil {
+		return errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to validate access").WithCause(err)
+	}
+	if count == 0 {
+		return errors.NewNotFoundError("knowledge base")
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aihub/backend-go/internal/knowledge"
+	"github.com/minio/minio-go/v7"
+)
+
+// ChunkCacheChecker 检查Redis中的分块缓存
+// 缓存按需写入，未缓存的分块不算差异；已删除、失效或内容过期的缓存修复时直接删除，下次读取时重新从数据库加载
+type ChunkCacheChecker struct {
+	cache *RedisChunkStore
+}
+
+// NewChunkCacheChecker 创建分块缓存检查器
+func NewChunkCacheChecker(cache *RedisChunkStore) *ChunkCacheChecker {
+	return &ChunkCacheChecker{cache: cache}
+}
+
+func (c *ChunkCacheChecker) Name() string { return "chunk_cache" }
+
+func (c *ChunkCacheChecker) Check(ctx context.Context, state *knowledge.ReconcileState, repair bool, report *knowledge.StoreReport, record func(knowledge.Discrepancy)) error {
+	if !c.cache.enabled || c.cache.client == nil {
+		return nil
+	}
+
+	documentIDs := make([]uint, 0, len(state.Documents))
+	for documentID, doc := range state.Documents {
+		if !doc.Trashed {
+			documentIDs = append(documentIDs, documentID)
+		}
+	}
+	sort.Slice(documentIDs, func(i, j int) bool { return documentIDs[i] < documentIDs[j] })
+
+	for _, documentID := range documentIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		cached, err := c.cache.CachedChunks(ctx, documentID)
+		if err != nil {
+			return err
+		}
+
+		var invalid []uint
+		for chunkID, hash := range cached {
+			// 检查期间新写入的分块
+			if chunkID > state.MaxChunkID {
+				continue
+			}
+			report.Checked++
+			expected, ok := state.Chunks[chunkID]
+			switch {
+			case !ok || expected.DocumentID != documentID || hash == "":
+				report.Orphaned++
+				record(knowledge.Discrepancy{Store: c.Name(), Kind: knowledge.DiscrepancyOrphan, DocumentID: documentID, ChunkID: chunkID})
+			case expected.ContentHash != "" && hash != expected.ContentHash:
+				report.Stale++
+				record(knowledge.Discrepancy{Store: c.Name(), Kind: knowledge.DiscrepancyStale, DocumentID: documentID, ChunkID: chunkID})
+			default:
+				continue
+			}
+			invalid = append(invalid, chunkID)
+		}
+
+		if repair && len(invalid) > 0 {
+			if err := c.cache.RemoveChunks(ctx, documentID, invalid); err != nil {
+				report.RepairFailed += len(invalid)
+				continue
+			}
+			report.Repaired += len(invalid)
+		}
+	}
+	return nil
+}
+
+// ObjectChecker 检查MinIO中的文档源文件、版本快照与上传对象
+// 不属于任何文档的对象修复时删除；源文件缺失无法自动修复，只报告
+type ObjectChecker struct {
+	client         *minio.Client
+	documentBucket string
+	uploadBucket   string
+}
+
+// NewObjectChecker 创建对象存储检查器，桶的含义与trash.NewObjectBackend一致
+func NewObjectChecker(client *minio.Client, documentBucket, uploadBucket string) *ObjectChecker {
+	return &ObjectChecker{client: client, documentBucket: documentBucket, uploadBucket: uploadBucket}
+}
+
+func (c *ObjectChecker) Name() string { return "object" }
+
+func (c *ObjectChecker) Check(ctx context.Context, state *knowledge.ReconcileState, repair bool, report *knowledge.StoreReport, record func(knowledge.Discrepancy)) error {
+	kbPrefix := fmt.Sprintf("knowledge-bases/%d/", state.KnowledgeBaseID)
+	versionPrefix := kbPrefix + "versions/"
+
+	filePaths := make(map[string]bool, len(state.Documents))
+	for _, doc := range state.Documents {
+		if doc.FilePath != "" {
+			filePaths[doc.FilePath] = true
+		}
+	}
+
+	found := make(map[string]bool)
+	err := c.scan(ctx, state, c.documentBucket, kbPrefix, func(key string) bool {
+		report.Checked++
+		if rest, ok := strings.CutPrefix(key, versionPrefix); ok {
+			documentID, _ := parseObjectDocumentID(rest)
+			_, known := state.Documents[documentID]
+			return known
+		}
+		found[key] = true
+		return filePaths[key]
+	}, repair, report, record)
+	if err != nil {
+		return err
+	}
+
+	if c.uploadBucket != "" {
+		err = c.scan(ctx, state, c.uploadBucket, fmt.Sprintf("knowledge/%d/", state.KnowledgeBaseID), func(key string) bool {
+			report.Checked++
+			documentID, _ := parseObjectDocumentID(strings.TrimPrefix(key, fmt.Sprintf("knowledge/%d/", state.KnowledgeBaseID)))
+			_, known := state.Documents[documentID]
+			return known
+		}, repair, report, record)
+		if err != nil {
+			return err
+		}
+	}
+
+	documentIDs := make([]uint, 0, len(state.Documents))
+	for documentID := range state.Documents {
+		documentIDs = append(documentIDs, documentID)
+	}
+	sort.Slice(documentIDs, func(i, j int) bool { return documentIDs[i] < documentIDs[j] })
+	for _, documentID := range documentIDs {
+		doc := state.Documents[documentID]
+		if doc.Trashed || doc.FilePath == "" || !strings.HasPrefix(doc.FilePath, kbPrefix) || found[doc.FilePath] {
+			continue
+		}
+		report.Missing++
+		record(knowledge.Discrepancy{Store: c.Name(), Kind: knowledge.DiscrepancyMissing, DocumentID: documentID, Object: doc.FilePath})
+	}
+	return nil
+}
+
+// scan 列举前缀下的对象，known返回false的对象记为孤立对象，repair为true时删除
+// 读取状态后写入的对象可能属于正在上传的文档，不检查
+func (c *ObjectChecker) scan(ctx context.Context, state *knowledge.ReconcileState, bucket, prefix string, known func(key string) bool, repair bool, report *knowledge.StoreReport, record func(knowledge.Discrepancy)) error {
+	// 提前返回时取消列举，避免列举协程阻塞
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var orphans []string
+	for object := range c.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			if minio.ToErrorResponse(object.Err).Code == "NoSuchBucket" {
+				return nil
+			}
+			return fmt.Errorf("failed to list %s/%s: %w", bucket, prefix, object.Err)
+		}
+		if !state.LoadedAt.IsZero() && object.LastModified.After(state.LoadedAt) {
+			continue
+		}
+		if known(object.Key) {
+			continue
+		}
+		report.Orphaned++
+		record(knowledge.Discrepancy{Store: c.Name(), Kind: knowledge.DiscrepancyOrphan, Object: bucket + "/" + object.Key})
+		orphans = append(orphans, object.Key)
+	}
+
+	if !repair {
+		return nil
+	}
+	for _, key := range orphans {
+		if err := c.client.RemoveObject(ctx, bucket, key, minio.RemoveObjectOptions{}); err != nil {
+			report.RepairFailed++
+			continue
+		}
+		report.Repaired++
+	}
+	return nil
+}
+
+// parseObjectDocumentID 解析"{document_id}"或"{document_id}/..."形式的对象路径
+func parseObjectDocumentID(rest string) (uint, bool) {
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		rest = rest[:i]
+	}
+	id, err := strconv.ParseUint(rest, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aihub/backend-go/internal/errors"
+	"github.com/aihub/backend-go/internal/interfaces"
+	"github.com/aihub/backend-go/internal/knowledge"
+	"github.com/aihub/backend-go/internal/models"
+	"gorm.io/gorm/clause"
+)
+
+// reconcileLease 检查记录保持running的最长时间，进程退出后遗留的记录超过该时间视为失败
+const reconcileLease = 6 * time.Hour
+
+// maxReconcileErrorLength 与error列长度一致
+const maxReconcileErrorLength = 500
+
+// reconcileCheckInterval 定时检查轮询到期知识库的间隔
+const reconcileCheckInterval = 10 * time.Minute
+
+// ReconcileService 知识库跨存储一致性检查服务，检查结果保存在knowledge_reconcile_runs
+type ReconcileService struct {
+	db     interfaces.DatabaseInterface
+	logger interfaces.LoggerInterface
+
+	mu         sync.RWMutex
+	reconciler *knowledge.Reconciler
+}
+
+// NewReconcileService 创建一致性检查服务
+func NewReconcileService(db interfaces.DatabaseInterface, logger interfaces.LoggerInterface) *ReconcileService {
+	return &ReconcileService{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SetReconciler 设置一致性检查器
+func (s *ReconcileService) SetReconciler(reconciler *knowledge.Reconciler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reconciler = reconciler
+}
+
+func (s *ReconcileService) getReconciler() (*knowledge.Reconciler, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.reconciler == nil {
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Reconciler not configured")
+	}
+	return s.reconciler, nil
+}
+
+// Reconcile 检查知识库并保存结果，repair为true时同时修复；同一知识库已有检查在运行时返回冲突
+func (s *ReconcileService) Reconcile(ctx context.Context, kbID uint, repair bool) (*models.KnowledgeReconcileRun, *knowledge.ReconcileReport, error) {
+	reconciler, err := s.getReconciler()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var kb models.KnowledgeBase
+	if err := s.db.GetDB().WithContext(ctx).Where("knowledge_base_id = ?", kbID).First(&kb).Error; err != nil {
+		return nil, nil, errors.NewNotFoundError("knowledge base")
+	}
+
+	run := models.KnowledgeReconcileRun{
+		KnowledgeBaseID: kbID,
+		Repair:          repair,
+		Status:          models.ReconcileRunning,
+		StartedAt:       time.Now(),
+	}
+	result := s.db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&run)
+	if result.Error != nil {
+		s.logger.Error("Failed to create reconcile run", "error", result.Error, "kbID", kbID)
+		return nil, nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to create reconcile run").WithCause(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil, errors.NewBusinessError(errors.ErrCodeConflict, "Reconcile already running for this knowledge base")
+	}
+
+	report, runErr := reconciler.Run(ctx, kbID, repair)
+	s.finish(&run, report, runErr)
+	if runErr != nil {
+		s.logger.Error("Reconcile failed", "error", runErr, "kbID", kbID, "runID", run.RunID)
+		return &run, report, errors.NewSystemError(errors.ErrCodeOperationFailed, "Reconcile failed").WithCause(runErr)
+	}
+
+	s.logger.Info("Reconcile finished", "kbID", kbID, "runID", run.RunID, "repair", repair,
+		"chunks", report.Chunks, "discrepancies", run.Discrepancies, "repaired", run.Repaired, "status", run.Status)
+	return &run, report, nil
+}
+
+// finish 保存检查结果，ctx取消后仍需写入，因此不使用请求的ctx
+func (s *ReconcileService) finish(run *models.KnowledgeReconcileRun, report *knowledge.ReconcileReport, runErr error) {
+	now := time.Now()
+	run.FinishedAt = &now
+	run.Status = models.ReconcileSucceeded
+
+	var messages []string
+	if report != nil {
+		encoded, _ := json.Marshal(report)
+		run.Report = string(encoded)
+		run.Discrepancies = report.TotalDiscrepancies()
+		run.Repaired = report.TotalRepaired()
+		messages = report.Errors()
+	}
+	if runErr != nil {
+		messages = append([]string{runErr.Error()}, messages...)
+	}
+	if len(messages) > 0 {
+		run.Status = models.ReconcileFailed
+		run.Error = strings.Join(messages, "; ")
+		if len(run.Error) > maxReconcileErrorLength {
+			run.Error = strings.ToValidUTF8(run.Error[:maxReconcileErrorLength], "")
+		}
+	}
+
+	err := s.db.GetDB().Model(&models.KnowledgeReconcileRun{}).
+		Where("run_id = ?", run.RunID).
+		Updates(map[string]interface{}{
+			"status":        run.Status,
+			"discrepancies": run.Discrepancies,
+			"repaired":      run.Repaired,
+			"report":        run.Report,
+			"error":         run.Error,
+			"finished_at":   run.FinishedAt,
+		}).Error
+	if err != nil {
+		s.logger.Error("Failed to save reconcile run", "error", err, "runID", run.RunID)
+	}
+}
+
+// KnowledgeBaseIDs 全部未删除的知识库
+func (s *ReconcileService) KnowledgeBaseIDs(ctx context.Context) ([]uint, error) {
+	var ids []uint
+	err := s.db.GetDB().WithContext(ctx).Model(&models.KnowledgeBase{}).
+		Order("knowledge_base_id").
+		Pluck("knowledge_base_id", &ids).Error
+	if err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to list knowledge bases").WithCause(err)
+	}
+	return ids, nil
+}
+
+// RunDue 检查interval内没有检查记录的知识库，返回检查的知识库数量
+func (s *ReconcileService) RunDue(ctx context.Context, interval time.Duration, repair bool) (int, error) {
+	db := s.db.GetDB().WithContext(ctx)
+	now := time.Now()
+
+	// 进程退出后遗留的running记录会阻止后续检查
+	err := db.Model(&models.KnowledgeReconcileRun{}).
+		Where("status = ? AND started_at < ?", models.ReconcileRunning, now.Add(-reconcileLease)).
+		Updates(map[string]interface{}{"status": models.ReconcileFailed, "error": "lease expired", "finished_at": now}).Error
+	if err != nil {
+		return 0, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to expire reconcile runs").WithCause(err)
+	}
+
+	var ids []uint
+	err = db.Model(&models.KnowledgeBase{}).
+		Where("NOT EXISTS (SELECT 1 FROM knowledge_reconcile_runs r WHERE r.knowledge_base_id = knowledge_bases.knowledge_base_id AND r.started_at > ?)", now.Add(-interval)).
+		Order("knowledge_base_id").
+		Pluck("knowledge_base_id", &ids).Error
+	if err != nil {
+		return 0, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to find due knowledge bases").WithCause(err)
+	}
+
+	checked := 0
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return checked, ctx.Err()
+		}
+		// 单个知识库失败已记录在检查记录中，继续检查其他知识库
+		if _, _, err := s.Reconcile(ctx, id, repair); err == nil {
+			checked++
+		}
+	}
+	return checked, nil
+}
+
+// RunSchedule 定时检查到期的知识库，直到ctx取消
+func (s *ReconcileService) RunSchedule(ctx context.Context, interval time.Duration, repair bool) {
+	ticker := time.NewTicker(reconcileCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		n, err := s.RunDue(ctx, interval, repair)
+		if err != nil && ctx.Err() == nil {
+			s.logger.Warn("Scheduled reconcile failed", "error", err)
+			continue
+		}
+		if n > 0 {
+			s.logger.Info("Scheduled reconcile finished", "knowledgeBases", n)
+		}
+	}
+}
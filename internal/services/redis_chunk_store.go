@@ -420,6 +420,55 @@ func (r *RedisChunkStore) DeleteDocumentChunks(ctx context.Context, documentID u
 	return r.client.Del(ctx, docChunksKey).Err()
 }
 
+// CachedChunks 获取文档已缓存分块的内容哈希，分块数据已过期但仍在列表中的哈希为空
+func (r *RedisChunkStore) CachedChunks(ctx context.Context, documentID uint) (map[uint]string, error) {
+	chunkIDs, err := r.GetChunksByDocument(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := r.client.Pipeline()
+	cmds := make(map[uint]*redis.StringCmd, len(chunkIDs))
+	for _, chunkID := range chunkIDs {
+		cmds[chunkID] = pipe.HGet(ctx, r.chunkKey(documentID, chunkID), "content")
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to get cached chunks: %w", err)
+	}
+
+	result := make(map[uint]string, len(chunkIDs))
+	for chunkID, cmd := range cmds {
+		content, err := cmd.Result()
+		if err == redis.Nil {
+			result[chunkID] = ""
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get cached chunk: %w", err)
+		}
+		result[chunkID] = calculateChunkHash(content)
+	}
+	return result, nil
+}
+
+// RemoveChunks 删除文档的指定分块缓存
+func (r *RedisChunkStore) RemoveChunks(ctx context.Context, documentID uint, chunkIDs []uint) error {
+	if !r.enabled || r.client == nil || len(chunkIDs) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(chunkIDs))
+	members := make([]interface{}, len(chunkIDs))
+	for i, chunkID := range chunkIDs {
+		keys[i] = r.chunkKey(documentID, chunkID)
+		members[i] = chunkID
+	}
+	if err := r.client.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("failed to delete chunks: %w", err)
+	}
+	return r.client.SRem(ctx, r.documentChunksKey(documentID), members...).Err()
+}
+
 // chunkKey 生成分块Redis键
 func (r *RedisChunkStore) chunkKey(documentID, chunkID uint) string {
 	return fmt.Sprintf("chunk:%d:%d", documentID, chunkID)
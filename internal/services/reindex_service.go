@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"sync"
+
+	"github.com/aihub/backend-go/internal/errors"
+	"github.com/aihub/backend-go/internal/interfaces"
+	"github.com/aihub/backend-go/internal/knowledge"
+	"github.com/aihub/backend-go/internal/models"
+)
+
+// ReindexService 知识库索引重建服务（管理员接口）
+type ReindexService struct {
+	db     interfaces.DatabaseInterface
+	logger interfaces.LoggerInterface
+
+	mu        sync.RWMutex
+	reindexer *knowledge.Reindexer
+	router    *knowledge.EmbeddingRouter
+}
+
+// NewReindexService 创建索引重建服务
+func NewReindexService(db interfaces.DatabaseInterface, logger interfaces.LoggerInterface) *ReindexService {
+	return &ReindexService{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SetBackends 设置重建使用的全文索引、向量存储与向量化调度器，scheduler为nil时只能重建全文索引
+func (s *ReindexService) SetBackends(indexer knowledge.FulltextIndexer, store knowledge.VectorStore, scheduler *knowledge.EmbedScheduler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reindexer = knowledge.NewReindexer(s.db.GetDB(), indexer, store, scheduler)
+	if s.router != nil {
+		s.reindexer.SetEmbeddingRouter(s.router)
+	}
+}
+
+// SetEmbeddingRouter 设置嵌入模型路由，设置后支持按知识库迁移嵌入模型
+func (s *ReindexService) SetEmbeddingRouter(router *knowledge.EmbeddingRouter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.router = router
+	if s.reindexer != nil {
+		s.reindexer.SetEmbeddingRouter(router)
+	}
+}
+
+// EmbeddingProfileInfo 知识库嵌入模型信息
+type EmbeddingProfileInfo struct {
+	Profile   knowledge.EmbeddingProfile  `json:"profile"`
+	Migrating *knowledge.EmbeddingProfile `json:"migrating,omitempty"` // 迁移中的目标模型
+}
+
+func (s *ReindexService) getReindexer() (*knowledge.Reindexer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.reindexer == nil {
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Reindex backends not configured")
+	}
+	return s.reindexer, nil
+}
+
+// StartReindex 启动知识库索引重建
+func (s *ReindexService) StartReindex(ctx context.Context, req knowledge.ReindexRequest) (*knowledge.ReindexJob, error) {
+	reindexer, err := s.getReindexer()
+	if err != nil {
+		return nil, err
+	}
+
+	var kb models.KnowledgeBase
+	if err := s.db.GetDB().WithContext(ctx).First(&kb, req.KnowledgeBaseID).Error; err != nil {
+		return nil, errors.NewNotFoundError("knowledge base")
+	}
+
+	job, err := reindexer.Start(req)
+	if err != nil {
+		if stderrors.Is(err, knowledge.ErrReindexInProgress) {
+			return &job, errors.NewBusinessError(errors.ErrCodeConflict, "Reindex already in progress").WithCause(err)
+		}
+		return nil, errors.NewBusinessError(errors.ErrCodeInvalidInput, err.Error()).WithCause(err)
+	}
+
+	s.logger.Info("Reindex started", "kbID", req.KnowledgeBaseID, "jobID", job.ID,
+		"fulltext", job.Request.Fulltext, "vector", job.Request.Vector, "reembed", job.Request.Reembed)
+	return &job, nil
+}
+
+// GetEmbeddingProfile 获取知识库记录的嵌入模型，未记录时返回全局配置的模型
+func (s *ReindexService) GetEmbeddingProfile(ctx context.Context, kbID uint) (*EmbeddingProfileInfo, error) {
+	s.mu.RLock()
+	router := s.router
+	s.mu.RUnlock()
+	if router == nil {
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Embedding router not configured")
+	}
+
+	var kb models.KnowledgeBase
+	if err := s.db.GetDB().WithContext(ctx).First(&kb, kbID).Error; err != nil {
+		return nil, errors.NewNotFoundError("knowledge base")
+	}
+
+	info := &EmbeddingProfileInfo{Profile: knowledge.EmbeddingProfile{
+		Provider:   kb.EmbeddingProvider,
+		Model:      kb.EmbeddingModel,
+		Dimensions: kb.EmbeddingDimensions,
+	}}
+	if info.Profile.IsZero() {
+		info.Profile = router.Fallback()
+	}
+	if target, ok := router.MigrationTarget(ctx, kbID); ok {
+		info.Migrating = &target
+	}
+	return info, nil
+}
+
+// MigrateEmbedding 将知识库的向量迁移到新的嵌入模型
+// 迁移期间检索双读新旧两个版本，完成后切换别名并记录新模型
+func (s *ReindexService) MigrateEmbedding(ctx context.Context, kbID uint, target knowledge.EmbeddingProfile) (*knowledge.ReindexJob, error) {
+	if target.IsZero() {
+		return nil, errors.NewValidationError("Embedding provider and model are required")
+	}
+	s.mu.RLock()
+	router := s.router
+	s.mu.RUnlock()
+	if router == nil {
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Embedding router not configured")
+	}
+
+	current, err := router.Profile(ctx, kbID)
+	if err == nil && current.String() == target.String() {
+		return nil, errors.NewBusinessError(errors.ErrCodeInvalidInput, "Knowledge base already uses this embedding model")
+	}
+
+	job, err := s.StartReindex(ctx, knowledge.ReindexRequest{
+		KnowledgeBaseID: kbID,
+		Embedding:       &target,
+	})
+	if err != nil {
+		return job, err
+	}
+	s.logger.Info("Embedding migration started", "kbID", kbID, "from", current.String(), "to", target.String())
+	return job, nil
+}
+
+// GetReindexStatus 获取知识库最近一次重建任务的进度
+func (s *ReindexService) GetReindexStatus(kbID uint) (*knowledge.ReindexJob, error) {
+	reindexer, err := s.getReindexer()
+	if err != nil {
+		return nil, err
+	}
+	job, err := reindexer.Status(kbID)
+	if err != nil {
+		return nil, errors.NewNotFoundError("reindex job")
+	}
+	return &job, nil
+}
+
+// CancelReindex 取消运行中的重建任务，别名保持指向旧版本
+func (s *ReindexService) CancelReindex(kbID uint) error {
+	reindexer, err := s.getReindexer()
+	if err != nil {
+		return err
+	}
+	if err := reindexer.Cancel(kbID); err != nil {
+		return errors.NewNotFoundError("running reindex job")
+	}
+	s.logger.Info("Reindex cancelled", "kbID", kbID)
+	return nil
+}
@@ -2,11 +2,14 @@ package services
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
+	"time"
 
 	"github.com/aihub/backend-go/internal/errors"
 	"github.com/aihub/backend-go/internal/interfaces"
 	"github.com/aihub/backend-go/internal/knowledge"
+	"github.com/aihub/backend-go/internal/models"
 )
 
 // SearchService 搜索服务
@@ -14,6 +17,9 @@ type SearchService struct {
 	db           interfaces.DatabaseInterface
 	logger       interfaces.LoggerInterface
 	searchEngine *knowledge.HybridSearchEngine
+
+	// 按历史版本检索（可选）
+	versionService *DocumentVersionService
 }
 
 // SearchResult 搜索结果
@@ -34,7 +40,8 @@ func NewSearchService(db interfaces.DatabaseInterface, logger interfaces.LoggerI
 	}
 }
 
-// SearchAllKnowledgeBases 在所有知识库中搜索
+// SearchAllKnowledgeBases 在所有知识库中搜索，按知识库分组返回，各组得分不可比较
+// Deprecated: 使用FederatedSearch
 func (s *SearchService) SearchAllKnowledgeBases(ctx context.Context, userID uint, query string, topK int, mode string, vectorThreshold float64) ([]interface{}, error) {
 	// 获取用户的所有知识库
 	kbService := NewKnowledgeBaseService(s.db, s.logger)
@@ -61,6 +68,108 @@ func (s *SearchService) SearchAllKnowledgeBases(ctx context.Context, userID uint
 	return allResults, nil
 }
 
+// FederatedSearchRequest 跨知识库检索请求
+type FederatedSearchRequest struct {
+	Query            string  `json:"query"`
+	KnowledgeBaseIDs []uint  `json:"knowledge_base_ids"` // 为空时检索用户的全部知识库
+	TopK             int     `json:"top_k"`
+	Mode             string  `json:"mode"`
+	VectorThreshold  float64 `json:"vector_threshold"`
+	TimeoutMs        int     `json:"timeout_ms"`    // 单个知识库的超时
+	Normalization    string  `json:"normalization"` // minmax | rrf | rerank
+}
+
+// FederatedSearch 跨知识库检索：并发检索用户可访问的知识库，得分统一归一化后合并
+// 返回合并后的结果与各知识库的统计；单个知识库不可用时不影响其他知识库
+func (s *SearchService) FederatedSearch(ctx context.Context, userID uint, req FederatedSearchRequest) (map[string]interface{}, error) {
+	if s.searchEngine == nil {
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Search engine not configured")
+	}
+	switch req.Normalization {
+	case "", knowledge.FederatedNormalizeMinMax, knowledge.FederatedNormalizeRRF, knowledge.FederatedNormalizeRerank:
+	default:
+		return nil, errors.NewValidationError("Unsupported normalization: " + req.Normalization)
+	}
+	if req.TopK <= 0 {
+		req.TopK = 10
+	}
+	if req.Mode == "" {
+		req.Mode = "hybrid"
+	}
+	if req.VectorThreshold == 0 {
+		req.VectorThreshold = 0.5
+	}
+
+	// 只检索用户拥有的知识库，请求中无权访问的知识库忽略
+	var knowledgeBases []models.KnowledgeBase
+	query := s.db.GetDB().WithContext(ctx).Select("knowledge_base_id, name").Where("owner_id = ?", userID)
+	if len(req.KnowledgeBaseIDs) > 0 {
+		query = query.Where("knowledge_base_id IN ?", req.KnowledgeBaseIDs)
+	}
+	if err := query.Order("knowledge_base_id").Find(&knowledgeBases).Error; err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to retrieve knowledge bases").WithCause(err)
+	}
+	if len(knowledgeBases) == 0 {
+		return nil, errors.NewNotFoundError("knowledge base")
+	}
+
+	kbIDs := make([]uint, len(knowledgeBases))
+	names := make(map[uint]string, len(knowledgeBases))
+	for i, kb := range knowledgeBases {
+		kbIDs[i] = kb.KnowledgeBaseID
+		names[kb.KnowledgeBaseID] = kb.Name
+	}
+
+	result, err := s.searchEngine.FederatedSearch(ctx, knowledge.FederatedSearchRequest{
+		KnowledgeBaseIDs: kbIDs,
+		Query:            req.Query,
+		Limit:            req.TopK,
+		Mode:             req.Mode,
+		VectorThreshold:  req.VectorThreshold,
+		Timeout:          time.Duration(req.TimeoutMs) * time.Millisecond,
+		Normalization:    req.Normalization,
+	})
+	if err != nil {
+		s.logger.Error("Federated search failed", "error", err, "userID", userID, "bases", len(kbIDs))
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Search engine failed").WithCause(err)
+	}
+
+	results := make([]map[string]interface{}, 0, len(result.Matches))
+	for _, match := range result.Matches {
+		results = append(results, map[string]interface{}{
+			"knowledge_base_id":   match.KnowledgeBaseID,
+			"knowledge_base_name": names[match.KnowledgeBaseID],
+			"document_id":         match.DocumentID,
+			"chunk_id":            match.ChunkID,
+			"content":             match.Content,
+			"score":               match.Score,
+			"raw_score":           match.RawScore,
+			"metadata":            match.Metadata,
+		})
+	}
+	facets := make([]map[string]interface{}, 0, len(result.Facets))
+	for _, facet := range result.Facets {
+		facets = append(facets, map[string]interface{}{
+			"knowledge_base_id":   facet.KnowledgeBaseID,
+			"knowledge_base_name": names[facet.KnowledgeBaseID],
+			"status":              facet.Status,
+			"error":               facet.Error,
+			"candidates":          facet.Candidates,
+			"returned":            facet.Returned,
+			"took_ms":             facet.TookMs,
+		})
+	}
+
+	s.logger.Info("Federated search completed", "userID", userID, "query", req.Query,
+		"bases", len(kbIDs), "results", len(results), "normalization", result.Normalization)
+	return map[string]interface{}{
+		"query":         req.Query,
+		"results":       results,
+		"facets":        facets,
+		"normalization": result.Normalization,
+	}, nil
+}
+
 // SearchKnowledgeBase 在指定知识库中搜索
 func (s *SearchService) SearchKnowledgeBase(ctx context.Context, kbID, userID uint, query string, topK int, mode string, vectorThreshold float64) ([]interface{}, error) {
 	// 验证知识库权限
@@ -84,20 +193,168 @@ func (s *SearchService) SearchKnowledgeBase(ctx context.Context, kbID, userID ui
 		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Search engine failed").WithCause(err)
 	}
 
-	// 转换结果格式
+	results := toSearchResults(matches)
+	s.logger.Info("Search completed in knowledge base", "kbID", kbID, "userID", userID, "query", query, "results", len(results))
+	return results, nil
+}
+
+// KnowledgeSearchRequest 带对话历史与查询转换选项的检索请求
+type KnowledgeSearchRequest struct {
+	Query           string                           `json:"query"`
+	TopK            int                              `json:"top_k"`
+	Mode            string                           `json:"mode"`
+	VectorThreshold float64                          `json:"vector_threshold"`
+	History         []knowledge.ChatTurn             `json:"history"`
+	Transform       *knowledge.QueryTransformOptions `json:"transform"` // 为空时使用知识库配置
+	Diversity       *knowledge.DiversityOptions      `json:"diversity"` // 为空时不做多样化
+}
+
+// SearchKnowledgeBaseWithContext 结合对话历史检索知识库，返回结果与实际使用的查询
+func (s *SearchService) SearchKnowledgeBaseWithContext(ctx context.Context, kbID, userID uint, req KnowledgeSearchRequest) (map[string]interface{}, error) {
+	if s.searchEngine == nil {
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Search engine not configured")
+	}
+	kbService := NewKnowledgeBaseService(s.db, s.logger)
+	if _, err := kbService.GetKnowledgeBase(kbID, userID); err != nil {
+		return nil, fmt.Errorf("knowledge base access denied: %w", err)
+	}
+	if req.TopK <= 0 {
+		req.TopK = 10
+	}
+	if req.Mode == "" {
+		req.Mode = "hybrid"
+	}
+	if req.VectorThreshold == 0 {
+		req.VectorThreshold = 0.5
+	}
+
+	matches, transformed, err := s.searchEngine.SearchTransformed(ctx, knowledge.HybridSearchRequest{
+		KnowledgeBaseID: kbID,
+		Query:           req.Query,
+		Limit:           req.TopK,
+		Mode:            req.Mode,
+		VectorThreshold: req.VectorThreshold,
+		History:         req.History,
+		Transform:       req.Transform,
+		Diversity:       req.Diversity,
+	})
+	if err != nil {
+		s.logger.Error("Search engine error", "error", err, "kbID", kbID, "query", req.Query)
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Search engine failed").WithCause(err)
+	}
+
+	results := toSearchResults(matches)
+	s.logger.Info("Search completed in knowledge base", "kbID", kbID, "userID", userID,
+		"query", req.Query, "standalone", transformed.Standalone, "variants", len(transformed.Variants), "results", len(results))
+	return map[string]interface{}{
+		"results":     results,
+		"query":       req.Query,
+		"transformed": transformed,
+	}, nil
+}
+
+// KnowledgeSearchPageRequest 分页检索请求，翻页时传入上一页返回的next_cursor与相同的查询
+type KnowledgeSearchPageRequest struct {
+	Query           string
+	Mode            string
+	VectorThreshold float64
+	PageSize        int
+	Cursor          string
+	Facets          []string // document | file_type | source | tag
+}
+
+// SearchKnowledgeBasePage 分页检索知识库，返回当前页、下一页游标、命中总数估计与分面统计
+func (s *SearchService) SearchKnowledgeBasePage(ctx context.Context, kbID, userID uint, req KnowledgeSearchPageRequest) (map[string]interface{}, error) {
+	if s.searchEngine == nil {
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Search engine not configured")
+	}
+	for _, field := range req.Facets {
+		if !knowledge.ValidFacetField(field) {
+			return nil, errors.NewValidationError("Unsupported facet: " + field)
+		}
+	}
+	kbService := NewKnowledgeBaseService(s.db, s.logger)
+	if _, err := kbService.GetKnowledgeBase(kbID, userID); err != nil {
+		return nil, fmt.Errorf("knowledge base access denied: %w", err)
+	}
+	if req.Mode == "" {
+		req.Mode = "hybrid"
+	}
+	if req.VectorThreshold == 0 {
+		req.VectorThreshold = 0.5
+	}
+
+	page, err := s.searchEngine.SearchPage(ctx, knowledge.PageRequest{
+		HybridSearchRequest: knowledge.HybridSearchRequest{
+			KnowledgeBaseID: kbID,
+			Query:           req.Query,
+			Mode:            req.Mode,
+			VectorThreshold: req.VectorThreshold,
+		},
+		PageSize: req.PageSize,
+		Cursor:   req.Cursor,
+		Facets:   req.Facets,
+	})
+	switch {
+	case stderrors.Is(err, knowledge.ErrInvalidCursor):
+		return nil, errors.NewValidationError("Invalid cursor")
+	case stderrors.Is(err, knowledge.ErrCursorExpired):
+		return nil, errors.NewValidationError("Cursor expired, please search again")
+	case err != nil:
+		s.logger.Error("Search engine error", "error", err, "kbID", kbID, "query", req.Query)
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Search engine failed").WithCause(err)
+	}
+
+	facets := page.Facets
+	if facets == nil {
+		facets = map[string][]knowledge.FacetCount{}
+	}
+	return map[string]interface{}{
+		"results":     toSearchResults(page.Matches),
+		"query":       req.Query,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor,
+		"total": map[string]interface{}{
+			"value":    page.Total,
+			"relation": page.TotalRelation,
+		},
+		"facets": facets,
+	}, nil
+}
+
+// SetVersionService 设置文档版本服务，设置后支持在文档历史版本上检索
+func (s *SearchService) SetVersionService(versionService *DocumentVersionService) {
+	s.versionService = versionService
+}
+
+// SearchDocumentAsOfVersion 在文档的历史版本上检索，用于审计
+func (s *SearchService) SearchDocumentAsOfVersion(ctx context.Context, kbID, userID, docID uint, version int, query string, topK int) (map[string]interface{}, error) {
+	if s.versionService == nil {
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Version search not configured")
+	}
+	matches, err := s.versionService.SearchAsOfVersion(ctx, kbID, docID, userID, version, query, topK)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"results":       toSearchResults(matches),
+		"query":         query,
+		"document_id":   docID,
+		"as_of_version": version,
+	}, nil
+}
+
+func toSearchResults(matches []knowledge.SearchMatch) []interface{} {
 	var results []interface{}
 	for _, match := range matches {
-		result := map[string]interface{}{
+		results = append(results, map[string]interface{}{
 			"document_id": match.DocumentID,
 			"content":     match.Content,
 			"score":       match.Score,
 			"metadata":    match.Metadata,
-		}
-		results = append(results, result)
+		})
 	}
-
-	s.logger.Info("Search completed in knowledge base", "kbID", kbID, "userID", userID, "query", query, "results", len(results))
-	return results, nil
+	return results
 }
 
 // GetCacheStats 获取缓存统计信息
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/aihub/backend-go/internal/errors"
+	"github.com/aihub/backend-go/internal/interfaces"
+	"github.com/aihub/backend-go/internal/models"
+	"github.com/aihub/backend-go/internal/transfer"
+)
+
+// TransferService 知识库导出与归档导入服务，导入以后台任务执行
+type TransferService struct {
+	db     interfaces.DatabaseInterface
+	logger interfaces.LoggerInterface
+
+	mu       sync.RWMutex
+	exporter *transfer.Exporter
+	importer *transfer.Importer
+	archives transfer.ArchiveStore
+	runner   *transfer.Runner
+	maxSize  int64 // 上传归档的大小上限，0表示不限制
+}
+
+// NewTransferService 创建导出导入服务
+func NewTransferService(db interfaces.DatabaseInterface, logger interfaces.LoggerInterface) *TransferService {
+	return &TransferService{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SetTransfer 设置导出器、导入器、归档存储与导入任务执行器
+func (s *TransferService) SetTransfer(exporter *transfer.Exporter, importer *transfer.Importer, archives transfer.ArchiveStore, runner *transfer.Runner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exporter = exporter
+	s.importer = importer
+	s.archives = archives
+	s.runner = runner
+}
+
+// SetMaxArchiveSize 设置上传归档的大小上限（字节），0表示不限制
+func (s *TransferService) SetMaxArchiveSize(size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxSize = size
+}
+
+func (s *TransferService) configured() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.exporter == nil || s.importer == nil || s.archives == nil || s.runner == nil {
+		return errors.NewSystemError(errors.ErrCodeExternalService, "Knowledge base transfer not configured")
+	}
+	return nil
+}
+
+// Export 把用户自己的知识库导出为归档写入w，includeEmbeddings为true时包含向量
+func (s *TransferService) Export(ctx context.Context, kbID, userID uint, includeEmbeddings bool, w io.Writer) (transfer.Manifest, error) {
+	if err := s.configured(); err != nil {
+		return transfer.Manifest{}, err
+	}
+	var count int64
+	err := s.db.GetDB().WithContext(ctx).Model(&models.KnowledgeBase{}).
+		Where("knowledge_base_id = ? AND owner_id = ?", kbID, userID).
+		Count(&count).Error
+	if err != nil {
+		return transfer.Manifest{}, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to load knowledge base").WithCause(err)
+	}
+	if count == 0 {
+		return transfer.Manifest{}, errors.NewNotFoundError("knowledge base")
+	}
+
+	manifest, err := s.exporter.Export(ctx, kbID, includeEmbeddings, w)
+	if err != nil {
+		s.logger.Error("Failed to export knowledge base", "error", err, "kbID", kbID)
+		return manifest, errors.NewSystemError(errors.ErrCodeOperationFailed, "Failed to export knowledge base").WithCause(err)
+	}
+	s.logger.Info("Knowledge base exported", "kbID", kbID, "documents", manifest.Documents, "chunks", manifest.Chunks)
+	return manifest, nil
+}
+
+// CreateImport 保存上传的归档并创建导入任务，归档无效时返回校验错误；name为空时使用归档中的知识库名称
+func (s *TransferService) CreateImport(ctx context.Context, userID uint, name, archiveName string, r io.Reader, size int64) (*models.KnowledgeImportJob, error) {
+	if err := s.configured(); err != nil {
+		return nil, err
+	}
+	if len(name) > 200 {
+		return nil, errors.NewValidationError("name must be at most 200 characters")
+	}
+	s.mu.RLock()
+	maxSize := s.maxSize
+	s.mu.RUnlock()
+	if maxSize > 0 && size > maxSize {
+		return nil, errors.NewValidationError(fmt.Sprintf("archive must be at most %d MB", maxSize>>20))
+	}
+
+	key := fmt.Sprintf("knowledge-imports/%d/%d.zip", userID, time.Now().UnixNano())
+	if err := s.archives.Save(ctx, key, r, size); err != nil {
+		s.logger.Error("Failed to save import archive", "error", err, "userID", userID)
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Failed to save archive").WithCause(err)
+	}
+
+	manifest, _, err := s.importer.Inspect(ctx, key)
+	if err != nil {
+		s.removeArchive(ctx, key)
+		if stderrors.Is(err, transfer.ErrInvalidArchive) {
+			return nil, errors.NewValidationError(err.Error())
+		}
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Failed to read archive").WithCause(err)
+	}
+
+	now := time.Now()
+	job := models.KnowledgeImportJob{
+		OwnerID:               userID,
+		ArchiveKey:            key,
+		ArchiveName:           archiveName,
+		Name:                  name,
+		SourceKnowledgeBaseID: manifest.SourceKnowledgeBaseID,
+		Status:                models.ImportPending,
+		Stage:                 models.ImportStagePrepare,
+		TotalDocuments:        manifest.Documents,
+		TotalChunks:           manifest.Chunks,
+		CreateTime:            now,
+		UpdateTime:            now,
+	}
+	if err := s.db.GetDB().WithContext(ctx).Create(&job).Error; err != nil {
+		s.removeArchive(ctx, key)
+		s.logger.Error("Failed to create import job", "error", err, "userID", userID)
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to create import job").WithCause(err)
+	}
+
+	s.runner.Wake()
+	s.logger.Info("Import job created", "jobID", job.JobID, "userID", userID, "documents", manifest.Documents)
+	return &job, nil
+}
+
+func (s *TransferService) removeArchive(ctx context.Context, key string) {
+	if err := s.archives.Remove(ctx, key); err != nil {
+		s.logger.Warn("Failed to remove import archive", "error", err, "key", key)
+	}
+}
+
+// GetImport 获取用户的导入任务
+func (s *TransferService) GetImport(ctx context.Context, jobID, userID uint) (*models.KnowledgeImportJob, error) {
+	var job models.KnowledgeImportJob
+	err := s.db.GetDB().WithContext(ctx).
+		Where("job_id = ? AND owner_id = ?", jobID, userID).
+		First(&job).Error
+	if err != nil {
+		return nil, errors.NewNotFoundError("import job")
+	}
+	return &job, nil
+}
+
+// ResumeImport 让失败的导入任务从断点重新执行
+func (s *TransferService) ResumeImport(ctx context.Context, jobID, userID uint) (*models.KnowledgeImportJob, error) {
+	if err := s.configured(); err != nil {
+		return nil, err
+	}
+	job, err := s.GetImport(ctx, jobID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := s.db.GetDB().WithContext(ctx).Model(&models.KnowledgeImportJob{}).
+		Where("job_id = ? AND status = ?", jobID, models.ImportFailed).
+		Updates(map[string]interface{}{
+			"status":      models.ImportPending,
+			"error":       "",
+			"finished_at": nil,
+			"update_time": time.Now(),
+		})
+	if result.Error != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to resume import job").WithCause(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.NewBusinessError(errors.ErrCodeConflict, fmt.Sprintf("Import job is %s, only failed jobs can be resumed", job.Status))
+	}
+
+	s.runner.Wake()
+	s.logger.Info("Import job resumed", "jobID", jobID, "stage", job.Stage)
+	return s.GetImport(ctx, jobID, userID)
+}
+
+// RunImport 在当前进程中执行导入任务直到结束，任务正由其他副本执行时返回冲突
+func (s *TransferService) RunImport(ctx context.Context, jobID uint) (*models.KnowledgeImportJob, error) {
+	if err := s.configured(); err != nil {
+		return nil, err
+	}
+	claimed, err := s.runner.RunJob(ctx, jobID)
+	if err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeOperationFailed, "Failed to run import job").WithCause(err)
+	}
+
+	var job models.KnowledgeImportJob
+	if err := s.db.GetDB().WithContext(ctx).Where("job_id = ?", jobID).First(&job).Error; err != nil {
+		return nil, errors.NewNotFoundError("import job")
+	}
+	if !claimed && job.Status == models.ImportRunning {
+		return &job, errors.NewBusinessError(errors.ErrCodeConflict, "Import job is running in another process")
+	}
+	return &job, nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+
+	"github.com/aihub/backend-go/internal/errors"
+	"github.com/aihub/backend-go/internal/interfaces"
+	"github.com/aihub/backend-go/internal/models"
+	"github.com/aihub/backend-go/internal/trash"
+	"gorm.io/gorm"
+)
+
+// TrashService 回收站查询、恢复与立即清理
+type TrashService struct {
+	db     interfaces.DatabaseInterface
+	logger interfaces.LoggerInterface
+}
+
+// NewTrashService 创建回收站服务
+func NewTrashService(db interfaces.DatabaseInterface, logger interfaces.LoggerInterface) *TrashService {
+	return &TrashService{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// ListTrash 列出用户回收站中待清理的条目，kbID为0时列出全部知识库
+func (s *TrashService) ListTrash(ctx context.Context, userID, kbID uint) ([]models.KnowledgePurgeTask, error) {
+	query := s.ownedTasks(ctx, userID).
+		Where("knowledge_purge_tasks.status = ?", models.PurgePending)
+	if kbID > 0 {
+		query = query.Where("knowledge_purge_tasks.knowledge_base_id = ?", kbID)
+	}
+
+	var tasks []models.KnowledgePurgeTask
+	if err := query.Order("knowledge_purge_tasks.create_time DESC").Find(&tasks).Error; err != nil {
+		s.logger.Error("Failed to list trash", "error", err, "userID", userID)
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to list trash").WithCause(err)
+	}
+	return tasks, nil
+}
+
+// Restore 恢复回收站中的条目，清理开始后不能再恢复
+func (s *TrashService) Restore(ctx context.Context, taskID, userID uint) (*models.KnowledgePurgeTask, error) {
+	if err := s.checkOwner(ctx, taskID, userID); err != nil {
+		return nil, err
+	}
+
+	var restored *models.KnowledgePurgeTask
+	err := s.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		restored, err = trash.Restore(tx, taskID)
+		return err
+	})
+	switch {
+	case stderrors.Is(err, trash.ErrNotFound):
+		return nil, errors.NewNotFoundError("trash item")
+	case stderrors.Is(err, trash.ErrPurgeStarted):
+		return nil, errors.NewValidationError("trash item is being purged and can no longer be restored")
+	case stderrors.Is(err, trash.ErrParentTrashed):
+		return nil, errors.NewValidationError("knowledge base is in trash, restore it first")
+	case err != nil:
+		s.logger.Error("Failed to restore trash item", "error", err, "taskID", taskID)
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to restore trash item").WithCause(err)
+	}
+
+	s.logger.Info("Trash item restored", "taskID", taskID, "type", restored.ItemType,
+		"kbID", restored.KnowledgeBaseID, "docID", restored.DocumentID, "userID", userID)
+	return restored, nil
+}
+
+// PurgeNow 跳过剩余的保留期，由后台清理任务尽快删除
+func (s *TrashService) PurgeNow(ctx context.Context, taskID, userID uint) error {
+	if err := s.checkOwner(ctx, taskID, userID); err != nil {
+		return err
+	}
+
+	err := trash.PurgeNow(s.db.GetDB().WithContext(ctx), taskID)
+	if stderrors.Is(err, trash.ErrNotFound) {
+		return errors.NewNotFoundError("trash item")
+	}
+	if err != nil {
+		s.logger.Error("Failed to schedule purge", "error", err, "taskID", taskID)
+		return errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to schedule purge").WithCause(err)
+	}
+
+	s.logger.Info("Trash item scheduled for purge", "taskID", taskID, "userID", userID)
+	return nil
+}
+
+// ownedTasks 用户所拥有知识库的清理任务，知识库本身可能也在回收站中
+func (s *TrashService) ownedTasks(ctx context.Context, userID uint) *gorm.DB {
+	return s.db.GetDB().WithContext(ctx).Model(&models.KnowledgePurgeTask{}).
+		Joins("JOIN knowledge_bases ON knowledge_bases.knowledge_base_id = knowledge_purge_tasks.knowledge_base_id").
+		Where("knowledge_bases.owner_id = ?", userID)
+}
+
+func (s *TrashService) checkOwner(ctx context.Context, taskID, userID uint) error {
+	var count int64
+	err := s.ownedTasks(ctx, userID).
+		Where("knowledge_purge_tasks.task_id = ? AND knowledge_purge_tasks.status = ?", taskID, models.PurgePending).
+		Count(&count).Error
+	if err != nil {
+		s.logger.Error("Failed to find trash item", "error", err, "taskID", taskID)
+		return errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to find trash item").WithCause(err)
+	}
+	if count == 0 {
+		return errors.NewNotFoundError("trash item")
+	}
+	return nil
+}
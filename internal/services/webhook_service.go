@@ -0,0 +1,320 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aihub/backend-go/internal/errors"
+	"github.com/aihub/backend-go/internal/interfaces"
+	"github.com/aihub/backend-go/internal/models"
+	"github.com/aihub/backend-go/internal/webhook"
+)
+
+// maxWebhooksPerKnowledgeBase 每个知识库的Webhook数量上限
+const maxWebhooksPerKnowledgeBase = 20
+
+// WebhookService 知识库Webhook订阅管理与投递记录查询
+type WebhookService struct {
+	db     interfaces.DatabaseInterface
+	logger interfaces.LoggerInterface
+
+	mu         sync.RWMutex
+	dispatcher *webhook.Dispatcher
+}
+
+// NewWebhookService 创建Webhook服务
+func NewWebhookService(db interfaces.DatabaseInterface, logger interfaces.LoggerInterface) *WebhookService {
+	return &WebhookService{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SetDispatcher 设置投递器，重放投递记录时使用
+func (s *WebhookService) SetDispatcher(dispatcher *webhook.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatcher = dispatcher
+}
+
+func (s *WebhookService) getDispatcher() *webhook.Dispatcher {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.dispatcher
+}
+
+// WebhookRequest 创建或修改Webhook的请求
+type WebhookRequest struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"` // 为空表示订阅全部事件
+	Description string   `json:"description"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+// CreatedWebhook 新建的Webhook，签名密钥只在创建时返回一次
+type CreatedWebhook struct {
+	models.KnowledgeWebhook
+	Secret string `json:"secret"`
+}
+
+// ListWebhooks 知识库的Webhook订阅
+func (s *WebhookService) ListWebhooks(ctx context.Context, kbID, userID uint) ([]models.KnowledgeWebhook, error) {
+	if err := s.checkAccess(ctx, kbID, userID); err != nil {
+		return nil, err
+	}
+	webhooks := []models.KnowledgeWebhook{}
+	err := s.db.GetDB().WithContext(ctx).
+		Where("knowledge_base_id = ?", kbID).
+		Order("webhook_id").
+		Find(&webhooks).Error
+	if err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to retrieve webhooks").WithCause(err)
+	}
+	return webhooks, nil
+}
+
+// GetWebhook 获取单个Webhook订阅
+func (s *WebhookService) GetWebhook(ctx context.Context, kbID, webhookID, userID uint) (*models.KnowledgeWebhook, error) {
+	if err := s.checkAccess(ctx, kbID, userID); err != nil {
+		return nil, err
+	}
+	return s.findWebhook(ctx, kbID, webhookID)
+}
+
+// CreateWebhook 创建Webhook订阅并生成签名密钥
+func (s *WebhookService) CreateWebhook(ctx context.Context, kbID, userID uint, req WebhookRequest) (*CreatedWebhook, error) {
+	if err := s.checkAccess(ctx, kbID, userID); err != nil {
+		return nil, err
+	}
+	endpoint, err := validateWebhookURL(ctx, req.URL)
+	if err != nil {
+		return nil, err
+	}
+	events, err := normalizeWebhookEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Description) > 200 {
+		return nil, errors.NewValidationError("Description cannot exceed 200 characters")
+	}
+
+	gormDB := s.db.GetDB().WithContext(ctx)
+	var count int64
+	if err := gormDB.Model(&models.KnowledgeWebhook{}).Where("knowledge_base_id = ?", kbID).Count(&count).Error; err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to count webhooks").WithCause(err)
+	}
+	if count >= maxWebhooksPerKnowledgeBase {
+		return nil, errors.NewValidationError("Too many webhooks for this knowledge base")
+	}
+
+	secret := webhook.GenerateSecret()
+	now := time.Now()
+	hook := models.KnowledgeWebhook{
+		KnowledgeBaseID: kbID,
+		URL:             endpoint,
+		Events:          events,
+		Secret:          secret,
+		Description:     req.Description,
+		Enabled:         req.Enabled == nil || *req.Enabled,
+		CreatedBy:       userID,
+		CreateTime:      now,
+		UpdateTime:      now,
+	}
+	if err := gormDB.Create(&hook).Error; err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to create webhook").WithCause(err)
+	}
+
+	s.logger.Info("Webhook created", "kbID", kbID, "webhookID", hook.WebhookID, "url", hook.URL)
+	return &CreatedWebhook{KnowledgeWebhook: hook, Secret: secret}, nil
+}
+
+// UpdateWebhook 修改Webhook订阅，重新启用时清零连续失败次数
+func (s *WebhookService) UpdateWebhook(ctx context.Context, kbID, webhookID, userID uint, req WebhookRequest) (*models.KnowledgeWebhook, error) {
+	if err := s.checkAccess(ctx, kbID, userID); err != nil {
+		return nil, err
+	}
+	hook, err := s.findWebhook(ctx, kbID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{"update_time": time.Now()}
+	if req.URL != "" {
+		endpoint, err := validateWebhookURL(ctx, req.URL)
+		if err != nil {
+			return nil, err
+		}
+		updates["url"] = endpoint
+	}
+	if req.Events != nil {
+		events, err := normalizeWebhookEvents(req.Events)
+		if err != nil {
+			return nil, err
+		}
+		updates["events"] = events
+	}
+	if req.Description != "" {
+		if len(req.Description) > 200 {
+			return nil, errors.NewValidationError("Description cannot exceed 200 characters")
+		}
+		updates["description"] = req.Description
+	}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+		if *req.Enabled && !hook.Enabled {
+			updates["consecutive_failures"] = 0
+			updates["disabled_reason"] = ""
+			updates["disabled_at"] = nil
+		}
+	}
+
+	gormDB := s.db.GetDB().WithContext(ctx)
+	if err := gormDB.Model(hook).Updates(updates).Error; err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to update webhook").WithCause(err)
+	}
+	return s.findWebhook(ctx, kbID, webhookID)
+}
+
+// DeleteWebhook 删除Webhook订阅，投递记录随之删除
+func (s *WebhookService) DeleteWebhook(ctx context.Context, kbID, webhookID, userID uint) error {
+	if err := s.checkAccess(ctx, kbID, userID); err != nil {
+		return err
+	}
+	result := s.db.GetDB().WithContext(ctx).
+		Where("webhook_id = ? AND knowledge_base_id = ?", webhookID, kbID).
+		Delete(&models.KnowledgeWebhook{})
+	if result.Error != nil {
+		return errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to delete webhook").WithCause(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.NewNotFoundError("webhook")
+	}
+	return nil
+}
+
+// ListDeliveries Webhook的投递记录，按时间倒序；status非空时按状态过滤
+func (s *WebhookService) ListDeliveries(ctx context.Context, kbID, webhookID, userID uint, status string, limit int) ([]models.KnowledgeWebhookDelivery, error) {
+	if err := s.checkAccess(ctx, kbID, userID); err != nil {
+		return nil, err
+	}
+	if _, err := s.findWebhook(ctx, kbID, webhookID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	query := s.db.GetDB().WithContext(ctx).Where("webhook_id = ?", webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	deliveries := []models.KnowledgeWebhookDelivery{}
+	if err := query.Order("delivery_id DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to retrieve webhook deliveries").WithCause(err)
+	}
+	return deliveries, nil
+}
+
+// ReplayDelivery 重新投递一条记录，新记录沿用原事件ID，接收方可据此去重
+func (s *WebhookService) ReplayDelivery(ctx context.Context, kbID, webhookID, deliveryID, userID uint) (*models.KnowledgeWebhookDelivery, error) {
+	if err := s.checkAccess(ctx, kbID, userID); err != nil {
+		return nil, err
+	}
+	hook, err := s.findWebhook(ctx, kbID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if !hook.Enabled {
+		return nil, errors.NewValidationError("Webhook is disabled")
+	}
+	dispatcher := s.getDispatcher()
+	if dispatcher == nil {
+		return nil, errors.NewSystemError(errors.ErrCodeExternalService, "Webhook dispatcher is not available")
+	}
+
+	var original models.KnowledgeWebhookDelivery
+	err = s.db.GetDB().WithContext(ctx).
+		Where("delivery_id = ? AND webhook_id = ?", deliveryID, webhookID).
+		First(&original).Error
+	if err != nil {
+		return nil, errors.NewNotFoundError("webhook delivery")
+	}
+
+	delivery, err := dispatcher.Replay(ctx, original)
+	if err != nil {
+		return nil, errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to replay webhook delivery").WithCause(err)
+	}
+	return delivery, nil
+}
+
+func (s *WebhookService) findWebhook(ctx context.Context, kbID, webhookID uint) (*models.KnowledgeWebhook, error) {
+	var hook models.KnowledgeWebhook
+	err := s.db.GetDB().WithContext(ctx).
+		Where("webhook_id = ? AND knowledge_base_id = ?", webhookID, kbID).
+		First(&hook).Error
+	if err != nil {
+		return nil, errors.NewNotFoundError("webhook")
+	}
+	return &hook, nil
+}
+
+// checkAccess 验证用户是否为知识库所有者
+func (s *WebhookService) checkAccess(ctx context.Context, kbID, userID uint) error {
+	var count int64
+	err := s.db.GetDB().WithContext(ctx).Model(&models.KnowledgeBase{}).
+		Where("knowledge_base_id = ? AND owner_id = ?", kbID, userID).
+		Count(&count).Error
+	if err != nil {
+		return errors.NewSystemError(errors.ErrCodeDatabaseError, "Failed to validate access").WithCause(err)
+	}
+	if count == 0 {
+		return errors.NewNotFoundError("knowledge base")
+	}
+	return nil
+}
+
+// validateWebhookURL 只允许带主机名的http/https地址，且不能解析到内网、回环或链路本地地址
+func validateWebhookURL(ctx context.Context, raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", errors.NewValidationError("Webhook URL is required")
+	}
+	if len(raw) > 500 {
+		return "", errors.NewValidationError("Webhook URL cannot exceed 500 characters")
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", errors.NewValidationError("Webhook URL must be an absolute http or https URL")
+	}
+	// 投递时拨号还会再检查一次实际地址，这里提前拒绝明显指向内网的地址
+	lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := webhook.CheckHost(lookupCtx, parsed.Hostname()); err != nil {
+		if stderrors.Is(err, webhook.ErrBlockedDestination) {
+			return "", errors.NewValidationError("Webhook URL must not point to a private, loopback or link-local address")
+		}
+		return "", errors.NewValidationError("Webhook URL host cannot be resolved")
+	}
+	return raw, nil
+}
+
+// normalizeWebhookEvents 校验并去重事件类型
+func normalizeWebhookEvents(events []string) (models.StringArray, error) {
+	normalized := models.StringArray{}
+	seen := make(map[string]bool, len(events))
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if !webhook.ValidEventType(event) {
+			return nil, errors.NewValidationError("Unsupported webhook event: " + event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			normalized = append(normalized, event)
+		}
+	}
+	return normalized, nil
+}
@@ -0,0 +1,307 @@
+package transfer
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aihub/backend-go/internal/knowledge"
+)
+
+// FormatVersion 归档格式版本，导入时拒绝更高版本的归档
+const FormatVersion = 1
+
+// 归档中的文件
+const (
+	manifestFile      = "manifest.json"
+	knowledgeBaseFile = "knowledge_base.json"
+	documentDir       = "documents/"
+)
+
+// maxEntrySize 归档中单个文件解压后的大小上限
+const maxEntrySize int64 = 1 << 30
+
+// ErrInvalidArchive 归档缺少必要文件或格式不受支持
+var ErrInvalidArchive = errors.New("invalid knowledge base archive")
+
+// Manifest 归档清单
+type Manifest struct {
+	FormatVersion         int                        `json:"format_version"`
+	ExportedAt            time.Time                  `json:"exported_at"`
+	SourceKnowledgeBaseID uint                       `json:"source_knowledge_base_id"`
+	Documents             int                        `json:"documents"`
+	Chunks                int                        `json:"chunks"`
+	IncludesEmbeddings    bool                       `json:"includes_embeddings"`
+	Embedding             knowledge.EmbeddingProfile `json:"embedding"` // 分块向量所用的模型，未记录时为零值
+}
+
+// KnowledgeBaseRecord 知识库配置
+type KnowledgeBaseRecord struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Config      string `json:"config,omitempty"`
+	IsPublic    bool   `json:"is_public"`
+}
+
+// DocumentRecord 文档元数据，提取的文本与源文件单独保存
+type DocumentRecord struct {
+	SourceDocumentID uint   `json:"source_document_id"`
+	Title            string `json:"title"`
+	Source           string `json:"source"`
+	SourceURL        string `json:"source_url,omitempty"`
+	Metadata         string `json:"metadata,omitempty"`
+	Status           string `json:"status"`
+	TotalTokens      int    `json:"total_tokens"`
+	ProcessingMode   string `json:"processing_mode,omitempty"`
+	Version          int    `json:"version"`
+	ContentHash      string `json:"content_hash,omitempty"`
+	ChangeType       string `json:"change_type,omitempty"`
+	OriginalName     string `json:"original_name,omitempty"` // 源文件名，为空表示归档中没有源文件
+}
+
+// ChunkRecord 分块，关联关系使用导出时的分块ID，导入时重新映射
+type ChunkRecord struct {
+	SourceChunkID       uint      `json:"source_chunk_id"`
+	Content             string    `json:"content"`
+	ChunkIndex          int       `json:"chunk_index"`
+	Metadata            string    `json:"metadata,omitempty"`
+	TokenCount          int       `json:"token_count"`
+	PrevChunkID         *uint     `json:"prev_chunk_id,omitempty"`
+	NextChunkID         *uint     `json:"next_chunk_id,omitempty"`
+	DocumentTotalTokens int       `json:"document_total_tokens"`
+	ChunkPosition       int       `json:"chunk_position"`
+	RelatedChunkIDs     []uint    `json:"related_chunk_ids,omitempty"`
+	ContentHash         string    `json:"content_hash,omitempty"`
+	Embedding           []float32 `json:"embedding,omitempty"`
+}
+
+func documentPath(index int, name string) string {
+	return fmt.Sprintf("%s%06d/%s", documentDir, index, name)
+}
+
+// Writer 按顺序写入归档，Close时写入清单
+type Writer struct {
+	zw       *zip.Writer
+	manifest Manifest
+}
+
+// NewWriter 创建归档写入器
+func NewWriter(w io.Writer, manifest Manifest) *Writer {
+	manifest.FormatVersion = FormatVersion
+	manifest.Documents = 0
+	manifest.Chunks = 0
+	return &Writer{zw: zip.NewWriter(w), manifest: manifest}
+}
+
+// WriteKnowledgeBase 写入知识库配置
+func (w *Writer) WriteKnowledgeBase(kb KnowledgeBaseRecord) error {
+	return w.writeJSON(knowledgeBaseFile, kb)
+}
+
+// AddDocument 写入一个文档，original为nil时不包含源文件
+func (w *Writer) AddDocument(doc DocumentRecord, content string, original io.Reader, chunks []ChunkRecord) error {
+	index := w.manifest.Documents
+	if original == nil {
+		doc.OriginalName = ""
+	}
+	if err := w.writeJSON(documentPath(index, "document.json"), doc); err != nil {
+		return err
+	}
+
+	f, err := w.zw.Create(documentPath(index, "content.txt"))
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, content); err != nil {
+		return err
+	}
+
+	if original != nil {
+		f, err := w.zw.Create(documentPath(index, "original"))
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, original); err != nil {
+			return fmt.Errorf("failed to copy original file: %w", err)
+		}
+	}
+
+	f, err = w.zw.Create(documentPath(index, "chunks.jsonl"))
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	for _, chunk := range chunks {
+		if err := encoder.Encode(chunk); err != nil {
+			return err
+		}
+	}
+
+	w.manifest.Documents++
+	w.manifest.Chunks += len(chunks)
+	return nil
+}
+
+// Close 写入清单并结束归档，返回写入的清单
+func (w *Writer) Close() (Manifest, error) {
+	if err := w.writeJSON(manifestFile, w.manifest); err != nil {
+		return w.manifest, err
+	}
+	return w.manifest, w.zw.Close()
+}
+
+func (w *Writer) writeJSON(name string, v interface{}) error {
+	f, err := w.zw.Create(name)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(f).Encode(v)
+}
+
+// Reader 随机读取归档，导入可以从任意文档继续
+type Reader struct {
+	zr       *zip.Reader
+	files    map[string]*zip.File
+	manifest Manifest
+}
+
+// NewReader 打开归档并校验清单
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	reader := &Reader{zr: zr, files: make(map[string]*zip.File, len(zr.File))}
+	for _, f := range zr.File {
+		reader.files[f.Name] = f
+	}
+
+	if err := reader.readJSON(manifestFile, &reader.manifest); err != nil {
+		return nil, err
+	}
+	if reader.manifest.FormatVersion < 1 || reader.manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version %d", ErrInvalidArchive, reader.manifest.FormatVersion)
+	}
+	if _, ok := reader.files[knowledgeBaseFile]; !ok {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidArchive, knowledgeBaseFile)
+	}
+	for i := 0; i < reader.manifest.Documents; i++ {
+		if _, ok := reader.files[documentPath(i, "document.json")]; !ok {
+			return nil, fmt.Errorf("%w: missing document %d", ErrInvalidArchive, i)
+		}
+	}
+	return reader, nil
+}
+
+// Manifest 归档清单
+func (r *Reader) Manifest() Manifest {
+	return r.manifest
+}
+
+// KnowledgeBase 知识库配置
+func (r *Reader) KnowledgeBase() (KnowledgeBaseRecord, error) {
+	var kb KnowledgeBaseRecord
+	err := r.readJSON(knowledgeBaseFile, &kb)
+	return kb, err
+}
+
+// Document 第index个文档的元数据与提取的文本
+func (r *Reader) Document(index int) (DocumentRecord, string, error) {
+	var doc DocumentRecord
+	if err := r.readJSON(documentPath(index, "document.json"), &doc); err != nil {
+		return doc, "", err
+	}
+	rc, err := r.open(documentPath(index, "content.txt"))
+	if err != nil {
+		return doc, "", err
+	}
+	defer rc.Close()
+	var content strings.Builder
+	if _, err := io.Copy(&content, rc); err != nil {
+		return doc, "", fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	return doc, content.String(), nil
+}
+
+// Chunks 第index个文档的分块
+func (r *Reader) Chunks(index int) ([]ChunkRecord, error) {
+	rc, err := r.open(documentPath(index, "chunks.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var chunks []ChunkRecord
+	scanner := bufio.NewScanner(rc)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var chunk ChunkRecord
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	return chunks, nil
+}
+
+// Original 第index个文档的源文件，归档中没有源文件时返回nil
+func (r *Reader) Original(index int) (io.ReadCloser, int64, error) {
+	f, ok := r.files[documentPath(index, "original")]
+	if !ok {
+		return nil, 0, nil
+	}
+	rc, err := openEntry(f)
+	if err != nil {
+		return nil, 0, err
+	}
+	return rc, int64(f.UncompressedSize64), nil
+}
+
+func (r *Reader) open(name string) (io.ReadCloser, error) {
+	f, ok := r.files[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidArchive, name)
+	}
+	return openEntry(f)
+}
+
+// openEntry 打开归档中的文件，超过大小上限时拒绝，读取量不超过文件头声明的解压大小
+func openEntry(f *zip.File) (io.ReadCloser, error) {
+	if f.UncompressedSize64 > uint64(maxEntrySize) {
+		return nil, fmt.Errorf("%w: %s exceeds %d bytes", ErrInvalidArchive, f.Name, maxEntrySize)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	return &limitedReadCloser{Reader: io.LimitReader(rc, int64(f.UncompressedSize64)), Closer: rc}, nil
+}
+
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func (r *Reader) readJSON(name string, v interface{}) error {
+	rc, err := r.open(name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := json.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidArchive, name, err)
+	}
+	return nil
+}
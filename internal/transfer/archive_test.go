@@ -0,0 +1,128 @@
+package transfer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aihub/backend-go/internal/knowledge"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	profile := knowledge.EmbeddingProfile{Provider: "openai", Model: "text-embedding-3-small", Dimensions: 2}
+	writer := NewWriter(&buf, Manifest{SourceKnowledgeBaseID: 7, IncludesEmbeddings: true, Embedding: profile})
+	require.NoError(t, writer.WriteKnowledgeBase(KnowledgeBaseRecord{Name: "产品手册", Description: "desc"}))
+
+	next := uint(101)
+	chunks := []ChunkRecord{
+		{SourceChunkID: 100, Content: "alpha", NextChunkID: &next, Embedding: []float32{0.5, 0.25}},
+		{SourceChunkID: 101, Content: "beta", RelatedChunkIDs: []uint{100}},
+	}
+	doc := DocumentRecord{SourceDocumentID: 10, Title: "a.txt", OriginalName: "a.txt"}
+	require.NoError(t, writer.AddDocument(doc, "alpha beta", strings.NewReader("raw bytes"), chunks))
+	// 没有源文件时清空文件名
+	require.NoError(t, writer.AddDocument(DocumentRecord{SourceDocumentID: 11, OriginalName: "b.pdf"}, "gamma", nil, nil))
+
+	manifest, err := writer.Close()
+	require.NoError(t, err)
+	assert.Equal(t, 2, manifest.Documents)
+	assert.Equal(t, 2, manifest.Chunks)
+
+	reader, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, FormatVersion, reader.Manifest().FormatVersion)
+	assert.Equal(t, profile, reader.Manifest().Embedding)
+
+	kb, err := reader.KnowledgeBase()
+	require.NoError(t, err)
+	assert.Equal(t, "产品手册", kb.Name)
+
+	gotDoc, content, err := reader.Document(0)
+	require.NoError(t, err)
+	assert.Equal(t, doc, gotDoc)
+	assert.Equal(t, "alpha beta", content)
+
+	gotChunks, err := reader.Chunks(0)
+	require.NoError(t, err)
+	assert.Equal(t, chunks, gotChunks)
+
+	original, size, err := reader.Original(0)
+	require.NoError(t, err)
+	data, err := io.ReadAll(original)
+	require.NoError(t, err)
+	original.Close()
+	assert.Equal(t, "raw bytes", string(data))
+	assert.Equal(t, int64(9), size)
+
+	gotDoc, _, err = reader.Document(1)
+	require.NoError(t, err)
+	assert.Empty(t, gotDoc.OriginalName)
+	original, _, err = reader.Original(1)
+	require.NoError(t, err)
+	assert.Nil(t, original)
+	gotChunks, err = reader.Chunks(1)
+	require.NoError(t, err)
+	assert.Empty(t, gotChunks)
+}
+
+func TestReaderRejectsInvalidArchives(t *testing.T) {
+	build := func(files map[string]interface{}) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for name, v := range files {
+			f, err := zw.Create(name)
+			require.NoError(t, err)
+			require.NoError(t, json.NewEncoder(f).Encode(v))
+		}
+		require.NoError(t, zw.Close())
+		return buf.Bytes()
+	}
+
+	cases := map[string][]byte{
+		"not a zip":        []byte("plain text"),
+		"missing manifest": build(map[string]interface{}{knowledgeBaseFile: KnowledgeBaseRecord{}}),
+		"future version": build(map[string]interface{}{
+			manifestFile:      Manifest{FormatVersion: FormatVersion + 1},
+			knowledgeBaseFile: KnowledgeBaseRecord{},
+		}),
+		"missing document": build(map[string]interface{}{
+			manifestFile:      Manifest{FormatVersion: FormatVersion, Documents: 1},
+			knowledgeBaseFile: KnowledgeBaseRecord{},
+		}),
+	}
+	for name, data := range cases {
+		_, err := NewReader(bytes.NewReader(data), int64(len(data)))
+		assert.ErrorIs(t, err, ErrInvalidArchive, name)
+	}
+}
+
+func TestReaderRejectsOversizedEntries(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create(manifestFile)
+	require.NoError(t, err)
+	require.NoError(t, json.NewEncoder(f).Encode(Manifest{FormatVersion: FormatVersion}))
+	// 文件头声明的解压大小超过上限，不解压直接拒绝
+	raw, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               knowledgeBaseFile,
+		Method:             zip.Store,
+		CompressedSize64:   2,
+		UncompressedSize64: uint64(maxEntrySize) + 1,
+	})
+	require.NoError(t, err)
+	_, err = raw.Write([]byte("{}"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	reader, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	_, err = reader.KnowledgeBase()
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+	assert.Contains(t, err.Error(), "exceeds")
+}
@@ -0,0 +1,174 @@
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/aihub/backend-go/internal/knowledge"
+	"github.com/aihub/backend-go/internal/logger"
+	"github.com/aihub/backend-go/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Exporter 把知识库导出为归档
+type Exporter struct {
+	db      *gorm.DB
+	objects Objects               // 为nil时不导出源文件
+	store   knowledge.VectorStore // 支持ChunkEmbeddingSource时从中读取向量，否则使用分块中保存的向量
+}
+
+// NewExporter 创建导出器
+func NewExporter(db *gorm.DB, objects Objects, store knowledge.VectorStore) *Exporter {
+	return &Exporter{db: db, objects: objects, store: store}
+}
+
+// Export 导出知识库中未删除的文档与有效分块，includeEmbeddings为true时包含向量及其模型
+func (e *Exporter) Export(ctx context.Context, kbID uint, includeEmbeddings bool, w io.Writer) (Manifest, error) {
+	var kb models.KnowledgeBase
+	if err := e.db.WithContext(ctx).Where("knowledge_base_id = ?", kbID).First(&kb).Error; err != nil {
+		return Manifest{}, fmt.Errorf("failed to load knowledge base: %w", err)
+	}
+
+	manifest := Manifest{
+		ExportedAt:            time.Now(),
+		SourceKnowledgeBaseID: kbID,
+		IncludesEmbeddings:    includeEmbeddings,
+	}
+	if includeEmbeddings {
+		manifest.Embedding = knowledge.EmbeddingProfile{
+			Provider:   kb.EmbeddingProvider,
+			Model:      kb.EmbeddingModel,
+			Dimensions: kb.EmbeddingDimensions,
+		}
+	}
+
+	writer := NewWriter(w, manifest)
+	err := writer.WriteKnowledgeBase(KnowledgeBaseRecord{
+		Name:        kb.Name,
+		Description: kb.Description,
+		Config:      kb.Config,
+		IsPublic:    kb.IsPublic,
+	})
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	var docs []models.KnowledgeDocument
+	err = e.db.WithContext(ctx).
+		Where("knowledge_base_id = ?", kbID).
+		Order("document_id").
+		Find(&docs).Error
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to load documents: %w", err)
+	}
+
+	for i := range docs {
+		if err := e.exportDocument(ctx, writer, &docs[i], includeEmbeddings); err != nil {
+			return Manifest{}, err
+		}
+	}
+	return writer.Close()
+}
+
+func (e *Exporter) exportDocument(ctx context.Context, writer *Writer, doc *models.KnowledgeDocument, includeEmbeddings bool) error {
+	var rows []models.KnowledgeChunk
+	err := e.db.WithContext(ctx).
+		Where("document_id = ? AND is_active IS NOT FALSE", doc.DocumentID).
+		Order("chunk_index, chunk_id").
+		Find(&rows).Error
+	if err != nil {
+		return fmt.Errorf("failed to load chunks of document %d: %w", doc.DocumentID, err)
+	}
+
+	var embeddings map[uint][]float32
+	if includeEmbeddings {
+		if embeddings, err = e.embeddings(ctx, doc.KnowledgeBaseID, rows); err != nil {
+			return err
+		}
+	}
+
+	chunks := make([]ChunkRecord, len(rows))
+	for i, row := range rows {
+		chunks[i] = ChunkRecord{
+			SourceChunkID:       row.ChunkID,
+			Content:             row.Content,
+			ChunkIndex:          row.ChunkIndex,
+			Metadata:            row.Metadata,
+			TokenCount:          row.TokenCount,
+			PrevChunkID:         row.PrevChunkID,
+			NextChunkID:         row.NextChunkID,
+			DocumentTotalTokens: row.DocumentTotalTokens,
+			ChunkPosition:       row.ChunkPosition,
+			ContentHash:         row.ContentHash,
+			Embedding:           embeddings[row.ChunkID],
+		}
+		if row.RelatedChunkIDs != "" {
+			_ = json.Unmarshal([]byte(row.RelatedChunkIDs), &chunks[i].RelatedChunkIDs)
+		}
+	}
+
+	record := DocumentRecord{
+		SourceDocumentID: doc.DocumentID,
+		Title:            doc.Title,
+		Source:           doc.Source,
+		SourceURL:        doc.SourceURL,
+		Metadata:         doc.Metadata,
+		Status:           doc.Status,
+		TotalTokens:      doc.TotalTokens,
+		ProcessingMode:   doc.ProcessingMode,
+		Version:          doc.Version,
+		ContentHash:      doc.ContentHash,
+		ChangeType:       doc.ChangeType,
+	}
+
+	var original io.Reader
+	if e.objects != nil && doc.FilePath != "" {
+		rc, err := e.objects.Get(ctx, doc.FilePath)
+		switch {
+		case errors.Is(err, ErrObjectNotFound):
+			logger.Warn("Original file missing, exporting extracted text only",
+				zap.Uint("document_id", doc.DocumentID), zap.String("file_path", doc.FilePath))
+		case err != nil:
+			return fmt.Errorf("failed to read original file of document %d: %w", doc.DocumentID, err)
+		default:
+			defer rc.Close()
+			original = rc
+			record.OriginalName = path.Base(doc.FilePath)
+		}
+	}
+
+	return writer.AddDocument(record, doc.Content, original, chunks)
+}
+
+// embeddings 读取分块向量，向量存储不支持按分块读取时解析分块中保存的向量
+func (e *Exporter) embeddings(ctx context.Context, kbID uint, rows []models.KnowledgeChunk) (map[uint][]float32, error) {
+	if source, ok := e.store.(knowledge.ChunkEmbeddingSource); ok && e.store.Ready() && len(rows) > 0 {
+		ids := make([]uint, len(rows))
+		for i, row := range rows {
+			ids[i] = row.ChunkID
+		}
+		embeddings, err := source.ChunkEmbeddings(ctx, kbID, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read embeddings: %w", err)
+		}
+		return embeddings, nil
+	}
+
+	embeddings := make(map[uint][]float32, len(rows))
+	for _, row := range rows {
+		if row.Embedding == "" {
+			continue
+		}
+		var embedding []float32
+		if json.Unmarshal([]byte(row.Embedding), &embedding) == nil && len(embedding) > 0 {
+			embeddings[row.ChunkID] = embedding
+		}
+	}
+	return embeddings, nil
+}
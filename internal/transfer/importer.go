@@ -0,0 +1,399 @@
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aihub/backend-go/internal/knowledge"
+	"github.com/aihub/backend-go/internal/logger"
+	"github.com/aihub/backend-go/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// chunkInsertBatchSize 每批插入的分块数
+const chunkInsertBatchSize = 200
+
+// errJobMoved 任务进度已被其他副本推进，本次执行放弃
+var errJobMoved = errors.New("import job progressed elsewhere")
+
+// Importer 从归档重建知识库
+// 准备阶段创建知识库；文档阶段每个文档与任务进度在同一事务中提交，中断后从下一个文档继续；
+// 索引阶段通过一致性检查把新分块写入全文索引与向量存储，重复执行只补写缺失的部分
+type Importer struct {
+	db       *gorm.DB
+	archives ArchiveStore
+	objects  Objects // 为nil时不恢复源文件
+	indexer  knowledge.FulltextIndexer
+	store    knowledge.VectorStore
+	router   *knowledge.EmbeddingRouter
+}
+
+// NewImporter 创建导入器，indexer或store为nil时跳过对应的索引
+func NewImporter(db *gorm.DB, archives ArchiveStore, objects Objects, indexer knowledge.FulltextIndexer, store knowledge.VectorStore) *Importer {
+	return &Importer{db: db, archives: archives, objects: objects, indexer: indexer, store: store}
+}
+
+// SetEmbeddingRouter 设置嵌入模型路由，用于判断能否沿用归档中的向量以及重新向量化
+func (im *Importer) SetEmbeddingRouter(router *knowledge.EmbeddingRouter) {
+	im.router = router
+}
+
+// Inspect 校验归档并读取清单与知识库配置，创建任务前调用
+func (im *Importer) Inspect(ctx context.Context, key string) (Manifest, KnowledgeBaseRecord, error) {
+	archive, err := im.archives.Open(ctx, key)
+	if err != nil {
+		return Manifest{}, KnowledgeBaseRecord{}, err
+	}
+	defer archive.Close()
+	reader, err := NewReader(archive, archive.Size())
+	if err != nil {
+		return Manifest{}, KnowledgeBaseRecord{}, err
+	}
+	kb, err := reader.KnowledgeBase()
+	return reader.Manifest(), kb, err
+}
+
+// Execute 从任务记录的阶段继续导入
+func (im *Importer) Execute(ctx context.Context, job *models.KnowledgeImportJob) error {
+	if job.Stage == models.ImportStageDone {
+		return nil
+	}
+
+	archive, err := im.archives.Open(ctx, job.ArchiveKey)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer archive.Close()
+	reader, err := NewReader(archive, archive.Size())
+	if err != nil {
+		return err
+	}
+
+	if job.KnowledgeBaseID == 0 {
+		if err := im.prepare(ctx, reader, job); err != nil {
+			return err
+		}
+	}
+	for job.ImportedDocuments < job.TotalDocuments {
+		if err := im.importDocument(ctx, reader, job); err != nil {
+			return err
+		}
+	}
+	if err := im.index(ctx, job); err != nil {
+		return err
+	}
+
+	if err := im.archives.Remove(ctx, job.ArchiveKey); err != nil {
+		logger.Warn("Failed to remove imported archive", zap.Uint("job_id", job.JobID), zap.Error(err))
+	}
+	return nil
+}
+
+// reuseEmbeddings 归档中的向量所用模型与本环境的模型一致时沿用，否则重新向量化
+// 未配置嵌入模型路由时无法重新向量化，直接沿用
+func (im *Importer) reuseEmbeddings(manifest Manifest) bool {
+	if !manifest.IncludesEmbeddings || manifest.Embedding.IsZero() {
+		return false
+	}
+	if im.router == nil {
+		return true
+	}
+	fallback := im.router.Fallback()
+	return fallback.Provider == manifest.Embedding.Provider && fallback.Model == manifest.Embedding.Model
+}
+
+// prepare 创建知识库并记录归档的规模，与任务进度在同一事务中提交
+func (im *Importer) prepare(ctx context.Context, reader *Reader, job *models.KnowledgeImportJob) error {
+	record, err := reader.KnowledgeBase()
+	if err != nil {
+		return err
+	}
+	manifest := reader.Manifest()
+	reuse := im.reuseEmbeddings(manifest)
+
+	name := job.Name
+	if name == "" {
+		name = record.Name
+	}
+	now := time.Now()
+	kb := models.KnowledgeBase{
+		Name:        name,
+		Description: record.Description,
+		Config:      record.Config,
+		OwnerID:     job.OwnerID,
+		IsPublic:    record.IsPublic,
+		Status:      "active",
+		CreateTime:  now,
+		UpdateTime:  now,
+	}
+	if reuse {
+		kb.EmbeddingProvider = manifest.Embedding.Provider
+		kb.EmbeddingModel = manifest.Embedding.Model
+		kb.EmbeddingDimensions = manifest.Embedding.Dimensions
+	}
+
+	err = im.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Owner").Create(&kb).Error; err != nil {
+			return fmt.Errorf("failed to create knowledge base: %w", err)
+		}
+		return advance(tx, job.JobID, map[string]interface{}{
+			"knowledge_base_id":        kb.KnowledgeBaseID,
+			"source_knowledge_base_id": manifest.SourceKnowledgeBaseID,
+			"stage":                    models.ImportStageDocuments,
+			"reuse_embeddings":         reuse,
+			"total_documents":          manifest.Documents,
+			"total_chunks":             manifest.Chunks,
+		}, "knowledge_base_id = 0")
+	})
+	if err != nil {
+		return err
+	}
+
+	job.KnowledgeBaseID = kb.KnowledgeBaseID
+	job.SourceKnowledgeBaseID = manifest.SourceKnowledgeBaseID
+	job.Stage = models.ImportStageDocuments
+	job.ReuseEmbeddings = reuse
+	job.TotalDocuments = manifest.Documents
+	job.TotalChunks = manifest.Chunks
+	logger.Info("Import knowledge base created",
+		zap.Uint("job_id", job.JobID), zap.Uint("kb_id", kb.KnowledgeBaseID), zap.Bool("reuse_embeddings", reuse))
+	return nil
+}
+
+// importDocument 导入下一个文档，分块ID与关联关系按新ID重新映射
+func (im *Importer) importDocument(ctx context.Context, reader *Reader, job *models.KnowledgeImportJob) error {
+	index := job.ImportedDocuments
+	record, content, err := reader.Document(index)
+	if err != nil {
+		return err
+	}
+	chunks, err := reader.Chunks(index)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	doc := models.KnowledgeDocument{
+		KnowledgeBaseID: job.KnowledgeBaseID,
+		Title:           record.Title,
+		Content:         content,
+		Source:          record.Source,
+		SourceURL:       record.SourceURL,
+		Metadata:        record.Metadata,
+		Status:          record.Status,
+		TotalTokens:     record.TotalTokens,
+		ProcessingMode:  record.ProcessingMode,
+		Version:         record.Version,
+		ContentHash:     record.ContentHash,
+		ChangeType:      record.ChangeType,
+		LastProcessedAt: now,
+		CreateTime:      now,
+		UpdateTime:      now,
+	}
+	if doc.Version == 0 {
+		doc.Version = 1
+	}
+
+	err = im.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("KnowledgeBase").Create(&doc).Error; err != nil {
+			return fmt.Errorf("failed to create document: %w", err)
+		}
+		// 源文件的键包含新文档ID，同名文件不会互相覆盖；写入失败时事务回滚，重试时重新创建文档
+		filePath, err := im.restoreOriginal(ctx, reader, index, job.KnowledgeBaseID, doc.DocumentID, record.OriginalName)
+		if err != nil {
+			return err
+		}
+		if filePath != "" {
+			if err := tx.Model(&doc).Update("file_path", filePath).Error; err != nil {
+				return fmt.Errorf("failed to save document file path: %w", err)
+			}
+		}
+		if err := insertChunks(tx, doc.DocumentID, chunks, job.ReuseEmbeddings, now); err != nil {
+			return err
+		}
+		return advance(tx, job.JobID, map[string]interface{}{
+			"imported_documents": index + 1,
+			"imported_chunks":    job.ImportedChunks + len(chunks),
+		}, "imported_documents = ?", index)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import document %d: %w", index, err)
+	}
+
+	job.ImportedDocuments = index + 1
+	job.ImportedChunks += len(chunks)
+	return nil
+}
+
+// restoreOriginal 写入源文件，返回对象键；事务提交失败时遗留的对象由一致性检查清理
+func (im *Importer) restoreOriginal(ctx context.Context, reader *Reader, index int, kbID, documentID uint, name string) (string, error) {
+	if im.objects == nil || name == "" || strings.ContainsAny(name, "/\\") {
+		return "", nil
+	}
+	original, size, err := reader.Original(index)
+	if err != nil || original == nil {
+		return "", err
+	}
+	defer original.Close()
+
+	key := fmt.Sprintf("knowledge-bases/%d/%d/%s", kbID, documentID, name)
+	if err := im.objects.Put(ctx, key, original, size); err != nil {
+		return "", fmt.Errorf("failed to restore original file: %w", err)
+	}
+	return key, nil
+}
+
+// insertChunks 插入文档的分块，再把前后块与关联块改写为新ID，指向其他文档的关联块丢弃
+func insertChunks(tx *gorm.DB, documentID uint, records []ChunkRecord, reuseEmbeddings bool, now time.Time) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	rows := make([]models.KnowledgeChunk, len(records))
+	for i, record := range records {
+		contentHash := record.ContentHash
+		if contentHash == "" {
+			contentHash = knowledge.ChunkHash(record.Content)
+		}
+		rows[i] = models.KnowledgeChunk{
+			DocumentID:          documentID,
+			Content:             record.Content,
+			ChunkIndex:          record.ChunkIndex,
+			Metadata:            record.Metadata,
+			TokenCount:          record.TokenCount,
+			DocumentTotalTokens: record.DocumentTotalTokens,
+			ChunkPosition:       record.ChunkPosition,
+			ContentHash:         contentHash,
+			IsActive:            true,
+			CreateTime:          now,
+			UpdateTime:          now,
+		}
+		if reuseEmbeddings && len(record.Embedding) > 0 {
+			encoded, _ := json.Marshal(record.Embedding)
+			rows[i].Embedding = string(encoded)
+		}
+	}
+	if err := tx.Omit("Document").CreateInBatches(&rows, chunkInsertBatchSize).Error; err != nil {
+		return fmt.Errorf("failed to create chunks: %w", err)
+	}
+
+	ids := make(map[uint]uint, len(records))
+	for i, record := range records {
+		ids[record.SourceChunkID] = rows[i].ChunkID
+	}
+	remap := func(id *uint) *uint {
+		if id == nil {
+			return nil
+		}
+		if mapped, ok := ids[*id]; ok {
+			return &mapped
+		}
+		return nil
+	}
+
+	for i, record := range records {
+		if record.PrevChunkID == nil && record.NextChunkID == nil && len(record.RelatedChunkIDs) == 0 {
+			continue
+		}
+		updates := map[string]interface{}{
+			"prev_chunk_id": remap(record.PrevChunkID),
+			"next_chunk_id": remap(record.NextChunkID),
+		}
+		if len(record.RelatedChunkIDs) > 0 {
+			related := make([]uint, 0, len(record.RelatedChunkIDs))
+			for _, id := range record.RelatedChunkIDs {
+				if mapped, ok := ids[id]; ok {
+					related = append(related, mapped)
+				}
+			}
+			encoded, _ := json.Marshal(related)
+			updates["related_chunk_ids"] = string(encoded)
+		}
+		if err := tx.Model(&models.KnowledgeChunk{}).Where("chunk_id = ?", rows[i].ChunkID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to link chunks: %w", err)
+		}
+	}
+	return nil
+}
+
+// index 写入全文索引与向量存储，分块中已有沿用的向量时不再向量化
+func (im *Importer) index(ctx context.Context, job *models.KnowledgeImportJob) error {
+	if job.Stage != models.ImportStageIndex {
+		err := advance(im.db.WithContext(ctx), job.JobID, map[string]interface{}{"stage": models.ImportStageIndex})
+		if err != nil {
+			return err
+		}
+		job.Stage = models.ImportStageIndex
+	}
+
+	reconciler := knowledge.NewReconciler(im.db, im.indexer, im.store)
+	if im.router != nil {
+		reconciler.SetEmbeddingRouter(im.router)
+	}
+	report, err := reconciler.Run(ctx, job.KnowledgeBaseID, true)
+	if err != nil {
+		return fmt.Errorf("failed to index chunks: %w", err)
+	}
+	var problems []string
+	for _, store := range report.Stores {
+		switch {
+		case store.Error != "":
+			problems = append(problems, store.Store+": "+store.Error)
+		case store.RepairFailed > 0:
+			problems = append(problems, fmt.Sprintf("%s: %d chunks not indexed", store.Store, store.RepairFailed))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("failed to index chunks: %s", strings.Join(problems, "; "))
+	}
+
+	err = advance(im.db.WithContext(ctx), job.JobID, map[string]interface{}{
+		"stage":          models.ImportStageDone,
+		"indexed_chunks": report.Chunks,
+	})
+	if err != nil {
+		return err
+	}
+	job.Stage = models.ImportStageDone
+	job.IndexedChunks = report.Chunks
+	return nil
+}
+
+// advance 在仍由本次执行持有的任务上更新进度，guard用于确认进度没有被其他副本推进
+func advance(tx *gorm.DB, jobID uint, updates map[string]interface{}, guard ...interface{}) error {
+	updates["update_time"] = time.Now()
+	query := tx.Model(&models.KnowledgeImportJob{}).
+		Where("job_id = ? AND status = ?", jobID, models.ImportRunning)
+	if len(guard) > 0 {
+		query = query.Where(guard[0], guard[1:]...)
+	}
+	result := query.Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save import progress: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errJobMoved
+	}
+	return nil
+}
+
+// Progress 导入进度（0-1），文档阶段占90%
+func Progress(job *models.KnowledgeImportJob) float64 {
+	switch job.Stage {
+	case models.ImportStageDone:
+		return 1
+	case models.ImportStageIndex:
+		return 0.9
+	case models.ImportStageDocuments:
+		if job.TotalDocuments == 0 {
+			return 0.9
+		}
+		return 0.9 * float64(job.ImportedDocuments) / float64(job.TotalDocuments)
+	}
+	return 0
+}
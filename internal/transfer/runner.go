@@ -0,0 +1,178 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aihub/backend-go/internal/logger"
+	"github.com/aihub/backend-go/internal/models"
+	"go.uber.org/zap"
+)
+
+// Executor 执行导入任务，从任务记录的进度继续
+type Executor interface {
+	Execute(ctx context.Context, job *models.KnowledgeImportJob) error
+}
+
+// Options 导入任务参数，零值使用默认值
+type Options struct {
+	BatchSize    int           // 每轮领取的任务数，默认2
+	PollInterval time.Duration // 轮询间隔，默认30s
+	Lease        time.Duration // 租约时长，执行期间每隔1/3续约，进程退出后超时可被重新领取，默认5m
+}
+
+const (
+	defaultImportBatchSize    = 2
+	defaultImportPollInterval = 30 * time.Second
+	defaultImportLease        = 5 * time.Minute
+	maxImportErrorLength      = 500
+)
+
+// Runner 导入任务执行器，在后台领取任务并执行
+type Runner struct {
+	store    Store
+	executor Executor
+	opts     Options
+	wake     chan struct{}
+}
+
+// NewRunner 创建导入任务执行器
+func NewRunner(store Store, executor Executor, opts Options) *Runner {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultImportBatchSize
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultImportPollInterval
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = defaultImportLease
+	}
+	return &Runner{store: store, executor: executor, opts: opts, wake: make(chan struct{}, 1)}
+}
+
+// Wake 有新任务时立即开始下一轮，不必等待轮询间隔
+func (r *Runner) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run 后台执行循环，直到ctx取消
+func (r *Runner) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := r.RunOnce(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Warn("Failed to run import jobs", zap.Error(err))
+				}
+				break
+			}
+			if n < r.opts.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// RunOnce 领取一批任务并依次执行，返回领取的任务数
+func (r *Runner) RunOnce(ctx context.Context) (int, error) {
+	jobs, err := r.store.Claim(ctx, time.Now(), r.opts.Lease, r.opts.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i := range jobs {
+		if err := r.execute(ctx, &jobs[i]); err != nil {
+			return len(jobs), err
+		}
+	}
+	return len(jobs), nil
+}
+
+// RunJob 在当前进程中执行指定任务，任务正由其他副本执行或已结束时返回false
+func (r *Runner) RunJob(ctx context.Context, jobID uint) (bool, error) {
+	job, err := r.store.ClaimJob(ctx, jobID, time.Now(), r.opts.Lease)
+	if err != nil || job == nil {
+		return false, err
+	}
+	return true, r.execute(ctx, job)
+}
+
+// execute 执行单个任务，导入失败只记录在任务上，返回的错误表示任务状态无法保存
+func (r *Runner) execute(ctx context.Context, job *models.KnowledgeImportJob) error {
+	jobCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.heartbeat(jobCtx, job.JobID)
+	}()
+	err := r.executor.Execute(jobCtx, job)
+	cancel()
+	<-done
+
+	switch {
+	case errors.Is(err, errJobMoved):
+		logger.Warn("Import job taken over by another worker", zap.Uint("job_id", job.JobID))
+		return nil
+	case err != nil && ctx.Err() != nil:
+		// 进程退出，租约到期后重新领取
+		return ctx.Err()
+	case err != nil:
+		logger.Warn("Import job failed",
+			zap.Uint("job_id", job.JobID),
+			zap.String("stage", job.Stage),
+			zap.Int("attempts", job.Attempts),
+			zap.Error(err))
+		if ferr := r.store.Fail(ctx, job.JobID, err.Error()); ferr != nil {
+			return fmt.Errorf("failed to record import failure: %w", ferr)
+		}
+		return nil
+	}
+
+	if err := r.store.Finish(ctx, job.JobID); err != nil {
+		return fmt.Errorf("failed to finish import job: %w", err)
+	}
+	logger.Info("Import job finished",
+		zap.Uint("job_id", job.JobID),
+		zap.Uint("kb_id", job.KnowledgeBaseID),
+		zap.Int("documents", job.ImportedDocuments),
+		zap.Int("chunks", job.ImportedChunks))
+	return nil
+}
+
+// heartbeat 执行期间定期续约
+func (r *Runner) heartbeat(ctx context.Context, jobID uint) {
+	ticker := time.NewTicker(r.opts.Lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.store.Extend(ctx, jobID, time.Now().Add(r.opts.Lease)); err != nil && ctx.Err() == nil {
+				logger.Warn("Failed to extend import job lease", zap.Uint("job_id", jobID), zap.Error(err))
+			}
+		}
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
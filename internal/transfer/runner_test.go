@@ -0,0 +1,170 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aihub/backend-go/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore 进程内存储，领取规则与GormStore一致
+type memoryStore struct {
+	mu   sync.Mutex
+	jobs []*models.KnowledgeImportJob
+}
+
+func (s *memoryStore) add(job models.KnowledgeImportJob) uint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job.JobID = uint(len(s.jobs) + 1)
+	if job.Status == "" {
+		job.Status = models.ImportPending
+	}
+	s.jobs = append(s.jobs, &job)
+	return job.JobID
+}
+
+func (s *memoryStore) claimable(job *models.KnowledgeImportJob, now time.Time) bool {
+	return job.Status == models.ImportPending || (job.Status == models.ImportRunning && job.LeaseUntil.Before(now))
+}
+
+func (s *memoryStore) claim(job *models.KnowledgeImportJob, now time.Time, lease time.Duration) models.KnowledgeImportJob {
+	job.Status = models.ImportRunning
+	job.Attempts++
+	job.LeaseUntil = now.Add(lease)
+	return *job
+}
+
+func (s *memoryStore) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.KnowledgeImportJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []models.KnowledgeImportJob
+	for _, job := range s.jobs {
+		if len(claimed) == limit {
+			break
+		}
+		if s.claimable(job, now) {
+			claimed = append(claimed, s.claim(job, now, lease))
+		}
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) ClaimJob(ctx context.Context, jobID uint, now time.Time, lease time.Duration) (*models.KnowledgeImportJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.jobs[jobID-1]
+	if !s.claimable(job, now) {
+		return nil, nil
+	}
+	claimed := s.claim(job, now, lease)
+	return &claimed, nil
+}
+
+func (s *memoryStore) Extend(ctx context.Context, jobID uint, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[jobID-1].LeaseUntil = until
+	return nil
+}
+
+func (s *memoryStore) Finish(ctx context.Context, jobID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[jobID-1].Status = models.ImportSucceeded
+	return nil
+}
+
+func (s *memoryStore) Fail(ctx context.Context, jobID uint, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[jobID-1].Status = models.ImportFailed
+	s.jobs[jobID-1].Error = reason
+	return nil
+}
+
+func (s *memoryStore) job(id uint) models.KnowledgeImportJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.jobs[id-1]
+}
+
+// fakeExecutor 记录执行时任务所处的阶段，按任务ID返回预设错误
+type fakeExecutor struct {
+	errs   map[uint]error
+	stages []string
+}
+
+func (e *fakeExecutor) Execute(ctx context.Context, job *models.KnowledgeImportJob) error {
+	e.stages = append(e.stages, job.Stage)
+	return e.errs[job.JobID]
+}
+
+func TestRunnerFinishesAndFailsJobs(t *testing.T) {
+	store := &memoryStore{}
+	ok := store.add(models.KnowledgeImportJob{Stage: models.ImportStagePrepare})
+	bad := store.add(models.KnowledgeImportJob{Stage: models.ImportStagePrepare})
+	executor := &fakeExecutor{errs: map[uint]error{bad: errors.New("archive corrupted")}}
+
+	n, err := NewRunner(store, executor, Options{}).RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, models.ImportSucceeded, store.job(ok).Status)
+	assert.Equal(t, models.ImportFailed, store.job(bad).Status)
+	assert.Equal(t, "archive corrupted", store.job(bad).Error)
+
+	// 失败的任务不会自动重试
+	n, err = NewRunner(store, executor, Options{}).RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestRunnerResumesJobAfterLeaseExpires(t *testing.T) {
+	store := &memoryStore{}
+	// 执行中的副本退出，任务停留在文档阶段
+	id := store.add(models.KnowledgeImportJob{
+		Status:            models.ImportRunning,
+		Stage:             models.ImportStageDocuments,
+		ImportedDocuments: 3,
+		LeaseUntil:        time.Now().Add(time.Minute),
+	})
+	executor := &fakeExecutor{}
+	runner := NewRunner(store, executor, Options{})
+
+	claimed, err := runner.RunJob(context.Background(), id)
+	require.NoError(t, err)
+	assert.False(t, claimed, "lease still held")
+
+	store.jobs[id-1].LeaseUntil = time.Now().Add(-time.Second)
+	claimed, err = runner.RunJob(context.Background(), id)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	assert.Equal(t, []string{models.ImportStageDocuments}, executor.stages)
+	assert.Equal(t, models.ImportSucceeded, store.job(id).Status)
+	assert.Equal(t, 1, store.job(id).Attempts)
+}
+
+func TestRunnerLeavesJobToOtherWorker(t *testing.T) {
+	store := &memoryStore{}
+	id := store.add(models.KnowledgeImportJob{Stage: models.ImportStageDocuments})
+	executor := &fakeExecutor{errs: map[uint]error{id: errJobMoved}}
+
+	_, err := NewRunner(store, executor, Options{}).RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, models.ImportRunning, store.job(id).Status)
+	assert.Empty(t, store.job(id).Error)
+}
+
+func TestProgress(t *testing.T) {
+	assert.Zero(t, Progress(&models.KnowledgeImportJob{Stage: models.ImportStagePrepare}))
+	assert.InDelta(t, 0.45, Progress(&models.KnowledgeImportJob{
+		Stage: models.ImportStageDocuments, TotalDocuments: 4, ImportedDocuments: 2,
+	}), 1e-9)
+	assert.InDelta(t, 0.9, Progress(&models.KnowledgeImportJob{Stage: models.ImportStageIndex}), 1e-9)
+	assert.Equal(t, 1.0, Progress(&models.KnowledgeImportJob{Stage: models.ImportStageDone}))
+}
@@ -0,0 +1,236 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// ErrObjectNotFound 对象不存在
+var ErrObjectNotFound = errors.New("object not found")
+
+// Objects 文档源文件所在的对象存储
+type Objects interface {
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+}
+
+// Archive 打开的归档文件，支持随机读取
+type Archive interface {
+	io.ReaderAt
+	io.Closer
+	Size() int64
+}
+
+// ArchiveStore 保存待导入的归档，导入任务中断后从中重新打开
+type ArchiveStore interface {
+	Save(ctx context.Context, key string, r io.Reader, size int64) error
+	Open(ctx context.Context, key string) (Archive, error)
+	Remove(ctx context.Context, key string) error
+}
+
+// MinIOObjects 基于MinIO的对象存储
+type MinIOObjects struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewMinIOObjects 创建MinIO对象存储
+func NewMinIOObjects(client *minio.Client, bucket string) *MinIOObjects {
+	return &MinIOObjects{client: client, bucket: bucket}
+}
+
+func (o *MinIOObjects) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	object, err := o.client.GetObject(ctx, o.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, mapMinIOError(err)
+	}
+	// GetObject不访问服务端，Stat确认对象存在
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		return nil, mapMinIOError(err)
+	}
+	return object, nil
+}
+
+func (o *MinIOObjects) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	_, err := o.client.PutObject(ctx, o.bucket, key, r, size, minio.PutObjectOptions{})
+	return err
+}
+
+// MinIOArchiveStore 把归档保存在MinIO中，多个副本都能继续导入
+type MinIOArchiveStore struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewMinIOArchiveStore 创建MinIO归档存储
+func NewMinIOArchiveStore(client *minio.Client, bucket string) *MinIOArchiveStore {
+	return &MinIOArchiveStore{client: client, bucket: bucket}
+}
+
+func (s *MinIOArchiveStore) Save(ctx context.Context, key string, r io.Reader, size int64) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: "application/zip"})
+	return err
+}
+
+func (s *MinIOArchiveStore) Open(ctx context.Context, key string) (Archive, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, mapMinIOError(err)
+	}
+	info, err := object.Stat()
+	if err != nil {
+		object.Close()
+		return nil, mapMinIOError(err)
+	}
+	return &minioArchive{Object: object, size: info.Size}, nil
+}
+
+func (s *MinIOArchiveStore) Remove(ctx context.Context, key string) error {
+	err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+	if err != nil && !errors.Is(mapMinIOError(err), ErrObjectNotFound) {
+		return err
+	}
+	return nil
+}
+
+type minioArchive struct {
+	*minio.Object
+	size int64
+}
+
+func (a *minioArchive) Size() int64 { return a.size }
+
+func mapMinIOError(err error) error {
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NoSuchBucket":
+		return fmt.Errorf("%w: %v", ErrObjectNotFound, err)
+	}
+	return err
+}
+
+// DirObjects 基于本地目录的对象存储，单机模式下代替MinIO，对象键即相对路径
+type DirObjects struct {
+	dir string
+}
+
+// NewDirObjects 创建本地目录对象存储
+func NewDirObjects(dir string) *DirObjects {
+	return &DirObjects{dir: dir}
+}
+
+func (o *DirObjects) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := localPath(o.dir, key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+	return f, err
+}
+
+func (o *DirObjects) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	path, err := localPath(o.dir, key)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, r)
+}
+
+// DirArchiveStore 把归档保存在本地目录，适用于没有对象存储的单机部署
+type DirArchiveStore struct {
+	dir string
+}
+
+// NewDirArchiveStore 创建本地目录归档存储
+func NewDirArchiveStore(dir string) *DirArchiveStore {
+	return &DirArchiveStore{dir: dir}
+}
+
+func (s *DirArchiveStore) path(key string) (string, error) {
+	return localPath(s.dir, key)
+}
+
+func (s *DirArchiveStore) Save(ctx context.Context, key string, r io.Reader, size int64) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, r)
+}
+
+func (s *DirArchiveStore) Open(ctx context.Context, key string) (Archive, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &fileArchive{File: f, size: info.Size()}, nil
+}
+
+func (s *DirArchiveStore) Remove(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+type fileArchive struct {
+	*os.File
+	size int64
+}
+
+func (a *fileArchive) Size() int64 { return a.size }
+
+// localPath 对象键对应的本地路径，拒绝跳出根目录的键
+func localPath(dir, key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return filepath.Join(dir, clean), nil
+}
+
+// writeFileAtomic 先写临时文件再重命名，避免中断后留下不完整的文件
+func writeFileAtomic(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
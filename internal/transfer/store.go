@@ -0,0 +1,98 @@
+package transfer
+
+import (
+	"context"
+	"time"
+
+	"github.com/aihub/backend-go/internal/database"
+	"github.com/aihub/backend-go/internal/models"
+	"gorm.io/gorm"
+)
+
+// Store 导入任务存储
+type Store interface {
+	// Claim 领取待执行或租约已过期的任务，递增attempts并设置租约，
+	// 同一任务不会被多个副本同时执行，执行进程退出后租约到期可被重新领取
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.KnowledgeImportJob, error)
+	// ClaimJob 按ID领取任务，任务不可领取时返回nil
+	ClaimJob(ctx context.Context, jobID uint, now time.Time, lease time.Duration) (*models.KnowledgeImportJob, error)
+	// Extend 延长执行中任务的租约
+	Extend(ctx context.Context, jobID uint, until time.Time) error
+	// Finish 标记任务成功
+	Finish(ctx context.Context, jobID uint) error
+	// Fail 标记任务失败，失败的任务需要手动恢复
+	Fail(ctx context.Context, jobID uint, reason string) error
+}
+
+// GormStore 基于数据库的导入任务存储
+type GormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore 创建数据库存储
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+func (s *GormStore) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.KnowledgeImportJob, error) {
+	var jobs []models.KnowledgeImportJob
+	err := s.db.WithContext(ctx).Raw(`
+		UPDATE knowledge_import_jobs
+		SET status = ?, attempts = attempts + 1, lease_until = ?, update_time = ?
+		WHERE job_id IN (
+			SELECT job_id FROM knowledge_import_jobs
+			WHERE status = ? OR (status = ? AND lease_until < ?)
+			ORDER BY job_id
+			LIMIT ?
+			`+database.SkipLocked(s.db)+`
+		)
+		RETURNING *`,
+		models.ImportRunning, now.Add(lease), now, models.ImportPending, models.ImportRunning, now, limit).
+		Scan(&jobs).Error
+	return jobs, err
+}
+
+func (s *GormStore) ClaimJob(ctx context.Context, jobID uint, now time.Time, lease time.Duration) (*models.KnowledgeImportJob, error) {
+	var jobs []models.KnowledgeImportJob
+	err := s.db.WithContext(ctx).Raw(`
+		UPDATE knowledge_import_jobs
+		SET status = ?, attempts = attempts + 1, lease_until = ?, update_time = ?
+		WHERE job_id = ? AND (status = ? OR (status = ? AND lease_until < ?))
+		RETURNING *`,
+		models.ImportRunning, now.Add(lease), now, jobID, models.ImportPending, models.ImportRunning, now).
+		Scan(&jobs).Error
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+func (s *GormStore) Extend(ctx context.Context, jobID uint, until time.Time) error {
+	return s.db.WithContext(ctx).Model(&models.KnowledgeImportJob{}).
+		Where("job_id = ? AND status = ?", jobID, models.ImportRunning).
+		Update("lease_until", until).Error
+}
+
+func (s *GormStore) Finish(ctx context.Context, jobID uint) error {
+	now := time.Now()
+	return s.db.WithContext(ctx).Model(&models.KnowledgeImportJob{}).
+		Where("job_id = ? AND status = ?", jobID, models.ImportRunning).
+		Updates(map[string]interface{}{
+			"status":      models.ImportSucceeded,
+			"error":       "",
+			"finished_at": now,
+			"update_time": now,
+		}).Error
+}
+
+func (s *GormStore) Fail(ctx context.Context, jobID uint, reason string) error {
+	now := time.Now()
+	return s.db.WithContext(ctx).Model(&models.KnowledgeImportJob{}).
+		Where("job_id = ? AND status = ?", jobID, models.ImportRunning).
+		Updates(map[string]interface{}{
+			"status":      models.ImportFailed,
+			"error":       truncate(reason, maxImportErrorLength),
+			"finished_at": now,
+			"update_time": now,
+		}).Error
+}
@@ -0,0 +1,194 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aihub/backend-go/internal/knowledge"
+	"github.com/minio/minio-go/v7"
+)
+
+// DocumentRef 待清理的文档
+type DocumentRef struct {
+	KnowledgeBaseID uint
+	DocumentID      uint
+	FilePath        string
+}
+
+// Backend 需要清理的外部存储，实现必须幂等：数据已不存在时返回nil
+type Backend interface {
+	Name() string
+	PurgeDocument(ctx context.Context, doc DocumentRef) error
+}
+
+// KnowledgeBaseBackend 支持按知识库整体清理的存储（可选接口），在逐个文档清理之后调用
+type KnowledgeBaseBackend interface {
+	PurgeKnowledgeBase(ctx context.Context, knowledgeBaseID uint) error
+}
+
+// VectorBackend 清理向量存储
+type VectorBackend struct {
+	store knowledge.VectorStore
+}
+
+// NewVectorBackend 创建向量存储清理器
+func NewVectorBackend(store knowledge.VectorStore) *VectorBackend {
+	return &VectorBackend{store: store}
+}
+
+func (b *VectorBackend) Name() string { return "vector" }
+
+func (b *VectorBackend) PurgeDocument(ctx context.Context, doc DocumentRef) error {
+	// 存储暂不可用时返回错误等待重试，避免遗留向量
+	if !b.store.Ready() {
+		return errors.New("vector store not ready")
+	}
+	return b.store.DeleteDocument(ctx, doc.KnowledgeBaseID, doc.DocumentID)
+}
+
+// FulltextBackend 清理全文索引
+type FulltextBackend struct {
+	indexer knowledge.FulltextIndexer
+}
+
+// NewFulltextBackend 创建全文索引清理器
+func NewFulltextBackend(indexer knowledge.FulltextIndexer) *FulltextBackend {
+	return &FulltextBackend{indexer: indexer}
+}
+
+func (b *FulltextBackend) Name() string { return "fulltext" }
+
+func (b *FulltextBackend) PurgeDocument(ctx context.Context, doc DocumentRef) error {
+	if !b.indexer.Ready() {
+		return errors.New("fulltext indexer not ready")
+	}
+	return b.indexer.RemoveDocument(ctx, doc.KnowledgeBaseID, doc.DocumentID)
+}
+
+// ChunkCache 文档分块缓存，*services.RedisChunkStore实现了该接口
+type ChunkCache interface {
+	DeleteDocumentChunks(ctx context.Context, documentID uint) error
+}
+
+// ChunkCacheBackend 清理Redis中的分块缓存
+type ChunkCacheBackend struct {
+	cache ChunkCache
+}
+
+// NewChunkCacheBackend 创建分块缓存清理器
+func NewChunkCacheBackend(cache ChunkCache) *ChunkCacheBackend {
+	return &ChunkCacheBackend{cache: cache}
+}
+
+func (b *ChunkCacheBackend) Name() string { return "chunk_cache" }
+
+func (b *ChunkCacheBackend) PurgeDocument(ctx context.Context, doc DocumentRef) error {
+	return b.cache.DeleteDocumentChunks(ctx, doc.DocumentID)
+}
+
+// ObjectBackend 清理MinIO中的源文件、版本快照与上传对象
+type ObjectBackend struct {
+	client         *minio.Client
+	documentBucket string // 文档源文件与版本快照所在的桶
+	uploadBucket   string // MinIOService.UploadKnowledgeDocument使用的桶
+}
+
+// NewObjectBackend 创建对象存储清理器
+func NewObjectBackend(client *minio.Client, documentBucket, uploadBucket string) *ObjectBackend {
+	return &ObjectBackend{client: client, documentBucket: documentBucket, uploadBucket: uploadBucket}
+}
+
+func (b *ObjectBackend) Name() string { return "object" }
+
+func (b *ObjectBackend) PurgeDocument(ctx context.Context, doc DocumentRef) error {
+	if doc.FilePath != "" {
+		if err := b.removeObject(ctx, b.documentBucket, doc.FilePath); err != nil {
+			return err
+		}
+	}
+	prefix := fmt.Sprintf("knowledge-bases/%d/versions/%d/", doc.KnowledgeBaseID, doc.DocumentID)
+	if err := b.removePrefix(ctx, b.documentBucket, prefix); err != nil {
+		return err
+	}
+	return b.removeObject(ctx, b.uploadBucket, fmt.Sprintf("knowledge/%d/%d", doc.KnowledgeBaseID, doc.DocumentID))
+}
+
+func (b *ObjectBackend) PurgeKnowledgeBase(ctx context.Context, knowledgeBaseID uint) error {
+	if err := b.removePrefix(ctx, b.documentBucket, fmt.Sprintf("knowledge-bases/%d/", knowledgeBaseID)); err != nil {
+		return err
+	}
+	return b.removePrefix(ctx, b.uploadBucket, fmt.Sprintf("knowledge/%d/", knowledgeBaseID))
+}
+
+// removeObject 删除单个对象，对象或桶不存在时视为成功
+func (b *ObjectBackend) removeObject(ctx context.Context, bucket, key string) error {
+	if bucket == "" {
+		return nil
+	}
+	err := b.client.RemoveObject(ctx, bucket, key, minio.RemoveObjectOptions{})
+	if err != nil && !isMissing(err) {
+		return fmt.Errorf("failed to remove %s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// removePrefix 删除前缀下的全部对象
+func (b *ObjectBackend) removePrefix(ctx context.Context, bucket, prefix string) error {
+	if bucket == "" {
+		return nil
+	}
+	// 提前返回时取消列举，避免列举协程阻塞
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	objects := b.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true})
+	for object := range objects {
+		if object.Err != nil {
+			if isMissing(object.Err) {
+				return nil
+			}
+			return fmt.Errorf("failed to list %s/%s: %w", bucket, prefix, object.Err)
+		}
+		if err := b.removeObject(ctx, bucket, object.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isMissing(err error) bool {
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchBucket", "NoSuchKey":
+		return true
+	}
+	return false
+}
+
+// DirObjectBackend 清理本地目录中的源文件与版本快照，单机模式下代替ObjectBackend，目录结构与文档桶一致
+type DirObjectBackend struct {
+	dir string
+}
+
+// NewDirObjectBackend 创建本地目录清理器
+func NewDirObjectBackend(dir string) *DirObjectBackend {
+	return &DirObjectBackend{dir: dir}
+}
+
+func (b *DirObjectBackend) Name() string { return "object" }
+
+func (b *DirObjectBackend) PurgeDocument(ctx context.Context, doc DocumentRef) error {
+	if doc.FilePath != "" && !strings.Contains(doc.FilePath, "..") {
+		path := filepath.Join(b.dir, filepath.Clean("/"+doc.FilePath))
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", doc.FilePath, err)
+		}
+	}
+	return os.RemoveAll(filepath.Join(b.dir, fmt.Sprintf("knowledge-bases/%d/versions/%d", doc.KnowledgeBaseID, doc.DocumentID)))
+}
+
+func (b *DirObjectBackend) PurgeKnowledgeBase(ctx context.Context, knowledgeBaseID uint) error {
+	return os.RemoveAll(filepath.Join(b.dir, fmt.Sprintf("knowledge-bases/%d", knowledgeBaseID)))
+}
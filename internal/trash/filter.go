@@ -0,0 +1,34 @@
+package trash
+
+import (
+	"context"
+
+	"github.com/aihub/backend-go/internal/models"
+	"gorm.io/gorm"
+)
+
+// SearchFilter 检索时排除回收站中的文档，回收站中的文档在各索引中保留到清理为止
+type SearchFilter struct {
+	db *gorm.DB
+}
+
+// NewSearchFilter 创建回收站检索过滤
+func NewSearchFilter(db *gorm.DB) *SearchFilter {
+	return &SearchFilter{db: db}
+}
+
+// ExcludedDocuments 知识库中位于回收站的文档
+func (f *SearchFilter) ExcludedDocuments(ctx context.Context, knowledgeBaseID uint) (map[uint]bool, error) {
+	var ids []uint
+	err := f.db.WithContext(ctx).Unscoped().Model(&models.KnowledgeDocument{}).
+		Where("knowledge_base_id = ? AND deleted_at IS NOT NULL", knowledgeBaseID).
+		Pluck("document_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	excluded := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		excluded[id] = true
+	}
+	return excluded, nil
+}
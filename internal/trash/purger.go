@@ -0,0 +1,178 @@
+package trash
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aihub/backend-go/internal/logger"
+	"github.com/aihub/backend-go/internal/models"
+	"go.uber.org/zap"
+)
+
+// Options 清理任务参数，零值使用默认值
+type Options struct {
+	BatchSize    int           // 每轮领取的任务数，默认10
+	PollInterval time.Duration // 轮询间隔，默认5m
+	Lease        time.Duration // 领取后的处理时限，超时可被其他副本重新领取，默认30m
+	BaseBackoff  time.Duration // 失败后的退避基数（按2的指数增长），默认1m
+	MaxBackoff   time.Duration // 单次退避上限，默认6h
+}
+
+const (
+	defaultPurgeBatchSize    = 10
+	defaultPurgePollInterval = 5 * time.Minute
+	defaultPurgeLease        = 30 * time.Minute
+	defaultPurgeBaseBackoff  = time.Minute
+	defaultPurgeMaxBackoff   = 6 * time.Hour
+	maxPurgeErrorLength      = 500
+)
+
+// Purger 回收站清理任务，保留期满后从每个存储中删除条目的数据，最后删除数据库记录
+// 各存储的清理都是幂等的，某个存储失败时记录已完成的存储，重试时只处理剩余的存储
+type Purger struct {
+	store    Store
+	backends []Backend
+	opts     Options
+}
+
+// NewPurger 创建清理任务
+func NewPurger(store Store, backends []Backend, opts Options) *Purger {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultPurgeBatchSize
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultPurgePollInterval
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = defaultPurgeLease
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = defaultPurgeBaseBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultPurgeMaxBackoff
+	}
+	return &Purger{store: store, backends: backends, opts: opts}
+}
+
+// Run 后台清理循环，直到ctx取消
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := p.RunOnce(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Warn("Failed to purge trash", zap.Error(err))
+				}
+				break
+			}
+			if n < p.opts.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 处理一批到期任务，返回领取的任务数
+func (p *Purger) RunOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+	tasks, err := p.store.Claim(ctx, now, p.opts.Lease, p.opts.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i := range tasks {
+		if err := p.purge(ctx, &tasks[i]); err != nil {
+			return len(tasks), err
+		}
+	}
+	return len(tasks), nil
+}
+
+// purge 处理单个任务，存储清理失败只记录在任务上，返回的错误表示任务状态无法保存
+func (p *Purger) purge(ctx context.Context, task *models.KnowledgePurgeTask) error {
+	docs, err := p.store.Documents(ctx, task)
+	if err != nil {
+		return p.fail(ctx, task, task.PurgedStores, err)
+	}
+
+	done := make(map[string]bool, len(task.PurgedStores))
+	for _, name := range task.PurgedStores {
+		done[name] = true
+	}
+	purged := append([]string(nil), task.PurgedStores...)
+	var failures []string
+	for _, backend := range p.backends {
+		if done[backend.Name()] {
+			continue
+		}
+		if err := p.purgeBackend(ctx, backend, task, docs); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", backend.Name(), err))
+			continue
+		}
+		purged = append(purged, backend.Name())
+	}
+	if len(failures) > 0 {
+		return p.fail(ctx, task, purged, fmt.Errorf("%s", strings.Join(failures, "; ")))
+	}
+
+	if err := p.store.Finish(ctx, task); err != nil {
+		return p.fail(ctx, task, purged, err)
+	}
+	logger.Info("Trash item purged",
+		zap.Uint("task_id", task.TaskID),
+		zap.String("type", task.ItemType),
+		zap.Uint("kb_id", task.KnowledgeBaseID),
+		zap.Uint("doc_id", task.DocumentID),
+		zap.Int("documents", len(docs)))
+	return nil
+}
+
+func (p *Purger) purgeBackend(ctx context.Context, backend Backend, task *models.KnowledgePurgeTask, docs []DocumentRef) error {
+	for _, doc := range docs {
+		if err := backend.PurgeDocument(ctx, doc); err != nil {
+			return fmt.Errorf("document %d: %w", doc.DocumentID, err)
+		}
+	}
+	if kbBackend, ok := backend.(KnowledgeBaseBackend); ok && task.ItemType == models.TrashItemKnowledgeBase {
+		return kbBackend.PurgeKnowledgeBase(ctx, task.KnowledgeBaseID)
+	}
+	return nil
+}
+
+// fail 记录失败并安排重试
+func (p *Purger) fail(ctx context.Context, task *models.KnowledgePurgeTask, purged []string, cause error) error {
+	next := time.Now().Add(p.backoff(task.Attempts))
+	logger.Warn("Failed to purge trash item",
+		zap.Uint("task_id", task.TaskID),
+		zap.String("type", task.ItemType),
+		zap.Int("attempts", task.Attempts),
+		zap.Time("next_attempt_at", next),
+		zap.Error(cause))
+	if err := p.store.MarkFailed(ctx, task.TaskID, purged, next, truncate(cause.Error(), maxPurgeErrorLength)); err != nil {
+		return fmt.Errorf("failed to record purge failure: %w", err)
+	}
+	return nil
+}
+
+// backoff 第attempt次失败后的等待时间
+func (p *Purger) backoff(attempt int) time.Duration {
+	wait := p.opts.BaseBackoff
+	for i := 1; i < attempt && wait < p.opts.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > p.opts.MaxBackoff {
+		wait = p.opts.MaxBackoff
+	}
+	return wait
+}
@@ -0,0 +1,221 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aihub/backend-go/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore 进程内存储，Claim的筛选规则与GormStore一致
+type memoryStore struct {
+	mu       sync.Mutex
+	tasks    []*models.KnowledgePurgeTask
+	docs     map[uint][]DocumentRef // knowledge_base_id -> 文档
+	finished []uint
+}
+
+func (s *memoryStore) add(task models.KnowledgePurgeTask) *models.KnowledgePurgeTask {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task.TaskID = uint(len(s.tasks) + 1)
+	task.Status = models.PurgePending
+	s.tasks = append(s.tasks, &task)
+	return &task
+}
+
+func (s *memoryStore) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.KnowledgePurgeTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []models.KnowledgePurgeTask
+	for _, task := range s.tasks {
+		if len(claimed) == limit {
+			break
+		}
+		if task.Status != models.PurgePending || task.PurgeAfter.After(now) || task.NextAttemptAt.After(now) {
+			continue
+		}
+		task.Attempts++
+		task.NextAttemptAt = now.Add(lease)
+		claimed = append(claimed, *task)
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) Documents(ctx context.Context, task *models.KnowledgePurgeTask) ([]DocumentRef, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var refs []DocumentRef
+	for _, doc := range s.docs[task.KnowledgeBaseID] {
+		if task.ItemType == models.TrashItemKnowledgeBase || doc.DocumentID == task.DocumentID {
+			refs = append(refs, doc)
+		}
+	}
+	return refs, nil
+}
+
+func (s *memoryStore) MarkFailed(ctx context.Context, taskID uint, purged []string, next time.Time, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task := s.tasks[taskID-1]
+	task.PurgedStores = models.StringArray(purged)
+	task.NextAttemptAt = next
+	task.LastError = lastError
+	return nil
+}
+
+func (s *memoryStore) Finish(ctx context.Context, task *models.KnowledgePurgeTask) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[task.TaskID-1].Status = models.PurgeCompleted
+	s.finished = append(s.finished, task.TaskID)
+	return nil
+}
+
+func (s *memoryStore) task(id uint) models.KnowledgePurgeTask {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.tasks[id-1]
+}
+
+// fakeBackend 记录清理调用，failures次数内返回错误
+type fakeBackend struct {
+	name     string
+	failures int
+	docs     []uint
+	kbs      []uint
+}
+
+func (b *fakeBackend) Name() string { return b.name }
+
+func (b *fakeBackend) PurgeDocument(ctx context.Context, doc DocumentRef) error {
+	if b.failures > 0 {
+		b.failures--
+		return errors.New("unavailable")
+	}
+	b.docs = append(b.docs, doc.DocumentID)
+	return nil
+}
+
+// fakeKnowledgeBaseBackend 同时支持按知识库整体清理
+type fakeKnowledgeBaseBackend struct {
+	fakeBackend
+}
+
+func (b *fakeKnowledgeBaseBackend) PurgeKnowledgeBase(ctx context.Context, knowledgeBaseID uint) error {
+	b.kbs = append(b.kbs, knowledgeBaseID)
+	return nil
+}
+
+func expired() models.KnowledgePurgeTask {
+	past := time.Now().Add(-time.Minute)
+	return models.KnowledgePurgeTask{PurgeAfter: past, NextAttemptAt: past}
+}
+
+func TestPurgerSkipsItemsWithinRetention(t *testing.T) {
+	store := &memoryStore{}
+	task := expired()
+	task.ItemType = models.TrashItemDocument
+	task.PurgeAfter = time.Now().Add(time.Hour)
+	task.NextAttemptAt = task.PurgeAfter
+	store.add(task)
+
+	backend := &fakeBackend{name: "vector"}
+	n, err := NewPurger(store, []Backend{backend}, Options{}).RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Empty(t, backend.docs)
+	assert.Empty(t, store.finished)
+}
+
+func TestPurgerPurgesDocumentFromEveryBackend(t *testing.T) {
+	store := &memoryStore{docs: map[uint][]DocumentRef{
+		1: {{KnowledgeBaseID: 1, DocumentID: 10}, {KnowledgeBaseID: 1, DocumentID: 11}},
+	}}
+	task := expired()
+	task.ItemType = models.TrashItemDocument
+	task.KnowledgeBaseID = 1
+	task.DocumentID = 11
+	store.add(task)
+
+	vector := &fakeBackend{name: "vector"}
+	objects := &fakeKnowledgeBaseBackend{fakeBackend{name: "object"}}
+	n, err := NewPurger(store, []Backend{vector, objects}, Options{}).RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	assert.Equal(t, []uint{11}, vector.docs)
+	assert.Equal(t, []uint{11}, objects.docs)
+	assert.Empty(t, objects.kbs, "document task must not purge the whole knowledge base")
+	assert.Equal(t, []uint{1}, store.finished)
+}
+
+func TestPurgerPurgesKnowledgeBase(t *testing.T) {
+	store := &memoryStore{docs: map[uint][]DocumentRef{
+		2: {{KnowledgeBaseID: 2, DocumentID: 20}, {KnowledgeBaseID: 2, DocumentID: 21}},
+	}}
+	task := expired()
+	task.ItemType = models.TrashItemKnowledgeBase
+	task.KnowledgeBaseID = 2
+	store.add(task)
+
+	vector := &fakeBackend{name: "vector"}
+	objects := &fakeKnowledgeBaseBackend{fakeBackend{name: "object"}}
+	_, err := NewPurger(store, []Backend{vector, objects}, Options{}).RunOnce(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []uint{20, 21}, vector.docs)
+	assert.Equal(t, []uint{20, 21}, objects.docs)
+	assert.Equal(t, []uint{2}, objects.kbs)
+	assert.Equal(t, []uint{1}, store.finished)
+}
+
+func TestPurgerRetriesOnlyFailedBackends(t *testing.T) {
+	store := &memoryStore{docs: map[uint][]DocumentRef{
+		1: {{KnowledgeBaseID: 1, DocumentID: 10}},
+	}}
+	task := expired()
+	task.ItemType = models.TrashItemDocument
+	task.KnowledgeBaseID = 1
+	task.DocumentID = 10
+	store.add(task)
+
+	vector := &fakeBackend{name: "vector"}
+	fulltext := &fakeBackend{name: "fulltext", failures: 1}
+	purger := NewPurger(store, []Backend{vector, fulltext}, Options{BaseBackoff: time.Minute})
+
+	_, err := purger.RunOnce(context.Background())
+	require.NoError(t, err)
+
+	failed := store.task(1)
+	assert.Equal(t, models.PurgePending, failed.Status)
+	assert.Equal(t, models.StringArray{"vector"}, failed.PurgedStores)
+	assert.Contains(t, failed.LastError, "fulltext: document 10: unavailable")
+	assert.True(t, failed.NextAttemptAt.After(time.Now()))
+	assert.Empty(t, store.finished)
+
+	// 到达重试时间后只重试失败的存储
+	store.tasks[0].NextAttemptAt = time.Now().Add(-time.Second)
+	_, err = purger.RunOnce(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []uint{10}, vector.docs, "purged backend must not run again")
+	assert.Equal(t, []uint{10}, fulltext.docs)
+	assert.Equal(t, []uint{1}, store.finished)
+	assert.Equal(t, 2, store.task(1).Attempts)
+}
+
+func TestPurgerBackoff(t *testing.T) {
+	purger := NewPurger(&memoryStore{}, nil, Options{BaseBackoff: time.Minute, MaxBackoff: 10 * time.Minute})
+
+	assert.Equal(t, time.Minute, purger.backoff(1))
+	assert.Equal(t, 2*time.Minute, purger.backoff(2))
+	assert.Equal(t, 8*time.Minute, purger.backoff(4))
+	assert.Equal(t, 10*time.Minute, purger.backoff(5))
+	assert.Equal(t, 10*time.Minute, purger.backoff(30))
+}
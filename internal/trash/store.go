@@ -0,0 +1,160 @@
+package trash
+
+import (
+	"context"
+	"time"
+
+	"github.com/aihub/backend-go/internal/database"
+	"github.com/aihub/backend-go/internal/models"
+	"gorm.io/gorm"
+)
+
+// Store 清理任务存储
+type Store interface {
+	// Claim 领取保留期满且到达重试时间的任务，递增attempts并把next_attempt_at延后lease作为租约，
+	// 同一任务不会被多个副本同时处理，处理进程退出后租约到期可被重新领取
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.KnowledgePurgeTask, error)
+	// Documents 任务涉及的文档，包括回收站中的文档
+	Documents(ctx context.Context, task *models.KnowledgePurgeTask) ([]DocumentRef, error)
+	// MarkFailed 记录已完成清理的存储与失败原因，next之前不再重试
+	MarkFailed(ctx context.Context, taskID uint, purged []string, next time.Time, lastError string) error
+	// Finish 删除数据库中的数据并标记任务完成
+	Finish(ctx context.Context, task *models.KnowledgePurgeTask) error
+}
+
+// GormStore 基于数据库的清理任务存储
+type GormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore 创建数据库存储
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+func (s *GormStore) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.KnowledgePurgeTask, error) {
+	var tasks []models.KnowledgePurgeTask
+	err := s.db.WithContext(ctx).Raw(`
+		UPDATE knowledge_purge_tasks
+		SET attempts = attempts + 1, next_attempt_at = ?, update_time = ?
+		WHERE task_id IN (
+			SELECT task_id FROM knowledge_purge_tasks
+			WHERE status = ? AND purge_after <= ? AND next_attempt_at <= ?
+			ORDER BY purge_after
+			LIMIT ?
+			`+database.SkipLocked(s.db)+`
+		)
+		RETURNING *`,
+		now.Add(lease), now, models.PurgePending, now, now, limit).
+		Scan(&tasks).Error
+	return tasks, err
+}
+
+func (s *GormStore) Documents(ctx context.Context, task *models.KnowledgePurgeTask) ([]DocumentRef, error) {
+	query := s.db.WithContext(ctx).Unscoped().Model(&models.KnowledgeDocument{}).
+		Select("knowledge_base_id, document_id, file_path")
+	if task.ItemType == models.TrashItemDocument {
+		query = query.Where("document_id = ?", task.DocumentID)
+	} else {
+		query = query.Where("knowledge_base_id = ?", task.KnowledgeBaseID)
+	}
+	var refs []DocumentRef
+	if err := query.Order("document_id").Scan(&refs).Error; err != nil {
+		return nil, err
+	}
+	// 文档行已删除时仍按ID清理外部存储
+	if len(refs) == 0 && task.ItemType == models.TrashItemDocument {
+		refs = append(refs, DocumentRef{KnowledgeBaseID: task.KnowledgeBaseID, DocumentID: task.DocumentID})
+	}
+	return refs, nil
+}
+
+func (s *GormStore) MarkFailed(ctx context.Context, taskID uint, purged []string, next time.Time, lastError string) error {
+	return s.db.WithContext(ctx).Model(&models.KnowledgePurgeTask{}).
+		Where("task_id = ?", taskID).
+		Updates(map[string]interface{}{
+			"purged_stores":   models.StringArray(purged),
+			"next_attempt_at": next,
+			"last_error":      lastError,
+			"update_time":     time.Now(),
+		}).Error
+}
+
+func (s *GormStore) Finish(ctx context.Context, task *models.KnowledgePurgeTask) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if task.ItemType == models.TrashItemDocument {
+			if err := purgeDocumentRows(tx, "document_id = ?", task.DocumentID); err != nil {
+				return err
+			}
+		} else if err := purgeKnowledgeBaseRows(tx, task.KnowledgeBaseID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Model(&models.KnowledgePurgeTask{}).
+			Where("task_id = ?", task.TaskID).
+			Updates(map[string]interface{}{
+				"status":       models.PurgeCompleted,
+				"completed_at": now,
+				"last_error":   "",
+				"update_time":  now,
+			}).Error
+	})
+}
+
+// purgeDocumentRows 删除文档及其分块与版本记录，只删除仍在回收站中的文档
+func purgeDocumentRows(tx *gorm.DB, where string, arg interface{}) error {
+	docIDs := tx.Unscoped().Model(&models.KnowledgeDocument{}).
+		Select("document_id").
+		Where(where+" AND deleted_at IS NOT NULL", arg)
+	if err := tx.Where("document_id IN (?)", docIDs).Delete(&models.KnowledgeChunk{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("document_id IN (?)", docIDs).Delete(&models.KnowledgeDocumentVersion{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where(where+" AND deleted_at IS NOT NULL", arg).Delete(&models.KnowledgeDocument{}).Error
+}
+
+// purgeKnowledgeBaseRows 删除知识库及其全部文档、词库、评测、搜索记录与Webhook
+func purgeKnowledgeBaseRows(tx *gorm.DB, kbID uint) error {
+	// 知识库在回收站中时其文档不必单独移入回收站，这里先统一标记
+	err := tx.Unscoped().Model(&models.KnowledgeDocument{}).
+		Where("knowledge_base_id = ? AND deleted_at IS NULL", kbID).
+		Update("deleted_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+	if err := purgeDocumentRows(tx, "knowledge_base_id = ?", kbID); err != nil {
+		return err
+	}
+
+	dependents := []interface{}{
+		&models.KnowledgeWebhookDelivery{},
+		&models.KnowledgeWebhook{},
+		&models.KnowledgeSynonym{},
+		&models.KnowledgeLexiconWord{},
+		&models.SearchEvaluationRun{},
+		&models.KnowledgeSearch{},
+	}
+	for _, model := range dependents {
+		if err := tx.Where("knowledge_base_id = ?", kbID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+
+	// 该知识库中单独删除的文档已一并清理
+	err = tx.Model(&models.KnowledgePurgeTask{}).
+		Where("knowledge_base_id = ? AND item_type = ? AND status = ?", kbID, models.TrashItemDocument, models.PurgePending).
+		Updates(map[string]interface{}{
+			"status":       models.PurgeCompleted,
+			"completed_at": time.Now(),
+			"update_time":  time.Now(),
+		}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Unscoped().
+		Where("knowledge_base_id = ? AND deleted_at IS NOT NULL", kbID).
+		Delete(&models.KnowledgeBase{}).Error
+}
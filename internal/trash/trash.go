@@ -0,0 +1,167 @@
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aihub/backend-go/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultRetention 未配置时回收站的保留时间
+const defaultRetention = 30 * 24 * time.Hour
+
+var (
+	// ErrNotFound 条目不存在或已在回收站中
+	ErrNotFound = errors.New("trash item not found")
+	// ErrPurgeStarted 清理已开始，条目不能再恢复
+	ErrPurgeStarted = errors.New("purge already started")
+	// ErrParentTrashed 文档所属的知识库也在回收站中，需先恢复知识库
+	ErrParentTrashed = errors.New("knowledge base is in trash")
+)
+
+// retention 回收站保留时间，启动流程按配置设置
+var retention atomic.Int64
+
+// SetRetention 设置保留时间，只影响之后移入回收站的条目
+func SetRetention(d time.Duration) {
+	retention.Store(int64(d))
+}
+
+// Retention 当前的保留时间
+func Retention() time.Duration {
+	if d := time.Duration(retention.Load()); d > 0 {
+		return d
+	}
+	return defaultRetention
+}
+
+// Item 移入回收站的条目
+type Item struct {
+	Type            string // models.TrashItemDocument | models.TrashItemKnowledgeBase
+	KnowledgeBaseID uint
+	DocumentID      uint
+	Title           string
+	DeletedBy       uint
+}
+
+// MoveToTrash 在调用方的事务中软删除条目并登记清理任务
+// 数据在保留期内仍留在各存储中，恢复时无需重建索引
+func MoveToTrash(tx *gorm.DB, item Item) (*models.KnowledgePurgeTask, error) {
+	var result *gorm.DB
+	switch item.Type {
+	case models.TrashItemDocument:
+		result = tx.Where("document_id = ? AND knowledge_base_id = ?", item.DocumentID, item.KnowledgeBaseID).
+			Delete(&models.KnowledgeDocument{})
+	case models.TrashItemKnowledgeBase:
+		item.DocumentID = 0
+		result = tx.Where("knowledge_base_id = ?", item.KnowledgeBaseID).Delete(&models.KnowledgeBase{})
+	default:
+		return nil, fmt.Errorf("unknown trash item type %q", item.Type)
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+
+	now := time.Now()
+	purgeAfter := now.Add(Retention())
+	task := models.KnowledgePurgeTask{
+		ItemType:        item.Type,
+		KnowledgeBaseID: item.KnowledgeBaseID,
+		DocumentID:      item.DocumentID,
+		Title:           truncate(item.Title, 200),
+		Status:          models.PurgePending,
+		PurgeAfter:      purgeAfter,
+		NextAttemptAt:   purgeAfter,
+		DeletedBy:       item.DeletedBy,
+		CreateTime:      now,
+		UpdateTime:      now,
+	}
+	if err := tx.Create(&task).Error; err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// Restore 在调用方的事务中恢复条目并撤销清理任务
+func Restore(tx *gorm.DB, taskID uint) (*models.KnowledgePurgeTask, error) {
+	var task models.KnowledgePurgeTask
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("task_id = ? AND status = ?", taskID, models.PurgePending).
+		First(&task).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 清理任务领取时递增attempts，之后部分存储可能已删除
+	if task.Attempts > 0 || len(task.PurgedStores) > 0 {
+		return nil, ErrPurgeStarted
+	}
+
+	switch task.ItemType {
+	case models.TrashItemDocument:
+		var trashedParents int64
+		err := tx.Unscoped().Model(&models.KnowledgeBase{}).
+			Where("knowledge_base_id = ? AND deleted_at IS NOT NULL", task.KnowledgeBaseID).
+			Count(&trashedParents).Error
+		if err != nil {
+			return nil, err
+		}
+		if trashedParents > 0 {
+			return nil, ErrParentTrashed
+		}
+		err = tx.Unscoped().Model(&models.KnowledgeDocument{}).
+			Where("document_id = ?", task.DocumentID).
+			Update("deleted_at", nil).Error
+		if err != nil {
+			return nil, err
+		}
+	case models.TrashItemKnowledgeBase:
+		err := tx.Unscoped().Model(&models.KnowledgeBase{}).
+			Where("knowledge_base_id = ?", task.KnowledgeBaseID).
+			Update("deleted_at", nil).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Delete(&task).Error; err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// PurgeNow 让条目跳过剩余的保留期，由下一轮清理处理
+func PurgeNow(tx *gorm.DB, taskID uint) error {
+	now := time.Now()
+	result := tx.Model(&models.KnowledgePurgeTask{}).
+		Where("task_id = ? AND status = ? AND purge_after > ?", taskID, models.PurgePending, now).
+		Updates(map[string]interface{}{
+			"purge_after":     now,
+			"next_attempt_at": now,
+			"update_time":     now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
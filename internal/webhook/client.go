@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedDestination Webhook地址指向内网、回环或链路本地地址
+var ErrBlockedDestination = errors.New("webhook destination address not allowed")
+
+// metadataIP 云厂商实例元数据地址
+var metadataIP = net.IPv4(169, 254, 169, 254)
+
+// IsBlockedIP 是否为不允许投递的地址：回环、私有网段（RFC1918、fc00::/7）、链路本地、未指定与组播地址
+func IsBlockedIP(ip net.IP) bool {
+	return ip.Equal(metadataIP) ||
+		ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified()
+}
+
+// CheckHost 解析主机名，任一地址不允许投递时返回ErrBlockedDestination
+// 仅用于创建时提前拒绝，投递时由拨号检查兜底，防止DNS重绑定
+func CheckHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if IsBlockedIP(ip) {
+			return ErrBlockedDestination
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve webhook host: %w", err)
+	}
+	for _, addr := range addrs {
+		if IsBlockedIP(addr.IP) {
+			return ErrBlockedDestination
+		}
+	}
+	return nil
+}
+
+// NewHTTPClient 创建投递使用的HTTP客户端
+// 拨号时检查实际连接的地址，不走环境代理，不跟随重定向（3xx按失败处理）
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: blockPrivateAddress,
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// blockPrivateAddress 拨号前检查解析后的目标地址
+func blockPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || IsBlockedIP(ip) {
+		return ErrBlockedDestination
+	}
+	return nil
+}
@@ -0,0 +1,345 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aihub/backend-go/internal/logger"
+	"github.com/aihub/backend-go/internal/models"
+	"go.uber.org/zap"
+)
+
+// Options 投递参数，零值使用默认值
+type Options struct {
+	Workers      int           // 并发投递数，默认4
+	BatchSize    int           // 每次领取的记录数，默认50
+	PollInterval time.Duration // 轮询到期记录的间隔，默认5s
+	Timeout      time.Duration // 单次请求超时，默认10s
+	MaxAttempts  int           // 最大投递次数，默认8
+	BaseBackoff  time.Duration // 重试退避基数（按2的指数增长），默认30s
+	MaxBackoff   time.Duration // 单次退避上限，默认6h
+	DisableAfter int           // 连续失败多少次后停用Webhook，默认20，负数表示不停用
+	UserAgent    string
+}
+
+const (
+	defaultWebhookWorkers      = 4
+	defaultWebhookBatchSize    = 50
+	defaultWebhookPollInterval = 5 * time.Second
+	defaultWebhookTimeout      = 10 * time.Second
+	defaultWebhookMaxAttempts  = 8
+	defaultWebhookBaseBackoff  = 30 * time.Second
+	defaultWebhookMaxBackoff   = 6 * time.Hour
+	defaultWebhookDisableAfter = 20
+	maxWebhookErrorLength      = 500
+)
+
+// 保存到投递记录的请求错误
+var (
+	errWebhookTimeout       = errors.New("webhook request timed out")
+	errWebhookRequestFailed = errors.New("webhook request failed")
+)
+
+// Dispatcher Webhook投递器
+// Publish把事件写入投递表后立即返回，Run在后台按到期时间领取并投递，失败按指数退避重试
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+	opts   Options
+	wake   chan struct{}
+}
+
+// NewDispatcher 创建投递器，client为nil时使用NewHTTPClient（拒绝内网地址与重定向）
+func NewDispatcher(store Store, client *http.Client, opts Options) *Dispatcher {
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWebhookWorkers
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultWebhookBatchSize
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultWebhookPollInterval
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultWebhookTimeout
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultWebhookMaxAttempts
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = defaultWebhookBaseBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultWebhookMaxBackoff
+	}
+	if opts.DisableAfter == 0 {
+		opts.DisableAfter = defaultWebhookDisableAfter
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = "aihub-knowledge-webhook/1.0"
+	}
+	if client == nil {
+		client = NewHTTPClient(opts.Timeout)
+	}
+	return &Dispatcher{
+		store:  store,
+		client: client,
+		opts:   opts,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Publish 为订阅了该事件的每个Webhook写入一条待投递记录
+func (d *Dispatcher) Publish(ctx context.Context, event Event) error {
+	webhooks, err := d.store.Subscriptions(ctx, event.KnowledgeBaseID, event.Type)
+	if err != nil {
+		return fmt.Errorf("failed to load webhook subscriptions: %w", err)
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+
+	now := time.Now()
+	deliveries := make([]*models.KnowledgeWebhookDelivery, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, &models.KnowledgeWebhookDelivery{
+			WebhookID:       webhook.WebhookID,
+			KnowledgeBaseID: event.KnowledgeBaseID,
+			EventID:         event.ID,
+			EventType:       event.Type,
+			Payload:         string(payload),
+			Status:          models.WebhookDeliveryPending,
+			NextAttemptAt:   now,
+			CreateTime:      now,
+			UpdateTime:      now,
+		})
+	}
+	if err := d.store.Enqueue(ctx, deliveries); err != nil {
+		return fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+	d.notify()
+	return nil
+}
+
+// Replay 重新投递一条记录，新记录沿用原事件ID与请求体
+func (d *Dispatcher) Replay(ctx context.Context, original models.KnowledgeWebhookDelivery) (*models.KnowledgeWebhookDelivery, error) {
+	now := time.Now()
+	replayOf := original.DeliveryID
+	delivery := &models.KnowledgeWebhookDelivery{
+		WebhookID:       original.WebhookID,
+		KnowledgeBaseID: original.KnowledgeBaseID,
+		EventID:         original.EventID,
+		EventType:       original.EventType,
+		Payload:         original.Payload,
+		Status:          models.WebhookDeliveryPending,
+		NextAttemptAt:   now,
+		ReplayOf:        &replayOf,
+		CreateTime:      now,
+		UpdateTime:      now,
+	}
+	if err := d.store.Enqueue(ctx, []*models.KnowledgeWebhookDelivery{delivery}); err != nil {
+		return nil, fmt.Errorf("failed to enqueue webhook replay: %w", err)
+	}
+	d.notify()
+	return delivery, nil
+}
+
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run 后台投递循环，直到ctx取消
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := d.RunOnce(ctx)
+			if err != nil {
+				logger.Warn("Failed to claim webhook deliveries", zap.Error(err))
+				break
+			}
+			if n < d.opts.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// RunOnce 领取一批到期记录并投递，返回领取数量
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	// 租约覆盖单次请求超时，留出保存结果的余量
+	lease := 2*d.opts.Timeout + time.Minute
+	deliveries, err := d.store.ClaimDue(ctx, time.Now(), d.opts.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	sem := make(chan struct{}, d.opts.Workers)
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(delivery *models.KnowledgeWebhookDelivery) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			d.deliver(ctx, delivery)
+		}(&deliveries[i])
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+// deliver 投递一条记录并保存结果
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.KnowledgeWebhookDelivery) {
+	webhook, err := d.store.Webhook(ctx, delivery.WebhookID)
+	if err != nil {
+		logger.Warn("Failed to load webhook", zap.Uint("webhook_id", delivery.WebhookID), zap.Error(err))
+		return
+	}
+	if webhook == nil || !webhook.Enabled {
+		delivery.Status = models.WebhookDeliverySkipped
+		delivery.LastError = "webhook disabled or deleted"
+		d.save(ctx, delivery)
+		return
+	}
+
+	delivery.Attempts++
+	statusCode, sendErr := d.send(ctx, webhook, delivery)
+	delivery.LastStatusCode = statusCode
+	now := time.Now()
+
+	if sendErr == nil {
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		d.save(ctx, delivery)
+		if _, err := d.store.RecordResult(ctx, webhook.WebhookID, true, d.opts.DisableAfter); err != nil {
+			logger.Warn("Failed to record webhook result", zap.Uint("webhook_id", webhook.WebhookID), zap.Error(err))
+		}
+		return
+	}
+
+	delivery.LastError = truncate(sendErr.Error(), maxWebhookErrorLength)
+	if delivery.Attempts >= d.opts.MaxAttempts {
+		delivery.Status = models.WebhookDeliveryFailed
+	} else {
+		delivery.Status = models.WebhookDeliveryPending
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+	}
+	d.save(ctx, delivery)
+
+	disabled, err := d.store.RecordResult(ctx, webhook.WebhookID, false, d.opts.DisableAfter)
+	if err != nil {
+		logger.Warn("Failed to record webhook result", zap.Uint("webhook_id", webhook.WebhookID), zap.Error(err))
+	}
+	if disabled {
+		logger.Warn("Webhook disabled after consecutive failures",
+			zap.Uint("webhook_id", webhook.WebhookID),
+			zap.Uint("kb_id", webhook.KnowledgeBaseID),
+			zap.String("url", webhook.URL))
+	}
+}
+
+// send 发送签名请求，非2xx视为失败
+func (d *Dispatcher) send(ctx context.Context, webhook *models.KnowledgeWebhook, delivery *models.KnowledgeWebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.opts.Timeout)
+	defer cancel()
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", d.opts.UserAgent)
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderEventType, delivery.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, body))
+
+	// 投递记录对知识库用户可见，只保存状态码与概括的错误，不保存响应内容和底层网络错误
+	resp, err := d.client.Do(req)
+	if err != nil {
+		logger.Warn("Webhook request failed", zap.Uint("webhook_id", webhook.WebhookID), zap.Error(err))
+		switch {
+		case errors.Is(err, ErrBlockedDestination):
+			return 0, ErrBlockedDestination
+		case errors.Is(err, context.DeadlineExceeded):
+			return 0, errWebhookTimeout
+		default:
+			return 0, errWebhookRequestFailed
+		}
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) save(ctx context.Context, delivery *models.KnowledgeWebhookDelivery) {
+	// 投递循环退出时也要保存已完成的结果
+	if err := d.store.SaveDelivery(context.WithoutCancel(ctx), delivery); err != nil {
+		logger.Warn("Failed to save webhook delivery", zap.Uint("delivery_id", delivery.DeliveryID), zap.Error(err))
+	}
+}
+
+// backoff 第attempt次失败后的等待时间
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	wait := d.opts.BaseBackoff
+	for i := 1; i < attempt && wait < d.opts.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.opts.MaxBackoff {
+		wait = d.opts.MaxBackoff
+	}
+	return wait
+}
+
+func disabledReason(failures int) string {
+	return fmt.Sprintf("disabled after %d consecutive failed deliveries", failures)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
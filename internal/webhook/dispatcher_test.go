@@ -0,0 +1,300 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aihub/backend-go/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore 进程内存储，行为与GormStore一致
+type memoryStore struct {
+	mu         sync.Mutex
+	webhooks   map[uint]*models.KnowledgeWebhook
+	deliveries []*models.KnowledgeWebhookDelivery
+}
+
+func newMemoryStore(webhooks ...models.KnowledgeWebhook) *memoryStore {
+	s := &memoryStore{webhooks: make(map[uint]*models.KnowledgeWebhook)}
+	for i := range webhooks {
+		webhook := webhooks[i]
+		s.webhooks[webhook.WebhookID] = &webhook
+	}
+	return s
+}
+
+func (s *memoryStore) Subscriptions(ctx context.Context, knowledgeBaseID uint, eventType string) ([]models.KnowledgeWebhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var matched []models.KnowledgeWebhook
+	for _, webhook := range s.webhooks {
+		if webhook.KnowledgeBaseID == knowledgeBaseID && webhook.Enabled && Subscribed(*webhook, eventType) {
+			matched = append(matched, *webhook)
+		}
+	}
+	return matched, nil
+}
+
+func (s *memoryStore) Webhook(ctx context.Context, webhookID uint) (*models.KnowledgeWebhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if webhook, ok := s.webhooks[webhookID]; ok {
+		copied := *webhook
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) Enqueue(ctx context.Context, deliveries []*models.KnowledgeWebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, delivery := range deliveries {
+		delivery.DeliveryID = uint(len(s.deliveries) + 1)
+		copied := *delivery
+		s.deliveries = append(s.deliveries, &copied)
+	}
+	return nil
+}
+
+func (s *memoryStore) ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]models.KnowledgeWebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []models.KnowledgeWebhookDelivery
+	for _, delivery := range s.deliveries {
+		if len(claimed) == limit {
+			break
+		}
+		if delivery.Status == models.WebhookDeliveryPending && !delivery.NextAttemptAt.After(now) {
+			delivery.NextAttemptAt = now.Add(lease)
+			claimed = append(claimed, *delivery)
+		}
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) SaveDelivery(ctx context.Context, delivery *models.KnowledgeWebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *delivery
+	s.deliveries[delivery.DeliveryID-1] = &copied
+	return nil
+}
+
+func (s *memoryStore) RecordResult(ctx context.Context, webhookID uint, success bool, disableAfter int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	webhook := s.webhooks[webhookID]
+	if success {
+		webhook.ConsecutiveFailures = 0
+		return false, nil
+	}
+	webhook.ConsecutiveFailures++
+	if disableAfter > 0 && webhook.Enabled && webhook.ConsecutiveFailures >= disableAfter {
+		webhook.Enabled = false
+		webhook.DisabledReason = disabledReason(disableAfter)
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *memoryStore) delivery(id uint) models.KnowledgeWebhookDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.deliveries[id-1]
+}
+
+// dueNow 让所有待重试记录立即到期
+func (s *memoryStore) dueNow() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, delivery := range s.deliveries {
+		delivery.NextAttemptAt = time.Time{}
+	}
+}
+
+func TestDispatcher_DeliversSignedPayload(t *testing.T) {
+	const secret = "whsec_test"
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header, body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	store := newMemoryStore(
+		models.KnowledgeWebhook{WebhookID: 1, KnowledgeBaseID: 7, URL: server.URL, Secret: secret, Enabled: true,
+			Events: models.StringArray{EventDocumentCompleted}},
+		// 未订阅该事件
+		models.KnowledgeWebhook{WebhookID: 2, KnowledgeBaseID: 7, URL: server.URL, Secret: secret, Enabled: true,
+			Events: models.StringArray{EventDocumentDeleted}},
+	)
+	dispatcher := NewDispatcher(store, server.Client(), Options{})
+	ctx := context.Background()
+
+	event := NewEvent(EventDocumentCompleted, 7, 42, map[string]interface{}{"title": "员工手册"})
+	require.NoError(t, dispatcher.Publish(ctx, event))
+	n, err := dispatcher.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	req := <-requests
+	assert.Equal(t, event.ID, req.header.Get(HeaderEventID))
+	assert.Equal(t, EventDocumentCompleted, req.header.Get(HeaderEventType))
+	require.NoError(t, Verify(secret, req.header.Get(HeaderTimestamp), req.header.Get(HeaderSignature), req.body, time.Minute))
+	assert.ErrorIs(t, Verify("other", req.header.Get(HeaderTimestamp), req.header.Get(HeaderSignature), req.body, 0), ErrInvalidSignature)
+
+	var payload Event
+	require.NoError(t, json.Unmarshal(req.body, &payload))
+	assert.Equal(t, uint(42), payload.DocumentID)
+	assert.Equal(t, "员工手册", payload.Data["title"])
+
+	delivery := store.delivery(1)
+	assert.Equal(t, models.WebhookDeliverySucceeded, delivery.Status)
+	assert.Equal(t, http.StatusNoContent, delivery.LastStatusCode)
+	assert.NotNil(t, delivery.DeliveredAt)
+}
+
+func TestDispatcher_RetriesWithBackoffAndReplay(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			http.Error(w, "temporarily unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	store := newMemoryStore(models.KnowledgeWebhook{WebhookID: 1, KnowledgeBaseID: 7, URL: server.URL, Secret: "s", Enabled: true})
+	dispatcher := NewDispatcher(store, server.Client(), Options{BaseBackoff: time.Minute})
+	ctx := context.Background()
+
+	require.NoError(t, dispatcher.Publish(ctx, NewEvent(EventDocumentFailed, 7, 42, nil)))
+	_, err := dispatcher.RunOnce(ctx)
+	require.NoError(t, err)
+
+	delivery := store.delivery(1)
+	assert.Equal(t, models.WebhookDeliveryPending, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.LastStatusCode)
+	// 只记录状态码，不保存响应内容
+	assert.Equal(t, "unexpected status 503", delivery.LastError)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), delivery.NextAttemptAt, 5*time.Second)
+
+	// 未到期不投递
+	n, err := dispatcher.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	store.dueNow()
+	_, err = dispatcher.RunOnce(ctx)
+	require.NoError(t, err)
+	delivery = store.delivery(1)
+	assert.Equal(t, models.WebhookDeliverySucceeded, delivery.Status)
+	assert.Equal(t, 2, delivery.Attempts)
+
+	// 重放沿用事件ID
+	replay, err := dispatcher.Replay(ctx, delivery)
+	require.NoError(t, err)
+	_, err = dispatcher.RunOnce(ctx)
+	require.NoError(t, err)
+	replayed := store.delivery(replay.DeliveryID)
+	assert.Equal(t, models.WebhookDeliverySucceeded, replayed.Status)
+	assert.Equal(t, delivery.EventID, replayed.EventID)
+	assert.Equal(t, delivery.DeliveryID, *replayed.ReplayOf)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestDispatcher_DisablesFailingEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	store := newMemoryStore(models.KnowledgeWebhook{WebhookID: 1, KnowledgeBaseID: 7, URL: server.URL, Secret: "s", Enabled: true})
+	dispatcher := NewDispatcher(store, server.Client(), Options{MaxAttempts: 2, DisableAfter: 3})
+	ctx := context.Background()
+
+	require.NoError(t, dispatcher.Publish(ctx, NewEvent(EventDocumentDeleted, 7, 1, nil)))
+	require.NoError(t, dispatcher.Publish(ctx, NewEvent(EventDocumentDeleted, 7, 2, nil)))
+	_, err := dispatcher.RunOnce(ctx)
+	require.NoError(t, err)
+	store.dueNow()
+	_, err = dispatcher.RunOnce(ctx)
+	require.NoError(t, err)
+
+	// 重试次数用尽
+	assert.Equal(t, models.WebhookDeliveryFailed, store.delivery(1).Status)
+	webhook, _ := store.Webhook(ctx, 1)
+	assert.False(t, webhook.Enabled)
+	assert.NotEmpty(t, webhook.DisabledReason)
+
+	// 停用后不再订阅新事件，已排队的记录跳过
+	require.NoError(t, dispatcher.Publish(ctx, NewEvent(EventDocumentDeleted, 7, 3, nil)))
+	assert.Len(t, store.deliveries, 2)
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	dispatcher := NewDispatcher(newMemoryStore(), nil, Options{BaseBackoff: time.Second, MaxBackoff: 5 * time.Second})
+	assert.Equal(t, time.Second, dispatcher.backoff(1))
+	assert.Equal(t, 4*time.Second, dispatcher.backoff(3))
+	assert.Equal(t, 5*time.Second, dispatcher.backoff(10))
+}
+
+func TestDispatcher_DefaultClientRejectsPrivateAddresses(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// httptest监听回环地址，默认客户端拨号时拒绝
+	store := newMemoryStore(models.KnowledgeWebhook{WebhookID: 1, KnowledgeBaseID: 7, URL: server.URL, Secret: "s", Enabled: true})
+	dispatcher := NewDispatcher(store, nil, Options{})
+	ctx := context.Background()
+
+	require.NoError(t, dispatcher.Publish(ctx, NewEvent(EventDocumentCompleted, 7, 42, nil)))
+	_, err := dispatcher.RunOnce(ctx)
+	require.NoError(t, err)
+
+	delivery := store.delivery(1)
+	assert.Equal(t, models.WebhookDeliveryPending, delivery.Status)
+	assert.Equal(t, 0, delivery.LastStatusCode)
+	assert.Equal(t, ErrBlockedDestination.Error(), delivery.LastError)
+	assert.Zero(t, atomic.LoadInt32(&calls))
+}
+
+func TestNewHTTPClient_RefusesRedirects(t *testing.T) {
+	client := NewHTTPClient(time.Second)
+	require.NotNil(t, client.CheckRedirect)
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/hook", nil)
+	assert.ErrorIs(t, client.CheckRedirect(req, []*http.Request{req}), http.ErrUseLastResponse)
+}
+
+func TestIsBlockedIP(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "169.254.1.1", "0.0.0.0", "::1", "fd00::1", "fe80::1", "::ffff:127.0.0.1"} {
+		assert.True(t, IsBlockedIP(net.ParseIP(addr)), addr)
+	}
+	for _, addr := range []string{"8.8.8.8", "172.32.0.1", "2606:4700::1111"} {
+		assert.False(t, IsBlockedIP(net.ParseIP(addr)), addr)
+	}
+
+	assert.ErrorIs(t, CheckHost(context.Background(), "169.254.169.254"), ErrBlockedDestination)
+	assert.NoError(t, CheckHost(context.Background(), "8.8.8.8"))
+}
@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// 事件类型
+const (
+	EventDocumentCompleted    = "document.completed"     // 文档处理完成
+	EventDocumentFailed       = "document.failed"        // 文档处理失败
+	EventDocumentDeleted      = "document.deleted"       // 文档删除
+	EventKnowledgeBaseUpdated = "knowledge_base.updated" // 知识库信息或配置修改
+	EventKnowledgeBaseDeleted = "knowledge_base.deleted" // 知识库删除
+)
+
+// EventTypes 可订阅的全部事件类型
+var EventTypes = []string{
+	EventDocumentCompleted,
+	EventDocumentFailed,
+	EventDocumentDeleted,
+	EventKnowledgeBaseUpdated,
+	EventKnowledgeBaseDeleted,
+}
+
+// ValidEventType 是否为可订阅的事件类型
+func ValidEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event 知识库事件，序列化后作为Webhook请求体
+type Event struct {
+	ID              string                 `json:"id"` // 同一事件的重试与重放保持不变，接收方据此去重
+	Type            string                 `json:"type"`
+	KnowledgeBaseID uint                   `json:"knowledge_base_id"`
+	DocumentID      uint                   `json:"document_id,omitempty"`
+	CreatedAt       time.Time              `json:"created_at"`
+	Data            map[string]interface{} `json:"data,omitempty"`
+}
+
+// NewEvent 创建事件，生成随机ID
+func NewEvent(eventType string, knowledgeBaseID, documentID uint, data map[string]interface{}) Event {
+	return Event{
+		ID:              newEventID(),
+		Type:            eventType,
+		KnowledgeBaseID: knowledgeBaseID,
+		DocumentID:      documentID,
+		CreatedAt:       time.Now().UTC(),
+		Data:            data,
+	}
+}
+
+func newEventID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return "evt_" + hex.EncodeToString(buf)
+}
+
+// Publisher 事件发布，服务层只依赖该接口
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
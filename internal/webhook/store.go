@@ -0,0 +1,140 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aihub/backend-go/internal/database"
+	"github.com/aihub/backend-go/internal/models"
+	"gorm.io/gorm"
+)
+
+// Store Webhook订阅与投递队列的存储
+type Store interface {
+	// Subscriptions 知识库下启用且订阅了eventType的Webhook
+	Subscriptions(ctx context.Context, knowledgeBaseID uint, eventType string) ([]models.KnowledgeWebhook, error)
+	// Webhook 按ID获取订阅，不存在时返回nil
+	Webhook(ctx context.Context, webhookID uint) (*models.KnowledgeWebhook, error)
+	// Enqueue 写入待投递记录，写入后回填DeliveryID
+	Enqueue(ctx context.Context, deliveries []*models.KnowledgeWebhookDelivery) error
+	// ClaimDue 领取到期的待投递记录，并把next_attempt_at推迟lease；投递进程中途退出时租约到期后重新领取
+	ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]models.KnowledgeWebhookDelivery, error)
+	// SaveDelivery 保存一次投递后的状态
+	SaveDelivery(ctx context.Context, delivery *models.KnowledgeWebhookDelivery) error
+	// RecordResult 更新Webhook的连续失败次数，失败次数达到disableAfter时停用，返回本次是否被停用
+	RecordResult(ctx context.Context, webhookID uint, success bool, disableAfter int) (bool, error)
+}
+
+// GormStore 基于数据库的存储，投递表即持久化队列
+type GormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore 创建数据库存储
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+func (s *GormStore) Subscriptions(ctx context.Context, knowledgeBaseID uint, eventType string) ([]models.KnowledgeWebhook, error) {
+	var webhooks []models.KnowledgeWebhook
+	err := s.db.WithContext(ctx).
+		Where("knowledge_base_id = ? AND enabled = ?", knowledgeBaseID, true).
+		Find(&webhooks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	matched := webhooks[:0]
+	for _, webhook := range webhooks {
+		if Subscribed(webhook, eventType) {
+			matched = append(matched, webhook)
+		}
+	}
+	return matched, nil
+}
+
+func (s *GormStore) Webhook(ctx context.Context, webhookID uint) (*models.KnowledgeWebhook, error) {
+	var webhook models.KnowledgeWebhook
+	err := s.db.WithContext(ctx).First(&webhook, webhookID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (s *GormStore) Enqueue(ctx context.Context, deliveries []*models.KnowledgeWebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Create(deliveries).Error
+}
+
+func (s *GormStore) ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]models.KnowledgeWebhookDelivery, error) {
+	// SKIP LOCKED让多个副本并行领取互不重复
+	var deliveries []models.KnowledgeWebhookDelivery
+	err := s.db.WithContext(ctx).Raw(`
+		UPDATE knowledge_webhook_deliveries SET next_attempt_at = ?, update_time = ?
+		WHERE delivery_id IN (
+			SELECT delivery_id FROM knowledge_webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT ?
+			`+database.SkipLocked(s.db)+`
+		)
+		RETURNING *`,
+		now.Add(lease), now, models.WebhookDeliveryPending, now, limit).
+		Scan(&deliveries).Error
+	return deliveries, err
+}
+
+func (s *GormStore) SaveDelivery(ctx context.Context, delivery *models.KnowledgeWebhookDelivery) error {
+	return s.db.WithContext(ctx).Model(delivery).Updates(map[string]interface{}{
+		"status":           delivery.Status,
+		"attempts":         delivery.Attempts,
+		"next_attempt_at":  delivery.NextAttemptAt,
+		"last_status_code": delivery.LastStatusCode,
+		"last_error":       delivery.LastError,
+		"delivered_at":     delivery.DeliveredAt,
+		"update_time":      time.Now(),
+	}).Error
+}
+
+func (s *GormStore) RecordResult(ctx context.Context, webhookID uint, success bool, disableAfter int) (bool, error) {
+	db := s.db.WithContext(ctx).Model(&models.KnowledgeWebhook{}).Where("webhook_id = ?", webhookID)
+	if success {
+		return false, db.Where("consecutive_failures > 0").Update("consecutive_failures", 0).Error
+	}
+	if err := db.Update("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error; err != nil {
+		return false, err
+	}
+	if disableAfter <= 0 {
+		return false, nil
+	}
+	now := time.Now()
+	result := s.db.WithContext(ctx).Model(&models.KnowledgeWebhook{}).
+		Where("webhook_id = ? AND enabled = ? AND consecutive_failures >= ?", webhookID, true, disableAfter).
+		Updates(map[string]interface{}{
+			"enabled":         false,
+			"disabled_at":     now,
+			"disabled_reason": disabledReason(disableAfter),
+			"update_time":     now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Subscribed Webhook是否订阅了该事件，未指定事件类型时订阅全部
+func Subscribed(webhook models.KnowledgeWebhook, eventType string) bool {
+	if len(webhook.Events) == 0 {
+		return true
+	}
+	for _, t := range webhook.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
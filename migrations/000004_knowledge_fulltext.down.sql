@@ -0,0 +1,9 @@
+-- +migrate Down
+DROP INDEX IF EXISTS idx_knowledge_chunks_content_trgm;
+DROP INDEX IF EXISTS idx_knowledge_chunks_search_vector;
+
+DROP TRIGGER IF EXISTS trg_knowledge_chunks_search_vector ON knowledge_chunks;
+DROP FUNCTION IF EXISTS knowledge_chunks_search_vector_update();
+
+ALTER TABLE knowledge_chunks
+DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,30 @@
+-- +migrate Up
+-- PostgreSQL full-text search for knowledge chunks
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Backfill only when the column is created; a NULL search_vector means
+-- the chunk was removed from the index.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name='knowledge_chunks' AND column_name='search_vector') THEN
+        ALTER TABLE knowledge_chunks ADD COLUMN search_vector tsvector;
+        UPDATE knowledge_chunks SET search_vector = to_tsvector('simple', coalesce(content, ''));
+    END IF;
+END $$;
+
+-- Keep search_vector in sync with content for every writer.
+-- DatabaseIndexer.IndexChunk later rewrites it with CJK bigrams added.
+CREATE OR REPLACE FUNCTION knowledge_chunks_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector := to_tsvector('simple', coalesce(NEW.content, ''));
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_knowledge_chunks_search_vector ON knowledge_chunks;
+CREATE TRIGGER trg_knowledge_chunks_search_vector
+BEFORE INSERT OR UPDATE OF content ON knowledge_chunks
+FOR EACH ROW EXECUTE FUNCTION knowledge_chunks_search_vector_update();
+
+CREATE INDEX IF NOT EXISTS idx_knowledge_chunks_search_vector ON knowledge_chunks USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_knowledge_chunks_content_trgm ON knowledge_chunks USING GIN (content gin_trgm_ops);
@@ -0,0 +1,5 @@
+-- +migrate Down
+ALTER TABLE knowledge_bases
+DROP COLUMN IF EXISTS embedding_dimensions,
+DROP COLUMN IF EXISTS embedding_model,
+DROP COLUMN IF EXISTS embedding_provider;
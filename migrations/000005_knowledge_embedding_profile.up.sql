@@ -0,0 +1,5 @@
+-- +migrate Up
+-- Record the embedding model each knowledge base's vectors were produced with
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS embedding_provider varchar(50);
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS embedding_model varchar(100);
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS embedding_dimensions integer DEFAULT 0;
@@ -0,0 +1,3 @@
+-- +migrate Down
+DROP TABLE IF EXISTS knowledge_lexicon_words;
+DROP TABLE IF EXISTS knowledge_synonyms;
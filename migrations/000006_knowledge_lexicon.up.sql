@@ -0,0 +1,21 @@
+-- +migrate Up
+-- Per knowledge base synonym sets, stop words and user dictionaries
+CREATE TABLE IF NOT EXISTS knowledge_synonyms (
+    synonym_id bigserial PRIMARY KEY,
+    knowledge_base_id bigint NOT NULL,
+    terms jsonb NOT NULL,
+    create_time timestamptz DEFAULT NOW(),
+    update_time timestamptz,
+    CONSTRAINT fk_knowledge_synonyms_kb FOREIGN KEY (knowledge_base_id) REFERENCES knowledge_bases(knowledge_base_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_knowledge_synonyms_knowledge_base_id ON knowledge_synonyms(knowledge_base_id);
+
+CREATE TABLE IF NOT EXISTS knowledge_lexicon_words (
+    word_id bigserial PRIMARY KEY,
+    knowledge_base_id bigint NOT NULL,
+    kind varchar(20) NOT NULL,
+    word varchar(100) NOT NULL,
+    create_time timestamptz DEFAULT NOW(),
+    CONSTRAINT fk_knowledge_lexicon_words_kb FOREIGN KEY (knowledge_base_id) REFERENCES knowledge_bases(knowledge_base_id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_knowledge_lexicon_word ON knowledge_lexicon_words(knowledge_base_id, kind, word);
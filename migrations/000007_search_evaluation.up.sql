@@ -0,0 +1,16 @@
+-- +migrate Up
+-- Search relevance evaluation runs (recall@k, MRR, nDCG, latency per configuration)
+CREATE TABLE IF NOT EXISTS search_evaluation_runs (
+    run_id bigserial PRIMARY KEY,
+    knowledge_base_id bigint NOT NULL,
+    name varchar(100),
+    query_set_hash varchar(64),
+    query_count integer,
+    embedder varchar(100),
+    results json NOT NULL,
+    created_by bigint,
+    create_time timestamptz DEFAULT NOW(),
+    CONSTRAINT fk_search_evaluation_runs_kb FOREIGN KEY (knowledge_base_id) REFERENCES knowledge_bases(knowledge_base_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_search_evaluation_runs_knowledge_base_id ON search_evaluation_runs(knowledge_base_id);
+CREATE INDEX IF NOT EXISTS idx_search_evaluation_runs_query_set_hash ON search_evaluation_runs(query_set_hash);
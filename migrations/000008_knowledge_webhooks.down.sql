@@ -0,0 +1,3 @@
+-- +migrate Down
+DROP TABLE IF EXISTS knowledge_webhook_deliveries;
+DROP TABLE IF EXISTS knowledge_webhooks;
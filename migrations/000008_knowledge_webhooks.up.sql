@@ -0,0 +1,41 @@
+-- +migrate Up
+-- Outbound webhooks for knowledge base and document events
+CREATE TABLE IF NOT EXISTS knowledge_webhooks (
+    webhook_id bigserial PRIMARY KEY,
+    knowledge_base_id bigint NOT NULL,
+    url varchar(500) NOT NULL,
+    events jsonb NOT NULL DEFAULT '[]',
+    secret varchar(100) NOT NULL,
+    description varchar(200),
+    enabled boolean DEFAULT true,
+    consecutive_failures integer DEFAULT 0,
+    disabled_reason varchar(200),
+    disabled_at timestamptz,
+    created_by bigint,
+    create_time timestamptz DEFAULT NOW(),
+    update_time timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_knowledge_webhooks_knowledge_base_id ON knowledge_webhooks(knowledge_base_id);
+
+-- Deliveries double as the persistent retry queue and the delivery log
+CREATE TABLE IF NOT EXISTS knowledge_webhook_deliveries (
+    delivery_id bigserial PRIMARY KEY,
+    webhook_id bigint NOT NULL,
+    knowledge_base_id bigint NOT NULL,
+    event_id varchar(64) NOT NULL,
+    event_type varchar(50) NOT NULL,
+    payload text NOT NULL,
+    status varchar(20) NOT NULL,
+    attempts integer DEFAULT 0,
+    next_attempt_at timestamptz,
+    last_status_code integer,
+    last_error varchar(500),
+    delivered_at timestamptz,
+    replay_of bigint,
+    create_time timestamptz DEFAULT NOW(),
+    update_time timestamptz,
+    CONSTRAINT fk_knowledge_webhook_deliveries_webhook FOREIGN KEY (webhook_id) REFERENCES knowledge_webhooks(webhook_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_knowledge_webhook_deliveries_webhook_id ON knowledge_webhook_deliveries(webhook_id);
+CREATE INDEX IF NOT EXISTS idx_knowledge_webhook_deliveries_event_id ON knowledge_webhook_deliveries(event_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON knowledge_webhook_deliveries(status, next_attempt_at);
@@ -0,0 +1,20 @@
+-- +migrate Up
+-- Transactional outbox: rows are written in the same transaction as the state
+-- change and relayed to Kafka in order per aggregate key
+CREATE TABLE IF NOT EXISTS outbox_events (
+    outbox_id bigserial PRIMARY KEY,
+    event_id varchar(64) NOT NULL,
+    topic varchar(200) NOT NULL,
+    aggregate_key varchar(200) NOT NULL,
+    payload text NOT NULL,
+    headers jsonb,
+    status varchar(20) NOT NULL,
+    attempts integer DEFAULT 0,
+    next_attempt_at timestamptz,
+    last_error varchar(500),
+    published_at timestamptz,
+    create_time timestamptz DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_events_event_id ON outbox_events(event_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_aggregate_key ON outbox_events(aggregate_key);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events(status);
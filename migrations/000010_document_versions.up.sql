@@ -0,0 +1,20 @@
+-- +migrate Up
+-- Every document version keeps its extracted text and source object; the text
+-- lives in MinIO when available and inline in content otherwise
+CREATE TABLE IF NOT EXISTS knowledge_document_versions (
+    version_id bigserial PRIMARY KEY,
+    document_id bigint NOT NULL,
+    knowledge_base_id bigint NOT NULL,
+    version integer NOT NULL,
+    title varchar(200) NOT NULL,
+    content_hash varchar(64),
+    change_type varchar(20),
+    content_length integer,
+    text_object_key varchar(500),
+    source_object_key varchar(500),
+    content text,
+    restored_from integer,
+    create_time timestamptz DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_document_versions_doc_version ON knowledge_document_versions(document_id, version);
+CREATE INDEX IF NOT EXISTS idx_knowledge_document_versions_knowledge_base_id ON knowledge_document_versions(knowledge_base_id);
@@ -0,0 +1,6 @@
+-- +migrate Down
+DROP TABLE IF EXISTS knowledge_purge_tasks;
+DROP INDEX IF EXISTS idx_knowledge_documents_deleted_at;
+DROP INDEX IF EXISTS idx_knowledge_bases_deleted_at;
+ALTER TABLE knowledge_documents DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS deleted_at;
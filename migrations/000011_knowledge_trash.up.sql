@@ -0,0 +1,27 @@
+-- +migrate Up
+-- Deleted knowledge bases and documents stay in a trash until the retention
+-- window ends; purge tasks then remove their data from every store
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+ALTER TABLE knowledge_documents ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_knowledge_bases_deleted_at ON knowledge_bases(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_knowledge_documents_deleted_at ON knowledge_documents(deleted_at);
+
+CREATE TABLE IF NOT EXISTS knowledge_purge_tasks (
+    task_id bigserial PRIMARY KEY,
+    item_type varchar(20) NOT NULL,
+    knowledge_base_id bigint NOT NULL,
+    document_id bigint DEFAULT 0,
+    title varchar(200),
+    status varchar(20) NOT NULL,
+    purge_after timestamptz,
+    purged_stores jsonb,
+    attempts integer DEFAULT 0,
+    next_attempt_at timestamptz,
+    last_error varchar(500),
+    deleted_by bigint,
+    completed_at timestamptz,
+    create_time timestamptz DEFAULT NOW(),
+    update_time timestamptz DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_knowledge_purge_tasks_knowledge_base_id ON knowledge_purge_tasks(knowledge_base_id);
+CREATE INDEX IF NOT EXISTS idx_purge_tasks_due ON knowledge_purge_tasks(status, purge_after);
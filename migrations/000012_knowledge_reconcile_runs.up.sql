@@ -0,0 +1,19 @@
+-- +migrate Up
+-- Each consistency check compares a knowledge base's chunks with the fulltext
+-- index, vector store, chunk cache and object storage; at most one run per
+-- knowledge base may be running at a time
+CREATE TABLE IF NOT EXISTS knowledge_reconcile_runs (
+    run_id bigserial PRIMARY KEY,
+    knowledge_base_id bigint NOT NULL,
+    repair boolean DEFAULT false,
+    status varchar(20) NOT NULL,
+    discrepancies integer DEFAULT 0,
+    repaired integer DEFAULT 0,
+    report text,
+    error varchar(500),
+    started_at timestamptz,
+    finished_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_knowledge_reconcile_runs_knowledge_base_id ON knowledge_reconcile_runs(knowledge_base_id);
+CREATE INDEX IF NOT EXISTS idx_knowledge_reconcile_runs_started_at ON knowledge_reconcile_runs(started_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reconcile_runs_running ON knowledge_reconcile_runs(knowledge_base_id) WHERE status = 'running';
@@ -0,0 +1,28 @@
+-- +migrate Up
+-- Knowledge base archives are imported by a background job that records its
+-- stage and the number of imported documents so it can resume after a restart
+CREATE TABLE IF NOT EXISTS knowledge_import_jobs (
+    job_id bigserial PRIMARY KEY,
+    owner_id bigint NOT NULL,
+    archive_key varchar(500) NOT NULL,
+    archive_name varchar(255),
+    name varchar(200),
+    source_knowledge_base_id bigint,
+    knowledge_base_id bigint DEFAULT 0,
+    status varchar(20) NOT NULL,
+    stage varchar(20) NOT NULL,
+    reuse_embeddings boolean DEFAULT false,
+    total_documents integer DEFAULT 0,
+    imported_documents integer DEFAULT 0,
+    total_chunks integer DEFAULT 0,
+    imported_chunks integer DEFAULT 0,
+    indexed_chunks integer DEFAULT 0,
+    attempts integer DEFAULT 0,
+    lease_until timestamptz,
+    error varchar(500),
+    create_time timestamptz DEFAULT NOW(),
+    update_time timestamptz DEFAULT NOW(),
+    finished_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_knowledge_import_jobs_owner_id ON knowledge_import_jobs(owner_id);
+CREATE INDEX IF NOT EXISTS idx_import_jobs_claim ON knowledge_import_jobs(status, lease_until);
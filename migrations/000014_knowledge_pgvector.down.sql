@@ -0,0 +1,13 @@
+-- +migrate Down
+-- The vector extension is left installed, other schemas may use it.
+DO $$
+DECLARE
+    tbl text;
+BEGIN
+    FOR tbl IN
+        SELECT table_name FROM information_schema.tables
+        WHERE table_schema = current_schema() AND table_name ~ '^knowledge_vectors_[0-9]+$'
+    LOOP
+        EXECUTE format('DROP TABLE IF EXISTS %I', tbl);
+    END LOOP;
+END $$;
@@ -0,0 +1,45 @@
+-- +migrate Up
+-- Native pgvector storage for chunk embeddings, one table per dimension
+-- (knowledge_vectors_<n>, the default table prefix of the pgvector vector store),
+-- backfilled from the JSON embeddings in knowledge_chunks. Skipped when the
+-- vector extension is not installed on the server.
+DO $$
+DECLARE
+    dim integer;
+    tbl text;
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'vector') THEN
+        RAISE NOTICE 'pgvector extension is not available, skipping vector backfill';
+        RETURN;
+    END IF;
+    CREATE EXTENSION IF NOT EXISTS vector;
+
+    FOR dim IN
+        SELECT DISTINCT CASE WHEN json_typeof(embedding) = 'array' THEN json_array_length(embedding) END AS dim
+        FROM knowledge_chunks
+        WHERE embedding IS NOT NULL
+    LOOP
+        CONTINUE WHEN dim IS NULL OR dim = 0;
+        tbl := 'knowledge_vectors_' || dim;
+
+        EXECUTE format('CREATE TABLE IF NOT EXISTS %I (
+            chunk_id bigint PRIMARY KEY,
+            knowledge_base_id bigint NOT NULL,
+            document_id bigint NOT NULL,
+            embedding vector(%s) NOT NULL,
+            update_time timestamptz NOT NULL DEFAULT NOW()
+        )', tbl, dim);
+        EXECUTE format('CREATE INDEX IF NOT EXISTS %I ON %I (knowledge_base_id, document_id)', 'idx_' || tbl || '_kb_doc', tbl);
+
+        EXECUTE format('INSERT INTO %I (chunk_id, knowledge_base_id, document_id, embedding)
+            SELECT c.chunk_id, d.knowledge_base_id, c.document_id, c.embedding::text::vector
+            FROM knowledge_chunks c
+            JOIN knowledge_documents d ON c.document_id = d.document_id
+            WHERE c.embedding IS NOT NULL
+                AND CASE WHEN json_typeof(c.embedding) = ''array'' THEN json_array_length(c.embedding) END = %s
+            ON CONFLICT (chunk_id) DO NOTHING', tbl, dim);
+
+        -- The ANN index is not built here: the pgvector store creates it on first
+        -- load with the configured index type (hnsw/ivfflat) and distance
+    END LOOP;
+END $$;
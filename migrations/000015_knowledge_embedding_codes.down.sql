@@ -0,0 +1,2 @@
+-- +migrate Down
+ALTER TABLE knowledge_chunks DROP COLUMN IF EXISTS embedding_code;
@@ -0,0 +1,4 @@
+-- +migrate Up
+-- Quantized (int8 / binary) embedding codes scanned by the database vector store
+-- before exact rescoring. Existing rows are encoded on read until re-embedded.
+ALTER TABLE knowledge_chunks ADD COLUMN IF NOT EXISTS embedding_code bytea;
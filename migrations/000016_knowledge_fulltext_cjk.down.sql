@@ -0,0 +1,9 @@
+-- +migrate Down
+CREATE OR REPLACE FUNCTION knowledge_chunks_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector := to_tsvector('simple', coalesce(NEW.content, ''));
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP FUNCTION IF EXISTS knowledge_cjk_bigrams(text);
@@ -0,0 +1,37 @@
+-- +migrate Up
+-- CJK bigrams for knowledge chunk full-text search.
+-- The character ranges must match cjkRanges in internal/knowledge/indexer_db.go,
+-- otherwise query bigrams built in Go will not match the indexed ones.
+CREATE OR REPLACE FUNCTION knowledge_cjk_bigrams(input text) RETURNS text AS $$
+DECLARE
+    run text;
+    tokens text[] := '{}';
+BEGIN
+    FOR run IN
+        SELECT m[1] FROM regexp_matches(coalesce(input, ''),
+            '([\u3040-\u30ff\u3400-\u4dbf\u4e00-\u9fff\uac00-\ud7af\uf900-\ufaff\U00020000-\U0002ffff]+)', 'g') AS m
+    LOOP
+        IF char_length(run) = 1 THEN
+            tokens := tokens || run;
+        END IF;
+        FOR i IN 1 .. char_length(run) - 1 LOOP
+            tokens := tokens || substr(run, i, 2);
+        END LOOP;
+    END LOOP;
+    RETURN array_to_string(tokens, ' ');
+END
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- Every writer gets the same tokens as DatabaseIndexer.IndexChunk,
+-- which additionally weights the file name higher.
+CREATE OR REPLACE FUNCTION knowledge_chunks_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector := to_tsvector('simple', coalesce(NEW.content, '') || ' ' || knowledge_cjk_bigrams(NEW.content));
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+-- Rebuild indexed chunks; NULL search_vector stays removed from the index.
+UPDATE knowledge_chunks
+SET search_vector = to_tsvector('simple', coalesce(content, '') || ' ' || knowledge_cjk_bigrams(content))
+WHERE search_vector IS NOT NULL;
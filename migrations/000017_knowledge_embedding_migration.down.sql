@@ -0,0 +1,6 @@
+-- +migrate Down
+ALTER TABLE knowledge_bases
+DROP COLUMN IF EXISTS embedding_migration_version,
+DROP COLUMN IF EXISTS embedding_migration_dimensions,
+DROP COLUMN IF EXISTS embedding_migration_model,
+DROP COLUMN IF EXISTS embedding_migration_provider;
@@ -0,0 +1,7 @@
+-- +migrate Up
+-- Persist in-progress embedding model migrations so every instance dual-reads
+-- the old and new vector index versions, and the state survives restarts
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS embedding_migration_provider varchar(50);
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS embedding_migration_model varchar(100);
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS embedding_migration_dimensions integer DEFAULT 0;
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS embedding_migration_version integer DEFAULT 0;
@@ -27,6 +27,7 @@ type UploadPluginRequest struct {
 	FileContent   []byte                 `protobuf:"bytes,1,opt,name=file_content,json=fileContent,proto3" json:"file_content,omitempty"` // 插件文件内容
 	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`                          // 文件名
 	UserId        uint32                 `protobuf:"varint,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`               // 用户ID
+	Visibility    string                 `protobuf:"bytes,4,opt,name=visibility,proto3" json:"visibility,omitempty"`                      // private, org, global（为空时管理员默认global，其他用户默认private）
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -82,15 +83,24 @@ func (x *UploadPluginRequest) GetUserId() uint32 {
 	return 0
 }
 
+func (x *UploadPluginRequest) GetVisibility() string {
+	if x != nil {
+		return x.Visibility
+	}
+	return ""
+}
+
 // 上传插件响应
 type UploadPluginResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	PluginId      string                 `protobuf:"bytes,3,opt,name=plugin_id,json=pluginId,proto3" json:"plugin_id,omitempty"`
-	Filename      string                 `protobuf:"bytes,4,opt,name=filename,proto3" json:"filename,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Success         bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message         string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	PluginId        string                 `protobuf:"bytes,3,opt,name=plugin_id,json=pluginId,proto3" json:"plugin_id,omitempty"`
+	Filename        string                 `protobuf:"bytes,4,opt,name=filename,proto3" json:"filename,omitempty"`
+	Version         string                 `protobuf:"bytes,5,opt,name=version,proto3" json:"version,omitempty"`                                        // 安装的版本
+	PreviousVersion string                 `protobuf:"bytes,6,opt,name=previous_version,json=previousVersion,proto3" json:"previous_version,omitempty"` // 被替换的版本（首次安装时为空）
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *UploadPluginResponse) Reset() {
@@ -151,6 +161,20 @@ func (x *UploadPluginResponse) GetFilename() string {
 	return ""
 }
 
+func (x *UploadPluginResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *UploadPluginResponse) GetPreviousVersion() string {
+	if x != nil {
+		return x.PreviousVersion
+	}
+	return ""
+}
+
 // 列出插件请求
 type ListPluginsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -208,6 +232,11 @@ type PluginInfo struct {
 	Provider      string                 `protobuf:"bytes,7,opt,name=provider,proto3" json:"provider,omitempty"`
 	State         string                 `protobuf:"bytes,8,opt,name=state,proto3" json:"state,omitempty"`
 	Capabilities  []*CapabilityInfo      `protobuf:"bytes,9,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	Stats         *PluginStats           `protobuf:"bytes,10,opt,name=stats,proto3" json:"stats,omitempty"`                     // 调用统计
+	OwnerId       uint32                 `protobuf:"varint,11,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"` // 安装者（系统安装的插件为0）
+	OrgId         uint32                 `protobuf:"varint,12,opt,name=org_id,json=orgId,proto3" json:"org_id,omitempty"`       // visibility为org时的组织ID
+	Visibility    string                 `protobuf:"bytes,13,opt,name=visibility,proto3" json:"visibility,omitempty"`
+	Enabled       bool                   `protobuf:"varint,14,opt,name=enabled,proto3" json:"enabled,omitempty"` // 对当前用户是否启用（叠加用户/组织覆盖设置）
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -305,6 +334,174 @@ func (x *PluginInfo) GetCapabilities() []*CapabilityInfo {
 	return nil
 }
 
+func (x *PluginInfo) GetStats() *PluginStats {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+func (x *PluginInfo) GetOwnerId() uint32 {
+	if x != nil {
+		return x.OwnerId
+	}
+	return 0
+}
+
+func (x *PluginInfo) GetOrgId() uint32 {
+	if x != nil {
+		return x.OrgId
+	}
+	return 0
+}
+
+func (x *PluginInfo) GetVisibility() string {
+	if x != nil {
+		return x.Visibility
+	}
+	return ""
+}
+
+func (x *PluginInfo) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+// 插件调用统计
+type PluginStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TotalCalls    int64                  `protobuf:"varint,1,opt,name=total_calls,json=totalCalls,proto3" json:"total_calls,omitempty"`
+	ErrorCalls    int64                  `protobuf:"varint,2,opt,name=error_calls,json=errorCalls,proto3" json:"error_calls,omitempty"`
+	RejectedCalls int64                  `protobuf:"varint,3,opt,name=rejected_calls,json=rejectedCalls,proto3" json:"rejected_calls,omitempty"` // 因并发限制被拒绝的调用
+	InFlight      int64                  `protobuf:"varint,4,opt,name=in_flight,json=inFlight,proto3" json:"in_flight,omitempty"`
+	AvgLatencyMs  float64                `protobuf:"fixed64,5,opt,name=avg_latency_ms,json=avgLatencyMs,proto3" json:"avg_latency_ms,omitempty"`
+	MaxLatencyMs  float64                `protobuf:"fixed64,6,opt,name=max_latency_ms,json=maxLatencyMs,proto3" json:"max_latency_ms,omitempty"`
+	RequestBytes  int64                  `protobuf:"varint,7,opt,name=request_bytes,json=requestBytes,proto3" json:"request_bytes,omitempty"`
+	ResponseBytes int64                  `protobuf:"varint,8,opt,name=response_bytes,json=responseBytes,proto3" json:"response_bytes,omitempty"`
+	LastError     string                 `protobuf:"bytes,9,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	LastCalledAt  int64                  `protobuf:"varint,10,opt,name=last_called_at,json=lastCalledAt,proto3" json:"last_called_at,omitempty"`
+	TimeoutMs     int64                  `protobuf:"varint,11,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`             // 当前生效的调用超时
+	MaxConcurrent int32                  `protobuf:"varint,12,opt,name=max_concurrent,json=maxConcurrent,proto3" json:"max_concurrent,omitempty"` // 当前生效的并发上限
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PluginStats) Reset() {
+	*x = PluginStats{}
+	mi := &file_proto_plugin_service_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PluginStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PluginStats) ProtoMessage() {}
+
+func (x *PluginStats) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_service_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PluginStats.ProtoReflect.Descriptor instead.
+func (*PluginStats) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *PluginStats) GetTotalCalls() int64 {
+	if x != nil {
+		return x.TotalCalls
+	}
+	return 0
+}
+
+func (x *PluginStats) GetErrorCalls() int64 {
+	if x != nil {
+		return x.ErrorCalls
+	}
+	return 0
+}
+
+func (x *PluginStats) GetRejectedCalls() int64 {
+	if x != nil {
+		return x.RejectedCalls
+	}
+	return 0
+}
+
+func (x *PluginStats) GetInFlight() int64 {
+	if x != nil {
+		return x.InFlight
+	}
+	return 0
+}
+
+func (x *PluginStats) GetAvgLatencyMs() float64 {
+	if x != nil {
+		return x.AvgLatencyMs
+	}
+	return 0
+}
+
+func (x *PluginStats) GetMaxLatencyMs() float64 {
+	if x != nil {
+		return x.MaxLatencyMs
+	}
+	return 0
+}
+
+func (x *PluginStats) GetRequestBytes() int64 {
+	if x != nil {
+		return x.RequestBytes
+	}
+	return 0
+}
+
+func (x *PluginStats) GetResponseBytes() int64 {
+	if x != nil {
+		return x.ResponseBytes
+	}
+	return 0
+}
+
+func (x *PluginStats) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *PluginStats) GetLastCalledAt() int64 {
+	if x != nil {
+		return x.LastCalledAt
+	}
+	return 0
+}
+
+func (x *PluginStats) GetTimeoutMs() int64 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+func (x *PluginStats) GetMaxConcurrent() int32 {
+	if x != nil {
+		return x.MaxConcurrent
+	}
+	return 0
+}
+
 // 能力信息
 type CapabilityInfo struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -316,7 +513,7 @@ type CapabilityInfo struct {
 
 func (x *CapabilityInfo) Reset() {
 	*x = CapabilityInfo{}
-	mi := &file_proto_plugin_service_proto_msgTypes[4]
+	mi := &file_proto_plugin_service_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -328,7 +525,7 @@ func (x *CapabilityInfo) String() string {
 func (*CapabilityInfo) ProtoMessage() {}
 
 func (x *CapabilityInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[4]
+	mi := &file_proto_plugin_service_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -341,7 +538,7 @@ func (x *CapabilityInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CapabilityInfo.ProtoReflect.Descriptor instead.
 func (*CapabilityInfo) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{4}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *CapabilityInfo) GetType() string {
@@ -369,7 +566,7 @@ type ListPluginsResponse struct {
 
 func (x *ListPluginsResponse) Reset() {
 	*x = ListPluginsResponse{}
-	mi := &file_proto_plugin_service_proto_msgTypes[5]
+	mi := &file_proto_plugin_service_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -381,7 +578,7 @@ func (x *ListPluginsResponse) String() string {
 func (*ListPluginsResponse) ProtoMessage() {}
 
 func (x *ListPluginsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[5]
+	mi := &file_proto_plugin_service_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -394,7 +591,7 @@ func (x *ListPluginsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListPluginsResponse.ProtoReflect.Descriptor instead.
 func (*ListPluginsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{5}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *ListPluginsResponse) GetSuccess() bool {
@@ -423,7 +620,7 @@ type GetModelsRequest struct {
 
 func (x *GetModelsRequest) Reset() {
 	*x = GetModelsRequest{}
-	mi := &file_proto_plugin_service_proto_msgTypes[6]
+	mi := &file_proto_plugin_service_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -435,7 +632,7 @@ func (x *GetModelsRequest) String() string {
 func (*GetModelsRequest) ProtoMessage() {}
 
 func (x *GetModelsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[6]
+	mi := &file_proto_plugin_service_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -448,7 +645,7 @@ func (x *GetModelsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetModelsRequest.ProtoReflect.Descriptor instead.
 func (*GetModelsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{6}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *GetModelsRequest) GetPluginId() string {
@@ -483,7 +680,7 @@ type GetModelsResponse struct {
 
 func (x *GetModelsResponse) Reset() {
 	*x = GetModelsResponse{}
-	mi := &file_proto_plugin_service_proto_msgTypes[7]
+	mi := &file_proto_plugin_service_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -495,7 +692,7 @@ func (x *GetModelsResponse) String() string {
 func (*GetModelsResponse) ProtoMessage() {}
 
 func (x *GetModelsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[7]
+	mi := &file_proto_plugin_service_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -508,7 +705,7 @@ func (x *GetModelsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetModelsResponse.ProtoReflect.Descriptor instead.
 func (*GetModelsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{7}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *GetModelsResponse) GetSuccess() bool {
@@ -534,7 +731,7 @@ type ModelList struct {
 
 func (x *ModelList) Reset() {
 	*x = ModelList{}
-	mi := &file_proto_plugin_service_proto_msgTypes[8]
+	mi := &file_proto_plugin_service_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -546,7 +743,7 @@ func (x *ModelList) String() string {
 func (*ModelList) ProtoMessage() {}
 
 func (x *ModelList) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[8]
+	mi := &file_proto_plugin_service_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -559,7 +756,7 @@ func (x *ModelList) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ModelList.ProtoReflect.Descriptor instead.
 func (*ModelList) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{8}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *ModelList) GetModels() []string {
@@ -574,13 +771,14 @@ type EnablePluginRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	PluginId      string                 `protobuf:"bytes,1,opt,name=plugin_id,json=pluginId,proto3" json:"plugin_id,omitempty"`
 	UserId        uint32                 `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Scope         string                 `protobuf:"bytes,3,opt,name=scope,proto3" json:"scope,omitempty"` // user, org, global（为空时可管理插件的用户为global，其他为user）
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *EnablePluginRequest) Reset() {
 	*x = EnablePluginRequest{}
-	mi := &file_proto_plugin_service_proto_msgTypes[9]
+	mi := &file_proto_plugin_service_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -592,7 +790,7 @@ func (x *EnablePluginRequest) String() string {
 func (*EnablePluginRequest) ProtoMessage() {}
 
 func (x *EnablePluginRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[9]
+	mi := &file_proto_plugin_service_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -605,7 +803,7 @@ func (x *EnablePluginRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EnablePluginRequest.ProtoReflect.Descriptor instead.
 func (*EnablePluginRequest) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{9}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *EnablePluginRequest) GetPluginId() string {
@@ -622,18 +820,26 @@ func (x *EnablePluginRequest) GetUserId() uint32 {
 	return 0
 }
 
+func (x *EnablePluginRequest) GetScope() string {
+	if x != nil {
+		return x.Scope
+	}
+	return ""
+}
+
 // 启用插件响应
 type EnablePluginResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
 	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Scope         string                 `protobuf:"bytes,3,opt,name=scope,proto3" json:"scope,omitempty"` // 实际生效的范围
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *EnablePluginResponse) Reset() {
 	*x = EnablePluginResponse{}
-	mi := &file_proto_plugin_service_proto_msgTypes[10]
+	mi := &file_proto_plugin_service_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -645,7 +851,7 @@ func (x *EnablePluginResponse) String() string {
 func (*EnablePluginResponse) ProtoMessage() {}
 
 func (x *EnablePluginResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[10]
+	mi := &file_proto_plugin_service_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -658,7 +864,7 @@ func (x *EnablePluginResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EnablePluginResponse.ProtoReflect.Descriptor instead.
 func (*EnablePluginResponse) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{10}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *EnablePluginResponse) GetSuccess() bool {
@@ -675,18 +881,26 @@ func (x *EnablePluginResponse) GetMessage() string {
 	return ""
 }
 
+func (x *EnablePluginResponse) GetScope() string {
+	if x != nil {
+		return x.Scope
+	}
+	return ""
+}
+
 // 禁用插件请求
 type DisablePluginRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	PluginId      string                 `protobuf:"bytes,1,opt,name=plugin_id,json=pluginId,proto3" json:"plugin_id,omitempty"`
 	UserId        uint32                 `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Scope         string                 `protobuf:"bytes,3,opt,name=scope,proto3" json:"scope,omitempty"` // user, org, global（为空时可管理插件的用户为global，其他为user）
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *DisablePluginRequest) Reset() {
 	*x = DisablePluginRequest{}
-	mi := &file_proto_plugin_service_proto_msgTypes[11]
+	mi := &file_proto_plugin_service_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -698,7 +912,7 @@ func (x *DisablePluginRequest) String() string {
 func (*DisablePluginRequest) ProtoMessage() {}
 
 func (x *DisablePluginRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[11]
+	mi := &file_proto_plugin_service_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -711,7 +925,7 @@ func (x *DisablePluginRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DisablePluginRequest.ProtoReflect.Descriptor instead.
 func (*DisablePluginRequest) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{11}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *DisablePluginRequest) GetPluginId() string {
@@ -728,18 +942,26 @@ func (x *DisablePluginRequest) GetUserId() uint32 {
 	return 0
 }
 
+func (x *DisablePluginRequest) GetScope() string {
+	if x != nil {
+		return x.Scope
+	}
+	return ""
+}
+
 // 禁用插件响应
 type DisablePluginResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
 	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Scope         string                 `protobuf:"bytes,3,opt,name=scope,proto3" json:"scope,omitempty"` // 实际生效的范围
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *DisablePluginResponse) Reset() {
 	*x = DisablePluginResponse{}
-	mi := &file_proto_plugin_service_proto_msgTypes[12]
+	mi := &file_proto_plugin_service_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -751,7 +973,7 @@ func (x *DisablePluginResponse) String() string {
 func (*DisablePluginResponse) ProtoMessage() {}
 
 func (x *DisablePluginResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[12]
+	mi := &file_proto_plugin_service_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -764,7 +986,7 @@ func (x *DisablePluginResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DisablePluginResponse.ProtoReflect.Descriptor instead.
 func (*DisablePluginResponse) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{12}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *DisablePluginResponse) GetSuccess() bool {
@@ -781,6 +1003,13 @@ func (x *DisablePluginResponse) GetMessage() string {
 	return ""
 }
 
+func (x *DisablePluginResponse) GetScope() string {
+	if x != nil {
+		return x.Scope
+	}
+	return ""
+}
+
 // 删除插件请求
 type DeletePluginRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -792,7 +1021,7 @@ type DeletePluginRequest struct {
 
 func (x *DeletePluginRequest) Reset() {
 	*x = DeletePluginRequest{}
-	mi := &file_proto_plugin_service_proto_msgTypes[13]
+	mi := &file_proto_plugin_service_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -804,7 +1033,7 @@ func (x *DeletePluginRequest) String() string {
 func (*DeletePluginRequest) ProtoMessage() {}
 
 func (x *DeletePluginRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[13]
+	mi := &file_proto_plugin_service_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -817,7 +1046,7 @@ func (x *DeletePluginRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeletePluginRequest.ProtoReflect.Descriptor instead.
 func (*DeletePluginRequest) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{13}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *DeletePluginRequest) GetPluginId() string {
@@ -845,7 +1074,7 @@ type DeletePluginResponse struct {
 
 func (x *DeletePluginResponse) Reset() {
 	*x = DeletePluginResponse{}
-	mi := &file_proto_plugin_service_proto_msgTypes[14]
+	mi := &file_proto_plugin_service_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -857,7 +1086,7 @@ func (x *DeletePluginResponse) String() string {
 func (*DeletePluginResponse) ProtoMessage() {}
 
 func (x *DeletePluginResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[14]
+	mi := &file_proto_plugin_service_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -870,7 +1099,7 @@ func (x *DeletePluginResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeletePluginResponse.ProtoReflect.Descriptor instead.
 func (*DeletePluginResponse) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{14}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *DeletePluginResponse) GetSuccess() bool {
@@ -892,13 +1121,14 @@ type EmbedRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	PluginId      string                 `protobuf:"bytes,1,opt,name=plugin_id,json=pluginId,proto3" json:"plugin_id,omitempty"`
 	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	UserId        uint32                 `protobuf:"varint,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *EmbedRequest) Reset() {
 	*x = EmbedRequest{}
-	mi := &file_proto_plugin_service_proto_msgTypes[15]
+	mi := &file_proto_plugin_service_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -910,7 +1140,7 @@ func (x *EmbedRequest) String() string {
 func (*EmbedRequest) ProtoMessage() {}
 
 func (x *EmbedRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[15]
+	mi := &file_proto_plugin_service_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -923,7 +1153,7 @@ func (x *EmbedRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EmbedRequest.ProtoReflect.Descriptor instead.
 func (*EmbedRequest) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{15}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *EmbedRequest) GetPluginId() string {
@@ -940,6 +1170,13 @@ func (x *EmbedRequest) GetText() string {
 	return ""
 }
 
+func (x *EmbedRequest) GetUserId() uint32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
 // 向量化响应
 type EmbedResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -953,7 +1190,7 @@ type EmbedResponse struct {
 
 func (x *EmbedResponse) Reset() {
 	*x = EmbedResponse{}
-	mi := &file_proto_plugin_service_proto_msgTypes[16]
+	mi := &file_proto_plugin_service_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -965,7 +1202,7 @@ func (x *EmbedResponse) String() string {
 func (*EmbedResponse) ProtoMessage() {}
 
 func (x *EmbedResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[16]
+	mi := &file_proto_plugin_service_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -978,7 +1215,7 @@ func (x *EmbedResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EmbedResponse.ProtoReflect.Descriptor instead.
 func (*EmbedResponse) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{16}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *EmbedResponse) GetSuccess() bool {
@@ -1014,13 +1251,14 @@ type EmbedBatchRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	PluginId      string                 `protobuf:"bytes,1,opt,name=plugin_id,json=pluginId,proto3" json:"plugin_id,omitempty"`
 	Texts         []string               `protobuf:"bytes,2,rep,name=texts,proto3" json:"texts,omitempty"`
+	UserId        uint32                 `protobuf:"varint,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *EmbedBatchRequest) Reset() {
 	*x = EmbedBatchRequest{}
-	mi := &file_proto_plugin_service_proto_msgTypes[17]
+	mi := &file_proto_plugin_service_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1032,7 +1270,7 @@ func (x *EmbedBatchRequest) String() string {
 func (*EmbedBatchRequest) ProtoMessage() {}
 
 func (x *EmbedBatchRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[17]
+	mi := &file_proto_plugin_service_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1045,7 +1283,7 @@ func (x *EmbedBatchRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EmbedBatchRequest.ProtoReflect.Descriptor instead.
 func (*EmbedBatchRequest) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{17}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *EmbedBatchRequest) GetPluginId() string {
@@ -1062,6 +1300,13 @@ func (x *EmbedBatchRequest) GetTexts() []string {
 	return nil
 }
 
+func (x *EmbedBatchRequest) GetUserId() uint32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
 // 批量向量化响应
 type EmbedBatchResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -1074,7 +1319,7 @@ type EmbedBatchResponse struct {
 
 func (x *EmbedBatchResponse) Reset() {
 	*x = EmbedBatchResponse{}
-	mi := &file_proto_plugin_service_proto_msgTypes[18]
+	mi := &file_proto_plugin_service_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1086,7 +1331,7 @@ func (x *EmbedBatchResponse) String() string {
 func (*EmbedBatchResponse) ProtoMessage() {}
 
 func (x *EmbedBatchResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[18]
+	mi := &file_proto_plugin_service_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1099,7 +1344,7 @@ func (x *EmbedBatchResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EmbedBatchResponse.ProtoReflect.Descriptor instead.
 func (*EmbedBatchResponse) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{18}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *EmbedBatchResponse) GetSuccess() bool {
@@ -1132,7 +1377,7 @@ type EmbeddingResult struct {
 
 func (x *EmbeddingResult) Reset() {
 	*x = EmbeddingResult{}
-	mi := &file_proto_plugin_service_proto_msgTypes[19]
+	mi := &file_proto_plugin_service_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1144,7 +1389,7 @@ func (x *EmbeddingResult) String() string {
 func (*EmbeddingResult) ProtoMessage() {}
 
 func (x *EmbeddingResult) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[19]
+	mi := &file_proto_plugin_service_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1157,7 +1402,7 @@ func (x *EmbeddingResult) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EmbeddingResult.ProtoReflect.Descriptor instead.
 func (*EmbeddingResult) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{19}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *EmbeddingResult) GetEmbedding() []float32 {
@@ -1173,13 +1418,14 @@ type RerankRequest struct {
 	PluginId      string                 `protobuf:"bytes,1,opt,name=plugin_id,json=pluginId,proto3" json:"plugin_id,omitempty"`
 	Query         string                 `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
 	Documents     []*RerankDocument      `protobuf:"bytes,3,rep,name=documents,proto3" json:"documents,omitempty"`
+	UserId        uint32                 `protobuf:"varint,4,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *RerankRequest) Reset() {
 	*x = RerankRequest{}
-	mi := &file_proto_plugin_service_proto_msgTypes[20]
+	mi := &file_proto_plugin_service_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1191,7 +1437,7 @@ func (x *RerankRequest) String() string {
 func (*RerankRequest) ProtoMessage() {}
 
 func (x *RerankRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[20]
+	mi := &file_proto_plugin_service_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1204,7 +1450,7 @@ func (x *RerankRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RerankRequest.ProtoReflect.Descriptor instead.
 func (*RerankRequest) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{20}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *RerankRequest) GetPluginId() string {
@@ -1228,6 +1474,13 @@ func (x *RerankRequest) GetDocuments() []*RerankDocument {
 	return nil
 }
 
+func (x *RerankRequest) GetUserId() uint32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
 // 重排序文档
 type RerankDocument struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -1240,7 +1493,7 @@ type RerankDocument struct {
 
 func (x *RerankDocument) Reset() {
 	*x = RerankDocument{}
-	mi := &file_proto_plugin_service_proto_msgTypes[21]
+	mi := &file_proto_plugin_service_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1252,7 +1505,7 @@ func (x *RerankDocument) String() string {
 func (*RerankDocument) ProtoMessage() {}
 
 func (x *RerankDocument) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[21]
+	mi := &file_proto_plugin_service_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1265,7 +1518,7 @@ func (x *RerankDocument) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RerankDocument.ProtoReflect.Descriptor instead.
 func (*RerankDocument) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{21}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *RerankDocument) GetId() uint32 {
@@ -1301,7 +1554,7 @@ type RerankResponse struct {
 
 func (x *RerankResponse) Reset() {
 	*x = RerankResponse{}
-	mi := &file_proto_plugin_service_proto_msgTypes[22]
+	mi := &file_proto_plugin_service_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1313,7 +1566,7 @@ func (x *RerankResponse) String() string {
 func (*RerankResponse) ProtoMessage() {}
 
 func (x *RerankResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[22]
+	mi := &file_proto_plugin_service_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1326,7 +1579,7 @@ func (x *RerankResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RerankResponse.ProtoReflect.Descriptor instead.
 func (*RerankResponse) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{22}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *RerankResponse) GetSuccess() bool {
@@ -1362,7 +1615,7 @@ type RerankResult struct {
 
 func (x *RerankResult) Reset() {
 	*x = RerankResult{}
-	mi := &file_proto_plugin_service_proto_msgTypes[23]
+	mi := &file_proto_plugin_service_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1374,7 +1627,7 @@ func (x *RerankResult) String() string {
 func (*RerankResult) ProtoMessage() {}
 
 func (x *RerankResult) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_plugin_service_proto_msgTypes[23]
+	mi := &file_proto_plugin_service_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1387,7 +1640,7 @@ func (x *RerankResult) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RerankResult.ProtoReflect.Descriptor instead.
 func (*RerankResult) Descriptor() ([]byte, []int) {
-	return file_proto_plugin_service_proto_rawDescGZIP(), []int{23}
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *RerankResult) GetDocument() *RerankDocument {
@@ -1411,115 +1664,1384 @@ func (x *RerankResult) GetRank() int32 {
 	return 0
 }
 
-var File_proto_plugin_service_proto protoreflect.FileDescriptor
+// 列出插件版本请求
+type ListPluginVersionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PluginId      string                 `protobuf:"bytes,1,opt,name=plugin_id,json=pluginId,proto3" json:"plugin_id,omitempty"`
+	UserId        uint32                 `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_proto_plugin_service_proto_rawDesc = "" +
-	"\n" +
-	"\x1aproto/plugin_service.proto\x12\x0eplugin_service\"m\n" +
-	"\x13UploadPluginRequest\x12!\n" +
-	"\ffile_content\x18\x01 \x01(\fR\vfileContent\x12\x1a\n" +
-	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x17\n" +
-	"\auser_id\x18\x03 \x01(\rR\x06userId\"\x83\x01\n" +
-	"\x14UploadPluginResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1b\n" +
-	"\tplugin_id\x18\x03 \x01(\tR\bpluginId\x12\x1a\n" +
-	"\bfilename\x18\x04 \x01(\tR\bfilename\"-\n" +
-	"\x12ListPluginsRequest\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\rR\x06userId\"\x94\x02\n" +
-	"\n" +
-	"PluginInfo\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
-	"\x04name\x18\x02 \x01(\tR\x04name\x12\x18\n" +
-	"\aversion\x18\x03 \x01(\tR\aversion\x12 \n" +
-	"\vdescription\x18\x04 \x01(\tR\vdescription\x12\x16\n" +
-	"\x06author\x18\x05 \x01(\tR\x06author\x12\x18\n" +
-	"\alicense\x18\x06 \x01(\tR\alicense\x12\x1a\n" +
-	"\bprovider\x18\a \x01(\tR\bprovider\x12\x14\n" +
-	"\x05state\x18\b \x01(\tR\x05state\x12B\n" +
-	"\fcapabilities\x18\t \x03(\v2\x1e.plugin_service.CapabilityInfoR\fcapabilities\"<\n" +
-	"\x0eCapabilityInfo\x12\x12\n" +
-	"\x04type\x18\x01 \x01(\tR\x04type\x12\x16\n" +
-	"\x06models\x18\x02 \x03(\tR\x06models\"e\n" +
-	"\x13ListPluginsResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x124\n" +
-	"\aplugins\x18\x02 \x03(\v2\x1a.plugin_service.PluginInfoR\aplugins\"a\n" +
-	"\x10GetModelsRequest\x12\x1b\n" +
-	"\tplugin_id\x18\x01 \x01(\tR\bpluginId\x12\x17\n" +
-	"\aapi_key\x18\x02 \x01(\tR\x06apiKey\x12\x17\n" +
-	"\auser_id\x18\x03 \x01(\rR\x06userId\"\xca\x01\n" +
-	"\x11GetModelsResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x12E\n" +
-	"\x06models\x18\x02 \x03(\v2-.plugin_service.GetModelsResponse.ModelsEntryR\x06models\x1aT\n" +
-	"\vModelsEntry\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\x12/\n" +
-	"\x05value\x18\x02 \x01(\v2\x19.plugin_service.ModelListR\x05value:\x028\x01\"#\n" +
-	"\tModelList\x12\x16\n" +
-	"\x06models\x18\x01 \x03(\tR\x06models\"K\n" +
-	"\x13EnablePluginRequest\x12\x1b\n" +
-	"\tplugin_id\x18\x01 \x01(\tR\bpluginId\x12\x17\n" +
-	"\auser_id\x18\x02 \x01(\rR\x06userId\"J\n" +
-	"\x14EnablePluginResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\"L\n" +
-	"\x14DisablePluginRequest\x12\x1b\n" +
-	"\tplugin_id\x18\x01 \x01(\tR\bpluginId\x12\x17\n" +
-	"\auser_id\x18\x02 \x01(\rR\x06userId\"K\n" +
-	"\x15DisablePluginResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\"K\n" +
-	"\x13DeletePluginRequest\x12\x1b\n" +
-	"\tplugin_id\x18\x01 \x01(\tR\bpluginId\x12\x17\n" +
-	"\auser_id\x18\x02 \x01(\rR\x06userId\"J\n" +
-	"\x14DeletePluginResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\"?\n" +
-	"\fEmbedRequest\x12\x1b\n" +
-	"\tplugin_id\x18\x01 \x01(\tR\bpluginId\x12\x12\n" +
-	"\x04text\x18\x02 \x01(\tR\x04text\"}\n" +
-	"\rEmbedResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1c\n" +
-	"\tembedding\x18\x02 \x03(\x02R\tembedding\x12\x1e\n" +
-	"\n" +
-	"dimensions\x18\x03 \x01(\x05R\n" +
-	"dimensions\x12\x14\n" +
-	"\x05error\x18\x04 \x01(\tR\x05error\"F\n" +
-	"\x11EmbedBatchRequest\x12\x1b\n" +
-	"\tplugin_id\x18\x01 \x01(\tR\bpluginId\x12\x14\n" +
-	"\x05texts\x18\x02 \x03(\tR\x05texts\"\x7f\n" +
-	"\x12EmbedBatchResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x129\n" +
-	"\aresults\x18\x02 \x03(\v2\x1f.plugin_service.EmbeddingResultR\aresults\x12\x14\n" +
-	"\x05error\x18\x03 \x01(\tR\x05error\"/\n" +
-	"\x0fEmbeddingResult\x12\x1c\n" +
-	"\tembedding\x18\x01 \x03(\x02R\tembedding\"\x80\x01\n" +
-	"\rRerankRequest\x12\x1b\n" +
-	"\tplugin_id\x18\x01 \x01(\tR\bpluginId\x12\x14\n" +
-	"\x05query\x18\x02 \x01(\tR\x05query\x12<\n" +
-	"\tdocuments\x18\x03 \x03(\v2\x1e.plugin_service.RerankDocumentR\tdocuments\"P\n" +
-	"\x0eRerankDocument\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\rR\x02id\x12\x18\n" +
-	"\acontent\x18\x02 \x01(\tR\acontent\x12\x14\n" +
-	"\x05score\x18\x03 \x01(\x01R\x05score\"x\n" +
-	"\x0eRerankResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x126\n" +
-	"\aresults\x18\x02 \x03(\v2\x1c.plugin_service.RerankResultR\aresults\x12\x14\n" +
-	"\x05error\x18\x03 \x01(\tR\x05error\"t\n" +
-	"\fRerankResult\x12:\n" +
-	"\bdocument\x18\x01 \x01(\v2\x1e.plugin_service.RerankDocumentR\bdocument\x12\x14\n" +
-	"\x05score\x18\x02 \x01(\x01R\x05score\x12\x12\n" +
-	"\x04rank\x18\x03 \x01(\x05R\x04rank2\x8c\x06\n" +
-	"\rPluginService\x12Y\n" +
-	"\fUploadPlugin\x12#.plugin_service.UploadPluginRequest\x1a$.plugin_service.UploadPluginResponse\x12V\n" +
-	"\vListPlugins\x12\".plugin_service.ListPluginsRequest\x1a#.plugin_service.ListPluginsResponse\x12P\n" +
-	"\tGetModels\x12 .plugin_service.GetModelsRequest\x1a!.plugin_service.GetModelsResponse\x12Y\n" +
-	"\fEnablePlugin\x12#.plugin_service.EnablePluginRequest\x1a$.plugin_service.EnablePluginResponse\x12\\\n" +
-	"\rDisablePlugin\x12$.plugin_service.DisablePluginRequest\x1a%.plugin_service.DisablePluginResponse\x12Y\n" +
-	"\fDeletePlugin\x12#.plugin_service.DeletePluginRequest\x1a$.plugin_service.DeletePluginResponse\x12D\n" +
-	"\x05Embed\x12\x1c.plugin_service.EmbedRequest\x1a\x1d.plugin_service.EmbedResponse\x12S\n" +
-	"\n" +
-	"EmbedBatch\x12!.plugin_service.EmbedBatchRequest\x1a\".plugin_service.EmbedBatchResponse\x12G\n" +
-	"\x06Rerank\x12\x1d.plugin_service.RerankRequest\x1a\x1e.plugin_service.RerankResponseB\x18Z\x16./proto/plugin_serviceb\x06proto3"
+func (x *ListPluginVersionsRequest) Reset() {
+	*x = ListPluginVersionsRequest{}
+	mi := &file_proto_plugin_service_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPluginVersionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPluginVersionsRequest) ProtoMessage() {}
+
+func (x *ListPluginVersionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_service_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPluginVersionsRequest.ProtoReflect.Descriptor instead.
+func (*ListPluginVersionsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ListPluginVersionsRequest) GetPluginId() string {
+	if x != nil {
+		return x.PluginId
+	}
+	return ""
+}
+
+func (x *ListPluginVersionsRequest) GetUserId() uint32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+// 插件版本信息
+type PluginVersionInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	Active        bool                   `protobuf:"varint,3,opt,name=active,proto3" json:"active,omitempty"`
+	InstalledAt   int64                  `protobuf:"varint,4,opt,name=installed_at,json=installedAt,proto3" json:"installed_at,omitempty"`
+	ActivatedAt   int64                  `protobuf:"varint,5,opt,name=activated_at,json=activatedAt,proto3" json:"activated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PluginVersionInfo) Reset() {
+	*x = PluginVersionInfo{}
+	mi := &file_proto_plugin_service_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PluginVersionInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PluginVersionInfo) ProtoMessage() {}
+
+func (x *PluginVersionInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_service_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PluginVersionInfo.ProtoReflect.Descriptor instead.
+func (*PluginVersionInfo) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *PluginVersionInfo) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *PluginVersionInfo) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *PluginVersionInfo) GetActive() bool {
+	if x != nil {
+		return x.Active
+	}
+	return false
+}
+
+func (x *PluginVersionInfo) GetInstalledAt() int64 {
+	if x != nil {
+		return x.InstalledAt
+	}
+	return 0
+}
+
+func (x *PluginVersionInfo) GetActivatedAt() int64 {
+	if x != nil {
+		return x.ActivatedAt
+	}
+	return 0
+}
+
+// 列出插件版本响应
+type ListPluginVersionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Versions      []*PluginVersionInfo   `protobuf:"bytes,2,rep,name=versions,proto3" json:"versions,omitempty"`
+	ActiveVersion string                 `protobuf:"bytes,3,opt,name=active_version,json=activeVersion,proto3" json:"active_version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPluginVersionsResponse) Reset() {
+	*x = ListPluginVersionsResponse{}
+	mi := &file_proto_plugin_service_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPluginVersionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPluginVersionsResponse) ProtoMessage() {}
+
+func (x *ListPluginVersionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_service_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPluginVersionsResponse.ProtoReflect.Descriptor instead.
+func (*ListPluginVersionsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *ListPluginVersionsResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ListPluginVersionsResponse) GetVersions() []*PluginVersionInfo {
+	if x != nil {
+		return x.Versions
+	}
+	return nil
+}
+
+func (x *ListPluginVersionsResponse) GetActiveVersion() string {
+	if x != nil {
+		return x.ActiveVersion
+	}
+	return ""
+}
+
+// 切换版本请求
+type ActivateVersionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PluginId      string                 `protobuf:"bytes,1,opt,name=plugin_id,json=pluginId,proto3" json:"plugin_id,omitempty"`
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	UserId        uint32                 `protobuf:"varint,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ActivateVersionRequest) Reset() {
+	*x = ActivateVersionRequest{}
+	mi := &file_proto_plugin_service_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ActivateVersionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ActivateVersionRequest) ProtoMessage() {}
+
+func (x *ActivateVersionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_service_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ActivateVersionRequest.ProtoReflect.Descriptor instead.
+func (*ActivateVersionRequest) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *ActivateVersionRequest) GetPluginId() string {
+	if x != nil {
+		return x.PluginId
+	}
+	return ""
+}
+
+func (x *ActivateVersionRequest) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *ActivateVersionRequest) GetUserId() uint32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+// 切换版本响应
+type ActivateVersionResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Success         bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message         string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Version         string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	PreviousVersion string                 `protobuf:"bytes,4,opt,name=previous_version,json=previousVersion,proto3" json:"previous_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ActivateVersionResponse) Reset() {
+	*x = ActivateVersionResponse{}
+	mi := &file_proto_plugin_service_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ActivateVersionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ActivateVersionResponse) ProtoMessage() {}
+
+func (x *ActivateVersionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_service_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ActivateVersionResponse.ProtoReflect.Descriptor instead.
+func (*ActivateVersionResponse) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ActivateVersionResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ActivateVersionResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ActivateVersionResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *ActivateVersionResponse) GetPreviousVersion() string {
+	if x != nil {
+		return x.PreviousVersion
+	}
+	return ""
+}
+
+// 回滚请求
+type RollbackRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PluginId      string                 `protobuf:"bytes,1,opt,name=plugin_id,json=pluginId,proto3" json:"plugin_id,omitempty"`
+	UserId        uint32                 `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RollbackRequest) Reset() {
+	*x = RollbackRequest{}
+	mi := &file_proto_plugin_service_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RollbackRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RollbackRequest) ProtoMessage() {}
+
+func (x *RollbackRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_service_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RollbackRequest.ProtoReflect.Descriptor instead.
+func (*RollbackRequest) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *RollbackRequest) GetPluginId() string {
+	if x != nil {
+		return x.PluginId
+	}
+	return ""
+}
+
+func (x *RollbackRequest) GetUserId() uint32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+// 回滚响应
+type RollbackResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Success         bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message         string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Version         string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	PreviousVersion string                 `protobuf:"bytes,4,opt,name=previous_version,json=previousVersion,proto3" json:"previous_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *RollbackResponse) Reset() {
+	*x = RollbackResponse{}
+	mi := &file_proto_plugin_service_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RollbackResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RollbackResponse) ProtoMessage() {}
+
+func (x *RollbackResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_service_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RollbackResponse.ProtoReflect.Descriptor instead.
+func (*RollbackResponse) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *RollbackResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RollbackResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *RollbackResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *RollbackResponse) GetPreviousVersion() string {
+	if x != nil {
+		return x.PreviousVersion
+	}
+	return ""
+}
+
+// 聊天消息
+type ChatMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Role          string                 `protobuf:"bytes,1,opt,name=role,proto3" json:"role,omitempty"` // system, user, assistant, tool
+	Content       string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	ToolCallId    string                 `protobuf:"bytes,4,opt,name=tool_call_id,json=toolCallId,proto3" json:"tool_call_id,omitempty"` // role为tool时对应的调用ID
+	ToolCalls     []*ToolCall            `protobuf:"bytes,5,rep,name=tool_calls,json=toolCalls,proto3" json:"tool_calls,omitempty"`      // 模型发起的工具调用
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatMessage) Reset() {
+	*x = ChatMessage{}
+	mi := &file_proto_plugin_service_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatMessage) ProtoMessage() {}
+
+func (x *ChatMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_service_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatMessage.ProtoReflect.Descriptor instead.
+func (*ChatMessage) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *ChatMessage) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetToolCallId() string {
+	if x != nil {
+		return x.ToolCallId
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetToolCalls() []*ToolCall {
+	if x != nil {
+		return x.ToolCalls
+	}
+	return nil
+}
+
+// 工具调用
+type ToolCall struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`           // 目前仅支持 function
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`           // 函数名
+	Arguments     string                 `protobuf:"bytes,4,opt,name=arguments,proto3" json:"arguments,omitempty"` // JSON格式的参数
+	Index         int32                  `protobuf:"varint,5,opt,name=index,proto3" json:"index,omitempty"`        // 流式增量中的序号
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ToolCall) Reset() {
+	*x = ToolCall{}
+	mi := &file_proto_plugin_service_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolCall) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolCall) ProtoMessage() {}
+
+func (x *ToolCall) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_service_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolCall.ProtoReflect.Descriptor instead.
+func (*ToolCall) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *ToolCall) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ToolCall) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ToolCall) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ToolCall) GetArguments() string {
+	if x != nil {
+		return x.Arguments
+	}
+	return ""
+}
+
+func (x *ToolCall) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+// 工具定义
+type ToolDefinition struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Type           string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"` // 目前仅支持 function
+	Name           string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Description    string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	ParametersJson []byte                 `protobuf:"bytes,4,opt,name=parameters_json,json=parametersJson,proto3" json:"parameters_json,omitempty"` // JSON Schema
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ToolDefinition) Reset() {
+	*x = ToolDefinition{}
+	mi := &file_proto_plugin_service_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolDefinition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolDefinition) ProtoMessage() {}
+
+func (x *ToolDefinition) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_service_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolDefinition.ProtoReflect.Descriptor instead.
+func (*ToolDefinition) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *ToolDefinition) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ToolDefinition) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ToolDefinition) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *ToolDefinition) GetParametersJson() []byte {
+	if x != nil {
+		return x.ParametersJson
+	}
+	return nil
+}
+
+// 采样参数
+type SamplingParams struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Temperature   float64                `protobuf:"fixed64,1,opt,name=temperature,proto3" json:"temperature,omitempty"`
+	MaxTokens     int32                  `protobuf:"varint,2,opt,name=max_tokens,json=maxTokens,proto3" json:"max_tokens,omitempty"`
+	TopP          float64                `protobuf:"fixed64,3,opt,name=top_p,json=topP,proto3" json:"top_p,omitempty"`
+	TopK          int32                  `protobuf:"varint,4,opt,name=top_k,json=topK,proto3" json:"top_k,omitempty"`
+	Stop          []string               `protobuf:"bytes,5,rep,name=stop,proto3" json:"stop,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SamplingParams) Reset() {
+	*x = SamplingParams{}
+	mi := &file_proto_plugin_service_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SamplingParams) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SamplingParams) ProtoMessage() {}
+
+func (x *SamplingParams) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_service_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SamplingParams.ProtoReflect.Descriptor instead.
+func (*SamplingParams) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *SamplingParams) GetTemperature() float64 {
+	if x != nil {
+		return x.Temperature
+	}
+	return 0
+}
+
+func (x *SamplingParams) GetMaxTokens() int32 {
+	if x != nil {
+		return x.MaxTokens
+	}
+	return 0
+}
+
+func (x *SamplingParams) GetTopP() float64 {
+	if x != nil {
+		return x.TopP
+	}
+	return 0
+}
+
+func (x *SamplingParams) GetTopK() int32 {
+	if x != nil {
+		return x.TopK
+	}
+	return 0
+}
+
+func (x *SamplingParams) GetStop() []string {
+	if x != nil {
+		return x.Stop
+	}
+	return nil
+}
+
+// 聊天请求
+type ChatRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	PluginId        string                 `protobuf:"bytes,1,opt,name=plugin_id,json=pluginId,proto3" json:"plugin_id,omitempty"`
+	Model           string                 `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"`
+	Messages        []*ChatMessage         `protobuf:"bytes,3,rep,name=messages,proto3" json:"messages,omitempty"`
+	Params          *SamplingParams        `protobuf:"bytes,4,opt,name=params,proto3" json:"params,omitempty"`
+	Tools           []*ToolDefinition      `protobuf:"bytes,5,rep,name=tools,proto3" json:"tools,omitempty"`
+	ToolChoice      string                 `protobuf:"bytes,6,opt,name=tool_choice,json=toolChoice,proto3" json:"tool_choice,omitempty"`                  // auto, none 或指定函数名
+	ExtraParamsJson []byte                 `protobuf:"bytes,7,opt,name=extra_params_json,json=extraParamsJson,proto3" json:"extra_params_json,omitempty"` // 插件特定参数
+	UserId          uint32                 `protobuf:"varint,8,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ChatRequest) Reset() {
+	*x = ChatRequest{}
+	mi := &file_proto_plugin_service_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatRequest) ProtoMessage() {}
+
+func (x *ChatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_service_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatRequest.ProtoReflect.Descriptor instead.
+func (*ChatRequest) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *ChatRequest) GetPluginId() string {
+	if x != nil {
+		return x.PluginId
+	}
+	return ""
+}
+
+func (x *ChatRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *ChatRequest) GetMessages() []*ChatMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+func (x *ChatRequest) GetParams() *SamplingParams {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *ChatRequest) GetTools() []*ToolDefinition {
+	if x != nil {
+		return x.Tools
+	}
+	return nil
+}
+
+func (x *ChatRequest) GetToolChoice() string {
+	if x != nil {
+		return x.ToolChoice
+	}
+	return ""
+}
+
+func (x *ChatRequest) GetExtraParamsJson() []byte {
+	if x != nil {
+		return x.ExtraParamsJson
+	}
+	return nil
+}
+
+func (x *ChatRequest) GetUserId() uint32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+// Token用量
+type ChatUsage struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	PromptTokens     int32                  `protobuf:"varint,1,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	CompletionTokens int32                  `protobuf:"varint,2,opt,name=completion_tokens,json=completionTokens,proto3" json:"completion_tokens,omitempty"`
+	TotalTokens      int32                  `protobuf:"varint,3,opt,name=total_tokens,json=totalTokens,proto3" json:"total_tokens,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ChatUsage) Reset() {
+	*x = ChatUsage{}
+	mi := &file_proto_plugin_service_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatUsage) ProtoMessage() {}
+
+func (x *ChatUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_service_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatUsage.ProtoReflect.Descriptor instead.
+func (*ChatUsage) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *ChatUsage) GetPromptTokens() int32 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *ChatUsage) GetCompletionTokens() int32 {
+	if x != nil {
+		return x.CompletionTokens
+	}
+	return 0
+}
+
+func (x *ChatUsage) GetTotalTokens() int32 {
+	if x != nil {
+		return x.TotalTokens
+	}
+	return 0
+}
+
+// 聊天候选
+type ChatChoice struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Message       *ChatMessage           `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	FinishReason  string                 `protobuf:"bytes,3,opt,name=finish_reason,json=finishReason,proto3" json:"finish_reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatChoice) Reset() {
+	*x = ChatChoice{}
+	mi := &file_proto_plugin_service_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatChoice) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatChoice) ProtoMessage() {}
+
+func (x *ChatChoice) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_service_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatChoice.ProtoReflect.Descriptor instead.
+func (*ChatChoice) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *ChatChoice) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *ChatChoice) GetMessage() *ChatMessage {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *ChatChoice) GetFinishReason() string {
+	if x != nil {
+		return x.FinishReason
+	}
+	return ""
+}
+
+// 聊天响应
+type ChatResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Id            string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Model         string                 `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`
+	Choices       []*ChatChoice          `protobuf:"bytes,4,rep,name=choices,proto3" json:"choices,omitempty"`
+	Usage         *ChatUsage             `protobuf:"bytes,5,opt,name=usage,proto3" json:"usage,omitempty"`
+	Error         string                 `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatResponse) Reset() {
+	*x = ChatResponse{}
+	mi := &file_proto_plugin_service_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatResponse) ProtoMessage() {}
+
+func (x *ChatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_service_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatResponse.ProtoReflect.Descriptor instead.
+func (*ChatResponse) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *ChatResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ChatResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ChatResponse) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *ChatResponse) GetChoices() []*ChatChoice {
+	if x != nil {
+		return x.Choices
+	}
+	return nil
+}
+
+func (x *ChatResponse) GetUsage() *ChatUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+func (x *ChatResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// 流式聊天分片
+type ChatStreamChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Model         string                 `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"`
+	Index         int32                  `protobuf:"varint,3,opt,name=index,proto3" json:"index,omitempty"`
+	Delta         *ChatMessage           `protobuf:"bytes,4,opt,name=delta,proto3" json:"delta,omitempty"` // 增量内容
+	FinishReason  string                 `protobuf:"bytes,5,opt,name=finish_reason,json=finishReason,proto3" json:"finish_reason,omitempty"`
+	Usage         *ChatUsage             `protobuf:"bytes,6,opt,name=usage,proto3" json:"usage,omitempty"` // 通常只在最后一个分片中返回
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatStreamChunk) Reset() {
+	*x = ChatStreamChunk{}
+	mi := &file_proto_plugin_service_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatStreamChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatStreamChunk) ProtoMessage() {}
+
+func (x *ChatStreamChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_plugin_service_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatStreamChunk.ProtoReflect.Descriptor instead.
+func (*ChatStreamChunk) Descriptor() ([]byte, []int) {
+	return file_proto_plugin_service_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *ChatStreamChunk) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ChatStreamChunk) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *ChatStreamChunk) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *ChatStreamChunk) GetDelta() *ChatMessage {
+	if x != nil {
+		return x.Delta
+	}
+	return nil
+}
+
+func (x *ChatStreamChunk) GetFinishReason() string {
+	if x != nil {
+		return x.FinishReason
+	}
+	return ""
+}
+
+func (x *ChatStreamChunk) GetUsage() *ChatUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+var File_proto_plugin_service_proto protoreflect.FileDescriptor
+
+const file_proto_plugin_service_proto_rawDesc = "" +
+	"\n" +
+	"\x1aproto/plugin_service.proto\x12\x0eplugin_service\"\x8d\x01\n" +
+	"\x13UploadPluginRequest\x12!\n" +
+	"\ffile_content\x18\x01 \x01(\fR\vfileContent\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x17\n" +
+	"\auser_id\x18\x03 \x01(\rR\x06userId\x12\x1e\n" +
+	"\n" +
+	"visibility\x18\x04 \x01(\tR\n" +
+	"visibility\"\xc8\x01\n" +
+	"\x14UploadPluginResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1b\n" +
+	"\tplugin_id\x18\x03 \x01(\tR\bpluginId\x12\x1a\n" +
+	"\bfilename\x18\x04 \x01(\tR\bfilename\x12\x18\n" +
+	"\aversion\x18\x05 \x01(\tR\aversion\x12)\n" +
+	"\x10previous_version\x18\x06 \x01(\tR\x0fpreviousVersion\"-\n" +
+	"\x12ListPluginsRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\rR\x06userId\"\xb3\x03\n" +
+	"\n" +
+	"PluginInfo\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\x12 \n" +
+	"\vdescription\x18\x04 \x01(\tR\vdescription\x12\x16\n" +
+	"\x06author\x18\x05 \x01(\tR\x06author\x12\x18\n" +
+	"\alicense\x18\x06 \x01(\tR\alicense\x12\x1a\n" +
+	"\bprovider\x18\a \x01(\tR\bprovider\x12\x14\n" +
+	"\x05state\x18\b \x01(\tR\x05state\x12B\n" +
+	"\fcapabilities\x18\t \x03(\v2\x1e.plugin_service.CapabilityInfoR\fcapabilities\x121\n" +
+	"\x05stats\x18\n" +
+	" \x01(\v2\x1b.plugin_service.PluginStatsR\x05stats\x12\x19\n" +
+	"\bowner_id\x18\v \x01(\rR\aownerId\x12\x15\n" +
+	"\x06org_id\x18\f \x01(\rR\x05orgId\x12\x1e\n" +
+	"\n" +
+	"visibility\x18\r \x01(\tR\n" +
+	"visibility\x12\x18\n" +
+	"\aenabled\x18\x0e \x01(\bR\aenabled\"\xb6\x03\n" +
+	"\vPluginStats\x12\x1f\n" +
+	"\vtotal_calls\x18\x01 \x01(\x03R\n" +
+	"totalCalls\x12\x1f\n" +
+	"\verror_calls\x18\x02 \x01(\x03R\n" +
+	"errorCalls\x12%\n" +
+	"\x0erejected_calls\x18\x03 \x01(\x03R\rrejectedCalls\x12\x1b\n" +
+	"\tin_flight\x18\x04 \x01(\x03R\binFlight\x12$\n" +
+	"\x0eavg_latency_ms\x18\x05 \x01(\x01R\favgLatencyMs\x12$\n" +
+	"\x0emax_latency_ms\x18\x06 \x01(\x01R\fmaxLatencyMs\x12#\n" +
+	"\rrequest_bytes\x18\a \x01(\x03R\frequestBytes\x12%\n" +
+	"\x0eresponse_bytes\x18\b \x01(\x03R\rresponseBytes\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\t \x01(\tR\tlastError\x12$\n" +
+	"\x0elast_called_at\x18\n" +
+	" \x01(\x03R\flastCalledAt\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\v \x01(\x03R\ttimeoutMs\x12%\n" +
+	"\x0emax_concurrent\x18\f \x01(\x05R\rmaxConcurrent\"<\n" +
+	"\x0eCapabilityInfo\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x16\n" +
+	"\x06models\x18\x02 \x03(\tR\x06models\"e\n" +
+	"\x13ListPluginsResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x124\n" +
+	"\aplugins\x18\x02 \x03(\v2\x1a.plugin_service.PluginInfoR\aplugins\"a\n" +
+	"\x10GetModelsRequest\x12\x1b\n" +
+	"\tplugin_id\x18\x01 \x01(\tR\bpluginId\x12\x17\n" +
+	"\aapi_key\x18\x02 \x01(\tR\x06apiKey\x12\x17\n" +
+	"\auser_id\x18\x03 \x01(\rR\x06userId\"\xca\x01\n" +
+	"\x11GetModelsResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12E\n" +
+	"\x06models\x18\x02 \x03(\v2-.plugin_service.GetModelsResponse.ModelsEntryR\x06models\x1aT\n" +
+	"\vModelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12/\n" +
+	"\x05value\x18\x02 \x01(\v2\x19.plugin_service.ModelListR\x05value:\x028\x01\"#\n" +
+	"\tModelList\x12\x16\n" +
+	"\x06models\x18\x01 \x03(\tR\x06models\"a\n" +
+	"\x13EnablePluginRequest\x12\x1b\n" +
+	"\tplugin_id\x18\x01 \x01(\tR\bpluginId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\rR\x06userId\x12\x14\n" +
+	"\x05scope\x18\x03 \x01(\tR\x05scope\"`\n" +
+	"\x14EnablePluginResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x14\n" +
+	"\x05scope\x18\x03 \x01(\tR\x05scope\"b\n" +
+	"\x14DisablePluginRequest\x12\x1b\n" +
+	"\tplugin_id\x18\x01 \x01(\tR\bpluginId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\rR\x06userId\x12\x14\n" +
+	"\x05scope\x18\x03 \x01(\tR\x05scope\"a\n" +
+	"\x15DisablePluginResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x14\n" +
+	"\x05scope\x18\x03 \x01(\tR\x05scope\"K\n" +
+	"\x13DeletePluginRequest\x12\x1b\n" +
+	"\tplugin_id\x18\x01 \x01(\tR\bpluginId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\rR\x06userId\"J\n" +
+	"\x14DeletePluginResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"X\n" +
+	"\fEmbedRequest\x12\x1b\n" +
+	"\tplugin_id\x18\x01 \x01(\tR\bpluginId\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\x12\x17\n" +
+	"\auser_id\x18\x03 \x01(\rR\x06userId\"}\n" +
+	"\rEmbedResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1c\n" +
+	"\tembedding\x18\x02 \x03(\x02R\tembedding\x12\x1e\n" +
+	"\n" +
+	"dimensions\x18\x03 \x01(\x05R\n" +
+	"dimensions\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"_\n" +
+	"\x11EmbedBatchRequest\x12\x1b\n" +
+	"\tplugin_id\x18\x01 \x01(\tR\bpluginId\x12\x14\n" +
+	"\x05texts\x18\x02 \x03(\tR\x05texts\x12\x17\n" +
+	"\auser_id\x18\x03 \x01(\rR\x06userId\"\x7f\n" +
+	"\x12EmbedBatchResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x129\n" +
+	"\aresults\x18\x02 \x03(\v2\x1f.plugin_service.EmbeddingResultR\aresults\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"/\n" +
+	"\x0fEmbeddingResult\x12\x1c\n" +
+	"\tembedding\x18\x01 \x03(\x02R\tembedding\"\x99\x01\n" +
+	"\rRerankRequest\x12\x1b\n" +
+	"\tplugin_id\x18\x01 \x01(\tR\bpluginId\x12\x14\n" +
+	"\x05query\x18\x02 \x01(\tR\x05query\x12<\n" +
+	"\tdocuments\x18\x03 \x03(\v2\x1e.plugin_service.RerankDocumentR\tdocuments\x12\x17\n" +
+	"\auser_id\x18\x04 \x01(\rR\x06userId\"P\n" +
+	"\x0eRerankDocument\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\x12\x14\n" +
+	"\x05score\x18\x03 \x01(\x01R\x05score\"x\n" +
+	"\x0eRerankResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x126\n" +
+	"\aresults\x18\x02 \x03(\v2\x1c.plugin_service.RerankResultR\aresults\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"t\n" +
+	"\fRerankResult\x12:\n" +
+	"\bdocument\x18\x01 \x01(\v2\x1e.plugin_service.RerankDocumentR\bdocument\x12\x14\n" +
+	"\x05score\x18\x02 \x01(\x01R\x05score\x12\x12\n" +
+	"\x04rank\x18\x03 \x01(\x05R\x04rank\"Q\n" +
+	"\x19ListPluginVersionsRequest\x12\x1b\n" +
+	"\tplugin_id\x18\x01 \x01(\tR\bpluginId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\rR\x06userId\"\xa7\x01\n" +
+	"\x11PluginVersionInfo\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x16\n" +
+	"\x06active\x18\x03 \x01(\bR\x06active\x12!\n" +
+	"\finstalled_at\x18\x04 \x01(\x03R\vinstalledAt\x12!\n" +
+	"\factivated_at\x18\x05 \x01(\x03R\vactivatedAt\"\x9c\x01\n" +
+	"\x1aListPluginVersionsResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12=\n" +
+	"\bversions\x18\x02 \x03(\v2!.plugin_service.PluginVersionInfoR\bversions\x12%\n" +
+	"\x0eactive_version\x18\x03 \x01(\tR\ractiveVersion\"h\n" +
+	"\x16ActivateVersionRequest\x12\x1b\n" +
+	"\tplugin_id\x18\x01 \x01(\tR\bpluginId\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\x12\x17\n" +
+	"\auser_id\x18\x03 \x01(\rR\x06userId\"\x92\x01\n" +
+	"\x17ActivateVersionResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\x12)\n" +
+	"\x10previous_version\x18\x04 \x01(\tR\x0fpreviousVersion\"G\n" +
+	"\x0fRollbackRequest\x12\x1b\n" +
+	"\tplugin_id\x18\x01 \x01(\tR\bpluginId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\rR\x06userId\"\x8b\x01\n" +
+	"\x10RollbackResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\x12)\n" +
+	"\x10previous_version\x18\x04 \x01(\tR\x0fpreviousVersion\"\xaa\x01\n" +
+	"\vChatMessage\x12\x12\n" +
+	"\x04role\x18\x01 \x01(\tR\x04role\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12 \n" +
+	"\ftool_call_id\x18\x04 \x01(\tR\n" +
+	"toolCallId\x127\n" +
+	"\n" +
+	"tool_calls\x18\x05 \x03(\v2\x18.plugin_service.ToolCallR\ttoolCalls\"v\n" +
+	"\bToolCall\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x1c\n" +
+	"\targuments\x18\x04 \x01(\tR\targuments\x12\x14\n" +
+	"\x05index\x18\x05 \x01(\x05R\x05index\"\x83\x01\n" +
+	"\x0eToolDefinition\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12'\n" +
+	"\x0fparameters_json\x18\x04 \x01(\fR\x0eparametersJson\"\x8f\x01\n" +
+	"\x0eSamplingParams\x12 \n" +
+	"\vtemperature\x18\x01 \x01(\x01R\vtemperature\x12\x1d\n" +
+	"\n" +
+	"max_tokens\x18\x02 \x01(\x05R\tmaxTokens\x12\x13\n" +
+	"\x05top_p\x18\x03 \x01(\x01R\x04topP\x12\x13\n" +
+	"\x05top_k\x18\x04 \x01(\x05R\x04topK\x12\x12\n" +
+	"\x04stop\x18\x05 \x03(\tR\x04stop\"\xcd\x02\n" +
+	"\vChatRequest\x12\x1b\n" +
+	"\tplugin_id\x18\x01 \x01(\tR\bpluginId\x12\x14\n" +
+	"\x05model\x18\x02 \x01(\tR\x05model\x127\n" +
+	"\bmessages\x18\x03 \x03(\v2\x1b.plugin_service.ChatMessageR\bmessages\x126\n" +
+	"\x06params\x18\x04 \x01(\v2\x1e.plugin_service.SamplingParamsR\x06params\x124\n" +
+	"\x05tools\x18\x05 \x03(\v2\x1e.plugin_service.ToolDefinitionR\x05tools\x12\x1f\n" +
+	"\vtool_choice\x18\x06 \x01(\tR\n" +
+	"toolChoice\x12*\n" +
+	"\x11extra_params_json\x18\a \x01(\fR\x0fextraParamsJson\x12\x17\n" +
+	"\auser_id\x18\b \x01(\rR\x06userId\"\x80\x01\n" +
+	"\tChatUsage\x12#\n" +
+	"\rprompt_tokens\x18\x01 \x01(\x05R\fpromptTokens\x12+\n" +
+	"\x11completion_tokens\x18\x02 \x01(\x05R\x10completionTokens\x12!\n" +
+	"\ftotal_tokens\x18\x03 \x01(\x05R\vtotalTokens\"~\n" +
+	"\n" +
+	"ChatChoice\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x125\n" +
+	"\amessage\x18\x02 \x01(\v2\x1b.plugin_service.ChatMessageR\amessage\x12#\n" +
+	"\rfinish_reason\x18\x03 \x01(\tR\ffinishReason\"\xcb\x01\n" +
+	"\fChatResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12\x14\n" +
+	"\x05model\x18\x03 \x01(\tR\x05model\x124\n" +
+	"\achoices\x18\x04 \x03(\v2\x1a.plugin_service.ChatChoiceR\achoices\x12/\n" +
+	"\x05usage\x18\x05 \x01(\v2\x19.plugin_service.ChatUsageR\x05usage\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\"\xd6\x01\n" +
+	"\x0fChatStreamChunk\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05model\x18\x02 \x01(\tR\x05model\x12\x14\n" +
+	"\x05index\x18\x03 \x01(\x05R\x05index\x121\n" +
+	"\x05delta\x18\x04 \x01(\v2\x1b.plugin_service.ChatMessageR\x05delta\x12#\n" +
+	"\rfinish_reason\x18\x05 \x01(\tR\ffinishReason\x12/\n" +
+	"\x05usage\x18\x06 \x01(\v2\x19.plugin_service.ChatUsageR\x05usage2\xbd\t\n" +
+	"\rPluginService\x12Y\n" +
+	"\fUploadPlugin\x12#.plugin_service.UploadPluginRequest\x1a$.plugin_service.UploadPluginResponse\x12V\n" +
+	"\vListPlugins\x12\".plugin_service.ListPluginsRequest\x1a#.plugin_service.ListPluginsResponse\x12P\n" +
+	"\tGetModels\x12 .plugin_service.GetModelsRequest\x1a!.plugin_service.GetModelsResponse\x12Y\n" +
+	"\fEnablePlugin\x12#.plugin_service.EnablePluginRequest\x1a$.plugin_service.EnablePluginResponse\x12\\\n" +
+	"\rDisablePlugin\x12$.plugin_service.DisablePluginRequest\x1a%.plugin_service.DisablePluginResponse\x12Y\n" +
+	"\fDeletePlugin\x12#.plugin_service.DeletePluginRequest\x1a$.plugin_service.DeletePluginResponse\x12D\n" +
+	"\x05Embed\x12\x1c.plugin_service.EmbedRequest\x1a\x1d.plugin_service.EmbedResponse\x12S\n" +
+	"\n" +
+	"EmbedBatch\x12!.plugin_service.EmbedBatchRequest\x1a\".plugin_service.EmbedBatchResponse\x12G\n" +
+	"\x06Rerank\x12\x1d.plugin_service.RerankRequest\x1a\x1e.plugin_service.RerankResponse\x12k\n" +
+	"\x12ListPluginVersions\x12).plugin_service.ListPluginVersionsRequest\x1a*.plugin_service.ListPluginVersionsResponse\x12b\n" +
+	"\x0fActivateVersion\x12&.plugin_service.ActivateVersionRequest\x1a'.plugin_service.ActivateVersionResponse\x12M\n" +
+	"\bRollback\x12\x1f.plugin_service.RollbackRequest\x1a .plugin_service.RollbackResponse\x12A\n" +
+	"\x04Chat\x12\x1b.plugin_service.ChatRequest\x1a\x1c.plugin_service.ChatResponse\x12L\n" +
+	"\n" +
+	"ChatStream\x12\x1b.plugin_service.ChatRequest\x1a\x1f.plugin_service.ChatStreamChunk0\x01B\x18Z\x16./proto/plugin_serviceb\x06proto3"
 
 var (
 	file_proto_plugin_service_proto_rawDescOnce sync.Once
@@ -1533,66 +3055,104 @@ func file_proto_plugin_service_proto_rawDescGZIP() []byte {
 	return file_proto_plugin_service_proto_rawDescData
 }
 
-var file_proto_plugin_service_proto_msgTypes = make([]protoimpl.MessageInfo, 25)
+var file_proto_plugin_service_proto_msgTypes = make([]protoimpl.MessageInfo, 42)
 var file_proto_plugin_service_proto_goTypes = []any{
-	(*UploadPluginRequest)(nil),   // 0: plugin_service.UploadPluginRequest
-	(*UploadPluginResponse)(nil),  // 1: plugin_service.UploadPluginResponse
-	(*ListPluginsRequest)(nil),    // 2: plugin_service.ListPluginsRequest
-	(*PluginInfo)(nil),            // 3: plugin_service.PluginInfo
-	(*CapabilityInfo)(nil),        // 4: plugin_service.CapabilityInfo
-	(*ListPluginsResponse)(nil),   // 5: plugin_service.ListPluginsResponse
-	(*GetModelsRequest)(nil),      // 6: plugin_service.GetModelsRequest
-	(*GetModelsResponse)(nil),     // 7: plugin_service.GetModelsResponse
-	(*ModelList)(nil),             // 8: plugin_service.ModelList
-	(*EnablePluginRequest)(nil),   // 9: plugin_service.EnablePluginRequest
-	(*EnablePluginResponse)(nil),  // 10: plugin_service.EnablePluginResponse
-	(*DisablePluginRequest)(nil),  // 11: plugin_service.DisablePluginRequest
-	(*DisablePluginResponse)(nil), // 12: plugin_service.DisablePluginResponse
-	(*DeletePluginRequest)(nil),   // 13: plugin_service.DeletePluginRequest
-	(*DeletePluginResponse)(nil),  // 14: plugin_service.DeletePluginResponse
-	(*EmbedRequest)(nil),          // 15: plugin_service.EmbedRequest
-	(*EmbedResponse)(nil),         // 16: plugin_service.EmbedResponse
-	(*EmbedBatchRequest)(nil),     // 17: plugin_service.EmbedBatchRequest
-	(*EmbedBatchResponse)(nil),    // 18: plugin_service.EmbedBatchResponse
-	(*EmbeddingResult)(nil),       // 19: plugin_service.EmbeddingResult
-	(*RerankRequest)(nil),         // 20: plugin_service.RerankRequest
-	(*RerankDocument)(nil),        // 21: plugin_service.RerankDocument
-	(*RerankResponse)(nil),        // 22: plugin_service.RerankResponse
-	(*RerankResult)(nil),          // 23: plugin_service.RerankResult
-	nil,                           // 24: plugin_service.GetModelsResponse.ModelsEntry
+	(*UploadPluginRequest)(nil),        // 0: plugin_service.UploadPluginRequest
+	(*UploadPluginResponse)(nil),       // 1: plugin_service.UploadPluginResponse
+	(*ListPluginsRequest)(nil),         // 2: plugin_service.ListPluginsRequest
+	(*PluginInfo)(nil),                 // 3: plugin_service.PluginInfo
+	(*PluginStats)(nil),                // 4: plugin_service.PluginStats
+	(*CapabilityInfo)(nil),             // 5: plugin_service.CapabilityInfo
+	(*ListPluginsResponse)(nil),        // 6: plugin_service.ListPluginsResponse
+	(*GetModelsRequest)(nil),           // 7: plugin_service.GetModelsRequest
+	(*GetModelsResponse)(nil),          // 8: plugin_service.GetModelsResponse
+	(*ModelList)(nil),                  // 9: plugin_service.ModelList
+	(*EnablePluginRequest)(nil),        // 10: plugin_service.EnablePluginRequest
+	(*EnablePluginResponse)(nil),       // 11: plugin_service.EnablePluginResponse
+	(*DisablePluginRequest)(nil),       // 12: plugin_service.DisablePluginRequest
+	(*DisablePluginResponse)(nil),      // 13: plugin_service.DisablePluginResponse
+	(*DeletePluginRequest)(nil),        // 14: plugin_service.DeletePluginRequest
+	(*DeletePluginResponse)(nil),       // 15: plugin_service.DeletePluginResponse
+	(*EmbedRequest)(nil),               // 16: plugin_service.EmbedRequest
+	(*EmbedResponse)(nil),              // 17: plugin_service.EmbedResponse
+	(*EmbedBatchRequest)(nil),          // 18: plugin_service.EmbedBatchRequest
+	(*EmbedBatchResponse)(nil),         // 19: plugin_service.EmbedBatchResponse
+	(*EmbeddingResult)(nil),            // 20: plugin_service.EmbeddingResult
+	(*RerankRequest)(nil),              // 21: plugin_service.RerankRequest
+	(*RerankDocument)(nil),             // 22: plugin_service.RerankDocument
+	(*RerankResponse)(nil),             // 23: plugin_service.RerankResponse
+	(*RerankResult)(nil),               // 24: plugin_service.RerankResult
+	(*ListPluginVersionsRequest)(nil),  // 25: plugin_service.ListPluginVersionsRequest
+	(*PluginVersionInfo)(nil),          // 26: plugin_service.PluginVersionInfo
+	(*ListPluginVersionsResponse)(nil), // 27: plugin_service.ListPluginVersionsResponse
+	(*ActivateVersionRequest)(nil),     // 28: plugin_service.ActivateVersionRequest
+	(*ActivateVersionResponse)(nil),    // 29: plugin_service.ActivateVersionResponse
+	(*RollbackRequest)(nil),            // 30: plugin_service.RollbackRequest
+	(*RollbackResponse)(nil),           // 31: plugin_service.RollbackResponse
+	(*ChatMessage)(nil),                // 32: plugin_service.ChatMessage
+	(*ToolCall)(nil),                   // 33: plugin_service.ToolCall
+	(*ToolDefinition)(nil),             // 34: plugin_service.ToolDefinition
+	(*SamplingParams)(nil),             // 35: plugin_service.SamplingParams
+	(*ChatRequest)(nil),                // 36: plugin_service.ChatRequest
+	(*ChatUsage)(nil),                  // 37: plugin_service.ChatUsage
+	(*ChatChoice)(nil),                 // 38: plugin_service.ChatChoice
+	(*ChatResponse)(nil),               // 39: plugin_service.ChatResponse
+	(*ChatStreamChunk)(nil),            // 40: plugin_service.ChatStreamChunk
+	nil,                                // 41: plugin_service.GetModelsResponse.ModelsEntry
 }
 var file_proto_plugin_service_proto_depIdxs = []int32{
-	4,  // 0: plugin_service.PluginInfo.capabilities:type_name -> plugin_service.CapabilityInfo
-	3,  // 1: plugin_service.ListPluginsResponse.plugins:type_name -> plugin_service.PluginInfo
-	24, // 2: plugin_service.GetModelsResponse.models:type_name -> plugin_service.GetModelsResponse.ModelsEntry
-	19, // 3: plugin_service.EmbedBatchResponse.results:type_name -> plugin_service.EmbeddingResult
-	21, // 4: plugin_service.RerankRequest.documents:type_name -> plugin_service.RerankDocument
-	23, // 5: plugin_service.RerankResponse.results:type_name -> plugin_service.RerankResult
-	21, // 6: plugin_service.RerankResult.document:type_name -> plugin_service.RerankDocument
-	8,  // 7: plugin_service.GetModelsResponse.ModelsEntry.value:type_name -> plugin_service.ModelList
-	0,  // 8: plugin_service.PluginService.UploadPlugin:input_type -> plugin_service.UploadPluginRequest
-	2,  // 9: plugin_service.PluginService.ListPlugins:input_type -> plugin_service.ListPluginsRequest
-	6,  // 10: plugin_service.PluginService.GetModels:input_type -> plugin_service.GetModelsRequest
-	9,  // 11: plugin_service.PluginService.EnablePlugin:input_type -> plugin_service.EnablePluginRequest
-	11, // 12: plugin_service.PluginService.DisablePlugin:input_type -> plugin_service.DisablePluginRequest
-	13, // 13: plugin_service.PluginService.DeletePlugin:input_type -> plugin_service.DeletePluginRequest
-	15, // 14: plugin_service.PluginService.Embed:input_type -> plugin_service.EmbedRequest
-	17, // 15: plugin_service.PluginService.EmbedBatch:input_type -> plugin_service.EmbedBatchRequest
-	20, // 16: plugin_service.PluginService.Rerank:input_type -> plugin_service.RerankRequest
-	1,  // 17: plugin_service.PluginService.UploadPlugin:output_type -> plugin_service.UploadPluginResponse
-	5,  // 18: plugin_service.PluginService.ListPlugins:output_type -> plugin_service.ListPluginsResponse
-	7,  // 19: plugin_service.PluginService.GetModels:output_type -> plugin_service.GetModelsResponse
-	10, // 20: plugin_service.PluginService.EnablePlugin:output_type -> plugin_service.EnablePluginResponse
-	12, // 21: plugin_service.PluginService.DisablePlugin:output_type -> plugin_service.DisablePluginResponse
-	14, // 22: plugin_service.PluginService.DeletePlugin:output_type -> plugin_service.DeletePluginResponse
-	16, // 23: plugin_service.PluginService.Embed:output_type -> plugin_service.EmbedResponse
-	18, // 24: plugin_service.PluginService.EmbedBatch:output_type -> plugin_service.EmbedBatchResponse
-	22, // 25: plugin_service.PluginService.Rerank:output_type -> plugin_service.RerankResponse
-	17, // [17:26] is the sub-list for method output_type
-	8,  // [8:17] is the sub-list for method input_type
-	8,  // [8:8] is the sub-list for extension type_name
-	8,  // [8:8] is the sub-list for extension extendee
-	0,  // [0:8] is the sub-list for field type_name
+	5,  // 0: plugin_service.PluginInfo.capabilities:type_name -> plugin_service.CapabilityInfo
+	4,  // 1: plugin_service.PluginInfo.stats:type_name -> plugin_service.PluginStats
+	3,  // 2: plugin_service.ListPluginsResponse.plugins:type_name -> plugin_service.PluginInfo
+	41, // 3: plugin_service.GetModelsResponse.models:type_name -> plugin_service.GetModelsResponse.ModelsEntry
+	20, // 4: plugin_service.EmbedBatchResponse.results:type_name -> plugin_service.EmbeddingResult
+	22, // 5: plugin_service.RerankRequest.documents:type_name -> plugin_service.RerankDocument
+	24, // 6: plugin_service.RerankResponse.results:type_name -> plugin_service.RerankResult
+	22, // 7: plugin_service.RerankResult.document:type_name -> plugin_service.RerankDocument
+	26, // 8: plugin_service.ListPluginVersionsResponse.versions:type_name -> plugin_service.PluginVersionInfo
+	33, // 9: plugin_service.ChatMessage.tool_calls:type_name -> plugin_service.ToolCall
+	32, // 10: plugin_service.ChatRequest.messages:type_name -> plugin_service.ChatMessage
+	35, // 11: plugin_service.ChatRequest.params:type_name -> plugin_service.SamplingParams
+	34, // 12: plugin_service.ChatRequest.tools:type_name -> plugin_service.ToolDefinition
+	32, // 13: plugin_service.ChatChoice.message:type_name -> plugin_service.ChatMessage
+	38, // 14: plugin_service.ChatResponse.choices:type_name -> plugin_service.ChatChoice
+	37, // 15: plugin_service.ChatResponse.usage:type_name -> plugin_service.ChatUsage
+	32, // 16: plugin_service.ChatStreamChunk.delta:type_name -> plugin_service.ChatMessage
+	37, // 17: plugin_service.ChatStreamChunk.usage:type_name -> plugin_service.ChatUsage
+	9,  // 18: plugin_service.GetModelsResponse.ModelsEntry.value:type_name -> plugin_service.ModelList
+	0,  // 19: plugin_service.PluginService.UploadPlugin:input_type -> plugin_service.UploadPluginRequest
+	2,  // 20: plugin_service.PluginService.ListPlugins:input_type -> plugin_service.ListPluginsRequest
+	7,  // 21: plugin_service.PluginService.GetModels:input_type -> plugin_service.GetModelsRequest
+	10, // 22: plugin_service.PluginService.EnablePlugin:input_type -> plugin_service.EnablePluginRequest
+	12, // 23: plugin_service.PluginService.DisablePlugin:input_type -> plugin_service.DisablePluginRequest
+	14, // 24: plugin_service.PluginService.DeletePlugin:input_type -> plugin_service.DeletePluginRequest
+	16, // 25: plugin_service.PluginService.Embed:input_type -> plugin_service.EmbedRequest
+	18, // 26: plugin_service.PluginService.EmbedBatch:input_type -> plugin_service.EmbedBatchRequest
+	21, // 27: plugin_service.PluginService.Rerank:input_type -> plugin_service.RerankRequest
+	25, // 28: plugin_service.PluginService.ListPluginVersions:input_type -> plugin_service.ListPluginVersionsRequest
+	28, // 29: plugin_service.PluginService.ActivateVersion:input_type -> plugin_service.ActivateVersionRequest
+	30, // 30: plugin_service.PluginService.Rollback:input_type -> plugin_service.RollbackRequest
+	36, // 31: plugin_service.PluginService.Chat:input_type -> plugin_service.ChatRequest
+	36, // 32: plugin_service.PluginService.ChatStream:input_type -> plugin_service.ChatRequest
+	1,  // 33: plugin_service.PluginService.UploadPlugin:output_type -> plugin_service.UploadPluginResponse
+	6,  // 34: plugin_service.PluginService.ListPlugins:output_type -> plugin_service.ListPluginsResponse
+	8,  // 35: plugin_service.PluginService.GetModels:output_type -> plugin_service.GetModelsResponse
+	11, // 36: plugin_service.PluginService.EnablePlugin:output_type -> plugin_service.EnablePluginResponse
+	13, // 37: plugin_service.PluginService.DisablePlugin:output_type -> plugin_service.DisablePluginResponse
+	15, // 38: plugin_service.PluginService.DeletePlugin:output_type -> plugin_service.DeletePluginResponse
+	17, // 39: plugin_service.PluginService.Embed:output_type -> plugin_service.EmbedResponse
+	19, // 40: plugin_service.PluginService.EmbedBatch:output_type -> plugin_service.EmbedBatchResponse
+	23, // 41: plugin_service.PluginService.Rerank:output_type -> plugin_service.RerankResponse
+	27, // 42: plugin_service.PluginService.ListPluginVersions:output_type -> plugin_service.ListPluginVersionsResponse
+	29, // 43: plugin_service.PluginService.ActivateVersion:output_type -> plugin_service.ActivateVersionResponse
+	31, // 44: plugin_service.PluginService.Rollback:output_type -> plugin_service.RollbackResponse
+	39, // 45: plugin_service.PluginService.Chat:output_type -> plugin_service.ChatResponse
+	40, // 46: plugin_service.PluginService.ChatStream:output_type -> plugin_service.ChatStreamChunk
+	33, // [33:47] is the sub-list for method output_type
+	19, // [19:33] is the sub-list for method input_type
+	19, // [19:19] is the sub-list for extension type_name
+	19, // [19:19] is the sub-list for extension extendee
+	0,  // [0:19] is the sub-list for field type_name
 }
 
 func init() { file_proto_plugin_service_proto_init() }
@@ -1606,7 +3166,7 @@ func file_proto_plugin_service_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_plugin_service_proto_rawDesc), len(file_proto_plugin_service_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   25,
+			NumMessages:   42,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
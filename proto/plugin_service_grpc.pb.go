@@ -19,15 +19,20 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	PluginService_UploadPlugin_FullMethodName  = "/plugin_service.PluginService/UploadPlugin"
-	PluginService_ListPlugins_FullMethodName   = "/plugin_service.PluginService/ListPlugins"
-	PluginService_GetModels_FullMethodName     = "/plugin_service.PluginService/GetModels"
-	PluginService_EnablePlugin_FullMethodName  = "/plugin_service.PluginService/EnablePlugin"
-	PluginService_DisablePlugin_FullMethodName = "/plugin_service.PluginService/DisablePlugin"
-	PluginService_DeletePlugin_FullMethodName  = "/plugin_service.PluginService/DeletePlugin"
-	PluginService_Embed_FullMethodName         = "/plugin_service.PluginService/Embed"
-	PluginService_EmbedBatch_FullMethodName    = "/plugin_service.PluginService/EmbedBatch"
-	PluginService_Rerank_FullMethodName        = "/plugin_service.PluginService/Rerank"
+	PluginService_UploadPlugin_FullMethodName       = "/plugin_service.PluginService/UploadPlugin"
+	PluginService_ListPlugins_FullMethodName        = "/plugin_service.PluginService/ListPlugins"
+	PluginService_GetModels_FullMethodName          = "/plugin_service.PluginService/GetModels"
+	PluginService_EnablePlugin_FullMethodName       = "/plugin_service.PluginService/EnablePlugin"
+	PluginService_DisablePlugin_FullMethodName      = "/plugin_service.PluginService/DisablePlugin"
+	PluginService_DeletePlugin_FullMethodName       = "/plugin_service.PluginService/DeletePlugin"
+	PluginService_Embed_FullMethodName              = "/plugin_service.PluginService/Embed"
+	PluginService_EmbedBatch_FullMethodName         = "/plugin_service.PluginService/EmbedBatch"
+	PluginService_Rerank_FullMethodName             = "/plugin_service.PluginService/Rerank"
+	PluginService_ListPluginVersions_FullMethodName = "/plugin_service.PluginService/ListPluginVersions"
+	PluginService_ActivateVersion_FullMethodName    = "/plugin_service.PluginService/ActivateVersion"
+	PluginService_Rollback_FullMethodName           = "/plugin_service.PluginService/Rollback"
+	PluginService_Chat_FullMethodName               = "/plugin_service.PluginService/Chat"
+	PluginService_ChatStream_FullMethodName         = "/plugin_service.PluginService/ChatStream"
 )
 
 // PluginServiceClient is the client API for PluginService service.
@@ -54,6 +59,16 @@ type PluginServiceClient interface {
 	EmbedBatch(ctx context.Context, in *EmbedBatchRequest, opts ...grpc.CallOption) (*EmbedBatchResponse, error)
 	// 重排序文档
 	Rerank(ctx context.Context, in *RerankRequest, opts ...grpc.CallOption) (*RerankResponse, error)
+	// 列出插件的已安装版本
+	ListPluginVersions(ctx context.Context, in *ListPluginVersionsRequest, opts ...grpc.CallOption) (*ListPluginVersionsResponse, error)
+	// 切换插件的当前版本
+	ActivateVersion(ctx context.Context, in *ActivateVersionRequest, opts ...grpc.CallOption) (*ActivateVersionResponse, error)
+	// 回滚到上一个版本
+	Rollback(ctx context.Context, in *RollbackRequest, opts ...grpc.CallOption) (*RollbackResponse, error)
+	// 聊天（非流式）
+	Chat(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (*ChatResponse, error)
+	// 流式聊天
+	ChatStream(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChatStreamChunk], error)
 }
 
 type pluginServiceClient struct {
@@ -154,6 +169,65 @@ func (c *pluginServiceClient) Rerank(ctx context.Context, in *RerankRequest, opt
 	return out, nil
 }
 
+func (c *pluginServiceClient) ListPluginVersions(ctx context.Context, in *ListPluginVersionsRequest, opts ...grpc.CallOption) (*ListPluginVersionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPluginVersionsResponse)
+	err := c.cc.Invoke(ctx, PluginService_ListPluginVersions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginServiceClient) ActivateVersion(ctx context.Context, in *ActivateVersionRequest, opts ...grpc.CallOption) (*ActivateVersionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ActivateVersionResponse)
+	err := c.cc.Invoke(ctx, PluginService_ActivateVersion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginServiceClient) Rollback(ctx context.Context, in *RollbackRequest, opts ...grpc.CallOption) (*RollbackResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RollbackResponse)
+	err := c.cc.Invoke(ctx, PluginService_Rollback_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginServiceClient) Chat(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (*ChatResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChatResponse)
+	err := c.cc.Invoke(ctx, PluginService_Chat_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginServiceClient) ChatStream(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChatStreamChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PluginService_ServiceDesc.Streams[0], PluginService_ChatStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ChatRequest, ChatStreamChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PluginService_ChatStreamClient = grpc.ServerStreamingClient[ChatStreamChunk]
+
 // PluginServiceServer is the server API for PluginService service.
 // All implementations must embed UnimplementedPluginServiceServer
 // for forward compatibility.
@@ -178,6 +252,16 @@ type PluginServiceServer interface {
 	EmbedBatch(context.Context, *EmbedBatchRequest) (*EmbedBatchResponse, error)
 	// 重排序文档
 	Rerank(context.Context, *RerankRequest) (*RerankResponse, error)
+	// 列出插件的已安装版本
+	ListPluginVersions(context.Context, *ListPluginVersionsRequest) (*ListPluginVersionsResponse, error)
+	// 切换插件的当前版本
+	ActivateVersion(context.Context, *ActivateVersionRequest) (*ActivateVersionResponse, error)
+	// 回滚到上一个版本
+	Rollback(context.Context, *RollbackRequest) (*RollbackResponse, error)
+	// 聊天（非流式）
+	Chat(context.Context, *ChatRequest) (*ChatResponse, error)
+	// 流式聊天
+	ChatStream(*ChatRequest, grpc.ServerStreamingServer[ChatStreamChunk]) error
 	mustEmbedUnimplementedPluginServiceServer()
 }
 
@@ -215,6 +299,21 @@ func (UnimplementedPluginServiceServer) EmbedBatch(context.Context, *EmbedBatchR
 func (UnimplementedPluginServiceServer) Rerank(context.Context, *RerankRequest) (*RerankResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method Rerank not implemented")
 }
+func (UnimplementedPluginServiceServer) ListPluginVersions(context.Context, *ListPluginVersionsRequest) (*ListPluginVersionsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListPluginVersions not implemented")
+}
+func (UnimplementedPluginServiceServer) ActivateVersion(context.Context, *ActivateVersionRequest) (*ActivateVersionResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ActivateVersion not implemented")
+}
+func (UnimplementedPluginServiceServer) Rollback(context.Context, *RollbackRequest) (*RollbackResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Rollback not implemented")
+}
+func (UnimplementedPluginServiceServer) Chat(context.Context, *ChatRequest) (*ChatResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Chat not implemented")
+}
+func (UnimplementedPluginServiceServer) ChatStream(*ChatRequest, grpc.ServerStreamingServer[ChatStreamChunk]) error {
+	return status.Error(codes.Unimplemented, "method ChatStream not implemented")
+}
 func (UnimplementedPluginServiceServer) mustEmbedUnimplementedPluginServiceServer() {}
 func (UnimplementedPluginServiceServer) testEmbeddedByValue()                       {}
 
@@ -398,6 +497,89 @@ func _PluginService_Rerank_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PluginService_ListPluginVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPluginVersionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginServiceServer).ListPluginVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PluginService_ListPluginVersions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginServiceServer).ListPluginVersions(ctx, req.(*ListPluginVersionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PluginService_ActivateVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ActivateVersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginServiceServer).ActivateVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PluginService_ActivateVersion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginServiceServer).ActivateVersion(ctx, req.(*ActivateVersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PluginService_Rollback_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RollbackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginServiceServer).Rollback(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PluginService_Rollback_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginServiceServer).Rollback(ctx, req.(*RollbackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PluginService_Chat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginServiceServer).Chat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PluginService_Chat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginServiceServer).Chat(ctx, req.(*ChatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PluginService_ChatStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ChatRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PluginServiceServer).ChatStream(m, &grpc.GenericServerStream[ChatRequest, ChatStreamChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PluginService_ChatStreamServer = grpc.ServerStreamingServer[ChatStreamChunk]
+
 // PluginService_ServiceDesc is the grpc.ServiceDesc for PluginService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -441,7 +623,29 @@ var PluginService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Rerank",
 			Handler:    _PluginService_Rerank_Handler,
 		},
+		{
+			MethodName: "ListPluginVersions",
+			Handler:    _PluginService_ListPluginVersions_Handler,
+		},
+		{
+			MethodName: "ActivateVersion",
+			Handler:    _PluginService_ActivateVersion_Handler,
+		},
+		{
+			MethodName: "Rollback",
+			Handler:    _PluginService_Rollback_Handler,
+		},
+		{
+			MethodName: "Chat",
+			Handler:    _PluginService_Chat_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ChatStream",
+			Handler:       _PluginService_ChatStream_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/plugin_service.proto",
 }